@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/compare"
+)
+
+// diffConfig holds parsed configuration for the diff subcommand
+type diffConfig struct {
+	expected   string
+	actual     string
+	threshold  int
+	idPrefixes map[string]string
+	verbose    bool
+	showHelp   bool
+}
+
+// parseDiffArgs parses the arguments following "diff" into a diffConfig
+func parseDiffArgs(args []string) (*diffConfig, error) {
+	cfg := &diffConfig{
+		idPrefixes: make(map[string]string),
+	}
+
+	var positional []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			switch {
+			case arg == "--help" || arg == "-h":
+				cfg.showHelp = true
+			case arg == "--verbose":
+				cfg.verbose = true
+			case strings.HasPrefix(arg, "--threshold="):
+				value := strings.TrimPrefix(arg, "--threshold=")
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("invalid threshold: %q (must be a non-negative integer)", value)
+				}
+				cfg.threshold = n
+			case strings.HasPrefix(arg, "--id-prefix="):
+				value := strings.TrimPrefix(arg, "--id-prefix=")
+				from, to, _ := strings.Cut(value, ":")
+				if from == "" {
+					return nil, fmt.Errorf("invalid id prefix: %q (must be FROM or FROM:TO)", value)
+				}
+				cfg.idPrefixes[from] = to
+			default:
+				return nil, fmt.Errorf("unknown flag: %s", arg)
+			}
+		} else {
+			positional = append(positional, arg)
+		}
+	}
+
+	if cfg.showHelp {
+		return cfg, nil
+	}
+
+	if len(positional) != 2 {
+		return nil, fmt.Errorf("diff requires exactly 2 arguments: <a> <b>")
+	}
+	cfg.expected = positional[0]
+	cfg.actual = positional[1]
+
+	return cfg, nil
+}
+
+// runDiff diffs the two feeds in cfg, writes a summary to w and returns the
+// total number of differing rows and files
+func runDiff(cfg *diffConfig, w io.Writer) (int, error) {
+	results, err := compare.DiffGTFS(cfg.expected, cfg.actual, compare.DiffOptions{
+		IDPrefixMapping: cfg.idPrefixes,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, result := range results {
+		removed, added, changed := result.Counts()
+		total += len(result.Differences)
+
+		line := fmt.Sprintf("%s: %d added, %d removed, %d changed", result.File, added, removed, changed)
+		if cols := result.ChangedColumns(); len(cols) > 0 {
+			line += fmt.Sprintf(" (%s)", strings.Join(cols, ", "))
+		}
+		if result.HeaderChanged() {
+			line += "; columns differ"
+		}
+		_, _ = fmt.Fprintln(w, line)
+	}
+
+	if cfg.verbose && len(results) > 0 {
+		_, _ = fmt.Fprintln(w)
+		_, _ = fmt.Fprint(w, compare.FormatDiffStyleOutput(results))
+	}
+
+	_, _ = fmt.Fprintf(w, "%d difference(s) across %d file(s)\n", total, len(results))
+	return total, nil
+}
+
+// printDiffUsage prints the usage information for the diff subcommand
+func printDiffUsage() {
+	fmt.Println(`gtfs-merge diff - Compare two GTFS feeds row by row
+
+Usage:
+  gtfs-merge diff [options] <a> <b>
+
+Arguments:
+  a, b                 GTFS feeds to compare (zip files or directories)
+
+Rows are matched by each file's primary key (e.g. stop_id, or trip_id and
+stop_sequence for stop_times.txt); column order is ignored. Rows only in <a>
+are reported as removed and rows only in <b> as added.
+
+Options:
+  --help, -h           Show this help message
+  --threshold=N        Number of differences tolerated before exiting
+                       with status 1 (default: 0)
+  --id-prefix=FROM[:TO]
+                       Rewrite ID prefix FROM to TO (default: empty) on both
+                       sides before matching; may be repeated
+  --verbose            Print every differing row
+
+Exit status is 0 when differences are within the threshold, 1 when they
+exceed it, and 2 on error.
+
+Examples:
+  gtfs-merge diff old.zip new.zip
+  gtfs-merge diff --threshold=10 --id-prefix=b- old.zip new.zip`)
+}
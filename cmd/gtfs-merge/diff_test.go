@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseDiffArgs(t *testing.T) {
+	cfg, err := parseDiffArgs([]string{"--threshold=5", "--id-prefix=b-", "--id-prefix=c-:x-", "a.zip", "b.zip"})
+	if err != nil {
+		t.Fatalf("parseDiffArgs failed: %v", err)
+	}
+
+	if cfg.expected != "a.zip" || cfg.actual != "b.zip" {
+		t.Errorf("expected a.zip and b.zip, got %q and %q", cfg.expected, cfg.actual)
+	}
+	if cfg.threshold != 5 {
+		t.Errorf("expected threshold 5, got %d", cfg.threshold)
+	}
+	if to, ok := cfg.idPrefixes["b-"]; !ok || to != "" {
+		t.Errorf("expected b- to map to empty, got %q (present=%v)", to, ok)
+	}
+	if cfg.idPrefixes["c-"] != "x-" {
+		t.Errorf("expected c- to map to x-, got %q", cfg.idPrefixes["c-"])
+	}
+}
+
+func TestParseDiffArgsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"one feed", []string{"a.zip"}},
+		{"three feeds", []string{"a.zip", "b.zip", "c.zip"}},
+		{"negative threshold", []string{"--threshold=-1", "a.zip", "b.zip"}},
+		{"non-numeric threshold", []string{"--threshold=many", "a.zip", "b.zip"}},
+		{"empty prefix", []string{"--id-prefix=:x", "a.zip", "b.zip"}},
+		{"unknown flag", []string{"--bogus", "a.zip", "b.zip"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseDiffArgs(tt.args); err == nil {
+				t.Errorf("expected error for args %v", tt.args)
+			}
+		})
+	}
+}
+
+func TestRunDiffIdenticalFeeds(t *testing.T) {
+	cfg := &diffConfig{expected: "../../testdata/simple_a", actual: "../../testdata/simple_a"}
+
+	var out bytes.Buffer
+	total, err := runDiff(cfg, &out)
+	if err != nil {
+		t.Fatalf("runDiff failed: %v", err)
+	}
+
+	if total != 0 {
+		t.Errorf("expected no differences, got %d:\n%s", total, out.String())
+	}
+	if !strings.Contains(out.String(), "0 difference(s)") {
+		t.Errorf("expected summary line, got:\n%s", out.String())
+	}
+}
+
+func TestRunDiffDifferentFeeds(t *testing.T) {
+	cfg := &diffConfig{expected: "../../testdata/simple_a", actual: "../../testdata/simple_b", verbose: true}
+
+	var out bytes.Buffer
+	total, err := runDiff(cfg, &out)
+	if err != nil {
+		t.Fatalf("runDiff failed: %v", err)
+	}
+
+	if total == 0 {
+		t.Fatal("expected differences between simple_a and simple_b")
+	}
+	for _, want := range []string{"stops.txt:", "added", "removed", "--- expected/stops.txt"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestDiffMainExitStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"identical", []string{"../../testdata/simple_a", "../../testdata/simple_a"}, 0},
+		{"over threshold", []string{"../../testdata/simple_a", "../../testdata/simple_b"}, 1},
+		{"within threshold", []string{"--threshold=1000", "../../testdata/simple_a", "../../testdata/simple_b"}, 0},
+		{"missing input", []string{"../../testdata/simple_a", "does-not-exist.zip"}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffMain(tt.args); got != tt.want {
+				t.Errorf("expected exit status %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/merge"
+)
+
+// extractCmdConfig holds parsed configuration for the extract subcommand
+type extractCmdConfig struct {
+	input    string
+	output   string
+	prefix   string
+	showHelp bool
+}
+
+// parseExtractArgs parses the arguments following "extract" into an extractCmdConfig
+func parseExtractArgs(args []string) (*extractCmdConfig, error) {
+	cfg := &extractCmdConfig{}
+
+	var positional []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			switch {
+			case arg == "--help" || arg == "-h":
+				cfg.showHelp = true
+			case strings.HasPrefix(arg, "--prefix="):
+				cfg.prefix = strings.TrimPrefix(arg, "--prefix=")
+			default:
+				return nil, fmt.Errorf("unknown flag: %s", arg)
+			}
+		} else {
+			positional = append(positional, arg)
+		}
+	}
+
+	if cfg.showHelp {
+		return cfg, nil
+	}
+
+	if len(positional) != 2 {
+		return nil, fmt.Errorf("extract requires exactly 2 arguments: <merged> <output>")
+	}
+	if cfg.prefix == "" {
+		return nil, fmt.Errorf("extract requires --prefix")
+	}
+	cfg.input = positional[0]
+	cfg.output = positional[1]
+
+	return cfg, nil
+}
+
+// runExtract writes the part of the merged feed carrying cfg.prefix to cfg.output
+func runExtract(cfg *extractCmdConfig) (*gtfs.Feed, error) {
+	merged, err := gtfs.ReadFromPath(cfg.input)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", cfg.input, err)
+	}
+
+	feed, err := merge.Extract(merged, cfg.prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := gtfs.WriteToPath(feed, cfg.output); err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+// printExtractUsage prints the usage information for the extract subcommand
+func printExtractUsage() {
+	fmt.Println(`gtfs-merge extract - Extract one input's part of a merged feed
+
+Usage:
+  gtfs-merge extract --prefix=PREFIX <merged> <output>
+
+Arguments:
+  merged               Merged GTFS feed (zip file or directory)
+  output               Output GTFS zip file
+
+Writes every entity whose ID starts with PREFIX, plus the entities they
+reference, as a standalone feed with PREFIX stripped from its IDs. Prefixes
+are only added on ID collisions, so an input's entities that never collided
+are included only when something prefixed references them. feed_info.txt is
+not extracted.
+
+Options:
+  --help, -h           Show this help message
+  --prefix=PREFIX      ID prefix of the input to extract (e.g. a-)
+
+Example:
+  gtfs-merge extract --prefix=a- merged.zip a.zip`)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/merge"
+)
+
+func TestParseExtractArgs(t *testing.T) {
+	cfg, err := parseExtractArgs([]string{"merged.zip", "--prefix=b-", "out.zip"})
+	if err != nil {
+		t.Fatalf("parseExtractArgs failed: %v", err)
+	}
+	if cfg.input != "merged.zip" || cfg.output != "out.zip" || cfg.prefix != "b-" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+
+	if _, err := parseExtractArgs([]string{"merged.zip", "out.zip"}); err == nil {
+		t.Error("expected error without --prefix")
+	}
+	if _, err := parseExtractArgs([]string{"--prefix=b-", "merged.zip"}); err == nil {
+		t.Error("expected error without an output")
+	}
+}
+
+func TestCLIExtractSubcommand(t *testing.T) {
+	// Given: a merged feed of minimal with itself, whose first copy is "a-"
+	tmpDir := t.TempDir()
+	merged := filepath.Join(tmpDir, "merged.zip")
+	// (MergeFiles would skip the second copy as identical)
+	var feeds []*gtfs.Feed
+	for range 2 {
+		feed, err := gtfs.ReadFromPath("../../testdata/minimal")
+		if err != nil {
+			t.Fatalf("failed to read minimal: %v", err)
+		}
+		feeds = append(feeds, feed)
+	}
+	mergedFeed, err := merge.New().MergeFeeds(feeds)
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+	if err := gtfs.WriteToPath(mergedFeed, merged); err != nil {
+		t.Fatalf("WriteToPath failed: %v", err)
+	}
+
+	// When: the "a-" part is extracted
+	cfg := &extractCmdConfig{input: merged, output: filepath.Join(tmpDir, "a.zip"), prefix: "a-"}
+	if _, err := runExtract(cfg); err != nil {
+		t.Fatalf("runExtract failed: %v", err)
+	}
+
+	// Then: the output holds the original trips under their original IDs
+	original, err := gtfs.ReadFromPath("../../testdata/minimal")
+	if err != nil {
+		t.Fatalf("failed to read minimal: %v", err)
+	}
+	extracted, err := gtfs.ReadFromPath(cfg.output)
+	if err != nil {
+		t.Fatalf("failed to read extracted feed: %v", err)
+	}
+	for id := range original.Trips {
+		if _, ok := extracted.Trips[id]; !ok {
+			t.Errorf("expected trip %s in extracted feed", id)
+		}
+	}
+	if len(extracted.Trips) != len(original.Trips) {
+		t.Errorf("expected %d trips, got %d", len(original.Trips), len(extracted.Trips))
+	}
+}
@@ -2,10 +2,19 @@
 package main
 
 import (
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
 	"github.com/aaronbrethorst/gtfs-merge-go/merge"
 	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
 )
@@ -21,6 +30,38 @@ type config struct {
 	duplicateDetection string
 	logging            string
 	files              map[string]fileConfig
+	extracts           []extractConfig
+	geojson            string                // path to write the merged feed as GeoJSON
+	encodings          map[int]gtfs.Encoding // by input index
+	overrides          map[int]string        // overrides files, by input index
+	stripIDPrefixes    map[int][]string      // ID namespace prefixes, by input index
+	onlyAgencies       map[int][]string      // agencies to keep, by input index
+	jsonSummary        bool
+	provenance         bool
+	idMapDir           string // directory for per-input ID map CSVs
+	force              bool
+	failOnIdentical    bool   // fail rather than skip identical inputs
+	skipInvalid        bool   // skip inputs that cannot be read
+	sanitizeText       bool   // clean control characters and invalid UTF-8 from inputs' text
+	normalizeColors    bool   // uppercase route colors and strip their '#'
+	fingerprintCache   string // file recording the last run, to skip repeats
+	stripNewlines      bool   // write line breaks inside values as spaces
+	crlf               bool   // end output lines with CRLF
+	quoteAll           bool   // quote every output field
+	checksum           bool   // write OUTPUT.sha256
+	profile            string
+	grayZone           float64
+	grayZonePolicy     string
+	routeStopScoring   string
+	blockedDuplicates  string   // CSV of pairs never to merge
+	prune              []string // kinds of unreferenced entities to delete
+	zipStore           []string // file patterns written uncompressed
+	uniqueStopCodes    string
+	frequencyOverlaps  string
+	serviceDays        int    // days from today checked for active service
+	noServiceCheck     bool   // skip the service check
+	serviceGapDays     int    // longest run of days a route may lack trips; 0 skips the check
+	readableTripIDs    string // pattern the merged trips are renamed after
 	showHelp           bool
 	showVersion        bool
 }
@@ -30,14 +71,38 @@ type fileConfig struct {
 	detection string
 }
 
+// extractConfig names a GTFS table to export on its own after the merge
+type extractConfig struct {
+	filename string // GTFS file, e.g. stop_times.txt
+	target   string // output path; gzipped when it ends in .gz
+}
+
+// parseExtract parses an --extract value of the form FILE[:TARGET].
+// TARGET defaults to FILE.
+func parseExtract(value string) (extractConfig, error) {
+	filename, target, _ := strings.Cut(value, ":")
+	if filename == "" {
+		return extractConfig{}, fmt.Errorf("invalid extract: %q (must be FILE or FILE:TARGET)", value)
+	}
+	if target == "" {
+		target = filename
+	}
+	return extractConfig{filename: filename, target: target}, nil
+}
+
 // parseArgs parses command-line arguments into a config
 func parseArgs(args []string) (*config, error) {
 	cfg := &config{
-		files: make(map[string]fileConfig),
+		files:       make(map[string]fileConfig),
+		serviceDays: 7,
 	}
 
 	var positional []string
 	var currentFile string
+	var pendingEncoding *gtfs.Encoding
+	var pendingOverrides string
+	var pendingStripPrefixes []string
+	var pendingOnlyAgencies []string
 
 	for i := 0; i < len(args); i++ {
 		arg := args[i]
@@ -51,11 +116,61 @@ func parseArgs(args []string) (*config, error) {
 				cfg.showVersion = true
 			case arg == "--debug":
 				cfg.debug = true
+			case arg == "--json":
+				cfg.jsonSummary = true
+			case arg == "--provenance":
+				cfg.provenance = true
+			case arg == "--force":
+				cfg.force = true
+			case arg == "--failOnIdenticalInputs":
+				cfg.failOnIdentical = true
+			case arg == "--skip-invalid":
+				cfg.skipInvalid = true
+			case arg == "--sanitizeText":
+				cfg.sanitizeText = true
+			case arg == "--normalizeColors":
+				cfg.normalizeColors = true
+			case arg == "--stripNewlines":
+				cfg.stripNewlines = true
+			case arg == "--crlf":
+				cfg.crlf = true
+			case arg == "--quote-all":
+				cfg.quoteAll = true
+			case arg == "--checksum":
+				cfg.checksum = true
+			case arg == "--noServiceCheck":
+				cfg.noServiceCheck = true
+			case strings.HasPrefix(arg, "--serviceDays="):
+				value := strings.TrimPrefix(arg, "--serviceDays=")
+				days, err := strconv.Atoi(value)
+				if err != nil || days < 1 {
+					return nil, fmt.Errorf("invalid service days: %q (must be a positive integer)", value)
+				}
+				cfg.serviceDays = days
+			case strings.HasPrefix(arg, "--serviceGapDays="):
+				value := strings.TrimPrefix(arg, "--serviceGapDays=")
+				days, err := strconv.Atoi(value)
+				if err != nil || days < 1 {
+					return nil, fmt.Errorf("invalid service gap days: %q (must be a positive integer)", value)
+				}
+				cfg.serviceGapDays = days
+			case strings.HasPrefix(arg, "--readableTripIDs="):
+				cfg.readableTripIDs = strings.TrimPrefix(arg, "--readableTripIDs=")
+				if cfg.readableTripIDs == "" {
+					return nil, fmt.Errorf("--readableTripIDs requires a pattern")
+				}
+				if err := merge.ValidateTripIDPattern(cfg.readableTripIDs); err != nil {
+					return nil, err
+				}
+			case strings.HasPrefix(arg, "--profile="):
+				cfg.profile = strings.TrimPrefix(arg, "--profile=")
+				if _, err := merge.ParseProfile(cfg.profile); err != nil {
+					return nil, fmt.Errorf("%w (must be concatenate, same-agency-editions, or regional-integration)", err)
+				}
 			case strings.HasPrefix(arg, "--duplicateDetection="):
 				mode := strings.TrimPrefix(arg, "--duplicateDetection=")
-				// Validate mode
-				if mode != "none" && mode != "identity" && mode != "fuzzy" {
-					return nil, fmt.Errorf("invalid duplicate detection mode: %q (must be none, identity, or fuzzy)", mode)
+				if _, err := strategy.ParseDuplicateDetection(mode); err != nil {
+					return nil, fmt.Errorf("%w (must be none, identity, or fuzzy)", err)
 				}
 				// If we have a current file, apply to it only (per-file config)
 				// Otherwise set as global default
@@ -66,8 +181,100 @@ func parseArgs(args []string) (*config, error) {
 				} else {
 					cfg.duplicateDetection = mode
 				}
+			case strings.HasPrefix(arg, "--grayZone="):
+				value := strings.TrimPrefix(arg, "--grayZone=")
+				margin, err := strconv.ParseFloat(value, 64)
+				if err != nil || margin < 0 || margin >= 1 {
+					return nil, fmt.Errorf("invalid gray zone margin: %q (must be a number from 0 to 1)", value)
+				}
+				cfg.grayZone = margin
+			case strings.HasPrefix(arg, "--grayZonePolicy="):
+				cfg.grayZonePolicy = strings.TrimPrefix(arg, "--grayZonePolicy=")
+				if _, err := strategy.ParseGrayZonePolicy(cfg.grayZonePolicy); err != nil {
+					return nil, fmt.Errorf("%w (must be near_miss, flag, or abort)", err)
+				}
+			case strings.HasPrefix(arg, "--routeStopScoring="):
+				cfg.routeStopScoring = strings.TrimPrefix(arg, "--routeStopScoring=")
+				if _, err := strategy.ParseRouteStopScoring(cfg.routeStopScoring); err != nil {
+					return nil, fmt.Errorf("%w (must be overlap or terminals)", err)
+				}
+			case strings.HasPrefix(arg, "--blocked-duplicates="):
+				cfg.blockedDuplicates = strings.TrimPrefix(arg, "--blocked-duplicates=")
+			case strings.HasPrefix(arg, "--prune="):
+				for _, kind := range strings.Split(strings.TrimPrefix(arg, "--prune="), ",") {
+					if _, err := merge.ParsePruneKind(kind); err != nil {
+						return nil, err
+					}
+					cfg.prune = append(cfg.prune, kind)
+				}
+			case strings.HasPrefix(arg, "--frequencyOverlaps="):
+				cfg.frequencyOverlaps = strings.TrimPrefix(arg, "--frequencyOverlaps=")
+				if _, err := merge.ParseFrequencyOverlapPolicy(cfg.frequencyOverlaps); err != nil {
+					return nil, fmt.Errorf("%w (must be ignore, warn, keep-exact, or keep-frequencies)", err)
+				}
+			case strings.HasPrefix(arg, "--uniqueStopCodes="):
+				cfg.uniqueStopCodes = strings.TrimPrefix(arg, "--uniqueStopCodes=")
+				if _, err := merge.ParseStopCodePolicy(cfg.uniqueStopCodes); err != nil {
+					return nil, fmt.Errorf("%w (must be warn, prefix, or error)", err)
+				}
+			case strings.HasPrefix(arg, "--zip-store="):
+				for _, pattern := range strings.Split(strings.TrimPrefix(arg, "--zip-store="), ",") {
+					if _, err := path.Match(pattern, ""); pattern == "" || err != nil {
+						return nil, fmt.Errorf("invalid zip store pattern: %q", pattern)
+					}
+					cfg.zipStore = append(cfg.zipStore, pattern)
+				}
+			case strings.HasPrefix(arg, "--fingerprintCache="):
+				cfg.fingerprintCache = strings.TrimPrefix(arg, "--fingerprintCache=")
+				if cfg.fingerprintCache == "" {
+					return nil, fmt.Errorf("invalid fingerprint cache: empty path")
+				}
+			case strings.HasPrefix(arg, "--id-map="):
+				cfg.idMapDir = strings.TrimPrefix(arg, "--id-map=")
+				if cfg.idMapDir == "" {
+					return nil, fmt.Errorf("invalid id map directory: empty path")
+				}
 			case strings.HasPrefix(arg, "--logging="):
 				cfg.logging = strings.TrimPrefix(arg, "--logging=")
+				if _, err := strategy.ParseDuplicateLogging(cfg.logging); err != nil {
+					return nil, fmt.Errorf("%w (must be none, warning, or error)", err)
+				}
+			case strings.HasPrefix(arg, "--extract="):
+				ec, err := parseExtract(strings.TrimPrefix(arg, "--extract="))
+				if err != nil {
+					return nil, err
+				}
+				cfg.extracts = append(cfg.extracts, ec)
+			case strings.HasPrefix(arg, "--geojson="):
+				cfg.geojson = strings.TrimPrefix(arg, "--geojson=")
+				if cfg.geojson == "" {
+					return nil, fmt.Errorf("--geojson requires a path")
+				}
+			case strings.HasPrefix(arg, "--encoding="):
+				enc, err := gtfs.ParseEncoding(strings.TrimPrefix(arg, "--encoding="))
+				if err != nil {
+					return nil, fmt.Errorf("%w (must be utf-8, latin1, windows-1252, or auto)", err)
+				}
+				pendingEncoding = &enc
+			case strings.HasPrefix(arg, "--overrides="):
+				pendingOverrides = strings.TrimPrefix(arg, "--overrides=")
+				if pendingOverrides == "" {
+					return nil, fmt.Errorf("invalid overrides: empty path")
+				}
+			case strings.HasPrefix(arg, "--strip-id-prefix="):
+				for _, prefix := range strings.Split(strings.TrimPrefix(arg, "--strip-id-prefix="), ",") {
+					if prefix == "" {
+						return nil, fmt.Errorf("invalid strip-id-prefix: empty prefix")
+					}
+					pendingStripPrefixes = append(pendingStripPrefixes, prefix)
+				}
+			case strings.HasPrefix(arg, "--only-agencies="):
+				for _, id := range strings.Split(strings.TrimPrefix(arg, "--only-agencies="), ",") {
+					if id == "" {
+						return nil, fmt.Errorf("invalid only-agencies: empty agency ID")
+					}
+					pendingOnlyAgencies = append(pendingOnlyAgencies, id)
+				}
 			case strings.HasPrefix(arg, "--file="):
 				currentFile = strings.TrimPrefix(arg, "--file=")
 				cfg.files[currentFile] = fileConfig{}
@@ -75,7 +282,36 @@ func parseArgs(args []string) (*config, error) {
 				return nil, fmt.Errorf("unknown flag: %s", arg)
 			}
 		} else {
-			// Positional argument
+			// Positional argument; a preceding --encoding, --overrides,
+			// --strip-id-prefix or --only-agencies applies to it
+			if pendingEncoding != nil {
+				if cfg.encodings == nil {
+					cfg.encodings = make(map[int]gtfs.Encoding)
+				}
+				cfg.encodings[len(positional)] = *pendingEncoding
+				pendingEncoding = nil
+			}
+			if pendingOverrides != "" {
+				if cfg.overrides == nil {
+					cfg.overrides = make(map[int]string)
+				}
+				cfg.overrides[len(positional)] = pendingOverrides
+				pendingOverrides = ""
+			}
+			if pendingStripPrefixes != nil {
+				if cfg.stripIDPrefixes == nil {
+					cfg.stripIDPrefixes = make(map[int][]string)
+				}
+				cfg.stripIDPrefixes[len(positional)] = pendingStripPrefixes
+				pendingStripPrefixes = nil
+			}
+			if pendingOnlyAgencies != nil {
+				if cfg.onlyAgencies == nil {
+					cfg.onlyAgencies = make(map[int][]string)
+				}
+				cfg.onlyAgencies[len(positional)] = pendingOnlyAgencies
+				pendingOnlyAgencies = nil
+			}
 			positional = append(positional, arg)
 			// Reset current file when we hit positional args
 			currentFile = ""
@@ -87,6 +323,10 @@ func parseArgs(args []string) (*config, error) {
 		return cfg, nil
 	}
 
+	if cfg.grayZonePolicy != "" && cfg.grayZone == 0 {
+		return nil, fmt.Errorf("--grayZonePolicy requires --grayZone")
+	}
+
 	// Validate positional arguments
 	if len(positional) < 3 {
 		return nil, fmt.Errorf("at least 3 arguments required: <input1> <input2> [...] <output>")
@@ -96,60 +336,331 @@ func parseArgs(args []string) (*config, error) {
 	cfg.inputs = positional[:len(positional)-1]
 	cfg.output = positional[len(positional)-1]
 
+	if _, ok := cfg.encodings[len(cfg.inputs)]; ok || pendingEncoding != nil {
+		return nil, fmt.Errorf("--encoding must precede the input it applies to")
+	}
+	if _, ok := cfg.overrides[len(cfg.inputs)]; ok || pendingOverrides != "" {
+		return nil, fmt.Errorf("--overrides must precede the input it applies to")
+	}
+	if _, ok := cfg.stripIDPrefixes[len(cfg.inputs)]; ok || pendingStripPrefixes != nil {
+		return nil, fmt.Errorf("--strip-id-prefix must precede the input it applies to")
+	}
+	if _, ok := cfg.onlyAgencies[len(cfg.inputs)]; ok || pendingOnlyAgencies != nil {
+		return nil, fmt.Errorf("--only-agencies must precede the input it applies to")
+	}
+
 	return cfg, nil
 }
 
-// runMerge executes the merge operation based on config
-func runMerge(cfg *config) error {
+// runMerge executes the merge operation based on config, reporting to
+// metrics when it is not nil, and returns the merge report
+func runMerge(cfg *config, metrics merge.Metrics) (*merge.Report, error) {
 	// Build merger options
 	var opts []merge.Option
 
+	if metrics != nil {
+		opts = append(opts, merge.WithMetrics(metrics))
+	}
+
 	if cfg.debug {
-		opts = append(opts, merge.WithDebug(true))
+		opts = append(opts, merge.WithDebug(true), merge.WithStrictOutput(true), merge.WithAccountingCheck(true))
+	}
+
+	if cfg.force {
+		opts = append(opts, merge.WithOverwriteInput(true))
+	}
+
+	if cfg.profile != "" {
+		profile, _ := merge.ParseProfile(cfg.profile)
+		opts = append(opts, merge.WithProfile(profile))
 	}
 
 	if cfg.duplicateDetection != "" {
 		detection, err := strategy.ParseDuplicateDetection(cfg.duplicateDetection)
 		if err != nil {
-			return fmt.Errorf("invalid duplicate detection: %w", err)
+			return nil, fmt.Errorf("invalid duplicate detection: %w", err)
 		}
 		opts = append(opts, merge.WithDefaultDetection(detection))
 	}
 
 	if cfg.logging != "" {
-		var logging strategy.DuplicateLogging
-		switch cfg.logging {
-		case "none":
-			logging = strategy.LogNone
-		case "warning":
-			logging = strategy.LogWarning
-		case "error":
-			logging = strategy.LogError
-		default:
-			return fmt.Errorf("invalid logging mode: %q (must be none, warning, or error)", cfg.logging)
+		logging, err := strategy.ParseDuplicateLogging(cfg.logging)
+		if err != nil {
+			return nil, fmt.Errorf("%w (must be none, warning, or error)", err)
 		}
 		opts = append(opts, merge.WithDefaultLogging(logging))
 	}
 
-	// Create merger
-	m := merge.New(opts...)
+	if cfg.grayZone > 0 {
+		policy := strategy.GrayZoneNearMiss
+		if cfg.grayZonePolicy != "" {
+			policy, _ = strategy.ParseGrayZonePolicy(cfg.grayZonePolicy)
+		}
+		opts = append(opts, merge.WithGrayZone(cfg.grayZone, policy))
+	}
+
+	if cfg.routeStopScoring != "" {
+		scoring, _ := strategy.ParseRouteStopScoring(cfg.routeStopScoring)
+		opts = append(opts, merge.WithRouteStopScoring(scoring))
+	}
+
+	if cfg.blockedDuplicates != "" {
+		pairs, err := merge.LoadBlockedPairs(cfg.blockedDuplicates)
+		if err != nil {
+			return nil, fmt.Errorf("reading blocked duplicates: %w", err)
+		}
+		opts = append(opts, merge.WithBlockedDuplicates(pairs))
+	}
+
+	if cfg.frequencyOverlaps != "" {
+		policy, _ := merge.ParseFrequencyOverlapPolicy(cfg.frequencyOverlaps)
+		opts = append(opts, merge.WithFrequencyOverlaps(policy))
+	}
+
+	if cfg.uniqueStopCodes != "" {
+		policy, _ := merge.ParseStopCodePolicy(cfg.uniqueStopCodes)
+		opts = append(opts, merge.WithUniqueStopCodes(policy))
+	}
+
+	if len(cfg.prune) > 0 {
+		opts = append(opts, merge.WithPruneUnreferenced(cfg.prune...))
+	}
+
+	if cfg.failOnIdentical {
+		opts = append(opts, merge.WithFailOnIdenticalInputs(true))
+	}
+
+	if cfg.skipInvalid {
+		opts = append(opts, merge.WithSkipInvalidInputs(true))
+	}
+
+	if cfg.fingerprintCache != "" {
+		opts = append(opts, merge.WithFingerprintCache(cfg.fingerprintCache))
+	}
+
+	if cfg.sanitizeText {
+		opts = append(opts, merge.WithSanitizeText(true))
+	}
+
+	if cfg.normalizeColors {
+		opts = append(opts, merge.WithNormalizeColors(true))
+	}
+
+	writerOptions := gtfs.WriterOptions{StripNewlines: cfg.stripNewlines, UseCRLF: cfg.crlf, QuoteAll: cfg.quoteAll, Checksum: cfg.checksum}
+	for _, pattern := range cfg.zipStore {
+		writerOptions.Compression = append(writerOptions.Compression, gtfs.CompressionRule{Pattern: pattern, Method: zip.Store})
+	}
+	if cfg.stripNewlines || cfg.crlf || cfg.quoteAll || len(cfg.zipStore) > 0 || cfg.checksum {
+		opts = append(opts, merge.WithWriterOptions(writerOptions))
+	}
+
+	if cfg.serviceDays > 0 && !cfg.noServiceCheck {
+		opts = append(opts, merge.WithServiceCheck(time.Now(), cfg.serviceDays))
+	}
+	if cfg.serviceGapDays > 0 {
+		opts = append(opts, merge.WithServiceGapCheck(cfg.serviceGapDays))
+	}
+	if cfg.readableTripIDs != "" {
+		opts = append(opts, merge.WithReadableTripIDs(cfg.readableTripIDs))
+	}
+
+	for index, enc := range cfg.encodings {
+		opts = append(opts, merge.WithInputReaderOptions(index, gtfs.ReaderOptions{Encoding: enc}))
+	}
+
+	for index, path := range cfg.overrides {
+		opts = append(opts, merge.WithOverrides(index, path))
+	}
+
+	for index, prefixes := range cfg.stripIDPrefixes {
+		opts = append(opts, merge.WithIDNamespaceStrip(index, prefixes))
+	}
+
+	for index, ids := range cfg.onlyAgencies {
+		opts = append(opts, merge.WithAgencyFilter(index, ids))
+	}
 
 	// Apply per-file configurations
 	for filename, fc := range cfg.files {
 		if fc.detection != "" {
-			s := m.GetStrategyForFile(filename)
-			if s != nil {
-				detection, err := strategy.ParseDuplicateDetection(fc.detection)
-				if err != nil {
-					return fmt.Errorf("invalid detection for %s: %w", filename, err)
-				}
-				s.SetDuplicateDetection(detection)
+			detection, err := strategy.ParseDuplicateDetection(fc.detection)
+			if err != nil {
+				return nil, fmt.Errorf("invalid detection for %s: %w", filename, err)
 			}
+			opts = append(opts, merge.WithDetectionFor(filename, detection))
 		}
 	}
 
+	// Create merger
+	m := merge.New(opts...)
+
 	// Execute merge
-	return m.MergeFiles(cfg.inputs, cfg.output)
+	report, err := m.MergeFilesWithReport(context.Background(), cfg.inputs, cfg.output)
+	if err != nil {
+		if errors.Is(err, merge.ErrOutputIsInput) {
+			return nil, fmt.Errorf("%w (use --force to overwrite it)", err)
+		}
+		return nil, err
+	}
+
+	for _, pair := range report.UnusedBlockedPairs {
+		fmt.Fprintf(os.Stderr, "WARNING: blocked duplicate pair never matched: %s\n", pair)
+	}
+
+	if err := writeExtracts(cfg, report.Feed, writerOptions); err != nil {
+		return nil, err
+	}
+
+	// Without a merge there is nothing to describe; the provenance and ID
+	// maps written with the output still apply
+	if report.CacheHit {
+		return report, nil
+	}
+
+	if cfg.provenance {
+		if err := writeProvenance(report, provenancePath(cfg.output)); err != nil {
+			return nil, fmt.Errorf("writing provenance: %w", err)
+		}
+	}
+
+	if cfg.idMapDir != "" {
+		if err := writeIDMaps(report, cfg.idMapDir); err != nil {
+			return nil, fmt.Errorf("writing id maps: %w", err)
+		}
+	}
+
+	if cfg.geojson != "" {
+		if err := writeGeoJSON(report, cfg.geojson); err != nil {
+			return nil, fmt.Errorf("writing GeoJSON: %w", err)
+		}
+	}
+
+	return report, nil
+}
+
+// writeIDMaps writes, for each input feed and entity kind, the feed's map
+// from original to merged IDs as CSV to dir/FEED.KIND.csv, creating dir if
+// needed
+func writeIDMaps(report *merge.Report, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for i := range report.Feeds {
+		feed := &report.Feeds[i]
+		for _, kind := range gtfs.EntityKinds {
+			if err := writeIDMap(feed, kind, filepath.Join(dir, feed.Name+"."+string(kind)+".csv")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeIDMap writes the feed's ID map for kind as CSV to path
+func writeIDMap(feed *merge.FeedReport, kind gtfs.EntityKind, path string) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	return feed.WriteIDMapCSV(f, kind)
+}
+
+// provenancePath returns the sidecar provenance CSV path for an output zip:
+// merged.zip → merged.provenance.csv in the same directory
+func provenancePath(output string) string {
+	return strings.TrimSuffix(output, filepath.Ext(output)) + ".provenance.csv"
+}
+
+// writeProvenance writes the report's entity provenance as CSV to path
+func writeProvenance(report *merge.Report, path string) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	return report.WriteProvenanceCSV(f)
+}
+
+// writeExtracts writes each requested table of the merged feed to its own
+// file with the output's writer options. Relative targets are placed
+// alongside the output zip. Without a merged feed, as on a cache hit, the
+// output is read back.
+func writeExtracts(cfg *config, merged *gtfs.Feed, options gtfs.WriterOptions) error {
+	if len(cfg.extracts) == 0 {
+		return nil
+	}
+
+	if merged == nil {
+		var err error
+		if merged, err = gtfs.ReadFromPath(cfg.output); err != nil {
+			return fmt.Errorf("reading merged output: %w", err)
+		}
+	}
+
+	for _, ec := range cfg.extracts {
+		target := ec.target
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(cfg.output), target)
+		}
+		if err := writeExtract(merged, ec.filename, target, options); err != nil {
+			return fmt.Errorf("extracting %s: %w", ec.filename, err)
+		}
+	}
+
+	return nil
+}
+
+// writeExtract writes a single table to path, gzipping when path ends in .gz
+func writeExtract(feed *gtfs.Feed, filename, path string, options gtfs.WriterOptions) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	if !strings.HasSuffix(path, ".gz") {
+		return gtfs.WriteFileWithOptions(feed, filename, f, options)
+	}
+
+	zw := gzip.NewWriter(f)
+	if err := gtfs.WriteFileWithOptions(feed, filename, zw, options); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// writeGeoJSON writes the merged feed to path as GeoJSON, each stop and
+// route listing the prefixes of the inputs it came from
+func writeGeoJSON(report *merge.Report, path string) (err error) {
+	merged := report.Feed
+	prefixes := make([]string, len(report.Feeds))
+	for i, fr := range report.Feeds {
+		prefixes[i] = fr.Prefix
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	return gtfs.WriteGeoJSON(merged, f, gtfs.GeoJSONOptions{SourcePrefixes: prefixes})
 }
 
 // printUsage prints the usage information
@@ -158,6 +669,9 @@ func printUsage() {
 
 Usage:
   gtfs-merge [options] <input1> <input2> [...] <output>
+  gtfs-merge diff [options] <a> <b>
+  gtfs-merge extract --prefix=PREFIX <merged> <output>
+  gtfs-merge validate [options] <feed> [...]
 
 Arguments:
   input1, input2, ...  Input GTFS feeds (zip files or directories)
@@ -165,8 +679,18 @@ Arguments:
 
 Options:
   --help, -h           Show this help message
-  --version, -v        Show version information
-  --debug              Enable debug output
+  --version, -v        Show version and build information (with --json, as JSON)
+  --debug              Enable debug output, and fail if the merged feed has
+                       broken references or rows that do not balance with
+                       those read, deduplicated and dropped
+  --json               Print the end-of-run summary as JSON (stable,
+                       machine-readable) instead of a table
+  --profile=PROFILE    Preset detection modes: concatenate (none, and
+                       every ID prefixed), same-agency-editions (identity), or
+                       regional-integration (fuzzy agencies, stops and
+                       routes, identity services, none otherwise); short
+                       names editions and regional; --duplicateDetection
+                       and --file override it
   --duplicateDetection=MODE
                        Duplicate detection mode: none, identity, fuzzy
                        (default: none)
@@ -174,19 +698,152 @@ Options:
                        (default: none)
   --file=FILENAME      Apply following options to specific GTFS file
                        (e.g., --file=stops.txt --duplicateDetection=fuzzy)
+  --grayZone=MARGIN    Treat fuzzy stop, route and trip matches scoring
+                       within MARGIN of the threshold as ambiguous
+  --grayZonePolicy=POLICY
+                       How ambiguous matches are resolved: near_miss
+                       (keep apart), flag (merge), or abort (fail the
+                       merge listing them) (default: near_miss)
+  --routeStopScoring=MODE
+                       How fuzzy route matching scores shared stops:
+                       overlap (every stop counts equally) or terminals
+                       (also require both ends of a trip to be shared)
+                       (default: overlap)
+  --blocked-duplicates=PATH
+                       CSV of entity pairs never to merge as duplicates,
+                       with columns file, feed_a, id_a, feed_b, id_b;
+                       feeds are named by input file basename
+  --prune=KINDS        After merging, delete stops, shapes, services,
+                       agencies or areas (comma-separated) that nothing
+                       references; parent stations of kept stops are kept
+  --uniqueStopCodes=POLICY
+                       Check that merged stops have distinct stop_codes:
+                       warn (list collisions), prefix (prefix colliding
+                       codes with their feed's prefix, listing any still
+                       shared), or error
+  --frequencyOverlaps=POLICY
+                       Look for frequency-based trips running the same
+                       service as exact-time trips (same route, service
+                       and stops, starting within the frequency window at
+                       about the headway): warn (keep both), keep-exact
+                       or keep-frequencies
+  --force              Allow the output to overwrite one of the inputs
+  --failOnIdenticalInputs
+                       Fail when two inputs are byte-for-byte identical
+                       (by default the later copy is skipped)
+  --skip-invalid       Skip, with a warning, inputs that are missing or
+                       fail to read, merging the rest; at least two
+                       inputs must be valid
+  --fingerprintCache=PATH
+                       Record the inputs' and options' hashes in PATH, and
+                       skip the merge when a later run finds them, and
+                       the output, unchanged. Files written by
+                       --provenance and --id-map are left as they are
+  --sanitizeText       Clean the inputs' names, descriptions and other text
+                       before merging: control characters become spaces,
+                       invalid UTF-8 becomes U+FFFD, and names are trimmed
+  --normalizeColors    Write route_color and route_text_color as uppercase
+                       hex without a leading '#' (e.g. #ff0000 as FF0000)
+  --stripNewlines      Write line breaks inside values (e.g. a multi-line
+                       stop_desc) as spaces, so every record is one line
+  --crlf               End output lines with CRLF rather than LF
+  --quote-all          Quote every output field, not only those that
+                       need it
+  --zip-store=PATTERNS Write the output files matching PATTERNS
+                       (comma-separated, e.g. stop_times.txt,shapes.txt or
+                       *.txt) uncompressed, for consumers that mmap them;
+                       other files are deflated
+  --checksum           Also write OUTPUT.sha256, the output's SHA-256 in
+                       the format sha256sum -c reads
+  --serviceDays=N      Warn when the merged feed has no active service on
+                       any of the N days from today, or its service ends
+                       within them (default: 7)
+  --noServiceCheck     Skip the check of upcoming service
+  --serviceGapDays=N   Warn about each route of the merged feed with no
+                       trips for more than N consecutive days between days
+                       it has some, such as between one edition of a feed
+                       ending and the next starting (off by default)
+  --readableTripIDs=PATTERN
+                       Rename the merged trips after PATTERN, e.g.
+                       {route_short}-{direction}-{start_time} for
+                       10-0-0815, from the fields route_short, route_id,
+                       direction, start_time, headsign, service_id,
+                       trip_id and seq; repeated IDs take a -2, -3...
+                       suffix. --id-map and --provenance use the new IDs
+  --provenance         Also write OUTPUT.provenance.csv listing the input
+                       feed(s) each merged agency, stop, route, trip,
+                       service, shape, fare and area came from
+  --id-map=DIR         Also write DIR/FEED.KIND.csv for each input FEED and
+                       entity KIND (agency, stop, route, trip, service,
+                       shape, fare, area, network), mapping original IDs
+                       to merged IDs (duplicates to the entity kept)
+  --encoding=ENC       Character encoding of the next input: utf-8
+                       (default), latin1, windows-1252, or auto to detect
+                       per file. Output is always UTF-8
+  --overrides=PATH     CSV of corrections to the next input, with columns
+                       file, id, column and value (e.g.
+                       stops.txt,s1,stop_name,Main St), applied before
+                       merging; unknown IDs and columns are warned about
+  --strip-id-prefix=P[,P...]
+                       Namespace prefix(es) of the next input's IDs to
+                       ignore when matching duplicates by identity, so
+                       KCM:1000 matches 1000 elsewhere; the ID kept is
+                       written unchanged. May be repeated
+  --only-agencies=ID[,ID...]
+                       Merge only these agencies of the next input,
+                       leaving out the other agencies' routes, trips and
+                       the services, shapes, stops and fares only they use
+  --extract=FILE[:TARGET]
+                       Also write FILE of the merged feed to TARGET
+                       (default: FILE) next to the output; gzipped when
+                       TARGET ends in .gz. May be repeated
+  --geojson=PATH       Also write the merged feed's stops and routes to PATH
+                       as GeoJSON, for a look at it on a map; each feature
+                       lists the ID prefixes of the inputs it came from
+                       (empty for the last input, which is not prefixed)
+
+After merging, a table lists for each GTFS file the rows read from each
+input, the duplicates merged away (when duplicate detection is enabled)
+and the rows written. The table is for people; scripts should use --json,
+which also lists the time spent reading, merging each file and writing.
+
+Exit status is 0 when every input is merged, 3 when inputs were skipped
+by --skip-invalid, and 1 on error.
 
 Examples:
   gtfs-merge feed1.zip feed2.zip merged.zip
   gtfs-merge --duplicateDetection=identity feed1.zip feed2.zip merged.zip
-  gtfs-merge --file=stops.txt --duplicateDetection=fuzzy feed1.zip feed2.zip merged.zip`)
+  gtfs-merge --file=stops.txt --duplicateDetection=fuzzy feed1.zip feed2.zip merged.zip
+  gtfs-merge feed1.zip --encoding=windows-1252 legacy.zip merged.zip
+  gtfs-merge --extract=stop_times.txt:stop_times.csv.gz feed1.zip feed2.zip merged.zip
+  gtfs-merge --geojson=merged.geojson feed1.zip feed2.zip merged.zip
+  gtfs-merge diff old.zip new.zip
+  gtfs-merge validate feed1.zip feed2.zip
+
+Run "gtfs-merge diff --help", "gtfs-merge extract --help" or
+"gtfs-merge validate --help" for their options.`)
 }
 
-// printVersion prints version information
-func printVersion() {
-	fmt.Printf("gtfs-merge version %s\n", Version)
+// printVersion prints version and build information, as JSON when asJSON
+func printVersion(asJSON bool) error {
+	info := currentVersionInfo()
+	if asJSON {
+		return writeVersionJSON(os.Stdout, info)
+	}
+	return writeVersion(os.Stdout, info)
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		os.Exit(diffMain(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "extract" {
+		os.Exit(extractMain(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(validateMain(os.Args[2:]))
+	}
+
 	cfg, err := parseArgs(os.Args[1:])
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -200,14 +857,126 @@ func main() {
 	}
 
 	if cfg.showVersion {
-		printVersion()
+		if err := printVersion(cfg.jsonSummary); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 		os.Exit(0)
 	}
 
-	if err := runMerge(cfg); err != nil {
+	metrics := merge.NewMemoryMetrics()
+	report, err := runMerge(cfg, metrics)
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("Successfully merged %d feeds into %s\n", len(cfg.inputs), cfg.output)
+	summary := buildSummary(report, metrics)
+	if cfg.jsonSummary {
+		if err := writeSummaryJSON(os.Stdout, summary); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(mergeExitStatus(report))
+	}
+
+	if report.CacheHit {
+		fmt.Printf("Inputs and options unchanged since %s was written; merge skipped (fingerprint cache hit)\n", cfg.output)
+		os.Exit(mergeExitStatus(report))
+	}
+
+	_ = writeSummaryTable(os.Stdout, summary, detectionEnabled(cfg))
+	if n := len(report.InvalidInputs); n > 0 {
+		fmt.Printf("Merged %d feeds into %s, skipping %d invalid inputs\n", len(report.Feeds), cfg.output, n)
+	} else {
+		fmt.Printf("Successfully merged %d feeds into %s\n", len(report.Feeds), cfg.output)
+	}
+	os.Exit(mergeExitStatus(report))
+}
+
+// exitSkippedInvalid is the exit status of a merge that succeeded without
+// the inputs --skip-invalid skipped
+const exitSkippedInvalid = 3
+
+// mergeExitStatus returns the exit status of a successful merge
+func mergeExitStatus(report *merge.Report) int {
+	if len(report.InvalidInputs) > 0 {
+		return exitSkippedInvalid
+	}
+	return 0
+}
+
+// diffMain runs the diff subcommand and returns the process exit status
+func diffMain(args []string) int {
+	cfg, err := parseDiffArgs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Use gtfs-merge diff --help for usage information")
+		return 2
+	}
+
+	if cfg.showHelp {
+		printDiffUsage()
+		return 0
+	}
+
+	total, err := runDiff(cfg, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if total > cfg.threshold {
+		return 1
+	}
+	return 0
+}
+
+// extractMain runs the extract subcommand and returns the process exit status
+func extractMain(args []string) int {
+	cfg, err := parseExtractArgs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Use gtfs-merge extract --help for usage information")
+		return 1
+	}
+
+	if cfg.showHelp {
+		printExtractUsage()
+		return 0
+	}
+
+	feed, err := runExtract(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Extracted %d trips, %d stops and %d routes into %s\n", len(feed.Trips), len(feed.Stops), len(feed.Routes), cfg.output)
+	return 0
+}
+
+// validateMain runs the validate subcommand and returns the process exit
+// status
+func validateMain(args []string) int {
+	cfg, err := parseValidateArgs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Use gtfs-merge validate --help for usage information")
+		return 2
+	}
+
+	if cfg.showHelp {
+		printValidateUsage()
+		return 0
+	}
+
+	failed, err := runValidate(cfg, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
 }
@@ -1,10 +1,20 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/merge"
 )
 
 // ============================================================================
@@ -255,6 +265,16 @@ func TestParseArgsInvalid(t *testing.T) {
 			args:      []string{"--duplicateDetection=invalid", "feed1.zip", "feed2.zip", "output.zip"},
 			expectErr: "invalid duplicate detection mode",
 		},
+		{
+			name:      "invalid logging mode",
+			args:      []string{"--logging=verbose", "feed1.zip", "feed2.zip", "output.zip"},
+			expectErr: "invalid duplicate logging mode",
+		},
+		{
+			name:      "invalid route stop scoring",
+			args:      []string{"--routeStopScoring=weighted", "feed1.zip", "feed2.zip", "output.zip"},
+			expectErr: "invalid route stop scoring",
+		},
 	}
 
 	for _, tt := range tests {
@@ -296,7 +316,7 @@ func TestCLIMergeTwoFeeds(t *testing.T) {
 		output: output,
 	}
 
-	err := runMerge(cfg)
+	_, err := runMerge(cfg, nil)
 	if err != nil {
 		t.Fatalf("runMerge failed: %v", err)
 	}
@@ -333,7 +353,7 @@ func TestCLIWithDuplicateDetection(t *testing.T) {
 				duplicateDetection: mode,
 			}
 
-			err := runMerge(cfg)
+			_, err := runMerge(cfg, nil)
 			if err != nil {
 				t.Fatalf("runMerge with %s detection failed: %v", mode, err)
 			}
@@ -368,7 +388,7 @@ func TestCLIWithPerFileConfig(t *testing.T) {
 		files:              map[string]fileConfig{"stops.txt": {detection: "fuzzy"}},
 	}
 
-	err := runMerge(cfg)
+	_, err := runMerge(cfg, nil)
 	if err != nil {
 		t.Fatalf("runMerge with per-file config failed: %v", err)
 	}
@@ -400,7 +420,7 @@ func TestCLIDebugOutput(t *testing.T) {
 		debug:  true,
 	}
 
-	err := runMerge(cfg)
+	_, err := runMerge(cfg, nil)
 	if err != nil {
 		t.Fatalf("runMerge with debug failed: %v", err)
 	}
@@ -420,7 +440,7 @@ func TestCLIErrorOnInvalidInput(t *testing.T) {
 		output: output,
 	}
 
-	err := runMerge(cfg)
+	_, err := runMerge(cfg, nil)
 	if err == nil {
 		t.Error("expected error for invalid input, got nil")
 	}
@@ -447,7 +467,7 @@ func TestCLIOutputFileSize(t *testing.T) {
 		output: output,
 	}
 
-	err := runMerge(cfg)
+	_, err := runMerge(cfg, nil)
 	if err != nil {
 		t.Fatalf("runMerge failed: %v", err)
 	}
@@ -488,7 +508,7 @@ func TestCLIMergeThreeFeeds(t *testing.T) {
 		output: output,
 	}
 
-	err := runMerge(cfg)
+	_, err := runMerge(cfg, nil)
 	if err != nil {
 		t.Fatalf("runMerge with three feeds failed: %v", err)
 	}
@@ -498,3 +518,678 @@ func TestCLIMergeThreeFeeds(t *testing.T) {
 		t.Error("output file was not created")
 	}
 }
+
+func TestParseArgsExtract(t *testing.T) {
+	args := []string{"--extract=stop_times.txt:st.csv.gz", "--extract=stops.txt", "a.zip", "b.zip", "out.zip"}
+
+	cfg, err := parseArgs(args)
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+
+	expected := []extractConfig{
+		{filename: "stop_times.txt", target: "st.csv.gz"},
+		{filename: "stops.txt", target: "stops.txt"},
+	}
+	if len(cfg.extracts) != len(expected) {
+		t.Fatalf("expected %d extracts, got %d", len(expected), len(cfg.extracts))
+	}
+	for i, want := range expected {
+		if cfg.extracts[i] != want {
+			t.Errorf("extract %d: expected %+v, got %+v", i, want, cfg.extracts[i])
+		}
+	}
+
+	if _, err := parseArgs([]string{"--extract=:out.csv", "a.zip", "b.zip", "out.zip"}); err == nil {
+		t.Error("expected error for extract without a file name")
+	}
+}
+
+func TestCLIExtract(t *testing.T) {
+	tmpDir := t.TempDir()
+	output := filepath.Join(tmpDir, "merged.zip")
+
+	cfg := &config{
+		inputs: []string{"../../testdata/simple_a", "../../testdata/simple_b"},
+		output: output,
+		extracts: []extractConfig{
+			{filename: "stop_times.txt", target: "stop_times.csv.gz"},
+			{filename: "stops.txt", target: "stops.csv"},
+		},
+	}
+
+	if _, err := runMerge(cfg, nil); err != nil {
+		t.Fatalf("runMerge failed: %v", err)
+	}
+
+	// Gzipped extract is written next to the output zip
+	f, err := os.Open(filepath.Join(tmpDir, "stop_times.csv.gz"))
+	if err != nil {
+		t.Fatalf("expected gzipped extract: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("extract is not gzipped: %v", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("failed to read extract: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "trip_id,stop_id,arrival_time,departure_time,stop_sequence") {
+		t.Errorf("unexpected stop_times header: %q", strings.SplitN(string(data), "\n", 2)[0])
+	}
+
+	// Plain extract is written uncompressed
+	stops, err := os.ReadFile(filepath.Join(tmpDir, "stops.csv"))
+	if err != nil {
+		t.Fatalf("expected plain extract: %v", err)
+	}
+	if !strings.HasPrefix(string(stops), "stop_id,") {
+		t.Errorf("unexpected stops header: %q", strings.SplitN(string(stops), "\n", 2)[0])
+	}
+}
+
+func TestWriteExtractsFromMergedFeed(t *testing.T) {
+	// Given: a merged feed and an output path nothing was written to
+	tmpDir := t.TempDir()
+	merged, err := gtfs.ReadFromPath("../../testdata/simple_a")
+	if err != nil {
+		t.Fatalf("failed to read simple_a: %v", err)
+	}
+	cfg := &config{
+		output:   filepath.Join(tmpDir, "merged.zip"),
+		extracts: []extractConfig{{filename: "stops.txt", target: "stops.csv"}},
+	}
+
+	// When: the extracts are written with CRLF line endings
+	if err := writeExtracts(cfg, merged, gtfs.WriterOptions{UseCRLF: true}); err != nil {
+		t.Fatalf("writeExtracts failed: %v", err)
+	}
+
+	// Then: they come from the feed in memory, with the writer options
+	stops, err := os.ReadFile(filepath.Join(tmpDir, "stops.csv"))
+	if err != nil {
+		t.Fatalf("expected plain extract: %v", err)
+	}
+	if !strings.HasPrefix(string(stops), "stop_id,") || !strings.Contains(string(stops), "\r\n") {
+		t.Errorf("unexpected stops extract: %q", stops)
+	}
+}
+
+func TestCLIGeoJSON(t *testing.T) {
+	// Given: two feeds
+	tmpDir := t.TempDir()
+	geojson := filepath.Join(tmpDir, "merged.geojson")
+	cfg, err := parseArgs([]string{
+		"--geojson=" + geojson,
+		"../../testdata/simple_a", "../../testdata/simple_b", filepath.Join(tmpDir, "merged.zip"),
+	})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if cfg.geojson != geojson {
+		t.Fatalf("Expected geojson %q, got %q", geojson, cfg.geojson)
+	}
+
+	// When: merged
+	if _, err := runMerge(cfg, nil); err != nil {
+		t.Fatalf("runMerge failed: %v", err)
+	}
+
+	// Then: the GeoJSON holds the merged stops and routes, each listing
+	// the prefix of the input it came from, empty for the last input
+	data, err := os.ReadFile(geojson)
+	if err != nil {
+		t.Fatalf("expected GeoJSON output: %v", err)
+	}
+	var collection struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Geometry struct {
+				Type string `json:"type"`
+			} `json:"geometry"`
+			Properties struct {
+				ID     string   `json:"id"`
+				Color  string   `json:"color"`
+				Source []string `json:"source"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(data, &collection); err != nil {
+		t.Fatalf("invalid GeoJSON: %v", err)
+	}
+	counts := make(map[string]int)
+	for _, f := range collection.Features {
+		counts[f.Geometry.Type]++
+		want := "a-"
+		if strings.Contains(f.Properties.ID, "_b") {
+			want = ""
+		}
+		if !slices.Equal(f.Properties.Source, []string{want}) {
+			t.Errorf("%s %s: expected source [%q], got %q", f.Geometry.Type, f.Properties.ID, want, f.Properties.Source)
+		}
+		if f.Properties.ID == "route_a1" && f.Properties.Color != "#FF0000" {
+			t.Errorf("Expected route_a1 color #FF0000, got %q", f.Properties.Color)
+		}
+	}
+	if collection.Type != "FeatureCollection" || counts["Point"] != 8 || counts["LineString"] == 0 {
+		t.Errorf("Expected a FeatureCollection of 8 stops and route lines, got %s with %v", collection.Type, counts)
+	}
+}
+
+func TestCLIExtractUnknownFile(t *testing.T) {
+	cfg := &config{
+		inputs:   []string{"../../testdata/simple_a", "../../testdata/simple_b"},
+		output:   filepath.Join(t.TempDir(), "merged.zip"),
+		extracts: []extractConfig{{filename: "bogus.txt", target: "bogus.csv"}},
+	}
+
+	_, err := runMerge(cfg, nil)
+	if !errors.Is(err, gtfs.ErrUnknownFile) {
+		t.Errorf("expected ErrUnknownFile, got %v", err)
+	}
+}
+
+func TestParseArgsEncoding(t *testing.T) {
+	cfg, err := parseArgs([]string{"a.zip", "--encoding=windows-1252", "b.zip", "out.zip"})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if len(cfg.encodings) != 1 || cfg.encodings[1] != gtfs.EncodingWindows1252 {
+		t.Errorf("expected windows-1252 for input 1, got %v", cfg.encodings)
+	}
+
+	if _, err := parseArgs([]string{"a.zip", "b.zip", "--encoding=latin1", "out.zip"}); err == nil {
+		t.Error("expected error for --encoding before the output")
+	}
+	if _, err := parseArgs([]string{"--encoding=ebcdic", "a.zip", "b.zip", "out.zip"}); err == nil {
+		t.Error("expected error for unknown encoding")
+	}
+}
+
+func TestParseArgsOverrides(t *testing.T) {
+	cfg, err := parseArgs([]string{"a.zip", "--overrides=fixes.csv", "b.zip", "out.zip"})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if len(cfg.overrides) != 1 || cfg.overrides[1] != "fixes.csv" {
+		t.Errorf("expected fixes.csv for input 1, got %v", cfg.overrides)
+	}
+
+	if _, err := parseArgs([]string{"a.zip", "b.zip", "--overrides=fixes.csv", "out.zip"}); err == nil {
+		t.Error("expected error for --overrides before the output")
+	}
+	if _, err := parseArgs([]string{"--overrides=", "a.zip", "b.zip", "out.zip"}); err == nil {
+		t.Error("expected error for an empty overrides path")
+	}
+}
+
+func TestParseArgsStripIDPrefix(t *testing.T) {
+	cfg, err := parseArgs([]string{"--strip-id-prefix=KCM:,ST:", "--strip-id-prefix=CT:", "a.zip", "b.zip", "out.zip"})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if !slices.Equal(cfg.stripIDPrefixes[0], []string{"KCM:", "ST:", "CT:"}) || len(cfg.stripIDPrefixes) != 1 {
+		t.Errorf("expected KCM:, ST: and CT: for input 0, got %v", cfg.stripIDPrefixes)
+	}
+
+	if _, err := parseArgs([]string{"a.zip", "b.zip", "--strip-id-prefix=KCM:", "out.zip"}); err == nil {
+		t.Error("expected error for --strip-id-prefix before the output")
+	}
+	if _, err := parseArgs([]string{"--strip-id-prefix=KCM:,", "a.zip", "b.zip", "out.zip"}); err == nil {
+		t.Error("expected error for an empty prefix")
+	}
+}
+
+func TestParseArgsOnlyAgencies(t *testing.T) {
+	cfg, err := parseArgs([]string{"a.zip", "--only-agencies=3,17,42", "b.zip", "out.zip"})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if !slices.Equal(cfg.onlyAgencies[1], []string{"3", "17", "42"}) || len(cfg.onlyAgencies) != 1 {
+		t.Errorf("expected 3, 17 and 42 for input 1, got %v", cfg.onlyAgencies)
+	}
+
+	if _, err := parseArgs([]string{"a.zip", "b.zip", "--only-agencies=3", "out.zip"}); err == nil {
+		t.Error("expected error for --only-agencies before the output")
+	}
+	if _, err := parseArgs([]string{"--only-agencies=", "a.zip", "b.zip", "out.zip"}); err == nil {
+		t.Error("expected error for an empty agency ID")
+	}
+}
+
+func TestCLIMergeLegacyEncoding(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "merged.zip")
+	cfg := &config{
+		inputs:    []string{"../../testdata/encoding_utf8", "../../testdata/encoding_windows1252"},
+		output:    output,
+		encodings: map[int]gtfs.Encoding{1: gtfs.EncodingWindows1252},
+	}
+	if _, err := runMerge(cfg, nil); err != nil {
+		t.Fatalf("runMerge failed: %v", err)
+	}
+
+	merged, err := gtfs.ReadFromPath(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	// Both copies of each stop carry the same UTF-8 name
+	expected := map[string]bool{"Café Central": true, "Estación Norte – Andén 1": true, "Müller Straße": true}
+	for id, stop := range merged.Stops {
+		if !expected[stop.Name] {
+			t.Errorf("stop %s: expected accented UTF-8 name, got %q", id, stop.Name)
+		}
+	}
+}
+
+func TestCLIProvenance(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config{
+		inputs:     []string{"../../testdata/simple_a", "../../testdata/simple_b"},
+		output:     filepath.Join(tmpDir, "merged.zip"),
+		provenance: true,
+	}
+	if _, err := runMerge(cfg, nil); err != nil {
+		t.Fatalf("runMerge failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, "merged.provenance.csv"))
+	if err != nil {
+		t.Fatalf("expected provenance sidecar: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "entity_kind,entity_id,feed_index,feed_name\n") {
+		t.Errorf("unexpected provenance header: %q", strings.SplitN(string(data), "\n", 2)[0])
+	}
+	if !strings.Contains(string(data), ",simple_b\n") {
+		t.Error("expected provenance rows naming simple_b")
+	}
+}
+
+func TestCLIIDMap(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg, err := parseArgs([]string{"--id-map=" + filepath.Join(tmpDir, "ids"),
+		"../../testdata/simple_a", "../../testdata/overlap", filepath.Join(tmpDir, "merged.zip")})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if _, err := runMerge(cfg, nil); err != nil {
+		t.Fatalf("runMerge failed: %v", err)
+	}
+
+	// simple_a's IDs that collide with overlap's are prefixed
+	data, err := os.ReadFile(filepath.Join(tmpDir, "ids", "simple_a.stop.csv"))
+	if err != nil {
+		t.Fatalf("expected stop ID map: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "original_id,merged_id\nstop_a1,a-stop_a1\n") {
+		t.Errorf("unexpected stop ID map: %q", data)
+	}
+	for _, name := range []string{"overlap.trip.csv", "overlap.network.csv"} {
+		if _, err := os.Stat(filepath.Join(tmpDir, "ids", name)); err != nil {
+			t.Errorf("expected %s: %v", name, err)
+		}
+	}
+
+	if _, err := parseArgs([]string{"--id-map=", "a.zip", "b.zip", "out.zip"}); err == nil {
+		t.Error("expected error for an empty id map directory")
+	}
+}
+
+func TestCLIReadableTripIDs(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg, err := parseArgs([]string{"--readableTripIDs={route_short}-{seq}", "--id-map=" + filepath.Join(tmpDir, "ids"),
+		"../../testdata/simple_a", "../../testdata/overlap", filepath.Join(tmpDir, "merged.zip")})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if _, err := runMerge(cfg, nil); err != nil {
+		t.Fatalf("runMerge failed: %v", err)
+	}
+
+	// Every merged trip is renamed, and the ID maps lead to the new IDs
+	merged, err := gtfs.ReadFromPath(filepath.Join(tmpDir, "merged.zip"))
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(tmpDir, "ids", "simple_a.trip.csv"))
+	if err != nil {
+		t.Fatalf("expected trip ID map: %v", err)
+	}
+	rows := strings.Split(strings.TrimSpace(string(data)), "\n")[1:]
+	if len(rows) == 0 {
+		t.Fatal("expected trip ID map rows")
+	}
+	for _, row := range rows {
+		_, mergedID, _ := strings.Cut(row, ",")
+		if merged.Trips[gtfs.TripID(mergedID)] == nil || !strings.Contains(mergedID, "-") {
+			t.Errorf("expected %q to map to a renamed merged trip", row)
+		}
+	}
+
+	for _, pattern := range []string{"", "{route}", "{seq"} {
+		if _, err := parseArgs([]string{"--readableTripIDs=" + pattern, "a.zip", "b.zip", "out.zip"}); err == nil {
+			t.Errorf("expected error for trip ID pattern %q", pattern)
+		}
+	}
+}
+
+func TestCLIFingerprintCache(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg, err := parseArgs([]string{"--fingerprintCache=" + filepath.Join(tmpDir, "cache.json"), "--provenance",
+		"../../testdata/simple_a", "../../testdata/overlap", filepath.Join(tmpDir, "merged.zip")})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+
+	// The first run merges, the second finds nothing changed
+	for i, wantHit := range []bool{false, true} {
+		report, err := runMerge(cfg, nil)
+		if err != nil {
+			t.Fatalf("run %d: runMerge failed: %v", i+1, err)
+		}
+		if report.CacheHit != wantHit {
+			t.Errorf("run %d: expected CacheHit=%t", i+1, wantHit)
+		}
+		if summary := buildSummary(report, nil); summary.CacheHit != wantHit {
+			t.Errorf("run %d: expected summary cache_hit=%t", i+1, wantHit)
+		}
+	}
+
+	// The provenance written by the merge is kept
+	data, err := os.ReadFile(provenancePath(cfg.output))
+	if err != nil || !strings.Contains(string(data), "stop_a1") {
+		t.Errorf("expected the first run's provenance, got %q, %v", data, err)
+	}
+
+	if _, err := parseArgs([]string{"--fingerprintCache=", "a.zip", "b.zip", "out.zip"}); err == nil {
+		t.Error("expected error for an empty fingerprint cache path")
+	}
+}
+
+func TestCLIRefusesToOverwriteInput(t *testing.T) {
+	// Given: the output path names one of the inputs
+	tmpDir := t.TempDir()
+	feed, err := gtfs.ReadFromPath("../../testdata/simple_a")
+	if err != nil {
+		t.Fatalf("failed to read simple_a: %v", err)
+	}
+	input := filepath.Join(tmpDir, "feed_a.zip")
+	if err := gtfs.WriteToPath(feed, input); err != nil {
+		t.Fatalf("failed to write feed_a.zip: %v", err)
+	}
+
+	// When: merged without --force
+	cfg, err := parseArgs([]string{input, "../../testdata/simple_b", input})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	_, err = runMerge(cfg, nil)
+
+	// Then: the merge is refused with a hint
+	if err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Fatalf("expected refusal mentioning --force, got %v", err)
+	}
+
+	// When: merged with --force
+	cfg, err = parseArgs([]string{"--force", input, "../../testdata/simple_b", input})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if !cfg.force {
+		t.Fatal("expected --force to be parsed")
+	}
+
+	// Then: the merge succeeds
+	if _, err := runMerge(cfg, nil); err != nil {
+		t.Fatalf("runMerge failed: %v", err)
+	}
+}
+
+func TestCLIBlockedDuplicates(t *testing.T) {
+	// Given: a blocklist for a stop ID both inputs share
+	tmpDir := t.TempDir()
+	blocklist := filepath.Join(tmpDir, "blocked.csv")
+	content := "file,feed_a,id_a,feed_b,id_b\nstops.txt,simple_a,stop_a1,overlap,stop_a1\n"
+	if err := os.WriteFile(blocklist, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write blocklist: %v", err)
+	}
+
+	// When: merged by identity
+	cfg, err := parseArgs([]string{"--duplicateDetection=identity", "--blocked-duplicates=" + blocklist,
+		"../../testdata/simple_a", "../../testdata/overlap", filepath.Join(tmpDir, "merged.zip")})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	report, err := runMerge(cfg, nil)
+	if err != nil {
+		t.Fatalf("runMerge failed: %v", err)
+	}
+
+	// Then: stop_a1 is kept from both feeds
+	if _, ok := report.Sources[gtfs.KindStop]["a-stop_a1"]; !ok {
+		t.Errorf("Expected simple_a's stop_a1 to be prefixed, got %v", report.Sources[gtfs.KindStop])
+	}
+	if len(report.UnusedBlockedPairs) != 0 {
+		t.Errorf("Expected the pair to be used, got %v", report.UnusedBlockedPairs)
+	}
+}
+
+func TestCLIDebugChecksOutputReferences(t *testing.T) {
+	// Given: an input whose trips reference a route it lacks
+	tmpDir := t.TempDir()
+	feed, err := gtfs.ReadFromPath("../../testdata/simple_a")
+	if err != nil {
+		t.Fatalf("failed to read simple_a: %v", err)
+	}
+	for _, trip := range feed.Trips {
+		trip.RouteID = "missing"
+	}
+	input := filepath.Join(tmpDir, "broken.zip")
+	if err := gtfs.WriteToPath(feed, input); err != nil {
+		t.Fatalf("failed to write broken.zip: %v", err)
+	}
+	args := []string{input, "../../testdata/simple_b", filepath.Join(tmpDir, "merged.zip")}
+
+	// When: merged without --debug
+	cfg, err := parseArgs(args)
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+
+	// Then: the broken reference is passed through
+	if _, err := runMerge(cfg, nil); err != nil {
+		t.Fatalf("runMerge failed: %v", err)
+	}
+
+	// When: merged with --debug
+	cfg, err = parseArgs(append([]string{"--debug"}, args...))
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	_, err = runMerge(cfg, nil)
+
+	// Then: the merge fails on the broken reference
+	if !errors.Is(err, merge.ErrBrokenOutputReferences) {
+		t.Fatalf("expected ErrBrokenOutputReferences, got %v", err)
+	}
+}
+
+func TestCLIStripNewlines(t *testing.T) {
+	cfg, err := parseArgs([]string{"--stripNewlines", "../../testdata/quoted_fields_feed", "../../testdata/simple_b", filepath.Join(t.TempDir(), "merged.zip")})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if !cfg.stripNewlines {
+		t.Fatal("expected stripNewlines=true")
+	}
+	if _, err := runMerge(cfg, nil); err != nil {
+		t.Fatalf("runMerge failed: %v", err)
+	}
+
+	merged, err := gtfs.ReadFromPath(cfg.output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if got := merged.Stops["s1"].Desc; got != "Upper level. Use the east stairs." {
+		t.Errorf("expected the stop_desc on one line, got %q", got)
+	}
+	if got := merged.Trips["t1"].Headsign; got != `Airport, via "Loop" Express` {
+		t.Errorf("expected the trip_headsign on one line, got %q", got)
+	}
+}
+
+func TestCLISanitizeText(t *testing.T) {
+	// simple_a, with a vertical tab in a stop_name
+	input := t.TempDir()
+	if err := os.CopyFS(input, os.DirFS("../../testdata/simple_a")); err != nil {
+		t.Fatalf("failed to copy simple_a: %v", err)
+	}
+	stops := filepath.Join(input, "stops.txt")
+	data, err := os.ReadFile(stops)
+	if err != nil {
+		t.Fatalf("failed to read stops.txt: %v", err)
+	}
+	data = bytes.Replace(data, []byte("Midtown Stop"), []byte("Midtown\x0BStop"), 1)
+	if err := os.WriteFile(stops, data, 0644); err != nil {
+		t.Fatalf("failed to write stops.txt: %v", err)
+	}
+
+	cfg, err := parseArgs([]string{"--sanitizeText", input, "../../testdata/simple_b", filepath.Join(t.TempDir(), "merged.zip")})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if !cfg.sanitizeText {
+		t.Fatal("expected sanitizeText=true")
+	}
+	if _, err := runMerge(cfg, nil); err != nil {
+		t.Fatalf("runMerge failed: %v", err)
+	}
+
+	merged, err := gtfs.ReadFromPath(cfg.output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if got := merged.Stops["stop_a2"].Name; got != "Midtown Stop" {
+		t.Errorf("expected the stop_name cleaned, got %q", got)
+	}
+}
+
+func TestCLICRLFQuoteAll(t *testing.T) {
+	cfg, err := parseArgs([]string{"--crlf", "--quote-all", "../../testdata/simple_a", "../../testdata/simple_b", filepath.Join(t.TempDir(), "merged.zip")})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if !cfg.crlf || !cfg.quoteAll {
+		t.Fatalf("expected crlf and quoteAll, got %+v", cfg)
+	}
+	if _, err := runMerge(cfg, nil); err != nil {
+		t.Fatalf("runMerge failed: %v", err)
+	}
+
+	zr, err := zip.OpenReader(cfg.output)
+	if err != nil {
+		t.Fatalf("failed to open output: %v", err)
+	}
+	defer func() { _ = zr.Close() }()
+	f, err := zr.Open("agency.txt")
+	if err != nil {
+		t.Fatalf("failed to open agency.txt: %v", err)
+	}
+	content, err := io.ReadAll(f)
+	_ = f.Close()
+	if err != nil {
+		t.Fatalf("failed to read agency.txt: %v", err)
+	}
+	if !strings.HasPrefix(string(content), `"agency_id","agency_name",`) || !strings.HasSuffix(string(content), "\"\r\n") {
+		t.Errorf("expected quoted fields and CRLF line endings, got %q", content)
+	}
+}
+
+func TestCLIChecksum(t *testing.T) {
+	cfg, err := parseArgs([]string{"--checksum", "../../testdata/simple_a", "../../testdata/simple_b", filepath.Join(t.TempDir(), "merged.zip")})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if _, err := runMerge(cfg, nil); err != nil {
+		t.Fatalf("runMerge failed: %v", err)
+	}
+
+	sidecar, err := os.ReadFile(gtfs.ChecksumPath(cfg.output))
+	if err != nil {
+		t.Fatalf("expected a checksum sidecar: %v", err)
+	}
+	if !strings.HasSuffix(string(sidecar), "  merged.zip\n") || len(sidecar) != 64+len("  merged.zip\n") {
+		t.Errorf("expected HASH  merged.zip, got %q", sidecar)
+	}
+}
+
+func TestCLIZipStore(t *testing.T) {
+	cfg, err := parseArgs([]string{"--zip-store=stop_times.txt,shapes.txt", "../../testdata/simple_a", "../../testdata/simple_b", filepath.Join(t.TempDir(), "merged.zip")})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if _, err := runMerge(cfg, nil); err != nil {
+		t.Fatalf("runMerge failed: %v", err)
+	}
+
+	zr, err := zip.OpenReader(cfg.output)
+	if err != nil {
+		t.Fatalf("failed to open output: %v", err)
+	}
+	defer func() { _ = zr.Close() }()
+	for _, f := range zr.File {
+		want := zip.Deflate
+		if f.Name == "stop_times.txt" {
+			want = zip.Store
+		}
+		if f.Method != want {
+			t.Errorf("expected %s to use method %d, got %d", f.Name, want, f.Method)
+		}
+	}
+
+	if _, err := parseArgs([]string{"--zip-store=stop_times.txt,", "a.zip", "b.zip", "out.zip"}); err == nil {
+		t.Error("expected an error for an empty pattern")
+	}
+	if _, err := parseArgs([]string{"--zip-store=[", "a.zip", "b.zip", "out.zip"}); err == nil {
+		t.Error("expected an error for a malformed pattern")
+	}
+}
+
+func TestCLIProfile(t *testing.T) {
+	tests := []struct {
+		name      string
+		flags     []string
+		wantStops int
+	}{
+		{"profile", []string{"--profile=regional"}, 5},
+		{"overridden per file", []string{"--profile=regional", "--file=stops.txt", "--duplicateDetection=none"}, 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: simple_a and fuzzy_similar, whose stops match by properties
+			output := filepath.Join(t.TempDir(), "merged.zip")
+			args := append(tt.flags, "../../testdata/simple_a", "../../testdata/fuzzy_similar", output)
+			cfg, err := parseArgs(args)
+			if err != nil {
+				t.Fatalf("parseArgs failed: %v", err)
+			}
+
+			// When: merged
+			report, err := runMerge(cfg, nil)
+			if err != nil {
+				t.Fatalf("runMerge failed: %v", err)
+			}
+
+			// Then: the stops are merged as the profile and flags say
+			if got := report.Merged["stops.txt"]; got != tt.wantStops {
+				t.Errorf("Expected %d stops, got %d", tt.wantStops, got)
+			}
+		})
+	}
+
+	if _, err := parseArgs([]string{"--profile=everything", "a.zip", "b.zip", "out.zip"}); err == nil {
+		t.Error("expected an error for an unknown profile")
+	}
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/merge"
+)
+
+// mergeSummary is the end-of-run per-file summary. Its JSON encoding (--json)
+// is the stable, machine-readable form; the table is meant for people.
+type mergeSummary struct {
+	// CacheHit is set when --fingerprintCache found the inputs, options
+	// and output unchanged and the merge was skipped; nothing else is then
+	// described
+	CacheHit bool `json:"cache_hit,omitempty"`
+
+	// Feeds names the input feeds, in input order
+	Feeds []string `json:"feeds"`
+
+	// Files has one entry per GTFS file present in any input or the output,
+	// in the order files are written
+	Files []fileSummary `json:"files"`
+
+	// GrayZone counts the fuzzy matches resolved by the gray zone policy,
+	// by entity; omitted when there were none
+	GrayZone []grayZoneSummary `json:"gray_zone,omitempty"`
+
+	// StopCodes counts stop_code collisions found by --uniqueStopCodes;
+	// omitted when the check was not requested or found none
+	StopCodes *stopCodeSummary `json:"stop_codes,omitempty"`
+
+	// FrequencyOverlaps lists the frequency trips found running the same
+	// service as exact-time trips by --frequencyOverlaps; omitted when there
+	// were none
+	FrequencyOverlaps []frequencyOverlapSummary `json:"frequency_overlaps,omitempty"`
+
+	// Pruned counts the unreferenced entities deleted by --prune, by
+	// entity; omitted when pruning was not requested
+	Pruned []pruneSummary `json:"pruned,omitempty"`
+
+	// SkippedInputs lists the inputs left out because they are identical
+	// to an earlier input; omitted when there were none
+	SkippedInputs []skippedInputSummary `json:"skipped_inputs,omitempty"`
+
+	// InvalidInputs lists the inputs --skip-invalid left out because they
+	// could not be read; omitted when there were none
+	InvalidInputs []invalidInputSummary `json:"invalid_inputs,omitempty"`
+
+	// Service describes the merged feed's active service over the days
+	// checked (--serviceDays); omitted when not checked
+	Service *serviceSummary `json:"service,omitempty"`
+
+	// ServiceGaps lists the runs of days on which a merged route has no
+	// trips (--serviceGapDays); omitted when there are none
+	ServiceGaps []serviceGapSummary `json:"service_gaps,omitempty"`
+
+	// Stages lists the time spent in each stage of the merge, in the order
+	// first run; omitted when not measured
+	Stages []stageSummary `json:"stages,omitempty"`
+}
+
+// stageSummary is the time spent in one stage of the merge (see the
+// merge.Stage constants)
+type stageSummary struct {
+	Stage string `json:"stage"`
+
+	// Runs is the number of times the stage ran, e.g. once per input
+	Runs int `json:"runs"`
+
+	// Seconds is the total time spent in the stage
+	Seconds float64 `json:"seconds"`
+}
+
+// serviceSummary describes the merged feed's service over the days checked
+// from the day of the merge. Dates are YYYYMMDD, as in GTFS.
+type serviceSummary struct {
+	From string `json:"from"`
+	Days int    `json:"days"`
+
+	// DatesWithoutService lists the days checked with no active service
+	DatesWithoutService []string `json:"dates_without_service"`
+
+	// LastServiceDate is the last day with any active service; empty if
+	// there is none
+	LastServiceDate string `json:"last_service_date"`
+
+	// Warnings describes the gaps in service found, if any
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// serviceGapSummary is a run of days on which a route has no trips, from
+// Start to End (YYYYMMDD)
+type serviceGapSummary struct {
+	RouteID string `json:"route_id"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+	Days    int    `json:"days"`
+}
+
+// skippedInputSummary names an input skipped as a copy of an earlier one
+type skippedInputSummary struct {
+	Path        string `json:"path"`
+	DuplicateOf string `json:"duplicate_of"`
+}
+
+// invalidInputSummary names an input skipped because it could not be read
+type invalidInputSummary struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// stopCodeSummary counts the stop codes shared by more than one stop
+type stopCodeSummary struct {
+	// Collisions is the number of shared codes
+	Collisions int `json:"collisions"`
+
+	// Stops is the number of stops sharing them
+	Stops int `json:"stops"`
+
+	// Prefixed is the number of codes rewritten with a feed prefix
+	Prefixed int `json:"prefixed"`
+
+	// Unresolved is the number of codes still shared after prefixing
+	Unresolved int `json:"unresolved"`
+}
+
+// frequencyOverlapSummary is a frequency trip running the same service as
+// exact-time trips, and how the overlap was resolved
+type frequencyOverlapSummary struct {
+	RouteID       string `json:"route_id"`
+	ServiceID     string `json:"service_id"`
+	FrequencyTrip string `json:"frequency_trip_id"`
+	ExactTrips    int    `json:"exact_trips"`
+	Resolution    string `json:"resolution"`
+}
+
+// pruneSummary counts the unreferenced entities of one kind deleted
+type pruneSummary struct {
+	Entity  string `json:"entity"`
+	Deleted int    `json:"deleted"`
+}
+
+// grayZoneSummary counts the gray zone decisions for one kind of entity
+type grayZoneSummary struct {
+	Entity string `json:"entity"`
+
+	// NearMisses is the number of pairs kept apart
+	NearMisses int `json:"near_misses"`
+
+	// Flagged is the number of pairs merged and flagged for review
+	Flagged int `json:"flagged"`
+}
+
+// fileSummary counts the rows of one GTFS file across the merge
+type fileSummary struct {
+	File string `json:"file"`
+
+	// Read is the number of rows read from each input feed, in input order
+	Read []int `json:"read"`
+
+	// Duplicates is the number of rows read but not written because they
+	// were merged into existing entities
+	Duplicates int `json:"duplicates"`
+
+	// Merged is the number of rows in the merged output
+	Merged int `json:"merged"`
+}
+
+// buildSummary tabulates a merge report by GTFS file, with the stage
+// timings recorded in metrics when it is not nil. The merger reports its
+// counters to metrics from the same report, so they match the summary's.
+func buildSummary(report *merge.Report, metrics *merge.MemoryMetrics) mergeSummary {
+	summary := mergeSummary{CacheHit: report.CacheHit, Feeds: make([]string, len(report.Feeds))}
+	for i, fr := range report.Feeds {
+		summary.Feeds[i] = fr.Name
+	}
+
+	for _, filename := range gtfs.FileNames() {
+		fs := fileSummary{
+			File:   filename,
+			Read:   make([]int, len(report.Feeds)),
+			Merged: report.Merged[filename],
+		}
+		present := fs.Merged > 0
+		for i := range report.Feeds {
+			fr := &report.Feeds[i]
+			fs.Read[i] = fr.Read[filename]
+			fs.Duplicates += fr.Duplicates(filename)
+			present = present || fs.Read[i] > 0
+		}
+		if present {
+			summary.Files = append(summary.Files, fs)
+		}
+	}
+
+	for _, entity := range []string{"stop", "route", "trip"} {
+		gs := grayZoneSummary{Entity: entity}
+		for _, gm := range report.GrayZone {
+			switch {
+			case gm.Entity != entity:
+			case gm.Merged:
+				gs.Flagged++
+			default:
+				gs.NearMisses++
+			}
+		}
+		if gs.NearMisses+gs.Flagged > 0 {
+			summary.GrayZone = append(summary.GrayZone, gs)
+		}
+	}
+
+	if len(report.StopCodeCollisions) > 0 {
+		sc := &stopCodeSummary{
+			Collisions: len(report.StopCodeCollisions),
+			Prefixed:   report.StopCodesPrefixed,
+			Unresolved: len(report.StopCodesUnresolved),
+		}
+		for _, c := range report.StopCodeCollisions {
+			sc.Stops += len(c.StopIDs)
+		}
+		summary.StopCodes = sc
+	}
+
+	for _, o := range report.FrequencyOverlaps {
+		summary.FrequencyOverlaps = append(summary.FrequencyOverlaps, frequencyOverlapSummary{
+			RouteID:       string(o.RouteID),
+			ServiceID:     string(o.ServiceID),
+			FrequencyTrip: string(o.FrequencyTrip),
+			ExactTrips:    len(o.ExactTrips),
+			Resolution:    o.Policy.String(),
+		})
+	}
+
+	for _, kind := range gtfs.EntityKinds {
+		if n, ok := report.Pruned[kind]; ok {
+			summary.Pruned = append(summary.Pruned, pruneSummary{Entity: string(kind), Deleted: n})
+		}
+	}
+
+	for _, si := range report.SkippedInputs {
+		summary.SkippedInputs = append(summary.SkippedInputs, skippedInputSummary{Path: si.Path, DuplicateOf: si.DuplicateOf})
+	}
+
+	for _, ii := range report.InvalidInputs {
+		summary.InvalidInputs = append(summary.InvalidInputs, invalidInputSummary{Path: ii.Path, Error: ii.Err.Error()})
+	}
+
+	if sc := report.ServiceCoverage; sc != nil {
+		ss := &serviceSummary{
+			From:                sc.From.Format("20060102"),
+			Days:                sc.Days,
+			DatesWithoutService: []string{},
+			Warnings:            sc.Warnings(),
+		}
+		for _, d := range sc.DatesWithoutService {
+			ss.DatesWithoutService = append(ss.DatesWithoutService, d.Format("20060102"))
+		}
+		if !sc.LastServiceDate.IsZero() {
+			ss.LastServiceDate = sc.LastServiceDate.Format("20060102")
+		}
+		summary.Service = ss
+	}
+
+	for _, gap := range report.ServiceGaps {
+		summary.ServiceGaps = append(summary.ServiceGaps, serviceGapSummary{
+			RouteID: string(gap.RouteID),
+			Start:   gap.Start.Format("20060102"),
+			End:     gap.End.Format("20060102"),
+			Days:    gap.Days,
+		})
+	}
+
+	if metrics != nil {
+		for _, stage := range metrics.Stages() {
+			st := metrics.Stage(stage)
+			summary.Stages = append(summary.Stages, stageSummary{Stage: stage, Runs: st.Count, Seconds: st.Total.Seconds()})
+		}
+	}
+
+	return summary
+}
+
+// writeSummaryTable writes the summary as an aligned table with one row per
+// file: FILE, one column per input feed, DUPLICATES (when showDuplicates)
+// and MERGED, followed by a line per entity with gray zone decisions, a line
+// of stop code collisions, a line per kind of entity pruned, a line per
+// input skipped and a warning per gap in upcoming service
+func writeSummaryTable(w io.Writer, summary mergeSummary, showDuplicates bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprint(tw, "FILE")
+	for _, name := range summary.Feeds {
+		_, _ = fmt.Fprintf(tw, "\t%s", name)
+	}
+	if showDuplicates {
+		_, _ = fmt.Fprint(tw, "\tDUPLICATES")
+	}
+	_, _ = fmt.Fprintln(tw, "\tMERGED")
+
+	for _, fs := range summary.Files {
+		_, _ = fmt.Fprint(tw, fs.File)
+		for _, n := range fs.Read {
+			_, _ = fmt.Fprintf(tw, "\t%d", n)
+		}
+		if showDuplicates {
+			_, _ = fmt.Fprintf(tw, "\t%d", fs.Duplicates)
+		}
+		_, _ = fmt.Fprintf(tw, "\t%d\n", fs.Merged)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, gs := range summary.GrayZone {
+		if _, err := fmt.Fprintf(w, "Gray zone %ss: %d near misses, %d flagged\n", gs.Entity, gs.NearMisses, gs.Flagged); err != nil {
+			return err
+		}
+	}
+	if sc := summary.StopCodes; sc != nil {
+		if _, err := fmt.Fprintf(w, "Stop code collisions: %d codes shared by %d stops, %d prefixed, %d unresolved\n", sc.Collisions, sc.Stops, sc.Prefixed, sc.Unresolved); err != nil {
+			return err
+		}
+	}
+	for _, fo := range summary.FrequencyOverlaps {
+		if _, err := fmt.Fprintf(w, "Frequency overlap on route %s: trip %s and %d exact-time trips (%s)\n", fo.RouteID, fo.FrequencyTrip, fo.ExactTrips, fo.Resolution); err != nil {
+			return err
+		}
+	}
+	for _, ps := range summary.Pruned {
+		if _, err := fmt.Fprintf(w, "Pruned unreferenced %ss: %d\n", ps.Entity, ps.Deleted); err != nil {
+			return err
+		}
+	}
+	for _, si := range summary.SkippedInputs {
+		if _, err := fmt.Fprintf(w, "Skipped input %s: identical to %s\n", si.Path, si.DuplicateOf); err != nil {
+			return err
+		}
+	}
+	for _, ii := range summary.InvalidInputs {
+		if _, err := fmt.Fprintf(w, "WARNING: skipped invalid input %s: %s\n", ii.Path, ii.Error); err != nil {
+			return err
+		}
+	}
+	if ss := summary.Service; ss != nil {
+		for _, warning := range ss.Warnings {
+			if _, err := fmt.Fprintf(w, "WARNING: %s\n", warning); err != nil {
+				return err
+			}
+		}
+	}
+	for _, gap := range summary.ServiceGaps {
+		if _, err := fmt.Fprintf(w, "WARNING: route %s has no trips from %s to %s (%d days)\n", gap.RouteID, gap.Start, gap.End, gap.Days); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSummaryJSON writes the summary as indented JSON
+func writeSummaryJSON(w io.Writer, summary mergeSummary) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(summary)
+}
+
+// detectionEnabled reports whether any duplicate detection was requested,
+// globally or for a single file
+func detectionEnabled(cfg *config) bool {
+	if cfg.duplicateDetection != "" && cfg.duplicateDetection != "none" {
+		return true
+	}
+	for _, fc := range cfg.files {
+		if fc.detection != "" && fc.detection != "none" {
+			return true
+		}
+	}
+	return false
+}
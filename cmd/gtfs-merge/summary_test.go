@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/merge"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+func TestSummaryTable(t *testing.T) {
+	cfg := &config{
+		inputs:             []string{"../../testdata/simple_a", "../../testdata/overlap"},
+		output:             filepath.Join(t.TempDir(), "merged.zip"),
+		duplicateDetection: "identity",
+	}
+	report, err := runMerge(cfg, nil)
+	if err != nil {
+		t.Fatalf("runMerge failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeSummaryTable(&buf, buildSummary(report, nil), detectionEnabled(cfg)); err != nil {
+		t.Fatalf("writeSummaryTable failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	// Header names each input feed and the duplicates column
+	if got := strings.Fields(lines[0]); strings.Join(got, " ") != "FILE simple_a overlap DUPLICATES MERGED" {
+		t.Errorf("unexpected header: %q", lines[0])
+	}
+
+	// Rows follow the written file order
+	if got := strings.Fields(lines[1]); strings.Join(got, " ") != "agency.txt 2 1 1 2" {
+		t.Errorf("unexpected agency row: %q", lines[1])
+	}
+	if len(lines) != 7 {
+		t.Errorf("expected header and 6 file rows, got %d lines:\n%s", len(lines), buf.String())
+	}
+}
+
+func TestSummaryTableWithoutDetection(t *testing.T) {
+	cfg := &config{
+		inputs: []string{"../../testdata/simple_a", "../../testdata/simple_b"},
+		output: filepath.Join(t.TempDir(), "merged.zip"),
+	}
+	report, err := runMerge(cfg, nil)
+	if err != nil {
+		t.Fatalf("runMerge failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeSummaryTable(&buf, buildSummary(report, nil), detectionEnabled(cfg)); err != nil {
+		t.Fatalf("writeSummaryTable failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "DUPLICATES") {
+		t.Errorf("expected no duplicates column without detection:\n%s", buf.String())
+	}
+}
+
+func TestSummaryJSON(t *testing.T) {
+	cfg := &config{
+		inputs:             []string{"../../testdata/simple_a", "../../testdata/overlap"},
+		output:             filepath.Join(t.TempDir(), "merged.zip"),
+		duplicateDetection: "identity",
+	}
+	metrics := merge.NewMemoryMetrics()
+	report, err := runMerge(cfg, metrics)
+	if err != nil {
+		t.Fatalf("runMerge failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeSummaryJSON(&buf, buildSummary(report, metrics)); err != nil {
+		t.Fatalf("writeSummaryJSON failed: %v", err)
+	}
+
+	var decoded mergeSummary
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("summary is not valid JSON: %v", err)
+	}
+	if strings.Join(decoded.Feeds, ",") != "simple_a,overlap" {
+		t.Errorf("unexpected feeds: %v", decoded.Feeds)
+	}
+	if len(decoded.Files) == 0 || decoded.Files[0].File != "agency.txt" {
+		t.Fatalf("expected agency.txt first, got %+v", decoded.Files)
+	}
+	agency := decoded.Files[0]
+	if agency.Read[0] != 2 || agency.Read[1] != 1 || agency.Duplicates != 1 || agency.Merged != 2 {
+		t.Errorf("unexpected agency counts: %+v", agency)
+	}
+
+	// The metrics counters agree with the summary, and its stages are listed
+	for _, fs := range decoded.Files {
+		if got := metrics.EntityCount(fs.File, merge.OpDuplicate); got != fs.Duplicates {
+			t.Errorf("%s: expected %d duplicates in metrics, got %d", fs.File, fs.Duplicates, got)
+		}
+		if got := metrics.EntityCount(fs.File, merge.OpMerged); got != fs.Merged {
+			t.Errorf("%s: expected %d merged in metrics, got %d", fs.File, fs.Merged, got)
+		}
+	}
+	if len(decoded.Stages) == 0 || decoded.Stages[0].Stage != merge.StageRead || decoded.Stages[0].Runs != 2 {
+		t.Errorf("expected 2 reads first in stages, got %+v", decoded.Stages)
+	}
+}
+
+func TestParseArgsJSON(t *testing.T) {
+	cfg, err := parseArgs([]string{"--json", "a.zip", "b.zip", "out.zip"})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if !cfg.jsonSummary {
+		t.Error("expected --json to enable the JSON summary")
+	}
+}
+
+func TestSummaryGrayZoneCounts(t *testing.T) {
+	report := &merge.Report{GrayZone: []strategy.GrayZoneMatch{
+		{Entity: "trip", Merged: true},
+		{Entity: "stop"},
+		{Entity: "stop", Merged: true},
+		{Entity: "stop"},
+	}}
+	summary := buildSummary(report, nil)
+
+	// Entities are listed in merge order, with near misses and flags counted
+	want := []grayZoneSummary{
+		{Entity: "stop", NearMisses: 2, Flagged: 1},
+		{Entity: "trip", NearMisses: 0, Flagged: 1},
+	}
+	if len(summary.GrayZone) != len(want) {
+		t.Fatalf("expected %+v, got %+v", want, summary.GrayZone)
+	}
+	for i := range want {
+		if summary.GrayZone[i] != want[i] {
+			t.Errorf("expected %+v, got %+v", want[i], summary.GrayZone[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := writeSummaryTable(&buf, summary, true); err != nil {
+		t.Fatalf("writeSummaryTable failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Gray zone stops: 2 near misses, 1 flagged\n") {
+		t.Errorf("expected gray zone counts after the table:\n%s", buf.String())
+	}
+}
+
+func TestParseArgsGrayZone(t *testing.T) {
+	cfg, err := parseArgs([]string{"--grayZone=0.05", "--grayZonePolicy=abort", "a.zip", "b.zip", "out.zip"})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if cfg.grayZone != 0.05 || cfg.grayZonePolicy != "abort" {
+		t.Errorf("unexpected gray zone config: %v %q", cfg.grayZone, cfg.grayZonePolicy)
+	}
+
+	for _, args := range [][]string{
+		{"--grayZone=wide", "a.zip", "b.zip", "out.zip"},
+		{"--grayZone=0.05", "--grayZonePolicy=maybe", "a.zip", "b.zip", "out.zip"},
+		{"--grayZonePolicy=flag", "a.zip", "b.zip", "out.zip"},
+	} {
+		if _, err := parseArgs(args); err == nil {
+			t.Errorf("expected an error for %v", args)
+		}
+	}
+}
+
+func TestSummaryPruned(t *testing.T) {
+	report := &merge.Report{Pruned: map[gtfs.EntityKind]int{gtfs.KindShape: 0, gtfs.KindStop: 12}}
+	summary := buildSummary(report, nil)
+
+	// Kinds are listed in entity order, including those with nothing pruned
+	want := []pruneSummary{{Entity: "stop", Deleted: 12}, {Entity: "shape", Deleted: 0}}
+	if len(summary.Pruned) != len(want) || summary.Pruned[0] != want[0] || summary.Pruned[1] != want[1] {
+		t.Fatalf("expected %+v, got %+v", want, summary.Pruned)
+	}
+
+	var buf bytes.Buffer
+	if err := writeSummaryTable(&buf, summary, false); err != nil {
+		t.Fatalf("writeSummaryTable failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Pruned unreferenced stops: 12\n") {
+		t.Errorf("expected pruned counts after the table:\n%s", buf.String())
+	}
+}
+
+func TestParseArgsPrune(t *testing.T) {
+	cfg, err := parseArgs([]string{"--prune=stops,shapes", "a.zip", "b.zip", "out.zip"})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if len(cfg.prune) != 2 || cfg.prune[0] != "stops" || cfg.prune[1] != "shapes" {
+		t.Errorf("unexpected prune kinds: %v", cfg.prune)
+	}
+
+	if _, err := parseArgs([]string{"--prune=stops,routes", "a.zip", "b.zip", "out.zip"}); err == nil {
+		t.Error("expected an error for an unknown prune kind")
+	}
+}
+
+func TestSummaryStopCodeCollisions(t *testing.T) {
+	report := &merge.Report{
+		StopCodeCollisions: []merge.StopCodeCollision{
+			{Code: "1001", StopIDs: []gtfs.StopID{"b1", "a1"}},
+			{Code: "1002", StopIDs: []gtfs.StopID{"b2", "a2", "c2"}},
+		},
+		StopCodesPrefixed: 3,
+		StopCodesUnresolved: []merge.StopCodeCollision{
+			{Code: "1002", StopIDs: []gtfs.StopID{"b2", "c2"}},
+		},
+	}
+	summary := buildSummary(report, nil)
+	if sc := summary.StopCodes; sc == nil || *sc != (stopCodeSummary{Collisions: 2, Stops: 5, Prefixed: 3, Unresolved: 1}) {
+		t.Fatalf("unexpected stop code summary: %+v", sc)
+	}
+
+	var buf bytes.Buffer
+	if err := writeSummaryTable(&buf, summary, false); err != nil {
+		t.Fatalf("writeSummaryTable failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Stop code collisions: 2 codes shared by 5 stops, 3 prefixed, 1 unresolved\n") {
+		t.Errorf("expected stop code counts after the table:\n%s", buf.String())
+	}
+
+	if _, err := parseArgs([]string{"--uniqueStopCodes=sometimes", "a.zip", "b.zip", "out.zip"}); err == nil {
+		t.Error("expected an error for an invalid stop code policy")
+	}
+}
+
+func TestSummarySkippedInputs(t *testing.T) {
+	report := &merge.Report{SkippedInputs: []merge.SkippedInput{{Path: "copy.zip", DuplicateOf: "feed.zip"}}}
+	summary := buildSummary(report, nil)
+
+	want := skippedInputSummary{Path: "copy.zip", DuplicateOf: "feed.zip"}
+	if len(summary.SkippedInputs) != 1 || summary.SkippedInputs[0] != want {
+		t.Fatalf("expected %+v, got %+v", want, summary.SkippedInputs)
+	}
+
+	var buf bytes.Buffer
+	if err := writeSummaryTable(&buf, summary, false); err != nil {
+		t.Fatalf("writeSummaryTable failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Skipped input copy.zip: identical to feed.zip\n") {
+		t.Errorf("expected the skipped input after the table:\n%s", buf.String())
+	}
+}
+
+func TestParseArgsFailOnIdenticalInputs(t *testing.T) {
+	cfg, err := parseArgs([]string{"--failOnIdenticalInputs", "a.zip", "b.zip", "out.zip"})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if !cfg.failOnIdentical {
+		t.Error("expected failOnIdentical=true")
+	}
+}
+
+func TestSummaryInvalidInputs(t *testing.T) {
+	report := &merge.Report{InvalidInputs: []merge.InvalidInput{{Path: "broken.zip", Err: gtfs.ErrMissingRequiredFile}}}
+	summary := buildSummary(report, nil)
+
+	want := invalidInputSummary{Path: "broken.zip", Error: "missing required GTFS file"}
+	if len(summary.InvalidInputs) != 1 || summary.InvalidInputs[0] != want {
+		t.Fatalf("expected %+v, got %+v", want, summary.InvalidInputs)
+	}
+	if status := mergeExitStatus(report); status != exitSkippedInvalid {
+		t.Errorf("expected exit status %d, got %d", exitSkippedInvalid, status)
+	}
+
+	var buf bytes.Buffer
+	if err := writeSummaryTable(&buf, summary, false); err != nil {
+		t.Fatalf("writeSummaryTable failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "WARNING: skipped invalid input broken.zip: missing required GTFS file\n") {
+		t.Errorf("expected the invalid input after the table:\n%s", buf.String())
+	}
+}
+
+func TestParseArgsSkipInvalid(t *testing.T) {
+	cfg, err := parseArgs([]string{"--skip-invalid", "a.zip", "b.zip", "out.zip"})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if !cfg.skipInvalid {
+		t.Error("expected skipInvalid=true")
+	}
+	if status := mergeExitStatus(&merge.Report{}); status != 0 {
+		t.Errorf("expected exit status 0 with nothing skipped, got %d", status)
+	}
+}
+
+func TestSummaryFrequencyOverlaps(t *testing.T) {
+	report := &merge.Report{FrequencyOverlaps: []merge.FrequencyOverlap{{
+		RouteID: "r1", ServiceID: "wkdy", FrequencyTrip: "template",
+		ExactTrips: []gtfs.TripID{"exact0", "exact1"}, Policy: merge.FrequencyOverlapKeepExact,
+	}}}
+	summary := buildSummary(report, nil)
+
+	want := frequencyOverlapSummary{RouteID: "r1", ServiceID: "wkdy", FrequencyTrip: "template", ExactTrips: 2, Resolution: "keep-exact"}
+	if len(summary.FrequencyOverlaps) != 1 || summary.FrequencyOverlaps[0] != want {
+		t.Fatalf("expected %+v, got %+v", want, summary.FrequencyOverlaps)
+	}
+
+	var buf bytes.Buffer
+	if err := writeSummaryTable(&buf, summary, false); err != nil {
+		t.Fatalf("writeSummaryTable failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Frequency overlap on route r1: trip template and 2 exact-time trips (keep-exact)\n") {
+		t.Errorf("expected the overlap after the table:\n%s", buf.String())
+	}
+
+	cfg, err := parseArgs([]string{"--frequencyOverlaps=keep-frequencies", "a.zip", "b.zip", "out.zip"})
+	if err != nil || cfg.frequencyOverlaps != "keep-frequencies" {
+		t.Errorf("expected keep-frequencies, got %+v, %v", cfg, err)
+	}
+	if _, err := parseArgs([]string{"--frequencyOverlaps=both", "a.zip", "b.zip", "out.zip"}); err == nil {
+		t.Error("expected error for an unknown policy")
+	}
+}
+
+func TestSummaryServiceCoverage(t *testing.T) {
+	from := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	coverage := &merge.ServiceCoverage{
+		From:                from,
+		Days:                7,
+		DatesWithoutService: []time.Time{from.AddDate(0, 0, 5), from.AddDate(0, 0, 6)},
+		LastServiceDate:     from.AddDate(0, 0, 4),
+	}
+	summary := buildSummary(&merge.Report{ServiceCoverage: coverage}, nil)
+
+	ss := summary.Service
+	if ss == nil || ss.From != "20261017" || ss.Days != 7 || ss.LastServiceDate != "20261021" ||
+		!slices.Equal(ss.DatesWithoutService, []string{"20261022", "20261023"}) || len(ss.Warnings) != 2 {
+		t.Fatalf("unexpected service summary: %+v", ss)
+	}
+
+	var buf bytes.Buffer
+	if err := writeSummaryTable(&buf, summary, false); err != nil {
+		t.Fatalf("writeSummaryTable failed: %v", err)
+	}
+	for _, warning := range coverage.Warnings() {
+		if !strings.Contains(buf.String(), "WARNING: "+warning+"\n") {
+			t.Errorf("expected %q after the table:\n%s", warning, buf.String())
+		}
+	}
+}
+
+func TestSummaryServiceGaps(t *testing.T) {
+	report := &merge.Report{ServiceGaps: []gtfs.ServiceGap{{
+		RouteID: "r1",
+		Start:   time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+		End:     time.Date(2026, 4, 2, 0, 0, 0, 0, time.UTC),
+		Days:    2,
+	}}}
+	summary := buildSummary(report, nil)
+
+	want := serviceGapSummary{RouteID: "r1", Start: "20260401", End: "20260402", Days: 2}
+	if len(summary.ServiceGaps) != 1 || summary.ServiceGaps[0] != want {
+		t.Fatalf("expected %+v, got %+v", want, summary.ServiceGaps)
+	}
+
+	var buf bytes.Buffer
+	if err := writeSummaryTable(&buf, summary, false); err != nil {
+		t.Fatalf("writeSummaryTable failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "WARNING: route r1 has no trips from 20260401 to 20260402 (2 days)\n") {
+		t.Errorf("expected the gap after the table:\n%s", buf.String())
+	}
+
+	cfg, err := parseArgs([]string{"a.zip", "b.zip", "out.zip"})
+	if err != nil || cfg.serviceGapDays != 0 {
+		t.Errorf("expected no gap check by default, got %+v, %v", cfg, err)
+	}
+	cfg, err = parseArgs([]string{"--serviceGapDays=2", "a.zip", "b.zip", "out.zip"})
+	if err != nil || cfg.serviceGapDays != 2 {
+		t.Errorf("expected gaps over 2 days to be warned about, got %+v, %v", cfg, err)
+	}
+	for _, value := range []string{"0", "-1", "two"} {
+		if _, err := parseArgs([]string{"--serviceGapDays=" + value, "a.zip", "b.zip", "out.zip"}); err == nil {
+			t.Errorf("expected an error for --serviceGapDays=%s", value)
+		}
+	}
+}
+
+func TestParseArgsServiceCheck(t *testing.T) {
+	cfg, err := parseArgs([]string{"a.zip", "b.zip", "out.zip"})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if cfg.serviceDays != 7 || cfg.noServiceCheck {
+		t.Errorf("expected a 7 day service check by default, got %d days, noServiceCheck=%v", cfg.serviceDays, cfg.noServiceCheck)
+	}
+
+	cfg, err = parseArgs([]string{"--serviceDays=14", "--noServiceCheck", "a.zip", "b.zip", "out.zip"})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if cfg.serviceDays != 14 || !cfg.noServiceCheck {
+		t.Errorf("expected 14 days and noServiceCheck=true, got %d and %v", cfg.serviceDays, cfg.noServiceCheck)
+	}
+
+	for _, value := range []string{"0", "-1", "week"} {
+		if _, err := parseArgs([]string{"--serviceDays=" + value, "a.zip", "b.zip", "out.zip"}); err == nil {
+			t.Errorf("expected an error for --serviceDays=%s", value)
+		}
+	}
+}
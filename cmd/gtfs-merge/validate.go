@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// validateConfig holds parsed configuration for the validate subcommand
+type validateConfig struct {
+	feeds    []string
+	strict   bool // count parse warnings as errors
+	showHelp bool
+}
+
+// parseValidateArgs parses the arguments following "validate" into a
+// validateConfig
+func parseValidateArgs(args []string) (*validateConfig, error) {
+	cfg := &validateConfig{}
+
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			switch arg {
+			case "--help", "-h":
+				cfg.showHelp = true
+			case "--strict":
+				cfg.strict = true
+			default:
+				return nil, fmt.Errorf("unknown flag: %s", arg)
+			}
+		} else {
+			cfg.feeds = append(cfg.feeds, arg)
+		}
+	}
+
+	if cfg.showHelp {
+		return cfg, nil
+	}
+
+	if len(cfg.feeds) == 0 {
+		return nil, fmt.Errorf("validate requires at least 1 argument: <feed> [...]")
+	}
+
+	return cfg, nil
+}
+
+// validationFiles names the GTFS file each ValidationError.EntityType is
+// found in
+var validationFiles = map[string]string{
+	"agency":             "agency.txt",
+	"stop":               "stops.txt",
+	"route":              "routes.txt",
+	"trip":               "trips.txt",
+	"stop_time":          "stop_times.txt",
+	"calendar":           "calendar.txt",
+	"calendar_date":      "calendar_dates.txt",
+	"frequency":          "frequencies.txt",
+	"transfer":           "transfers.txt",
+	"fare_attribute":     "fare_attributes.txt",
+	"fare_rule":          "fare_rules.txt",
+	"pathway":            "pathways.txt",
+	"route_network":      "route_networks.txt",
+	"fare_transfer_rule": "fare_transfer_rules.txt",
+	"fare_leg_rule":      "fare_leg_rules.txt",
+	"fare_product":       "fare_products.txt",
+	"timeframe":          "timeframes.txt",
+}
+
+// feedValidation is the outcome of validating one feed
+type feedValidation struct {
+	Path     string
+	Errors   int
+	Warnings int
+
+	// issues lists each problem found, by GTFS file; problems with no file
+	// of their own are listed under "(feed)"
+	issues map[string][]string
+}
+
+// failed reports whether the feed fails validation; warnings count only
+// under strict
+func (fv *feedValidation) failed(strict bool) bool {
+	return fv.Errors > 0 || (strict && fv.Warnings > 0)
+}
+
+// validateFeed reads and validates the feed at path. The feed's
+// ValidationErrors are errors; the malformed values Feed.ParseWarnings
+// collects while reading are warnings.
+func validateFeed(path string) (*feedValidation, error) {
+	feed, err := gtfs.ReadFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fv := &feedValidation{Path: path, issues: make(map[string][]string)}
+	for _, err := range feed.Validate() {
+		file := "(feed)"
+		var ve *gtfs.ValidationError
+		if errors.As(err, &ve) && validationFiles[ve.EntityType] != "" {
+			file = validationFiles[ve.EntityType]
+		}
+		fv.issues[file] = append(fv.issues[file], "error: "+err.Error())
+		fv.Errors++
+	}
+	for _, pe := range feed.ParseWarnings {
+		file := pe.File
+		if file == "" {
+			file = "(feed)"
+		}
+		fv.issues[file] = append(fv.issues[file], "warning: "+pe.Error())
+		fv.Warnings++
+	}
+	return fv, nil
+}
+
+// runValidate validates each feed in cfg independently, writes its issues
+// grouped by file to w, followed by a summary table when there are several
+// feeds, and returns the number of feeds that failed
+func runValidate(cfg *validateConfig, w io.Writer) (int, error) {
+	var results []*feedValidation
+	for _, path := range cfg.feeds {
+		fv, err := validateFeed(path)
+		if err != nil {
+			return 0, fmt.Errorf("reading %s: %w", path, err)
+		}
+		results = append(results, fv)
+		writeFeedValidation(w, fv)
+	}
+
+	failed := 0
+	for _, fv := range results {
+		if fv.failed(cfg.strict) {
+			failed++
+		}
+	}
+
+	if len(results) > 1 {
+		_, _ = fmt.Fprintln(w)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "FEED\tERRORS\tWARNINGS\tSTATUS")
+		for _, fv := range results {
+			status := "ok"
+			if fv.failed(cfg.strict) {
+				status = "FAIL"
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", fv.Path, fv.Errors, fv.Warnings, status)
+		}
+		if err := tw.Flush(); err != nil {
+			return failed, err
+		}
+	}
+
+	return failed, nil
+}
+
+// writeFeedValidation writes fv's issues grouped by file, each file with
+// its count, then the feed's totals
+func writeFeedValidation(w io.Writer, fv *feedValidation) {
+	_, _ = fmt.Fprintf(w, "%s:\n", fv.Path)
+	for _, file := range slices.Sorted(maps.Keys(fv.issues)) {
+		_, _ = fmt.Fprintf(w, "  %s: %d issue(s)\n", file, len(fv.issues[file]))
+		for _, issue := range fv.issues[file] {
+			_, _ = fmt.Fprintf(w, "    %s\n", issue)
+		}
+	}
+	_, _ = fmt.Fprintf(w, "  %d error(s), %d warning(s)\n", fv.Errors, fv.Warnings)
+}
+
+// printValidateUsage prints the usage information for the validate
+// subcommand
+func printValidateUsage() {
+	fmt.Println(`gtfs-merge validate - Check GTFS feeds without merging them
+
+Usage:
+  gtfs-merge validate [options] <feed> [...]
+
+Arguments:
+  feed                 GTFS feeds to check (zip files or directories); each
+                       is validated on its own
+
+Each feed's problems are listed grouped by file. Errors are required fields
+that are missing, references to entities the feed lacks, duplicate keys and
+invalid location types. Warnings are malformed values the reader replaced
+with their defaults. With several feeds, a table summarizes them.
+
+Options:
+  --help, -h           Show this help message
+  --strict             Fail feeds that have warnings, not only errors
+
+Exit status is 0 when every feed passes, 1 when any fails, and 2 on error.
+
+Examples:
+  gtfs-merge validate feed.zip
+  gtfs-merge validate --strict feed1.zip feed2.zip`)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseValidateArgs(t *testing.T) {
+	cfg, err := parseValidateArgs([]string{"--strict", "a.zip", "b.zip"})
+	if err != nil {
+		t.Fatalf("parseValidateArgs failed: %v", err)
+	}
+	if !cfg.strict || len(cfg.feeds) != 2 || cfg.feeds[0] != "a.zip" || cfg.feeds[1] != "b.zip" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+
+	for _, args := range [][]string{{}, {"--bogus", "a.zip"}} {
+		if _, err := parseValidateArgs(args); err == nil {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+}
+
+func TestRunValidate(t *testing.T) {
+	// Given: a valid feed and one serving stations in stop_times
+	cfg := &validateConfig{feeds: []string{"../../testdata/minimal", "../../testdata/station_stop_times"}}
+
+	// When: validated
+	var buf bytes.Buffer
+	failed, err := runValidate(cfg, &buf)
+	if err != nil {
+		t.Fatalf("runValidate failed: %v", err)
+	}
+
+	// Then: only the second feed fails
+	if failed != 1 {
+		t.Errorf("expected 1 failed feed, got %d", failed)
+	}
+
+	// And: its issues are grouped by file with counts, and summarized
+	out := buf.String()
+	for _, want := range []string{
+		"  pathways.txt: 1 issue(s)\n",
+		"  stop_times.txt: 2 issue(s)\n",
+		"  3 error(s), 0 warning(s)\n",
+		"FEED",
+		"../../testdata/minimal             0       0         ok\n",
+		"../../testdata/station_stop_times  3       0         FAIL\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestValidateMainExitStatus(t *testing.T) {
+	// A copy of the minimal feed with a malformed stop_lat, which the reader
+	// reports as a warning
+	warned := t.TempDir()
+	for _, name := range []string{"agency.txt", "calendar.txt", "routes.txt", "stop_times.txt", "trips.txt"} {
+		data, err := os.ReadFile(filepath.Join("../../testdata/minimal", name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(warned, name), data, 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	stops := "stop_id,stop_name,stop_lat,stop_lon\nstop1,Main Street Station,north,-122.4194\n"
+	if err := os.WriteFile(filepath.Join(warned, "stops.txt"), []byte(stops), 0644); err != nil {
+		t.Fatalf("failed to write stops.txt: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"valid", []string{"../../testdata/minimal"}, 0},
+		{"errors", []string{"../../testdata/minimal", "../../testdata/station_stop_times"}, 1},
+		{"warnings", []string{warned}, 0},
+		{"warnings with strict", []string{"--strict", warned}, 1},
+		{"missing input", []string{"does-not-exist.zip"}, 2},
+		{"no input", nil, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateMain(tt.args); got != tt.want {
+				t.Errorf("expected exit status %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/compare"
+)
+
+// Commit and BuildDate describe the build; like Version they can be set
+// with -ldflags "-X main.Commit=... -X main.BuildDate=...", and otherwise
+// come from the VCS information the go command embeds
+var (
+	Commit    = ""
+	BuildDate = ""
+)
+
+// versionInfo is what --version reports. Its JSON encoding (--version
+// --json) is the machine-readable form.
+type versionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+
+	// Modified is true when the build had uncommitted changes
+	Modified bool `json:"modified,omitempty"`
+
+	// JavaMerger is the onebusaway-gtfs-merge version whose behavior the
+	// compare suite last validated this build against
+	JavaMerger string `json:"java_merger"`
+}
+
+// currentVersionInfo describes the running binary
+func currentVersionInfo() versionInfo {
+	bi, _ := debug.ReadBuildInfo()
+	return buildVersionInfo(bi)
+}
+
+// buildVersionInfo combines the -ldflags variables with bi, which may be nil.
+// Values set with -ldflags take precedence.
+func buildVersionInfo(bi *debug.BuildInfo) versionInfo {
+	info := versionInfo{
+		Version:    Version,
+		Commit:     Commit,
+		BuildDate:  BuildDate,
+		GoVersion:  runtime.Version(),
+		JavaMerger: compare.JavaMergerVersion,
+	}
+	if bi == nil {
+		return info
+	}
+	if info.Version == "dev" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		info.Version = bi.Main.Version
+	}
+	if bi.GoVersion != "" {
+		info.GoVersion = bi.GoVersion
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = s.Value
+			}
+		case "vcs.time":
+			if info.BuildDate == "" {
+				info.BuildDate = s.Value
+			}
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+	return info
+}
+
+// writeVersion writes info as one line per field, omitting unknown ones
+func writeVersion(w io.Writer, info versionInfo) error {
+	commit := info.Commit
+	if info.Modified && commit != "" {
+		commit += " (modified)"
+	}
+	lines := []struct{ label, value string }{
+		{"gtfs-merge version", info.Version},
+		{"commit:", commit},
+		{"built:", info.BuildDate},
+		{"go:", info.GoVersion},
+		{"java merger:", "onebusaway-gtfs-merge " + info.JavaMerger},
+	}
+	for i, l := range lines {
+		if l.value == "" {
+			continue
+		}
+		// The first line reads as a sentence; the rest are indented fields
+		format := "  %s %s\n"
+		if i == 0 {
+			format = "%s %s\n"
+		}
+		if _, err := fmt.Fprintf(w, format, l.label, l.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeVersionJSON writes info as indented JSON
+func writeVersionJSON(w io.Writer, info versionInfo) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(info)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/compare"
+)
+
+func TestBuildVersionInfoFromBuildInfo(t *testing.T) {
+	// Given: build info embedded by the go command, and no -ldflags values
+	bi := &debug.BuildInfo{
+		GoVersion: "go1.25.5",
+		Main:      debug.Module{Version: "v1.4.0"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123abcd"},
+			{Key: "vcs.time", Value: "2026-01-02T03:04:05Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+
+	// When: building the version info
+	info := buildVersionInfo(bi)
+
+	// Then: every field comes from the build info
+	expected := versionInfo{
+		Version:    "v1.4.0",
+		Commit:     "0123abcd",
+		BuildDate:  "2026-01-02T03:04:05Z",
+		GoVersion:  "go1.25.5",
+		Modified:   true,
+		JavaMerger: compare.JavaMergerVersion,
+	}
+	if info != expected {
+		t.Errorf("Expected %+v, got %+v", expected, info)
+	}
+}
+
+func TestBuildVersionInfoLdflagsTakePrecedence(t *testing.T) {
+	// Given: values set with -ldflags
+	defer func(v, c, d string) { Version, Commit, BuildDate = v, c, d }(Version, Commit, BuildDate)
+	Version, Commit, BuildDate = "1.5.0", "feedface", "2026-03-04"
+	bi := &debug.BuildInfo{
+		Main:     debug.Module{Version: "(devel)"},
+		Settings: []debug.BuildSetting{{Key: "vcs.revision", Value: "0123abcd"}},
+	}
+
+	// When: building the version info
+	info := buildVersionInfo(bi)
+
+	// Then: the -ldflags values win over the build info
+	if info.Version != "1.5.0" || info.Commit != "feedface" || info.BuildDate != "2026-03-04" {
+		t.Errorf("Expected the -ldflags values, got %+v", info)
+	}
+}
+
+func TestWriteVersion(t *testing.T) {
+	info := versionInfo{
+		Version:    "v1.4.0",
+		Commit:     "0123abcd",
+		BuildDate:  "2026-01-02T03:04:05Z",
+		GoVersion:  "go1.25.5",
+		Modified:   true,
+		JavaMerger: "11.2.0",
+	}
+	var buf bytes.Buffer
+	if err := writeVersion(&buf, info); err != nil {
+		t.Fatalf("writeVersion failed: %v", err)
+	}
+	expected := `gtfs-merge version v1.4.0
+  commit: 0123abcd (modified)
+  built: 2026-01-02T03:04:05Z
+  go: go1.25.5
+  java merger: onebusaway-gtfs-merge 11.2.0
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+
+	// Unknown fields are left out
+	buf.Reset()
+	if err := writeVersion(&buf, versionInfo{Version: "dev", GoVersion: "go1.25.5", JavaMerger: "11.2.0"}); err != nil {
+		t.Fatalf("writeVersion failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "commit:") || strings.Contains(buf.String(), "built:") {
+		t.Errorf("Expected no commit or build date lines, got:\n%s", buf.String())
+	}
+}
+
+func TestWriteVersionJSON(t *testing.T) {
+	var buf bytes.Buffer
+	info := versionInfo{Version: "v1.4.0", Commit: "0123abcd", GoVersion: "go1.25.5", JavaMerger: "11.2.0"}
+	if err := writeVersionJSON(&buf, info); err != nil {
+		t.Fatalf("writeVersionJSON failed: %v", err)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON, got %v:\n%s", err, buf.String())
+	}
+	expected := map[string]any{
+		"version":     "v1.4.0",
+		"commit":      "0123abcd",
+		"go_version":  "go1.25.5",
+		"java_merger": "11.2.0",
+	}
+	if len(decoded) != len(expected) {
+		t.Errorf("Expected keys %v, got %v", expected, decoded)
+	}
+	for k, v := range expected {
+		if decoded[k] != v {
+			t.Errorf("Expected %s=%v, got %v", k, v, decoded[k])
+		}
+	}
+}
+
+func TestJavaMergerVersionMatchesDownloadScript(t *testing.T) {
+	script, err := os.ReadFile("../../testdata/java/download.sh")
+	if err != nil {
+		t.Fatalf("failed to read download.sh: %v", err)
+	}
+	if !strings.Contains(string(script), `VERSION="`+compare.JavaMergerVersion+`"`) {
+		t.Errorf("Expected download.sh to fetch version %s", compare.JavaMergerVersion)
+	}
+}
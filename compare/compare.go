@@ -5,7 +5,9 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -44,6 +46,8 @@ type Difference struct {
 	Location string // Row key or line number
 	Expected string
 	Actual   string
+	// Columns lists the columns whose values differ (RowDifferent only)
+	Columns []string
 }
 
 // DiffResult represents differences between two GTFS files
@@ -52,9 +56,80 @@ type DiffResult struct {
 	Differences []Difference
 }
 
+// headerLocation is the Difference.Location used for header differences
+const headerLocation = "header"
+
+// Counts returns the number of removed (RowMissing), added (RowExtra)
+// and changed (RowDifferent) rows in the result. A header difference is
+// not counted as a row; see HeaderChanged.
+func (r DiffResult) Counts() (removed, added, changed int) {
+	for _, d := range r.Differences {
+		switch d.Type {
+		case RowMissing:
+			removed++
+		case RowExtra:
+			added++
+		case RowDifferent:
+			if d.Location != headerLocation {
+				changed++
+			}
+		}
+	}
+	return removed, added, changed
+}
+
+// HeaderChanged reports whether the two files have different column sets
+func (r DiffResult) HeaderChanged() bool {
+	for _, d := range r.Differences {
+		if d.Type == RowDifferent && d.Location == headerLocation {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangedColumns returns the sorted set of columns that differ across all
+// changed rows in the result
+func (r DiffResult) ChangedColumns() []string {
+	seen := make(map[string]bool)
+	var cols []string
+	for _, d := range r.Differences {
+		for _, col := range d.Columns {
+			if !seen[col] {
+				seen[col] = true
+				cols = append(cols, col)
+			}
+		}
+	}
+	sort.Strings(cols)
+	return cols
+}
+
+// DiffOptions configures DiffGTFS and DiffCSV
+type DiffOptions struct {
+	// IDPrefixMapping rewrites ID prefixes before rows are matched, so a
+	// feed whose IDs were prefixed during a merge (e.g. "b-") can be diffed
+	// against one that was not. Keys are prefixes and values their
+	// replacements; {"b-": ""} strips "b-". The mapping applies to both
+	// sides, to columns ending in "_id" and to parent_station. When several
+	// prefixes match, the longest wins.
+	IDPrefixMapping map[string]string
+}
+
 // CompareGTFS compares two GTFS outputs and returns differences
 // Both paths should be zip files or directories containing GTFS data
 func CompareGTFS(expectedPath, actualPath string) ([]DiffResult, error) {
+	return DiffGTFS(expectedPath, actualPath, DiffOptions{})
+}
+
+// DiffGTFS compares two GTFS feeds file by file. Rows are matched by each
+// file's primary key (see PrimaryKey) rather than by position, and values
+// are compared by column name, so column order does not matter. Rows only
+// in expectedPath are reported as RowMissing, rows only in actualPath as
+// RowExtra, and matched rows with differing values as RowDifferent with the
+// differing column names in Difference.Columns. Results are sorted by file
+// name and row key.
+func DiffGTFS(expectedPath, actualPath string, opts DiffOptions) ([]DiffResult, error) {
 	// Read both GTFS archives
 	expectedFiles, err := readGTFSFiles(expectedPath)
 	if err != nil {
@@ -97,7 +172,7 @@ func CompareGTFS(expectedPath, actualPath string) ([]DiffResult, error) {
 		}
 
 		// Compare normalized content
-		diff, err := CompareCSV(filename, normalizedExpected, normalizedActual)
+		diff, err := DiffCSV(filename, normalizedExpected, normalizedActual, opts)
 		if err != nil {
 			return nil, fmt.Errorf("comparing %s: %w", filename, err)
 		}
@@ -122,11 +197,21 @@ func CompareGTFS(expectedPath, actualPath string) ([]DiffResult, error) {
 		}
 	}
 
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].File < results[j].File
+	})
+
 	return results, nil
 }
 
 // CompareCSV compares two normalized CSV contents
 func CompareCSV(filename string, expected, actual []byte) (*DiffResult, error) {
+	return DiffCSV(filename, expected, actual, DiffOptions{})
+}
+
+// DiffCSV compares two normalized CSV contents by primary key.
+// See DiffGTFS for how rows are matched and reported.
+func DiffCSV(filename string, expected, actual []byte, opts DiffOptions) (*DiffResult, error) {
 	expectedLines := splitLines(expected)
 	actualLines := splitLines(actual)
 
@@ -137,50 +222,59 @@ func CompareCSV(filename string, expected, actual []byte) (*DiffResult, error) {
 	var diffs []Difference
 
 	// Compare headers
-	if len(expectedLines) > 0 && len(actualLines) > 0 {
-		if expectedLines[0] != actualLines[0] {
-			diffs = append(diffs, Difference{
-				Type:     RowDifferent,
-				Location: "header",
-				Expected: expectedLines[0],
-				Actual:   actualLines[0],
-			})
-		}
+	var expectedHeader, actualHeader string
+	if len(expectedLines) > 0 {
+		expectedHeader = expectedLines[0]
+		expectedLines = expectedLines[1:]
+	}
+	if len(actualLines) > 0 {
+		actualHeader = actualLines[0]
+		actualLines = actualLines[1:]
+	}
+	if expectedHeader != actualHeader {
+		diffs = append(diffs, Difference{
+			Type:     RowDifferent,
+			Location: headerLocation,
+			Expected: expectedHeader,
+			Actual:   actualHeader,
+		})
 	}
 
 	// Build maps of rows by their primary key
 	primaryKey := PrimaryKey(filename)
-	expectedRows := buildRowMap(expectedLines[1:], expectedLines[0], primaryKey)
-	actualRows := buildRowMap(actualLines[1:], actualLines[0], primaryKey)
+	expectedRows := buildRowMap(expectedLines, expectedHeader, primaryKey, opts)
+	actualRows := buildRowMap(actualLines, actualHeader, primaryKey, opts)
 
 	// Find missing and different rows
-	for key, expectedRow := range expectedRows {
+	for _, key := range sortedRowKeys(expectedRows) {
+		expectedRow := expectedRows[key]
 		actualRow, exists := actualRows[key]
 		if !exists {
 			diffs = append(diffs, Difference{
 				Type:     RowMissing,
 				Location: key,
-				Expected: expectedRow,
+				Expected: expectedRow.line,
 				Actual:   "",
 			})
-		} else if expectedRow != actualRow {
+		} else if cols := changedColumns(expectedRow.values, actualRow.values); len(cols) > 0 {
 			diffs = append(diffs, Difference{
 				Type:     RowDifferent,
 				Location: key,
-				Expected: expectedRow,
-				Actual:   actualRow,
+				Expected: expectedRow.line,
+				Actual:   actualRow.line,
+				Columns:  cols,
 			})
 		}
 	}
 
 	// Find extra rows
-	for key, actualRow := range actualRows {
+	for _, key := range sortedRowKeys(actualRows) {
 		if _, exists := expectedRows[key]; !exists {
 			diffs = append(diffs, Difference{
 				Type:     RowExtra,
 				Location: key,
 				Expected: "",
-				Actual:   actualRow,
+				Actual:   actualRows[key].line,
 			})
 		}
 	}
@@ -195,6 +289,24 @@ func CompareCSV(filename string, expected, actual []byte) (*DiffResult, error) {
 	}, nil
 }
 
+// changedColumns returns the sorted names of columns whose values differ.
+// A column absent from one side compares as empty.
+func changedColumns(expected, actual map[string]string) []string {
+	var cols []string
+	for col, value := range expected {
+		if actual[col] != value {
+			cols = append(cols, col)
+		}
+	}
+	for col, value := range actual {
+		if _, ok := expected[col]; !ok && value != "" {
+			cols = append(cols, col)
+		}
+	}
+	sort.Strings(cols)
+	return cols
+}
+
 // readGTFSFiles reads all CSV files from a GTFS zip or directory
 func readGTFSFiles(path string) (map[string][]byte, error) {
 	// Try to open as zip file
@@ -204,10 +316,36 @@ func readGTFSFiles(path string) (map[string][]byte, error) {
 		return readFromZip(r)
 	}
 
-	// TODO: Add directory reading support if needed
+	info, statErr := os.Stat(path)
+	if statErr == nil && info.IsDir() {
+		return readFromDir(path)
+	}
+
 	return nil, fmt.Errorf("could not read GTFS from %s: %w", path, err)
 }
 
+// readFromDir reads all CSV files from a GTFS directory
+func readFromDir(dir string) (map[string][]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".txt") {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", entry.Name(), err)
+		}
+		files[entry.Name()] = content
+	}
+
+	return files, nil
+}
+
 // readFromZip reads all CSV files from a zip archive
 func readFromZip(r *zip.ReadCloser) (map[string][]byte, error) {
 	files := make(map[string][]byte)
@@ -255,49 +393,76 @@ func splitLines(content []byte) []string {
 	return lines
 }
 
-// buildRowMap builds a map of rows keyed by their primary key values
-func buildRowMap(rows []string, header string, primaryKey []string) map[string]string {
-	result := make(map[string]string)
-
-	if len(primaryKey) == 0 {
-		// No primary key, use line number
-		for i, row := range rows {
-			result[fmt.Sprintf("line_%d", i+1)] = row
-		}
-		return result
-	}
+// csvRow is a parsed row with its original line and values keyed by column
+type csvRow struct {
+	line   string
+	values map[string]string
+}
 
-	// Parse header to find primary key column indices
+// buildRowMap builds a map of rows keyed by their primary key values
+func buildRowMap(rows []string, header string, primaryKey []string, opts DiffOptions) map[string]csvRow {
+	result := make(map[string]csvRow)
 	headerCols := parseCSVLine(header)
-	keyIndices := make([]int, len(primaryKey))
-	for i, key := range primaryKey {
-		keyIndices[i] = -1
+
+	for i, row := range rows {
+		cols := parseCSVLine(row)
+		values := make(map[string]string, len(headerCols))
 		for j, col := range headerCols {
-			if col == key {
-				keyIndices[i] = j
-				break
+			if j < len(cols) {
+				values[col] = opts.normalizeID(col, cols[j])
 			}
 		}
-	}
 
-	// Build map using primary key
-	for _, row := range rows {
-		cols := parseCSVLine(row)
-		var keyParts []string
-		for _, idx := range keyIndices {
-			if idx >= 0 && idx < len(cols) {
-				keyParts = append(keyParts, cols[idx])
-			} else {
-				keyParts = append(keyParts, "")
+		var key string
+		if len(primaryKey) == 0 {
+			// No primary key, use line number
+			key = fmt.Sprintf("line_%d", i+1)
+		} else {
+			keyParts := make([]string, len(primaryKey))
+			for k, col := range primaryKey {
+				keyParts[k] = values[col]
 			}
+			key = strings.Join(keyParts, "|")
 		}
-		key := strings.Join(keyParts, "|")
-		result[key] = row
+		result[key] = csvRow{line: row, values: values}
 	}
 
 	return result
 }
 
+// sortedRowKeys returns the keys of rows in sorted order
+func sortedRowKeys(rows map[string]csvRow) []string {
+	keys := make([]string, 0, len(rows))
+	for key := range rows {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// normalizeID applies IDPrefixMapping to value if column holds an ID
+func (o DiffOptions) normalizeID(column, value string) string {
+	if len(o.IDPrefixMapping) == 0 || value == "" {
+		return value
+	}
+	if !strings.HasSuffix(column, "_id") && column != "parent_station" {
+		return value
+	}
+
+	best := ""
+	found := false
+	for prefix := range o.IDPrefixMapping {
+		if strings.HasPrefix(value, prefix) && (!found || len(prefix) > len(best)) {
+			best = prefix
+			found = true
+		}
+	}
+	if !found {
+		return value
+	}
+	return o.IDPrefixMapping[best] + strings.TrimPrefix(value, best)
+}
+
 // parseCSVLine parses a single CSV line into fields
 // This is a simple parser that handles basic quoting
 func parseCSVLine(line string) []string {
@@ -350,7 +515,11 @@ func FormatDiffStyleOutput(results []DiffResult) string {
 			case RowExtra:
 				buf.WriteString(fmt.Sprintf("+ [%s] %s\n", diff.Location, diff.Actual))
 			case RowDifferent:
-				buf.WriteString(fmt.Sprintf("! [%s]\n", diff.Location))
+				if len(diff.Columns) > 0 {
+					buf.WriteString(fmt.Sprintf("! [%s] (%s)\n", diff.Location, strings.Join(diff.Columns, ", ")))
+				} else {
+					buf.WriteString(fmt.Sprintf("! [%s]\n", diff.Location))
+				}
 				buf.WriteString(fmt.Sprintf("-   %s\n", diff.Expected))
 				buf.WriteString(fmt.Sprintf("+   %s\n", diff.Actual))
 			case ColumnMissing:
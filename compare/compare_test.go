@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"testing"
 
 	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
@@ -120,7 +121,7 @@ func TestCompare_SimpleMergeWithPrefixing(t *testing.T) {
 	javaOutput := filepath.Join(tmpDir, "java_merged.zip")
 	goOutput := filepath.Join(tmpDir, "go_merged.zip")
 
-	// When: merged with detection=none (forces prefixing)
+	// When: merged with detection=none (colliding IDs are prefixed)
 	err := javaMerger.MergeQuiet([]string{inputA, inputOverlap}, javaOutput, WithDuplicateDetection("none"))
 	if err != nil {
 		t.Fatalf("Java merge failed: %v", err)
@@ -488,7 +489,7 @@ func TestDetectionModes_JavaIdentityVsNone(t *testing.T) {
 }
 
 func TestDetectionModes_GoMatchesJavaNone(t *testing.T) {
-	// Go currently implements DetectionNone behavior
+	// Go defaults to DetectionNone
 	// This test verifies Go's output matches Java with detection=none
 	jarPath := skipIfNoJava(t)
 
@@ -508,7 +509,7 @@ func TestDetectionModes_GoMatchesJavaNone(t *testing.T) {
 		t.Fatalf("Java merge failed: %v", err)
 	}
 
-	// Go (currently always uses none detection behavior)
+	// Go (detection=none by default)
 	err = goMerger.MergeFiles([]string{inputA, inputB}, goOutput)
 	if err != nil {
 		t.Fatalf("Go merge failed: %v", err)
@@ -543,6 +544,64 @@ func TestDetectionModes_GoMatchesJavaNone(t *testing.T) {
 	}
 }
 
+func TestDetectionModes_NoneIDShapesMatchJava(t *testing.T) {
+	// With detection=none both tools prefix only the IDs that collide with
+	// one already merged, so the merged feeds should have the same IDs
+	jarPath := skipIfNoJava(t)
+
+	javaMerger := NewJavaMerger(jarPath)
+	goMerger := merge.New(merge.WithDefaultDetection(strategy.DetectionNone))
+
+	testCases := []struct {
+		name   string
+		inputs []string
+	}{
+		{name: "simple_a_overlap", inputs: []string{"../testdata/simple_a", "../testdata/overlap"}},
+		{name: "overlap_simple_a", inputs: []string{"../testdata/overlap", "../testdata/simple_a"}},
+		{name: "three_with_overlap", inputs: []string{"../testdata/simple_a", "../testdata/overlap", "../testdata/minimal"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			javaOutput := filepath.Join(tmpDir, "java_none.zip")
+			goOutput := filepath.Join(tmpDir, "go_none.zip")
+
+			if err := javaMerger.MergeQuiet(tc.inputs, javaOutput, WithDuplicateDetection("none")); err != nil {
+				t.Fatalf("Java merge failed: %v", err)
+			}
+			if err := goMerger.MergeFiles(tc.inputs, goOutput); err != nil {
+				t.Fatalf("Go merge failed: %v", err)
+			}
+
+			javaFeed, err := gtfs.ReadFromPath(javaOutput)
+			if err != nil {
+				t.Fatalf("Failed to read Java output: %v", err)
+			}
+			goFeed, err := gtfs.ReadFromPath(goOutput)
+			if err != nil {
+				t.Fatalf("Failed to read Go output: %v", err)
+			}
+
+			compareIDs(t, "agency_id", javaFeed.AgencyOrder, goFeed.AgencyOrder)
+			compareIDs(t, "stop_id", javaFeed.StopOrder, goFeed.StopOrder)
+			compareIDs(t, "route_id", javaFeed.RouteOrder, goFeed.RouteOrder)
+			compareIDs(t, "trip_id", javaFeed.TripOrder, goFeed.TripOrder)
+			compareIDs(t, "service_id", javaFeed.CalendarOrder, goFeed.CalendarOrder)
+		})
+	}
+}
+
+// compareIDs reports the IDs of one kind that only one tool's output has
+func compareIDs[K ~string](t *testing.T, kind string, java, golang []K) {
+	t.Helper()
+	javaIDs := slices.Sorted(slices.Values(java))
+	goIDs := slices.Sorted(slices.Values(golang))
+	if !slices.Equal(javaIDs, goIDs) {
+		t.Errorf("%s mismatch: Java=%v, Go=%v", kind, javaIDs, goIDs)
+	}
+}
+
 func TestDetectionModes_ThreeFeedMerge(t *testing.T) {
 	// Test merging three feeds with different detection modes
 	jarPath := skipIfNoJava(t)
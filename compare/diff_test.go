@@ -0,0 +1,145 @@
+package compare
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffCSVReportsChangedColumns(t *testing.T) {
+	// Given: the same stops with a renamed stop, a removed stop and an added stop
+	expected := `stop_id,stop_name,stop_lat,stop_lon
+S1,Stop A,47.500000,-122.100000
+S2,Stop B,47.550000,-122.200000
+`
+	actual := `stop_id,stop_name,stop_lat,stop_lon
+S1,Stop A Renamed,47.500000,-122.100000
+S3,Stop C,47.600000,-122.300000
+`
+	// When: diffed
+	result, err := DiffCSV("stops.txt", []byte(expected), []byte(actual), DiffOptions{})
+	if err != nil {
+		t.Fatalf("DiffCSV failed: %v", err)
+	}
+
+	// Then: one row of each kind is reported, keyed by stop_id
+	removed, added, changed := result.Counts()
+	if removed != 1 || added != 1 || changed != 1 {
+		t.Fatalf("Expected 1 removed, 1 added, 1 changed; got %d, %d, %d", removed, added, changed)
+	}
+	for _, d := range result.Differences {
+		switch d.Type {
+		case RowDifferent:
+			if d.Location != "S1" || !reflect.DeepEqual(d.Columns, []string{"stop_name"}) {
+				t.Errorf("Expected S1 to differ in stop_name, got %s %v", d.Location, d.Columns)
+			}
+		case RowMissing:
+			if d.Location != "S2" {
+				t.Errorf("Expected S2 to be removed, got %s", d.Location)
+			}
+		case RowExtra:
+			if d.Location != "S3" {
+				t.Errorf("Expected S3 to be added, got %s", d.Location)
+			}
+		}
+	}
+}
+
+func TestDiffCSVCompositeKey(t *testing.T) {
+	// Given: stop_times where only the second stop's time changed
+	expected := `trip_id,arrival_time,departure_time,stop_id,stop_sequence
+T1,08:00:00,08:00:00,S1,1
+T1,08:10:00,08:10:00,S2,2
+`
+	actual := `trip_id,arrival_time,departure_time,stop_id,stop_sequence
+T1,08:00:00,08:00:00,S1,1
+T1,08:12:00,08:12:00,S2,2
+`
+	// When: diffed
+	result, err := DiffCSV("stop_times.txt", []byte(expected), []byte(actual), DiffOptions{})
+	if err != nil {
+		t.Fatalf("DiffCSV failed: %v", err)
+	}
+
+	// Then: the change is attributed to (trip_id, stop_sequence)
+	if result == nil || len(result.Differences) != 1 {
+		t.Fatalf("Expected 1 difference, got %+v", result)
+	}
+	d := result.Differences[0]
+	if d.Location != "T1|2" {
+		t.Errorf("Expected location T1|2, got %q", d.Location)
+	}
+	if !reflect.DeepEqual(d.Columns, []string{"arrival_time", "departure_time"}) {
+		t.Errorf("Expected arrival_time and departure_time to differ, got %v", d.Columns)
+	}
+}
+
+func TestDiffCSVIDPrefixMapping(t *testing.T) {
+	// Given: the actual feed carries merge prefixes on its IDs
+	expected := `route_id,service_id,trip_id
+R1,WEEK,T1
+`
+	actual := `route_id,service_id,trip_id
+b-R1,b-WEEK,b-T1
+`
+	// When: diffed with the prefix stripped
+	opts := DiffOptions{IDPrefixMapping: map[string]string{"b-": ""}}
+	result, err := DiffCSV("trips.txt", []byte(expected), []byte(actual), opts)
+	if err != nil {
+		t.Fatalf("DiffCSV failed: %v", err)
+	}
+
+	// Then: no differences are reported
+	if result != nil {
+		t.Errorf("Expected no differences, got %+v", result.Differences)
+	}
+}
+
+func TestDiffGTFSIgnoresColumnOrder(t *testing.T) {
+	// Given: identical feeds read from directories
+	// When: diffed against themselves
+	results, err := DiffGTFS("../testdata/simple_a", "../testdata/simple_a", DiffOptions{})
+	if err != nil {
+		t.Fatalf("DiffGTFS failed: %v", err)
+	}
+
+	// Then: there are no differences
+	if len(results) != 0 {
+		t.Errorf("Expected no differences, got:\n%s", FormatDiffStyleOutput(results))
+	}
+
+	// And: reordered columns normalize to the same content
+	a, err := NormalizeCSV("stops.txt", []byte("stop_id,stop_name,stop_lat,stop_lon\nS1,A,1,2\n"))
+	if err != nil {
+		t.Fatalf("NormalizeCSV failed: %v", err)
+	}
+	b, err := NormalizeCSV("stops.txt", []byte("stop_lon,stop_lat,stop_name,stop_id\n2,1,A,S1\n"))
+	if err != nil {
+		t.Fatalf("NormalizeCSV failed: %v", err)
+	}
+	if result, _ := DiffCSV("stops.txt", a, b, DiffOptions{}); result != nil {
+		t.Errorf("Expected no differences for reordered columns, got %+v", result.Differences)
+	}
+}
+
+func TestDiffGTFSDifferentFeeds(t *testing.T) {
+	// Given: two unrelated feeds
+	// When: diffed
+	results, err := DiffGTFS("../testdata/simple_a", "../testdata/simple_b", DiffOptions{})
+	if err != nil {
+		t.Fatalf("DiffGTFS failed: %v", err)
+	}
+
+	// Then: differences are reported in file order
+	if len(results) == 0 {
+		t.Fatal("Expected differences between simple_a and simple_b")
+	}
+	for i := 1; i < len(results); i++ {
+		if results[i-1].File > results[i].File {
+			t.Errorf("Expected results sorted by file, got %s before %s", results[i-1].File, results[i].File)
+		}
+	}
+	if out := FormatDiffStyleOutput(results); !strings.Contains(out, "stops.txt") {
+		t.Errorf("Expected stops.txt in diff output, got:\n%s", out)
+	}
+}
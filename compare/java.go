@@ -1,5 +1,6 @@
-// Package compare provides utilities for comparing GTFS outputs between
-// the Java onebusaway-gtfs-merge tool and the Go implementation.
+// Package compare provides row-level diffing of GTFS feeds, used both to
+// compare outputs between the Java onebusaway-gtfs-merge tool and the Go
+// implementation and to diff merged feeds between releases (see DiffGTFS).
 package compare
 
 import (
@@ -10,6 +11,11 @@ import (
 	"runtime"
 )
 
+// JavaMergerVersion is the onebusaway-gtfs-merge-cli version the Java
+// comparison tests were last validated against. It must match the JAR
+// fetched by testdata/java/download.sh.
+const JavaMergerVersion = "11.2.0"
+
 // JavaMerger invokes the onebusaway-gtfs-merge-cli Java tool
 type JavaMerger struct {
 	JARPath   string
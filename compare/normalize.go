@@ -24,7 +24,7 @@ var gtfsColumnOrders = map[string][]string{
 	"routes.txt": {
 		"route_id", "agency_id", "route_short_name", "route_long_name", "route_desc",
 		"route_type", "route_url", "route_color", "route_text_color", "route_sort_order",
-		"continuous_pickup", "continuous_drop_off",
+		"continuous_pickup", "continuous_drop_off", "network_id",
 	},
 	"trips.txt": {
 		"route_id", "service_id", "trip_id", "trip_headsign", "trip_short_name",
@@ -72,25 +72,60 @@ var gtfsColumnOrders = map[string][]string{
 		"length", "traversal_time", "stair_count", "max_slope", "min_width",
 		"signposted_as", "reversed_signposted_as",
 	},
+	"networks.txt": {
+		"network_id", "network_name",
+	},
+	"route_networks.txt": {
+		"network_id", "route_id",
+	},
+	"fare_leg_rules.txt": {
+		"leg_group_id", "network_id", "from_area_id", "to_area_id",
+		"from_timeframe_group_id", "to_timeframe_group_id", "fare_product_id", "rule_priority",
+	},
+	"fare_transfer_rules.txt": {
+		"from_leg_group_id", "to_leg_group_id", "transfer_count", "duration_limit",
+		"duration_limit_type", "fare_transfer_type", "fare_product_id",
+	},
+	"fare_products.txt": {
+		"fare_product_id", "fare_product_name", "rider_category_id", "fare_media_id",
+		"amount", "currency",
+	},
+	"timeframes.txt": {
+		"timeframe_group_id", "start_time", "end_time", "service_id",
+	},
+	"fare_media.txt": {
+		"fare_media_id", "fare_media_name", "fare_media_type",
+	},
+	"rider_categories.txt": {
+		"rider_category_id", "rider_category_name", "is_default_fare_category", "eligibility_url",
+	},
 }
 
 // gtfsPrimaryKeys defines the primary key columns for each GTFS file
 var gtfsPrimaryKeys = map[string][]string{
-	"agency.txt":          {"agency_id"},
-	"stops.txt":           {"stop_id"},
-	"routes.txt":          {"route_id"},
-	"trips.txt":           {"trip_id"},
-	"stop_times.txt":      {"trip_id", "stop_sequence"},
-	"calendar.txt":        {"service_id"},
-	"calendar_dates.txt":  {"service_id", "date"},
-	"shapes.txt":          {"shape_id", "shape_pt_sequence"},
-	"frequencies.txt":     {"trip_id", "start_time"},
-	"transfers.txt":       {"from_stop_id", "to_stop_id"},
-	"fare_attributes.txt": {"fare_id"},
-	"fare_rules.txt":      {"fare_id", "route_id", "origin_id", "destination_id"},
-	"feed_info.txt":       {"feed_publisher_name"},
-	"areas.txt":           {"area_id"},
-	"pathways.txt":        {"pathway_id"},
+	"agency.txt":              {"agency_id"},
+	"stops.txt":               {"stop_id"},
+	"routes.txt":              {"route_id"},
+	"trips.txt":               {"trip_id"},
+	"stop_times.txt":          {"trip_id", "stop_sequence"},
+	"calendar.txt":            {"service_id"},
+	"calendar_dates.txt":      {"service_id", "date"},
+	"shapes.txt":              {"shape_id", "shape_pt_sequence"},
+	"frequencies.txt":         {"trip_id", "start_time"},
+	"transfers.txt":           {"from_stop_id", "to_stop_id"},
+	"fare_attributes.txt":     {"fare_id"},
+	"fare_rules.txt":          {"fare_id", "route_id", "origin_id", "destination_id"},
+	"feed_info.txt":           {"feed_publisher_name"},
+	"areas.txt":               {"area_id"},
+	"pathways.txt":            {"pathway_id"},
+	"networks.txt":            {"network_id"},
+	"route_networks.txt":      {"route_id"},
+	"fare_leg_rules.txt":      {"network_id", "from_area_id", "to_area_id", "from_timeframe_group_id", "to_timeframe_group_id", "fare_product_id"},
+	"fare_transfer_rules.txt": {"from_leg_group_id", "to_leg_group_id", "fare_product_id", "transfer_count", "duration_limit"},
+	"fare_products.txt":       {"fare_product_id", "rider_category_id", "fare_media_id"},
+	"timeframes.txt":          {"timeframe_group_id", "start_time", "end_time", "service_id"},
+	"fare_media.txt":          {"fare_media_id"},
+	"rider_categories.txt":    {"rider_category_id"},
 }
 
 // floatColumns lists columns that should have normalized float precision
@@ -104,6 +139,7 @@ var floatColumns = map[string]bool{
 	"max_slope":           true,
 	"min_width":           true,
 	"price":               true,
+	"amount":              true,
 	"youth_price":         true,
 	"senior_price":        true,
 }
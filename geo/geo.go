@@ -0,0 +1,83 @@
+// Package geo provides the great-circle distance, bounding box and spatial
+// index helpers used by fuzzy stop matching, for callers that need the same
+// geometry (e.g. transfer generation or bounding-box filters).
+package geo
+
+import "math"
+
+// EarthRadiusMeters is the mean radius of the Earth in meters
+const EarthRadiusMeters = 6371000.0
+
+// metersPerDegreeLat is the length of one degree of latitude
+const metersPerDegreeLat = EarthRadiusMeters * math.Pi / 180
+
+// HaversineMeters calculates the great-circle distance in meters between two
+// points on the Earth's surface using the Haversine formula
+func HaversineMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	lat1Rad := lat1 * math.Pi / 180
+	lat2Rad := lat2 * math.Pi / 180
+	dLat := lat2Rad - lat1Rad
+	dLon := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1Rad)*math.Cos(lat2Rad)*
+			math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return EarthRadiusMeters * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// BoundingBox is a latitude/longitude rectangle, in degrees. Boxes do not
+// wrap across the antimeridian.
+type BoundingBox struct {
+	MinLat, MinLon float64
+	MaxLat, MaxLon float64
+}
+
+// Contains reports whether the point lies within the box, edges included
+func (b BoundingBox) Contains(lat, lon float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lon >= b.MinLon && lon <= b.MaxLon
+}
+
+// BoundingBoxAround returns a box containing every point within
+// radiusMeters of (lat, lon). Near the poles the box spans all longitudes.
+// Since boxes do not wrap, the part of a circle crossing the antimeridian
+// is cut off; see BoundingBoxesAround.
+func BoundingBoxAround(lat, lon, radiusMeters float64) BoundingBox {
+	dLat := radiusMeters / metersPerDegreeLat
+	box := BoundingBox{
+		MinLat: math.Max(lat-dLat, -90),
+		MaxLat: math.Min(lat+dLat, 90),
+		MinLon: -180,
+		MaxLon: 180,
+	}
+
+	// The box is widest in longitude at whichever edge is nearer a pole
+	maxAbsLat := math.Max(math.Abs(box.MinLat), math.Abs(box.MaxLat))
+	if cos := math.Cos(maxAbsLat * math.Pi / 180); cos > 1e-6 {
+		if dLon := dLat / cos; dLon < 180 {
+			box.MinLon = math.Max(lon-dLon, -180)
+			box.MaxLon = math.Min(lon+dLon, 180)
+		}
+	}
+	return box
+}
+
+// BoundingBoxesAround returns the boxes that together contain every point
+// within radiusMeters of (lat, lon): BoundingBoxAround's box and, if the
+// circle crosses the antimeridian, a second box for the part on the other
+// side of it
+func BoundingBoxesAround(lat, lon, radiusMeters float64) []BoundingBox {
+	box := BoundingBoxAround(lat, lon, radiusMeters)
+	wrapped := BoundingBox{MinLat: box.MinLat, MaxLat: box.MaxLat, MinLon: -180, MaxLon: 180}
+	switch {
+	case box.MinLon == -180 && box.MaxLon == 180:
+		return []BoundingBox{box}
+	case box.MinLon == -180:
+		wrapped.MinLon = 2*lon - box.MaxLon + 360
+	case box.MaxLon == 180:
+		wrapped.MaxLon = 2*lon - box.MinLon - 360
+	default:
+		return []BoundingBox{box}
+	}
+	return []BoundingBox{box, wrapped}
+}
@@ -0,0 +1,95 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHaversineMeters(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		expected, tolerance    float64
+	}{
+		{"same point", 47.6062, -122.3321, 47.6062, -122.3321, 0, 1e-9},
+		{"0.01 degree of latitude", 47.0, -122.0, 47.01, -122.0, 1111.95, 0.01},
+		{"Seattle to Portland", 47.6062, -122.3321, 45.5152, -122.6784, 233_600, 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HaversineMeters(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+			if math.Abs(got-tt.expected) > tt.tolerance {
+				t.Errorf("Expected %.2fm, got %.2fm", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestBoundingBoxContains(t *testing.T) {
+	box := BoundingBox{MinLat: 47, MinLon: -123, MaxLat: 48, MaxLon: -122}
+	tests := []struct {
+		lat, lon float64
+		expected bool
+	}{
+		{47.5, -122.5, true},
+		{47, -123, true}, // edges are included
+		{48.1, -122.5, false},
+		{47.5, -121.9, false},
+	}
+	for _, tt := range tests {
+		if got := box.Contains(tt.lat, tt.lon); got != tt.expected {
+			t.Errorf("Contains(%v, %v): expected %v, got %v", tt.lat, tt.lon, tt.expected, got)
+		}
+	}
+}
+
+func TestBoundingBoxAround(t *testing.T) {
+	// Given: a 500m radius around a point
+	lat, lon := 47.6062, -122.3321
+	box := BoundingBoxAround(lat, lon, 500)
+
+	// Then: points 499m away in each direction are inside, and 0.01° (about
+	// 750m of longitude here) is outside
+	for _, p := range [][2]float64{
+		{lat + 499/metersPerDegreeLat, lon},
+		{lat - 499/metersPerDegreeLat, lon},
+		{lat, lon + 499/(metersPerDegreeLat*math.Cos(lat*math.Pi/180))},
+		{lat, lon - 499/(metersPerDegreeLat*math.Cos(lat*math.Pi/180))},
+	} {
+		if !box.Contains(p[0], p[1]) {
+			t.Errorf("Expected %v in %+v", p, box)
+		}
+	}
+	if box.Contains(lat, lon+0.01) {
+		t.Errorf("Expected 0.01° east to be outside %+v", box)
+	}
+
+	// Near a pole the box spans every longitude
+	if polar := BoundingBoxAround(89.999, 0, 500); polar.MinLon != -180 || polar.MaxLon != 180 || polar.MaxLat != 90 {
+		t.Errorf("Expected a polar box to span all longitudes, got %+v", polar)
+	}
+}
+
+func TestBoundingBoxesAround(t *testing.T) {
+	// Given: a 500m radius around a point just west of the antimeridian
+	lat, lon := -17.7, 179.999
+	boxes := BoundingBoxesAround(lat, lon, 500)
+
+	// Then: a second box covers the part east of it, and a point 300m east
+	// across the antimeridian is in one of them
+	if len(boxes) != 2 {
+		t.Fatalf("Expected 2 boxes, got %+v", boxes)
+	}
+	if boxes[1].MinLon != -180 || boxes[1].MaxLon <= -180 || boxes[1].MaxLon > -179.99 {
+		t.Errorf("Expected the second box to start at -180, got %+v", boxes[1])
+	}
+	eastLon := lon + 300/(metersPerDegreeLat*math.Cos(lat*math.Pi/180)) - 360
+	if boxes[0].Contains(lat, eastLon) || !boxes[1].Contains(lat, eastLon) {
+		t.Errorf("Expected %v in the second box only, got %+v", eastLon, boxes)
+	}
+
+	// And: a circle clear of the antimeridian has one box
+	if boxes := BoundingBoxesAround(47.6062, -122.3321, 500); len(boxes) != 1 {
+		t.Errorf("Expected 1 box, got %+v", boxes)
+	}
+}
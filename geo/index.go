@@ -0,0 +1,101 @@
+package geo
+
+import (
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// stopIndexCellDegrees is the side of a StopIndex grid cell, about 550m of
+// latitude, so a fuzzy stop search (up to 500m) touches few cells
+const stopIndexCellDegrees = 0.005
+
+// StopIndex is a grid index of stops by location, for finding the stops near
+// a point without scanning them all
+type StopIndex struct {
+	cells map[gridCell][]indexedStop
+	n     int
+}
+
+// gridCell identifies a StopIndex cell by its row and column
+type gridCell struct {
+	lat, lon int
+}
+
+// indexedStop is a stop with its position in the order stops were added
+type indexedStop struct {
+	seq  int
+	stop *gtfs.Stop
+}
+
+// NewStopIndex returns an index of stops
+func NewStopIndex(stops []*gtfs.Stop) *StopIndex {
+	x := &StopIndex{cells: make(map[gridCell][]indexedStop)}
+	for _, stop := range stops {
+		x.Add(stop)
+	}
+	return x
+}
+
+// Add adds a stop to the index
+func (x *StopIndex) Add(stop *gtfs.Stop) {
+	c := cellOf(stop.Lat, stop.Lon)
+	x.cells[c] = append(x.cells[c], indexedStop{seq: x.n, stop: stop})
+	x.n++
+}
+
+// Len returns the number of stops in the index
+func (x *StopIndex) Len() int {
+	return x.n
+}
+
+// Nearby returns the stops within radiusMeters of (lat, lon), in the order
+// they were added to the index. A radius crossing the antimeridian finds
+// the stops on both sides of it.
+func (x *StopIndex) Nearby(lat, lon, radiusMeters float64) []*gtfs.Stop {
+	var found []indexedStop
+	visit := func(stops []indexedStop) {
+		for _, s := range stops {
+			if HaversineMeters(lat, lon, s.stop.Lat, s.stop.Lon) <= radiusMeters {
+				found = append(found, s)
+			}
+		}
+	}
+	for _, box := range BoundingBoxesAround(lat, lon, radiusMeters) {
+		lo, hi := cellOf(box.MinLat, box.MinLon), cellOf(box.MaxLat, box.MaxLon)
+
+		// For large radii it is cheaper to visit every occupied cell
+		if (hi.lat-lo.lat+1)*(hi.lon-lo.lon+1) > len(x.cells) {
+			for c, stops := range x.cells {
+				if c.lat >= lo.lat && c.lat <= hi.lat && c.lon >= lo.lon && c.lon <= hi.lon {
+					visit(stops)
+				}
+			}
+		} else {
+			for cLat := lo.lat; cLat <= hi.lat; cLat++ {
+				for cLon := lo.lon; cLon <= hi.lon; cLon++ {
+					visit(x.cells[gridCell{cLat, cLon}])
+				}
+			}
+		}
+	}
+
+	// The two boxes around the antimeridian may share a cell
+	sort.Slice(found, func(i, j int) bool { return found[i].seq < found[j].seq })
+	found = slices.CompactFunc(found, func(a, b indexedStop) bool { return a.seq == b.seq })
+	stops := make([]*gtfs.Stop, len(found))
+	for i, s := range found {
+		stops[i] = s.stop
+	}
+	return stops
+}
+
+// cellOf returns the cell containing a point
+func cellOf(lat, lon float64) gridCell {
+	return gridCell{
+		lat: int(math.Floor(lat / stopIndexCellDegrees)),
+		lon: int(math.Floor(lon / stopIndexCellDegrees)),
+	}
+}
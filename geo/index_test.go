@@ -0,0 +1,93 @@
+package geo
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+func TestStopIndexNearby(t *testing.T) {
+	// Given: stops about 0m, 70m, 400m and 2km north of a point, added out
+	// of distance order, plus one on the far side of a cell boundary
+	lat, lon := 47.6099, -122.3331
+	stops := []*gtfs.Stop{
+		{ID: "far", Lat: lat + 0.018, Lon: lon},
+		{ID: "400m", Lat: lat + 0.0036, Lon: lon},
+		{ID: "here", Lat: lat, Lon: lon},
+		{ID: "70m", Lat: lat + 0.00063, Lon: lon},
+		{ID: "west", Lat: lat, Lon: lon - 0.004},
+	}
+	index := NewStopIndex(stops)
+
+	// When: searching within 500m
+	got := index.Nearby(lat, lon, 500)
+
+	// Then: the stops within the radius come back in the order added
+	want := []gtfs.StopID{"400m", "here", "70m", "west"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, stopIDs(got))
+	}
+	for i := range want {
+		if got[i].ID != want[i] {
+			t.Errorf("Expected %v, got %v", want, stopIDs(got))
+			break
+		}
+	}
+	if index.Len() != 5 {
+		t.Errorf("Expected 5 stops, got %d", index.Len())
+	}
+}
+
+func TestStopIndexNearbyAntimeridian(t *testing.T) {
+	// Given: stops about 100m either side of the antimeridian
+	stops := []*gtfs.Stop{
+		{ID: "west", Lat: -17.7, Lon: 179.999},
+		{ID: "east", Lat: -17.7, Lon: -179.999},
+	}
+	index := NewStopIndex(stops)
+
+	// Then: searching from either side finds both
+	for _, s := range stops {
+		if got := index.Nearby(s.Lat, s.Lon, 500); len(got) != 2 {
+			t.Errorf("Expected both stops near %s, got %v", s.ID, stopIDs(got))
+		}
+	}
+}
+
+func TestStopIndexNearbyMatchesLinearScan(t *testing.T) {
+	// Given: a grid of stops around a point
+	var stops []*gtfs.Stop
+	for i := 0; i < 40; i++ {
+		for j := 0; j < 40; j++ {
+			stops = append(stops, &gtfs.Stop{
+				ID:  gtfs.StopID(fmt.Sprintf("s%d-%d", i, j)),
+				Lat: 47.60 + float64(i)*0.0007,
+				Lon: -122.33 + float64(j)*0.0011,
+			})
+		}
+	}
+	index := NewStopIndex(stops)
+
+	// Then: every radius finds exactly what a linear scan does
+	for _, radius := range []float64{0, 50, 500, 2000, 50_000} {
+		var want []gtfs.StopID
+		for _, s := range stops {
+			if HaversineMeters(47.614, -122.31, s.Lat, s.Lon) <= radius {
+				want = append(want, s.ID)
+			}
+		}
+		got := stopIDs(index.Nearby(47.614, -122.31, radius))
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("radius %v: expected %d stops, got %d", radius, len(want), len(got))
+		}
+	}
+}
+
+func stopIDs(stops []*gtfs.Stop) []gtfs.StopID {
+	ids := make([]gtfs.StopID, len(stops))
+	for i, s := range stops {
+		ids[i] = s.ID
+	}
+	return ids
+}
@@ -1,8 +1,12 @@
 package gtfs
 
 import (
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -103,3 +107,137 @@ func BenchmarkParsing(b *testing.B) {
 		_ = len(feed.Calendars)
 	}
 }
+
+// writeLargeShapesFeed writes simple_a with a synthetic shapes.txt of
+// shapes shapes of points points each, and returns its directory
+func writeLargeShapesFeed(b *testing.B, shapes, points int) string {
+	b.Helper()
+	src := filepath.Join("..", "testdata", "simple_a")
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		b.Fatal(err)
+	}
+	dir := b.TempDir()
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(src, e.Name()))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, e.Name()), data, 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("shape_id,shape_pt_lat,shape_pt_lon,shape_pt_sequence,shape_dist_traveled\n")
+	for s := 0; s < shapes; s++ {
+		for p := 0; p < points; p++ {
+			fmt.Fprintf(&sb, "shape%d,%.6f,%.6f,%d,%d\n", s, 47.6+float64(p)*1e-4, -122.3-float64(s)*1e-4, p+1, p*10)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "shapes.txt"), []byte(sb.String()), 0644); err != nil {
+		b.Fatal(err)
+	}
+	return dir
+}
+
+// BenchmarkReadLargeShapes benchmarks reading a feed dominated by shapes.txt
+// (200 shapes of 500 points), reporting the heap the read feed retains
+func BenchmarkReadLargeShapes(b *testing.B) {
+	dir := writeLargeShapesFeed(b, 200, 500)
+
+	var retained uint64
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		feed, err := ReadFromPath(dir)
+		if err != nil {
+			b.Fatal(err)
+		}
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		runtime.KeepAlive(feed)
+		retained = after.HeapAlloc - before.HeapAlloc
+	}
+	b.ReportMetric(float64(retained), "retained-B")
+}
+
+// BenchmarkParseStopTimes compares parsing 1M synthetic stop_times rows by
+// column name (ParseStopTime) with the reader's by-index stopTimeParser
+func BenchmarkParseStopTimes(b *testing.B) {
+	const rows = 1_000_000
+	header := []string{"trip_id", "arrival_time", "departure_time", "stop_id", "stop_sequence",
+		"stop_headsign", "pickup_type", "drop_off_type", "shape_dist_traveled", "timepoint"}
+
+	// A trip's worth of distinct records, cycled to make up the rows
+	records := make([][]string, 50)
+	for i := range records {
+		t := fmt.Sprintf("%02d:%02d:00", 8+i/60, i%60)
+		records[i] = []string{"trip1", t, t, fmt.Sprintf("stop%d", i), fmt.Sprint(i + 1),
+			"", "0", "0", fmt.Sprintf("%d.5", i*100), "1"}
+	}
+
+	b.Run("ByName", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			row := NewCSVRow(header, nil)
+			for n := 0; n < rows; n++ {
+				row.reset(records[n%len(records)], n+2)
+				_ = ParseStopTime(row)
+			}
+		}
+	})
+
+	b.Run("ByIndex", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			row := NewCSVRow(header, nil)
+			var parser stopTimeParser
+			for n := 0; n < rows; n++ {
+				row.reset(records[n%len(records)], n+2)
+				_ = parser.parse(row)
+			}
+		}
+	})
+}
+
+// BenchmarkWriteLargeStopTimes benchmarks writing 1M stop_times rows (20k
+// trips of 50 stops, with shape_dist_traveled), reporting the GC pause time
+// and number of collections per write as well as allocations
+func BenchmarkWriteLargeStopTimes(b *testing.B) {
+	const trips, stops = 20_000, 50
+	feed := NewFeed()
+	feed.StopTimes = make([]*StopTime, 0, trips*stops)
+	for t := 0; t < trips; t++ {
+		tripID := TripID(fmt.Sprintf("trip%d", t))
+		for s := 0; s < stops; s++ {
+			at := fmt.Sprintf("%02d:%02d:00", 6+s/60, s%60)
+			dist := float64(s) * 412.5
+			feed.StopTimes = append(feed.StopTimes, &StopTime{
+				TripID: tripID, StopID: StopID(fmt.Sprintf("stop%d", s)),
+				ArrivalTime: at, DepartureTime: at, StopSequence: s + 1, ShapeDistTraveled: &dist,
+			})
+		}
+	}
+	feed.AddColumn("stop_times.txt", "shape_dist_traveled")
+
+	var pause uint64
+	var gcs uint32
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		if err := WriteFile(feed, "stop_times.txt", io.Discard); err != nil {
+			b.Fatal(err)
+		}
+		runtime.ReadMemStats(&after)
+		pause += after.PauseTotalNs - before.PauseTotalNs
+		gcs += after.NumGC - before.NumGC
+	}
+	b.ReportMetric(float64(pause)/float64(b.N), "gc-pause-ns/op")
+	b.ReportMetric(float64(gcs)/float64(b.N), "gcs/op")
+}
@@ -0,0 +1,68 @@
+package gtfs
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Colors a route takes when route_color or route_text_color is empty
+const (
+	DefaultRouteColor     = "FFFFFF"
+	DefaultRouteTextColor = "000000"
+)
+
+// NormalizeColor returns color, a route_color or route_text_color value,
+// without surrounding whitespace or a leading '#' and with its letters
+// uppercased, so "#ff0000" becomes "FF0000". Other malformed values are
+// only trimmed and uppercased; see IsValidColor.
+func NormalizeColor(color string) string {
+	return strings.ToUpper(strings.TrimPrefix(strings.TrimSpace(color), "#"))
+}
+
+// IsValidColor reports whether color is six hexadecimal digits, the format
+// GTFS requires of route_color and route_text_color. Lowercase digits are
+// valid; a leading '#' is not.
+func IsValidColor(color string) bool {
+	if len(color) != 6 {
+		return false
+	}
+	_, err := strconv.ParseUint(color, 16, 32)
+	return err == nil
+}
+
+// ContrastRatio returns the contrast ratio of two valid colors (see
+// IsValidColor) as WCAG 2 defines it, from their relative luminance: 1 for
+// the same color, up to 21 for black on white. It reports false if either
+// color is invalid.
+func ContrastRatio(a, b string) (float64, bool) {
+	la, ok := relativeLuminance(a)
+	if !ok {
+		return 0, false
+	}
+	lb, ok := relativeLuminance(b)
+	if !ok {
+		return 0, false
+	}
+	return (math.Max(la, lb) + 0.05) / (math.Min(la, lb) + 0.05), true
+}
+
+// relativeLuminance returns the WCAG 2 relative luminance of a valid
+// color, from 0 for black to 1 for white
+func relativeLuminance(color string) (float64, bool) {
+	if !IsValidColor(color) {
+		return 0, false
+	}
+	rgb, _ := strconv.ParseUint(color, 16, 32)
+	var luminance float64
+	for i, weight := range []float64{0.2126, 0.7152, 0.0722} {
+		c := float64(rgb>>(16-8*i)&0xFF) / 255
+		if c <= 0.04045 {
+			c /= 12.92
+		} else {
+			c = math.Pow((c+0.055)/1.055, 2.4)
+		}
+		luminance += weight * c
+	}
+	return luminance, true
+}
@@ -0,0 +1,60 @@
+package gtfs
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalizeColor(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"FF0000", "FF0000"},
+		{"ff0000", "FF0000"},
+		{"#ff0000", "FF0000"},
+		{" #00aA00 ", "00AA00"},
+		{"", ""},
+		{"#", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeColor(tt.in); got != tt.want {
+			t.Errorf("NormalizeColor(%q): expected %q, got %q", tt.in, tt.want, got)
+		}
+	}
+}
+
+func TestIsValidColor(t *testing.T) {
+	for color, want := range map[string]bool{
+		"FF0000":  true,
+		"00aa00":  true,
+		"#FF0000": false,
+		"F00":     false,
+		"GG0000":  false,
+		"":        false,
+		"+FFFFF":  false,
+	} {
+		if got := IsValidColor(color); got != want {
+			t.Errorf("IsValidColor(%q): expected %v, got %v", color, want, got)
+		}
+	}
+}
+
+func TestContrastRatio(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"000000", "FFFFFF", 21},
+		{"FFFFFF", "000000", 21},
+		{"777777", "777777", 1},
+		{"FFFFFF", "FFFF00", 1.07},
+		{"FFFFFF", "0000FF", 8.59},
+	}
+	for _, tt := range tests {
+		got, ok := ContrastRatio(tt.a, tt.b)
+		if !ok || math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("ContrastRatio(%s, %s): expected %.2f, got %.2f (ok %v)", tt.a, tt.b, tt.want, got, ok)
+		}
+	}
+	if _, ok := ContrastRatio("#FFFFFF", "000000"); ok {
+		t.Error("Expected an invalid color to have no contrast ratio")
+	}
+}
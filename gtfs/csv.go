@@ -3,6 +3,7 @@ package gtfs
 import (
 	"encoding/csv"
 	"errors"
+	"fmt"
 	"io"
 	"strconv"
 	"strings"
@@ -13,6 +14,7 @@ import (
 type CSVReader struct {
 	reader     *csv.Reader
 	headerRead bool
+	line       int // 1-based line of the most recent header or record
 }
 
 // NewCSVReader creates a new CSVReader from an io.Reader.
@@ -35,6 +37,7 @@ func (c *CSVReader) ReadHeader() ([]string, error) {
 		return nil, err
 	}
 	c.headerRead = true
+	c.line, _ = c.reader.FieldPos(0)
 
 	// Strip UTF-8 BOM from first field if present
 	if len(record) > 0 {
@@ -74,10 +77,17 @@ func (c *CSVReader) ReadRecord() ([]string, error) {
 			continue
 		}
 
+		c.line, _ = c.reader.FieldPos(0)
 		return record, nil
 	}
 }
 
+// Line returns the 1-based line number on which the most recently read
+// header or record starts, or 0 if nothing has been read yet.
+func (c *CSVReader) Line() int {
+	return c.line
+}
+
 // stripBOM removes the UTF-8 BOM (Byte Order Mark) from the beginning of a string.
 func stripBOM(s string) string {
 	const bom = "\xEF\xBB\xBF"
@@ -97,11 +107,45 @@ func isEmptyRecord(record []string) bool {
 	return true
 }
 
+// ParseError describes a malformed row or field value found while reading
+// a GTFS file. In lenient mode these are collected in Feed.ParseWarnings;
+// in strict mode the first one is returned from the read.
+type ParseError struct {
+	File    string // GTFS file name, e.g. "stop_times.txt"; empty if unknown
+	Line    int    // 1-based line number; 0 if unknown
+	Column  string // offending column; empty for row-level problems
+	Value   string // offending value
+	Message string // description, e.g. "invalid stop_sequence \"abc\""
+}
+
+// Error formats the problem with its location,
+// e.g. `stop_times.txt line 184233: invalid stop_sequence "abc"`
+func (e *ParseError) Error() string {
+	switch {
+	case e.File != "" && e.Line > 0:
+		return fmt.Sprintf("%s line %d: %s", e.File, e.Line, e.Message)
+	case e.File != "":
+		return fmt.Sprintf("%s: %s", e.File, e.Message)
+	case e.Line > 0:
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	default:
+		return e.Message
+	}
+}
+
 // CSVRow provides convenient access to CSV record fields by column name.
+// Typed getters fall back to a default for malformed values and record the
+// problem, retrievable with Issues.
 type CSVRow struct {
 	header  []string
 	record  []string
 	indices map[string]int
+
+	// file and line locate the row for ParseError reporting
+	file string
+	line int
+
+	issues []*ParseError
 }
 
 // NewCSVRow creates a new CSVRow from a header and record.
@@ -117,6 +161,41 @@ func NewCSVRow(header, record []string) *CSVRow {
 	}
 }
 
+// reset points the row at the next record of the same file, on line
+func (r *CSVRow) reset(record []string, line int) {
+	r.record = record
+	r.line = line
+	r.issues = nil
+}
+
+// Issues returns the problems recorded while reading this row's fields
+func (r *CSVRow) Issues() []*ParseError {
+	return r.issues
+}
+
+// addIssue records a problem with this row
+func (r *CSVRow) addIssue(column, value, message string) {
+	r.issues = append(r.issues, &ParseError{
+		File:    r.file,
+		Line:    r.line,
+		Column:  column,
+		Value:   value,
+		Message: message,
+	})
+}
+
+// invalidValue records a field value that could not be parsed
+func (r *CSVRow) invalidValue(column, value string) {
+	r.addIssue(column, value, fmt.Sprintf("invalid %s %q", column, value))
+}
+
+// checkFieldCount records a row whose field count differs from the header's
+func (r *CSVRow) checkFieldCount() {
+	if len(r.record) != len(r.header) {
+		r.addIssue("", "", fmt.Sprintf("row has %d fields, header has %d", len(r.record), len(r.header)))
+	}
+}
+
 // Get returns the value of the field with the given column name.
 // Returns an empty string if the column doesn't exist.
 func (r *CSVRow) Get(column string) string {
@@ -127,59 +206,130 @@ func (r *CSVRow) Get(column string) string {
 	return r.record[idx]
 }
 
+// column is a column of one file resolved to its index in the header, so
+// hot parsers can read fields by index rather than looking each one up by
+// name per row. idx is -1 if the file has no such column.
+type column struct {
+	name string
+	idx  int
+}
+
+// column resolves name against the row's header
+func (r *CSVRow) column(name string) column {
+	idx, ok := r.indices[name]
+	if !ok {
+		idx = -1
+	}
+	return column{name: name, idx: idx}
+}
+
+// at returns the value of c, or an empty string if the file or this record
+// lacks it; it matches Get for the same column
+func (r *CSVRow) at(c column) string {
+	if c.idx < 0 || c.idx >= len(r.record) {
+		return ""
+	}
+	return r.record[c.idx]
+}
+
+// atInt returns the value of c as an int, as GetInt does
+func (r *CSVRow) atInt(c column) int {
+	return r.intValue(c.name, r.at(c))
+}
+
+// atIntPtr returns the value of c as a pointer to int, as GetIntPtr does
+func (r *CSVRow) atIntPtr(c column) *int {
+	return r.intPtrValue(c.name, r.at(c))
+}
+
+// atFloat returns the value of c as a float64, as GetFloat does
+func (r *CSVRow) atFloat(c column) float64 {
+	return r.floatValue(c.name, r.at(c))
+}
+
+// atFloatPtr returns the value of c as a pointer to float64, as GetFloatPtr
+// does
+func (r *CSVRow) atFloatPtr(c column) *float64 {
+	return r.floatPtrValue(c.name, r.at(c))
+}
+
 // GetInt returns the value of the field as an int.
-// Returns 0 if the field is empty, missing, or not a valid integer.
+// Returns 0 if the field is empty, missing, or not a valid integer;
+// invalid values are recorded as issues.
 func (r *CSVRow) GetInt(column string) int {
-	s := r.Get(column)
+	return r.intValue(column, r.Get(column))
+}
+
+// intValue parses s, the value of column, as GetInt does
+func (r *CSVRow) intValue(column, s string) int {
 	if s == "" {
 		return 0
 	}
 	val, err := strconv.Atoi(s)
 	if err != nil {
+		r.invalidValue(column, s)
 		return 0
 	}
 	return val
 }
 
 // GetIntPtr returns the value of the field as a pointer to int.
-// Returns nil if the field is empty or missing, otherwise returns a pointer to the parsed value.
+// Returns nil if the field is empty, missing, or invalid (recorded as an issue),
+// otherwise returns a pointer to the parsed value.
 // Use this for optional integer fields where 0 is a meaningful value distinct from "not set".
 func (r *CSVRow) GetIntPtr(column string) *int {
-	s := r.Get(column)
+	return r.intPtrValue(column, r.Get(column))
+}
+
+// intPtrValue parses s, the value of column, as GetIntPtr does
+func (r *CSVRow) intPtrValue(column, s string) *int {
 	if s == "" {
 		return nil
 	}
 	val, err := strconv.Atoi(s)
 	if err != nil {
+		r.invalidValue(column, s)
 		return nil
 	}
 	return &val
 }
 
 // GetFloat returns the value of the field as a float64.
-// Returns 0.0 if the field is empty, missing, or not a valid float.
+// Returns 0.0 if the field is empty, missing, or not a valid float;
+// invalid values are recorded as issues.
 func (r *CSVRow) GetFloat(column string) float64 {
-	s := r.Get(column)
+	return r.floatValue(column, r.Get(column))
+}
+
+// floatValue parses s, the value of column, as GetFloat does
+func (r *CSVRow) floatValue(column, s string) float64 {
 	if s == "" {
 		return 0.0
 	}
 	val, err := strconv.ParseFloat(s, 64)
 	if err != nil {
+		r.invalidValue(column, s)
 		return 0.0
 	}
 	return val
 }
 
 // GetFloatPtr returns the value of the field as a pointer to float64.
-// Returns nil if the field is empty or missing, otherwise returns a pointer to the parsed value.
+// Returns nil if the field is empty, missing, or invalid (recorded as an issue),
+// otherwise returns a pointer to the parsed value.
 // Use this for optional float fields where 0.0 is a meaningful value distinct from "not set".
 func (r *CSVRow) GetFloatPtr(column string) *float64 {
-	s := r.Get(column)
+	return r.floatPtrValue(column, r.Get(column))
+}
+
+// floatPtrValue parses s, the value of column, as GetFloatPtr does
+func (r *CSVRow) floatPtrValue(column, s string) *float64 {
 	if s == "" {
 		return nil
 	}
 	val, err := strconv.ParseFloat(s, 64)
 	if err != nil {
+		r.invalidValue(column, s)
 		return nil
 	}
 	return &val
@@ -187,7 +337,16 @@ func (r *CSVRow) GetFloatPtr(column string) *float64 {
 
 // GetBool returns the value of the field as a bool.
 // Returns true for "1" or "true" (case-insensitive), false otherwise.
+// Values other than empty, "0", "1", "true" and "false" are recorded as issues.
 func (r *CSVRow) GetBool(column string) bool {
 	s := strings.ToLower(r.Get(column))
-	return s == "1" || s == "true"
+	switch s {
+	case "1", "true":
+		return true
+	case "", "0", "false":
+		return false
+	default:
+		r.invalidValue(column, r.Get(column))
+		return false
+	}
 }
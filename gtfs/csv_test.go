@@ -1,8 +1,10 @@
 package gtfs
 
 import (
+	"encoding/csv"
 	"errors"
 	"io"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -363,3 +365,124 @@ func TestCSVRowGetBool(t *testing.T) {
 		t.Errorf("expected false for empty")
 	}
 }
+
+func TestCSVReaderLine(t *testing.T) {
+	// Given: a file with a blank line and a quoted field spanning two lines
+	input := "id,name\n1,One\n\n2,\"Two\nlines\"\n3,Three\n"
+	reader := NewCSVReader(strings.NewReader(input))
+
+	if _, err := reader.ReadHeader(); err != nil {
+		t.Fatalf("ReadHeader failed: %v", err)
+	}
+	if got := reader.Line(); got != 1 {
+		t.Errorf("header: expected line 1, got %d", got)
+	}
+
+	// Then: each record reports the line it starts on
+	for _, expected := range []int{2, 4, 6} {
+		if _, err := reader.ReadRecord(); err != nil {
+			t.Fatalf("ReadRecord failed: %v", err)
+		}
+		if got := reader.Line(); got != expected {
+			t.Errorf("expected line %d, got %d", expected, got)
+		}
+	}
+}
+
+func TestCSVRowIssues(t *testing.T) {
+	header := []string{"seq", "dist", "monday", "ok"}
+	record := []string{"abc", "1.2.3", "yes", "7"}
+	row := NewCSVRow(header, record)
+	row.file, row.line = "stop_times.txt", 184233
+
+	row.GetInt("seq")
+	row.GetFloatPtr("dist")
+	row.GetBool("monday")
+	row.GetInt("ok")
+
+	issues := row.Issues()
+	if len(issues) != 3 {
+		t.Fatalf("expected 3 issues, got %d: %v", len(issues), issues)
+	}
+	if got, want := issues[0].Error(), `stop_times.txt line 184233: invalid seq "abc"`; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if issues[1].Column != "dist" || issues[1].Value != "1.2.3" {
+		t.Errorf("expected dist issue, got %+v", issues[1])
+	}
+	if issues[2].Column != "monday" {
+		t.Errorf("expected monday issue, got %+v", issues[2])
+	}
+}
+
+func TestCSVRowCheckFieldCount(t *testing.T) {
+	row := NewCSVRow([]string{"a", "b", "c"}, []string{"1", "2"})
+	row.checkFieldCount()
+
+	issues := row.Issues()
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	if got, want := issues[0].Error(), "row has 2 fields, header has 3"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestFieldLimiter(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  [][]string
+		lines []int // lines of the truncated fields
+	}{
+		{
+			name:  "short fields unchanged",
+			input: "a,b\n\"x,y\",\"\"\"hi\"\"\"\n",
+			want:  [][]string{{"a", "b"}, {"x,y", `"hi"`}},
+		},
+		{
+			name:  "unquoted",
+			input: "abcdefgh,b\nc,d\n",
+			want:  [][]string{{"abcdef", "b"}, {"c", "d"}},
+			lines: []int{1},
+		},
+		{
+			name:  "quoted with escaped quotes, commas and newlines",
+			input: "id,desc\n1,\"ab,\n\"\"cdefg\"\n2,ok\n",
+			want:  [][]string{{"id", "desc"}, {"1", "ab,\n\""}, {"2", "ok"}},
+			lines: []int{2},
+		},
+		{
+			name:  "not splitting a character",
+			input: "abcdeé\n",
+			want:  [][]string{{"abcde"}},
+			lines: []int{1},
+		},
+		{
+			name:  "closing quote at end of input",
+			input: "\"abcdefgh\"",
+			want:  [][]string{{"abcdef"}},
+			lines: []int{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := newFieldLimiter(strings.NewReader(tt.input), &ReaderOptions{MaxFieldLength: 6, TruncateLongFields: true}).(*fieldLimiter)
+			got, err := csv.NewReader(limiter).ReadAll()
+			if err != nil {
+				t.Fatalf("ReadAll failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+			var lines []int
+			for _, tr := range limiter.truncated {
+				lines = append(lines, tr.line)
+			}
+			if !reflect.DeepEqual(lines, tt.lines) {
+				t.Errorf("expected truncations on lines %v, got %v", tt.lines, lines)
+			}
+		})
+	}
+}
@@ -1,38 +1,155 @@
 package gtfs
 
 import (
+	"bufio"
 	"encoding/csv"
 	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 // CSVWriter wraps the standard csv.Writer for GTFS output.
 // It uses standard CSV formatting with CRLF line endings converted to LF.
+// Values containing commas, double quotes, CR or LF are quoted as in
+// RFC 4180, with quotes doubled. A CRLF inside a value is written as LF,
+// matching the file's line endings and what CSVReader reads back.
+// WriterOptions can instead have lines end in CRLF and every field quoted.
 type CSVWriter struct {
 	writer *csv.Writer
+
+	// lineBreaks rewrites the line breaks inside values
+	lineBreaks *strings.Replacer
+
+	// out, if set, is written to in place of writer, for the line endings
+	// and quoting WriterOptions allow: lineEnd ends each record, and
+	// quoteAll quotes every field. csv.Writer cannot quote every field,
+	// and with UseCRLF drops the CRs inside values.
+	out      *bufio.Writer
+	lineEnd  string
+	quoteAll bool
 }
 
+var (
+	// crlfToLF turns CRLFs inside values into LFs
+	crlfToLF = strings.NewReplacer("\r\n", "\n")
+
+	// lineBreaksToSpace turns each line break inside values into a space
+	lineBreaksToSpace = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+)
+
+// writeBufferSize is the size of the chunks CSVWriter writes to its
+// io.Writer, large enough that a big file, such as stop_times.txt, is
+// compressed in few calls
+const writeBufferSize = 64 * 1024
+
 // NewCSVWriter creates a new CSVWriter that writes to the given io.Writer.
+// Output is buffered, and written in chunks of writeBufferSize bytes.
 func NewCSVWriter(w io.Writer) *CSVWriter {
-	csvWriter := csv.NewWriter(w)
+	// csv.Writer uses a *bufio.Writer of at least its default size as is
+	csvWriter := csv.NewWriter(bufio.NewWriterSize(w, writeBufferSize))
 	// Use Unix-style line endings (LF) for consistency
 	csvWriter.UseCRLF = false
 	return &CSVWriter{
-		writer: csvWriter,
+		writer:     csvWriter,
+		lineBreaks: crlfToLF,
 	}
 }
 
+// newCSVWriter creates a CSVWriter configured by the writer options.
+// A nil receiver uses the defaults.
+func (o *WriterOptions) newCSVWriter(w io.Writer) *CSVWriter {
+	c := NewCSVWriter(w)
+	if o == nil {
+		return c
+	}
+	if o.StripNewlines {
+		c.lineBreaks = lineBreaksToSpace
+	}
+	if o.UseCRLF || o.QuoteAll {
+		c.out = bufio.NewWriterSize(w, writeBufferSize)
+		c.lineEnd = "\n"
+		if o.UseCRLF {
+			c.lineEnd = "\r\n"
+		}
+		c.quoteAll = o.QuoteAll
+	}
+	return c
+}
+
 // WriteHeader writes the header row to the CSV.
 func (c *CSVWriter) WriteHeader(header []string) error {
-	return c.writer.Write(header)
+	return c.write(header)
 }
 
-// WriteRecord writes a data record to the CSV.
+// WriteRecord writes a data record to the CSV. The record is neither
+// modified nor retained, so callers may reuse it for the next record.
 func (c *CSVWriter) WriteRecord(record []string) error {
-	return c.writer.Write(record)
+	copied := false
+	for i, field := range record {
+		if !hasLineBreak(field) {
+			continue
+		}
+		if !copied {
+			record = append([]string(nil), record...)
+			copied = true
+		}
+		record[i] = c.lineBreaks.Replace(field)
+	}
+	return c.write(record)
+}
+
+// hasLineBreak reports whether s contains a CR or LF. It is called for
+// every field written, and strings.IndexByte is much faster than
+// strings.ContainsAny.
+func hasLineBreak(s string) bool {
+	return strings.IndexByte(s, '\n') >= 0 || strings.IndexByte(s, '\r') >= 0
+}
+
+// write writes record as a CSV line, to c.out if set.
+func (c *CSVWriter) write(record []string) error {
+	if c.out == nil {
+		return c.writer.Write(record)
+	}
+	for i, field := range record {
+		if i > 0 {
+			_ = c.out.WriteByte(',')
+		}
+		if !c.quoteAll && !fieldNeedsQuotes(field) {
+			_, _ = c.out.WriteString(field)
+			continue
+		}
+		field = strings.ReplaceAll(field, `"`, `""`)
+		if c.lineEnd != "\n" {
+			field = strings.ReplaceAll(field, "\n", c.lineEnd)
+		}
+		_ = c.out.WriteByte('"')
+		_, _ = c.out.WriteString(field)
+		_ = c.out.WriteByte('"')
+	}
+	// A bufio.Writer keeps the first error it hits, so this last write
+	// returns any error from the writes above.
+	_, err := c.out.WriteString(c.lineEnd)
+	return err
+}
+
+// fieldNeedsQuotes reports whether csv.Writer would quote field.
+func fieldNeedsQuotes(field string) bool {
+	if field == "" {
+		return false
+	}
+	if field == `\.` || strings.ContainsAny(field, ",\"\r\n") {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(field)
+	return unicode.IsSpace(r)
 }
 
 // Flush writes any buffered data to the underlying writer.
 func (c *CSVWriter) Flush() error {
+	if c.out != nil {
+		return c.out.Flush()
+	}
 	c.writer.Flush()
 	return c.writer.Error()
 }
@@ -0,0 +1,210 @@
+package gtfs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/internal/enumtext"
+)
+
+// Encoding identifies the character encoding of a feed's text files.
+// Whatever the input encoding, feeds are held and written as UTF-8.
+type Encoding int
+
+const (
+	// EncodingUTF8 - files are read as UTF-8 without transcoding
+	EncodingUTF8 Encoding = iota
+
+	// EncodingLatin1 - files are ISO-8859-1 and transcoded to UTF-8
+	EncodingLatin1
+
+	// EncodingWindows1252 - files are Windows-1252 and transcoded to UTF-8
+	EncodingWindows1252
+
+	// EncodingAuto - each file is checked: a UTF-8 BOM or valid UTF-8 is
+	// read as UTF-8, invalid UTF-8 that decodes cleanly in the legacy
+	// encoding is transcoded, and anything else is read as UTF-8 with a
+	// parse warning
+	EncodingAuto
+)
+
+// String returns the string representation of Encoding
+func (e Encoding) String() string {
+	switch e {
+	case EncodingUTF8:
+		return "utf-8"
+	case EncodingLatin1:
+		return "latin1"
+	case EncodingWindows1252:
+		return "windows-1252"
+	case EncodingAuto:
+		return "auto"
+	default:
+		return fmt.Sprintf("Encoding(%d)", e)
+	}
+}
+
+// ParseEncoding parses a string into an Encoding value
+func ParseEncoding(s string) (Encoding, error) {
+	switch strings.ToLower(s) {
+	case "utf-8", "utf8":
+		return EncodingUTF8, nil
+	case "latin1", "latin-1", "iso-8859-1":
+		return EncodingLatin1, nil
+	case "windows-1252", "cp1252":
+		return EncodingWindows1252, nil
+	case "auto":
+		return EncodingAuto, nil
+	default:
+		return EncodingUTF8, fmt.Errorf("invalid encoding: %q", s)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (e Encoding) MarshalText() ([]byte, error) {
+	return enumtext.Marshal(e, ParseEncoding)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (e *Encoding) UnmarshalText(text []byte) error {
+	return enumtext.Unmarshal(e, text, ParseEncoding)
+}
+
+// encodingSniffSize is how much of a file EncodingAuto examines
+const encodingSniffSize = 1 << 20
+
+// windows1252High maps bytes 0x80-0x9F to their Windows-1252 characters.
+// Zero marks the five bytes Windows-1252 leaves undefined.
+var windows1252High = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
+
+// decodeLegacyByte returns the character for b in the legacy encoding, and
+// false if b is undefined or a C1 control character there
+func decodeLegacyByte(enc Encoding, b byte) (rune, bool) {
+	if b < 0x80 || b >= 0xA0 {
+		return rune(b), true
+	}
+	if enc == EncodingWindows1252 {
+		r := windows1252High[b-0x80]
+		return r, r != 0
+	}
+	return rune(b), false
+}
+
+// legacyReader transcodes a single-byte legacy encoding to UTF-8
+type legacyReader struct {
+	r       io.Reader
+	enc     Encoding
+	buf     []byte
+	pending []byte
+}
+
+// Read implements io.Reader
+func (l *legacyReader) Read(p []byte) (int, error) {
+	if len(l.pending) == 0 {
+		if cap(l.buf) == 0 {
+			l.buf = make([]byte, 4096)
+		}
+		n, err := l.r.Read(l.buf[:cap(l.buf)])
+		if n == 0 {
+			return 0, err
+		}
+		out := make([]byte, 0, n*2)
+		for _, b := range l.buf[:n] {
+			// Undefined bytes map to the C1 control of the same value,
+			// as Windows itself does
+			r, _ := decodeLegacyByte(l.enc, b)
+			out = utf8.AppendRune(out, r)
+		}
+		l.pending = out
+	}
+	n := copy(p, l.pending)
+	l.pending = l.pending[n:]
+	return n, nil
+}
+
+// encodingDecision is the outcome of sniffing a file's encoding
+type encodingDecision int
+
+const (
+	decideUTF8 encodingDecision = iota
+	decideLegacy
+	decideUnsure
+)
+
+// sniffEncoding decides whether sample, the start of a file, is UTF-8 or the
+// legacy encoding. Only invalid UTF-8 that decodes entirely to defined legacy
+// characters counts as legacy; anything else that isn't valid UTF-8 is unsure.
+func sniffEncoding(sample []byte, legacy Encoding, truncated bool) encodingDecision {
+	if bytes.HasPrefix(sample, []byte("\xEF\xBB\xBF")) {
+		return decideUTF8
+	}
+
+	valid := true
+	for i := 0; i < len(sample); {
+		r, size := utf8.DecodeRune(sample[i:])
+		if r == utf8.RuneError && size <= 1 {
+			// A rune cut off by the sample boundary is not evidence either way
+			if truncated && !utf8.FullRune(sample[i:]) {
+				break
+			}
+			valid = false
+			break
+		}
+		i += size
+	}
+	if valid {
+		return decideUTF8
+	}
+
+	for _, b := range sample {
+		if _, ok := decodeLegacyByte(legacy, b); !ok {
+			return decideUnsure
+		}
+	}
+	return decideLegacy
+}
+
+// decodeFile wraps a file's reader so it yields UTF-8 according to
+// opts.Encoding. Under EncodingAuto, a file whose encoding can't be
+// determined is read as UTF-8 and a warning is added to the feed.
+func decodeFile(feed *Feed, filename string, r io.Reader, opts *ReaderOptions) (io.Reader, error) {
+	if opts == nil {
+		return r, nil
+	}
+
+	switch opts.Encoding {
+	case EncodingLatin1, EncodingWindows1252:
+		return &legacyReader{r: r, enc: opts.Encoding}, nil
+	case EncodingAuto:
+		legacy := opts.LegacyEncoding
+		if legacy != EncodingLatin1 {
+			legacy = EncodingWindows1252
+		}
+
+		br := bufio.NewReaderSize(r, encodingSniffSize)
+		sample, err := br.Peek(encodingSniffSize)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return nil, err
+		}
+
+		switch sniffEncoding(sample, legacy, err == nil) {
+		case decideLegacy:
+			return &legacyReader{r: br, enc: legacy}, nil
+		case decideUnsure:
+			feed.ParseWarnings = append(feed.ParseWarnings, &ParseError{
+				File:    filename,
+				Message: fmt.Sprintf("invalid UTF-8 that is not valid %s either; reading as UTF-8", legacy),
+			})
+		}
+		return br, nil
+	default:
+		return r, nil
+	}
+}
@@ -0,0 +1,183 @@
+package gtfs
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// accentedStopNames are the stop names of the encoding_* fixtures
+var accentedStopNames = map[StopID]string{
+	"stop1": "Café Central",
+	"stop2": "Estación Norte – Andén 1",
+	"stop3": "Müller Straße",
+}
+
+func checkAccentedStops(t *testing.T, feed *Feed) {
+	t.Helper()
+	for id, want := range accentedStopNames {
+		stop := feed.Stops[id]
+		if stop == nil {
+			t.Fatalf("missing stop %s", id)
+		}
+		if stop.Name != want {
+			t.Errorf("stop %s: expected name %q, got %q", id, want, stop.Name)
+		}
+	}
+	if got := feed.Agencies["agency1"].Name; got != "Tránsito Municipal" {
+		t.Errorf("expected agency name %q, got %q", "Tránsito Municipal", got)
+	}
+}
+
+func TestReadEncodings(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		encoding Encoding
+	}{
+		{"utf-8 default", "../testdata/encoding_utf8", EncodingUTF8},
+		{"utf-8 auto", "../testdata/encoding_utf8", EncodingAuto},
+		{"windows-1252 explicit", "../testdata/encoding_windows1252", EncodingWindows1252},
+		{"windows-1252 auto", "../testdata/encoding_windows1252", EncodingAuto},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := ReadFromPathWithOptions(tt.path, ReaderOptions{Encoding: tt.encoding})
+			if err != nil {
+				t.Fatalf("ReadFromPathWithOptions failed: %v", err)
+			}
+			checkAccentedStops(t, feed)
+			if len(feed.ParseWarnings) != 0 {
+				t.Errorf("expected no warnings, got %v", feed.ParseWarnings)
+			}
+		})
+	}
+}
+
+func TestReadWindows1252AsUTF8IsLossy(t *testing.T) {
+	// Without an encoding option, legacy bytes are not transcoded
+	feed, err := ReadFromPath("../testdata/encoding_windows1252")
+	if err != nil {
+		t.Fatalf("ReadFromPath failed: %v", err)
+	}
+	if feed.Stops["stop1"].Name == accentedStopNames["stop1"] {
+		t.Error("expected Windows-1252 bytes to be read verbatim without an encoding option")
+	}
+}
+
+func TestReadAutoUnsureWarnsAndDefaultsToUTF8(t *testing.T) {
+	// Given: Windows-1252 data (with an en dash, byte 0x96) but Latin-1 as
+	// the fallback, under which 0x96 is a control character
+	feed, err := ReadFromPathWithOptions("../testdata/encoding_windows1252", ReaderOptions{
+		Encoding:       EncodingAuto,
+		LegacyEncoding: EncodingLatin1,
+	})
+	if err != nil {
+		t.Fatalf("ReadFromPathWithOptions failed: %v", err)
+	}
+
+	// Then: stops.txt is read as UTF-8 with a warning
+	var warned bool
+	for _, w := range feed.ParseWarnings {
+		if w.File == "stops.txt" && strings.Contains(w.Message, "reading as UTF-8") {
+			warned = true
+		}
+	}
+	if !warned {
+		t.Errorf("expected an encoding warning for stops.txt, got %v", feed.ParseWarnings)
+	}
+
+	// And: agency.txt, which has no 0x80-0x9F bytes, is still transcoded
+	if got := feed.Agencies["agency1"].Name; got != "Tránsito Municipal" {
+		t.Errorf("expected agency.txt transcoded from Latin-1, got %q", got)
+	}
+}
+
+func TestSniffEncoding(t *testing.T) {
+	tests := []struct {
+		name      string
+		sample    string
+		truncated bool
+		expected  encodingDecision
+	}{
+		{"ascii", "stop_id,stop_name\n1,Main\n", false, decideUTF8},
+		{"utf-8", "1,Café\n", false, decideUTF8},
+		{"bom", "\xEF\xBB\xBFstop_id\n1,Caf\xE9\n", false, decideUTF8},
+		{"windows-1252", "1,Caf\xE9 \x96 Bar\n", false, decideLegacy},
+		{"undefined byte", "1,Caf\xE9\x81\n", false, decideUnsure},
+		{"rune cut at sample end", "1,Caf\xC3", true, decideUTF8},
+		{"truncated rune at file end", "1,Caf\xC3", false, decideLegacy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sniffEncoding([]byte(tt.sample), EncodingWindows1252, tt.truncated); got != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLegacyReader(t *testing.T) {
+	tests := []struct {
+		enc      Encoding
+		input    string
+		expected string
+	}{
+		{EncodingLatin1, "Caf\xE9 \xDCber", "Café Über"},
+		{EncodingWindows1252, "\x93Quote\x94 \x80 \x96", "“Quote” € –"},
+		{EncodingLatin1, "\x96", "\u0096"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.enc.String(), func(t *testing.T) {
+			got, err := io.ReadAll(&legacyReader{r: strings.NewReader(tt.input), enc: tt.enc})
+			if err != nil {
+				t.Fatalf("ReadAll failed: %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseEncoding(t *testing.T) {
+	for input, want := range map[string]Encoding{
+		"utf-8":        EncodingUTF8,
+		"latin1":       EncodingLatin1,
+		"ISO-8859-1":   EncodingLatin1,
+		"cp1252":       EncodingWindows1252,
+		"windows-1252": EncodingWindows1252,
+		"auto":         EncodingAuto,
+	} {
+		got, err := ParseEncoding(input)
+		if err != nil || got != want {
+			t.Errorf("ParseEncoding(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	if _, err := ParseEncoding("ebcdic"); err == nil {
+		t.Error("expected error for unknown encoding")
+	}
+}
+
+func TestEncodingText(t *testing.T) {
+	for _, e := range []Encoding{EncodingUTF8, EncodingLatin1, EncodingWindows1252, EncodingAuto} {
+		text, err := e.MarshalText()
+		if err != nil || string(text) != e.String() {
+			t.Errorf("%v.MarshalText() = %q, %v", e, text, err)
+		}
+		var got Encoding
+		if err := got.UnmarshalText(text); err != nil || got != e {
+			t.Errorf("UnmarshalText(%q) = %v, %v; want %v", text, got, err, e)
+		}
+	}
+	if _, err := Encoding(99).MarshalText(); err == nil {
+		t.Error("expected error marshaling an unknown encoding")
+	}
+	var got Encoding
+	if err := got.UnmarshalText([]byte("ebcdic")); err == nil {
+		t.Error("expected error for unknown encoding")
+	}
+}
@@ -2,67 +2,160 @@ package gtfs
 
 // Feed represents a complete GTFS feed
 type Feed struct {
-	Agencies          map[AgencyID]*Agency
-	AgencyOrder       []AgencyID // Tracks insertion order for deterministic output
-	Stops             map[StopID]*Stop
-	StopOrder         []StopID // Tracks insertion order for deterministic output
-	Routes            map[RouteID]*Route
-	RouteOrder        []RouteID // Tracks insertion order for deterministic output
-	Trips             map[TripID]*Trip
-	TripOrder         []TripID    // Tracks insertion order for deterministic output
-	StopTimes         []*StopTime // Keyed by TripID+Sequence (already ordered)
-	Calendars         map[ServiceID]*Calendar
-	CalendarOrder     []ServiceID // Tracks insertion order for deterministic output
-	CalendarDates     map[ServiceID][]*CalendarDate
-	CalendarDateOrder []ServiceID // Tracks insertion order for deterministic output
-	Shapes            map[ShapeID][]*ShapePoint
-	ShapeOrder        []ShapeID    // Tracks insertion order for deterministic output
-	Frequencies       []*Frequency // Already ordered
-	Transfers         []*Transfer  // Already ordered
-	FareAttributes    map[FareID]*FareAttribute
-	FareAttrOrder     []FareID             // Tracks insertion order for deterministic output
-	FareRules         []*FareRule          // Already ordered
-	FeedInfos         map[string]*FeedInfo // keyed by feed_id
-	FeedInfoOrder     []string             // Tracks insertion order for deterministic output
-	Areas             map[AreaID]*Area
-	AreaOrder         []AreaID   // Tracks insertion order for deterministic output
-	Pathways          []*Pathway // Already ordered
+	Agencies           map[AgencyID]*Agency
+	AgencyOrder        []AgencyID // Tracks insertion order for deterministic output
+	Stops              map[StopID]*Stop
+	StopOrder          []StopID // Tracks insertion order for deterministic output
+	Routes             map[RouteID]*Route
+	RouteOrder         []RouteID // Tracks insertion order for deterministic output
+	Trips              map[TripID]*Trip
+	TripOrder          []TripID    // Tracks insertion order for deterministic output
+	StopTimes          []*StopTime // Keyed by TripID+Sequence (already ordered)
+	Calendars          map[ServiceID]*Calendar
+	CalendarOrder      []ServiceID // Tracks insertion order for deterministic output
+	CalendarDates      map[ServiceID][]*CalendarDate
+	CalendarDateOrder  []ServiceID              // Tracks insertion order for deterministic output
+	Shapes             map[ShapeID][]ShapePoint // Points held by value, in file order
+	ShapeOrder         []ShapeID                // Tracks insertion order for deterministic output
+	Frequencies        []*Frequency             // Already ordered
+	Transfers          []*Transfer              // Already ordered
+	FareAttributes     map[FareID]*FareAttribute
+	FareAttrOrder      []FareID             // Tracks insertion order for deterministic output
+	FareRules          []*FareRule          // Already ordered
+	FeedInfos          map[string]*FeedInfo // keyed by feed_id
+	FeedInfoOrder      []string             // Tracks insertion order for deterministic output
+	Areas              map[AreaID]*Area
+	AreaOrder          []AreaID   // Tracks insertion order for deterministic output
+	Pathways           []*Pathway // Already ordered
+	Networks           map[NetworkID]*Network
+	NetworkOrder       []NetworkID         // Tracks insertion order for deterministic output
+	RouteNetworks      []*RouteNetwork     // Already ordered
+	FareLegRules       []*FareLegRule      // Already ordered
+	FareTransferRules  []*FareTransferRule // Already ordered
+	FareProducts       []*FareProduct      // Already ordered
+	Timeframes         []*Timeframe        // Already ordered
+	FareMedia          map[FareMediaID]*FareMedia
+	FareMediaOrder     []FareMediaID // Tracks insertion order for deterministic output
+	RiderCategories    map[RiderCategoryID]*RiderCategory
+	RiderCategoryOrder []RiderCategoryID // Tracks insertion order for deterministic output
 
 	// ColumnSets tracks which columns were present in each file when reading.
 	// Key is the filename (e.g., "stop_times.txt"), value is set of column names.
 	// Used during writing to only output columns that were present in source data.
 	ColumnSets map[string]map[string]bool
+
+	// ParseWarnings collects malformed rows and values found while reading
+	// (lenient mode), each located by file and line
+	ParseWarnings []*ParseError
+
+	// PartialRead lists files that were skipped when reading (see
+	// ReaderOptions.SkipFiles). A non-empty list means the feed is
+	// incomplete and must not be merged.
+	PartialRead []string
+
+	// RowsRead is the number of data rows read from each file, keyed by
+	// filename. Rows repeating an ID overwrite the earlier row, so it can
+	// exceed RowCounts. It is nil for feeds that were not read.
+	RowsRead map[string]int
+
+	// ContentHash is the hex SHA-256 of the raw bytes of the GTFS files
+	// read, by filename in read order, so byte-identical inputs have the
+	// same hash. It is empty for feeds that were not read.
+	ContentHash string
+
+	// Sources records which input feeds contributed each ID-keyed entity of
+	// a merged feed, by entity kind and merged ID (see SourceOf). It is nil
+	// for feeds that were read rather than merged.
+	Sources map[EntityKind]map[string][]int
 }
 
 // NewFeed creates an empty feed with all maps and slices initialized
 func NewFeed() *Feed {
 	return &Feed{
-		Agencies:          make(map[AgencyID]*Agency),
-		AgencyOrder:       make([]AgencyID, 0),
-		Stops:             make(map[StopID]*Stop),
-		StopOrder:         make([]StopID, 0),
-		Routes:            make(map[RouteID]*Route),
-		RouteOrder:        make([]RouteID, 0),
-		Trips:             make(map[TripID]*Trip),
-		TripOrder:         make([]TripID, 0),
-		StopTimes:         make([]*StopTime, 0),
-		Calendars:         make(map[ServiceID]*Calendar),
-		CalendarOrder:     make([]ServiceID, 0),
-		CalendarDates:     make(map[ServiceID][]*CalendarDate),
-		CalendarDateOrder: make([]ServiceID, 0),
-		Shapes:            make(map[ShapeID][]*ShapePoint),
-		ShapeOrder:        make([]ShapeID, 0),
-		Frequencies:       make([]*Frequency, 0),
-		Transfers:         make([]*Transfer, 0),
-		FareAttributes:    make(map[FareID]*FareAttribute),
-		FareAttrOrder:     make([]FareID, 0),
-		FareRules:         make([]*FareRule, 0),
-		FeedInfos:         make(map[string]*FeedInfo),
-		FeedInfoOrder:     make([]string, 0),
-		Areas:             make(map[AreaID]*Area),
-		AreaOrder:         make([]AreaID, 0),
-		Pathways:          make([]*Pathway, 0),
-		ColumnSets:        make(map[string]map[string]bool),
+		Agencies:           make(map[AgencyID]*Agency),
+		AgencyOrder:        make([]AgencyID, 0),
+		Stops:              make(map[StopID]*Stop),
+		StopOrder:          make([]StopID, 0),
+		Routes:             make(map[RouteID]*Route),
+		RouteOrder:         make([]RouteID, 0),
+		Trips:              make(map[TripID]*Trip),
+		TripOrder:          make([]TripID, 0),
+		StopTimes:          make([]*StopTime, 0),
+		Calendars:          make(map[ServiceID]*Calendar),
+		CalendarOrder:      make([]ServiceID, 0),
+		CalendarDates:      make(map[ServiceID][]*CalendarDate),
+		CalendarDateOrder:  make([]ServiceID, 0),
+		Shapes:             make(map[ShapeID][]ShapePoint),
+		ShapeOrder:         make([]ShapeID, 0),
+		Frequencies:        make([]*Frequency, 0),
+		Transfers:          make([]*Transfer, 0),
+		FareAttributes:     make(map[FareID]*FareAttribute),
+		FareAttrOrder:      make([]FareID, 0),
+		FareRules:          make([]*FareRule, 0),
+		FeedInfos:          make(map[string]*FeedInfo),
+		FeedInfoOrder:      make([]string, 0),
+		Areas:              make(map[AreaID]*Area),
+		AreaOrder:          make([]AreaID, 0),
+		Pathways:           make([]*Pathway, 0),
+		Networks:           make(map[NetworkID]*Network),
+		NetworkOrder:       make([]NetworkID, 0),
+		RouteNetworks:      make([]*RouteNetwork, 0),
+		FareLegRules:       make([]*FareLegRule, 0),
+		FareTransferRules:  make([]*FareTransferRule, 0),
+		FareProducts:       make([]*FareProduct, 0),
+		Timeframes:         make([]*Timeframe, 0),
+		FareMedia:          make(map[FareMediaID]*FareMedia),
+		FareMediaOrder:     make([]FareMediaID, 0),
+		RiderCategories:    make(map[RiderCategoryID]*RiderCategory),
+		RiderCategoryOrder: make([]RiderCategoryID, 0),
+		ColumnSets:         make(map[string]map[string]bool),
+	}
+}
+
+// EnsureInitialized lazily allocates any nil maps on the feed.
+// Feeds built as zero values (e.g. &gtfs.Feed{}) instead of through NewFeed
+// can then be safely used as merge sources, merge targets, or writer inputs.
+// Existing data is left untouched. Nil order slices are valid (appending to a
+// nil slice allocates), so only maps need initialization.
+func (f *Feed) EnsureInitialized() {
+	if f.Agencies == nil {
+		f.Agencies = make(map[AgencyID]*Agency)
+	}
+	if f.Stops == nil {
+		f.Stops = make(map[StopID]*Stop)
+	}
+	if f.Routes == nil {
+		f.Routes = make(map[RouteID]*Route)
+	}
+	if f.Trips == nil {
+		f.Trips = make(map[TripID]*Trip)
+	}
+	if f.Calendars == nil {
+		f.Calendars = make(map[ServiceID]*Calendar)
+	}
+	if f.CalendarDates == nil {
+		f.CalendarDates = make(map[ServiceID][]*CalendarDate)
+	}
+	if f.Shapes == nil {
+		f.Shapes = make(map[ShapeID][]ShapePoint)
+	}
+	if f.FareAttributes == nil {
+		f.FareAttributes = make(map[FareID]*FareAttribute)
+	}
+	if f.FeedInfos == nil {
+		f.FeedInfos = make(map[string]*FeedInfo)
+	}
+	if f.Areas == nil {
+		f.Areas = make(map[AreaID]*Area)
+	}
+	if f.Networks == nil {
+		f.Networks = make(map[NetworkID]*Network)
+	}
+	if f.FareMedia == nil {
+		f.FareMedia = make(map[FareMediaID]*FareMedia)
+	}
+	if f.RiderCategories == nil {
+		f.RiderCategories = make(map[RiderCategoryID]*RiderCategory)
 	}
 }
 
@@ -78,6 +171,15 @@ func (f *Feed) AddColumnSet(filename string, columns []string) {
 	f.ColumnSets[filename] = colSet
 }
 
+// AddColumn marks a single column as present for a given file.
+// Files without column tracking already include every column, so they
+// are left untracked.
+func (f *Feed) AddColumn(filename, column string) {
+	if colSet, exists := f.ColumnSets[filename]; exists {
+		colSet[column] = true
+	}
+}
+
 // MergeColumnSets merges column sets from another feed using union.
 // Columns present in ANY feed are kept (to match Java behavior).
 // This ensures we don't lose data when feeds have different optional columns.
@@ -185,8 +287,26 @@ func (f *Feed) AddArea(a *Area) {
 	f.AreaOrder = append(f.AreaOrder, a.ID)
 }
 
+// AddNetwork adds a network to both the map and order slice
+func (f *Feed) AddNetwork(n *Network) {
+	f.Networks[n.ID] = n
+	f.NetworkOrder = append(f.NetworkOrder, n.ID)
+}
+
+// AddFareMedia adds a fare media to both the map and order slice
+func (f *Feed) AddFareMedia(m *FareMedia) {
+	f.FareMedia[m.ID] = m
+	f.FareMediaOrder = append(f.FareMediaOrder, m.ID)
+}
+
+// AddRiderCategory adds a rider category to both the map and order slice
+func (f *Feed) AddRiderCategory(c *RiderCategory) {
+	f.RiderCategories[c.ID] = c
+	f.RiderCategoryOrder = append(f.RiderCategoryOrder, c.ID)
+}
+
 // AddShape adds a shape point to the map and tracks order for the shape ID
-func (f *Feed) AddShape(sp *ShapePoint) {
+func (f *Feed) AddShape(sp ShapePoint) {
 	// Track order only for first occurrence of this shape_id
 	if _, exists := f.Shapes[sp.ShapeID]; !exists {
 		f.ShapeOrder = append(f.ShapeOrder, sp.ShapeID)
@@ -194,6 +314,51 @@ func (f *Feed) AddShape(sp *ShapePoint) {
 	f.Shapes[sp.ShapeID] = append(f.Shapes[sp.ShapeID], sp)
 }
 
+// RowCounts returns the number of rows each GTFS file holds in the feed,
+// keyed by filename (e.g. "stop_times.txt"). Files without rows are omitted.
+func (f *Feed) RowCounts() map[string]int {
+	calendarDates := 0
+	for _, dates := range f.CalendarDates {
+		calendarDates += len(dates)
+	}
+	shapePoints := 0
+	for _, points := range f.Shapes {
+		shapePoints += len(points)
+	}
+
+	counts := make(map[string]int)
+	for filename, n := range map[string]int{
+		"agency.txt":              len(f.Agencies),
+		"stops.txt":               len(f.Stops),
+		"routes.txt":              len(f.Routes),
+		"trips.txt":               len(f.Trips),
+		"stop_times.txt":          len(f.StopTimes),
+		"calendar.txt":            len(f.Calendars),
+		"calendar_dates.txt":      calendarDates,
+		"shapes.txt":              shapePoints,
+		"frequencies.txt":         len(f.Frequencies),
+		"transfers.txt":           len(f.Transfers),
+		"fare_attributes.txt":     len(f.FareAttributes),
+		"fare_rules.txt":          len(f.FareRules),
+		"feed_info.txt":           len(f.FeedInfos),
+		"areas.txt":               len(f.Areas),
+		"pathways.txt":            len(f.Pathways),
+		"networks.txt":            len(f.Networks),
+		"route_networks.txt":      len(f.RouteNetworks),
+		"fare_leg_rules.txt":      len(f.FareLegRules),
+		"fare_transfer_rules.txt": len(f.FareTransferRules),
+		"fare_products.txt":       len(f.FareProducts),
+		"timeframes.txt":          len(f.Timeframes),
+		"fare_media.txt":          len(f.FareMedia),
+		"rider_categories.txt":    len(f.RiderCategories),
+	} {
+		if n > 0 {
+			counts[filename] = n
+		}
+	}
+	return counts
+}
+
 // SyncOrderSlices populates all order slices from their corresponding maps.
 // This is useful for tests that add entries directly to maps without using AddX methods.
 // Note: The order will be non-deterministic since Go maps don't preserve insertion order.
@@ -257,4 +422,53 @@ func (f *Feed) SyncOrderSlices() {
 	for id := range f.Areas {
 		f.AreaOrder = append(f.AreaOrder, id)
 	}
+
+	// Networks
+	f.NetworkOrder = make([]NetworkID, 0, len(f.Networks))
+	for id := range f.Networks {
+		f.NetworkOrder = append(f.NetworkOrder, id)
+	}
+
+	// FareMedia
+	f.FareMediaOrder = make([]FareMediaID, 0, len(f.FareMedia))
+	for id := range f.FareMedia {
+		f.FareMediaOrder = append(f.FareMediaOrder, id)
+	}
+
+	// RiderCategories
+	f.RiderCategoryOrder = make([]RiderCategoryID, 0, len(f.RiderCategories))
+	for id := range f.RiderCategories {
+		f.RiderCategoryOrder = append(f.RiderCategoryOrder, id)
+	}
+}
+
+// LegGroupIDs returns the leg groups the feed's fare leg rules define
+func (f *Feed) LegGroupIDs() map[LegGroupID]bool {
+	groups := make(map[LegGroupID]bool)
+	for _, rule := range f.FareLegRules {
+		if rule.LegGroupID != "" {
+			groups[rule.LegGroupID] = true
+		}
+	}
+	return groups
+}
+
+// FareProductIDs returns the fare products the feed's fare_products.txt
+// defines
+func (f *Feed) FareProductIDs() map[FareProductID]bool {
+	products := make(map[FareProductID]bool, len(f.FareProducts))
+	for _, product := range f.FareProducts {
+		products[product.ID] = true
+	}
+	return products
+}
+
+// TimeframeGroupIDs returns the timeframe groups the feed's timeframes.txt
+// defines
+func (f *Feed) TimeframeGroupIDs() map[TimeframeGroupID]bool {
+	groups := make(map[TimeframeGroupID]bool, len(f.Timeframes))
+	for _, tf := range f.Timeframes {
+		groups[tf.GroupID] = true
+	}
+	return groups
 }
@@ -253,7 +253,7 @@ func TestFeedAddCalendarDate(t *testing.T) {
 func TestFeedAddShapePoint(t *testing.T) {
 	feed := NewFeed()
 
-	shapePoint := &ShapePoint{
+	shapePoint := ShapePoint{
 		ShapeID:      "shape1",
 		Lat:          47.6062,
 		Lon:          -122.3321,
@@ -305,7 +305,7 @@ func TestFeedAddTransfer(t *testing.T) {
 		FromStopID:      "stop1",
 		ToStopID:        "stop2",
 		TransferType:    0, // Recommended transfer
-		MinTransferTime: 120,
+		MinTransferTime: intPtr(120),
 	}
 
 	feed.Transfers = append(feed.Transfers, transfer)
@@ -329,7 +329,7 @@ func TestFeedAddFareAttribute(t *testing.T) {
 		Price:         2.50,
 		CurrencyType:  "USD",
 		PaymentMethod: 0,
-		Transfers:     0,
+		Transfers:     intPtr(0),
 	}
 
 	feed.FareAttributes[fareAttr.FareID] = fareAttr
@@ -438,3 +438,49 @@ func TestFeedSetFeedInfo(t *testing.T) {
 		t.Errorf("expected publisher name 'Transit Authority', got '%s'", feed.FeedInfos["1"].PublisherName)
 	}
 }
+
+func TestFeedEnsureInitialized(t *testing.T) {
+	// Zero-value feed (not built via NewFeed)
+	feed := &Feed{}
+	feed.EnsureInitialized()
+
+	if feed.Agencies == nil || feed.Stops == nil || feed.Routes == nil || feed.Trips == nil {
+		t.Fatal("expected core entity maps to be initialized")
+	}
+	if feed.Calendars == nil || feed.CalendarDates == nil || feed.Shapes == nil {
+		t.Fatal("expected calendar and shape maps to be initialized")
+	}
+	if feed.FareAttributes == nil || feed.FeedInfos == nil || feed.Areas == nil {
+		t.Fatal("expected optional entity maps to be initialized")
+	}
+
+	// Writing into the maps must not panic
+	feed.AddAgency(&Agency{ID: "a1", Name: "Agency"})
+	feed.AddFeedInfo(&FeedInfo{FeedID: "1"})
+	feed.AddArea(&Area{ID: "area1"})
+
+	// Existing data is preserved on subsequent calls
+	feed.EnsureInitialized()
+	if len(feed.Agencies) != 1 {
+		t.Errorf("expected 1 agency after re-initialization, got %d", len(feed.Agencies))
+	}
+}
+
+func TestFeedAddColumn(t *testing.T) {
+	feed := NewFeed()
+	feed.AddColumnSet("routes.txt", []string{"route_id", "route_type"})
+
+	feed.AddColumn("routes.txt", "agency_id")
+	if !feed.HasColumn("routes.txt", "agency_id") {
+		t.Error("expected agency_id to be tracked after AddColumn")
+	}
+	if !feed.HasColumn("routes.txt", "route_id") {
+		t.Error("expected existing columns to be kept")
+	}
+
+	// Untracked files stay untracked so every column remains included
+	feed.AddColumn("agency.txt", "agency_id")
+	if _, tracked := feed.ColumnSets["agency.txt"]; tracked {
+		t.Error("expected agency.txt to remain untracked")
+	}
+}
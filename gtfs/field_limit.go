@@ -0,0 +1,286 @@
+package gtfs
+
+import (
+	"fmt"
+	"io"
+)
+
+// DefaultMaxFieldLength is the longest field, in bytes, read by default;
+// see ReaderOptions.MaxFieldLength
+const DefaultMaxFieldLength = 1 << 20
+
+// fieldTooLongError reports a field longer than the reader's limit, located
+// by the line it starts on and its index in its record
+type fieldTooLongError struct {
+	line  int
+	field int
+	max   int
+}
+
+// Error implements error
+func (e *fieldTooLongError) Error() string {
+	return fmt.Sprintf("line %d: field %d is longer than %d bytes", e.line, e.field+1, e.max)
+}
+
+// parseError describes e as a ParseError in filename, naming the field's
+// column from header
+func (e *fieldTooLongError) parseError(filename string, header []string) *ParseError {
+	column := fmt.Sprintf("field %d", e.field+1)
+	if e.field < len(header) {
+		column = header[e.field]
+	}
+	return &ParseError{
+		File:    filename,
+		Line:    e.line,
+		Column:  column,
+		Message: fmt.Sprintf("%s is longer than %d bytes", column, e.max),
+	}
+}
+
+// fieldTruncation records a field fieldLimiter truncated
+type fieldTruncation struct {
+	line  int
+	field int
+}
+
+// fieldLimiter passes CSV through, tracking quoting well enough to measure
+// each field, so that no field longer than max bytes reaches the
+// csv.Reader, which would otherwise hold it in memory however long it is.
+// A longer field fails the read with a *fieldTooLongError or, when
+// truncate is set, is cut to at most max bytes, at a character boundary,
+// and recorded in truncated.
+type fieldLimiter struct {
+	r        io.Reader
+	max      int
+	truncate bool
+
+	line      int  // 1-based line of the byte being scanned
+	field     int  // index of the current field in its record
+	fieldLine int  // line the current field starts on
+	length    int  // bytes of the current field kept so far
+	atStart   bool // only leading spaces seen in the current field
+	quoted    bool // inside a quoted field
+	quotePend bool // a quote seen in a quoted field, not yet known to be escaped or closing
+	dropping  bool // truncating the current field
+	// charLeft is the bytes left of the character being scanned, which
+	// are kept or dropped with its first
+	charLeft  int
+	truncated []fieldTruncation
+
+	// buf holds bytes read from r; out[pos:] those scanned but not yet
+	// returned; err the error to return once out is drained
+	buf []byte
+	out []byte
+	pos int
+	err error
+}
+
+// newFieldLimiter limits the fields read from r as opts directs, or
+// returns r if opts sets no limit
+func newFieldLimiter(r io.Reader, opts *ReaderOptions) io.Reader {
+	max, truncate := DefaultMaxFieldLength, false
+	if opts != nil {
+		if opts.MaxFieldLength < 0 {
+			return r
+		}
+		if opts.MaxFieldLength > 0 {
+			max = opts.MaxFieldLength
+		}
+		truncate = opts.TruncateLongFields
+	}
+	return &fieldLimiter{r: r, max: max, truncate: truncate, line: 1, fieldLine: 1, atStart: true}
+}
+
+// Read implements io.Reader
+func (f *fieldLimiter) Read(p []byte) (int, error) {
+	for f.pos == len(f.out) {
+		if f.err != nil {
+			return 0, f.err
+		}
+		if cap(f.buf) == 0 {
+			f.buf = make([]byte, 32*1024)
+		}
+		n, err := f.r.Read(f.buf[:cap(f.buf)])
+		f.out, f.pos = f.out[:0], 0
+		for i := 0; i < n; i++ {
+			if run := f.plainRun(f.buf[i:n]); run > 0 {
+				f.out = append(f.out, f.buf[i:i+run]...)
+				i += run - 1
+				continue
+			}
+			if scanErr := f.scan(f.buf[i]); scanErr != nil {
+				err = scanErr
+				break
+			}
+		}
+		if err == io.EOF && f.quotePend {
+			f.out = append(f.out, '"')
+			f.quotePend = false
+		}
+		f.err = err
+	}
+	n := copy(p, f.out[f.pos:])
+	f.pos += n
+	return n, nil
+}
+
+// plainRun counts the leading bytes of p that scan would keep as they are
+// without any change of state but the field's length: ASCII content
+// other than quotes and separators, while the field has room for it. The
+// field's length and atStart are updated for them.
+func (f *fieldLimiter) plainRun(p []byte) int {
+	if f.quotePend || f.dropping || f.charLeft > 0 {
+		return 0
+	}
+	room := min(len(p), f.max-f.length)
+	n := 0
+	for n < room {
+		b := p[n]
+		if b >= 0x80 || b == '"' || b == ',' || b == '\n' {
+			break
+		}
+		if b != ' ' && b != '\t' {
+			f.atStart = false
+		}
+		n++
+	}
+	f.length += n
+	return n
+}
+
+// scan processes one byte, appending what is kept to f.out
+func (f *fieldLimiter) scan(b byte) error {
+	if f.quotePend {
+		f.quotePend = false
+		if b == '"' {
+			// An escaped quote, kept or dropped as one character
+			f.charLeft = 0
+			if err := f.startCharacter(2); err != nil {
+				return err
+			}
+			f.content('"')
+			f.content('"')
+			return nil
+		}
+		// The pending quote closed the field
+		f.out = append(f.out, '"')
+		f.quoted = false
+	}
+
+	switch {
+	case f.quoted && b == '"':
+		f.quotePend = true
+		return nil
+	case f.quoted:
+		if b == '\n' {
+			f.line++
+		}
+		return f.next(b)
+	case b == '"' && f.atStart:
+		f.quoted = true
+		f.atStart = false
+		f.out = append(f.out, b)
+		return nil
+	case b == ',':
+		f.endField()
+		f.field++
+		f.out = append(f.out, b)
+		return nil
+	case b == '\n':
+		f.endField()
+		f.field = 0
+		f.line++
+		f.fieldLine = f.line
+		f.out = append(f.out, b)
+		return nil
+	default:
+		if b != ' ' && b != '\t' {
+			f.atStart = false
+		}
+		return f.next(b)
+	}
+}
+
+// endField resets the per-field state at a field's end
+func (f *fieldLimiter) endField() {
+	f.length = 0
+	f.atStart = true
+	f.dropping = false
+	f.charLeft = 0
+	f.fieldLine = f.line
+}
+
+// next keeps or drops b, a byte of the current field's content. Whether a
+// character fits is decided at its first byte, from the length its UTF-8
+// leading byte gives, so truncation never splits one.
+func (f *fieldLimiter) next(b byte) error {
+	if f.charLeft == 0 {
+		if err := f.startCharacter(utf8SequenceLength(b)); err != nil {
+			return err
+		}
+	}
+	f.content(b)
+	return nil
+}
+
+// startCharacter decides whether the current field has room for a
+// character of n bytes, failing or starting to truncate if not
+func (f *fieldLimiter) startCharacter(n int) error {
+	f.charLeft = n
+	if f.dropping || f.length+n <= f.max {
+		return nil
+	}
+	if !f.truncate {
+		return &fieldTooLongError{line: f.fieldLine, field: f.field, max: f.max}
+	}
+	f.dropping = true
+	f.truncated = append(f.truncated, fieldTruncation{line: f.fieldLine, field: f.field})
+	return nil
+}
+
+// content keeps b, a byte of the current character, unless the field is
+// being truncated
+func (f *fieldLimiter) content(b byte) {
+	if f.charLeft > 0 {
+		f.charLeft--
+	}
+	if f.dropping {
+		return
+	}
+	f.length++
+	f.out = append(f.out, b)
+}
+
+// utf8SequenceLength returns the length of the UTF-8 sequence b begins,
+// or 1 if b cannot begin one
+func utf8SequenceLength(b byte) int {
+	switch {
+	case b >= 0xF0 && b < 0xF8:
+		return 4
+	case b >= 0xE0:
+		return 3
+	case b >= 0xC0:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// truncationWarnings describes the fields f truncated in filename, naming
+// their columns from header
+func (f *fieldLimiter) truncationWarnings(filename string, header []string) []*ParseError {
+	warnings := make([]*ParseError, 0, len(f.truncated))
+	for _, t := range f.truncated {
+		column := fmt.Sprintf("field %d", t.field+1)
+		if t.field < len(header) {
+			column = header[t.field]
+		}
+		warnings = append(warnings, &ParseError{
+			File:    filename,
+			Line:    t.line,
+			Column:  column,
+			Message: fmt.Sprintf("%s truncated to %d bytes", column, f.max),
+		})
+	}
+	return warnings
+}
@@ -0,0 +1,200 @@
+package gtfs
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// GeoJSONOptions configures WriteGeoJSON. The zero value writes every stop
+// and route without source properties.
+type GeoJSONOptions struct {
+	// SourcePrefixes gives, by input index (see Feed.Sources), the prefix
+	// of each input feed of a merged feed. When set, each feature's
+	// "source" property lists the prefixes of the inputs that contributed
+	// its stop or route.
+	SourcePrefixes []string
+}
+
+// geoJSONPrecision is the number of decimal places of the coordinates
+// written, matching the default of the CSV writer
+const geoJSONPrecision = 6
+
+// geoJSONFeatureCollection, geoJSONFeature and geoJSONGeometry hold the
+// minimal subset of GeoJSON (RFC 7946) WriteGeoJSON writes
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string          `json:"type"`
+	Geometry   geoJSONGeometry `json:"geometry"`
+	Properties map[string]any  `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// WriteGeoJSON writes feed to w as a GeoJSON FeatureCollection for a quick
+// look at it on a map: each stop with coordinates as a Point, with its id,
+// name and source, followed by each route's distinct geometries as
+// LineStrings, with its id, name, color and source. A route is drawn along
+// the shapes of its trips, and along the stops of those without a shape,
+// one LineString per shape or stop pattern. Features follow the order of
+// stops.txt, routes.txt and trips.txt.
+func WriteGeoJSON(feed *Feed, w io.Writer, opts GeoJSONOptions) error {
+	collection := geoJSONFeatureCollection{Type: "FeatureCollection", Features: []geoJSONFeature{}}
+
+	for _, id := range feed.StopOrder {
+		stop := feed.Stops[id]
+		if stop == nil || !hasCoordinates(stop.Lat, stop.Lon) {
+			continue
+		}
+		properties := map[string]any{"id": string(stop.ID), "name": stop.Name}
+		opts.addSource(properties, feed, KindStop, string(stop.ID))
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONGeometry{Type: "Point", Coordinates: geoJSONPosition(stop.Lat, stop.Lon)},
+			Properties: properties,
+		})
+	}
+
+	tripsByRoute := make(map[RouteID][]*Trip)
+	for _, id := range feed.TripOrder {
+		if trip := feed.Trips[id]; trip != nil {
+			tripsByRoute[trip.RouteID] = append(tripsByRoute[trip.RouteID], trip)
+		}
+	}
+	stopTimesByTrip := make(map[TripID][]*StopTime)
+	for _, st := range feed.StopTimes {
+		stopTimesByTrip[st.TripID] = append(stopTimesByTrip[st.TripID], st)
+	}
+
+	for _, id := range feed.RouteOrder {
+		route := feed.Routes[id]
+		if route == nil {
+			continue
+		}
+		drawn := make(map[string]bool)
+		for _, trip := range tripsByRoute[route.ID] {
+			var key string
+			var stopTimes []*StopTime
+			points, hasShape := feed.Shapes[trip.ShapeID]
+			hasShape = hasShape && trip.ShapeID != ""
+			if hasShape {
+				key = "shape:" + string(trip.ShapeID)
+			} else {
+				stopTimes = stopTimesByTrip[trip.ID]
+				sort.SliceStable(stopTimes, func(i, j int) bool {
+					return stopTimes[i].StopSequence < stopTimes[j].StopSequence
+				})
+				stopIDs := make([]string, len(stopTimes))
+				for i, st := range stopTimes {
+					stopIDs[i] = string(st.StopID)
+				}
+				key = "stops:" + strings.Join(stopIDs, "\x00")
+			}
+			if drawn[key] {
+				continue
+			}
+			drawn[key] = true
+
+			var line [][]float64
+			if hasShape {
+				line = shapeLine(points)
+			} else {
+				line = stopLine(feed, stopTimes)
+			}
+			if len(line) < 2 {
+				continue
+			}
+
+			properties := map[string]any{"id": string(route.ID), "name": route.ShortName}
+			if route.ShortName == "" {
+				properties["name"] = route.LongName
+			}
+			if route.Color != "" {
+				// stroke styles the line in viewers following simplestyle
+				properties["color"] = "#" + route.Color
+				properties["stroke"] = "#" + route.Color
+			}
+			if hasShape {
+				properties["shape_id"] = string(trip.ShapeID)
+			}
+			opts.addSource(properties, feed, KindRoute, string(route.ID))
+			collection.Features = append(collection.Features, geoJSONFeature{
+				Type:       "Feature",
+				Geometry:   geoJSONGeometry{Type: "LineString", Coordinates: line},
+				Properties: properties,
+			})
+		}
+	}
+
+	return json.NewEncoder(w).Encode(collection)
+}
+
+// addSource sets properties["source"] to the prefixes of the inputs that
+// contributed the entity, if SourcePrefixes is set and feed records them
+func (opts GeoJSONOptions) addSource(properties map[string]any, feed *Feed, kind EntityKind, id string) {
+	if opts.SourcePrefixes == nil {
+		return
+	}
+	indices := feed.SourceOf(kind, id)
+	if len(indices) == 0 {
+		return
+	}
+	prefixes := make([]string, 0, len(indices))
+	for _, i := range indices {
+		if i < len(opts.SourcePrefixes) {
+			prefixes = append(prefixes, opts.SourcePrefixes[i])
+		}
+	}
+	properties["source"] = prefixes
+}
+
+// shapeLine returns the positions of a shape's points in shape_pt_sequence
+// order
+func shapeLine(points []ShapePoint) [][]float64 {
+	sorted := make([]ShapePoint, len(points))
+	copy(sorted, points)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Sequence < sorted[j].Sequence
+	})
+	line := make([][]float64, 0, len(sorted))
+	for _, p := range sorted {
+		if hasCoordinates(p.Lat, p.Lon) {
+			line = append(line, geoJSONPosition(p.Lat, p.Lon))
+		}
+	}
+	return line
+}
+
+// stopLine returns the positions of the stops of a trip's stop times, in
+// order, skipping stops the feed lacks or that have no coordinates
+func stopLine(feed *Feed, stopTimes []*StopTime) [][]float64 {
+	line := make([][]float64, 0, len(stopTimes))
+	for _, st := range stopTimes {
+		if stop := feed.Stops[st.StopID]; stop != nil && hasCoordinates(stop.Lat, stop.Lon) {
+			line = append(line, geoJSONPosition(stop.Lat, stop.Lon))
+		}
+	}
+	return line
+}
+
+// hasCoordinates reports whether lat and lon are set; GTFS leaves them
+// empty, read as zero, for generic nodes and boarding areas
+func hasCoordinates(lat, lon float64) bool {
+	return lat != 0 || lon != 0
+}
+
+// geoJSONPosition returns a GeoJSON position, longitude first, rounded to
+// geoJSONPrecision decimal places
+func geoJSONPosition(lat, lon float64) []float64 {
+	scale := math.Pow10(geoJSONPrecision)
+	return []float64{math.Round(lon*scale) / scale, math.Round(lat*scale) / scale}
+}
@@ -0,0 +1,94 @@
+package gtfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files under testdata/geojson")
+
+func TestWriteGeoJSONGolden(t *testing.T) {
+	for _, name := range []string{"simple_a", "all_optional_feed"} {
+		t.Run(name, func(t *testing.T) {
+			// Given: a feed with stop-to-stop routes (simple_a) or shapes
+			// (all_optional_feed)
+			feed, err := ReadFromPath(filepath.Join("../testdata", name))
+			if err != nil {
+				t.Fatalf("ReadFromPath failed: %v", err)
+			}
+
+			// When: written as GeoJSON
+			var buf bytes.Buffer
+			if err := WriteGeoJSON(feed, &buf, GeoJSONOptions{}); err != nil {
+				t.Fatalf("WriteGeoJSON failed: %v", err)
+			}
+
+			// Then: it matches the golden file
+			golden := filepath.Join("../testdata/geojson", name+".geojson")
+			if *updateGolden {
+				if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+					t.Fatalf("failed to update %s: %v", golden, err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read %s (run go test -update to create it): %v", golden, err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("Expected %s, got:\n%s", golden, buf.String())
+			}
+		})
+	}
+}
+
+func TestWriteGeoJSONSources(t *testing.T) {
+	// Given: a merged feed recording which inputs contributed a stop and a
+	// route
+	feed, err := ReadFromPath("../testdata/simple_a")
+	if err != nil {
+		t.Fatalf("ReadFromPath failed: %v", err)
+	}
+	feed.AddSource(KindStop, "stop_a1", 0)
+	feed.AddSource(KindStop, "stop_a1", 1)
+	feed.AddSource(KindRoute, "route_a1", 1)
+
+	// When: written as GeoJSON with the inputs' prefixes
+	var buf bytes.Buffer
+	if err := WriteGeoJSON(feed, &buf, GeoJSONOptions{SourcePrefixes: []string{"", "a-"}}); err != nil {
+		t.Fatalf("WriteGeoJSON failed: %v", err)
+	}
+	var collection struct {
+		Features []struct {
+			Geometry struct {
+				Type string `json:"type"`
+			} `json:"geometry"`
+			Properties struct {
+				ID     string   `json:"id"`
+				Source []string `json:"source"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &collection); err != nil {
+		t.Fatalf("invalid GeoJSON: %v", err)
+	}
+
+	// Then: each feature lists the prefixes of its inputs, and features
+	// without provenance list none
+	want := map[string][]string{
+		"Point stop_a1":       {"", "a-"},
+		"Point stop_a2":       nil,
+		"LineString route_a1": {"a-"},
+		"LineString route_a2": nil,
+	}
+	for _, f := range collection.Features {
+		key := f.Geometry.Type + " " + f.Properties.ID
+		if expected, ok := want[key]; ok && !slices.Equal(f.Properties.Source, expected) {
+			t.Errorf("%s: expected source %q, got %q", key, expected, f.Properties.Source)
+		}
+	}
+}
@@ -25,6 +25,27 @@ type FareID string
 // AreaID is a unique identifier for an area
 type AreaID string
 
+// NetworkID is a unique identifier for a network
+type NetworkID string
+
+// LegGroupID identifies a group of fare leg rules, which fare transfer
+// rules refer to
+type LegGroupID string
+
+// FareProductID identifies a fare product, which may have several
+// fare_products.txt rows, one per rider category and fare media
+type FareProductID string
+
+// TimeframeGroupID identifies a group of timeframes, which fare leg rules
+// refer to
+type TimeframeGroupID string
+
+// FareMediaID is a unique identifier for a fare media
+type FareMediaID string
+
+// RiderCategoryID is a unique identifier for a rider category
+type RiderCategoryID string
+
 // Agency represents a transit agency (agency.txt)
 type Agency struct {
 	ID       AgencyID
@@ -67,8 +88,9 @@ type Route struct {
 	Color             string
 	TextColor         string
 	SortOrder         *int // Pointer to distinguish "not set" (nil) from "set to 0"
-	ContinuousPickup  int
-	ContinuousDropOff int
+	ContinuousPickup  *int // nil (empty) means the GTFS default, 1 (no continuous stopping)
+	ContinuousDropOff *int // nil (empty) means the GTFS default, 1 (no continuous stopping)
+	NetworkID         NetworkID
 }
 
 // Trip represents a trip (trips.txt)
@@ -95,8 +117,8 @@ type StopTime struct {
 	StopHeadsign      string
 	PickupType        int
 	DropOffType       int
-	ContinuousPickup  int
-	ContinuousDropOff int
+	ContinuousPickup  *int     // nil (empty) means the GTFS default, 1 (no continuous stopping)
+	ContinuousDropOff *int     // nil (empty) means the GTFS default, 1 (no continuous stopping)
 	ShapeDistTraveled *float64 // Pointer to distinguish "not set" (nil) from "set to 0"
 	Timepoint         *int     // Pointer to distinguish "not set" (nil) from "set to 0"
 }
@@ -145,7 +167,7 @@ type Transfer struct {
 	FromStopID      StopID
 	ToStopID        StopID
 	TransferType    int
-	MinTransferTime int
+	MinTransferTime *int // Pointer to distinguish "not set" (nil) from "set to 0"
 	FromRouteID     RouteID
 	ToRouteID       RouteID
 	FromTripID      TripID
@@ -158,11 +180,11 @@ type FareAttribute struct {
 	Price            float64
 	CurrencyType     string
 	PaymentMethod    int
-	Transfers        int
+	Transfers        *int // nil (empty) means unlimited, 0 means no transfers
 	AgencyID         AgencyID
-	TransferDuration int
-	YouthPrice       float64
-	SeniorPrice      float64
+	TransferDuration *int     // Pointer to distinguish "not set" (nil) from "set to 0"
+	YouthPrice       *float64 // Pointer to distinguish "not set" (nil) from "set to 0"
+	SeniorPrice      *float64 // Pointer to distinguish "not set" (nil) from "set to 0"
 }
 
 // FareRule represents fare rules (fare_rules.txt)
@@ -186,6 +208,10 @@ type FeedInfo struct {
 	ContactEmail  string
 	ContactURL    string
 	FeedID        string
+
+	// SourceFeed identifies the input feed this row came from (e.g. the
+	// input file basename). It is set during merge and is not written.
+	SourceFeed string
 }
 
 // Area represents a geographic area (areas.txt)
@@ -194,6 +220,83 @@ type Area struct {
 	Name string
 }
 
+// Network represents a group of routes for fares (networks.txt)
+type Network struct {
+	ID   NetworkID
+	Name string
+}
+
+// RouteNetwork assigns a route to a network (route_networks.txt). A route
+// belongs to at most one network.
+type RouteNetwork struct {
+	NetworkID NetworkID
+	RouteID   RouteID
+}
+
+// FareLegRule represents a Fares v2 rule for the fare of a leg
+// (fare_leg_rules.txt). Rows sharing a LegGroupID form one leg group.
+type FareLegRule struct {
+	LegGroupID           LegGroupID
+	NetworkID            NetworkID
+	FromAreaID           AreaID
+	ToAreaID             AreaID
+	FromTimeframeGroupID TimeframeGroupID
+	ToTimeframeGroupID   TimeframeGroupID
+	FareProductID        FareProductID
+	RulePriority         *int // Pointer to distinguish "not set" (nil) from "set to 0"
+}
+
+// FareTransferRule represents a Fares v2 rule for the fare of a transfer
+// between legs of two leg groups (fare_transfer_rules.txt)
+type FareTransferRule struct {
+	FromLegGroupID    LegGroupID
+	ToLegGroupID      LegGroupID
+	TransferCount     *int
+	DurationLimit     *int
+	DurationLimitType *int
+	FareTransferType  int
+	FareProductID     FareProductID
+}
+
+// FareProduct represents a Fares v2 fare product's price for one rider
+// category and fare media (fare_products.txt). Rows sharing an ID form one
+// fare product.
+type FareProduct struct {
+	ID              FareProductID
+	Name            string
+	RiderCategoryID RiderCategoryID
+	FareMediaID     FareMediaID
+	Amount          string // As written, since its precision depends on the currency
+	Currency        string
+}
+
+// Timeframe represents a Fares v2 time of day and service during which a
+// fare leg rule applies (timeframes.txt). Rows sharing a GroupID form one
+// timeframe group.
+type Timeframe struct {
+	GroupID   TimeframeGroupID
+	StartTime string
+	EndTime   string
+	ServiceID ServiceID
+}
+
+// FareMedia represents a Fares v2 fare media, such as a transit card or a
+// mobile app (fare_media.txt)
+type FareMedia struct {
+	ID   FareMediaID
+	Name string
+	Type int
+}
+
+// RiderCategory represents a Fares v2 category of riders, such as seniors
+// (rider_categories.txt)
+type RiderCategory struct {
+	ID                    RiderCategoryID
+	Name                  string
+	IsDefaultFareCategory *int // Pointer to distinguish "not set" (nil) from "set to 0"
+	EligibilityURL        string
+}
+
 // Pathway represents a station pathway (pathways.txt)
 type Pathway struct {
 	ID                   string
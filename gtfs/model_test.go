@@ -87,8 +87,8 @@ func TestRouteFields(t *testing.T) {
 		{"Color", "string"},
 		{"TextColor", "string"},
 		{"SortOrder", "*int"},
-		{"ContinuousPickup", "int"},
-		{"ContinuousDropOff", "int"},
+		{"ContinuousPickup", "*int"},
+		{"ContinuousDropOff", "*int"},
 	}
 
 	checkFields(t, reflect.TypeOf(Route{}), expected)
@@ -121,8 +121,8 @@ func TestStopTimeFields(t *testing.T) {
 		{"StopHeadsign", "string"},
 		{"PickupType", "int"},
 		{"DropOffType", "int"},
-		{"ContinuousPickup", "int"},
-		{"ContinuousDropOff", "int"},
+		{"ContinuousPickup", "*int"},
+		{"ContinuousDropOff", "*int"},
 		{"ShapeDistTraveled", "*float64"},
 		{"Timepoint", "*int"},
 	}
@@ -186,7 +186,7 @@ func TestTransferFields(t *testing.T) {
 		{"FromStopID", "gtfs.StopID"},
 		{"ToStopID", "gtfs.StopID"},
 		{"TransferType", "int"},
-		{"MinTransferTime", "int"},
+		{"MinTransferTime", "*int"},
 	}
 
 	checkFields(t, reflect.TypeOf(Transfer{}), expected)
@@ -198,9 +198,11 @@ func TestFareAttributeFields(t *testing.T) {
 		{"Price", "float64"},
 		{"CurrencyType", "string"},
 		{"PaymentMethod", "int"},
-		{"Transfers", "int"},
+		{"Transfers", "*int"},
 		{"AgencyID", "gtfs.AgencyID"},
-		{"TransferDuration", "int"},
+		{"TransferDuration", "*int"},
+		{"YouthPrice", "*float64"},
+		{"SeniorPrice", "*float64"},
 	}
 
 	checkFields(t, reflect.TypeOf(FareAttribute{}), expected)
@@ -243,6 +245,77 @@ func TestAreaFields(t *testing.T) {
 	checkFields(t, reflect.TypeOf(Area{}), expected)
 }
 
+func TestNetworkFields(t *testing.T) {
+	expected := []fieldSpec{
+		{"ID", "gtfs.NetworkID"},
+		{"Name", "string"},
+	}
+
+	checkFields(t, reflect.TypeOf(Network{}), expected)
+}
+
+func TestRouteNetworkFields(t *testing.T) {
+	expected := []fieldSpec{
+		{"NetworkID", "gtfs.NetworkID"},
+		{"RouteID", "gtfs.RouteID"},
+	}
+
+	checkFields(t, reflect.TypeOf(RouteNetwork{}), expected)
+}
+
+func TestFareLegRuleFields(t *testing.T) {
+	expected := []fieldSpec{
+		{"LegGroupID", "gtfs.LegGroupID"},
+		{"NetworkID", "gtfs.NetworkID"},
+		{"FromAreaID", "gtfs.AreaID"},
+		{"ToAreaID", "gtfs.AreaID"},
+		{"FromTimeframeGroupID", "gtfs.TimeframeGroupID"},
+		{"ToTimeframeGroupID", "gtfs.TimeframeGroupID"},
+		{"FareProductID", "gtfs.FareProductID"},
+		{"RulePriority", "*int"},
+	}
+
+	checkFields(t, reflect.TypeOf(FareLegRule{}), expected)
+}
+
+func TestFareTransferRuleFields(t *testing.T) {
+	expected := []fieldSpec{
+		{"FromLegGroupID", "gtfs.LegGroupID"},
+		{"ToLegGroupID", "gtfs.LegGroupID"},
+		{"TransferCount", "*int"},
+		{"DurationLimit", "*int"},
+		{"DurationLimitType", "*int"},
+		{"FareTransferType", "int"},
+		{"FareProductID", "gtfs.FareProductID"},
+	}
+
+	checkFields(t, reflect.TypeOf(FareTransferRule{}), expected)
+}
+
+func TestFareProductFields(t *testing.T) {
+	expected := []fieldSpec{
+		{"ID", "gtfs.FareProductID"},
+		{"Name", "string"},
+		{"RiderCategoryID", "gtfs.RiderCategoryID"},
+		{"FareMediaID", "gtfs.FareMediaID"},
+		{"Amount", "string"},
+		{"Currency", "string"},
+	}
+
+	checkFields(t, reflect.TypeOf(FareProduct{}), expected)
+}
+
+func TestTimeframeFields(t *testing.T) {
+	expected := []fieldSpec{
+		{"GroupID", "gtfs.TimeframeGroupID"},
+		{"StartTime", "string"},
+		{"EndTime", "string"},
+		{"ServiceID", "gtfs.ServiceID"},
+	}
+
+	checkFields(t, reflect.TypeOf(Timeframe{}), expected)
+}
+
 func TestPathwayFields(t *testing.T) {
 	expected := []fieldSpec{
 		{"ID", "string"},
@@ -0,0 +1,63 @@
+package gtfs
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// SortTripRows reorders StopTimes and Frequencies so that each trip's rows
+// are contiguous, stop_times ordered by stop_sequence and frequencies by
+// start_time. Trips keep the order in which their first row appears, and
+// rows that compare equal keep their relative order.
+func (f *Feed) SortTripRows() {
+	sortByTrip(f.StopTimes, func(st *StopTime) TripID { return st.TripID }, func(a, b *StopTime) int {
+		return cmp.Compare(a.StopSequence, b.StopSequence)
+	})
+	sortByTrip(f.Frequencies, func(fr *Frequency) TripID { return fr.TripID }, func(a, b *Frequency) int {
+		return cmp.Compare(TimeSeconds(a.StartTime), TimeSeconds(b.StartTime))
+	})
+}
+
+// sortByTrip stably sorts rows by the position of their trip's first row,
+// then by compare
+func sortByTrip[T any](rows []T, trip func(T) TripID, compare func(a, b T) int) {
+	first := make(map[TripID]int)
+	for i, row := range rows {
+		if _, seen := first[trip(row)]; !seen {
+			first[trip(row)] = i
+		}
+	}
+	slices.SortStableFunc(rows, func(a, b T) int {
+		if c := cmp.Compare(first[trip(a)], first[trip(b)]); c != 0 {
+			return c
+		}
+		return compare(a, b)
+	})
+}
+
+// FormatTime formats seconds since midnight as a GTFS time (HH:MM:SS),
+// the inverse of TimeSeconds
+func FormatTime(seconds int) string {
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+// TimeSeconds converts a GTFS time (H:MM:SS, possibly past 24:00:00) to
+// seconds since midnight, or -1 if it is empty or malformed
+func TimeSeconds(s string) int {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 3 {
+		return -1
+	}
+	seconds := 0
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return -1
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds
+}
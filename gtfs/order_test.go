@@ -0,0 +1,60 @@
+package gtfs
+
+import "testing"
+
+func TestSortTripRows(t *testing.T) {
+	// Given: a feed whose stop_times and frequencies are interleaved and
+	// out of order
+	feed, err := ReadFromPath("../testdata/shuffled_stop_times")
+	if err != nil {
+		t.Fatalf("failed to read feed: %v", err)
+	}
+
+	// When: sorted
+	feed.SortTripRows()
+
+	// Then: each trip's stop_times are contiguous and in stop_sequence
+	// order, trips in order of first appearance
+	expected := []struct {
+		trip TripID
+		seq  int
+	}{{"loop_am", 1}, {"loop_am", 2}, {"loop_am", 3}, {"loop_pm", 1}, {"loop_pm", 2}, {"loop_pm", 3}}
+	for i, st := range feed.StopTimes {
+		if st.TripID != expected[i].trip || st.StopSequence != expected[i].seq {
+			t.Errorf("row %d: expected %s seq %d, got %s seq %d", i, expected[i].trip, expected[i].seq, st.TripID, st.StopSequence)
+		}
+	}
+
+	// And: frequencies are grouped by trip and ordered by start_time as a
+	// time, not a string
+	expectedStarts := []string{"6:00:00", "10:00:00", "16:00:00"}
+	for i, f := range feed.Frequencies {
+		if f.StartTime != expectedStarts[i] {
+			t.Errorf("frequency %d: expected start %s, got %s %s", i, expectedStarts[i], f.TripID, f.StartTime)
+		}
+	}
+}
+
+func TestTimeSeconds(t *testing.T) {
+	tests := map[string]int{
+		"00:00:00": 0,
+		"6:00:00":  21600,
+		"25:30:15": 91815,
+		"":         -1,
+		"8:00":     -1,
+		"aa:00:00": -1,
+	}
+	for in, want := range tests {
+		if got := TimeSeconds(in); got != want {
+			t.Errorf("TimeSeconds(%q) = %d, expected %d", in, got, want)
+		}
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	for _, in := range []string{"00:00:00", "06:00:00", "25:30:15"} {
+		if got := FormatTime(TimeSeconds(in)); got != in {
+			t.Errorf("FormatTime(TimeSeconds(%q)) = %q", in, got)
+		}
+	}
+}
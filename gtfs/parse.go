@@ -47,8 +47,9 @@ func ParseRoute(row *CSVRow) *Route {
 		Color:             row.Get("route_color"),
 		TextColor:         row.Get("route_text_color"),
 		SortOrder:         row.GetIntPtr("route_sort_order"),
-		ContinuousPickup:  row.GetInt("continuous_pickup"),
-		ContinuousDropOff: row.GetInt("continuous_drop_off"),
+		ContinuousPickup:  row.GetIntPtr("continuous_pickup"),
+		ContinuousDropOff: row.GetIntPtr("continuous_drop_off"),
+		NetworkID:         NetworkID(row.Get("network_id")),
 	}
 }
 
@@ -79,13 +80,59 @@ func ParseStopTime(row *CSVRow) *StopTime {
 		StopHeadsign:      row.Get("stop_headsign"),
 		PickupType:        row.GetInt("pickup_type"),
 		DropOffType:       row.GetInt("drop_off_type"),
-		ContinuousPickup:  row.GetInt("continuous_pickup"),
-		ContinuousDropOff: row.GetInt("continuous_drop_off"),
+		ContinuousPickup:  row.GetIntPtr("continuous_pickup"),
+		ContinuousDropOff: row.GetIntPtr("continuous_drop_off"),
 		ShapeDistTraveled: row.GetFloatPtr("shape_dist_traveled"),
 		Timepoint:         row.GetIntPtr("timepoint"),
 	}
 }
 
+// stopTimeParser parses stop_times.txt rows like ParseStopTime, but reads
+// fields by index: the columns are resolved once per file, when it sees the
+// first row of a file, rather than looked up by name for every row.
+type stopTimeParser struct {
+	row *CSVRow // the row the columns were resolved for
+
+	tripID, arrivalTime, departureTime, stopID, stopSequence, stopHeadsign column
+	pickupType, dropOffType, continuousPickup, continuousDropOff           column
+	shapeDistTraveled, timepoint                                           column
+}
+
+// parse parses row into a StopTime
+func (p *stopTimeParser) parse(row *CSVRow) *StopTime {
+	if p.row != row {
+		*p = stopTimeParser{
+			row:               row,
+			tripID:            row.column("trip_id"),
+			arrivalTime:       row.column("arrival_time"),
+			departureTime:     row.column("departure_time"),
+			stopID:            row.column("stop_id"),
+			stopSequence:      row.column("stop_sequence"),
+			stopHeadsign:      row.column("stop_headsign"),
+			pickupType:        row.column("pickup_type"),
+			dropOffType:       row.column("drop_off_type"),
+			continuousPickup:  row.column("continuous_pickup"),
+			continuousDropOff: row.column("continuous_drop_off"),
+			shapeDistTraveled: row.column("shape_dist_traveled"),
+			timepoint:         row.column("timepoint"),
+		}
+	}
+	return &StopTime{
+		TripID:            TripID(row.at(p.tripID)),
+		ArrivalTime:       row.at(p.arrivalTime),
+		DepartureTime:     row.at(p.departureTime),
+		StopID:            StopID(row.at(p.stopID)),
+		StopSequence:      row.atInt(p.stopSequence),
+		StopHeadsign:      row.at(p.stopHeadsign),
+		PickupType:        row.atInt(p.pickupType),
+		DropOffType:       row.atInt(p.dropOffType),
+		ContinuousPickup:  row.atIntPtr(p.continuousPickup),
+		ContinuousDropOff: row.atIntPtr(p.continuousDropOff),
+		ShapeDistTraveled: row.atFloatPtr(p.shapeDistTraveled),
+		Timepoint:         row.atIntPtr(p.timepoint),
+	}
+}
+
 // ParseCalendar parses a CSVRow into a Calendar struct.
 func ParseCalendar(row *CSVRow) *Calendar {
 	return &Calendar{
@@ -111,9 +158,10 @@ func ParseCalendarDate(row *CSVRow) *CalendarDate {
 	}
 }
 
-// ParseShapePoint parses a CSVRow into a ShapePoint struct.
-func ParseShapePoint(row *CSVRow) *ShapePoint {
-	return &ShapePoint{
+// ParseShapePoint parses a CSVRow into a ShapePoint. Unlike the other
+// parsers it returns a value, since feeds hold shape points by value.
+func ParseShapePoint(row *CSVRow) ShapePoint {
+	return ShapePoint{
 		ShapeID:      ShapeID(row.Get("shape_id")),
 		Lat:          row.GetFloat("shape_pt_lat"),
 		Lon:          row.GetFloat("shape_pt_lon"),
@@ -122,6 +170,35 @@ func ParseShapePoint(row *CSVRow) *ShapePoint {
 	}
 }
 
+// shapePointParser parses shapes.txt rows like ParseShapePoint, reading
+// fields by index as stopTimeParser does
+type shapePointParser struct {
+	row *CSVRow // the row the columns were resolved for
+
+	shapeID, lat, lon, sequence, distTraveled column
+}
+
+// parse parses row into a ShapePoint
+func (p *shapePointParser) parse(row *CSVRow) ShapePoint {
+	if p.row != row {
+		*p = shapePointParser{
+			row:          row,
+			shapeID:      row.column("shape_id"),
+			lat:          row.column("shape_pt_lat"),
+			lon:          row.column("shape_pt_lon"),
+			sequence:     row.column("shape_pt_sequence"),
+			distTraveled: row.column("shape_dist_traveled"),
+		}
+	}
+	return ShapePoint{
+		ShapeID:      ShapeID(row.at(p.shapeID)),
+		Lat:          row.atFloat(p.lat),
+		Lon:          row.atFloat(p.lon),
+		Sequence:     row.atInt(p.sequence),
+		DistTraveled: row.atFloatPtr(p.distTraveled),
+	}
+}
+
 // ParseFrequency parses a CSVRow into a Frequency struct.
 func ParseFrequency(row *CSVRow) *Frequency {
 	return &Frequency{
@@ -139,7 +216,7 @@ func ParseTransfer(row *CSVRow) *Transfer {
 		FromStopID:      StopID(row.Get("from_stop_id")),
 		ToStopID:        StopID(row.Get("to_stop_id")),
 		TransferType:    row.GetInt("transfer_type"),
-		MinTransferTime: row.GetInt("min_transfer_time"),
+		MinTransferTime: row.GetIntPtr("min_transfer_time"),
 		FromRouteID:     RouteID(row.Get("from_route_id")),
 		ToRouteID:       RouteID(row.Get("to_route_id")),
 		FromTripID:      TripID(row.Get("from_trip_id")),
@@ -154,11 +231,11 @@ func ParseFareAttribute(row *CSVRow) *FareAttribute {
 		Price:            row.GetFloat("price"),
 		CurrencyType:     row.Get("currency_type"),
 		PaymentMethod:    row.GetInt("payment_method"),
-		Transfers:        row.GetInt("transfers"),
+		Transfers:        row.GetIntPtr("transfers"),
 		AgencyID:         AgencyID(row.Get("agency_id")),
-		TransferDuration: row.GetInt("transfer_duration"),
-		YouthPrice:       row.GetFloat("youth_price"),
-		SeniorPrice:      row.GetFloat("senior_price"),
+		TransferDuration: row.GetIntPtr("transfer_duration"),
+		YouthPrice:       row.GetFloatPtr("youth_price"),
+		SeniorPrice:      row.GetFloatPtr("senior_price"),
 	}
 }
 
@@ -197,6 +274,90 @@ func ParseArea(row *CSVRow) *Area {
 	}
 }
 
+// ParseNetwork parses a CSVRow into a Network struct.
+func ParseNetwork(row *CSVRow) *Network {
+	return &Network{
+		ID:   NetworkID(row.Get("network_id")),
+		Name: row.Get("network_name"),
+	}
+}
+
+// ParseRouteNetwork parses a CSVRow into a RouteNetwork struct.
+func ParseRouteNetwork(row *CSVRow) *RouteNetwork {
+	return &RouteNetwork{
+		NetworkID: NetworkID(row.Get("network_id")),
+		RouteID:   RouteID(row.Get("route_id")),
+	}
+}
+
+// ParseFareLegRule parses a CSVRow into a FareLegRule struct.
+func ParseFareLegRule(row *CSVRow) *FareLegRule {
+	return &FareLegRule{
+		LegGroupID:           LegGroupID(row.Get("leg_group_id")),
+		NetworkID:            NetworkID(row.Get("network_id")),
+		FromAreaID:           AreaID(row.Get("from_area_id")),
+		ToAreaID:             AreaID(row.Get("to_area_id")),
+		FromTimeframeGroupID: TimeframeGroupID(row.Get("from_timeframe_group_id")),
+		ToTimeframeGroupID:   TimeframeGroupID(row.Get("to_timeframe_group_id")),
+		FareProductID:        FareProductID(row.Get("fare_product_id")),
+		RulePriority:         row.GetIntPtr("rule_priority"),
+	}
+}
+
+// ParseFareTransferRule parses a CSVRow into a FareTransferRule struct.
+func ParseFareTransferRule(row *CSVRow) *FareTransferRule {
+	return &FareTransferRule{
+		FromLegGroupID:    LegGroupID(row.Get("from_leg_group_id")),
+		ToLegGroupID:      LegGroupID(row.Get("to_leg_group_id")),
+		TransferCount:     row.GetIntPtr("transfer_count"),
+		DurationLimit:     row.GetIntPtr("duration_limit"),
+		DurationLimitType: row.GetIntPtr("duration_limit_type"),
+		FareTransferType:  row.GetInt("fare_transfer_type"),
+		FareProductID:     FareProductID(row.Get("fare_product_id")),
+	}
+}
+
+// ParseFareProduct parses a CSVRow into a FareProduct struct.
+func ParseFareProduct(row *CSVRow) *FareProduct {
+	return &FareProduct{
+		ID:              FareProductID(row.Get("fare_product_id")),
+		Name:            row.Get("fare_product_name"),
+		RiderCategoryID: RiderCategoryID(row.Get("rider_category_id")),
+		FareMediaID:     FareMediaID(row.Get("fare_media_id")),
+		Amount:          row.Get("amount"),
+		Currency:        row.Get("currency"),
+	}
+}
+
+// ParseTimeframe parses a CSVRow into a Timeframe struct.
+func ParseTimeframe(row *CSVRow) *Timeframe {
+	return &Timeframe{
+		GroupID:   TimeframeGroupID(row.Get("timeframe_group_id")),
+		StartTime: row.Get("start_time"),
+		EndTime:   row.Get("end_time"),
+		ServiceID: ServiceID(row.Get("service_id")),
+	}
+}
+
+// ParseFareMedia parses a CSVRow into a FareMedia struct.
+func ParseFareMedia(row *CSVRow) *FareMedia {
+	return &FareMedia{
+		ID:   FareMediaID(row.Get("fare_media_id")),
+		Name: row.Get("fare_media_name"),
+		Type: row.GetInt("fare_media_type"),
+	}
+}
+
+// ParseRiderCategory parses a CSVRow into a RiderCategory struct.
+func ParseRiderCategory(row *CSVRow) *RiderCategory {
+	return &RiderCategory{
+		ID:                    RiderCategoryID(row.Get("rider_category_id")),
+		Name:                  row.Get("rider_category_name"),
+		IsDefaultFareCategory: row.GetIntPtr("is_default_fare_category"),
+		EligibilityURL:        row.Get("eligibility_url"),
+	}
+}
+
 // ParsePathway parses a CSVRow into a Pathway struct.
 func ParsePathway(row *CSVRow) *Pathway {
 	return &Pathway{
@@ -2,6 +2,7 @@ package gtfs
 
 import (
 	"io"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -227,11 +228,11 @@ route1,agency1,R1,Route One,The first route,3,http://routes.example.com/route1,F
 	if route.SortOrder == nil || *route.SortOrder != 1 {
 		t.Errorf("expected SortOrder 1, got %v", route.SortOrder)
 	}
-	if route.ContinuousPickup != 0 {
-		t.Errorf("expected ContinuousPickup 0, got %d", route.ContinuousPickup)
+	if route.ContinuousPickup == nil || *route.ContinuousPickup != 0 {
+		t.Errorf("expected ContinuousPickup 0, got %v", route.ContinuousPickup)
 	}
-	if route.ContinuousDropOff != 1 {
-		t.Errorf("expected ContinuousDropOff 1, got %d", route.ContinuousDropOff)
+	if route.ContinuousDropOff == nil || *route.ContinuousDropOff != 1 {
+		t.Errorf("expected ContinuousDropOff 1, got %v", route.ContinuousDropOff)
 	}
 }
 
@@ -318,11 +319,11 @@ trip1,08:15:00,08:16:00,stop2,2,,0,0,0,0,1250.5,0`
 	if st.DropOffType != 0 {
 		t.Errorf("expected DropOffType 0, got %d", st.DropOffType)
 	}
-	if st.ContinuousPickup != 1 {
-		t.Errorf("expected ContinuousPickup 1, got %d", st.ContinuousPickup)
+	if st.ContinuousPickup == nil || *st.ContinuousPickup != 1 {
+		t.Errorf("expected ContinuousPickup 1, got %v", st.ContinuousPickup)
 	}
-	if st.ContinuousDropOff != 1 {
-		t.Errorf("expected ContinuousDropOff 1, got %d", st.ContinuousDropOff)
+	if st.ContinuousDropOff == nil || *st.ContinuousDropOff != 1 {
+		t.Errorf("expected ContinuousDropOff 1, got %v", st.ContinuousDropOff)
 	}
 	if st.ShapeDistTraveled == nil || *st.ShapeDistTraveled != 0.0 {
 		t.Errorf("expected ShapeDistTraveled 0.0, got %v", st.ShapeDistTraveled)
@@ -355,6 +356,30 @@ trip1,25:30:00,25:35:00,stop1,1`
 
 // ==================== Calendar Tests ====================
 
+func TestStopTimeParserMatchesParseStopTime(t *testing.T) {
+	// Columns reordered, some missing, a short row and invalid values
+	content := `stop_sequence,stop_id,trip_id,timepoint,arrival_time,departure_time,pickup_type,shape_dist_traveled
+1,stop1,trip1,1,08:00:00,08:02:00,0,0.0
+x,stop2,trip1,,08:15:00,08:16:00,y,z
+3,stop3,trip1`
+
+	_, rows := parseCSVRows(t, content)
+	var parser stopTimeParser
+	for i, row := range rows {
+		want := ParseStopTime(row)
+		wantIssues := row.Issues()
+		row.issues = nil
+
+		got := parser.parse(row)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("row %d: expected %+v, got %+v", i, want, got)
+		}
+		if !reflect.DeepEqual(row.Issues(), wantIssues) {
+			t.Errorf("row %d: expected issues %v, got %v", i, wantIssues, row.Issues())
+		}
+	}
+}
+
 func TestParseCalendar(t *testing.T) {
 	content := `service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date
 service1,1,1,1,1,1,0,0,20240101,20241231`
@@ -467,6 +492,29 @@ shape1,40.7160,-74.0020,3,500.0`
 	}
 }
 
+func TestShapePointParserMatchesParseShapePoint(t *testing.T) {
+	content := `shape_pt_sequence,shape_id,shape_pt_lon,shape_pt_lat
+1,shape1,-74.0060,40.7128
+two,shape1,west,40.7145
+3,shape1`
+
+	_, rows := parseCSVRows(t, content)
+	var parser shapePointParser
+	for i, row := range rows {
+		want := ParseShapePoint(row)
+		wantIssues := row.Issues()
+		row.issues = nil
+
+		got := parser.parse(row)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("row %d: expected %+v, got %+v", i, want, got)
+		}
+		if !reflect.DeepEqual(row.Issues(), wantIssues) {
+			t.Errorf("row %d: expected issues %v, got %v", i, wantIssues, row.Issues())
+		}
+	}
+}
+
 // ==================== Frequency Tests ====================
 
 func TestParseFrequencies(t *testing.T) {
@@ -527,14 +575,14 @@ stop2,stop3,2,`
 	if tr.TransferType != 0 {
 		t.Errorf("expected TransferType 0, got %d", tr.TransferType)
 	}
-	if tr.MinTransferTime != 180 {
-		t.Errorf("expected MinTransferTime 180, got %d", tr.MinTransferTime)
+	if tr.MinTransferTime == nil || *tr.MinTransferTime != 180 {
+		t.Errorf("expected MinTransferTime 180, got %v", tr.MinTransferTime)
 	}
 
 	// Test second transfer with no min_transfer_time
 	tr2 := ParseTransfer(rows[1])
-	if tr2.MinTransferTime != 0 {
-		t.Errorf("expected MinTransferTime 0 (empty), got %d", tr2.MinTransferTime)
+	if tr2.MinTransferTime != nil {
+		t.Errorf("expected MinTransferTime nil (empty), got %d", *tr2.MinTransferTime)
 	}
 }
 
@@ -563,14 +611,14 @@ fare1,2.50,USD,0,2,agency1,7200`
 	if fa.PaymentMethod != 0 {
 		t.Errorf("expected PaymentMethod 0, got %d", fa.PaymentMethod)
 	}
-	if fa.Transfers != 2 {
-		t.Errorf("expected Transfers 2, got %d", fa.Transfers)
+	if fa.Transfers == nil || *fa.Transfers != 2 {
+		t.Errorf("expected Transfers 2, got %v", fa.Transfers)
 	}
 	if fa.AgencyID != "agency1" {
 		t.Errorf("expected AgencyID 'agency1', got '%s'", fa.AgencyID)
 	}
-	if fa.TransferDuration != 7200 {
-		t.Errorf("expected TransferDuration 7200, got %d", fa.TransferDuration)
+	if fa.TransferDuration == nil || *fa.TransferDuration != 7200 {
+		t.Errorf("expected TransferDuration 7200, got %v", fa.TransferDuration)
 	}
 }
 
@@ -668,6 +716,58 @@ area2,Suburban Zone`
 	}
 }
 
+// ==================== Network Tests ====================
+
+func TestParseNetworks(t *testing.T) {
+	content := `network_id,network_name
+standard,Standard Fare`
+
+	_, rows := parseCSVRows(t, content)
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(rows))
+	}
+
+	network := ParseNetwork(rows[0])
+
+	if network.ID != "standard" {
+		t.Errorf("expected ID 'standard', got '%s'", network.ID)
+	}
+	if network.Name != "Standard Fare" {
+		t.Errorf("expected Name 'Standard Fare', got '%s'", network.Name)
+	}
+}
+
+func TestParseRouteNetworks(t *testing.T) {
+	content := `network_id,route_id
+standard,local`
+
+	_, rows := parseCSVRows(t, content)
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(rows))
+	}
+
+	rn := ParseRouteNetwork(rows[0])
+
+	if rn.NetworkID != "standard" {
+		t.Errorf("expected NetworkID 'standard', got '%s'", rn.NetworkID)
+	}
+	if rn.RouteID != "local" {
+		t.Errorf("expected RouteID 'local', got '%s'", rn.RouteID)
+	}
+}
+
+func TestParseRouteNetworkID(t *testing.T) {
+	content := `route_id,route_short_name,route_type,network_id
+route1,R1,3,standard`
+
+	_, rows := parseCSVRows(t, content)
+	route := ParseRoute(rows[0])
+
+	if route.NetworkID != "standard" {
+		t.Errorf("expected NetworkID 'standard', got '%s'", route.NetworkID)
+	}
+}
+
 // ==================== Pathway Tests ====================
 
 func TestParsePathways(t *testing.T) {
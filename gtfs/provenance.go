@@ -0,0 +1,61 @@
+package gtfs
+
+import "sort"
+
+// EntityKind names a kind of ID-keyed GTFS entity for provenance lookups
+type EntityKind string
+
+// Entity kinds tracked in Feed.Sources
+const (
+	KindAgency  EntityKind = "agency"
+	KindStop    EntityKind = "stop"
+	KindRoute   EntityKind = "route"
+	KindTrip    EntityKind = "trip"
+	KindService EntityKind = "service"
+	KindShape   EntityKind = "shape"
+	KindFare    EntityKind = "fare"
+	KindArea    EntityKind = "area"
+	KindNetwork EntityKind = "network"
+
+	KindLegGroup       EntityKind = "leg_group"
+	KindFareProduct    EntityKind = "fare_product"
+	KindTimeframeGroup EntityKind = "timeframe_group"
+	KindFareMedia      EntityKind = "fare_media"
+	KindRiderCategory  EntityKind = "rider_category"
+)
+
+// EntityKinds lists every EntityKind in a stable order
+var EntityKinds = []EntityKind{
+	KindAgency, KindStop, KindRoute, KindTrip, KindService, KindShape, KindFare, KindArea, KindNetwork,
+	KindLegGroup, KindFareProduct, KindTimeframeGroup, KindFareMedia, KindRiderCategory,
+}
+
+// SourceOf returns the indices (in input order) of the input feeds that
+// contributed the entity of the given kind and merged ID, or nil if the feed
+// has no provenance for it. Deduplicated entities list every contributing feed.
+func (f *Feed) SourceOf(kind EntityKind, id string) []int {
+	return f.Sources[kind][id]
+}
+
+// AddSource records that the input feed at index contributed the entity of
+// the given kind and merged ID
+func (f *Feed) AddSource(kind EntityKind, id string, index int) {
+	if f.Sources == nil {
+		f.Sources = make(map[EntityKind]map[string][]int)
+	}
+	ids := f.Sources[kind]
+	if ids == nil {
+		ids = make(map[string][]int)
+		f.Sources[kind] = ids
+	}
+
+	sources := ids[id]
+	i := sort.SearchInts(sources, index)
+	if i < len(sources) && sources[i] == index {
+		return
+	}
+	sources = append(sources, 0)
+	copy(sources[i+1:], sources[i:])
+	sources[i] = index
+	ids[id] = sources
+}
@@ -0,0 +1,27 @@
+package gtfs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFeedAddSource(t *testing.T) {
+	feed := NewFeed()
+
+	// Sources are kept sorted and deduplicated
+	feed.AddSource(KindStop, "s1", 2)
+	feed.AddSource(KindStop, "s1", 0)
+	feed.AddSource(KindStop, "s1", 2)
+	feed.AddSource(KindStop, "s1", 1)
+	feed.AddSource(KindTrip, "s1", 3)
+
+	if got := feed.SourceOf(KindStop, "s1"); !reflect.DeepEqual(got, []int{0, 1, 2}) {
+		t.Errorf("expected stop s1 sources [0 1 2], got %v", got)
+	}
+	if got := feed.SourceOf(KindTrip, "s1"); !reflect.DeepEqual(got, []int{3}) {
+		t.Errorf("expected trip s1 sources [3], got %v", got)
+	}
+	if got := feed.SourceOf(KindRoute, "s1"); got != nil {
+		t.Errorf("expected no route sources, got %v", got)
+	}
+}
@@ -2,10 +2,16 @@ package gtfs
 
 import (
 	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
@@ -31,23 +37,73 @@ var ErrMissingRequiredFile = errors.New("missing required GTFS file")
 // ErrMissingCalendarFile is returned when neither calendar.txt nor calendar_dates.txt is present
 var ErrMissingCalendarFile = errors.New("missing calendar.txt or calendar_dates.txt")
 
+// ErrEncryptedZip is returned when a zip archive's entries are encrypted
+var ErrEncryptedZip = errors.New("password-protected zip archives are not supported")
+
+// ErrNestedZipTooLarge is returned when a nested zip is larger than
+// ReaderOptions.MaxNestedZipSize
+var ErrNestedZipTooLarge = errors.New("nested zip archive too large")
+
+// ErrNestedZipTooDeep is returned when a nested zip holds only another zip
+// in turn; only a zip of a zip is read through
+var ErrNestedZipTooDeep = errors.New("zip archives nested too deeply")
+
+// DefaultMaxNestedZipSize is the largest nested zip, in bytes, read by
+// default; see ReaderOptions.MaxNestedZipSize
+const DefaultMaxNestedZipSize = 1 << 30
+
 // ReadFromPath reads a GTFS feed from a file path (zip or directory)
 func ReadFromPath(path string) (*Feed, error) {
+	return ReadFromPathContext(context.Background(), path)
+}
+
+// ReadFromPathWithOptions reads a GTFS feed from a file path (zip or directory)
+// using the given reader options
+func ReadFromPathWithOptions(path string, options ReaderOptions) (*Feed, error) {
+	return ReadFromPathContextWithOptions(context.Background(), path, options)
+}
+
+// ReadMetadataOnly reads a GTFS feed from a file path (zip or directory)
+// without stop_times.txt and shapes.txt. The result is a partial feed
+// (see Feed.PartialRead) suitable for inspection but not for merging.
+func ReadMetadataOnly(path string) (*Feed, error) {
+	return ReadFromPathWithOptions(path, MetadataOnlyOptions())
+}
+
+// ReadFromPathContext is like ReadFromPath but stops between files once ctx
+// is canceled, returning ctx.Err() wrapped with the file being read.
+func ReadFromPathContext(ctx context.Context, path string) (*Feed, error) {
+	return ReadFromPathContextWithOptions(ctx, path, ReaderOptions{})
+}
+
+// ReadFromPathContextWithOptions combines ReadFromPathContext and
+// ReadFromPathWithOptions
+func ReadFromPathContextWithOptions(ctx context.Context, path string, options ReaderOptions) (*Feed, error) {
 	info, err := os.Stat(path)
 	if err != nil {
 		return nil, fmt.Errorf("cannot access path %s: %w", path, err)
 	}
 
 	if info.IsDir() {
-		return readFromDirectory(path)
+		return readFromDirectory(ctx, path, &options)
 	}
 
 	// Assume it's a zip file
-	return readFromZipPath(path)
+	return readFromZipPath(ctx, path, &options)
 }
 
 // readFromDirectory reads a GTFS feed from a directory
-func readFromDirectory(dirPath string) (*Feed, error) {
+func readFromDirectory(ctx context.Context, dirPath string, opts *ReaderOptions) (*Feed, error) {
+	opener, err := directoryOpener(ctx, dirPath)
+	if err != nil {
+		return nil, err
+	}
+	return readFeed(opener, opts)
+}
+
+// directoryOpener returns an opener for the GTFS files in dirPath, once
+// the required files are found there
+func directoryOpener(ctx context.Context, dirPath string) (func(string) (io.ReadCloser, error), error) {
 	// Check for required files
 	for _, filename := range requiredFiles {
 		filePath := filepath.Join(dirPath, filename)
@@ -69,43 +125,78 @@ func readFromDirectory(dirPath string) (*Feed, error) {
 		return nil, ErrMissingCalendarFile
 	}
 
-	feed := NewFeed()
-
-	// Read each file using an opener function
-	opener := func(filename string) (io.ReadCloser, error) {
+	return func(filename string) (io.ReadCloser, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		filePath := filepath.Join(dirPath, filename)
 		return os.Open(filePath)
-	}
+	}, nil
+}
 
-	if err := readFeedFiles(feed, opener); err != nil {
+// readFeed reads a GTFS feed from the files opener opens
+func readFeed(opener func(string) (io.ReadCloser, error), opts *ReaderOptions) (*Feed, error) {
+	feed := NewFeed()
+	if err := readFeedFiles(feed, opener, opts); err != nil {
 		return nil, err
 	}
-
 	return feed, nil
 }
 
 // readFromZipPath reads a GTFS feed from a zip file path
-func readFromZipPath(zipPath string) (*Feed, error) {
+func readFromZipPath(ctx context.Context, zipPath string, opts *ReaderOptions) (*Feed, error) {
 	r, err := zip.OpenReader(zipPath)
 	if err != nil {
 		return nil, fmt.Errorf("cannot open zip file %s: %w", zipPath, err)
 	}
 	defer func() { _ = r.Close() }()
 
-	return readFromZipReader(&r.Reader)
+	return readFromZipReader(ctx, &r.Reader, opts)
 }
 
 // ReadFromZip reads a GTFS feed from a zip reader
 func ReadFromZip(r io.ReaderAt, size int64) (*Feed, error) {
+	return ReadFromZipWithOptions(r, size, ReaderOptions{})
+}
+
+// ReadFromZipWithOptions reads a GTFS feed from a zip reader using the given
+// reader options
+func ReadFromZipWithOptions(r io.ReaderAt, size int64, options ReaderOptions) (*Feed, error) {
 	zr, err := zip.NewReader(r, size)
 	if err != nil {
 		return nil, fmt.Errorf("cannot read zip: %w", err)
 	}
-	return readFromZipReader(zr)
+	return readFromZipReader(context.Background(), zr, &options)
 }
 
 // readFromZipReader reads a GTFS feed from a zip.Reader
-func readFromZipReader(zr *zip.Reader) (*Feed, error) {
+func readFromZipReader(ctx context.Context, zr *zip.Reader, opts *ReaderOptions) (*Feed, error) {
+	opener, err := zipOpener(ctx, zr, opts, 0)
+	if err != nil {
+		return nil, err
+	}
+	return readFeed(opener, opts)
+}
+
+// zipOpener returns an opener for the GTFS files in zr, or in the zip it
+// nests, once the required files are found there; depth is the number of
+// archives zr is nested in
+func zipOpener(ctx context.Context, zr *zip.Reader, opts *ReaderOptions, depth int) (func(string) (io.ReadCloser, error), error) {
+	for _, f := range zr.File {
+		if f.Flags&zipFlagEncrypted != 0 {
+			return nil, fmt.Errorf("%w: %s is encrypted", ErrEncryptedZip, f.Name)
+		}
+	}
+	if nested := nestedZip(zr); nested != nil {
+		if opts != nil && opts.DisableNestedZip {
+			return nil, fmt.Errorf("%w: archive holds only the nested zip %s", ErrMissingRequiredFile, nested.Name)
+		}
+		if depth > 0 {
+			return nil, fmt.Errorf("%w: nested zip holds only the zip %s", ErrNestedZipTooDeep, nested.Name)
+		}
+		return nestedZipOpener(ctx, nested, opts, depth+1)
+	}
+
 	// Build a map of file names to zip file entries
 	// Handle nested directories by stripping the prefix
 	fileMap := make(map[string]*zip.File)
@@ -160,22 +251,74 @@ func readFromZipReader(zr *zip.Reader) (*Feed, error) {
 		return nil, ErrMissingCalendarFile
 	}
 
-	feed := NewFeed()
-
-	// Read each file using an opener function
-	opener := func(filename string) (io.ReadCloser, error) {
+	return func(filename string) (io.ReadCloser, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		f, ok := fileMap[filename]
 		if !ok {
 			return nil, os.ErrNotExist
 		}
 		return f.Open()
-	}
+	}, nil
+}
 
-	if err := readFeedFiles(feed, opener); err != nil {
-		return nil, err
+// zipFlagEncrypted is the general purpose flag bit marking an encrypted zip
+// entry
+const zipFlagEncrypted = 0x1
+
+// nestedZip returns the archive's only zip entry if it has no .txt entries,
+// as when a feed is published as a zip of a zip, or nil. Directories and
+// macOS resource forks are ignored.
+func nestedZip(zr *zip.Reader) *zip.File {
+	var nested *zip.File
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") {
+			continue
+		}
+		switch strings.ToLower(path.Ext(f.Name)) {
+		case ".txt":
+			return nil
+		case ".zip":
+			if nested != nil {
+				return nil
+			}
+			nested = f
+		}
 	}
+	return nested
+}
 
-	return feed, nil
+// nestedZipOpener returns an opener for the GTFS files in f, a zip archive
+// within another, at depth. Its entries can only be reached by reading it
+// into memory, so it is read only up to opts.maxNestedZipSize.
+func nestedZipOpener(ctx context.Context, f *zip.File, opts *ReaderOptions, depth int) (func(string) (io.ReadCloser, error), error) {
+	limit := opts.maxNestedZipSize()
+	if limit >= 0 && f.UncompressedSize64 > uint64(limit) {
+		return nil, fmt.Errorf("%w: %s is %d bytes, over %d", ErrNestedZipTooLarge, f.Name, f.UncompressedSize64, limit)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("cannot open nested zip %s: %w", f.Name, err)
+	}
+	var r io.Reader = rc
+	if limit >= 0 {
+		// The size in the header may understate the data
+		r = io.LimitReader(rc, limit+1)
+	}
+	data, err := io.ReadAll(r)
+	_ = rc.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read nested zip %s: %w", f.Name, err)
+	}
+	if limit >= 0 && int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: %s is over %d bytes", ErrNestedZipTooLarge, f.Name, limit)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read nested zip %s: %w", f.Name, err)
+	}
+	return zipOpener(ctx, zr, opts, depth)
 }
 
 // isGTFSFile returns true if the filename is a known GTFS file
@@ -185,7 +328,9 @@ func isGTFSFile(name string) bool {
 		"stop_times.txt", "calendar.txt", "calendar_dates.txt",
 		"fare_attributes.txt", "fare_rules.txt", "shapes.txt",
 		"frequencies.txt", "transfers.txt", "feed_info.txt",
-		"areas.txt", "pathways.txt",
+		"areas.txt", "pathways.txt", "networks.txt", "route_networks.txt",
+		"fare_leg_rules.txt", "fare_transfer_rules.txt", "fare_products.txt",
+		"timeframes.txt", "fare_media.txt", "rider_categories.txt",
 	}
 	for _, f := range gtfsFiles {
 		if name == f {
@@ -196,9 +341,14 @@ func isGTFSFile(name string) bool {
 }
 
 // readFeedFiles reads all GTFS files using the provided opener function
-func readFeedFiles(feed *Feed, opener func(string) (io.ReadCloser, error)) error {
+func readFeedFiles(feed *Feed, opener func(string) (io.ReadCloser, error), opts *ReaderOptions) error {
+	// Hash each file as it is read rather than in a second pass
+	hash := sha256.New()
+	opener = hashingOpener(opener, hash)
+	defer func() { feed.ContentHash = hex.EncodeToString(hash.Sum(nil)) }()
+
 	// Read agencies
-	if err := readFileIntoFeed(feed, opener, "agency.txt", func(row *CSVRow) {
+	if err := readFileIntoFeed(feed, opener, opts, "agency.txt", func(row *CSVRow) {
 		agency := ParseAgency(row)
 		feed.Agencies[agency.ID] = agency
 		feed.AgencyOrder = append(feed.AgencyOrder, agency.ID)
@@ -207,7 +357,7 @@ func readFeedFiles(feed *Feed, opener func(string) (io.ReadCloser, error)) error
 	}
 
 	// Read stops
-	if err := readFileIntoFeed(feed, opener, "stops.txt", func(row *CSVRow) {
+	if err := readFileIntoFeed(feed, opener, opts, "stops.txt", func(row *CSVRow) {
 		stop := ParseStop(row)
 		feed.Stops[stop.ID] = stop
 		feed.StopOrder = append(feed.StopOrder, stop.ID)
@@ -216,7 +366,7 @@ func readFeedFiles(feed *Feed, opener func(string) (io.ReadCloser, error)) error
 	}
 
 	// Read routes
-	if err := readFileIntoFeed(feed, opener, "routes.txt", func(row *CSVRow) {
+	if err := readFileIntoFeed(feed, opener, opts, "routes.txt", func(row *CSVRow) {
 		route := ParseRoute(row)
 		feed.Routes[route.ID] = route
 		feed.RouteOrder = append(feed.RouteOrder, route.ID)
@@ -225,7 +375,7 @@ func readFeedFiles(feed *Feed, opener func(string) (io.ReadCloser, error)) error
 	}
 
 	// Read trips
-	if err := readFileIntoFeed(feed, opener, "trips.txt", func(row *CSVRow) {
+	if err := readFileIntoFeed(feed, opener, opts, "trips.txt", func(row *CSVRow) {
 		trip := ParseTrip(row)
 		feed.Trips[trip.ID] = trip
 		feed.TripOrder = append(feed.TripOrder, trip.ID)
@@ -233,16 +383,17 @@ func readFeedFiles(feed *Feed, opener func(string) (io.ReadCloser, error)) error
 		return fmt.Errorf("reading trips.txt: %w", err)
 	}
 
-	// Read stop_times
-	if err := readFileIntoFeed(feed, opener, "stop_times.txt", func(row *CSVRow) {
-		stopTime := ParseStopTime(row)
+	// Read stop_times, the largest file, by column index
+	var stopTimes stopTimeParser
+	if err := readFileIntoFeed(feed, opener, opts, "stop_times.txt", func(row *CSVRow) {
+		stopTime := stopTimes.parse(row)
 		feed.StopTimes = append(feed.StopTimes, stopTime)
 	}); err != nil {
 		return fmt.Errorf("reading stop_times.txt: %w", err)
 	}
 
 	// Read calendar (optional - but at least one of calendar/calendar_dates required)
-	if err := readOptionalFileIntoFeed(feed, opener, "calendar.txt", func(row *CSVRow) {
+	if err := readOptionalFileIntoFeed(feed, opener, opts, "calendar.txt", func(row *CSVRow) {
 		calendar := ParseCalendar(row)
 		feed.Calendars[calendar.ServiceID] = calendar
 		feed.CalendarOrder = append(feed.CalendarOrder, calendar.ServiceID)
@@ -250,9 +401,16 @@ func readFeedFiles(feed *Feed, opener func(string) (io.ReadCloser, error)) error
 		return fmt.Errorf("reading calendar.txt: %w", err)
 	}
 
-	// Read calendar_dates (optional)
-	if err := readOptionalFileIntoFeed(feed, opener, "calendar_dates.txt", func(row *CSVRow) {
+	// Read calendar_dates (optional). GTFS allows one row per service and
+	// date; repeats are kept for the merge to resolve but flagged here.
+	seenDates := make(map[calendarDateKey]bool)
+	if err := readOptionalFileIntoFeed(feed, opener, opts, "calendar_dates.txt", func(row *CSVRow) {
 		calDate := ParseCalendarDate(row)
+		key := calendarDateKey{calDate.ServiceID, calDate.Date}
+		if seenDates[key] {
+			row.addIssue("date", calDate.Date, fmt.Sprintf("duplicate calendar_date for service_id %q date %q", calDate.ServiceID, calDate.Date))
+		}
+		seenDates[key] = true
 		// Only track order for first occurrence of each service_id
 		if _, exists := feed.CalendarDates[calDate.ServiceID]; !exists {
 			feed.CalendarDateOrder = append(feed.CalendarDateOrder, calDate.ServiceID)
@@ -262,20 +420,32 @@ func readFeedFiles(feed *Feed, opener func(string) (io.ReadCloser, error)) error
 		return fmt.Errorf("reading calendar_dates.txt: %w", err)
 	}
 
-	// Read shapes (optional)
-	if err := readOptionalFileIntoFeed(feed, opener, "shapes.txt", func(row *CSVRow) {
-		shapePoint := ParseShapePoint(row)
-		// Only track order for first occurrence of each shape_id
-		if _, exists := feed.Shapes[shapePoint.ShapeID]; !exists {
+	// Empty calendar files count as absent
+	if feed.ColumnSets["calendar.txt"] == nil && feed.ColumnSets["calendar_dates.txt"] == nil &&
+		!opts.skipFile("calendar.txt") && !opts.skipFile("calendar_dates.txt") {
+		return fmt.Errorf("%w (both are empty)", ErrMissingCalendarFile)
+	}
+
+	// Read shapes (optional), by column index
+	var shapePoints shapePointParser
+	if err := readOptionalFileIntoFeed(feed, opener, opts, "shapes.txt", func(row *CSVRow) {
+		shapePoint := shapePoints.parse(row)
+		points, exists := feed.Shapes[shapePoint.ShapeID]
+		if exists {
+			// Share the first point's shape_id, so later points don't each
+			// keep their CSV line alive
+			shapePoint.ShapeID = points[0].ShapeID
+		} else {
+			// Only track order for first occurrence of each shape_id
 			feed.ShapeOrder = append(feed.ShapeOrder, shapePoint.ShapeID)
 		}
-		feed.Shapes[shapePoint.ShapeID] = append(feed.Shapes[shapePoint.ShapeID], shapePoint)
+		feed.Shapes[shapePoint.ShapeID] = append(points, shapePoint)
 	}); err != nil {
 		return fmt.Errorf("reading shapes.txt: %w", err)
 	}
 
 	// Read frequencies (optional)
-	if err := readOptionalFileIntoFeed(feed, opener, "frequencies.txt", func(row *CSVRow) {
+	if err := readOptionalFileIntoFeed(feed, opener, opts, "frequencies.txt", func(row *CSVRow) {
 		frequency := ParseFrequency(row)
 		feed.Frequencies = append(feed.Frequencies, frequency)
 	}); err != nil {
@@ -283,7 +453,7 @@ func readFeedFiles(feed *Feed, opener func(string) (io.ReadCloser, error)) error
 	}
 
 	// Read transfers (optional)
-	if err := readOptionalFileIntoFeed(feed, opener, "transfers.txt", func(row *CSVRow) {
+	if err := readOptionalFileIntoFeed(feed, opener, opts, "transfers.txt", func(row *CSVRow) {
 		transfer := ParseTransfer(row)
 		feed.Transfers = append(feed.Transfers, transfer)
 	}); err != nil {
@@ -291,7 +461,7 @@ func readFeedFiles(feed *Feed, opener func(string) (io.ReadCloser, error)) error
 	}
 
 	// Read fare_attributes (optional)
-	if err := readOptionalFileIntoFeed(feed, opener, "fare_attributes.txt", func(row *CSVRow) {
+	if err := readOptionalFileIntoFeed(feed, opener, opts, "fare_attributes.txt", func(row *CSVRow) {
 		fareAttr := ParseFareAttribute(row)
 		feed.FareAttributes[fareAttr.FareID] = fareAttr
 		feed.FareAttrOrder = append(feed.FareAttrOrder, fareAttr.FareID)
@@ -300,7 +470,7 @@ func readFeedFiles(feed *Feed, opener func(string) (io.ReadCloser, error)) error
 	}
 
 	// Read fare_rules (optional)
-	if err := readOptionalFileIntoFeed(feed, opener, "fare_rules.txt", func(row *CSVRow) {
+	if err := readOptionalFileIntoFeed(feed, opener, opts, "fare_rules.txt", func(row *CSVRow) {
 		fareRule := ParseFareRule(row)
 		feed.FareRules = append(feed.FareRules, fareRule)
 	}); err != nil {
@@ -308,19 +478,20 @@ func readFeedFiles(feed *Feed, opener func(string) (io.ReadCloser, error)) error
 	}
 
 	// Read feed_info (optional)
-	if err := readOptionalFileIntoFeed(feed, opener, "feed_info.txt", func(row *CSVRow) {
+	if err := readOptionalFileIntoFeed(feed, opener, opts, "feed_info.txt", func(row *CSVRow) {
 		fi := ParseFeedInfo(row)
-		if fi.FeedID == "" {
-			fi.FeedID = "1" // Java assigns 1 to feeds without feed_id
+		// A blank feed_id is kept blank here; the merge assigns a stable
+		// per-source value (see strategy.FeedInfoMergeStrategy).
+		if _, exists := feed.FeedInfos[fi.FeedID]; !exists {
+			feed.FeedInfoOrder = append(feed.FeedInfoOrder, fi.FeedID)
 		}
 		feed.FeedInfos[fi.FeedID] = fi // overwrites if same id
-		feed.FeedInfoOrder = append(feed.FeedInfoOrder, fi.FeedID)
 	}); err != nil {
 		return fmt.Errorf("reading feed_info.txt: %w", err)
 	}
 
 	// Read areas (optional)
-	if err := readOptionalFileIntoFeed(feed, opener, "areas.txt", func(row *CSVRow) {
+	if err := readOptionalFileIntoFeed(feed, opener, opts, "areas.txt", func(row *CSVRow) {
 		area := ParseArea(row)
 		feed.Areas[area.ID] = area
 		feed.AreaOrder = append(feed.AreaOrder, area.ID)
@@ -329,26 +500,172 @@ func readFeedFiles(feed *Feed, opener func(string) (io.ReadCloser, error)) error
 	}
 
 	// Read pathways (optional)
-	if err := readOptionalFileIntoFeed(feed, opener, "pathways.txt", func(row *CSVRow) {
+	if err := readOptionalFileIntoFeed(feed, opener, opts, "pathways.txt", func(row *CSVRow) {
 		pathway := ParsePathway(row)
 		feed.Pathways = append(feed.Pathways, pathway)
 	}); err != nil {
 		return fmt.Errorf("reading pathways.txt: %w", err)
 	}
 
+	// Read networks (optional)
+	if err := readOptionalFileIntoFeed(feed, opener, opts, "networks.txt", func(row *CSVRow) {
+		network := ParseNetwork(row)
+		feed.Networks[network.ID] = network
+		feed.NetworkOrder = append(feed.NetworkOrder, network.ID)
+	}); err != nil {
+		return fmt.Errorf("reading networks.txt: %w", err)
+	}
+
+	// Read route_networks (optional)
+	if err := readOptionalFileIntoFeed(feed, opener, opts, "route_networks.txt", func(row *CSVRow) {
+		feed.RouteNetworks = append(feed.RouteNetworks, ParseRouteNetwork(row))
+	}); err != nil {
+		return fmt.Errorf("reading route_networks.txt: %w", err)
+	}
+
+	// Read fare_leg_rules (optional)
+	if err := readOptionalFileIntoFeed(feed, opener, opts, "fare_leg_rules.txt", func(row *CSVRow) {
+		feed.FareLegRules = append(feed.FareLegRules, ParseFareLegRule(row))
+	}); err != nil {
+		return fmt.Errorf("reading fare_leg_rules.txt: %w", err)
+	}
+
+	// Read fare_transfer_rules (optional)
+	if err := readOptionalFileIntoFeed(feed, opener, opts, "fare_transfer_rules.txt", func(row *CSVRow) {
+		feed.FareTransferRules = append(feed.FareTransferRules, ParseFareTransferRule(row))
+	}); err != nil {
+		return fmt.Errorf("reading fare_transfer_rules.txt: %w", err)
+	}
+
+	// Read fare_products (optional)
+	if err := readOptionalFileIntoFeed(feed, opener, opts, "fare_products.txt", func(row *CSVRow) {
+		feed.FareProducts = append(feed.FareProducts, ParseFareProduct(row))
+	}); err != nil {
+		return fmt.Errorf("reading fare_products.txt: %w", err)
+	}
+
+	// Read timeframes (optional)
+	if err := readOptionalFileIntoFeed(feed, opener, opts, "timeframes.txt", func(row *CSVRow) {
+		feed.Timeframes = append(feed.Timeframes, ParseTimeframe(row))
+	}); err != nil {
+		return fmt.Errorf("reading timeframes.txt: %w", err)
+	}
+
+	// Read fare_media (optional)
+	if err := readOptionalFileIntoFeed(feed, opener, opts, "fare_media.txt", func(row *CSVRow) {
+		media := ParseFareMedia(row)
+		feed.FareMedia[media.ID] = media
+		feed.FareMediaOrder = append(feed.FareMediaOrder, media.ID)
+	}); err != nil {
+		return fmt.Errorf("reading fare_media.txt: %w", err)
+	}
+
+	// Read rider_categories (optional)
+	if err := readOptionalFileIntoFeed(feed, opener, opts, "rider_categories.txt", func(row *CSVRow) {
+		category := ParseRiderCategory(row)
+		feed.RiderCategories[category.ID] = category
+		feed.RiderCategoryOrder = append(feed.RiderCategoryOrder, category.ID)
+	}); err != nil {
+		return fmt.Errorf("reading rider_categories.txt: %w", err)
+	}
+
 	return nil
 }
 
+// hashingOpener wraps opener so that each file opened is written to h,
+// after its name, as it is read
+func hashingOpener(opener func(string) (io.ReadCloser, error), h hash.Hash) func(string) (io.ReadCloser, error) {
+	return func(filename string) (io.ReadCloser, error) {
+		rc, err := opener(filename)
+		if err != nil {
+			return nil, err
+		}
+		_, _ = io.WriteString(h, filename+"\x00")
+		return struct {
+			io.Reader
+			io.Closer
+		}{io.TeeReader(rc, h), rc}, nil
+	}
+}
+
+// feedFiles lists the GTFS files in the order readFeedFiles reads them,
+// which is the order their bytes are hashed in
+var feedFiles = []string{
+	"agency.txt", "stops.txt", "routes.txt", "trips.txt", "stop_times.txt",
+	"calendar.txt", "calendar_dates.txt", "shapes.txt", "frequencies.txt",
+	"transfers.txt", "fare_attributes.txt", "fare_rules.txt", "feed_info.txt",
+	"areas.txt", "pathways.txt", "networks.txt", "route_networks.txt",
+	"fare_leg_rules.txt", "fare_transfer_rules.txt", "fare_products.txt",
+	"timeframes.txt", "fare_media.txt", "rider_categories.txt",
+}
+
+// HashPath returns the ContentHash of the feed at path, a directory or
+// zip file, as read with default options, without parsing its files. It
+// fails as reading would if a required file is missing.
+func HashPath(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot access path %s: %w", path, err)
+	}
+
+	var opener func(string) (io.ReadCloser, error)
+	if info.IsDir() {
+		opener, err = directoryOpener(context.Background(), path)
+	} else {
+		r, zipErr := zip.OpenReader(path)
+		if zipErr != nil {
+			return "", fmt.Errorf("cannot open zip file %s: %w", path, zipErr)
+		}
+		defer func() { _ = r.Close() }()
+		opener, err = zipOpener(context.Background(), &r.Reader, nil, 0)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	opener = hashingOpener(opener, hash)
+	for _, filename := range feedFiles {
+		rc, err := opener(filename)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(io.Discard, rc)
+		_ = rc.Close()
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
 // readFileIntoFeed reads a required GTFS file and processes each row
-func readFileIntoFeed(feed *Feed, opener func(string) (io.ReadCloser, error), filename string, process func(*CSVRow)) error {
+func readFileIntoFeed(feed *Feed, opener func(string) (io.ReadCloser, error), opts *ReaderOptions, filename string, process func(*CSVRow)) error {
+	if opts.skipFile(filename) {
+		feed.PartialRead = append(feed.PartialRead, filename)
+		return nil
+	}
+
 	rc, err := opener(filename)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = rc.Close() }()
 
-	reader := NewCSVReader(rc)
+	decoded, err := decodeFile(feed, filename, rc, opts)
+	if err != nil {
+		return err
+	}
+	limited := newFieldLimiter(decoded, opts)
+	reader := NewCSVReader(limited)
 	header, err := reader.ReadHeader()
+	if err == io.EOF {
+		// An empty file is treated as absent
+		return fmt.Errorf("%w: %s is empty", ErrMissingRequiredFile, filename)
+	}
 	if err != nil {
 		return fmt.Errorf("reading header: %w", err)
 	}
@@ -356,23 +673,16 @@ func readFileIntoFeed(feed *Feed, opener func(string) (io.ReadCloser, error), fi
 	// Track which columns were present in this file
 	feed.AddColumnSet(filename, header)
 
-	for {
-		record, err := reader.ReadRecord()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return fmt.Errorf("reading record: %w", err)
-		}
-		row := NewCSVRow(header, record)
-		process(row)
-	}
-
-	return nil
+	return readRecords(feed, reader, limited, opts, filename, header, process)
 }
 
 // readOptionalFileIntoFeed reads an optional GTFS file if it exists
-func readOptionalFileIntoFeed(feed *Feed, opener func(string) (io.ReadCloser, error), filename string, process func(*CSVRow)) error {
+func readOptionalFileIntoFeed(feed *Feed, opener func(string) (io.ReadCloser, error), opts *ReaderOptions, filename string, process func(*CSVRow)) error {
+	if opts.skipFile(filename) {
+		feed.PartialRead = append(feed.PartialRead, filename)
+		return nil
+	}
+
 	rc, err := opener(filename)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -382,11 +692,22 @@ func readOptionalFileIntoFeed(feed *Feed, opener func(string) (io.ReadCloser, er
 	}
 	defer func() { _ = rc.Close() }()
 
-	reader := NewCSVReader(rc)
+	decoded, err := decodeFile(feed, filename, rc, opts)
+	if err != nil {
+		return err
+	}
+	limited := newFieldLimiter(decoded, opts)
+	reader := NewCSVReader(limited)
 	header, err := reader.ReadHeader()
 	if err != nil {
 		if err == io.EOF {
-			return nil // Empty file is OK for optional files
+			// An empty file is treated as absent, unlike a header-only
+			// file, whose columns are still recorded
+			feed.ParseWarnings = append(feed.ParseWarnings, &ParseError{
+				File:    filename,
+				Message: "file is empty; treated as absent",
+			})
+			return nil
 		}
 		return fmt.Errorf("reading header: %w", err)
 	}
@@ -394,17 +715,47 @@ func readOptionalFileIntoFeed(feed *Feed, opener func(string) (io.ReadCloser, er
 	// Track which columns were present in this file
 	feed.AddColumnSet(filename, header)
 
+	return readRecords(feed, reader, limited, opts, filename, header, process)
+}
+
+// readRecords processes each remaining record of a file, read from limited
+// (see newFieldLimiter). Malformed rows and values are returned as a
+// *ParseError in strict mode and collected in feed.ParseWarnings
+// otherwise. Fields too long to read are returned as a *ParseError, or if
+// truncated, collected in feed.ParseWarnings.
+func readRecords(feed *Feed, reader *CSVReader, limited io.Reader, opts *ReaderOptions, filename string, header []string, process func(*CSVRow)) error {
+	// One row is reused for every record, so the column index is built once
+	// per file rather than once per row; process must not keep it
+	row := NewCSVRow(header, nil)
+	row.file = filename
 	for {
 		record, err := reader.ReadRecord()
 		if err == io.EOF {
-			break
+			if limiter, ok := limited.(*fieldLimiter); ok {
+				feed.ParseWarnings = append(feed.ParseWarnings, limiter.truncationWarnings(filename, header)...)
+			}
+			return nil
 		}
 		if err != nil {
+			var tooLong *fieldTooLongError
+			if errors.As(err, &tooLong) {
+				return tooLong.parseError(filename, header)
+			}
 			return fmt.Errorf("reading record: %w", err)
 		}
-		row := NewCSVRow(header, record)
+		row.reset(record, reader.Line())
+		row.checkFieldCount()
 		process(row)
-	}
+		if feed.RowsRead == nil {
+			feed.RowsRead = make(map[string]int)
+		}
+		feed.RowsRead[filename]++
 
-	return nil
+		if issues := row.Issues(); len(issues) > 0 {
+			if opts.Strict {
+				return issues[0]
+			}
+			feed.ParseWarnings = append(feed.ParseWarnings, issues...)
+		}
+	}
 }
@@ -0,0 +1,83 @@
+package gtfs
+
+// ReaderOptions configures how a feed is read.
+// The zero value reproduces the default reader behavior.
+type ReaderOptions struct {
+	// Strict makes the read fail on the first malformed row or field value
+	// (a *ParseError). When false, malformed values fall back to their
+	// defaults and each problem is collected in Feed.ParseWarnings.
+	Strict bool
+
+	// SkipFiles lists GTFS files (e.g. "stop_times.txt") that are not read.
+	// Their collections are left empty and the files are recorded in
+	// Feed.PartialRead. Required files are still checked for presence.
+	SkipFiles []string
+
+	// Encoding is the character encoding of the feed's files. The zero value
+	// reads them as UTF-8; EncodingAuto detects it per file.
+	Encoding Encoding
+
+	// LegacyEncoding is the encoding EncodingAuto falls back to for files
+	// that are not valid UTF-8: EncodingWindows1252 (default, a superset of
+	// Latin-1's printable characters) or EncodingLatin1
+	LegacyEncoding Encoding
+
+	// MaxFieldLength is the longest field, in bytes as written in the file,
+	// that is read: 0 means DefaultMaxFieldLength, and a negative value
+	// reads fields of any length. Files are streamed, so this bounds the
+	// memory a single malformed or bloated row can take. A longer field
+	// fails the read with a *ParseError naming its file, line and column,
+	// unless TruncateLongFields is set.
+	MaxFieldLength int
+
+	// TruncateLongFields cuts fields longer than MaxFieldLength down to it
+	// instead of failing, recording each in Feed.ParseWarnings
+	TruncateLongFields bool
+
+	// DisableNestedZip stops a zip archive whose only entry is another zip,
+	// with no .txt files beside it, from being read as that inner archive
+	DisableNestedZip bool
+
+	// MaxNestedZipSize is the largest nested zip, in bytes once
+	// decompressed, that is read: 0 means DefaultMaxNestedZipSize, and a
+	// negative value reads nested zips of any size. A nested zip is read
+	// into memory, so this bounds the memory it can take. A larger one
+	// fails the read with ErrNestedZipTooLarge.
+	MaxNestedZipSize int64
+}
+
+// metadataOnlyFiles are the large files skipped by ReadMetadataOnly
+var metadataOnlyFiles = []string{"stop_times.txt", "shapes.txt"}
+
+// MetadataOnlyOptions returns ReaderOptions that skip stop_times.txt and
+// shapes.txt, for operations that only need the small tables
+func MetadataOnlyOptions() ReaderOptions {
+	return ReaderOptions{SkipFiles: append([]string(nil), metadataOnlyFiles...)}
+}
+
+// skipFile reports whether filename should not be read.
+// A nil receiver skips nothing.
+func (o *ReaderOptions) skipFile(filename string) bool {
+	if o == nil {
+		return false
+	}
+	for _, f := range o.SkipFiles {
+		if f == filename {
+			return true
+		}
+	}
+	return false
+}
+
+// maxNestedZipSize returns the largest nested zip to read, or -1 for no
+// limit. A nil receiver returns the default.
+func (o *ReaderOptions) maxNestedZipSize() int64 {
+	switch {
+	case o == nil || o.MaxNestedZipSize == 0:
+		return DefaultMaxNestedZipSize
+	case o.MaxNestedZipSize < 0:
+		return -1
+	default:
+		return o.MaxNestedZipSize
+	}
+}
@@ -2,8 +2,12 @@ package gtfs
 
 import (
 	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -408,6 +412,97 @@ func TestReadFromZipMissingRequired(t *testing.T) {
 	}
 }
 
+// createZipOfZip wraps the zip at innerPath in a new zip at destPath, under
+// the name innerName
+func createZipOfZip(t *testing.T, innerPath, innerName, destPath string) {
+	t.Helper()
+	inner, err := os.ReadFile(innerPath)
+	if err != nil {
+		t.Fatalf("failed to read inner zip: %v", err)
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create(innerName)
+	if err != nil {
+		t.Fatalf("failed to add inner zip: %v", err)
+	}
+	if _, err := w.Write(inner); err != nil {
+		t.Fatalf("failed to write inner zip: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to close zip: %v", err)
+	}
+	if err := os.WriteFile(destPath, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("failed to write zip: %v", err)
+	}
+}
+
+func TestReadFromZipOfZip(t *testing.T) {
+	// Given: a zip whose only entry is a zipped feed
+	dir := t.TempDir()
+	innerPath := filepath.Join(dir, "inner.zip")
+	if err := createTestZip(t, "../testdata/minimal", innerPath); err != nil {
+		t.Fatalf("failed to create zip: %v", err)
+	}
+	outerPath := filepath.Join(dir, "gtfs.zip")
+	createZipOfZip(t, innerPath, "feed.zip", outerPath)
+
+	t.Run("read through", func(t *testing.T) {
+		feed, err := ReadFromPath(outerPath)
+		if err != nil {
+			t.Fatalf("ReadFromPath failed: %v", err)
+		}
+		if len(feed.Agencies) != 1 || len(feed.Stops) != 1 {
+			t.Errorf("expected the inner feed, got %v", feed.RowCounts())
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		_, err := ReadFromPathWithOptions(outerPath, ReaderOptions{DisableNestedZip: true})
+		if !errors.Is(err, ErrMissingRequiredFile) || !strings.Contains(err.Error(), "feed.zip") {
+			t.Errorf("expected a missing file error naming feed.zip, got %v", err)
+		}
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		_, err := ReadFromPathWithOptions(outerPath, ReaderOptions{MaxNestedZipSize: 100})
+		if !errors.Is(err, ErrNestedZipTooLarge) || !strings.Contains(err.Error(), "feed.zip") {
+			t.Errorf("expected ErrNestedZipTooLarge naming feed.zip, got %v", err)
+		}
+	})
+
+	t.Run("too deep", func(t *testing.T) {
+		deepPath := filepath.Join(dir, "deep.zip")
+		createZipOfZip(t, outerPath, "gtfs.zip", deepPath)
+		_, err := ReadFromPath(deepPath)
+		if !errors.Is(err, ErrNestedZipTooDeep) || !strings.Contains(err.Error(), "feed.zip") {
+			t.Errorf("expected ErrNestedZipTooDeep naming feed.zip, got %v", err)
+		}
+	})
+}
+
+func TestReadFromZipEncrypted(t *testing.T) {
+	// Given: a zip with an entry flagged as encrypted
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "agency.txt", Method: zip.Store, Flags: 0x1})
+	if err != nil {
+		t.Fatalf("failed to add entry: %v", err)
+	}
+	if _, err := w.Write([]byte("not really ciphertext")); err != nil {
+		t.Fatalf("failed to write entry: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to close zip: %v", err)
+	}
+
+	// When/Then: reading it says why it can't be read
+	_, err = ReadFromZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if !errors.Is(err, ErrEncryptedZip) || !strings.Contains(err.Error(), "agency.txt") {
+		t.Errorf("expected ErrEncryptedZip naming agency.txt, got %v", err)
+	}
+}
+
 // Helper function to create a test zip file from a directory
 func createTestZip(t *testing.T, srcDir, destPath string) error {
 	t.Helper()
@@ -498,3 +593,412 @@ func createPartialZip(t *testing.T, destPath string) error {
 	_, err = w.Write([]byte("agency_id,agency_name,agency_url,agency_timezone\nagency1,Test,http://test.com,UTC\n"))
 	return err
 }
+
+func TestReadFromPathContextCanceled(t *testing.T) {
+	// Given: a context that is already canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// When: reading a valid feed
+	_, err := ReadFromPathContext(ctx, "../testdata/simple_a")
+
+	// Then: the cancellation is returned with the file being read
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "agency.txt") {
+		t.Errorf("expected error to name agency.txt, got %v", err)
+	}
+}
+
+// writeMalformedFeed writes a minimal feed whose stop_times.txt has an invalid
+// stop_sequence on line 3 and a short row on line 4
+func writeMalformedFeed(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"agency.txt":     "agency_id,agency_name,agency_url,agency_timezone\nagency1,Test,http://test.com,UTC\n",
+		"stops.txt":      "stop_id,stop_name,stop_lat,stop_lon\nstop1,Stop,0.0,0.0\n",
+		"routes.txt":     "route_id,agency_id,route_short_name,route_long_name,route_type\nroute1,agency1,1,Test,3\n",
+		"trips.txt":      "route_id,service_id,trip_id\nroute1,service1,trip1\n",
+		"stop_times.txt": "trip_id,arrival_time,departure_time,stop_id,stop_sequence\ntrip1,08:00:00,08:00:00,stop1,1\ntrip1,08:05:00,08:05:00,stop1,abc\ntrip1,08:10:00\n",
+		"calendar.txt":   "service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date\nservice1,1,1,1,1,1,0,0,20240101,20241231\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestReadLenientCollectsParseWarnings(t *testing.T) {
+	feed, err := ReadFromPath(writeMalformedFeed(t))
+	if err != nil {
+		t.Fatalf("ReadFromPath failed: %v", err)
+	}
+
+	// Malformed rows are still read, with defaults
+	if len(feed.StopTimes) != 3 {
+		t.Errorf("expected 3 stop times, got %d", len(feed.StopTimes))
+	}
+
+	expected := []string{
+		`stop_times.txt line 3: invalid stop_sequence "abc"`,
+		"stop_times.txt line 4: row has 2 fields, header has 5",
+	}
+	if len(feed.ParseWarnings) != len(expected) {
+		t.Fatalf("expected %d warnings, got %d: %v", len(expected), len(feed.ParseWarnings), feed.ParseWarnings)
+	}
+	for i, want := range expected {
+		if got := feed.ParseWarnings[i].Error(); got != want {
+			t.Errorf("warning %d: expected %q, got %q", i, want, got)
+		}
+	}
+}
+
+func TestReadStrictReturnsParseError(t *testing.T) {
+	_, err := ReadFromPathWithOptions(writeMalformedFeed(t), ReaderOptions{Strict: true})
+	if err == nil {
+		t.Fatal("expected error in strict mode, got nil")
+	}
+
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("expected *ParseError, got %T: %v", err, err)
+	}
+	if pe.File != "stop_times.txt" || pe.Line != 3 || pe.Column != "stop_sequence" {
+		t.Errorf("unexpected parse error location: %+v", pe)
+	}
+}
+
+func TestReadDuplicateCalendarDateWarns(t *testing.T) {
+	// Given: calendar_dates.txt both adds and removes service1 on one date
+	dir := writeMalformedFeed(t)
+	content := "service_id,date,exception_type\nservice1,20240704,1\nservice1,20240705,2\nservice1,20240704,2\n"
+	if err := os.WriteFile(filepath.Join(dir, "calendar_dates.txt"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write calendar_dates.txt: %v", err)
+	}
+
+	// When: reading the feed
+	feed, err := ReadFromPath(dir)
+	if err != nil {
+		t.Fatalf("ReadFromPath failed: %v", err)
+	}
+
+	// Then: the repeated date is a warning, and the row is kept for the merge
+	// to resolve
+	want := `calendar_dates.txt line 4: duplicate calendar_date for service_id "service1" date "20240704"`
+	var found bool
+	for _, w := range feed.ParseWarnings {
+		found = found || w.Error() == want
+	}
+	if !found {
+		t.Errorf("Expected warning %q, got %v", want, feed.ParseWarnings)
+	}
+	if n := len(feed.CalendarDates["service1"]); n != 3 {
+		t.Errorf("Expected 3 calendar dates, got %d", n)
+	}
+}
+
+func TestReadCountsRowsRead(t *testing.T) {
+	// Given: a feed whose stops.txt repeats a stop_id
+	dir := writeMalformedFeed(t)
+	content := "stop_id,stop_name,stop_lat,stop_lon\nstop1,Stop,0.0,0.0\nstop1,Stop again,0.0,0.0\n"
+	if err := os.WriteFile(filepath.Join(dir, "stops.txt"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write stops.txt: %v", err)
+	}
+
+	// When: reading the feed
+	feed, err := ReadFromPath(dir)
+	if err != nil {
+		t.Fatalf("ReadFromPath failed: %v", err)
+	}
+
+	// Then: every data row is counted, though the repeated ID keeps one stop
+	if got := feed.RowsRead["stops.txt"]; got != 2 {
+		t.Errorf("Expected 2 stops.txt rows read, got %d", got)
+	}
+	if len(feed.Stops) != 1 {
+		t.Errorf("Expected 1 stop, got %d", len(feed.Stops))
+	}
+	if got := feed.RowsRead["stop_times.txt"]; got != 3 {
+		t.Errorf("Expected 3 stop_times.txt rows read, got %d", got)
+	}
+
+	// And: files not present are not counted
+	if _, ok := feed.RowsRead["transfers.txt"]; ok {
+		t.Errorf("Expected no transfers.txt count, got %v", feed.RowsRead)
+	}
+}
+
+func TestReadValidFeedHasNoParseWarnings(t *testing.T) {
+	feed, err := ReadFromPathWithOptions("../testdata/simple_a", ReaderOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("ReadFromPathWithOptions failed: %v", err)
+	}
+	if len(feed.ParseWarnings) != 0 {
+		t.Errorf("expected no warnings, got %v", feed.ParseWarnings)
+	}
+}
+
+func TestReadHeaderOnlyFile(t *testing.T) {
+	// Given: a feed whose transfers.txt has only a header row
+	feed, err := ReadFromPath("../testdata/header_only_transfers")
+	if err != nil {
+		t.Fatalf("ReadFromPath failed: %v", err)
+	}
+
+	// Then: it reads as no transfers, with its columns still recorded and
+	// nothing to warn about
+	if len(feed.Transfers) != 0 {
+		t.Errorf("Expected no transfers, got %d", len(feed.Transfers))
+	}
+	if cols := feed.ColumnSets["transfers.txt"]; !cols["min_transfer_time"] {
+		t.Errorf("Expected transfers.txt columns to be recorded, got %v", cols)
+	}
+	if len(feed.ParseWarnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", feed.ParseWarnings)
+	}
+}
+
+func TestReadZeroByteFile(t *testing.T) {
+	// Given: a feed whose calendar_dates.txt is zero bytes
+	feed, err := ReadFromPath("../testdata/zero_byte_calendar_dates")
+	if err != nil {
+		t.Fatalf("ReadFromPath failed: %v", err)
+	}
+
+	// Then: it is treated as absent, with a warning
+	if _, tracked := feed.ColumnSets["calendar_dates.txt"]; tracked || len(feed.CalendarDates) != 0 {
+		t.Errorf("Expected calendar_dates.txt to be absent, got columns %v", feed.ColumnSets["calendar_dates.txt"])
+	}
+	want := "calendar_dates.txt: file is empty; treated as absent"
+	if len(feed.ParseWarnings) != 1 || feed.ParseWarnings[0].Error() != want {
+		t.Errorf("Expected warning %q, got %v", want, feed.ParseWarnings)
+	}
+}
+
+func TestReadZeroByteRequiredFile(t *testing.T) {
+	tests := []struct {
+		name  string
+		empty []string
+		want  error
+	}{
+		{"required file", []string{"stops.txt"}, ErrMissingRequiredFile},
+		{"only calendar file", []string{"calendar.txt"}, ErrMissingCalendarFile},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: a feed whose file is zero bytes
+			dir := writeMalformedFeed(t)
+			for _, name := range tt.empty {
+				if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+					t.Fatalf("failed to write %s: %v", name, err)
+				}
+			}
+
+			// When: read
+			_, err := ReadFromPath(dir)
+
+			// Then: it fails as if the file were missing
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestReadMetadataOnly(t *testing.T) {
+	feed, err := ReadMetadataOnly("../testdata/all_optional_feed")
+	if err != nil {
+		t.Fatalf("ReadMetadataOnly failed: %v", err)
+	}
+
+	// Large tables are skipped and recorded
+	if len(feed.StopTimes) != 0 {
+		t.Errorf("expected no stop times, got %d", len(feed.StopTimes))
+	}
+	if len(feed.Shapes) != 0 {
+		t.Errorf("expected no shapes, got %d", len(feed.Shapes))
+	}
+	expected := []string{"stop_times.txt", "shapes.txt"}
+	if strings.Join(feed.PartialRead, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected PartialRead %v, got %v", expected, feed.PartialRead)
+	}
+
+	// Small tables are still read
+	if len(feed.Agencies) == 0 || len(feed.Stops) == 0 || len(feed.Routes) == 0 || len(feed.Trips) == 0 {
+		t.Error("expected agencies, stops, routes and trips to be read")
+	}
+}
+
+func TestReadFromZipWithSkipFiles(t *testing.T) {
+	zipPath := filepath.Join(t.TempDir(), "feed.zip")
+	if err := createTestZip(t, "../testdata/simple_a", zipPath); err != nil {
+		t.Fatalf("failed to create zip: %v", err)
+	}
+	data, err := os.ReadFile(zipPath)
+	if err != nil {
+		t.Fatalf("failed to read zip: %v", err)
+	}
+
+	feed, err := ReadFromZipWithOptions(bytes.NewReader(data), int64(len(data)), ReaderOptions{SkipFiles: []string{"stop_times.txt"}})
+	if err != nil {
+		t.Fatalf("ReadFromZipWithOptions failed: %v", err)
+	}
+	if len(feed.StopTimes) != 0 {
+		t.Errorf("expected no stop times, got %d", len(feed.StopTimes))
+	}
+	if len(feed.PartialRead) != 1 || feed.PartialRead[0] != "stop_times.txt" {
+		t.Errorf("expected PartialRead [stop_times.txt], got %v", feed.PartialRead)
+	}
+}
+
+func TestReadFullFeedIsNotPartial(t *testing.T) {
+	feed, err := ReadFromPath("../testdata/simple_a")
+	if err != nil {
+		t.Fatalf("ReadFromPath failed: %v", err)
+	}
+	if len(feed.PartialRead) != 0 {
+		t.Errorf("expected full read, got PartialRead %v", feed.PartialRead)
+	}
+}
+
+func TestReadContentHash(t *testing.T) {
+	// Given: simple_a, a copy of it, and simple_b
+	copyPath := filepath.Join(t.TempDir(), "copy")
+	if err := os.CopyFS(copyPath, os.DirFS("../testdata/simple_a")); err != nil {
+		t.Fatalf("failed to copy simple_a: %v", err)
+	}
+	read := func(path string) string {
+		feed, err := ReadFromPath(path)
+		if err != nil {
+			t.Fatalf("ReadFromPath(%s) failed: %v", path, err)
+		}
+		return feed.ContentHash
+	}
+
+	// Then: identical files hash the same and different files do not
+	a, copyOfA, b := read("../testdata/simple_a"), read(copyPath), read("../testdata/simple_b")
+	if len(a) != 64 {
+		t.Errorf("Expected a hex SHA-256, got %q", a)
+	}
+	if a != copyOfA {
+		t.Errorf("Expected a copy to hash the same: %s != %s", a, copyOfA)
+	}
+	if a == b {
+		t.Errorf("Expected different feeds to hash differently")
+	}
+}
+
+func TestHashPath(t *testing.T) {
+	// Given: simple_a as a directory and as a zip, and a feed with optional
+	// files
+	zipPath := filepath.Join(t.TempDir(), "simple_a.zip")
+	if err := createTestZip(t, "../testdata/simple_a", zipPath); err != nil {
+		t.Fatalf("failed to create zip: %v", err)
+	}
+
+	// Then: each hashes to the ContentHash it is read with
+	for _, path := range []string{"../testdata/simple_a", zipPath, "../testdata/all_optional_feed"} {
+		feed, err := ReadFromPath(path)
+		if err != nil {
+			t.Fatalf("ReadFromPath(%s) failed: %v", path, err)
+		}
+		hash, err := HashPath(path)
+		if err != nil {
+			t.Fatalf("HashPath(%s) failed: %v", path, err)
+		}
+		if hash != feed.ContentHash {
+			t.Errorf("%s: expected %s, got %s", path, feed.ContentHash, hash)
+		}
+	}
+
+	// And: a feed missing a required file fails as reading it would
+	dir := t.TempDir()
+	if _, err := HashPath(dir); !errors.Is(err, ErrMissingRequiredFile) {
+		t.Errorf("Expected ErrMissingRequiredFile, got %v", err)
+	}
+}
+
+// writeLongFieldFeed writes a minimal feed, zipped, whose second stop has a
+// stop_desc of descLen bytes, and returns the zip's path
+func writeLongFieldFeed(t *testing.T, descLen int) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"agency.txt": "agency_id,agency_name,agency_url,agency_timezone\nagency1,Test,http://test.com,UTC\n",
+		"stops.txt": "stop_id,stop_name,stop_desc,stop_lat,stop_lon\n" +
+			"stop1,First,short,47.1,-122.1\n" +
+			"stop2,Second,\"data:image/png;base64," + strings.Repeat("A", descLen-22) + "\",47.2,-122.2\n" +
+			"stop3,Third,,47.3,-122.3\n",
+		"routes.txt":     "route_id,agency_id,route_short_name,route_long_name,route_type\nroute1,agency1,1,Test,3\n",
+		"trips.txt":      "route_id,service_id,trip_id\nroute1,service1,trip1\n",
+		"stop_times.txt": "trip_id,arrival_time,departure_time,stop_id,stop_sequence\ntrip1,08:00:00,08:00:00,stop1,1\n",
+		"calendar.txt":   "service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date\nservice1,1,1,1,1,1,0,0,20240101,20241231\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	zipPath := filepath.Join(t.TempDir(), "feed.zip")
+	if err := createTestZip(t, dir, zipPath); err != nil {
+		t.Fatalf("failed to create zip: %v", err)
+	}
+	return zipPath
+}
+
+func TestReadLongField(t *testing.T) {
+	// Given: a 2 MB stop_desc, past the default limit
+	zipPath := writeLongFieldFeed(t, 2<<20)
+
+	t.Run("fails by default", func(t *testing.T) {
+		_, err := ReadFromPath(zipPath)
+
+		// Then: the error names the file, line and column
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Fatalf("expected *ParseError, got %T: %v", err, err)
+		}
+		if pe.File != "stops.txt" || pe.Line != 3 || pe.Column != "stop_desc" {
+			t.Errorf("unexpected parse error location: %+v", pe)
+		}
+		if want := "stops.txt line 3: stop_desc is longer than 1048576 bytes"; pe.Error() != want {
+			t.Errorf("expected %q, got %q", want, pe.Error())
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		feed, err := ReadFromPathWithOptions(zipPath, ReaderOptions{TruncateLongFields: true})
+		if err != nil {
+			t.Fatalf("ReadFromPathWithOptions failed: %v", err)
+		}
+
+		// Then: the field is cut to the limit, and the rest of the row and
+		// file read as usual
+		if got := len(feed.Stops["stop2"].Desc); got != DefaultMaxFieldLength {
+			t.Errorf("expected stop_desc of %d bytes, got %d", DefaultMaxFieldLength, got)
+		}
+		if feed.Stops["stop2"].Lat != 47.2 || feed.Stops["stop3"] == nil {
+			t.Errorf("expected the rest of stops.txt read, got %+v and %+v", feed.Stops["stop2"].Lat, feed.Stops["stop3"])
+		}
+
+		// And: the truncation is warned about
+		want := "stops.txt line 3: stop_desc truncated to 1048576 bytes"
+		if len(feed.ParseWarnings) != 1 || feed.ParseWarnings[0].Error() != want {
+			t.Errorf("expected warning %q, got %v", want, feed.ParseWarnings)
+		}
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		feed, err := ReadFromPathWithOptions(zipPath, ReaderOptions{MaxFieldLength: -1})
+		if err != nil {
+			t.Fatalf("ReadFromPathWithOptions failed: %v", err)
+		}
+		if got := len(feed.Stops["stop2"].Desc); got != 2<<20 {
+			t.Errorf("expected stop_desc of %d bytes, got %d", 2<<20, got)
+		}
+	})
+}
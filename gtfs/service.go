@@ -0,0 +1,202 @@
+package gtfs
+
+import (
+	"slices"
+	"time"
+)
+
+// dateFormat is the layout of GTFS dates (YYYYMMDD)
+const dateFormat = "20060102"
+
+// ActiveServiceOn returns the IDs of the services active on date, in ID
+// order: those whose calendar runs on date's weekday between its
+// start_date and end_date, unless calendar_dates removes date from them
+// (exception_type 2), and those calendar_dates adds date to (exception_type
+// 1). Only date's year, month and day, in its location, are used.
+func (f *Feed) ActiveServiceOn(date time.Time) []ServiceID {
+	day := date.Format(dateFormat)
+	active := make(map[ServiceID]bool)
+	for id, cal := range f.Calendars {
+		if cal.StartDate <= day && day <= cal.EndDate && cal.runsOn(date.Weekday()) {
+			active[id] = true
+		}
+	}
+	for id, dates := range f.CalendarDates {
+		for _, cd := range dates {
+			if cd.Date != day {
+				continue
+			}
+			switch cd.ExceptionType {
+			case 1:
+				active[id] = true
+			case 2:
+				delete(active, id)
+			}
+		}
+	}
+
+	ids := make([]ServiceID, 0, len(active))
+	for id := range active {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// LastServiceDate returns the last date on which any service is active
+// (see ActiveServiceOn), at midnight UTC. The second result is false if no
+// service is ever active.
+func (f *Feed) LastServiceDate() (time.Time, bool) {
+	start, end, ok := f.serviceWindow()
+	if !ok {
+		return time.Time{}, false
+	}
+	for day := end; !day.Before(start); day = day.AddDate(0, 0, -1) {
+		if len(f.ActiveServiceOn(day)) > 0 {
+			return day, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// serviceWindow returns the earliest and latest dates, at midnight UTC, that
+// calendar.txt and calendar_dates.txt give any service, between which alone
+// service can be active. The third result is false if they give none.
+func (f *Feed) serviceWindow() (time.Time, time.Time, bool) {
+	var first, last string
+	widen := func(from, to string) {
+		if first == "" || from < first {
+			first = from
+		}
+		if to > last {
+			last = to
+		}
+	}
+	for _, cal := range f.Calendars {
+		if cal.StartDate <= cal.EndDate && cal.runsOnAnyDay() {
+			widen(cal.StartDate, cal.EndDate)
+		}
+	}
+	for _, dates := range f.CalendarDates {
+		for _, cd := range dates {
+			if cd.ExceptionType == 1 {
+				widen(cd.Date, cd.Date)
+			}
+		}
+	}
+
+	start, err := time.Parse(dateFormat, first)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	end, err := time.Parse(dateFormat, last)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	return start, end, true
+}
+
+// ServiceGap is a run of consecutive days on which a route has no trips
+// (see FindServiceGaps)
+type ServiceGap struct {
+	RouteID RouteID
+
+	// Start and End are the first and last days without service, at
+	// midnight UTC, and Days the number of days from Start to End
+	Start time.Time
+	End   time.Time
+	Days  int
+}
+
+// FindServiceGaps returns the runs of more than maxGapDays consecutive days
+// on which a route of feed has no trips, as when one edition of a feed ends
+// days before the next begins: a route's trips run on the days their
+// services are active (see Feed.ActiveServiceOn). Only the days between two
+// days a route runs are gaps, so a route starting after, or ending before,
+// the rest of the feed has none there. Gaps are in routes.txt order, then
+// date order.
+func FindServiceGaps(feed *Feed, maxGapDays int) []ServiceGap {
+	start, end, ok := feed.serviceWindow()
+	if !ok {
+		return nil
+	}
+	days := int(end.Sub(start).Hours()/24) + 1
+
+	servicesByRoute := make(map[RouteID]map[ServiceID]bool)
+	for _, trip := range feed.Trips {
+		if servicesByRoute[trip.RouteID] == nil {
+			servicesByRoute[trip.RouteID] = make(map[ServiceID]bool)
+		}
+		servicesByRoute[trip.RouteID][trip.ServiceID] = true
+	}
+
+	// Each service's active days are expanded once, indexed from start
+	active := make(map[ServiceID][]bool)
+	serviceDays := func(id ServiceID) []bool {
+		if on, ok := active[id]; ok {
+			return on
+		}
+		on := make([]bool, days)
+		if cal := feed.Calendars[id]; cal != nil {
+			for i := range days {
+				day := start.AddDate(0, 0, i)
+				date := day.Format(dateFormat)
+				on[i] = cal.StartDate <= date && date <= cal.EndDate && cal.runsOn(day.Weekday())
+			}
+		}
+		for _, cd := range feed.CalendarDates[id] {
+			day, err := time.Parse(dateFormat, cd.Date)
+			if err != nil {
+				continue
+			}
+			i := int(day.Sub(start).Hours() / 24)
+			if i < 0 || i >= days {
+				continue
+			}
+			switch cd.ExceptionType {
+			case 1:
+				on[i] = true
+			case 2:
+				on[i] = false
+			}
+		}
+		active[id] = on
+		return on
+	}
+
+	var gaps []ServiceGap
+	for _, routeID := range feed.RouteOrder {
+		runs := make([]bool, days)
+		for serviceID := range servicesByRoute[routeID] {
+			for i, on := range serviceDays(serviceID) {
+				runs[i] = runs[i] || on
+			}
+		}
+		last := -1 // the last day the route ran
+		for i, on := range runs {
+			if !on {
+				continue
+			}
+			if last >= 0 && i-last-1 > maxGapDays {
+				gaps = append(gaps, ServiceGap{
+					RouteID: routeID,
+					Start:   start.AddDate(0, 0, last+1),
+					End:     start.AddDate(0, 0, i-1),
+					Days:    i - last - 1,
+				})
+			}
+			last = i
+		}
+	}
+	return gaps
+}
+
+// runsOn reports whether the calendar runs on weekday
+func (c *Calendar) runsOn(weekday time.Weekday) bool {
+	return [...]bool{c.Sunday, c.Monday, c.Tuesday, c.Wednesday, c.Thursday, c.Friday, c.Saturday}[weekday]
+}
+
+// runsOnAnyDay reports whether the calendar runs on any weekday
+func (c *Calendar) runsOnAnyDay() bool {
+	return c.Monday || c.Tuesday || c.Wednesday || c.Thursday || c.Friday || c.Saturday || c.Sunday
+}
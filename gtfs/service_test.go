@@ -0,0 +1,133 @@
+package gtfs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// serviceFeed returns a feed with weekday service through October 2026,
+// Saturday service in October, a holiday removing weekday service and a
+// special event service added on a Sunday
+func serviceFeed() *Feed {
+	feed := NewFeed()
+	feed.AddCalendar(&Calendar{
+		ServiceID: "weekday", Monday: true, Tuesday: true, Wednesday: true, Thursday: true, Friday: true,
+		StartDate: "20260101", EndDate: "20261031",
+	})
+	feed.AddCalendar(&Calendar{ServiceID: "saturday", Saturday: true, StartDate: "20261001", EndDate: "20261031"})
+	feed.AddCalendarDate(&CalendarDate{ServiceID: "weekday", Date: "20261012", ExceptionType: 2})
+	feed.AddCalendarDate(&CalendarDate{ServiceID: "event", Date: "20261108", ExceptionType: 1})
+	return feed
+}
+
+// date returns the given day at midnight UTC
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestActiveServiceOn(t *testing.T) {
+	feed := serviceFeed()
+	tests := []struct {
+		name string
+		date time.Time
+		want []ServiceID
+	}{
+		{"weekday", date(2026, 10, 14), []ServiceID{"weekday"}},
+		{"saturday", date(2026, 10, 17), []ServiceID{"saturday"}},
+		{"sunday", date(2026, 10, 18), []ServiceID{}},
+		{"removed by exception", date(2026, 10, 12), []ServiceID{}},
+		{"added by exception", date(2026, 11, 8), []ServiceID{"event"}},
+		{"before start_date", date(2025, 12, 31), []ServiceID{}},
+		{"on end_date", date(2026, 10, 30), []ServiceID{"weekday"}},
+		{"after end_date", date(2026, 11, 2), []ServiceID{}},
+		{"time of day and location ignored", time.Date(2026, 10, 14, 23, 30, 0, 0, time.FixedZone("PDT", -7*3600)), []ServiceID{"weekday"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := feed.ActiveServiceOn(tt.date); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLastServiceDate(t *testing.T) {
+	// Given: service whose last day is an added date
+	feed := serviceFeed()
+
+	// Then: the added date is the last day of service
+	if got, ok := feed.LastServiceDate(); !ok || !got.Equal(date(2026, 11, 8)) {
+		t.Errorf("Expected 2026-11-08, got %v %v", got, ok)
+	}
+
+	// Given: the added date removed, and the last calendar day removed too
+	feed.CalendarDates["event"] = nil
+	feed.AddCalendarDate(&CalendarDate{ServiceID: "saturday", Date: "20261031", ExceptionType: 2})
+
+	// Then: the last day of service is the last weekday left
+	if got, ok := feed.LastServiceDate(); !ok || !got.Equal(date(2026, 10, 30)) {
+		t.Errorf("Expected 2026-10-30, got %v %v", got, ok)
+	}
+
+	// Given: no service at all
+	// Then: there is no last day
+	if got, ok := NewFeed().LastServiceDate(); ok {
+		t.Errorf("Expected no last service date, got %v", got)
+	}
+}
+
+func TestFindServiceGaps(t *testing.T) {
+	// Given: route r1 run daily by two editions with two days between them,
+	// route r2 on weekdays with a Monday holiday, and route r3 only by the
+	// second edition
+	feed := NewFeed()
+	daily := func(id ServiceID, start, end string) *Calendar {
+		return &Calendar{ServiceID: id, Monday: true, Tuesday: true, Wednesday: true, Thursday: true,
+			Friday: true, Saturday: true, Sunday: true, StartDate: start, EndDate: end}
+	}
+	feed.AddCalendar(daily("q1", "20260101", "20260331"))
+	feed.AddCalendar(daily("q2", "20260403", "20260630"))
+	feed.AddCalendar(&Calendar{ServiceID: "weekday", Monday: true, Tuesday: true, Wednesday: true,
+		Thursday: true, Friday: true, StartDate: "20260101", EndDate: "20260630"})
+	feed.AddCalendarDate(&CalendarDate{ServiceID: "weekday", Date: "20260525", ExceptionType: 2})
+	for _, id := range []RouteID{"r1", "r2", "r3"} {
+		feed.AddRoute(&Route{ID: id})
+	}
+	for _, trip := range []*Trip{
+		{ID: "t1", RouteID: "r1", ServiceID: "q1"},
+		{ID: "t2", RouteID: "r1", ServiceID: "q2"},
+		{ID: "t3", RouteID: "r2", ServiceID: "weekday"},
+		{ID: "t4", RouteID: "r3", ServiceID: "q2"},
+	} {
+		feed.AddTrip(trip)
+	}
+
+	// When: gaps longer than a day are found
+	got := FindServiceGaps(feed, 1)
+
+	// Then: r1's gap between the editions comes first, then every weekend
+	// of r2, and r3 has no gap before it starts
+	want := ServiceGap{RouteID: "r1", Start: date(2026, 4, 1), End: date(2026, 4, 2), Days: 2}
+	if len(got) == 0 || got[0] != want {
+		t.Fatalf("Expected %v first, got %v", want, got)
+	}
+	for _, gap := range got[1:] {
+		if gap.RouteID != "r2" {
+			t.Errorf("Expected only r2's weekends after r1's gap, got %v", gap)
+		}
+	}
+
+	// When: only gaps longer than two days are found
+	// Then: the holiday weekend is r2's only gap
+	want = ServiceGap{RouteID: "r2", Start: date(2026, 5, 23), End: date(2026, 5, 25), Days: 3}
+	if got := FindServiceGaps(feed, 2); len(got) != 1 || got[0] != want {
+		t.Errorf("Expected only %v, got %v", want, got)
+	}
+
+	// When: only gaps longer than three days are found
+	// Then: there are none
+	if got := FindServiceGaps(feed, 3); len(got) != 0 {
+		t.Errorf("Expected no gaps, got %v", got)
+	}
+}
@@ -1,14 +1,84 @@
 package gtfs
 
 import (
+	"errors"
 	"fmt"
 )
 
+// ValidationCode classifies a validation error
+type ValidationCode int
+
+const (
+	// ValidationMissingRequiredField - a required field (or, for the feed,
+	// a required entity) is absent
+	ValidationMissingRequiredField ValidationCode = iota
+
+	// ValidationBrokenReference - a field names an entity the feed lacks
+	ValidationBrokenReference
+
+	// ValidationInvalidValue - a field is present but its value is invalid
+	ValidationInvalidValue
+
+	// ValidationDuplicateKey - two rows share a key that must be unique
+	ValidationDuplicateKey
+
+	// ValidationInvalidLocationType - a reference names a stop whose
+	// location_type is not allowed there, such as a stop_time served at a
+	// station (location_type 1) rather than one of its platforms
+	ValidationInvalidLocationType
+)
+
+// Sentinel errors matched by errors.Is for each ValidationCode
+var (
+	ErrMissingRequiredField = errors.New("missing required field")
+	ErrBrokenReference      = errors.New("broken reference")
+	ErrInvalidValue         = errors.New("invalid value")
+	ErrDuplicateKey         = errors.New("duplicate key")
+	ErrInvalidLocationType  = errors.New("invalid location_type")
+)
+
+// String returns the string representation of ValidationCode
+func (c ValidationCode) String() string {
+	switch c {
+	case ValidationMissingRequiredField:
+		return "missing_required_field"
+	case ValidationBrokenReference:
+		return "broken_reference"
+	case ValidationInvalidValue:
+		return "invalid_value"
+	case ValidationDuplicateKey:
+		return "duplicate_key"
+	case ValidationInvalidLocationType:
+		return "invalid_location_type"
+	default:
+		return fmt.Sprintf("ValidationCode(%d)", c)
+	}
+}
+
+// err returns the sentinel error for the code
+func (c ValidationCode) err() error {
+	switch c {
+	case ValidationMissingRequiredField:
+		return ErrMissingRequiredField
+	case ValidationBrokenReference:
+		return ErrBrokenReference
+	case ValidationInvalidValue:
+		return ErrInvalidValue
+	case ValidationDuplicateKey:
+		return ErrDuplicateKey
+	case ValidationInvalidLocationType:
+		return ErrInvalidLocationType
+	default:
+		return nil
+	}
+}
+
 // ValidationError represents a validation error with context
 type ValidationError struct {
 	EntityType string
 	EntityID   string
 	Field      string
+	Code       ValidationCode
 	Message    string
 }
 
@@ -19,6 +89,12 @@ func (e *ValidationError) Error() string {
 	return fmt.Sprintf("%s: %s", e.EntityType, e.Message)
 }
 
+// Unwrap returns the sentinel error for the error's Code, so that e.g.
+// errors.Is(err, ErrBrokenReference) holds
+func (e *ValidationError) Unwrap() error {
+	return e.Code.err()
+}
+
 // Validate checks the feed for GTFS compliance and referential integrity.
 // Returns a slice of errors, or nil if the feed is valid.
 func (f *Feed) Validate() []error {
@@ -28,6 +104,7 @@ func (f *Feed) Validate() []error {
 	if len(f.Agencies) == 0 {
 		errs = append(errs, &ValidationError{
 			EntityType: "feed",
+			Code:       ValidationMissingRequiredField,
 			Message:    "feed must have at least one agency",
 		})
 	}
@@ -52,6 +129,9 @@ func (f *Feed) Validate() []error {
 		errs = append(errs, f.validateCalendar(calendar)...)
 	}
 
+	// Validate calendar_dates (one row per service and date)
+	errs = append(errs, f.validateCalendarDates()...)
+
 	// Validate trips (required fields and route/service/shape references)
 	for _, trip := range f.Trips {
 		errs = append(errs, f.validateTrip(trip)...)
@@ -62,10 +142,12 @@ func (f *Feed) Validate() []error {
 		errs = append(errs, f.validateStopTime(stopTime)...)
 	}
 
-	// Validate transfers (stop references)
+	// Validate transfers (stop references, self-transfers and
+	// min_transfer_time) and that no transfer is repeated
 	for _, transfer := range f.Transfers {
 		errs = append(errs, f.validateTransfer(transfer)...)
 	}
+	errs = append(errs, f.validateTransferDuplicates()...)
 
 	// Validate frequencies (trip references)
 	for _, frequency := range f.Frequencies {
@@ -87,9 +169,27 @@ func (f *Feed) Validate() []error {
 		errs = append(errs, f.validatePathway(pathway)...)
 	}
 
+	// Validate route_networks (network and route references, one per route)
+	errs = append(errs, f.validateRouteNetworks()...)
+
+	// Validate fare_transfer_rules (leg group and fare product references)
+	errs = append(errs, f.validateFareTransferRules()...)
+
+	// Validate the other Fares v2 files' references
+	errs = append(errs, f.validateFareLegRules()...)
+	errs = append(errs, f.validateFareProducts()...)
+	errs = append(errs, f.validateTimeframes()...)
+
 	return errs
 }
 
+// ValidateAll is like Validate but joins the errors into one (see
+// errors.Join), or returns nil if the feed is valid. Each *ValidationError
+// remains reachable through errors.As and errors.Is.
+func (f *Feed) ValidateAll() error {
+	return errors.Join(f.Validate()...)
+}
+
 // validateAgency checks agency required fields
 func (f *Feed) validateAgency(agency *Agency) []error {
 	var errs []error
@@ -99,6 +199,7 @@ func (f *Feed) validateAgency(agency *Agency) []error {
 			EntityType: "agency",
 			EntityID:   string(agency.ID),
 			Field:      "agency_name",
+			Code:       ValidationMissingRequiredField,
 			Message:    "agency_name is required",
 		})
 	}
@@ -108,6 +209,7 @@ func (f *Feed) validateAgency(agency *Agency) []error {
 			EntityType: "agency",
 			EntityID:   string(agency.ID),
 			Field:      "agency_url",
+			Code:       ValidationMissingRequiredField,
 			Message:    "agency_url is required",
 		})
 	}
@@ -117,6 +219,7 @@ func (f *Feed) validateAgency(agency *Agency) []error {
 			EntityType: "agency",
 			EntityID:   string(agency.ID),
 			Field:      "agency_timezone",
+			Code:       ValidationMissingRequiredField,
 			Message:    "agency_timezone is required",
 		})
 	}
@@ -133,6 +236,7 @@ func (f *Feed) validateStop(stop *Stop) []error {
 			EntityType: "stop",
 			EntityID:   string(stop.ID),
 			Field:      "stop_id",
+			Code:       ValidationMissingRequiredField,
 			Message:    "stop_id is required",
 		})
 	}
@@ -143,6 +247,7 @@ func (f *Feed) validateStop(stop *Stop) []error {
 			EntityType: "stop",
 			EntityID:   string(stop.ID),
 			Field:      "stop_name",
+			Code:       ValidationMissingRequiredField,
 			Message:    "stop_name is required for location_type 0, 1, or 2",
 		})
 	}
@@ -154,12 +259,77 @@ func (f *Feed) validateStop(stop *Stop) []error {
 				EntityType: "stop",
 				EntityID:   string(stop.ID),
 				Field:      "parent_station",
+				Code:       ValidationBrokenReference,
 				Message:    fmt.Sprintf("stop references non-existent parent_station '%s'", stop.ParentStation),
 			})
 		}
 	}
 
-	return errs
+	return append(errs, f.validateStopHierarchy(stop)...)
+}
+
+// locationTypeNames names each location_type
+var locationTypeNames = []string{"stop or platform", "station", "entrance/exit", "generic node", "boarding area"}
+
+// describeLocationType describes t, a valid location_type, as e.g.
+// "station (location_type 1)"
+func describeLocationType(t int) string {
+	return fmt.Sprintf("%s (location_type %d)", locationTypeNames[t], t)
+}
+
+// validateStopHierarchy checks a stop's location_type and its place in the
+// station hierarchy: stations have no parent; entrances, generic nodes
+// and boarding areas require one; boarding areas belong to a platform,
+// and every other child to a station
+func (f *Feed) validateStopHierarchy(stop *Stop) []error {
+	if stop.LocationType < 0 || stop.LocationType >= len(locationTypeNames) {
+		return []error{&ValidationError{
+			EntityType: "stop",
+			EntityID:   string(stop.ID),
+			Field:      "location_type",
+			Code:       ValidationInvalidValue,
+			Message:    fmt.Sprintf("location_type %d is not between 0 and 4", stop.LocationType),
+		}}
+	}
+
+	switch {
+	case stop.LocationType == 1 && stop.ParentStation != "":
+		return []error{&ValidationError{
+			EntityType: "stop",
+			EntityID:   string(stop.ID),
+			Field:      "parent_station",
+			Code:       ValidationInvalidValue,
+			Message:    describeLocationType(1) + " cannot have a parent_station",
+		}}
+	case stop.LocationType >= 2 && stop.ParentStation == "":
+		return []error{&ValidationError{
+			EntityType: "stop",
+			EntityID:   string(stop.ID),
+			Field:      "parent_station",
+			Code:       ValidationMissingRequiredField,
+			Message:    "parent_station is required for " + describeLocationType(stop.LocationType),
+		}}
+	}
+
+	parent, ok := f.Stops[stop.ParentStation]
+	if !ok || stop.LocationType == 1 {
+		return nil
+	}
+	want, wantName := 1, describeLocationType(1)
+	if stop.LocationType == 4 {
+		want, wantName = 0, "platform (location_type 0)"
+	}
+	if parent.LocationType != want {
+		return []error{&ValidationError{
+			EntityType: "stop",
+			EntityID:   string(stop.ID),
+			Field:      "parent_station",
+			Code:       ValidationInvalidLocationType,
+			Message: fmt.Sprintf("parent_station of %s must be a %s, but '%s' has location_type %d",
+				describeLocationType(stop.LocationType), wantName, stop.ParentStation, parent.LocationType),
+		}}
+	}
+	return nil
 }
 
 // validateRoute checks route required fields and agency reference
@@ -171,6 +341,7 @@ func (f *Feed) validateRoute(route *Route) []error {
 			EntityType: "route",
 			EntityID:   string(route.ID),
 			Field:      "route_id",
+			Code:       ValidationMissingRequiredField,
 			Message:    "route_id is required",
 		})
 	}
@@ -181,6 +352,7 @@ func (f *Feed) validateRoute(route *Route) []error {
 			EntityType: "route",
 			EntityID:   string(route.ID),
 			Field:      "route_short_name/route_long_name",
+			Code:       ValidationMissingRequiredField,
 			Message:    "either route_short_name or route_long_name is required",
 		})
 	}
@@ -192,6 +364,7 @@ func (f *Feed) validateRoute(route *Route) []error {
 				EntityType: "route",
 				EntityID:   string(route.ID),
 				Field:      "agency_id",
+				Code:       ValidationBrokenReference,
 				Message:    fmt.Sprintf("route references non-existent agency '%s'", route.AgencyID),
 			})
 		}
@@ -201,10 +374,40 @@ func (f *Feed) validateRoute(route *Route) []error {
 			EntityType: "route",
 			EntityID:   string(route.ID),
 			Field:      "agency_id",
+			Code:       ValidationMissingRequiredField,
 			Message:    "agency_id is required when multiple agencies exist",
 		})
 	}
 
+	// route_color and route_text_color are six hex digits, without '#'
+	for _, c := range []struct{ field, value string }{
+		{"route_color", route.Color},
+		{"route_text_color", route.TextColor},
+	} {
+		if c.value != "" && !IsValidColor(c.value) {
+			errs = append(errs, &ValidationError{
+				EntityType: "route",
+				EntityID:   string(route.ID),
+				Field:      c.field,
+				Code:       ValidationInvalidValue,
+				Message:    fmt.Sprintf("%s '%s' must be six hexadecimal digits without '#'", c.field, c.value),
+			})
+		}
+	}
+
+	// network_id names a networks.txt row when the feed has any
+	if route.NetworkID != "" && len(f.Networks) > 0 {
+		if _, exists := f.Networks[route.NetworkID]; !exists {
+			errs = append(errs, &ValidationError{
+				EntityType: "route",
+				EntityID:   string(route.ID),
+				Field:      "network_id",
+				Code:       ValidationBrokenReference,
+				Message:    fmt.Sprintf("route references non-existent network '%s'", route.NetworkID),
+			})
+		}
+	}
+
 	return errs
 }
 
@@ -217,6 +420,7 @@ func (f *Feed) validateCalendar(calendar *Calendar) []error {
 			EntityType: "calendar",
 			EntityID:   string(calendar.ServiceID),
 			Field:      "service_id",
+			Code:       ValidationMissingRequiredField,
 			Message:    "service_id is required",
 		})
 	}
@@ -226,6 +430,7 @@ func (f *Feed) validateCalendar(calendar *Calendar) []error {
 			EntityType: "calendar",
 			EntityID:   string(calendar.ServiceID),
 			Field:      "start_date",
+			Code:       ValidationMissingRequiredField,
 			Message:    "start_date is required",
 		})
 	}
@@ -235,6 +440,7 @@ func (f *Feed) validateCalendar(calendar *Calendar) []error {
 			EntityType: "calendar",
 			EntityID:   string(calendar.ServiceID),
 			Field:      "end_date",
+			Code:       ValidationMissingRequiredField,
 			Message:    "end_date is required",
 		})
 	}
@@ -242,6 +448,36 @@ func (f *Feed) validateCalendar(calendar *Calendar) []error {
 	return errs
 }
 
+// calendarDateKey identifies a calendar_dates row; GTFS allows one per key
+type calendarDateKey struct {
+	serviceID ServiceID
+	date      string
+}
+
+// validateCalendarDates checks that no service has two rows for one date
+func (f *Feed) validateCalendarDates() []error {
+	var errs []error
+
+	for _, serviceID := range f.CalendarDateOrder {
+		seen := make(map[calendarDateKey]bool)
+		for _, calDate := range f.CalendarDates[serviceID] {
+			key := calendarDateKey{calDate.ServiceID, calDate.Date}
+			if seen[key] {
+				errs = append(errs, &ValidationError{
+					EntityType: "calendar_date",
+					EntityID:   string(serviceID),
+					Field:      "date",
+					Code:       ValidationDuplicateKey,
+					Message:    fmt.Sprintf("duplicate calendar_date for date %s", calDate.Date),
+				})
+			}
+			seen[key] = true
+		}
+	}
+
+	return errs
+}
+
 // validateTrip checks trip required fields and references
 func (f *Feed) validateTrip(trip *Trip) []error {
 	var errs []error
@@ -251,6 +487,7 @@ func (f *Feed) validateTrip(trip *Trip) []error {
 			EntityType: "trip",
 			EntityID:   string(trip.ID),
 			Field:      "trip_id",
+			Code:       ValidationMissingRequiredField,
 			Message:    "trip_id is required",
 		})
 	}
@@ -260,6 +497,7 @@ func (f *Feed) validateTrip(trip *Trip) []error {
 			EntityType: "trip",
 			EntityID:   string(trip.ID),
 			Field:      "route_id",
+			Code:       ValidationMissingRequiredField,
 			Message:    "route_id is required",
 		})
 	} else if _, exists := f.Routes[trip.RouteID]; !exists {
@@ -267,6 +505,7 @@ func (f *Feed) validateTrip(trip *Trip) []error {
 			EntityType: "trip",
 			EntityID:   string(trip.ID),
 			Field:      "route_id",
+			Code:       ValidationBrokenReference,
 			Message:    fmt.Sprintf("trip references non-existent route '%s'", trip.RouteID),
 		})
 	}
@@ -276,6 +515,7 @@ func (f *Feed) validateTrip(trip *Trip) []error {
 			EntityType: "trip",
 			EntityID:   string(trip.ID),
 			Field:      "service_id",
+			Code:       ValidationMissingRequiredField,
 			Message:    "service_id is required",
 		})
 	} else {
@@ -287,6 +527,7 @@ func (f *Feed) validateTrip(trip *Trip) []error {
 				EntityType: "trip",
 				EntityID:   string(trip.ID),
 				Field:      "service_id",
+				Code:       ValidationBrokenReference,
 				Message:    fmt.Sprintf("trip references non-existent service '%s'", trip.ServiceID),
 			})
 		}
@@ -299,6 +540,7 @@ func (f *Feed) validateTrip(trip *Trip) []error {
 				EntityType: "trip",
 				EntityID:   string(trip.ID),
 				Field:      "shape_id",
+				Code:       ValidationBrokenReference,
 				Message:    fmt.Sprintf("trip references non-existent shape '%s'", trip.ShapeID),
 			})
 		}
@@ -315,6 +557,7 @@ func (f *Feed) validateStopTime(stopTime *StopTime) []error {
 		errs = append(errs, &ValidationError{
 			EntityType: "stop_time",
 			Field:      "trip_id",
+			Code:       ValidationMissingRequiredField,
 			Message:    "trip_id is required",
 		})
 	} else if _, exists := f.Trips[stopTime.TripID]; !exists {
@@ -322,6 +565,7 @@ func (f *Feed) validateStopTime(stopTime *StopTime) []error {
 			EntityType: "stop_time",
 			EntityID:   fmt.Sprintf("trip %s seq %d", stopTime.TripID, stopTime.StopSequence),
 			Field:      "trip_id",
+			Code:       ValidationBrokenReference,
 			Message:    fmt.Sprintf("stop_time references non-existent trip '%s'", stopTime.TripID),
 		})
 	}
@@ -331,21 +575,34 @@ func (f *Feed) validateStopTime(stopTime *StopTime) []error {
 			EntityType: "stop_time",
 			EntityID:   fmt.Sprintf("trip %s seq %d", stopTime.TripID, stopTime.StopSequence),
 			Field:      "stop_id",
+			Code:       ValidationMissingRequiredField,
 			Message:    "stop_id is required",
 		})
-	} else if _, exists := f.Stops[stopTime.StopID]; !exists {
+	} else if stop, exists := f.Stops[stopTime.StopID]; !exists {
 		errs = append(errs, &ValidationError{
 			EntityType: "stop_time",
 			EntityID:   fmt.Sprintf("trip %s seq %d", stopTime.TripID, stopTime.StopSequence),
 			Field:      "stop_id",
+			Code:       ValidationBrokenReference,
 			Message:    fmt.Sprintf("stop_time references non-existent stop '%s'", stopTime.StopID),
 		})
+	} else if stop.LocationType != 0 {
+		// Only stops and platforms (location_type 0) can be served by a trip
+		errs = append(errs, &ValidationError{
+			EntityType: "stop_time",
+			EntityID:   fmt.Sprintf("trip %s seq %d", stopTime.TripID, stopTime.StopSequence),
+			Field:      "stop_id",
+			Code:       ValidationInvalidLocationType,
+			Message:    fmt.Sprintf("stop_time references stop '%s' with location_type %d", stopTime.StopID, stop.LocationType),
+		})
 	}
 
 	return errs
 }
 
-// validateTransfer checks transfer stop references
+// validateTransfer checks transfer stop references, that a transfer from a
+// stop to itself is an in-seat transfer, and that min_transfer_time is only
+// given for transfer types that allow it
 func (f *Feed) validateTransfer(transfer *Transfer) []error {
 	var errs []error
 
@@ -353,6 +610,7 @@ func (f *Feed) validateTransfer(transfer *Transfer) []error {
 		errs = append(errs, &ValidationError{
 			EntityType: "transfer",
 			Field:      "from_stop_id",
+			Code:       ValidationBrokenReference,
 			Message:    fmt.Sprintf("transfer references non-existent from_stop_id '%s'", transfer.FromStopID),
 		})
 	}
@@ -361,10 +619,71 @@ func (f *Feed) validateTransfer(transfer *Transfer) []error {
 		errs = append(errs, &ValidationError{
 			EntityType: "transfer",
 			Field:      "to_stop_id",
+			Code:       ValidationBrokenReference,
 			Message:    fmt.Sprintf("transfer references non-existent to_stop_id '%s'", transfer.ToStopID),
 		})
 	}
 
+	// An in-seat transfer (types 4 and 5) stays at one stop by definition
+	if transfer.FromStopID != "" && transfer.FromStopID == transfer.ToStopID &&
+		transfer.TransferType != 4 && transfer.TransferType != 5 {
+		errs = append(errs, &ValidationError{
+			EntityType: "transfer",
+			Field:      "to_stop_id",
+			Code:       ValidationInvalidValue,
+			Message:    fmt.Sprintf("transfer from stop '%s' to itself with transfer_type %d", transfer.FromStopID, transfer.TransferType),
+		})
+	}
+
+	// Transfers that are impossible (3) or in-seat (4 and 5) take no time
+	if transfer.MinTransferTime != nil && transfer.TransferType >= 3 {
+		errs = append(errs, &ValidationError{
+			EntityType: "transfer",
+			Field:      "min_transfer_time",
+			Code:       ValidationInvalidValue,
+			Message:    fmt.Sprintf("transfer from stop '%s' to stop '%s' has min_transfer_time with transfer_type %d", transfer.FromStopID, transfer.ToStopID, transfer.TransferType),
+		})
+	}
+
+	return errs
+}
+
+// transferKey identifies a transfer by every one of its fields
+type transferKey struct {
+	from, to           StopID
+	fromRoute, toRoute RouteID
+	fromTrip, toTrip   TripID
+	transferType       int
+	minTransferTime    int
+	hasMinTransferTime bool
+}
+
+// validateTransferDuplicates checks that no transfer repeats another
+// exactly
+func (f *Feed) validateTransferDuplicates() []error {
+	var errs []error
+
+	seen := make(map[transferKey]bool, len(f.Transfers))
+	for _, t := range f.Transfers {
+		key := transferKey{
+			from: t.FromStopID, to: t.ToStopID,
+			fromRoute: t.FromRouteID, toRoute: t.ToRouteID,
+			fromTrip: t.FromTripID, toTrip: t.ToTripID,
+			transferType: t.TransferType,
+		}
+		if t.MinTransferTime != nil {
+			key.minTransferTime, key.hasMinTransferTime = *t.MinTransferTime, true
+		}
+		if seen[key] {
+			errs = append(errs, &ValidationError{
+				EntityType: "transfer",
+				Code:       ValidationDuplicateKey,
+				Message:    fmt.Sprintf("duplicate transfer from stop '%s' to stop '%s'", t.FromStopID, t.ToStopID),
+			})
+		}
+		seen[key] = true
+	}
+
 	return errs
 }
 
@@ -376,6 +695,7 @@ func (f *Feed) validateFrequency(frequency *Frequency) []error {
 		errs = append(errs, &ValidationError{
 			EntityType: "frequency",
 			Field:      "trip_id",
+			Code:       ValidationBrokenReference,
 			Message:    fmt.Sprintf("frequency references non-existent trip '%s'", frequency.TripID),
 		})
 	}
@@ -394,6 +714,7 @@ func (f *Feed) validateFareAttribute(fareAttr *FareAttribute) []error {
 				EntityType: "fare_attribute",
 				EntityID:   string(fareAttr.FareID),
 				Field:      "agency_id",
+				Code:       ValidationBrokenReference,
 				Message:    fmt.Sprintf("fare_attribute references non-existent agency '%s'", fareAttr.AgencyID),
 			})
 		}
@@ -411,6 +732,7 @@ func (f *Feed) validateFareRule(fareRule *FareRule) []error {
 		errs = append(errs, &ValidationError{
 			EntityType: "fare_rule",
 			Field:      "fare_id",
+			Code:       ValidationBrokenReference,
 			Message:    fmt.Sprintf("fare_rule references non-existent fare_id '%s'", fareRule.FareID),
 		})
 	}
@@ -421,6 +743,7 @@ func (f *Feed) validateFareRule(fareRule *FareRule) []error {
 			errs = append(errs, &ValidationError{
 				EntityType: "fare_rule",
 				Field:      "route_id",
+				Code:       ValidationBrokenReference,
 				Message:    fmt.Sprintf("fare_rule references non-existent route_id '%s'", fareRule.RouteID),
 			})
 		}
@@ -433,22 +756,216 @@ func (f *Feed) validateFareRule(fareRule *FareRule) []error {
 func (f *Feed) validatePathway(pathway *Pathway) []error {
 	var errs []error
 
-	if _, exists := f.Stops[pathway.FromStopID]; !exists {
+	if from, exists := f.Stops[pathway.FromStopID]; !exists {
 		errs = append(errs, &ValidationError{
 			EntityType: "pathway",
 			EntityID:   pathway.ID,
 			Field:      "from_stop_id",
+			Code:       ValidationBrokenReference,
 			Message:    fmt.Sprintf("pathway references non-existent from_stop_id '%s'", pathway.FromStopID),
 		})
+	} else if from.LocationType == 1 {
+		// Pathways connect locations within a station, never the station itself
+		errs = append(errs, &ValidationError{
+			EntityType: "pathway",
+			EntityID:   pathway.ID,
+			Field:      "from_stop_id",
+			Code:       ValidationInvalidLocationType,
+			Message:    fmt.Sprintf("pathway from_stop_id references station '%s'", pathway.FromStopID),
+		})
 	}
 
-	if _, exists := f.Stops[pathway.ToStopID]; !exists {
+	if to, exists := f.Stops[pathway.ToStopID]; !exists {
 		errs = append(errs, &ValidationError{
 			EntityType: "pathway",
 			EntityID:   pathway.ID,
 			Field:      "to_stop_id",
+			Code:       ValidationBrokenReference,
 			Message:    fmt.Sprintf("pathway references non-existent to_stop_id '%s'", pathway.ToStopID),
 		})
+	} else if to.LocationType == 1 {
+		errs = append(errs, &ValidationError{
+			EntityType: "pathway",
+			EntityID:   pathway.ID,
+			Field:      "to_stop_id",
+			Code:       ValidationInvalidLocationType,
+			Message:    fmt.Sprintf("pathway to_stop_id references station '%s'", pathway.ToStopID),
+		})
+	}
+
+	return errs
+}
+
+// validateRouteNetworks checks route_network references and that no route
+// is in more than one network
+func (f *Feed) validateRouteNetworks() []error {
+	var errs []error
+
+	seen := make(map[RouteID]bool)
+	for _, rn := range f.RouteNetworks {
+		if _, exists := f.Networks[rn.NetworkID]; !exists {
+			errs = append(errs, &ValidationError{
+				EntityType: "route_network",
+				EntityID:   string(rn.RouteID),
+				Field:      "network_id",
+				Code:       ValidationBrokenReference,
+				Message:    fmt.Sprintf("route_network references non-existent network '%s'", rn.NetworkID),
+			})
+		}
+
+		if _, exists := f.Routes[rn.RouteID]; !exists {
+			errs = append(errs, &ValidationError{
+				EntityType: "route_network",
+				EntityID:   string(rn.RouteID),
+				Field:      "route_id",
+				Code:       ValidationBrokenReference,
+				Message:    fmt.Sprintf("route_network references non-existent route '%s'", rn.RouteID),
+			})
+		}
+
+		if seen[rn.RouteID] {
+			errs = append(errs, &ValidationError{
+				EntityType: "route_network",
+				EntityID:   string(rn.RouteID),
+				Field:      "route_id",
+				Code:       ValidationDuplicateKey,
+				Message:    fmt.Sprintf("route '%s' is in more than one network", rn.RouteID),
+			})
+		}
+		seen[rn.RouteID] = true
+	}
+
+	return errs
+}
+
+// validateFareTransferRules checks that the leg groups fare_transfer_rules
+// refer to are defined by fare_leg_rules, and their fare products by
+// fare_products. An empty leg group matches any leg, so is not a reference.
+func (f *Feed) validateFareTransferRules() []error {
+	var errs []error
+
+	groups := f.LegGroupIDs()
+	products := f.FareProductIDs()
+	for _, rule := range f.FareTransferRules {
+		for _, ref := range []struct {
+			field string
+			id    LegGroupID
+		}{
+			{"from_leg_group_id", rule.FromLegGroupID},
+			{"to_leg_group_id", rule.ToLegGroupID},
+		} {
+			if ref.id == "" || groups[ref.id] {
+				continue
+			}
+			errs = append(errs, &ValidationError{
+				EntityType: "fare_transfer_rule",
+				EntityID:   fmt.Sprintf("%s -> %s", rule.FromLegGroupID, rule.ToLegGroupID),
+				Field:      ref.field,
+				Code:       ValidationBrokenReference,
+				Message:    fmt.Sprintf("fare_transfer_rule references non-existent leg group '%s'", ref.id),
+			})
+		}
+		if rule.FareProductID != "" && !products[rule.FareProductID] {
+			errs = append(errs, &ValidationError{
+				EntityType: "fare_transfer_rule",
+				EntityID:   fmt.Sprintf("%s -> %s", rule.FromLegGroupID, rule.ToLegGroupID),
+				Field:      "fare_product_id",
+				Code:       ValidationBrokenReference,
+				Message:    fmt.Sprintf("fare_transfer_rule references non-existent fare product '%s'", rule.FareProductID),
+			})
+		}
+	}
+
+	return errs
+}
+
+// validateFareLegRules checks that the fare products and timeframe groups
+// fare_leg_rules refer to are defined by fare_products and timeframes
+func (f *Feed) validateFareLegRules() []error {
+	var errs []error
+
+	products := f.FareProductIDs()
+	timeframes := f.TimeframeGroupIDs()
+	for _, rule := range f.FareLegRules {
+		if rule.FareProductID != "" && !products[rule.FareProductID] {
+			errs = append(errs, &ValidationError{
+				EntityType: "fare_leg_rule",
+				EntityID:   string(rule.LegGroupID),
+				Field:      "fare_product_id",
+				Code:       ValidationBrokenReference,
+				Message:    fmt.Sprintf("fare_leg_rule references non-existent fare product '%s'", rule.FareProductID),
+			})
+		}
+		for _, ref := range []struct {
+			field string
+			id    TimeframeGroupID
+		}{
+			{"from_timeframe_group_id", rule.FromTimeframeGroupID},
+			{"to_timeframe_group_id", rule.ToTimeframeGroupID},
+		} {
+			if ref.id == "" || timeframes[ref.id] {
+				continue
+			}
+			errs = append(errs, &ValidationError{
+				EntityType: "fare_leg_rule",
+				EntityID:   string(rule.LegGroupID),
+				Field:      ref.field,
+				Code:       ValidationBrokenReference,
+				Message:    fmt.Sprintf("fare_leg_rule references non-existent timeframe group '%s'", ref.id),
+			})
+		}
+	}
+
+	return errs
+}
+
+// validateFareProducts checks the rider category and fare media references
+// of fare_products
+func (f *Feed) validateFareProducts() []error {
+	var errs []error
+
+	for _, product := range f.FareProducts {
+		if product.RiderCategoryID != "" && f.RiderCategories[product.RiderCategoryID] == nil {
+			errs = append(errs, &ValidationError{
+				EntityType: "fare_product",
+				EntityID:   string(product.ID),
+				Field:      "rider_category_id",
+				Code:       ValidationBrokenReference,
+				Message:    fmt.Sprintf("fare_product references non-existent rider category '%s'", product.RiderCategoryID),
+			})
+		}
+		if product.FareMediaID != "" && f.FareMedia[product.FareMediaID] == nil {
+			errs = append(errs, &ValidationError{
+				EntityType: "fare_product",
+				EntityID:   string(product.ID),
+				Field:      "fare_media_id",
+				Code:       ValidationBrokenReference,
+				Message:    fmt.Sprintf("fare_product references non-existent fare media '%s'", product.FareMediaID),
+			})
+		}
+	}
+
+	return errs
+}
+
+// validateTimeframes checks that each timeframe's service is defined by
+// calendar.txt or calendar_dates.txt
+func (f *Feed) validateTimeframes() []error {
+	var errs []error
+
+	for _, tf := range f.Timeframes {
+		_, inCalendar := f.Calendars[tf.ServiceID]
+		_, inCalendarDates := f.CalendarDates[tf.ServiceID]
+		if inCalendar || inCalendarDates {
+			continue
+		}
+		errs = append(errs, &ValidationError{
+			EntityType: "timeframe",
+			EntityID:   string(tf.GroupID),
+			Field:      "service_id",
+			Code:       ValidationBrokenReference,
+			Message:    fmt.Sprintf("timeframe references non-existent service '%s'", tf.ServiceID),
+		})
 	}
 
 	return errs
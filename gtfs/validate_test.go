@@ -1,6 +1,8 @@
 package gtfs
 
 import (
+	"errors"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -271,6 +273,69 @@ func TestValidateStopParentRef(t *testing.T) {
 	}
 }
 
+func TestValidateStopHierarchy(t *testing.T) {
+	// Given: a station with one stop of every other location_type
+	valid := func() *Feed {
+		feed := NewFeed()
+		feed.Agencies["agency1"] = &Agency{ID: "agency1", Name: "Test Agency", URL: "http://test.com", Timezone: "America/New_York"}
+		feed.Stops["station"] = &Stop{ID: "station", Name: "Station", LocationType: 1}
+		feed.Stops["platform"] = &Stop{ID: "platform", Name: "Platform", ParentStation: "station"}
+		feed.Stops["entrance"] = &Stop{ID: "entrance", Name: "Entrance", LocationType: 2, ParentStation: "station"}
+		feed.Stops["node"] = &Stop{ID: "node", LocationType: 3, ParentStation: "station"}
+		feed.Stops["boarding"] = &Stop{ID: "boarding", LocationType: 4, ParentStation: "platform"}
+		return feed
+	}
+	if errs := valid().Validate(); len(errs) > 0 {
+		t.Errorf("Expected no errors for a valid hierarchy, got %v", errs)
+	}
+
+	tests := []struct {
+		name   string
+		change func(*Feed)
+		field  string
+		code   ValidationCode
+		msg    string
+	}{
+		{"station with parent", func(f *Feed) { f.Stops["station"].ParentStation = "platform" }, "parent_station", ValidationInvalidValue,
+			"station (location_type 1) cannot have a parent_station"},
+		{"entrance without parent", func(f *Feed) { f.Stops["entrance"].ParentStation = "" }, "parent_station", ValidationMissingRequiredField,
+			"parent_station is required for entrance/exit (location_type 2)"},
+		{"boarding area without parent", func(f *Feed) { f.Stops["boarding"].ParentStation = "" }, "parent_station", ValidationMissingRequiredField,
+			"parent_station is required for boarding area (location_type 4)"},
+		{"boarding area on station", func(f *Feed) { f.Stops["boarding"].ParentStation = "station" }, "parent_station", ValidationInvalidLocationType,
+			"parent_station of boarding area (location_type 4) must be a platform (location_type 0), but 'station' has location_type 1"},
+		{"node on platform", func(f *Feed) { f.Stops["node"].ParentStation = "platform" }, "parent_station", ValidationInvalidLocationType,
+			"parent_station of generic node (location_type 3) must be a station (location_type 1), but 'platform' has location_type 0"},
+		{"unknown location type", func(f *Feed) { f.Stops["node"].LocationType = 7 }, "location_type", ValidationInvalidValue,
+			"location_type 7 is not between 0 and 4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := valid()
+			tt.change(feed)
+			errs := feed.Validate()
+			if len(errs) != 1 {
+				t.Fatalf("Expected one error, got %v", errs)
+			}
+			var ve *ValidationError
+			if !errors.As(errs[0], &ve) || ve.Field != tt.field || ve.Code != tt.code || ve.Message != tt.msg {
+				t.Errorf("Expected %s %v %q, got %#v", tt.field, tt.code, tt.msg, errs[0])
+			}
+		})
+	}
+
+	// And: the merge fixtures exercising the hierarchy are valid
+	for _, dir := range []string{"station_hierarchy_a", "station_hierarchy_b"} {
+		feed, err := ReadFromPath("../testdata/" + dir)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", dir, err)
+		}
+		if errs := feed.Validate(); len(errs) > 0 {
+			t.Errorf("%s: expected no errors, got %v", dir, errs)
+		}
+	}
+}
+
 func TestValidateTransferStopRefs(t *testing.T) {
 	// Transfer with valid stop references
 	feed := NewFeed()
@@ -307,12 +372,71 @@ func TestValidateTransferStopRefs(t *testing.T) {
 	}
 }
 
+func TestValidateTransferRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		transfer *Transfer
+		want     error
+	}{
+		{"transfer within a stop", &Transfer{FromStopID: "stop1", ToStopID: "stop1", TransferType: 2, MinTransferTime: intPtr(60)}, ErrInvalidValue},
+		{"in-seat transfer", &Transfer{FromStopID: "stop1", ToStopID: "stop1", TransferType: 4, FromTripID: "trip1", ToTripID: "trip2"}, nil},
+		{"min_transfer_time on an impossible transfer", &Transfer{FromStopID: "stop1", ToStopID: "stop2", TransferType: 3, MinTransferTime: intPtr(60)}, ErrInvalidValue},
+		{"min_transfer_time on a minimum time transfer", &Transfer{FromStopID: "stop1", ToStopID: "stop2", TransferType: 2, MinTransferTime: intPtr(60)}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: a feed with the transfer
+			feed := NewFeed()
+			feed.Agencies["agency1"] = &Agency{ID: "agency1", Name: "Test Agency", URL: "http://test.com", Timezone: "America/New_York"}
+			feed.Stops["stop1"] = &Stop{ID: "stop1", Name: "Stop 1", Lat: 40.0, Lon: -74.0}
+			feed.Stops["stop2"] = &Stop{ID: "stop2", Name: "Stop 2", Lat: 40.1, Lon: -74.1}
+			feed.Transfers = append(feed.Transfers, tt.transfer)
+
+			// When: validating
+			errs := feed.Validate()
+
+			// Then: the transfer is reported only if invalid
+			if tt.want == nil && len(errs) > 0 {
+				t.Errorf("Expected no errors, got %v", errs)
+			}
+			if tt.want != nil && (len(errs) != 1 || !errors.Is(errs[0], tt.want)) {
+				t.Errorf("Expected one %v, got %v", tt.want, errs)
+			}
+		})
+	}
+}
+
+func TestValidateTransferDuplicates(t *testing.T) {
+	// Given: a transfer repeated exactly, and one differing only in its
+	// min_transfer_time
+	feed := NewFeed()
+	feed.Agencies["agency1"] = &Agency{ID: "agency1", Name: "Test Agency", URL: "http://test.com", Timezone: "America/New_York"}
+	feed.Stops["stop1"] = &Stop{ID: "stop1", Name: "Stop 1", Lat: 40.0, Lon: -74.0}
+	feed.Stops["stop2"] = &Stop{ID: "stop2", Name: "Stop 2", Lat: 40.1, Lon: -74.1}
+	feed.Transfers = append(feed.Transfers,
+		&Transfer{FromStopID: "stop1", ToStopID: "stop2", TransferType: 2, MinTransferTime: intPtr(60)},
+		&Transfer{FromStopID: "stop1", ToStopID: "stop2", TransferType: 2, MinTransferTime: intPtr(120)},
+		&Transfer{FromStopID: "stop1", ToStopID: "stop2", TransferType: 2, MinTransferTime: intPtr(60)},
+	)
+
+	// When: validating
+	errs := feed.Validate()
+
+	// Then: only the repeat is reported
+	if len(errs) != 1 || !errors.Is(errs[0], ErrDuplicateKey) {
+		t.Fatalf("Expected one ErrDuplicateKey, got %v", errs)
+	}
+	if !strings.Contains(errs[0].Error(), "from stop 'stop1' to stop 'stop2'") {
+		t.Errorf("Expected error to name the stops, got %v", errs[0])
+	}
+}
+
 func TestValidateFareRuleRefs(t *testing.T) {
 	// FareRule with valid references
 	feed := NewFeed()
 	feed.Agencies["agency1"] = &Agency{ID: "agency1", Name: "Test Agency", URL: "http://test.com", Timezone: "America/New_York"}
 	feed.Routes["route1"] = &Route{ID: "route1", AgencyID: "agency1", ShortName: "R1", Type: 3}
-	feed.FareAttributes["fare1"] = &FareAttribute{FareID: "fare1", Price: 2.50, CurrencyType: "USD", PaymentMethod: 0, Transfers: 0}
+	feed.FareAttributes["fare1"] = &FareAttribute{FareID: "fare1", Price: 2.50, CurrencyType: "USD", PaymentMethod: 0, Transfers: intPtr(0)}
 	feed.FareRules = append(feed.FareRules, &FareRule{FareID: "fare1", RouteID: "route1"})
 
 	errs := feed.Validate()
@@ -324,7 +448,7 @@ func TestValidateFareRuleRefs(t *testing.T) {
 	feed2 := NewFeed()
 	feed2.Agencies["agency1"] = &Agency{ID: "agency1", Name: "Test Agency", URL: "http://test.com", Timezone: "America/New_York"}
 	feed2.Routes["route1"] = &Route{ID: "route1", AgencyID: "agency1", ShortName: "R1", Type: 3}
-	feed2.FareAttributes["fare1"] = &FareAttribute{FareID: "fare1", Price: 2.50, CurrencyType: "USD", PaymentMethod: 0, Transfers: 0}
+	feed2.FareAttributes["fare1"] = &FareAttribute{FareID: "fare1", Price: 2.50, CurrencyType: "USD", PaymentMethod: 0, Transfers: intPtr(0)}
 	feed2.FareRules = append(feed2.FareRules, &FareRule{FareID: "nonexistent", RouteID: "route1"})
 
 	errs = feed2.Validate()
@@ -336,7 +460,7 @@ func TestValidateFareRuleRefs(t *testing.T) {
 	feed3 := NewFeed()
 	feed3.Agencies["agency1"] = &Agency{ID: "agency1", Name: "Test Agency", URL: "http://test.com", Timezone: "America/New_York"}
 	feed3.Routes["route1"] = &Route{ID: "route1", AgencyID: "agency1", ShortName: "R1", Type: 3}
-	feed3.FareAttributes["fare1"] = &FareAttribute{FareID: "fare1", Price: 2.50, CurrencyType: "USD", PaymentMethod: 0, Transfers: 0}
+	feed3.FareAttributes["fare1"] = &FareAttribute{FareID: "fare1", Price: 2.50, CurrencyType: "USD", PaymentMethod: 0, Transfers: intPtr(0)}
 	feed3.FareRules = append(feed3.FareRules, &FareRule{FareID: "fare1", RouteID: "nonexistent"})
 
 	errs = feed3.Validate()
@@ -347,7 +471,7 @@ func TestValidateFareRuleRefs(t *testing.T) {
 	// FareRule with empty route_id (valid - applies to all routes)
 	feed4 := NewFeed()
 	feed4.Agencies["agency1"] = &Agency{ID: "agency1", Name: "Test Agency", URL: "http://test.com", Timezone: "America/New_York"}
-	feed4.FareAttributes["fare1"] = &FareAttribute{FareID: "fare1", Price: 2.50, CurrencyType: "USD", PaymentMethod: 0, Transfers: 0}
+	feed4.FareAttributes["fare1"] = &FareAttribute{FareID: "fare1", Price: 2.50, CurrencyType: "USD", PaymentMethod: 0, Transfers: intPtr(0)}
 	feed4.FareRules = append(feed4.FareRules, &FareRule{FareID: "fare1", RouteID: ""})
 
 	errs = feed4.Validate()
@@ -362,7 +486,7 @@ func TestValidateShapeInTrip(t *testing.T) {
 	feed.Agencies["agency1"] = &Agency{ID: "agency1", Name: "Test Agency", URL: "http://test.com", Timezone: "America/New_York"}
 	feed.Routes["route1"] = &Route{ID: "route1", AgencyID: "agency1", ShortName: "R1", Type: 3}
 	feed.Calendars["service1"] = &Calendar{ServiceID: "service1", Monday: true, StartDate: "20240101", EndDate: "20241231"}
-	feed.Shapes["shape1"] = []*ShapePoint{{ShapeID: "shape1", Lat: 40.0, Lon: -74.0, Sequence: 1}}
+	feed.Shapes["shape1"] = []ShapePoint{{ShapeID: "shape1", Lat: 40.0, Lon: -74.0, Sequence: 1}}
 	feed.Trips["trip1"] = &Trip{ID: "trip1", RouteID: "route1", ServiceID: "service1", ShapeID: "shape1"}
 
 	errs := feed.Validate()
@@ -375,7 +499,7 @@ func TestValidateShapeInTrip(t *testing.T) {
 	feed2.Agencies["agency1"] = &Agency{ID: "agency1", Name: "Test Agency", URL: "http://test.com", Timezone: "America/New_York"}
 	feed2.Routes["route1"] = &Route{ID: "route1", AgencyID: "agency1", ShortName: "R1", Type: 3}
 	feed2.Calendars["service1"] = &Calendar{ServiceID: "service1", Monday: true, StartDate: "20240101", EndDate: "20241231"}
-	feed2.Shapes["shape1"] = []*ShapePoint{{ShapeID: "shape1", Lat: 40.0, Lon: -74.0, Sequence: 1}}
+	feed2.Shapes["shape1"] = []ShapePoint{{ShapeID: "shape1", Lat: 40.0, Lon: -74.0, Sequence: 1}}
 	feed2.Trips["trip1"] = &Trip{ID: "trip1", RouteID: "route1", ServiceID: "service1", ShapeID: "nonexistent"}
 
 	errs = feed2.Validate()
@@ -480,11 +604,208 @@ func TestValidatePathwayStopRefs(t *testing.T) {
 	}
 }
 
+func TestValidateLocationTypeRefs(t *testing.T) {
+	// Given: a feed whose southbound trip stops at two stations, and whose
+	// pathways start at a station and at an entrance
+	feed, err := ReadFromPath("../testdata/station_stop_times")
+	if err != nil {
+		t.Fatalf("failed to read feed: %v", err)
+	}
+
+	// When: validating
+	errs := feed.Validate()
+
+	// Then: each reference to a station is an invalid location_type
+	var got []string
+	for _, err := range errs {
+		var ve *ValidationError
+		if !errors.As(err, &ve) || ve.Code != ValidationInvalidLocationType {
+			t.Errorf("Expected only invalid location_type errors, got %v", err)
+			continue
+		}
+		got = append(got, ve.EntityType+" "+ve.EntityID+" "+ve.Field)
+	}
+	expected := map[string]bool{
+		"stop_time trip red_sb seq 1 stop_id":      true,
+		"stop_time trip red_sb seq 2 stop_id":      true,
+		"pathway station_to_platform from_stop_id": true,
+	}
+	if len(got) != len(expected) {
+		t.Errorf("Expected %d errors, got %v", len(expected), got)
+	}
+	for _, g := range got {
+		if !expected[g] {
+			t.Errorf("Unexpected error for %s", g)
+		}
+	}
+	if !errors.Is(feed.ValidateAll(), ErrInvalidLocationType) {
+		t.Errorf("Expected ErrInvalidLocationType from ValidateAll")
+	}
+}
+
+func TestValidateRouteNetworkRefs(t *testing.T) {
+	// Given: a feed whose route_networks are valid
+	feed, err := ReadFromPath("../testdata/networks")
+	if err != nil {
+		t.Fatalf("failed to read feed: %v", err)
+	}
+	if errs := feed.Validate(); len(errs) > 0 {
+		t.Fatalf("Expected no errors for valid route_networks, got: %v", errs)
+	}
+
+	// When: route_networks name a missing network and a missing route, and
+	// put one route in two networks
+	feed.RouteNetworks = append(feed.RouteNetworks,
+		&RouteNetwork{NetworkID: "gone", RouteID: "express"},
+		&RouteNetwork{NetworkID: "standard", RouteID: "gone"})
+	errs := feed.Validate()
+
+	// Then: each problem is reported against route_network
+	expected := map[string]ValidationCode{
+		"express network_id": ValidationBrokenReference,
+		"express route_id":   ValidationDuplicateKey,
+		"gone route_id":      ValidationBrokenReference,
+	}
+	if len(errs) != len(expected) {
+		t.Errorf("Expected %d errors, got %v", len(expected), errs)
+	}
+	for _, err := range errs {
+		var ve *ValidationError
+		if !errors.As(err, &ve) || ve.EntityType != "route_network" {
+			t.Errorf("Expected a route_network error, got %v", err)
+			continue
+		}
+		if code, ok := expected[ve.EntityID+" "+ve.Field]; !ok || code != ve.Code {
+			t.Errorf("Unexpected error %v (%s)", err, ve.Code)
+		}
+	}
+}
+
+func TestValidateFareTransferRuleLegGroups(t *testing.T) {
+	// Given: a feed whose fare transfer rules are valid
+	feed, err := ReadFromPath("../testdata/fares_v2")
+	if err != nil {
+		t.Fatalf("failed to read feed: %v", err)
+	}
+	if errs := feed.Validate(); len(errs) > 0 {
+		t.Fatalf("Expected no errors for valid fare_transfer_rules, got: %v", errs)
+	}
+
+	// When: a rule names a leg group no fare leg rule defines, and another
+	// leaves its leg groups empty
+	feed.FareTransferRules = append(feed.FareTransferRules,
+		&FareTransferRule{FromLegGroupID: "local_leg", ToLegGroupID: "gone"},
+		&FareTransferRule{})
+	errs := feed.Validate()
+
+	// Then: only the missing leg group is a broken reference
+	var ve *ValidationError
+	if len(errs) != 1 || !errors.As(errs[0], &ve) || ve.Field != "to_leg_group_id" || ve.Code != ValidationBrokenReference {
+		t.Errorf("Expected one to_leg_group_id broken reference, got %v", errs)
+	}
+}
+
+func TestValidateFaresV2References(t *testing.T) {
+	// Given: the valid Fares v2 fixture
+	feed, err := ReadFromPath("../testdata/fares_v2")
+	if err != nil {
+		t.Fatalf("failed to read feed: %v", err)
+	}
+
+	// When: each kind of reference names something the feed lacks
+	feed.FareLegRules[0].FareProductID = "gone_product"
+	feed.FareLegRules[1].FromTimeframeGroupID = "gone_timeframe"
+	feed.FareTransferRules[0].FareProductID = "gone_product"
+	feed.FareProducts[0].RiderCategoryID = "gone_category"
+	feed.FareProducts[1].FareMediaID = "gone_media"
+	feed.Timeframes = append(feed.Timeframes, &Timeframe{GroupID: "offpeak", ServiceID: "gone_service"})
+	errs := feed.Validate()
+
+	// Then: each is a broken reference
+	expected := map[string]bool{
+		"fare_leg_rule fare_product_id":         true,
+		"fare_leg_rule from_timeframe_group_id": true,
+		"fare_transfer_rule fare_product_id":    true,
+		"fare_product rider_category_id":        true,
+		"fare_product fare_media_id":            true,
+		"timeframe service_id":                  true,
+	}
+	if len(errs) != len(expected) {
+		t.Errorf("Expected %d errors, got %v", len(expected), errs)
+	}
+	for _, err := range errs {
+		var ve *ValidationError
+		if !errors.As(err, &ve) || ve.Code != ValidationBrokenReference || !expected[ve.EntityType+" "+ve.Field] {
+			t.Errorf("Unexpected error %v", err)
+		}
+	}
+}
+
+func TestValidateRouteNetworkID(t *testing.T) {
+	// Given: a route whose network_id is not in networks.txt
+	feed, err := ReadFromPath("../testdata/networks")
+	if err != nil {
+		t.Fatalf("failed to read feed: %v", err)
+	}
+	feed.RouteNetworks = nil
+	feed.Routes["local"].NetworkID = "gone"
+
+	// When: validating
+	err = feed.ValidateAll()
+
+	// Then: the route's network_id is a broken reference
+	var ve *ValidationError
+	if !errors.As(err, &ve) || ve.EntityType != "route" || ve.Field != "network_id" {
+		t.Errorf("Expected a route network_id error, got %v", err)
+	}
+
+	// And: without networks.txt, network_id is a free-standing group
+	feed.Networks = nil
+	if errs := feed.Validate(); len(errs) > 0 {
+		t.Errorf("Expected no errors without networks.txt, got %v", errs)
+	}
+}
+
+func TestValidateRouteColors(t *testing.T) {
+	tests := []struct {
+		name, color, textColor string
+		invalid                []string
+	}{
+		{"unset", "", "", nil},
+		{"uppercase", "FF0000", "FFFFFF", nil},
+		{"lowercase", "ff0000", "ffffff", nil},
+		{"leading #", "#FF0000", "FFFFFF", []string{"route_color"}},
+		{"too short and not hex", "F00", "WHITE!", []string{"route_color", "route_text_color"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: a route with the colors
+			feed := NewFeed()
+			feed.Agencies["agency1"] = &Agency{ID: "agency1", Name: "Test Agency", URL: "http://test.com", Timezone: "America/New_York"}
+			feed.Routes["route1"] = &Route{ID: "route1", ShortName: "R1", Type: 3, Color: tt.color, TextColor: tt.textColor}
+
+			// When: validating
+			var fields []string
+			for _, err := range feed.Validate() {
+				var ve *ValidationError
+				if errors.As(err, &ve) && errors.Is(err, ErrInvalidValue) {
+					fields = append(fields, ve.Field)
+				}
+			}
+
+			// Then: each malformed color is an invalid value
+			if !slices.Equal(fields, tt.invalid) {
+				t.Errorf("Expected invalid %v, got %v", tt.invalid, fields)
+			}
+		})
+	}
+}
+
 func TestValidateFareAttributeAgencyRef(t *testing.T) {
 	// FareAttribute with valid agency_id reference
 	feed := NewFeed()
 	feed.Agencies["agency1"] = &Agency{ID: "agency1", Name: "Test Agency", URL: "http://test.com", Timezone: "America/New_York"}
-	feed.FareAttributes["fare1"] = &FareAttribute{FareID: "fare1", Price: 2.50, CurrencyType: "USD", PaymentMethod: 0, Transfers: 0, AgencyID: "agency1"}
+	feed.FareAttributes["fare1"] = &FareAttribute{FareID: "fare1", Price: 2.50, CurrencyType: "USD", PaymentMethod: 0, Transfers: intPtr(0), AgencyID: "agency1"}
 
 	errs := feed.Validate()
 	if len(errs) > 0 {
@@ -494,7 +815,7 @@ func TestValidateFareAttributeAgencyRef(t *testing.T) {
 	// FareAttribute with invalid agency_id reference
 	feed2 := NewFeed()
 	feed2.Agencies["agency1"] = &Agency{ID: "agency1", Name: "Test Agency", URL: "http://test.com", Timezone: "America/New_York"}
-	feed2.FareAttributes["fare1"] = &FareAttribute{FareID: "fare1", Price: 2.50, CurrencyType: "USD", PaymentMethod: 0, Transfers: 0, AgencyID: "nonexistent"}
+	feed2.FareAttributes["fare1"] = &FareAttribute{FareID: "fare1", Price: 2.50, CurrencyType: "USD", PaymentMethod: 0, Transfers: intPtr(0), AgencyID: "nonexistent"}
 
 	errs = feed2.Validate()
 	if len(errs) == 0 {
@@ -504,7 +825,7 @@ func TestValidateFareAttributeAgencyRef(t *testing.T) {
 	// FareAttribute with empty agency_id (valid)
 	feed3 := NewFeed()
 	feed3.Agencies["agency1"] = &Agency{ID: "agency1", Name: "Test Agency", URL: "http://test.com", Timezone: "America/New_York"}
-	feed3.FareAttributes["fare1"] = &FareAttribute{FareID: "fare1", Price: 2.50, CurrencyType: "USD", PaymentMethod: 0, Transfers: 0, AgencyID: ""}
+	feed3.FareAttributes["fare1"] = &FareAttribute{FareID: "fare1", Price: 2.50, CurrencyType: "USD", PaymentMethod: 0, Transfers: intPtr(0), AgencyID: ""}
 
 	errs = feed3.Validate()
 	if len(errs) > 0 {
@@ -764,6 +1085,66 @@ func TestValidateCalendarRequired(t *testing.T) {
 	}
 }
 
+func TestValidateCalendarDatesDuplicate(t *testing.T) {
+	// Given: service1 is both added and removed on 20240704
+	feed := NewFeed()
+	feed.Agencies["agency1"] = &Agency{ID: "agency1", Name: "Test Agency", URL: "http://test.com", Timezone: "America/New_York"}
+	feed.AddCalendarDate(&CalendarDate{ServiceID: "service1", Date: "20240704", ExceptionType: 1})
+	feed.AddCalendarDate(&CalendarDate{ServiceID: "service1", Date: "20240705", ExceptionType: 2})
+	feed.AddCalendarDate(&CalendarDate{ServiceID: "service1", Date: "20240704", ExceptionType: 2})
+
+	// When: validating
+	errs := feed.Validate()
+
+	// Then: only the repeated date is reported
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %d: %v", len(errs), errs)
+	}
+	if !strings.Contains(errs[0].Error(), "20240704") {
+		t.Errorf("Expected error to name the date, got %v", errs[0])
+	}
+	if !errors.Is(errs[0], ErrDuplicateKey) {
+		t.Errorf("Expected ErrDuplicateKey, got %v", errs[0])
+	}
+}
+
+func TestValidationErrorCodes(t *testing.T) {
+	// Given: a route without a name whose trip names a missing service
+	feed := NewFeed()
+	feed.Agencies["agency1"] = &Agency{ID: "agency1", Name: "Test Agency", URL: "http://test.com", Timezone: "America/New_York"}
+	feed.AddRoute(&Route{ID: "route1", AgencyID: "agency1", Type: 3})
+	feed.AddTrip(&Trip{ID: "trip1", RouteID: "route1", ServiceID: "missing"})
+
+	// When: validating
+	err := feed.ValidateAll()
+
+	// Then: each problem is classified and reachable through errors.Is/As
+	if !errors.Is(err, ErrMissingRequiredField) {
+		t.Errorf("Expected ErrMissingRequiredField in %v", err)
+	}
+	if !errors.Is(err, ErrBrokenReference) {
+		t.Errorf("Expected ErrBrokenReference in %v", err)
+	}
+	if errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("Expected no ErrDuplicateKey in %v", err)
+	}
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("Expected a *ValidationError in %v", err)
+	}
+	if ve.Code != ValidationMissingRequiredField || ve.EntityType != "route" {
+		t.Errorf("Expected the route's missing name first, got %+v", ve)
+	}
+}
+
+func TestValidateAllValidFeed(t *testing.T) {
+	feed := NewFeed()
+	feed.Agencies["agency1"] = &Agency{ID: "agency1", Name: "Test Agency", URL: "http://test.com", Timezone: "America/New_York"}
+	if err := feed.ValidateAll(); err != nil {
+		t.Errorf("Expected nil for a valid feed, got %v", err)
+	}
+}
+
 // ============================================================================
 // Additional validation tests for completeness
 // ============================================================================
@@ -151,6 +151,81 @@ func TestWriteCSVEscapeNewlines(t *testing.T) {
 	}
 }
 
+// TestWriteCSVEscapeCarriageReturns verifies that values containing CR are
+// quoted, that a CRLF inside a value is written as LF, and that the record
+// passed in is left unchanged
+func TestWriteCSVEscapeCarriageReturns(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewCSVWriter(&buf)
+
+	_ = w.WriteHeader([]string{"stop_id", "stop_desc", "stop_name"})
+	record := []string{"S1", "Bay 3\rLevel 2", "Line 1\r\nLine 2"}
+	if err := w.WriteRecord(record); err != nil {
+		t.Fatalf("WriteRecord failed: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	expected := "stop_id,stop_desc,stop_name\nS1,\"Bay 3\rLevel 2\",\"Line 1\nLine 2\"\n"
+	if buf.String() != expected {
+		t.Errorf("output mismatch:\ngot:  %q\nwant: %q", buf.String(), expected)
+	}
+	if record[2] != "Line 1\r\nLine 2" {
+		t.Errorf("expected the record to be unchanged, got %q", record[2])
+	}
+}
+
+// TestWriteCSVStripNewlines verifies that a writer created with
+// StripNewlines writes line breaks inside values as spaces
+func TestWriteCSVStripNewlines(t *testing.T) {
+	var buf bytes.Buffer
+	opts := &WriterOptions{StripNewlines: true}
+	w := opts.newCSVWriter(&buf)
+
+	_ = w.WriteHeader([]string{"stop_id", "stop_desc"})
+	_ = w.WriteRecord([]string{"S1", "a\nb\r\nc\rd, e"})
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	expected := "stop_id,stop_desc\nS1,\"a b c d, e\"\n"
+	if buf.String() != expected {
+		t.Errorf("output mismatch:\ngot:  %q\nwant: %q", buf.String(), expected)
+	}
+}
+
+// TestWriteCSVLineEndingsAndQuoting verifies the records written with
+// UseCRLF and QuoteAll
+func TestWriteCSVLineEndingsAndQuoting(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     WriterOptions
+		expected string
+	}{
+		{"default", WriterOptions{}, "stop_id,stop_desc\nS1,\"say \"\"hi\"\"\"\nS2,\"a\nb\"\nS3,\n"},
+		{"crlf", WriterOptions{UseCRLF: true}, "stop_id,stop_desc\r\nS1,\"say \"\"hi\"\"\"\r\nS2,\"a\r\nb\"\r\nS3,\r\n"},
+		{"quote all", WriterOptions{QuoteAll: true}, "\"stop_id\",\"stop_desc\"\n\"S1\",\"say \"\"hi\"\"\"\n\"S2\",\"a\nb\"\n\"S3\",\"\"\n"},
+		{"both", WriterOptions{UseCRLF: true, QuoteAll: true}, "\"stop_id\",\"stop_desc\"\r\n\"S1\",\"say \"\"hi\"\"\"\r\n\"S2\",\"a\r\nb\"\r\n\"S3\",\"\"\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := tt.opts.newCSVWriter(&buf)
+			_ = w.WriteHeader([]string{"stop_id", "stop_desc"})
+			_ = w.WriteRecord([]string{"S1", `say "hi"`})
+			_ = w.WriteRecord([]string{"S2", "a\r\nb"})
+			_ = w.WriteRecord([]string{"S3", ""})
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Flush failed: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("output mismatch:\ngot:  %q\nwant: %q", buf.String(), tt.expected)
+			}
+		})
+	}
+}
+
 // TestWriteCSVMultipleRecords verifies that multiple records are written correctly
 func TestWriteCSVMultipleRecords(t *testing.T) {
 	var buf bytes.Buffer
@@ -2,108 +2,305 @@ package gtfs
 
 import (
 	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
-	"strings"
+	"syscall"
 )
 
+// ErrUnknownFile is returned when asked to write a file that is not a
+// supported GTFS file
+var ErrUnknownFile = errors.New("unknown GTFS file")
+
 // WriteToPath writes a GTFS feed to a zip file at the given path.
 func WriteToPath(feed *Feed, path string) error {
-	f, err := os.Create(path)
+	return WriteToPathWithOptions(feed, path, WriterOptions{})
+}
+
+// WriteToPathWithOptions writes a GTFS feed to a zip file at the given path
+// using the provided writer options.
+func WriteToPathWithOptions(feed *Feed, path string, options WriterOptions) error {
+	return WriteToPathContext(context.Background(), feed, path, options)
+}
+
+// ErrOutputVerification is returned when a zip written to a path cannot be
+// read back, as when the storage lost part of it; nothing is left at the
+// path
+var ErrOutputVerification = errors.New("output failed verification")
+
+// outputFile is the temporary file WriteToPathContext writes: an *os.File,
+// except in tests injecting faults
+type outputFile interface {
+	io.Writer
+	io.ReaderAt
+	Name() string
+	Chmod(mode os.FileMode) error
+	Stat() (os.FileInfo, error)
+	Sync() error
+	Close() error
+}
+
+// createOutputFile creates the temporary file WriteToPathContext writes
+var createOutputFile = func(dir, pattern string) (outputFile, error) {
+	return os.CreateTemp(dir, pattern)
+}
+
+// WriteToPathContext is like WriteToPathWithOptions but stops between files
+// once ctx is canceled, returning ctx.Err() wrapped with the file being written.
+// The feed is written to a temporary file in the destination directory that
+// replaces path only once writing succeeds, so a failed or interrupted write
+// never leaves a partial zip at path. Before the rename the file is synced
+// to storage and its central directory read back, failing with
+// ErrOutputVerification if it is unreadable; after it the directory is
+// synced, so the new file survives a power loss. Under options.Checksum a
+// PATH.sha256 sidecar is written alongside.
+func WriteToPathContext(ctx context.Context, feed *Feed, path string, options WriterOptions) (err error) {
+	f, err := createOutputFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
 	if err != nil {
 		return fmt.Errorf("cannot create file %s: %w", path, err)
 	}
-	defer func() { _ = f.Close() }()
+	closed := false
+	defer func() {
+		if err != nil {
+			if !closed {
+				_ = f.Close()
+			}
+			_ = os.Remove(f.Name())
+		}
+	}()
 
-	if err := WriteToZip(feed, f); err != nil {
-		return err
+	// CreateTemp makes the file private; keep the mode of the file being
+	// replaced, or use the usual mode for a new file
+	mode := os.FileMode(0o644)
+	if info, statErr := os.Stat(path); statErr == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := f.Chmod(mode); err != nil {
+		return fmt.Errorf("cannot create file %s: %w", path, err)
 	}
 
-	return nil
-}
-
-// WriteToZip writes a GTFS feed to a zip archive.
-func WriteToZip(feed *Feed, w io.Writer) error {
-	zw := zip.NewWriter(w)
-	defer func() { _ = zw.Close() }()
-
-	// Write required files
-	if err := writeAgencies(zw, feed); err != nil {
-		return fmt.Errorf("writing agency.txt: %w", err)
+	// The checksum is of the bytes written, hashed as they are written
+	hash := sha256.New()
+	var w io.Writer = f
+	if options.Checksum {
+		w = io.MultiWriter(f, hash)
+	}
+	if err := WriteToZipContext(ctx, feed, w, options); err != nil {
+		return err
 	}
-	if err := writeStops(zw, feed); err != nil {
-		return fmt.Errorf("writing stops.txt: %w", err)
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("cannot write file %s: %w", path, err)
 	}
-	if err := writeRoutes(zw, feed); err != nil {
-		return fmt.Errorf("writing routes.txt: %w", err)
+	if err := verifyZip(f); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrOutputVerification, path, err)
 	}
-	if err := writeTrips(zw, feed); err != nil {
-		return fmt.Errorf("writing trips.txt: %w", err)
+	closed = true
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("cannot write file %s: %w", path, err)
 	}
-	if err := writeStopTimes(zw, feed); err != nil {
-		return fmt.Errorf("writing stop_times.txt: %w", err)
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("cannot write file %s: %w", path, err)
 	}
 
-	// Write calendar files (at least one required)
-	if len(feed.Calendars) > 0 {
-		if err := writeCalendars(zw, feed); err != nil {
-			return fmt.Errorf("writing calendar.txt: %w", err)
+	if options.Checksum {
+		if err := writeChecksum(path, hex.EncodeToString(hash.Sum(nil)), mode); err != nil {
+			return fmt.Errorf("cannot write checksum of %s: %w", path, err)
 		}
 	}
-	if len(feed.CalendarDates) > 0 {
-		if err := writeCalendarDates(zw, feed); err != nil {
-			return fmt.Errorf("writing calendar_dates.txt: %w", err)
-		}
+	if err := syncDir(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("cannot write file %s: %w", path, err)
 	}
+	return nil
+}
 
-	// Write optional files (only if data exists)
-	if len(feed.Shapes) > 0 {
-		if err := writeShapes(zw, feed); err != nil {
-			return fmt.Errorf("writing shapes.txt: %w", err)
-		}
+// verifyZip reads back the central directory of the zip in f, checking that
+// it lists the required GTFS files
+func verifyZip(f outputFile) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
 	}
-	if len(feed.Frequencies) > 0 {
-		if err := writeFrequencies(zw, feed); err != nil {
-			return fmt.Errorf("writing frequencies.txt: %w", err)
-		}
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return err
 	}
-	if len(feed.Transfers) > 0 {
-		if err := writeTransfers(zw, feed); err != nil {
-			return fmt.Errorf("writing transfers.txt: %w", err)
+	for _, filename := range requiredFiles {
+		if !slices.ContainsFunc(zr.File, func(zf *zip.File) bool { return zf.Name == filename }) {
+			return fmt.Errorf("%s missing from the archive", filename)
 		}
 	}
-	if len(feed.FareAttributes) > 0 {
-		if err := writeFareAttributes(zw, feed); err != nil {
-			return fmt.Errorf("writing fare_attributes.txt: %w", err)
-		}
+	return nil
+}
+
+// ChecksumPath returns the path of the SHA-256 sidecar written for the
+// output at path under WriterOptions.Checksum
+func ChecksumPath(path string) string {
+	return path + ".sha256"
+}
+
+// writeChecksum writes the sidecar of the output at path, whose SHA-256 is
+// sum, in the format sha256sum -c reads. Like the output, it is synced
+// before it replaces any earlier sidecar.
+func writeChecksum(path, sum string, mode os.FileMode) (err error) {
+	f, err := createOutputFile(filepath.Dir(path), "."+filepath.Base(ChecksumPath(path))+".tmp-*")
+	if err != nil {
+		return err
 	}
-	if len(feed.FareRules) > 0 {
-		if err := writeFareRules(zw, feed); err != nil {
-			return fmt.Errorf("writing fare_rules.txt: %w", err)
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
 		}
+	}()
+	if err := f.Chmod(mode); err != nil {
+		return err
 	}
-	if len(feed.FeedInfos) > 0 {
-		if err := writeFeedInfo(zw, feed); err != nil {
-			return fmt.Errorf("writing feed_info.txt: %w", err)
-		}
+	if _, err := fmt.Fprintf(f, "%s  %s\n", sum, filepath.Base(path)); err != nil {
+		return err
 	}
-	if len(feed.Areas) > 0 {
-		if err := writeAreas(zw, feed); err != nil {
-			return fmt.Errorf("writing areas.txt: %w", err)
-		}
+	if err := f.Sync(); err != nil {
+		return err
 	}
-	if len(feed.Pathways) > 0 {
-		if err := writePathways(zw, feed); err != nil {
-			return fmt.Errorf("writing pathways.txt: %w", err)
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), ChecksumPath(path))
+}
+
+// syncDir syncs the directory at dir, making the renames into it durable.
+// Platforms that cannot sync a directory are not treated as failing.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = d.Close() }()
+	if err := d.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) && !errors.Is(err, errors.ErrUnsupported) {
+		return err
+	}
+	return nil
+}
+
+// WriteToZip writes a GTFS feed to a zip archive.
+func WriteToZip(feed *Feed, w io.Writer) error {
+	return WriteToZipWithOptions(feed, w, WriterOptions{})
+}
+
+// WriteToZipWithOptions writes a GTFS feed to a zip archive using the
+// provided writer options.
+func WriteToZipWithOptions(feed *Feed, w io.Writer, options WriterOptions) error {
+	return WriteToZipContext(context.Background(), feed, w, options)
+}
+
+// feedFileWriter describes how to write one GTFS file
+type feedFileWriter struct {
+	filename string
+	// present reports whether the feed has data for the file;
+	// nil means the file is required and always written
+	present func(*Feed) bool
+	write   func(io.Writer, *Feed, *WriterOptions) error
+}
+
+// feedFileWriters lists the GTFS files in the order they are written
+var feedFileWriters = []feedFileWriter{
+	// Required files
+	{"agency.txt", nil, writeAgencies},
+	{"stops.txt", nil, writeStops},
+	{"routes.txt", nil, writeRoutes},
+	{"trips.txt", nil, writeTrips},
+	{"stop_times.txt", nil, writeStopTimes},
+
+	// Calendar files (at least one required)
+	{"calendar.txt", func(f *Feed) bool { return len(f.Calendars) > 0 }, writeCalendars},
+	{"calendar_dates.txt", func(f *Feed) bool { return len(f.CalendarDates) > 0 }, writeCalendarDates},
+
+	// Optional files (only if data exists)
+	{"shapes.txt", func(f *Feed) bool { return len(f.Shapes) > 0 }, writeShapes},
+	{"frequencies.txt", func(f *Feed) bool { return len(f.Frequencies) > 0 }, writeFrequencies},
+	{"transfers.txt", func(f *Feed) bool { return len(f.Transfers) > 0 }, writeTransfers},
+	{"fare_attributes.txt", func(f *Feed) bool { return len(f.FareAttributes) > 0 }, writeFareAttributes},
+	{"fare_rules.txt", func(f *Feed) bool { return len(f.FareRules) > 0 }, writeFareRules},
+	{"feed_info.txt", func(f *Feed) bool { return len(f.FeedInfos) > 0 }, writeFeedInfo},
+	{"areas.txt", func(f *Feed) bool { return len(f.Areas) > 0 }, writeAreas},
+	{"pathways.txt", func(f *Feed) bool { return len(f.Pathways) > 0 }, writePathways},
+	{"networks.txt", func(f *Feed) bool { return len(f.Networks) > 0 }, writeNetworks},
+	{"route_networks.txt", func(f *Feed) bool { return len(f.RouteNetworks) > 0 }, writeRouteNetworks},
+	{"fare_leg_rules.txt", func(f *Feed) bool { return len(f.FareLegRules) > 0 }, writeFareLegRules},
+	{"fare_transfer_rules.txt", func(f *Feed) bool { return len(f.FareTransferRules) > 0 }, writeFareTransferRules},
+	{"fare_products.txt", func(f *Feed) bool { return len(f.FareProducts) > 0 }, writeFareProducts},
+	{"timeframes.txt", func(f *Feed) bool { return len(f.Timeframes) > 0 }, writeTimeframes},
+	{"fare_media.txt", func(f *Feed) bool { return len(f.FareMedia) > 0 }, writeFareMedia},
+	{"rider_categories.txt", func(f *Feed) bool { return len(f.RiderCategories) > 0 }, writeRiderCategories},
+}
+
+// FileNames returns the GTFS files the writer supports, in the order they
+// are written
+func FileNames() []string {
+	names := make([]string, len(feedFileWriters))
+	for i, fw := range feedFileWriters {
+		names[i] = fw.filename
+	}
+	return names
+}
+
+// WriteToZipContext is like WriteToZipWithOptions but stops between files
+// once ctx is canceled, returning ctx.Err() wrapped with the file being written.
+func WriteToZipContext(ctx context.Context, feed *Feed, w io.Writer, options WriterOptions) error {
+	opts := &options
+	zw := zip.NewWriter(w)
+	defer func() { _ = zw.Close() }()
+
+	for _, fw := range feedFileWriters {
+		if fw.present != nil && !fw.present(feed) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("writing %s: %w", fw.filename, err)
+		}
+		method, err := opts.compressionMethod(fw.filename)
+		if err != nil {
+			return fmt.Errorf("writing %s: %w", fw.filename, err)
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: fw.filename, Method: method})
+		if err != nil {
+			return fmt.Errorf("writing %s: %w", fw.filename, err)
+		}
+		if err := fw.write(w, feed, opts); err != nil {
+			return fmt.Errorf("writing %s: %w", fw.filename, err)
 		}
 	}
 
 	return zw.Close()
 }
 
+// WriteFile writes a single GTFS file (e.g. "stop_times.txt") of feed to w as
+// CSV, choosing columns exactly as the zip writer does. It returns an error
+// wrapping ErrUnknownFile if filename is not a GTFS file the writer supports.
+func WriteFile(feed *Feed, filename string, w io.Writer) error {
+	return WriteFileWithOptions(feed, filename, w, WriterOptions{})
+}
+
+// WriteFileWithOptions is like WriteFile but uses the provided writer options.
+func WriteFileWithOptions(feed *Feed, filename string, w io.Writer, options WriterOptions) error {
+	for _, fw := range feedFileWriters {
+		if fw.filename == filename {
+			if err := fw.write(w, feed, &options); err != nil {
+				return fmt.Errorf("writing %s: %w", filename, err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownFile, filename)
+}
+
 // Helper functions for formatting values
 func formatInt(v int) string {
 	return strconv.Itoa(v)
@@ -141,17 +338,15 @@ func formatFloatPtr(v *float64) string {
 	if v == nil {
 		return ""
 	}
-	s := strconv.FormatFloat(*v, 'f', -1, 64)
+	// Formatted into a buffer on the stack, so that the only allocation
+	// is the string returned
+	var buf [32]byte
+	b := strconv.AppendFloat(buf[:0], *v, 'f', -1, 64)
 	// Ensure at least one decimal place to match Java output
-	if !strings.Contains(s, ".") {
-		s += ".0"
+	if !slices.Contains(b, '.') {
+		b = append(b, ".0"...)
 	}
-	return s
-}
-
-// formatLatLon formats latitude/longitude with 6 decimal places to match Java output.
-func formatLatLon(v float64) string {
-	return strconv.FormatFloat(v, 'f', 6, 64)
+	return string(b)
 }
 
 // formatPriceFloat formats a price with 6 decimal places, including when the value is 0
@@ -159,6 +354,15 @@ func formatPriceFloat(v float64) string {
 	return strconv.FormatFloat(v, 'f', 6, 64)
 }
 
+// formatPricePtr formats a pointer to a price.
+// Returns empty string for nil, otherwise formats the value with 6 decimal places.
+func formatPricePtr(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return formatPriceFloat(*v)
+}
+
 func formatBool(v bool) string {
 	if v {
 		return "1"
@@ -203,13 +407,8 @@ func (c *columnChecker) allFound() bool {
 }
 
 // writeAgencies writes agency.txt
-func writeAgencies(zw *zip.Writer, feed *Feed) error {
-	w, err := zw.Create("agency.txt")
-	if err != nil {
-		return err
-	}
-
-	csvw := NewCSVWriter(w)
+func writeAgencies(w io.Writer, feed *Feed, opts *WriterOptions) error {
+	csvw := opts.newCSVWriter(w)
 
 	// Define all possible columns in order, with their getters
 	type colDef struct {
@@ -230,7 +429,7 @@ func writeAgencies(zw *zip.Writer, feed *Feed) error {
 	// Filter to only columns present in source data
 	var activeCols []colDef
 	for _, col := range allCols {
-		if feed.HasColumn("agency.txt", col.name) {
+		if opts.includeColumn("agency.txt", col.name, feed.HasColumn("agency.txt", col.name)) {
 			activeCols = append(activeCols, col)
 		}
 	}
@@ -244,6 +443,8 @@ func writeAgencies(zw *zip.Writer, feed *Feed) error {
 		return err
 	}
 
+	record := make([]string, len(activeCols))
+
 	// Sort by agency ID for deterministic output (matches Java behavior of global sorting)
 	agencyIDs := make([]AgencyID, 0, len(feed.Agencies))
 	for id := range feed.Agencies {
@@ -255,7 +456,6 @@ func writeAgencies(zw *zip.Writer, feed *Feed) error {
 		if a == nil {
 			continue // Skip if agency was removed
 		}
-		record := make([]string, len(activeCols))
 		for i, col := range activeCols {
 			record[i] = col.getter(a)
 		}
@@ -268,13 +468,8 @@ func writeAgencies(zw *zip.Writer, feed *Feed) error {
 }
 
 // writeStops writes stops.txt
-func writeStops(zw *zip.Writer, feed *Feed) error {
-	w, err := zw.Create("stops.txt")
-	if err != nil {
-		return err
-	}
-
-	csvw := NewCSVWriter(w)
+func writeStops(w io.Writer, feed *Feed, opts *WriterOptions) error {
+	csvw := opts.newCSVWriter(w)
 
 	// Define all possible columns in order, with their getters
 	type colDef struct {
@@ -284,8 +479,8 @@ func writeStops(zw *zip.Writer, feed *Feed) error {
 	allCols := []colDef{
 		{"stop_id", func(s *Stop) string { return string(s.ID) }},
 		{"stop_name", func(s *Stop) string { return s.Name }},
-		{"stop_lat", func(s *Stop) string { return formatLatLon(s.Lat) }},
-		{"stop_lon", func(s *Stop) string { return formatLatLon(s.Lon) }},
+		{"stop_lat", func(s *Stop) string { return opts.formatCoordinate(s.Lat) }},
+		{"stop_lon", func(s *Stop) string { return opts.formatCoordinate(s.Lon) }},
 		{"stop_code", func(s *Stop) string { return s.Code }},
 		{"stop_desc", func(s *Stop) string { return s.Desc }},
 		{"zone_id", func(s *Stop) string { return s.ZoneID }},
@@ -350,9 +545,8 @@ func writeStops(zw *zip.Writer, feed *Feed) error {
 	// Filter columns: include if required OR (in source AND has non-default value)
 	var activeCols []colDef
 	for _, col := range allCols {
-		if requiredCols[col.name] {
-			activeCols = append(activeCols, col)
-		} else if feed.HasColumn("stops.txt", col.name) && checker.hasNonDefaultValue(col.name) {
+		include := requiredCols[col.name] || (feed.HasColumn("stops.txt", col.name) && checker.hasNonDefaultValue(col.name))
+		if opts.includeColumn("stops.txt", col.name, include) {
 			activeCols = append(activeCols, col)
 		}
 	}
@@ -366,6 +560,8 @@ func writeStops(zw *zip.Writer, feed *Feed) error {
 		return err
 	}
 
+	record := make([]string, len(activeCols))
+
 	// Sort by stop ID for deterministic output (matches Java behavior of global sorting)
 	stopIDs := make([]StopID, 0, len(feed.Stops))
 	for id := range feed.Stops {
@@ -377,7 +573,6 @@ func writeStops(zw *zip.Writer, feed *Feed) error {
 		if s == nil {
 			continue // Skip if stop was removed
 		}
-		record := make([]string, len(activeCols))
 		for i, col := range activeCols {
 			record[i] = col.getter(s)
 		}
@@ -390,13 +585,8 @@ func writeStops(zw *zip.Writer, feed *Feed) error {
 }
 
 // writeRoutes writes routes.txt
-func writeRoutes(zw *zip.Writer, feed *Feed) error {
-	w, err := zw.Create("routes.txt")
-	if err != nil {
-		return err
-	}
-
-	csvw := NewCSVWriter(w)
+func writeRoutes(w io.Writer, feed *Feed, opts *WriterOptions) error {
+	csvw := opts.newCSVWriter(w)
 
 	// Define all possible columns in order, with their getters
 	type colDef struct {
@@ -414,8 +604,9 @@ func writeRoutes(zw *zip.Writer, feed *Feed) error {
 		{"route_color", func(r *Route) string { return r.Color }},
 		{"route_text_color", func(r *Route) string { return r.TextColor }},
 		{"route_sort_order", func(r *Route) string { return formatIntPtr(r.SortOrder) }},
-		{"continuous_pickup", func(r *Route) string { return formatOptionalInt(r.ContinuousPickup) }},
-		{"continuous_drop_off", func(r *Route) string { return formatOptionalInt(r.ContinuousDropOff) }},
+		{"continuous_pickup", func(r *Route) string { return formatIntPtr(r.ContinuousPickup) }},
+		{"continuous_drop_off", func(r *Route) string { return formatIntPtr(r.ContinuousDropOff) }},
+		{"network_id", func(r *Route) string { return string(r.NetworkID) }},
 	}
 
 	// Required columns are always included
@@ -427,7 +618,7 @@ func writeRoutes(zw *zip.Writer, feed *Feed) error {
 	optionalCols := []string{
 		"agency_id", "route_short_name", "route_long_name", "route_desc",
 		"route_url", "route_color", "route_text_color", "route_sort_order",
-		"continuous_pickup", "continuous_drop_off",
+		"continuous_pickup", "continuous_drop_off", "network_id",
 	}
 	checker := newColumnChecker(optionalCols)
 
@@ -457,12 +648,15 @@ func writeRoutes(zw *zip.Writer, feed *Feed) error {
 		if r.SortOrder != nil {
 			checker.markNonDefault("route_sort_order")
 		}
-		if r.ContinuousPickup != 0 {
+		if r.ContinuousPickup != nil {
 			checker.markNonDefault("continuous_pickup")
 		}
-		if r.ContinuousDropOff != 0 {
+		if r.ContinuousDropOff != nil {
 			checker.markNonDefault("continuous_drop_off")
 		}
+		if r.NetworkID != "" {
+			checker.markNonDefault("network_id")
+		}
 		if checker.allFound() {
 			break
 		}
@@ -471,9 +665,8 @@ func writeRoutes(zw *zip.Writer, feed *Feed) error {
 	// Filter columns: include if required OR (in source AND has non-default value)
 	var activeCols []colDef
 	for _, col := range allCols {
-		if requiredCols[col.name] {
-			activeCols = append(activeCols, col)
-		} else if feed.HasColumn("routes.txt", col.name) && checker.hasNonDefaultValue(col.name) {
+		include := requiredCols[col.name] || (feed.HasColumn("routes.txt", col.name) && checker.hasNonDefaultValue(col.name))
+		if opts.includeColumn("routes.txt", col.name, include) {
 			activeCols = append(activeCols, col)
 		}
 	}
@@ -487,6 +680,8 @@ func writeRoutes(zw *zip.Writer, feed *Feed) error {
 		return err
 	}
 
+	record := make([]string, len(activeCols))
+
 	// Sort by route ID for deterministic output (matches Java behavior of global sorting)
 	routeIDs := make([]RouteID, 0, len(feed.Routes))
 	for id := range feed.Routes {
@@ -498,7 +693,6 @@ func writeRoutes(zw *zip.Writer, feed *Feed) error {
 		if r == nil {
 			continue // Skip if route was removed
 		}
-		record := make([]string, len(activeCols))
 		for i, col := range activeCols {
 			record[i] = col.getter(r)
 		}
@@ -511,13 +705,8 @@ func writeRoutes(zw *zip.Writer, feed *Feed) error {
 }
 
 // writeTrips writes trips.txt
-func writeTrips(zw *zip.Writer, feed *Feed) error {
-	w, err := zw.Create("trips.txt")
-	if err != nil {
-		return err
-	}
-
-	csvw := NewCSVWriter(w)
+func writeTrips(w io.Writer, feed *Feed, opts *WriterOptions) error {
+	csvw := opts.newCSVWriter(w)
 
 	// Define all possible columns in order, with their getters
 	type colDef struct {
@@ -580,9 +769,8 @@ func writeTrips(zw *zip.Writer, feed *Feed) error {
 	// Filter columns: include if required OR (in source AND has non-default value)
 	var activeCols []colDef
 	for _, col := range allCols {
-		if requiredCols[col.name] {
-			activeCols = append(activeCols, col)
-		} else if feed.HasColumn("trips.txt", col.name) && checker.hasNonDefaultValue(col.name) {
+		include := requiredCols[col.name] || (feed.HasColumn("trips.txt", col.name) && checker.hasNonDefaultValue(col.name))
+		if opts.includeColumn("trips.txt", col.name, include) {
 			activeCols = append(activeCols, col)
 		}
 	}
@@ -596,6 +784,8 @@ func writeTrips(zw *zip.Writer, feed *Feed) error {
 		return err
 	}
 
+	record := make([]string, len(activeCols))
+
 	// Sort by trip ID for deterministic output (matches Java behavior of global sorting)
 	tripIDs := make([]TripID, 0, len(feed.Trips))
 	for id := range feed.Trips {
@@ -607,7 +797,6 @@ func writeTrips(zw *zip.Writer, feed *Feed) error {
 		if t == nil {
 			continue // Skip if trip was removed
 		}
-		record := make([]string, len(activeCols))
 		for i, col := range activeCols {
 			record[i] = col.getter(t)
 		}
@@ -620,13 +809,8 @@ func writeTrips(zw *zip.Writer, feed *Feed) error {
 }
 
 // writeStopTimes writes stop_times.txt
-func writeStopTimes(zw *zip.Writer, feed *Feed) error {
-	w, err := zw.Create("stop_times.txt")
-	if err != nil {
-		return err
-	}
-
-	csvw := NewCSVWriter(w)
+func writeStopTimes(w io.Writer, feed *Feed, opts *WriterOptions) error {
+	csvw := opts.newCSVWriter(w)
 
 	// Define all possible columns in order, with their getters
 	type colDef struct {
@@ -644,8 +828,8 @@ func writeStopTimes(zw *zip.Writer, feed *Feed) error {
 		{"shape_dist_traveled", func(st *StopTime) string { return formatFloatPtr(st.ShapeDistTraveled) }},
 		{"pickup_type", func(st *StopTime) string { return formatOptionalInt(st.PickupType) }},
 		{"drop_off_type", func(st *StopTime) string { return formatOptionalInt(st.DropOffType) }},
-		{"continuous_pickup", func(st *StopTime) string { return formatOptionalInt(st.ContinuousPickup) }},
-		{"continuous_drop_off", func(st *StopTime) string { return formatOptionalInt(st.ContinuousDropOff) }},
+		{"continuous_pickup", func(st *StopTime) string { return formatIntPtr(st.ContinuousPickup) }},
+		{"continuous_drop_off", func(st *StopTime) string { return formatIntPtr(st.ContinuousDropOff) }},
 	}
 
 	// Required columns are always included
@@ -672,10 +856,10 @@ func writeStopTimes(zw *zip.Writer, feed *Feed) error {
 		if st.DropOffType != 0 {
 			checker.markNonDefault("drop_off_type")
 		}
-		if st.ContinuousPickup != 0 {
+		if st.ContinuousPickup != nil {
 			checker.markNonDefault("continuous_pickup")
 		}
-		if st.ContinuousDropOff != 0 {
+		if st.ContinuousDropOff != nil {
 			checker.markNonDefault("continuous_drop_off")
 		}
 		if st.ShapeDistTraveled != nil {
@@ -693,9 +877,8 @@ func writeStopTimes(zw *zip.Writer, feed *Feed) error {
 	// Filter columns: include if required OR (in source AND has non-default value)
 	var activeCols []colDef
 	for _, col := range allCols {
-		if requiredCols[col.name] {
-			activeCols = append(activeCols, col)
-		} else if feed.HasColumn("stop_times.txt", col.name) && checker.hasNonDefaultValue(col.name) {
+		include := requiredCols[col.name] || (feed.HasColumn("stop_times.txt", col.name) && checker.hasNonDefaultValue(col.name))
+		if opts.includeColumn("stop_times.txt", col.name, include) {
 			activeCols = append(activeCols, col)
 		}
 	}
@@ -709,8 +892,9 @@ func writeStopTimes(zw *zip.Writer, feed *Feed) error {
 		return err
 	}
 
+	record := make([]string, len(activeCols))
+
 	for _, st := range feed.StopTimes {
-		record := make([]string, len(activeCols))
 		for i, col := range activeCols {
 			record[i] = col.getter(st)
 		}
@@ -723,13 +907,8 @@ func writeStopTimes(zw *zip.Writer, feed *Feed) error {
 }
 
 // writeCalendars writes calendar.txt
-func writeCalendars(zw *zip.Writer, feed *Feed) error {
-	w, err := zw.Create("calendar.txt")
-	if err != nil {
-		return err
-	}
-
-	csvw := NewCSVWriter(w)
+func writeCalendars(w io.Writer, feed *Feed, opts *WriterOptions) error {
+	csvw := opts.newCSVWriter(w)
 
 	// Define all possible columns in order, with their getters
 	type colDef struct {
@@ -752,7 +931,7 @@ func writeCalendars(zw *zip.Writer, feed *Feed) error {
 	// Filter to only columns present in source data
 	var activeCols []colDef
 	for _, col := range allCols {
-		if feed.HasColumn("calendar.txt", col.name) {
+		if opts.includeColumn("calendar.txt", col.name, feed.HasColumn("calendar.txt", col.name)) {
 			activeCols = append(activeCols, col)
 		}
 	}
@@ -766,6 +945,8 @@ func writeCalendars(zw *zip.Writer, feed *Feed) error {
 		return err
 	}
 
+	record := make([]string, len(activeCols))
+
 	// Sort by service ID for deterministic output (matches Java behavior of global sorting)
 	calendarIDs := make([]ServiceID, 0, len(feed.Calendars))
 	for id := range feed.Calendars {
@@ -777,7 +958,6 @@ func writeCalendars(zw *zip.Writer, feed *Feed) error {
 		if c == nil {
 			continue // Skip if calendar was removed
 		}
-		record := make([]string, len(activeCols))
 		for i, col := range activeCols {
 			record[i] = col.getter(c)
 		}
@@ -790,13 +970,8 @@ func writeCalendars(zw *zip.Writer, feed *Feed) error {
 }
 
 // writeCalendarDates writes calendar_dates.txt
-func writeCalendarDates(zw *zip.Writer, feed *Feed) error {
-	w, err := zw.Create("calendar_dates.txt")
-	if err != nil {
-		return err
-	}
-
-	csvw := NewCSVWriter(w)
+func writeCalendarDates(w io.Writer, feed *Feed, opts *WriterOptions) error {
+	csvw := opts.newCSVWriter(w)
 
 	// Define all possible columns in order, with their getters
 	type colDef struct {
@@ -812,7 +987,7 @@ func writeCalendarDates(zw *zip.Writer, feed *Feed) error {
 	// Filter to only columns present in source data
 	var activeCols []colDef
 	for _, col := range allCols {
-		if feed.HasColumn("calendar_dates.txt", col.name) {
+		if opts.includeColumn("calendar_dates.txt", col.name, feed.HasColumn("calendar_dates.txt", col.name)) {
 			activeCols = append(activeCols, col)
 		}
 	}
@@ -826,6 +1001,8 @@ func writeCalendarDates(zw *zip.Writer, feed *Feed) error {
 		return err
 	}
 
+	record := make([]string, len(activeCols))
+
 	// Sort by service ID for deterministic output (matches Java behavior of global sorting)
 	calendarDateIDs := make([]ServiceID, 0, len(feed.CalendarDates))
 	for id := range feed.CalendarDates {
@@ -839,7 +1016,6 @@ func writeCalendarDates(zw *zip.Writer, feed *Feed) error {
 		}
 		// Keep dates in insertion order (not sorted)
 		for _, cd := range dates {
-			record := make([]string, len(activeCols))
 			for i, col := range activeCols {
 				record[i] = col.getter(cd)
 			}
@@ -853,13 +1029,8 @@ func writeCalendarDates(zw *zip.Writer, feed *Feed) error {
 }
 
 // writeShapes writes shapes.txt
-func writeShapes(zw *zip.Writer, feed *Feed) error {
-	w, err := zw.Create("shapes.txt")
-	if err != nil {
-		return err
-	}
-
-	csvw := NewCSVWriter(w)
+func writeShapes(w io.Writer, feed *Feed, opts *WriterOptions) error {
+	csvw := opts.newCSVWriter(w)
 
 	// Define all possible columns in order, with their getters
 	type colDef struct {
@@ -869,8 +1040,8 @@ func writeShapes(zw *zip.Writer, feed *Feed) error {
 	allCols := []colDef{
 		{"shape_id", func(sp *ShapePoint) string { return string(sp.ShapeID) }},
 		{"shape_pt_sequence", func(sp *ShapePoint) string { return formatInt(sp.Sequence) }},
-		{"shape_pt_lat", func(sp *ShapePoint) string { return formatLatLon(sp.Lat) }},
-		{"shape_pt_lon", func(sp *ShapePoint) string { return formatLatLon(sp.Lon) }},
+		{"shape_pt_lat", func(sp *ShapePoint) string { return opts.formatCoordinate(sp.Lat) }},
+		{"shape_pt_lon", func(sp *ShapePoint) string { return opts.formatCoordinate(sp.Lon) }},
 		{"shape_dist_traveled", func(sp *ShapePoint) string { return formatFloatPtr(sp.DistTraveled) }},
 	}
 
@@ -901,9 +1072,8 @@ func writeShapes(zw *zip.Writer, feed *Feed) error {
 	// Filter columns: include if required OR (in source AND has non-default value)
 	var activeCols []colDef
 	for _, col := range allCols {
-		if requiredCols[col.name] {
-			activeCols = append(activeCols, col)
-		} else if feed.HasColumn("shapes.txt", col.name) && checker.hasNonDefaultValue(col.name) {
+		include := requiredCols[col.name] || (feed.HasColumn("shapes.txt", col.name) && checker.hasNonDefaultValue(col.name))
+		if opts.includeColumn("shapes.txt", col.name, include) {
 			activeCols = append(activeCols, col)
 		}
 	}
@@ -917,6 +1087,8 @@ func writeShapes(zw *zip.Writer, feed *Feed) error {
 		return err
 	}
 
+	record := make([]string, len(activeCols))
+
 	// Sort shape IDs for deterministic output order
 	shapeIDs := make([]ShapeID, 0, len(feed.Shapes))
 	for id := range feed.Shapes {
@@ -928,10 +1100,9 @@ func writeShapes(zw *zip.Writer, feed *Feed) error {
 
 	for _, shapeID := range shapeIDs {
 		points := feed.Shapes[shapeID]
-		for _, sp := range points {
-			record := make([]string, len(activeCols))
+		for j := range points {
 			for i, col := range activeCols {
-				record[i] = col.getter(sp)
+				record[i] = col.getter(&points[j])
 			}
 			if err := csvw.WriteRecord(record); err != nil {
 				return err
@@ -943,13 +1114,8 @@ func writeShapes(zw *zip.Writer, feed *Feed) error {
 }
 
 // writeFrequencies writes frequencies.txt
-func writeFrequencies(zw *zip.Writer, feed *Feed) error {
-	w, err := zw.Create("frequencies.txt")
-	if err != nil {
-		return err
-	}
-
-	csvw := NewCSVWriter(w)
+func writeFrequencies(w io.Writer, feed *Feed, opts *WriterOptions) error {
+	csvw := opts.newCSVWriter(w)
 
 	// Define all possible columns in order, with their getters
 	type colDef struct {
@@ -986,9 +1152,8 @@ func writeFrequencies(zw *zip.Writer, feed *Feed) error {
 	// Filter columns: include if required OR (in source AND has non-default value)
 	var activeCols []colDef
 	for _, col := range allCols {
-		if requiredCols[col.name] {
-			activeCols = append(activeCols, col)
-		} else if feed.HasColumn("frequencies.txt", col.name) && checker.hasNonDefaultValue(col.name) {
+		include := requiredCols[col.name] || (feed.HasColumn("frequencies.txt", col.name) && checker.hasNonDefaultValue(col.name))
+		if opts.includeColumn("frequencies.txt", col.name, include) {
 			activeCols = append(activeCols, col)
 		}
 	}
@@ -1002,18 +1167,20 @@ func writeFrequencies(zw *zip.Writer, feed *Feed) error {
 		return err
 	}
 
-	// Sort frequencies by trip_id, start_time for deterministic output
+	record := make([]string, len(activeCols))
+
+	// Sort frequencies by trip_id, start_time for deterministic output,
+	// comparing times as times so that 6:00:00 precedes 10:00:00
 	sortedFreqs := make([]*Frequency, len(feed.Frequencies))
 	copy(sortedFreqs, feed.Frequencies)
-	sort.Slice(sortedFreqs, func(i, j int) bool {
+	sort.SliceStable(sortedFreqs, func(i, j int) bool {
 		if sortedFreqs[i].TripID != sortedFreqs[j].TripID {
 			return string(sortedFreqs[i].TripID) < string(sortedFreqs[j].TripID)
 		}
-		return sortedFreqs[i].StartTime < sortedFreqs[j].StartTime
+		return TimeSeconds(sortedFreqs[i].StartTime) < TimeSeconds(sortedFreqs[j].StartTime)
 	})
 
 	for _, f := range sortedFreqs {
-		record := make([]string, len(activeCols))
 		for i, col := range activeCols {
 			record[i] = col.getter(f)
 		}
@@ -1026,13 +1193,8 @@ func writeFrequencies(zw *zip.Writer, feed *Feed) error {
 }
 
 // writeTransfers writes transfers.txt
-func writeTransfers(zw *zip.Writer, feed *Feed) error {
-	w, err := zw.Create("transfers.txt")
-	if err != nil {
-		return err
-	}
-
-	csvw := NewCSVWriter(w)
+func writeTransfers(w io.Writer, feed *Feed, opts *WriterOptions) error {
+	csvw := opts.newCSVWriter(w)
 
 	// Define all possible columns in order, with their getters
 	type colDef struct {
@@ -1045,7 +1207,7 @@ func writeTransfers(zw *zip.Writer, feed *Feed) error {
 		{"to_stop_id", func(t *Transfer) string { return string(t.ToStopID) }},
 		{"to_route_id", func(t *Transfer) string { return string(t.ToRouteID) }},
 		{"transfer_type", func(t *Transfer) string { return formatOptionalInt(t.TransferType) }},
-		{"min_transfer_time", func(t *Transfer) string { return formatOptionalInt(t.MinTransferTime) }},
+		{"min_transfer_time", func(t *Transfer) string { return formatIntPtr(t.MinTransferTime) }},
 		{"from_trip_id", func(t *Transfer) string { return string(t.FromTripID) }},
 		{"to_trip_id", func(t *Transfer) string { return string(t.ToTripID) }},
 	}
@@ -1055,13 +1217,24 @@ func writeTransfers(zw *zip.Writer, feed *Feed) error {
 		"from_stop_id": true, "to_stop_id": true,
 	}
 
+	// min_transfer_time is only included if at least one row has a value, so
+	// rows from feeds without the column don't force an all-empty column
+	optionalCols := []string{"min_transfer_time"}
+	checker := newColumnChecker(optionalCols)
+	for _, t := range feed.Transfers {
+		if t.MinTransferTime != nil {
+			checker.markNonDefault("min_transfer_time")
+			break
+		}
+	}
+
 	// Filter columns: include if required OR present in source data
-	// Match Java behavior: include columns if they were in any source feed, even if all values are default
+	// Match Java behavior: include columns if they were in any source feed, even if all values are
+	// default, except for the optional columns above
 	var activeCols []colDef
 	for _, col := range allCols {
-		if requiredCols[col.name] {
-			activeCols = append(activeCols, col)
-		} else if feed.HasColumn("transfers.txt", col.name) {
+		include := requiredCols[col.name] || (feed.HasColumn("transfers.txt", col.name) && checker.hasNonDefaultValue(col.name))
+		if opts.includeColumn("transfers.txt", col.name, include) {
 			activeCols = append(activeCols, col)
 		}
 	}
@@ -1075,6 +1248,8 @@ func writeTransfers(zw *zip.Writer, feed *Feed) error {
 		return err
 	}
 
+	record := make([]string, len(activeCols))
+
 	// Sort transfers by from_stop_id, then to_stop_id for deterministic output
 	sortedTransfers := make([]*Transfer, len(feed.Transfers))
 	copy(sortedTransfers, feed.Transfers)
@@ -1086,7 +1261,6 @@ func writeTransfers(zw *zip.Writer, feed *Feed) error {
 	})
 
 	for _, t := range sortedTransfers {
-		record := make([]string, len(activeCols))
 		for i, col := range activeCols {
 			record[i] = col.getter(t)
 		}
@@ -1099,13 +1273,8 @@ func writeTransfers(zw *zip.Writer, feed *Feed) error {
 }
 
 // writeFareAttributes writes fare_attributes.txt
-func writeFareAttributes(zw *zip.Writer, feed *Feed) error {
-	w, err := zw.Create("fare_attributes.txt")
-	if err != nil {
-		return err
-	}
-
-	csvw := NewCSVWriter(w)
+func writeFareAttributes(w io.Writer, feed *Feed, opts *WriterOptions) error {
+	csvw := opts.newCSVWriter(w)
 
 	// Define all possible columns in order, with their getters
 	type colDef struct {
@@ -1117,27 +1286,44 @@ func writeFareAttributes(zw *zip.Writer, feed *Feed) error {
 		{"price", func(fa *FareAttribute) string { return strconv.FormatFloat(fa.Price, 'f', 6, 64) }},
 		{"currency_type", func(fa *FareAttribute) string { return fa.CurrencyType }},
 		{"payment_method", func(fa *FareAttribute) string { return formatInt(fa.PaymentMethod) }}, // Always output, 0 is valid
-		{"transfers", func(fa *FareAttribute) string { return formatOptionalInt(fa.Transfers) }},  // Empty means unlimited
+		{"transfers", func(fa *FareAttribute) string { return formatIntPtr(fa.Transfers) }},       // Empty means unlimited
 		{"agency_id", func(fa *FareAttribute) string { return string(fa.AgencyID) }},
-		{"transfer_duration", func(fa *FareAttribute) string { return formatOptionalInt(fa.TransferDuration) }},
-		{"youth_price", func(fa *FareAttribute) string { return formatPriceFloat(fa.YouthPrice) }},   // Always output with 6 decimals
-		{"senior_price", func(fa *FareAttribute) string { return formatPriceFloat(fa.SeniorPrice) }}, // Always output with 6 decimals
+		{"transfer_duration", func(fa *FareAttribute) string { return formatIntPtr(fa.TransferDuration) }},
+		{"youth_price", func(fa *FareAttribute) string { return formatPricePtr(fa.YouthPrice) }},   // 6 decimals when set
+		{"senior_price", func(fa *FareAttribute) string { return formatPricePtr(fa.SeniorPrice) }}, // 6 decimals when set
 	}
 
 	// Required columns are always included
-	// Note: youth_price and senior_price are always included to match Java behavior
 	requiredCols := map[string]bool{
 		"fare_id": true, "price": true, "currency_type": true,
-		"youth_price": true, "senior_price": true,
+	}
+
+	// Optional columns are only included if at least one fare has a value,
+	// so fares from feeds without the column don't force an all-empty column
+	optionalCols := []string{"transfer_duration", "youth_price", "senior_price"}
+	checker := newColumnChecker(optionalCols)
+	for _, fa := range feed.FareAttributes {
+		if fa.TransferDuration != nil {
+			checker.markNonDefault("transfer_duration")
+		}
+		if fa.YouthPrice != nil {
+			checker.markNonDefault("youth_price")
+		}
+		if fa.SeniorPrice != nil {
+			checker.markNonDefault("senior_price")
+		}
+		if checker.allFound() {
+			break
+		}
 	}
 
 	// Filter columns: include if required OR present in source data
-	// Match Java behavior: include columns if they were in any source feed, even if all values are default
+	// Match Java behavior: include columns if they were in any source feed, even if all values are
+	// default, except for the optional columns above
 	var activeCols []colDef
 	for _, col := range allCols {
-		if requiredCols[col.name] {
-			activeCols = append(activeCols, col)
-		} else if feed.HasColumn("fare_attributes.txt", col.name) {
+		include := requiredCols[col.name] || (feed.HasColumn("fare_attributes.txt", col.name) && checker.hasNonDefaultValue(col.name))
+		if opts.includeColumn("fare_attributes.txt", col.name, include) {
 			activeCols = append(activeCols, col)
 		}
 	}
@@ -1151,6 +1337,8 @@ func writeFareAttributes(zw *zip.Writer, feed *Feed) error {
 		return err
 	}
 
+	record := make([]string, len(activeCols))
+
 	// Sort by fare ID for deterministic output (matches Java behavior of global sorting)
 	fareIDs := make([]FareID, 0, len(feed.FareAttributes))
 	for id := range feed.FareAttributes {
@@ -1162,7 +1350,6 @@ func writeFareAttributes(zw *zip.Writer, feed *Feed) error {
 		if fa == nil {
 			continue // Skip if fare was removed
 		}
-		record := make([]string, len(activeCols))
 		for i, col := range activeCols {
 			record[i] = col.getter(fa)
 		}
@@ -1175,13 +1362,8 @@ func writeFareAttributes(zw *zip.Writer, feed *Feed) error {
 }
 
 // writeFareRules writes fare_rules.txt
-func writeFareRules(zw *zip.Writer, feed *Feed) error {
-	w, err := zw.Create("fare_rules.txt")
-	if err != nil {
-		return err
-	}
-
-	csvw := NewCSVWriter(w)
+func writeFareRules(w io.Writer, feed *Feed, opts *WriterOptions) error {
+	csvw := opts.newCSVWriter(w)
 
 	// Define all possible columns in order, with their getters
 	type colDef struct {
@@ -1199,7 +1381,7 @@ func writeFareRules(zw *zip.Writer, feed *Feed) error {
 	// Filter to only columns present in source data
 	var activeCols []colDef
 	for _, col := range allCols {
-		if feed.HasColumn("fare_rules.txt", col.name) {
+		if opts.includeColumn("fare_rules.txt", col.name, feed.HasColumn("fare_rules.txt", col.name)) {
 			activeCols = append(activeCols, col)
 		}
 	}
@@ -1213,6 +1395,8 @@ func writeFareRules(zw *zip.Writer, feed *Feed) error {
 		return err
 	}
 
+	record := make([]string, len(activeCols))
+
 	// Sort fare rules by fare_id, route_id, origin_id, destination_id for deterministic output
 	sortedFareRules := make([]*FareRule, len(feed.FareRules))
 	copy(sortedFareRules, feed.FareRules)
@@ -1230,7 +1414,6 @@ func writeFareRules(zw *zip.Writer, feed *Feed) error {
 	})
 
 	for _, fr := range sortedFareRules {
-		record := make([]string, len(activeCols))
 		for i, col := range activeCols {
 			record[i] = col.getter(fr)
 		}
@@ -1243,13 +1426,8 @@ func writeFareRules(zw *zip.Writer, feed *Feed) error {
 }
 
 // writeFeedInfo writes feed_info.txt
-func writeFeedInfo(zw *zip.Writer, feed *Feed) error {
-	w, err := zw.Create("feed_info.txt")
-	if err != nil {
-		return err
-	}
-
-	csvw := NewCSVWriter(w)
+func writeFeedInfo(w io.Writer, feed *Feed, opts *WriterOptions) error {
+	csvw := opts.newCSVWriter(w)
 
 	// Define all possible columns in order, with their getters
 	type colDef struct {
@@ -1272,7 +1450,7 @@ func writeFeedInfo(zw *zip.Writer, feed *Feed) error {
 	// Filter to only columns present in source data
 	var activeCols []colDef
 	for _, col := range allCols {
-		if feed.HasColumn("feed_info.txt", col.name) {
+		if opts.includeColumn("feed_info.txt", col.name, feed.HasColumn("feed_info.txt", col.name)) {
 			activeCols = append(activeCols, col)
 		}
 	}
@@ -1286,6 +1464,8 @@ func writeFeedInfo(zw *zip.Writer, feed *Feed) error {
 		return err
 	}
 
+	record := make([]string, len(activeCols))
+
 	// Sort for deterministic output (matches Java behavior of global sorting)
 	feedInfoIDs := make([]string, 0, len(feed.FeedInfos))
 	for id := range feed.FeedInfos {
@@ -1297,7 +1477,6 @@ func writeFeedInfo(zw *zip.Writer, feed *Feed) error {
 		if fi == nil {
 			continue // Skip if feed info was removed
 		}
-		record := make([]string, len(activeCols))
 		for i, col := range activeCols {
 			record[i] = col.getter(fi)
 		}
@@ -1310,13 +1489,8 @@ func writeFeedInfo(zw *zip.Writer, feed *Feed) error {
 }
 
 // writeAreas writes areas.txt
-func writeAreas(zw *zip.Writer, feed *Feed) error {
-	w, err := zw.Create("areas.txt")
-	if err != nil {
-		return err
-	}
-
-	csvw := NewCSVWriter(w)
+func writeAreas(w io.Writer, feed *Feed, opts *WriterOptions) error {
+	csvw := opts.newCSVWriter(w)
 
 	// Define all possible columns in order, with their getters
 	type colDef struct {
@@ -1331,7 +1505,7 @@ func writeAreas(zw *zip.Writer, feed *Feed) error {
 	// Filter to only columns present in source data
 	var activeCols []colDef
 	for _, col := range allCols {
-		if feed.HasColumn("areas.txt", col.name) {
+		if opts.includeColumn("areas.txt", col.name, feed.HasColumn("areas.txt", col.name)) {
 			activeCols = append(activeCols, col)
 		}
 	}
@@ -1345,6 +1519,8 @@ func writeAreas(zw *zip.Writer, feed *Feed) error {
 		return err
 	}
 
+	record := make([]string, len(activeCols))
+
 	// Sort by area ID for deterministic output (matches Java behavior of global sorting)
 	areaIDs := make([]AreaID, 0, len(feed.Areas))
 	for id := range feed.Areas {
@@ -1356,7 +1532,6 @@ func writeAreas(zw *zip.Writer, feed *Feed) error {
 		if a == nil {
 			continue // Skip if area was removed
 		}
-		record := make([]string, len(activeCols))
 		for i, col := range activeCols {
 			record[i] = col.getter(a)
 		}
@@ -1369,13 +1544,8 @@ func writeAreas(zw *zip.Writer, feed *Feed) error {
 }
 
 // writePathways writes pathways.txt
-func writePathways(zw *zip.Writer, feed *Feed) error {
-	w, err := zw.Create("pathways.txt")
-	if err != nil {
-		return err
-	}
-
-	csvw := NewCSVWriter(w)
+func writePathways(w io.Writer, feed *Feed, opts *WriterOptions) error {
+	csvw := opts.newCSVWriter(w)
 
 	// Define all possible columns in order, with their getters
 	type colDef struct {
@@ -1441,9 +1611,8 @@ func writePathways(zw *zip.Writer, feed *Feed) error {
 	// Filter columns: include if required OR (in source AND has non-default value)
 	var activeCols []colDef
 	for _, col := range allCols {
-		if requiredCols[col.name] {
-			activeCols = append(activeCols, col)
-		} else if feed.HasColumn("pathways.txt", col.name) && checker.hasNonDefaultValue(col.name) {
+		include := requiredCols[col.name] || (feed.HasColumn("pathways.txt", col.name) && checker.hasNonDefaultValue(col.name))
+		if opts.includeColumn("pathways.txt", col.name, include) {
 			activeCols = append(activeCols, col)
 		}
 	}
@@ -1457,6 +1626,8 @@ func writePathways(zw *zip.Writer, feed *Feed) error {
 		return err
 	}
 
+	record := make([]string, len(activeCols))
+
 	// Sort pathways by pathway_id for deterministic output
 	sortedPathways := make([]*Pathway, len(feed.Pathways))
 	copy(sortedPathways, feed.Pathways)
@@ -1465,7 +1636,6 @@ func writePathways(zw *zip.Writer, feed *Feed) error {
 	})
 
 	for _, p := range sortedPathways {
-		record := make([]string, len(activeCols))
 		for i, col := range activeCols {
 			record[i] = col.getter(p)
 		}
@@ -1476,3 +1646,407 @@ func writePathways(zw *zip.Writer, feed *Feed) error {
 
 	return csvw.Flush()
 }
+
+// writeNetworks writes networks.txt
+func writeNetworks(w io.Writer, feed *Feed, opts *WriterOptions) error {
+	csvw := opts.newCSVWriter(w)
+
+	// Define all possible columns in order, with their getters
+	type colDef struct {
+		name   string
+		getter func(*Network) string
+	}
+	allCols := []colDef{
+		{"network_id", func(n *Network) string { return string(n.ID) }},
+		{"network_name", func(n *Network) string { return n.Name }},
+	}
+
+	// Filter to only columns present in source data
+	var activeCols []colDef
+	for _, col := range allCols {
+		if opts.includeColumn("networks.txt", col.name, feed.HasColumn("networks.txt", col.name)) {
+			activeCols = append(activeCols, col)
+		}
+	}
+
+	// Build header from active columns
+	header := make([]string, len(activeCols))
+	for i, col := range activeCols {
+		header[i] = col.name
+	}
+	if err := csvw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(activeCols))
+
+	// Sort by network ID for deterministic output
+	networkIDs := make([]NetworkID, 0, len(feed.Networks))
+	for id := range feed.Networks {
+		networkIDs = append(networkIDs, id)
+	}
+	sort.Slice(networkIDs, func(i, j int) bool { return networkIDs[i] < networkIDs[j] })
+	for _, id := range networkIDs {
+		n := feed.Networks[id]
+		if n == nil {
+			continue // Skip if network was removed
+		}
+		for i, col := range activeCols {
+			record[i] = col.getter(n)
+		}
+		if err := csvw.WriteRecord(record); err != nil {
+			return err
+		}
+	}
+
+	return csvw.Flush()
+}
+
+// writeRouteNetworks writes route_networks.txt
+func writeRouteNetworks(w io.Writer, feed *Feed, opts *WriterOptions) error {
+	csvw := opts.newCSVWriter(w)
+
+	// Define all possible columns in order, with their getters; both are
+	// required
+	type colDef struct {
+		name   string
+		getter func(*RouteNetwork) string
+	}
+	allCols := []colDef{
+		{"network_id", func(rn *RouteNetwork) string { return string(rn.NetworkID) }},
+		{"route_id", func(rn *RouteNetwork) string { return string(rn.RouteID) }},
+	}
+
+	var activeCols []colDef
+	for _, col := range allCols {
+		if opts.includeColumn("route_networks.txt", col.name, true) {
+			activeCols = append(activeCols, col)
+		}
+	}
+
+	// Build header from active columns
+	header := make([]string, len(activeCols))
+	for i, col := range activeCols {
+		header[i] = col.name
+	}
+	if err := csvw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(activeCols))
+
+	for _, rn := range feed.RouteNetworks {
+		for i, col := range activeCols {
+			record[i] = col.getter(rn)
+		}
+		if err := csvw.WriteRecord(record); err != nil {
+			return err
+		}
+	}
+
+	return csvw.Flush()
+}
+
+// writeFareLegRules writes fare_leg_rules.txt
+func writeFareLegRules(w io.Writer, feed *Feed, opts *WriterOptions) error {
+	csvw := opts.newCSVWriter(w)
+
+	// Define all possible columns in order, with their getters
+	type colDef struct {
+		name   string
+		getter func(*FareLegRule) string
+	}
+	allCols := []colDef{
+		{"leg_group_id", func(r *FareLegRule) string { return string(r.LegGroupID) }},
+		{"network_id", func(r *FareLegRule) string { return string(r.NetworkID) }},
+		{"from_area_id", func(r *FareLegRule) string { return string(r.FromAreaID) }},
+		{"to_area_id", func(r *FareLegRule) string { return string(r.ToAreaID) }},
+		{"from_timeframe_group_id", func(r *FareLegRule) string { return string(r.FromTimeframeGroupID) }},
+		{"to_timeframe_group_id", func(r *FareLegRule) string { return string(r.ToTimeframeGroupID) }},
+		{"fare_product_id", func(r *FareLegRule) string { return string(r.FareProductID) }},
+		{"rule_priority", func(r *FareLegRule) string { return formatIntPtr(r.RulePriority) }},
+	}
+
+	// Filter columns: include if required OR present in source data
+	var activeCols []colDef
+	for _, col := range allCols {
+		include := col.name == "fare_product_id" || feed.HasColumn("fare_leg_rules.txt", col.name)
+		if opts.includeColumn("fare_leg_rules.txt", col.name, include) {
+			activeCols = append(activeCols, col)
+		}
+	}
+
+	// Build header from active columns
+	header := make([]string, len(activeCols))
+	for i, col := range activeCols {
+		header[i] = col.name
+	}
+	if err := csvw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(activeCols))
+
+	for _, r := range feed.FareLegRules {
+		for i, col := range activeCols {
+			record[i] = col.getter(r)
+		}
+		if err := csvw.WriteRecord(record); err != nil {
+			return err
+		}
+	}
+
+	return csvw.Flush()
+}
+
+// writeFareTransferRules writes fare_transfer_rules.txt
+func writeFareTransferRules(w io.Writer, feed *Feed, opts *WriterOptions) error {
+	csvw := opts.newCSVWriter(w)
+
+	// Define all possible columns in order, with their getters
+	type colDef struct {
+		name   string
+		getter func(*FareTransferRule) string
+	}
+	allCols := []colDef{
+		{"from_leg_group_id", func(r *FareTransferRule) string { return string(r.FromLegGroupID) }},
+		{"to_leg_group_id", func(r *FareTransferRule) string { return string(r.ToLegGroupID) }},
+		{"transfer_count", func(r *FareTransferRule) string { return formatIntPtr(r.TransferCount) }},
+		{"duration_limit", func(r *FareTransferRule) string { return formatIntPtr(r.DurationLimit) }},
+		{"duration_limit_type", func(r *FareTransferRule) string { return formatIntPtr(r.DurationLimitType) }},
+		{"fare_transfer_type", func(r *FareTransferRule) string { return formatInt(r.FareTransferType) }}, // Always output, 0 is valid
+		{"fare_product_id", func(r *FareTransferRule) string { return string(r.FareProductID) }},
+	}
+
+	// Filter columns: include if required OR present in source data
+	var activeCols []colDef
+	for _, col := range allCols {
+		include := col.name == "fare_transfer_type" || feed.HasColumn("fare_transfer_rules.txt", col.name)
+		if opts.includeColumn("fare_transfer_rules.txt", col.name, include) {
+			activeCols = append(activeCols, col)
+		}
+	}
+
+	// Build header from active columns
+	header := make([]string, len(activeCols))
+	for i, col := range activeCols {
+		header[i] = col.name
+	}
+	if err := csvw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(activeCols))
+
+	for _, r := range feed.FareTransferRules {
+		for i, col := range activeCols {
+			record[i] = col.getter(r)
+		}
+		if err := csvw.WriteRecord(record); err != nil {
+			return err
+		}
+	}
+
+	return csvw.Flush()
+}
+
+// writeFareProducts writes fare_products.txt
+func writeFareProducts(w io.Writer, feed *Feed, opts *WriterOptions) error {
+	csvw := opts.newCSVWriter(w)
+
+	// Define all possible columns in order, with their getters
+	type colDef struct {
+		name   string
+		getter func(*FareProduct) string
+	}
+	allCols := []colDef{
+		{"fare_product_id", func(r *FareProduct) string { return string(r.ID) }},
+		{"fare_product_name", func(r *FareProduct) string { return r.Name }},
+		{"rider_category_id", func(r *FareProduct) string { return string(r.RiderCategoryID) }},
+		{"fare_media_id", func(r *FareProduct) string { return string(r.FareMediaID) }},
+		{"amount", func(r *FareProduct) string { return r.Amount }},
+		{"currency", func(r *FareProduct) string { return r.Currency }},
+	}
+
+	// Filter columns: include if required OR present in source data
+	var activeCols []colDef
+	for _, col := range allCols {
+		include := col.name == "fare_product_id" || col.name == "amount" || col.name == "currency" || feed.HasColumn("fare_products.txt", col.name)
+		if opts.includeColumn("fare_products.txt", col.name, include) {
+			activeCols = append(activeCols, col)
+		}
+	}
+
+	// Build header from active columns
+	header := make([]string, len(activeCols))
+	for i, col := range activeCols {
+		header[i] = col.name
+	}
+	if err := csvw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(activeCols))
+
+	for _, r := range feed.FareProducts {
+		for i, col := range activeCols {
+			record[i] = col.getter(r)
+		}
+		if err := csvw.WriteRecord(record); err != nil {
+			return err
+		}
+	}
+
+	return csvw.Flush()
+}
+
+// writeTimeframes writes timeframes.txt
+func writeTimeframes(w io.Writer, feed *Feed, opts *WriterOptions) error {
+	csvw := opts.newCSVWriter(w)
+
+	// Define all possible columns in order, with their getters
+	type colDef struct {
+		name   string
+		getter func(*Timeframe) string
+	}
+	allCols := []colDef{
+		{"timeframe_group_id", func(r *Timeframe) string { return string(r.GroupID) }},
+		{"start_time", func(r *Timeframe) string { return r.StartTime }},
+		{"end_time", func(r *Timeframe) string { return r.EndTime }},
+		{"service_id", func(r *Timeframe) string { return string(r.ServiceID) }},
+	}
+
+	// Filter columns: include if required OR present in source data
+	var activeCols []colDef
+	for _, col := range allCols {
+		include := col.name == "timeframe_group_id" || col.name == "service_id" || feed.HasColumn("timeframes.txt", col.name)
+		if opts.includeColumn("timeframes.txt", col.name, include) {
+			activeCols = append(activeCols, col)
+		}
+	}
+
+	// Build header from active columns
+	header := make([]string, len(activeCols))
+	for i, col := range activeCols {
+		header[i] = col.name
+	}
+	if err := csvw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(activeCols))
+
+	for _, r := range feed.Timeframes {
+		for i, col := range activeCols {
+			record[i] = col.getter(r)
+		}
+		if err := csvw.WriteRecord(record); err != nil {
+			return err
+		}
+	}
+
+	return csvw.Flush()
+}
+
+// writeFareMedia writes fare_media.txt
+func writeFareMedia(w io.Writer, feed *Feed, opts *WriterOptions) error {
+	csvw := opts.newCSVWriter(w)
+
+	// Define all possible columns in order, with their getters
+	type colDef struct {
+		name   string
+		getter func(*FareMedia) string
+	}
+	allCols := []colDef{
+		{"fare_media_id", func(r *FareMedia) string { return string(r.ID) }},
+		{"fare_media_name", func(r *FareMedia) string { return r.Name }},
+		{"fare_media_type", func(r *FareMedia) string { return formatInt(r.Type) }},
+	}
+
+	// Filter columns: include if required OR present in source data
+	var activeCols []colDef
+	for _, col := range allCols {
+		include := col.name == "fare_media_id" || col.name == "fare_media_type" || feed.HasColumn("fare_media.txt", col.name)
+		if opts.includeColumn("fare_media.txt", col.name, include) {
+			activeCols = append(activeCols, col)
+		}
+	}
+
+	// Build header from active columns
+	header := make([]string, len(activeCols))
+	for i, col := range activeCols {
+		header[i] = col.name
+	}
+	if err := csvw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(activeCols))
+
+	for _, id := range feed.FareMediaOrder {
+		r := feed.FareMedia[id]
+		if r == nil {
+			continue // Skip if fare media was removed
+		}
+		for i, col := range activeCols {
+			record[i] = col.getter(r)
+		}
+		if err := csvw.WriteRecord(record); err != nil {
+			return err
+		}
+	}
+
+	return csvw.Flush()
+}
+
+// writeRiderCategories writes rider_categories.txt
+func writeRiderCategories(w io.Writer, feed *Feed, opts *WriterOptions) error {
+	csvw := opts.newCSVWriter(w)
+
+	// Define all possible columns in order, with their getters
+	type colDef struct {
+		name   string
+		getter func(*RiderCategory) string
+	}
+	allCols := []colDef{
+		{"rider_category_id", func(r *RiderCategory) string { return string(r.ID) }},
+		{"rider_category_name", func(r *RiderCategory) string { return r.Name }},
+		{"is_default_fare_category", func(r *RiderCategory) string { return formatIntPtr(r.IsDefaultFareCategory) }},
+		{"eligibility_url", func(r *RiderCategory) string { return r.EligibilityURL }},
+	}
+
+	// Filter columns: include if required OR present in source data
+	var activeCols []colDef
+	for _, col := range allCols {
+		include := col.name == "rider_category_id" || col.name == "rider_category_name" || col.name == "is_default_fare_category" || feed.HasColumn("rider_categories.txt", col.name)
+		if opts.includeColumn("rider_categories.txt", col.name, include) {
+			activeCols = append(activeCols, col)
+		}
+	}
+
+	// Build header from active columns
+	header := make([]string, len(activeCols))
+	for i, col := range activeCols {
+		header[i] = col.name
+	}
+	if err := csvw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(activeCols))
+
+	for _, id := range feed.RiderCategoryOrder {
+		r := feed.RiderCategories[id]
+		if r == nil {
+			continue // Skip if rider category was removed
+		}
+		for i, col := range activeCols {
+			record[i] = col.getter(r)
+		}
+		if err := csvw.WriteRecord(record); err != nil {
+			return err
+		}
+	}
+
+	return csvw.Flush()
+}
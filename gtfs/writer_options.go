@@ -0,0 +1,129 @@
+package gtfs
+
+import (
+	"archive/zip"
+	"fmt"
+	"path"
+	"strconv"
+)
+
+// WriterOptions configures how a feed is written.
+// The zero value reproduces the default writer behavior.
+type WriterOptions struct {
+	// ForceIncludeColumns lists columns, keyed by filename (e.g. "stops.txt"),
+	// that are always emitted even when the default heuristics would drop them
+	// (absent from the source data, or every value is the default).
+	ForceIncludeColumns map[string][]string
+
+	// ExcludeColumns lists columns, keyed by filename, that are never emitted.
+	// Exclusion takes precedence over ForceIncludeColumns and applies to
+	// columns the writer would otherwise always include.
+	ExcludeColumns map[string][]string
+
+	// CoordinatePrecision is the number of decimal places written for stop
+	// and shape point coordinates. Zero keeps the default of 6, matching the
+	// Java output; PreserveCoordinates writes every digit held in memory.
+	// Coordinates are rounded only as they are written.
+	CoordinatePrecision int
+
+	// StripNewlines replaces each line break (CRLF, CR or LF) inside a value
+	// with a space, for consumers that cannot read multi-line records. By
+	// default such values are written quoted, with their line breaks.
+	StripNewlines bool
+
+	// UseCRLF ends each record with CRLF rather than LF, as do the line
+	// breaks written inside quoted values
+	UseCRLF bool
+
+	// QuoteAll quotes every field, header included, rather than only those
+	// that need it
+	QuoteAll bool
+
+	// Compression sets the zip compression method of the files matching
+	// each rule, such as zip.Store for files consumers mmap in place. The
+	// first matching rule applies; other files are deflated.
+	Compression []CompressionRule
+
+	// Checksum also writes, next to a zip written to a path, a sidecar
+	// holding its SHA-256 in the format sha256sum -c reads (see
+	// ChecksumPath), so downstream consumers can verify it
+	Checksum bool
+}
+
+// CompressionRule sets the zip compression method of the files whose names
+// match Pattern
+type CompressionRule struct {
+	// Pattern matches file names as path.Match does, e.g. "stop_times.txt"
+	// or "*.txt"
+	Pattern string
+
+	// Method is zip.Store or zip.Deflate
+	Method uint16
+}
+
+// PreserveCoordinates, as WriterOptions.CoordinatePrecision, writes
+// coordinates with all their digits
+const PreserveCoordinates = -1
+
+// defaultCoordinatePrecision is the number of decimal places the Java merger
+// writes for coordinates
+const defaultCoordinatePrecision = 6
+
+// formatCoordinate formats a latitude or longitude according to
+// CoordinatePrecision. A nil receiver uses the default precision.
+func (o *WriterOptions) formatCoordinate(v float64) string {
+	precision := defaultCoordinatePrecision
+	if o != nil && o.CoordinatePrecision != 0 {
+		precision = o.CoordinatePrecision
+	}
+	if precision < 0 {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(v, 'f', precision, 64)
+}
+
+// includeColumn applies the column overrides for filename to the writer's
+// default decision. Column order is unaffected: callers iterate their
+// canonical column list and only ask whether each column is active.
+// A nil receiver applies no overrides.
+func (o *WriterOptions) includeColumn(filename, column string, defaultInclude bool) bool {
+	if o == nil {
+		return defaultInclude
+	}
+	if containsColumn(o.ExcludeColumns[filename], column) {
+		return false
+	}
+	if containsColumn(o.ForceIncludeColumns[filename], column) {
+		return true
+	}
+	return defaultInclude
+}
+
+// containsColumn reports whether column is in cols
+func containsColumn(cols []string, column string) bool {
+	for _, c := range cols {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// compressionMethod returns the zip compression method of filename: that of
+// the first matching Compression rule, or zip.Deflate. A nil receiver always
+// deflates.
+func (o *WriterOptions) compressionMethod(filename string) (uint16, error) {
+	if o == nil {
+		return zip.Deflate, nil
+	}
+	for _, rule := range o.Compression {
+		matched, err := path.Match(rule.Pattern, filename)
+		if err != nil {
+			return 0, fmt.Errorf("compression pattern %q: %w", rule.Pattern, err)
+		}
+		if matched {
+			return rule.Method, nil
+		}
+	}
+	return zip.Deflate, nil
+}
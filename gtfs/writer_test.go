@@ -2,8 +2,19 @@ package gtfs
 
 import (
 	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"os"
+	"path"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
 	"testing"
 )
 
@@ -286,6 +297,32 @@ func TestWriteEmptyFeed(t *testing.T) {
 	}
 }
 
+// TestWriteZeroValueFeed verifies that a feed not built via NewFeed can be written
+// and that nil optional collections produce no optional files instead of panicking
+func TestWriteZeroValueFeed(t *testing.T) {
+	feed := &Feed{
+		Agencies: map[AgencyID]*Agency{
+			"agency1": {ID: "agency1", Name: "Test Agency", URL: "http://example.com", Timezone: "UTC"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteToZip(feed, &buf); err != nil {
+		t.Fatalf("WriteToZip failed: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("failed to open written zip: %v", err)
+	}
+	for _, f := range zr.File {
+		switch f.Name {
+		case "feed_info.txt", "areas.txt", "shapes.txt", "calendar_dates.txt", "fare_attributes.txt":
+			t.Errorf("did not expect %s for nil collection", f.Name)
+		}
+	}
+}
+
 // TestWriteAllOptionalFiles verifies that optional files are written when present
 func TestWriteAllOptionalFiles(t *testing.T) {
 	feed := NewFeed()
@@ -341,7 +378,7 @@ func TestWriteAllOptionalFiles(t *testing.T) {
 	)
 	feed.Shapes[ShapeID("shape1")] = append(
 		feed.Shapes[ShapeID("shape1")],
-		&ShapePoint{ShapeID: ShapeID("shape1"), Lat: 47.0, Lon: -122.0, Sequence: 1},
+		ShapePoint{ShapeID: ShapeID("shape1"), Lat: 47.0, Lon: -122.0, Sequence: 1},
 	)
 	feed.Frequencies = append(feed.Frequencies, &Frequency{
 		TripID:      TripID("trip1"),
@@ -524,3 +561,825 @@ func TestWriteSkipsEmptyOptionalFiles(t *testing.T) {
 		}
 	}
 }
+
+// readZipHeader returns the header row of filename within the zip in buf
+func readZipHeader(t *testing.T, buf *bytes.Buffer, filename string) string {
+	t.Helper()
+	return strings.SplitN(readZipFile(t, buf, filename), "\n", 2)[0]
+}
+
+// readZipFile returns the contents of filename in the zip written to buf
+func readZipFile(t *testing.T, buf *bytes.Buffer, filename string) string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("failed to open written zip: %v", err)
+	}
+	for _, f := range zr.File {
+		if f.Name != filename {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", filename, err)
+		}
+		defer func() { _ = rc.Close() }()
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", filename, err)
+		}
+		return string(data)
+	}
+	t.Fatalf("%s not found in zip", filename)
+	return ""
+}
+
+// TestWriteWithForceIncludeColumns verifies that force-included columns are
+// emitted in canonical order even when every value is the default
+func TestWriteWithForceIncludeColumns(t *testing.T) {
+	// Given: stops whose wheelchair_boarding values are all default
+	feed := NewFeed()
+	feed.Stops["s1"] = &Stop{ID: "s1", Name: "Stop 1", Lat: 47.6, Lon: -122.3}
+
+	// When: writing with wheelchair_boarding force-included
+	var buf bytes.Buffer
+	opts := WriterOptions{
+		ForceIncludeColumns: map[string][]string{"stops.txt": {"wheelchair_boarding", "stop_code"}},
+	}
+	if err := WriteToZipWithOptions(feed, &buf, opts); err != nil {
+		t.Fatalf("WriteToZipWithOptions failed: %v", err)
+	}
+
+	// Then: the columns are present in canonical order
+	header := readZipHeader(t, &buf, "stops.txt")
+	expected := "stop_id,stop_name,stop_lat,stop_lon,stop_code,wheelchair_boarding"
+	if header != expected {
+		t.Errorf("Expected header %q, got %q", expected, header)
+	}
+}
+
+// TestWriteWithExcludeColumns verifies that excluded columns are dropped,
+// including columns the writer would otherwise emit
+func TestWriteWithExcludeColumns(t *testing.T) {
+	// Given: a fare attribute with populated transfer_duration, youth_price
+	// and senior_price
+	feed := NewFeed()
+	feed.FareAttributes["f1"] = &FareAttribute{
+		FareID:           "f1",
+		Price:            2.5,
+		CurrencyType:     "USD",
+		TransferDuration: intPtr(3600),
+		YouthPrice:       floatPtr(1.25),
+		SeniorPrice:      floatPtr(1.00),
+	}
+	feed.AddColumn("fare_attributes.txt", "youth_price")
+	feed.AddColumn("fare_attributes.txt", "senior_price")
+
+	// When: writing with the populated youth_price and a force-included
+	// transfer_duration both excluded
+	var buf bytes.Buffer
+	opts := WriterOptions{
+		ExcludeColumns:      map[string][]string{"fare_attributes.txt": {"transfer_duration", "youth_price"}},
+		ForceIncludeColumns: map[string][]string{"fare_attributes.txt": {"transfer_duration"}},
+	}
+	if err := WriteToZipWithOptions(feed, &buf, opts); err != nil {
+		t.Fatalf("WriteToZipWithOptions failed: %v", err)
+	}
+
+	// Then: neither column is written; exclusion wins over force-include
+	header := readZipHeader(t, &buf, "fare_attributes.txt")
+	if !strings.Contains(header, "senior_price") {
+		t.Errorf("Expected senior_price to remain, got header %q", header)
+	}
+	for _, col := range strings.Split(header, ",") {
+		if col == "transfer_duration" || col == "youth_price" {
+			t.Errorf("Expected %s to be excluded, got header %q", col, header)
+		}
+	}
+}
+
+// TestWriteOptionalTransferColumns verifies that min_transfer_time keeps an
+// explicit 0 distinct from an unset value, and is omitted when no transfer
+// has one
+func TestWriteOptionalTransferColumns(t *testing.T) {
+	// Given: transfers with min_transfer_time 180, 0 and unset, as when only
+	// some merged inputs had the column
+	feed := NewFeed()
+	feed.Transfers = []*Transfer{
+		{FromStopID: "a", ToStopID: "b", TransferType: 2, MinTransferTime: intPtr(180)},
+		{FromStopID: "b", ToStopID: "c", TransferType: 2, MinTransferTime: intPtr(0)},
+		{FromStopID: "c", ToStopID: "d", TransferType: 1},
+	}
+
+	// When: written
+	var buf bytes.Buffer
+	if err := WriteToZip(feed, &buf); err != nil {
+		t.Fatalf("WriteToZip failed: %v", err)
+	}
+
+	// Then: 0 is written as "0" and the unset value as an empty cell
+	got := readZipFile(t, &buf, "transfers.txt")
+	for _, want := range []string{"a,,b,,2,180,,\n", "b,,c,,2,0,,\n", "c,,d,,1,,,\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected transfers.txt to contain %q, got:\n%s", want, got)
+		}
+	}
+
+	// Given: no transfer has a min_transfer_time
+	feed.Transfers[0].MinTransferTime = nil
+	feed.Transfers[1].MinTransferTime = nil
+
+	// When: written
+	buf.Reset()
+	if err := WriteToZip(feed, &buf); err != nil {
+		t.Fatalf("WriteToZip failed: %v", err)
+	}
+
+	// Then: the column is omitted
+	if header := readZipHeader(t, &buf, "transfers.txt"); strings.Contains(header, "min_transfer_time") {
+		t.Errorf("Expected min_transfer_time to be omitted, got header %q", header)
+	}
+}
+
+// TestWriteContinuousStoppingRoundTrip verifies that continuous_pickup and
+// continuous_drop_off keep an explicit 0 (continuous stopping) distinct from
+// an unset value, which GTFS reads as 1 (none)
+func TestWriteContinuousStoppingRoundTrip(t *testing.T) {
+	// Given: routes and stop_times with continuous stopping 0, 1 and unset
+	feed, err := ReadFromPath("../testdata/minimal")
+	if err != nil {
+		t.Fatalf("failed to read feed: %v", err)
+	}
+	for _, file := range []string{"routes.txt", "stop_times.txt"} {
+		feed.AddColumn(file, "continuous_pickup")
+		feed.AddColumn(file, "continuous_drop_off")
+	}
+	feed.Routes["route1"].ContinuousPickup = intPtr(0)
+	feed.Routes["route1"].ContinuousDropOff = intPtr(1)
+	feed.AddRoute(&Route{ID: "route2", AgencyID: "agency1", ShortName: "2", Type: 3})
+	feed.StopTimes[0].ContinuousPickup = intPtr(0)
+	feed.StopTimes = append(feed.StopTimes, &StopTime{
+		TripID: "trip1", ArrivalTime: "08:10:00", DepartureTime: "08:10:00", StopID: "stop1", StopSequence: 2,
+		ContinuousDropOff: intPtr(0),
+	})
+
+	// When: written and read back
+	var buf bytes.Buffer
+	if err := WriteToZip(feed, &buf); err != nil {
+		t.Fatalf("WriteToZip failed: %v", err)
+	}
+	routes := readZipFile(t, bytes.NewBuffer(buf.Bytes()), "routes.txt")
+	stopTimes := readZipFile(t, bytes.NewBuffer(buf.Bytes()), "stop_times.txt")
+	got, err := ReadFromZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("ReadFromZip failed: %v", err)
+	}
+
+	// Then: 0 is written as "0" and the unset value as an empty cell
+	for _, want := range []string{"route1,1,Main Line,3,0,1\n", "route2,2,,3,,\n"} {
+		if !strings.Contains(routes, want) {
+			t.Errorf("Expected routes.txt to contain %q, got:\n%s", want, routes)
+		}
+	}
+	for _, want := range []string{"08:00:00,08:00:00,1,0,\n", "08:10:00,08:10:00,2,,0\n"} {
+		if !strings.Contains(stopTimes, want) {
+			t.Errorf("Expected stop_times.txt to contain %q, got:\n%s", want, stopTimes)
+		}
+	}
+
+	// And: every value reads back as written
+	r1, r2 := got.Routes["route1"], got.Routes["route2"]
+	if formatIntPtr(r1.ContinuousPickup) != "0" || formatIntPtr(r1.ContinuousDropOff) != "1" ||
+		r2.ContinuousPickup != nil || r2.ContinuousDropOff != nil {
+		t.Errorf("Expected route continuous stopping 0/1 and unset, got %v/%v and %v/%v",
+			r1.ContinuousPickup, r1.ContinuousDropOff, r2.ContinuousPickup, r2.ContinuousDropOff)
+	}
+	st1, st2 := got.StopTimes[0], got.StopTimes[1]
+	if formatIntPtr(st1.ContinuousPickup) != "0" || st1.ContinuousDropOff != nil ||
+		st2.ContinuousPickup != nil || formatIntPtr(st2.ContinuousDropOff) != "0" {
+		t.Errorf("Expected stop_time continuous stopping 0/unset and unset/0, got %v/%v and %v/%v",
+			st1.ContinuousPickup, st1.ContinuousDropOff, st2.ContinuousPickup, st2.ContinuousDropOff)
+	}
+}
+
+// TestWriteOptionalFareAttributeColumns verifies that transfers,
+// transfer_duration, youth_price and senior_price are written empty only for
+// fares without a value, and that the optional columns are omitted when no
+// fare has a value
+func TestWriteOptionalFareAttributeColumns(t *testing.T) {
+	// Given: a fare with no transfers, a youth price and a transfer duration,
+	// and a fare with unlimited transfers and none of the optional values
+	feed := NewFeed()
+	for _, col := range []string{"transfers", "transfer_duration", "youth_price", "senior_price"} {
+		feed.AddColumn("fare_attributes.txt", col)
+	}
+	feed.FareAttributes["f1"] = &FareAttribute{
+		FareID: "f1", Price: 2.5, CurrencyType: "USD", Transfers: intPtr(0),
+		TransferDuration: intPtr(0), YouthPrice: floatPtr(1.25),
+	}
+	feed.FareAttributes["f2"] = &FareAttribute{FareID: "f2", Price: 3, CurrencyType: "USD"}
+
+	// When: written
+	var buf bytes.Buffer
+	if err := WriteToZip(feed, &buf); err != nil {
+		t.Fatalf("WriteToZip failed: %v", err)
+	}
+
+	// Then: senior_price, which no fare has, is omitted, and the other
+	// optional values are empty only for the fare without them
+	got := readZipFile(t, &buf, "fare_attributes.txt")
+	for _, want := range []string{
+		"fare_id,price,currency_type,payment_method,transfers,agency_id,transfer_duration,youth_price\n",
+		"f1,2.500000,USD,0,0,,0,1.250000\n",
+		"f2,3.000000,USD,0,,,,\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected fare_attributes.txt to contain %q, got:\n%s", want, got)
+		}
+	}
+}
+
+// TestWriteQuotedFieldsRoundTrip verifies that names, descriptions and
+// headsigns containing commas, double quotes, CR and LF are quoted on write
+// and read back unchanged
+func TestWriteQuotedFieldsRoundTrip(t *testing.T) {
+	// Given: a feed whose text fields contain every character needing quotes
+	feed, err := ReadFromPath("../testdata/quoted_fields_feed")
+	if err != nil {
+		t.Fatalf("failed to read feed: %v", err)
+	}
+	values := map[string]string{
+		"agency_name":     feed.Agencies["qa"].Name,
+		"route_long_name": feed.Routes["r1"].LongName,
+		"stop_name":       feed.Stops["s2"].Name,
+		"stop_desc":       feed.Stops["s1"].Desc,
+		"stop_desc (CR)":  feed.Stops["s2"].Desc,
+		"leading space":   feed.Stops["s3"].Name,
+		"trip_headsign":   feed.Trips["t1"].Headsign,
+		"stop_headsign":   feed.StopTimes[0].StopHeadsign,
+	}
+	expected := map[string]string{
+		"agency_name":     "Quotes, Commas & Co.",
+		"route_long_name": `The "Express" Line`,
+		"stop_name":       `O'Hare "Blue"`,
+		"stop_desc":       "Upper level.\nUse the east stairs.",
+		"stop_desc (CR)":  "Bay 3\rCarriage return",
+		"leading space":   " Leading Space",
+		"trip_headsign":   "Airport, via \"Loop\"\nExpress",
+		"stop_headsign":   "To \"O'Hare\",\nlast stop",
+	}
+	for field, want := range expected {
+		if values[field] != want {
+			t.Errorf("Expected %s %q as read, got %q", field, want, values[field])
+		}
+	}
+
+	// When: written and read back
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.zip")
+	if err := WriteToPath(feed, path); err != nil {
+		t.Fatalf("WriteToPath failed: %v", err)
+	}
+	written, err := ReadFromPath(path)
+	if err != nil {
+		t.Fatalf("failed to read written feed: %v", err)
+	}
+
+	// Then: every value is unchanged
+	got := map[string]string{
+		"agency_name":     written.Agencies["qa"].Name,
+		"route_long_name": written.Routes["r1"].LongName,
+		"stop_name":       written.Stops["s2"].Name,
+		"stop_desc":       written.Stops["s1"].Desc,
+		"stop_desc (CR)":  written.Stops["s2"].Desc,
+		"leading space":   written.Stops["s3"].Name,
+		"trip_headsign":   written.Trips["t1"].Headsign,
+		"stop_headsign":   written.StopTimes[0].StopHeadsign,
+	}
+	for field, want := range expected {
+		if got[field] != want {
+			t.Errorf("Expected %s %q after a round trip, got %q", field, want, got[field])
+		}
+	}
+}
+
+// TestWriteStripNewlines verifies that StripNewlines writes each record on
+// one line, with line breaks inside values replaced by spaces
+func TestWriteStripNewlines(t *testing.T) {
+	// Given: a feed with multi-line descriptions and headsigns
+	feed, err := ReadFromPath("../testdata/quoted_fields_feed")
+	if err != nil {
+		t.Fatalf("failed to read feed: %v", err)
+	}
+
+	// When: written with StripNewlines
+	var buf bytes.Buffer
+	if err := WriteToZipWithOptions(feed, &buf, WriterOptions{StripNewlines: true}); err != nil {
+		t.Fatalf("WriteToZipWithOptions failed: %v", err)
+	}
+
+	// Then: each file has one line per record plus the header
+	for filename, rows := range map[string]int{"stops.txt": 3, "trips.txt": 1, "stop_times.txt": 3} {
+		content := readZipFile(t, &buf, filename)
+		if strings.Contains(content, "\r") {
+			t.Errorf("Expected no CR in %s, got %q", filename, content)
+		}
+		if lines := strings.Count(content, "\n"); lines != rows+1 {
+			t.Errorf("Expected %d lines in %s, got %d: %q", rows+1, filename, lines, content)
+		}
+	}
+
+	// And: line breaks became spaces, and other quoting is kept
+	stops := readZipFile(t, &buf, "stops.txt")
+	for _, want := range []string{"Upper level. Use the east stairs.\n", "Bay 3 Carriage return\n", `"O'Hare ""Blue"""`} {
+		if !strings.Contains(stops, want) {
+			t.Errorf("Expected stops.txt to contain %q, got:\n%s", want, stops)
+		}
+	}
+	if trips := readZipFile(t, &buf, "trips.txt"); !strings.Contains(trips, `"Airport, via ""Loop"" Express"`) {
+		t.Errorf("Expected the headsign on one line, got:\n%s", trips)
+	}
+}
+
+// TestWriteLineEndingsAndQuotingRoundTrip verifies that feeds written with
+// UseCRLF and QuoteAll read back unchanged
+func TestWriteLineEndingsAndQuotingRoundTrip(t *testing.T) {
+	feed, err := ReadFromPath("../testdata/quoted_fields_feed")
+	if err != nil {
+		t.Fatalf("failed to read feed: %v", err)
+	}
+	for name, opts := range map[string]WriterOptions{
+		"crlf":      {UseCRLF: true},
+		"quote all": {QuoteAll: true},
+		"both":      {UseCRLF: true, QuoteAll: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteToZipWithOptions(feed, &buf, opts); err != nil {
+				t.Fatalf("WriteToZipWithOptions failed: %v", err)
+			}
+			if stops := readZipFile(t, &buf, "stops.txt"); opts.UseCRLF != strings.HasSuffix(stops, "\r\n") || opts.QuoteAll != strings.HasPrefix(stops, `"`) {
+				t.Errorf("Expected stops.txt written with %+v, got %q", opts, stops)
+			}
+
+			written, err := ReadFromZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				t.Fatalf("failed to read written feed: %v", err)
+			}
+			if !reflect.DeepEqual(written.RowCounts(), feed.RowCounts()) {
+				t.Errorf("Expected %v rows, got %v", feed.RowCounts(), written.RowCounts())
+			}
+			for id, stop := range feed.Stops {
+				if got := written.Stops[id]; got == nil || got.Name != stop.Name || got.Desc != stop.Desc {
+					t.Errorf("Expected stop %s %q/%q after a round trip, got %+v", id, stop.Name, stop.Desc, got)
+				}
+			}
+			if got, want := written.Trips["t1"].Headsign, feed.Trips["t1"].Headsign; got != want {
+				t.Errorf("Expected trip_headsign %q after a round trip, got %q", want, got)
+			}
+		})
+	}
+}
+
+// TestWriteNetworksRoundTrip verifies that networks.txt, route_networks.txt
+// and the network_id column of routes.txt survive a write and read
+func TestWriteNetworksRoundTrip(t *testing.T) {
+	// Given: a feed with networks, and a route using network_id
+	feed, err := ReadFromPath("../testdata/networks")
+	if err != nil {
+		t.Fatalf("failed to read feed: %v", err)
+	}
+	feed.AddColumn("routes.txt", "network_id")
+	feed.Routes["local"].NetworkID = "standard"
+
+	// When: written and read back
+	var buf bytes.Buffer
+	if err := WriteToZip(feed, &buf); err != nil {
+		t.Fatalf("WriteToZip failed: %v", err)
+	}
+	if header := readZipHeader(t, bytes.NewBuffer(buf.Bytes()), "route_networks.txt"); header != "network_id,route_id" {
+		t.Errorf("Expected route_networks.txt header network_id,route_id, got %q", header)
+	}
+	got, err := ReadFromZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("ReadFromZip failed: %v", err)
+	}
+
+	// Then: every network value is kept
+	if len(got.Networks) != 2 || got.Networks["premium"].Name != "Premium Fare" {
+		t.Errorf("Expected both networks, got %v", got.Networks)
+	}
+	if len(got.RouteNetworks) != 2 || *got.RouteNetworks[1] != (RouteNetwork{NetworkID: "premium", RouteID: "express"}) {
+		t.Errorf("Expected both route_networks, got %v", got.RouteNetworks)
+	}
+	if got.Routes["local"].NetworkID != "standard" || got.Routes["express"].NetworkID != "" {
+		t.Errorf("Expected network_id only on local, got %q and %q", got.Routes["local"].NetworkID, got.Routes["express"].NetworkID)
+	}
+}
+
+// TestWriteFaresV2RoundTrip verifies that fare_leg_rules.txt,
+// fare_transfer_rules.txt and the fare products, timeframes, fare media and
+// rider categories they refer to survive a write and read
+func TestWriteFaresV2RoundTrip(t *testing.T) {
+	// Given: a feed with fare leg and transfer rules
+	feed, err := ReadFromPath("../testdata/fares_v2")
+	if err != nil {
+		t.Fatalf("failed to read feed: %v", err)
+	}
+
+	// When: written and read back
+	var buf bytes.Buffer
+	if err := WriteToZip(feed, &buf); err != nil {
+		t.Fatalf("WriteToZip failed: %v", err)
+	}
+	if header := readZipHeader(t, bytes.NewBuffer(buf.Bytes()), "fare_leg_rules.txt"); header != "leg_group_id,network_id,from_timeframe_group_id,fare_product_id" {
+		t.Errorf("Expected fare_leg_rules.txt header leg_group_id,network_id,from_timeframe_group_id,fare_product_id, got %q", header)
+	}
+	got, err := ReadFromZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("ReadFromZip failed: %v", err)
+	}
+
+	// Then: every rule is kept
+	wantLeg := FareLegRule{LegGroupID: "express_leg", NetworkID: "premium", FromTimeframeGroupID: "peak", FareProductID: "express_ride"}
+	if len(got.FareLegRules) != 2 || *got.FareLegRules[1] != wantLeg {
+		t.Errorf("Expected both fare_leg_rules, got %v", got.FareLegRules)
+	}
+	if len(got.FareTransferRules) != 2 {
+		t.Fatalf("Expected both fare_transfer_rules, got %v", got.FareTransferRules)
+	}
+	rule := got.FareTransferRules[0]
+	if rule.FromLegGroupID != "local_leg" || rule.ToLegGroupID != "express_leg" ||
+		rule.DurationLimit == nil || *rule.DurationLimit != 5400 || rule.TransferCount != nil {
+		t.Errorf("Expected local_leg to express_leg within 5400s, got %+v", rule)
+	}
+
+	// And: so is every fare product row, timeframe, fare media and rider
+	// category, amounts as written
+	wantProduct := FareProduct{ID: "single_ride", Name: "Single Ride", RiderCategoryID: "senior", FareMediaID: "card", Amount: "1.00", Currency: "USD"}
+	if len(got.FareProducts) != 3 || *got.FareProducts[1] != wantProduct {
+		t.Errorf("Expected 3 fare_products with %+v second, got %v", wantProduct, got.FareProducts)
+	}
+	wantTimeframe := Timeframe{GroupID: "peak", StartTime: "06:00:00", EndTime: "09:00:00", ServiceID: "weekday"}
+	if len(got.Timeframes) != 1 || *got.Timeframes[0] != wantTimeframe {
+		t.Errorf("Expected timeframe %+v, got %v", wantTimeframe, got.Timeframes)
+	}
+	if media := got.FareMedia["card"]; media == nil || media.Type != 2 || media.Name != "Transit Card" {
+		t.Errorf("Expected fare media card of type 2, got %+v", media)
+	}
+	if senior := got.RiderCategories["senior"]; len(got.RiderCategoryOrder) != 2 || senior == nil ||
+		senior.IsDefaultFareCategory == nil || *senior.IsDefaultFareCategory != 0 {
+		t.Errorf("Expected rider categories adult and senior, got %v", got.RiderCategories)
+	}
+}
+
+// TestWriteCoordinatePrecision verifies that coordinates are rounded to the
+// configured precision on write, leaving the feed's values untouched
+func TestWriteCoordinatePrecision(t *testing.T) {
+	// Given: a shape with 13-decimal coordinates
+	feed := NewFeed()
+	for i := 0; i < 100; i++ {
+		feed.AddShape(ShapePoint{
+			ShapeID:  "s1",
+			Lat:      47.6062095123456 + float64(i)*0.0001234567891,
+			Lon:      -122.3320708123456 - float64(i)*0.0001234567891,
+			Sequence: i + 1,
+		})
+	}
+
+	write := func(precision int) string {
+		var buf bytes.Buffer
+		if err := WriteToZipWithOptions(feed, &buf, WriterOptions{CoordinatePrecision: precision}); err != nil {
+			t.Fatalf("WriteToZipWithOptions failed: %v", err)
+		}
+		return readZipFile(t, &buf, "shapes.txt")
+	}
+
+	// When: written with every digit, the default and 6 decimals
+	full, def, six := write(PreserveCoordinates), write(0), write(6)
+
+	// Then: full precision round-trips every digit
+	if !strings.Contains(full, "47.6062095123456,-122.3320708123456") {
+		t.Errorf("Expected full-precision coordinates, got %q", strings.SplitN(full, "\n", 3)[1])
+	}
+
+	// And: 6 decimals is the default and rounds the coordinates
+	if def != six {
+		t.Error("Expected the default precision to be 6 decimals")
+	}
+	if !strings.Contains(six, "47.606210,-122.332071") {
+		t.Errorf("Expected coordinates rounded to 6 decimals, got %q", strings.SplitN(six, "\n", 3)[1])
+	}
+
+	// And: rounding shrinks shapes.txt substantially
+	if len(six) > len(full)*85/100 {
+		t.Errorf("Expected 6-decimal shapes.txt to be at least 15%% smaller: %d vs %d bytes", len(six), len(full))
+	}
+
+	// And: the in-memory coordinates keep full precision
+	if got := feed.Shapes["s1"][0].Lat; got != 47.6062095123456 {
+		t.Errorf("Expected in-memory latitude to be unchanged, got %v", got)
+	}
+}
+
+func TestWriteToZipContextCanceled(t *testing.T) {
+	// Given: a context that is already canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// When: writing a feed
+	var buf bytes.Buffer
+	err := WriteToZipContext(ctx, NewFeed(), &buf, WriterOptions{})
+
+	// Then: the cancellation is returned with the file being written
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "writing agency.txt") {
+		t.Errorf("expected error to name agency.txt, got %v", err)
+	}
+}
+
+func TestWriteToPathContextFailureKeepsExistingFile(t *testing.T) {
+	// Given: an existing file at the output path
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "feed.zip")
+	if err := os.WriteFile(path, []byte("original"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// When: a write fails partway (canceled)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := WriteToPathContext(ctx, NewFeed(), path, WriterOptions{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	// Then: the existing file is untouched and no temp file is left behind
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "original" {
+		t.Errorf("expected original contents to survive, got %q (%v)", data, err)
+	}
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only feed.zip in output directory, got %d entries", len(entries))
+	}
+}
+
+func TestWriteToPathReplacesExistingFile(t *testing.T) {
+	// Given: an existing file at the output path
+	path := filepath.Join(t.TempDir(), "feed.zip")
+	if err := os.WriteFile(path, []byte("original"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// When: a feed is written there
+	if err := WriteToPath(NewFeed(), path); err != nil {
+		t.Fatalf("WriteToPath failed: %v", err)
+	}
+
+	// Then: it is replaced by a valid zip with the same permissions
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("expected a valid zip, got %v", err)
+	}
+	_ = zr.Close()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected mode 0600 to be kept, got %v", info.Mode().Perm())
+	}
+}
+
+// faultyFile is an output file that loses the bytes written past limit,
+// as storage does on a power loss, while reporting them written, and
+// fails Sync with syncErr
+type faultyFile struct {
+	*os.File
+	limit   int64
+	written int64
+	syncErr error
+}
+
+// Write implements io.Writer
+func (f *faultyFile) Write(p []byte) (int, error) {
+	keep := min(int64(len(p)), max(f.limit-f.written, 0))
+	f.written += int64(len(p))
+	if _, err := f.File.Write(p[:keep]); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync returns syncErr, or syncs the file
+func (f *faultyFile) Sync() error {
+	if f.syncErr != nil {
+		return f.syncErr
+	}
+	return f.File.Sync()
+}
+
+// injectFaults makes WriteToPathContext write through a faultyFile set up
+// by configure, until the test ends
+func injectFaults(t *testing.T, configure func(*faultyFile)) {
+	t.Helper()
+	create := createOutputFile
+	createOutputFile = func(dir, pattern string) (outputFile, error) {
+		f, err := os.CreateTemp(dir, pattern)
+		if err != nil {
+			return nil, err
+		}
+		faulty := &faultyFile{File: f, limit: math.MaxInt64}
+		configure(faulty)
+		return faulty, nil
+	}
+	t.Cleanup(func() { createOutputFile = create })
+}
+
+func TestWriteToPathFaults(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*faultyFile)
+		want      error
+	}{
+		{"lost tail", func(f *faultyFile) { f.limit = 100 }, ErrOutputVerification},
+		{"sync fails", func(f *faultyFile) { f.syncErr = syscall.EIO }, syscall.EIO},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: an existing output, and storage that fails
+			tmpDir := t.TempDir()
+			path := filepath.Join(tmpDir, "feed.zip")
+			if err := os.WriteFile(path, []byte("original"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			injectFaults(t, tt.configure)
+
+			// When: a feed is written there
+			err := WriteToPathWithOptions(NewFeed(), path, WriterOptions{Checksum: true})
+
+			// Then: the write fails, leaving the existing output and
+			// nothing else
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil || string(data) != "original" {
+				t.Errorf("expected original contents to survive, got %q (%v)", data, err)
+			}
+			entries, err := os.ReadDir(tmpDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("expected only feed.zip in output directory, got %d entries", len(entries))
+			}
+		})
+	}
+}
+
+func TestWriteToPathChecksum(t *testing.T) {
+	// Given: a feed written with a checksum
+	path := filepath.Join(t.TempDir(), "feed.zip")
+	if err := WriteToPathWithOptions(NewFeed(), path, WriterOptions{Checksum: true}); err != nil {
+		t.Fatalf("WriteToPathWithOptions failed: %v", err)
+	}
+
+	// Then: the sidecar holds the output's SHA-256 and name
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sidecar, err := os.ReadFile(ChecksumPath(path))
+	if err != nil {
+		t.Fatalf("expected a sidecar: %v", err)
+	}
+	if want := fmt.Sprintf("%x  feed.zip\n", sha256.Sum256(data)); string(sidecar) != want {
+		t.Errorf("expected sidecar %q, got %q", want, sidecar)
+	}
+
+	// And: without the option none is written
+	other := filepath.Join(t.TempDir(), "feed.zip")
+	if err := WriteToPath(NewFeed(), other); err != nil {
+		t.Fatalf("WriteToPath failed: %v", err)
+	}
+	if _, err := os.Stat(ChecksumPath(other)); !os.IsNotExist(err) {
+		t.Errorf("expected no sidecar, got %v", err)
+	}
+}
+
+// TestWriteCompression verifies that files matching a Compression rule are
+// written with its method, other files deflated, and that the mixed archive
+// reads back
+func TestWriteCompression(t *testing.T) {
+	// Given: a feed and rules storing stop_times.txt and the calendar files
+	feed, err := ReadFromPath("../testdata/simple_a")
+	if err != nil {
+		t.Fatalf("ReadFromPath failed: %v", err)
+	}
+	options := WriterOptions{Compression: []CompressionRule{
+		{Pattern: "stop_times.txt", Method: zip.Store},
+		{Pattern: "calendar*.txt", Method: zip.Store},
+	}}
+
+	// When: written to a zip
+	zipPath := filepath.Join(t.TempDir(), "feed.zip")
+	if err := WriteToPathWithOptions(feed, zipPath, options); err != nil {
+		t.Fatalf("WriteToPathWithOptions failed: %v", err)
+	}
+
+	// Then: each file has the requested method
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		t.Fatalf("failed to open written zip: %v", err)
+	}
+	defer func() { _ = zr.Close() }()
+	for _, f := range zr.File {
+		want := zip.Deflate
+		if f.Name == "stop_times.txt" || f.Name == "calendar.txt" {
+			want = zip.Store
+		}
+		if f.Method != want {
+			t.Errorf("Expected %s to use method %d, got %d", f.Name, want, f.Method)
+		}
+	}
+
+	// And: the archive reads back
+	got, err := ReadFromPath(zipPath)
+	if err != nil {
+		t.Fatalf("ReadFromPath failed: %v", err)
+	}
+	if !reflect.DeepEqual(got.RowCounts(), feed.RowCounts()) {
+		t.Errorf("Expected row counts %v, got %v", feed.RowCounts(), got.RowCounts())
+	}
+
+	// When: a pattern is malformed
+	options.Compression = []CompressionRule{{Pattern: "[", Method: zip.Store}}
+
+	// Then: writing fails
+	var buf bytes.Buffer
+	if err := WriteToZipWithOptions(feed, &buf, options); !errors.Is(err, path.ErrBadPattern) {
+		t.Errorf("Expected path.ErrBadPattern, got %v", err)
+	}
+}
+
+// TestWriteFile verifies that a single table is written exactly as it
+// appears inside the zip
+func TestWriteFile(t *testing.T) {
+	feed, err := ReadFromPath("../testdata/simple_a")
+	if err != nil {
+		t.Fatalf("ReadFromPath failed: %v", err)
+	}
+
+	var zipBuf bytes.Buffer
+	if err := WriteToZip(feed, &zipBuf); err != nil {
+		t.Fatalf("WriteToZip failed: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(zipBuf.Bytes()), int64(zipBuf.Len()))
+	if err != nil {
+		t.Fatalf("failed to open written zip: %v", err)
+	}
+
+	for _, filename := range []string{"stop_times.txt", "stops.txt", "calendar.txt"} {
+		var fileBuf bytes.Buffer
+		if err := WriteFile(feed, filename, &fileBuf); err != nil {
+			t.Fatalf("WriteFile(%s) failed: %v", filename, err)
+		}
+
+		rc, err := zr.Open(filename)
+		if err != nil {
+			t.Fatalf("%s missing from zip: %v", filename, err)
+		}
+		fromZip, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", filename, err)
+		}
+
+		if fileBuf.String() != string(fromZip) {
+			t.Errorf("%s differs from zip entry:\n%s\nvs\n%s", filename, fileBuf.String(), fromZip)
+		}
+	}
+}
+
+// TestWriteFileUnknown verifies that unsupported file names are rejected
+func TestWriteFileUnknown(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteFile(NewFeed(), "bogus.txt", &buf)
+	if !errors.Is(err, ErrUnknownFile) {
+		t.Errorf("expected ErrUnknownFile, got %v", err)
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
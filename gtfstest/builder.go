@@ -0,0 +1,342 @@
+// Package gtfstest builds GTFS feeds in code, for tests and for
+// synthesizing fixtures. A FeedBuilder takes only the fields that matter to
+// the caller and fills in the rest, including entities that are referenced
+// but never declared, so that the feed it builds passes gtfs.Feed.Validate:
+//
+//	feed := gtfstest.NewFeedBuilder().
+//		Agency("a1").
+//		Route("r1").
+//		Trip("t1", "r1", "svc1").
+//		StopTimes("t1", "08:00", "s1", "s2", "s3").
+//		MustBuild()
+package gtfstest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// Defaults for the fields a FeedBuilder fills in
+const (
+	// DefaultAgencyID is the ID of the agency added to a feed that declares
+	// none
+	DefaultAgencyID = "agency"
+
+	// DefaultTimezone is the agency_timezone of agencies
+	DefaultTimezone = "UTC"
+
+	// DefaultStartDate and DefaultEndDate bound the calendars of services
+	DefaultStartDate = "20240101"
+	DefaultEndDate   = "20241231"
+
+	// DefaultStopInterval is the time between consecutive stops of
+	// StopTimes, unless changed with FeedBuilder.StopInterval
+	DefaultStopInterval = 5 * time.Minute
+
+	// DefaultLat and DefaultLon place the first stop created for a
+	// reference; each later one is placed DefaultStopSpacing degrees north
+	// of the previous
+	DefaultLat         = 47.6
+	DefaultLon         = -122.3
+	DefaultStopSpacing = 0.005
+)
+
+// FeedBuilder accumulates the entities of a feed. Its methods return the
+// builder, for chaining; a method given invalid input records the error,
+// which Build returns. Declaring an entity again replaces it.
+type FeedBuilder struct {
+	agencies  []gtfs.Agency
+	stops     []gtfs.Stop
+	routes    []gtfs.Route
+	calendars []gtfs.Calendar
+	trips     []gtfs.Trip
+	stopTimes []gtfs.StopTime
+	shapes    []gtfs.ShapePoint
+	freqs     []gtfs.Frequency
+
+	interval time.Duration
+	err      error
+}
+
+// NewFeedBuilder returns an empty FeedBuilder
+func NewFeedBuilder() *FeedBuilder {
+	return &FeedBuilder{interval: DefaultStopInterval}
+}
+
+// Agency declares an agency, named "Agency ID" with an example.com URL and
+// DefaultTimezone; fns may change any field but the ID
+func (b *FeedBuilder) Agency(id string, fns ...func(*gtfs.Agency)) *FeedBuilder {
+	a := newAgency(gtfs.AgencyID(id))
+	apply(&a, fns)
+	a.ID = gtfs.AgencyID(id)
+	b.agencies = put(b.agencies, a, func(a gtfs.Agency) gtfs.AgencyID { return a.ID })
+	return b
+}
+
+// Stop declares a stop at lat, lon, named "Stop ID"; fns may change any
+// field but the ID
+func (b *FeedBuilder) Stop(id string, lat, lon float64, fns ...func(*gtfs.Stop)) *FeedBuilder {
+	s := gtfs.Stop{ID: gtfs.StopID(id), Name: "Stop " + id, Lat: lat, Lon: lon}
+	apply(&s, fns)
+	s.ID = gtfs.StopID(id)
+	b.stops = put(b.stops, s, func(s gtfs.Stop) gtfs.StopID { return s.ID })
+	return b
+}
+
+// Route declares a bus route whose short name is its ID. A route without
+// an agency_id is given the first agency's when built; fns may change any
+// field but the ID.
+func (b *FeedBuilder) Route(id string, fns ...func(*gtfs.Route)) *FeedBuilder {
+	r := newRoute(gtfs.RouteID(id))
+	apply(&r, fns)
+	r.ID = gtfs.RouteID(id)
+	b.routes = put(b.routes, r, func(r gtfs.Route) gtfs.RouteID { return r.ID })
+	return b
+}
+
+// Service declares a calendar running every day from DefaultStartDate to
+// DefaultEndDate; fns may change any field but the service_id
+func (b *FeedBuilder) Service(id string, fns ...func(*gtfs.Calendar)) *FeedBuilder {
+	c := newCalendar(gtfs.ServiceID(id))
+	apply(&c, fns)
+	c.ServiceID = gtfs.ServiceID(id)
+	b.calendars = put(b.calendars, c, func(c gtfs.Calendar) gtfs.ServiceID { return c.ServiceID })
+	return b
+}
+
+// Trip declares a trip of routeID on serviceID. The route and service are
+// created with their defaults when built if they are not declared; fns may
+// change any field but the ID.
+func (b *FeedBuilder) Trip(id, routeID, serviceID string, fns ...func(*gtfs.Trip)) *FeedBuilder {
+	t := gtfs.Trip{ID: gtfs.TripID(id), RouteID: gtfs.RouteID(routeID), ServiceID: gtfs.ServiceID(serviceID)}
+	apply(&t, fns)
+	t.ID = gtfs.TripID(id)
+	b.trips = put(b.trips, t, func(t gtfs.Trip) gtfs.TripID { return t.ID })
+	return b
+}
+
+// StopInterval sets the time between consecutive stops of later StopTimes
+// calls
+func (b *FeedBuilder) StopInterval(d time.Duration) *FeedBuilder {
+	if d < time.Second {
+		b.fail(fmt.Errorf("stop interval %s is under a second", d))
+		return b
+	}
+	b.interval = d
+	return b
+}
+
+// StopTimes appends stop_times to tripID serving stops in order, the first
+// arriving and departing at start (HH:MM or HH:MM:SS) and each later one
+// the stop interval after the previous. Sequences continue from the trip's
+// earlier stop_times. Stops not declared are created when built.
+func (b *FeedBuilder) StopTimes(tripID, start string, stops ...string) *FeedBuilder {
+	at, err := parseTime(start)
+	if err != nil {
+		b.fail(fmt.Errorf("stop_times of trip %q: %w", tripID, err))
+		return b
+	}
+	seq := 0
+	for _, st := range b.stopTimes {
+		if st.TripID == gtfs.TripID(tripID) {
+			seq = max(seq, st.StopSequence)
+		}
+	}
+	step := int(b.interval / time.Second)
+	for i, stop := range stops {
+		t := gtfs.FormatTime(at + i*step)
+		b.stopTimes = append(b.stopTimes, gtfs.StopTime{
+			TripID:        gtfs.TripID(tripID),
+			StopID:        gtfs.StopID(stop),
+			StopSequence:  seq + i + 1,
+			ArrivalTime:   t,
+			DepartureTime: t,
+		})
+	}
+	return b
+}
+
+// Shape declares a shape through points, each a latitude and longitude,
+// numbered from 1
+func (b *FeedBuilder) Shape(id string, points ...[2]float64) *FeedBuilder {
+	kept := b.shapes[:0]
+	for _, p := range b.shapes {
+		if p.ShapeID != gtfs.ShapeID(id) {
+			kept = append(kept, p)
+		}
+	}
+	b.shapes = kept
+	for i, p := range points {
+		b.shapes = append(b.shapes, gtfs.ShapePoint{ShapeID: gtfs.ShapeID(id), Lat: p[0], Lon: p[1], Sequence: i + 1})
+	}
+	return b
+}
+
+// Frequency repeats tripID every headway from start to end (HH:MM or
+// HH:MM:SS)
+func (b *FeedBuilder) Frequency(tripID, start, end string, headway time.Duration) *FeedBuilder {
+	from, err := parseTime(start)
+	var to int
+	if err == nil {
+		to, err = parseTime(end)
+	}
+	if err == nil && to < from {
+		err = fmt.Errorf("end %q is before start %q", end, start)
+	}
+	if err == nil && headway < time.Second {
+		err = fmt.Errorf("headway %s is under a second", headway)
+	}
+	if err != nil {
+		b.fail(fmt.Errorf("frequency of trip %q: %w", tripID, err))
+		return b
+	}
+	b.freqs = append(b.freqs, gtfs.Frequency{
+		TripID:      gtfs.TripID(tripID),
+		StartTime:   gtfs.FormatTime(from),
+		EndTime:     gtfs.FormatTime(to),
+		HeadwaySecs: int(headway / time.Second),
+	})
+	return b
+}
+
+// Build returns a new feed holding the declared entities, in the order
+// they were declared, followed by those created for references: an agency
+// if none was declared, and the routes, services and stops referenced but
+// not declared. It returns an error if a method was given invalid input or
+// the feed fails gtfs.Feed.Validate, e.g. for a trip whose shape was never
+// declared. Build may be called again, and each feed it returns is the
+// caller's to modify.
+func (b *FeedBuilder) Build() (*gtfs.Feed, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	f := gtfs.NewFeed()
+
+	agencies := b.agencies
+	if len(agencies) == 0 {
+		agencies = []gtfs.Agency{newAgency(DefaultAgencyID)}
+	}
+	for _, a := range agencies {
+		f.AddAgency(&a)
+	}
+	for _, s := range b.stops {
+		f.AddStop(&s)
+	}
+	for _, r := range b.routes {
+		f.AddRoute(&r)
+	}
+	for _, c := range b.calendars {
+		f.AddCalendar(&c)
+	}
+	for _, t := range b.trips {
+		f.AddTrip(&t)
+	}
+	for _, st := range b.stopTimes {
+		f.StopTimes = append(f.StopTimes, &st)
+	}
+	for _, p := range b.shapes {
+		f.AddShape(p)
+	}
+	for _, fr := range b.freqs {
+		f.Frequencies = append(f.Frequencies, &fr)
+	}
+
+	// Fill in what is referenced but not declared
+	for _, t := range b.trips {
+		if _, ok := f.Routes[t.RouteID]; !ok && t.RouteID != "" {
+			r := newRoute(t.RouteID)
+			f.AddRoute(&r)
+		}
+		if _, ok := f.Calendars[t.ServiceID]; !ok && t.ServiceID != "" {
+			c := newCalendar(t.ServiceID)
+			f.AddCalendar(&c)
+		}
+	}
+	for _, id := range f.RouteOrder {
+		if r := f.Routes[id]; r.AgencyID == "" {
+			r.AgencyID = f.AgencyOrder[0]
+		}
+	}
+	for _, st := range f.StopTimes {
+		if _, ok := f.Stops[st.StopID]; !ok && st.StopID != "" {
+			lat := DefaultLat + float64(len(f.StopOrder))*DefaultStopSpacing
+			f.AddStop(&gtfs.Stop{ID: st.StopID, Name: "Stop " + string(st.StopID), Lat: lat, Lon: DefaultLon})
+		}
+	}
+
+	if err := f.ValidateAll(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// MustBuild is like Build but panics if Build returns an error
+func (b *FeedBuilder) MustBuild() *gtfs.Feed {
+	f, err := b.Build()
+	if err != nil {
+		panic("gtfstest: " + err.Error())
+	}
+	return f
+}
+
+// fail records err unless an earlier error was recorded
+func (b *FeedBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// newAgency returns the default agency with ID id
+func newAgency(id gtfs.AgencyID) gtfs.Agency {
+	return gtfs.Agency{ID: id, Name: "Agency " + string(id), URL: "https://example.com/" + string(id), Timezone: DefaultTimezone}
+}
+
+// newRoute returns the default route with ID id
+func newRoute(id gtfs.RouteID) gtfs.Route {
+	return gtfs.Route{ID: id, ShortName: string(id), Type: 3}
+}
+
+// newCalendar returns the default calendar of service id
+func newCalendar(id gtfs.ServiceID) gtfs.Calendar {
+	return gtfs.Calendar{
+		ServiceID: id,
+		Monday:    true, Tuesday: true, Wednesday: true, Thursday: true, Friday: true, Saturday: true, Sunday: true,
+		StartDate: DefaultStartDate,
+		EndDate:   DefaultEndDate,
+	}
+}
+
+// apply calls each of fns on v
+func apply[T any](v *T, fns []func(*T)) {
+	for _, fn := range fns {
+		fn(v)
+	}
+}
+
+// put replaces the entity of list with v's key with v, or appends v if
+// there is none
+func put[T any, K comparable](list []T, v T, key func(T) K) []T {
+	for i, e := range list {
+		if key(e) == key(v) {
+			list[i] = v
+			return list
+		}
+	}
+	return append(list, v)
+}
+
+// parseTime converts a time given as HH:MM or HH:MM:SS to seconds since
+// midnight
+func parseTime(s string) (int, error) {
+	if strings.Count(s, ":") == 1 {
+		s += ":00"
+	}
+	seconds := gtfs.TimeSeconds(s)
+	if seconds < 0 {
+		return 0, fmt.Errorf("invalid time %q: want HH:MM or HH:MM:SS", s)
+	}
+	return seconds, nil
+}
@@ -0,0 +1,144 @@
+package gtfstest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+func TestFeedBuilderFillsDefaults(t *testing.T) {
+	// Given: a trip whose route, service and stops are never declared
+	feed, err := NewFeedBuilder().
+		Trip("t1", "r1", "svc1").
+		StopTimes("t1", "08:00", "s1", "s2", "s3").
+		Build()
+
+	// Then: they are created, with an agency, and the feed is valid
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if len(feed.Agencies) != 1 || feed.Agencies[DefaultAgencyID] == nil {
+		t.Errorf("Expected the default agency, got %v", feed.AgencyOrder)
+	}
+	if r := feed.Routes["r1"]; r == nil || r.AgencyID != DefaultAgencyID {
+		t.Errorf("Expected route r1 of the default agency, got %+v", r)
+	}
+	if c := feed.Calendars["svc1"]; c == nil || c.StartDate != DefaultStartDate {
+		t.Errorf("Expected service svc1 from %s, got %+v", DefaultStartDate, c)
+	}
+	if len(feed.StopOrder) != 3 || feed.Stops["s1"].Lat == feed.Stops["s2"].Lat {
+		t.Errorf("Expected 3 stops at distinct places, got %v", feed.Stops)
+	}
+
+	// And: the stops are served at the default interval
+	want := []string{"08:00:00", "08:05:00", "08:10:00"}
+	for i, st := range feed.StopTimes {
+		if st.StopSequence != i+1 || st.ArrivalTime != want[i] || st.DepartureTime != want[i] {
+			t.Errorf("Expected stop time %d at %s, got %+v", i+1, want[i], st)
+		}
+	}
+}
+
+func TestFeedBuilderDeclared(t *testing.T) {
+	// Given: entities declared with their fields changed, one declared twice
+	b := NewFeedBuilder().
+		Agency("a1").
+		Agency("a2", func(a *gtfs.Agency) { a.Name = "Second" }).
+		Stop("s1", 47.61, -122.33, func(s *gtfs.Stop) { s.Name = "Main St" }).
+		Route("r1", func(r *gtfs.Route) { r.AgencyID = "a2"; r.Type = 0 }).
+		Route("r2").
+		Service("wkdy", func(c *gtfs.Calendar) { c.Saturday, c.Sunday = false, false }).
+		Shape("sh1", [2]float64{47.61, -122.33}, [2]float64{47.62, -122.33}).
+		Trip("t1", "r1", "wkdy", func(t *gtfs.Trip) { t.ShapeID = "sh1" }).
+		Trip("t1", "r2", "wkdy", func(t *gtfs.Trip) { t.ShapeID = "sh1"; t.Headsign = "Downtown" }).
+		StopInterval(2*time.Minute).
+		StopTimes("t1", "23:59:30", "s1", "s2").
+		StopTimes("t1", "24:10", "s3").
+		Frequency("t1", "06:00", "09:00", 10*time.Minute)
+	feed, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	// Then: each keeps its fields, in the order declared
+	if feed.Agencies["a2"].Name != "Second" || feed.AgencyOrder[0] != "a1" {
+		t.Errorf("Expected agencies a1 and a2, named Second, got %v", feed.Agencies)
+	}
+	if feed.Stops["s1"].Name != "Main St" || feed.Stops["s1"].Lat != 47.61 {
+		t.Errorf("Expected s1 Main St at 47.61, got %+v", feed.Stops["s1"])
+	}
+	if feed.Routes["r1"].AgencyID != "a2" || feed.Routes["r2"].AgencyID != "a1" {
+		t.Errorf("Expected r1 of a2 and r2 of the first agency, got %q and %q", feed.Routes["r1"].AgencyID, feed.Routes["r2"].AgencyID)
+	}
+	if c := feed.Calendars["wkdy"]; !c.Monday || c.Saturday {
+		t.Errorf("Expected wkdy to run weekdays only, got %+v", c)
+	}
+	if len(feed.Shapes["sh1"]) != 2 || feed.Shapes["sh1"][1].Sequence != 2 {
+		t.Errorf("Expected sh1 with 2 points, got %v", feed.Shapes["sh1"])
+	}
+
+	// And: the trip declared again is replaced
+	if len(feed.TripOrder) != 1 || feed.Trips["t1"].RouteID != "r2" || feed.Trips["t1"].Headsign != "Downtown" {
+		t.Errorf("Expected only t1 on r2 to Downtown, got %+v", feed.Trips)
+	}
+
+	// And: stop_times continue the trip's sequence, past midnight
+	var got []string
+	for _, st := range feed.StopTimes {
+		got = append(got, fmt.Sprintf("%d %s %s", st.StopSequence, st.StopID, st.ArrivalTime))
+	}
+	want := []string{"1 s1 23:59:30", "2 s2 24:01:30", "3 s3 24:10:00"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected stop_times %v, got %v", want, got)
+	}
+	if f := feed.Frequencies; len(f) != 1 || f[0].StartTime != "06:00:00" || f[0].HeadwaySecs != 600 {
+		t.Errorf("Expected a 10 minute frequency from 06:00:00, got %+v", f)
+	}
+
+	// And: building again returns a feed of its own
+	feed.Stops["s1"].Name = "Changed"
+	again := b.MustBuild()
+	if again.Stops["s1"].Name != "Main St" {
+		t.Errorf("Expected a new feed, got stop %q", again.Stops["s1"].Name)
+	}
+}
+
+func TestFeedBuilderErrors(t *testing.T) {
+	for name, b := range map[string]*FeedBuilder{
+		"invalid start":     NewFeedBuilder().StopTimes("t1", "8am", "s1"),
+		"invalid frequency": NewFeedBuilder().Trip("t1", "r1", "svc1").Frequency("t1", "09:00", "08:00", time.Minute),
+		"invalid interval":  NewFeedBuilder().StopInterval(0),
+		"undeclared shape":  NewFeedBuilder().Trip("t1", "r1", "svc1", func(t *gtfs.Trip) { t.ShapeID = "sh1" }),
+		"stop time of no trip": NewFeedBuilder().
+			StopTimes("t1", "08:00", "s1"),
+	} {
+		if _, err := b.Build(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected MustBuild to panic")
+		}
+	}()
+	NewFeedBuilder().StopInterval(0).MustBuild()
+}
+
+func ExampleFeedBuilder() {
+	feed := NewFeedBuilder().
+		Agency("a1").
+		Route("r1").
+		Trip("t1", "r1", "svc1").
+		StopTimes("t1", "08:00", "s1", "s2", "s3").
+		MustBuild()
+	for _, st := range feed.StopTimes {
+		fmt.Println(st.StopID, st.DepartureTime)
+	}
+	// Output:
+	// s1 08:00:00
+	// s2 08:05:00
+	// s3 08:10:00
+}
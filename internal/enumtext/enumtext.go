@@ -0,0 +1,30 @@
+// Package enumtext implements encoding.TextMarshaler and
+// encoding.TextUnmarshaler for the named integer enums of the other
+// packages, such as gtfs.Encoding and strategy.DuplicateDetection.
+package enumtext
+
+import "fmt"
+
+// Marshal returns v's name, failing for values without one, such as
+// DuplicateDetection(99), so that they can't be written out and read back
+// as something else
+func Marshal[T interface {
+	comparable
+	fmt.Stringer
+}](v T, parse func(string) (T, error)) ([]byte, error) {
+	name := v.String()
+	if parsed, err := parse(name); err != nil || parsed != v {
+		return nil, fmt.Errorf("cannot marshal %s", name)
+	}
+	return []byte(name), nil
+}
+
+// Unmarshal sets *v to the value named by text
+func Unmarshal[T any](v *T, text []byte, parse func(string) (T, error)) error {
+	parsed, err := parse(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
@@ -0,0 +1,66 @@
+package enumtext
+
+import (
+	"fmt"
+	"testing"
+)
+
+// color is an enum with names for 0 and 1 only
+type color int
+
+func (c color) String() string {
+	switch c {
+	case 0:
+		return "red"
+	case 1:
+		return "blue"
+	default:
+		return fmt.Sprintf("color(%d)", int(c))
+	}
+}
+
+func parseColor(s string) (color, error) {
+	switch s {
+	case "red":
+		return 0, nil
+	case "blue":
+		return 1, nil
+	default:
+		return 0, fmt.Errorf("invalid color: %q", s)
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	for _, c := range []color{0, 1} {
+		text, err := Marshal(c, parseColor)
+		if err != nil {
+			t.Fatalf("Marshal(%v) failed: %v", c, err)
+		}
+		var got color
+		if err := Unmarshal(&got, text, parseColor); err != nil || got != c {
+			t.Errorf("Expected %v to round-trip, got %v, %v", c, got, err)
+		}
+	}
+}
+
+func TestMarshalUnnamed(t *testing.T) {
+	// Given: a value with no name, whose String doesn't parse
+	if _, err := Marshal(color(99), parseColor); err == nil {
+		t.Error("Expected an error marshaling color(99)")
+	}
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	// Given: a value already set
+	got := color(1)
+
+	// When: an unknown name is unmarshaled
+	if err := Unmarshal(&got, []byte("green"), parseColor); err == nil {
+		t.Error("Expected an error unmarshaling green")
+	}
+
+	// Then: the value is left as it was
+	if got != 1 {
+		t.Errorf("Expected blue to be kept, got %v", got)
+	}
+}
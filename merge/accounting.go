@@ -0,0 +1,84 @@
+package merge
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// ErrAccountingMismatch indicates that, for some file, the rows read from
+// the inputs less those deduplicated and dropped are not the rows merged
+// (see WithAccountingCheck)
+var ErrAccountingMismatch = errors.New("row accounting mismatch")
+
+// Unaccounted returns, for each file whose rows do not balance, the number
+// of rows merged less the rows read from every input, less those
+// deduplicated and dropped: positive when rows appeared from nowhere,
+// negative when rows were lost without being counted. It is empty when
+// every row is accounted for.
+func (r *Report) Unaccounted() map[string]int {
+	expected := make(map[string]int)
+	for _, fr := range r.Feeds {
+		for filename, n := range fr.Read {
+			expected[filename] += n
+		}
+		for filename, n := range fr.Deduplicated {
+			expected[filename] -= n
+		}
+		for filename, n := range fr.Dropped {
+			expected[filename] -= n
+		}
+	}
+	for filename, n := range r.Dropped {
+		expected[filename] -= n
+	}
+
+	unaccounted := make(map[string]int)
+	for _, filename := range gtfs.FileNames() {
+		if delta := r.Merged[filename] - expected[filename]; delta != 0 {
+			unaccounted[filename] = delta
+		}
+	}
+	return unaccounted
+}
+
+// checkAccounting returns an ErrAccountingMismatch naming each file of
+// report whose rows do not balance, with its delta
+func checkAccounting(report *Report) error {
+	unaccounted := report.Unaccounted()
+	if len(unaccounted) == 0 {
+		return nil
+	}
+	files := make([]string, 0, len(unaccounted))
+	for _, filename := range slices.Sorted(maps.Keys(unaccounted)) {
+		files = append(files, fmt.Sprintf("%s %+d", filename, unaccounted[filename]))
+	}
+	return fmt.Errorf("%w: rows merged less rows read, deduplicated and dropped: %s", ErrAccountingMismatch, strings.Join(files, ", "))
+}
+
+// removedRows runs remove and returns the number of rows it removed from
+// each file of feed
+func removedRows(feed *gtfs.Feed, remove func()) map[string]int {
+	before := feed.RowCounts()
+	remove()
+	return rowCountDelta(feed.RowCounts(), before)
+}
+
+// addCounts adds each count of from to to, creating to if needed, and
+// returns it
+func addCounts(to, from map[string]int) map[string]int {
+	for filename, n := range from {
+		if n == 0 {
+			continue
+		}
+		if to == nil {
+			to = make(map[string]int)
+		}
+		to[filename] += n
+	}
+	return to
+}
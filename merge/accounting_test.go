@@ -0,0 +1,105 @@
+package merge
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+func TestWithAccountingCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"no detection", nil},
+		{"identity", []Option{WithDefaultDetection(strategy.DetectionIdentity)}},
+		{"fuzzy", []Option{WithDefaultDetection(strategy.DetectionFuzzy)}},
+		{"identity, intra-feed and pruned", []Option{
+			WithDefaultDetection(strategy.DetectionIdentity),
+			WithIntraFeedDedup(true),
+			WithPruneUnreferenced("stop", "shape", "service"),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: two overlapping feeds read from disk
+			feeds := readFixtures(t, "../testdata/simple_a", "../testdata/overlap")
+
+			// When: merged with the accounting check
+			m := New(append(tt.opts, WithAccountingCheck(true))...)
+			if _, err := m.MergeFeeds(feeds); err != nil {
+				t.Fatalf("MergeFeeds failed: %v", err)
+			}
+
+			// Then: every row read is merged, deduplicated or dropped
+			if got := m.Report().Unaccounted(); len(got) != 0 {
+				t.Errorf("Expected every row accounted for, got %v", got)
+			}
+		})
+	}
+}
+
+func TestWithAccountingCheckMismatch(t *testing.T) {
+	// Given: a feed whose read counts claim a stop that never reached it
+	newFeeds := func() []*gtfs.Feed {
+		feeds := readFixtures(t, "../testdata/simple_a", "../testdata/simple_b")
+		feeds[0].RowsRead["stops.txt"]++
+		return feeds
+	}
+
+	// When: merged without the accounting check
+	m := New()
+	if _, err := m.MergeFeeds(newFeeds()); err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: the merge succeeds, and the report shows the missing row
+	if got := m.Report().Unaccounted(); len(got) != 1 || got["stops.txt"] != -1 {
+		t.Errorf("Expected stops.txt -1 unaccounted, got %v", got)
+	}
+
+	// When: merged with the accounting check
+	_, err := New(WithAccountingCheck(true)).MergeFeeds(newFeeds())
+
+	// Then: the merge fails, naming the file and its delta
+	if !errors.Is(err, ErrAccountingMismatch) {
+		t.Fatalf("Expected ErrAccountingMismatch, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "stops.txt -1") {
+		t.Errorf("Expected the error to name stops.txt -1, got %q", err)
+	}
+}
+
+func TestAccountingCountsDroppedRows(t *testing.T) {
+	// Given: a two-agency feed filtered to one agency, and a copy of
+	// simple_a merged by identity onto another
+	feeds := append([]*gtfs.Feed{twoAgencyFeed()}, readFixtures(t, "../testdata/simple_a", "../testdata/simple_a")...)
+
+	// When: merged with the accounting check
+	m := New(
+		WithAgencyFilter(0, []string{"a1"}),
+		WithDefaultDetection(strategy.DetectionIdentity),
+		WithAccountingCheck(true),
+	)
+	if _, err := m.MergeFeeds(feeds); err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+	report := m.Report()
+
+	// Then: the filtered agency's rows are dropped
+	if got := report.Feeds[0].Dropped["agency.txt"]; got != 1 {
+		t.Errorf("Expected 1 agency.txt row dropped, got %v", report.Feeds[0].Dropped)
+	}
+
+	// And: every row of the copy merged second (feeds are merged last to
+	// first) is deduplicated
+	repeated := report.Feeds[1]
+	for filename, read := range repeated.Read {
+		if got := repeated.Deduplicated[filename]; got != read {
+			t.Errorf("%s: expected %d deduplicated, got %d", filename, read, got)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package merge
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// agencyFilterResult describes what filterAgencies removed from one input
+// feed
+type agencyFilterResult struct {
+	// removed is the number of rows dropped, keyed by filename
+	removed map[string]int
+
+	// missing lists the agencies to keep that the feed does not have
+	missing []gtfs.AgencyID
+}
+
+// filterAgencies removes from feed every agency not in keep, with the
+// routes they run and those routes' trips, stop times and frequencies. A
+// route without an agency_id belongs to the feed's only agency. Services
+// and shapes only removed trips used are removed too, unless a timeframe
+// uses the service, as are stops only their stop times visited unless
+// keepStops is set, though never the parent station of a stop that stays.
+// Networks only removed routes belonged to go, with the fare leg rules that
+// name them and the fare transfer rules of the leg groups that leaves
+// without a rule. Fare attributes of removed agencies go, and so do the fare
+// rules, route networks, transfers and pathways that refer to anything
+// removed.
+func filterAgencies(feed *gtfs.Feed, keep []gtfs.AgencyID, keepStops bool) agencyFilterResult {
+	res := agencyFilterResult{removed: make(map[string]int)}
+	kept := make(map[gtfs.AgencyID]bool, len(keep))
+	for _, id := range keep {
+		kept[id] = true
+		if feed.Agencies[id] == nil {
+			res.missing = append(res.missing, id)
+		}
+	}
+
+	var onlyAgency gtfs.AgencyID
+	if len(feed.AgencyOrder) == 1 {
+		onlyAgency = feed.AgencyOrder[0]
+	}
+	routeNetworks := routeNetworkIDs(feed)
+	legGroups := feed.LegGroupIDs()
+	feed.AgencyOrder = dropEntities(feed.AgencyOrder, feed.Agencies, "agency.txt", res.removed, func(id gtfs.AgencyID) bool {
+		return !kept[id]
+	})
+	feed.RouteOrder = dropEntities(feed.RouteOrder, feed.Routes, "routes.txt", res.removed, func(id gtfs.RouteID) bool {
+		agency := feed.Routes[id].AgencyID
+		if agency == "" {
+			agency = onlyAgency
+		}
+		return !kept[agency]
+	})
+
+	// Services and shapes stay while a remaining trip, or a timeframe,
+	// uses them
+	usedServices := make(map[gtfs.ServiceID]bool)
+	for _, tf := range feed.Timeframes {
+		usedServices[tf.ServiceID] = true
+	}
+	usedShapes := make(map[gtfs.ShapeID]bool)
+	droppedServices := make(map[gtfs.ServiceID]bool)
+	droppedShapes := make(map[gtfs.ShapeID]bool)
+	feed.TripOrder = dropEntities(feed.TripOrder, feed.Trips, "trips.txt", res.removed, func(id gtfs.TripID) bool {
+		trip := feed.Trips[id]
+		if feed.Routes[trip.RouteID] != nil {
+			usedServices[trip.ServiceID] = true
+			usedShapes[trip.ShapeID] = true
+			return false
+		}
+		droppedServices[trip.ServiceID] = true
+		droppedShapes[trip.ShapeID] = true
+		return true
+	})
+
+	// Likewise stops, while a remaining stop time visits them
+	usedStops := make(map[gtfs.StopID]bool)
+	droppedStops := make(map[gtfs.StopID]bool)
+	feed.StopTimes = dropRows(feed.StopTimes, "stop_times.txt", res.removed, func(st *gtfs.StopTime) bool {
+		if feed.Trips[st.TripID] != nil {
+			usedStops[st.StopID] = true
+			return false
+		}
+		droppedStops[st.StopID] = true
+		return true
+	})
+	feed.Frequencies = dropRows(feed.Frequencies, "frequencies.txt", res.removed, func(f *gtfs.Frequency) bool {
+		return feed.Trips[f.TripID] == nil
+	})
+
+	feed.CalendarOrder = dropEntities(feed.CalendarOrder, feed.Calendars, "calendar.txt", res.removed, func(id gtfs.ServiceID) bool {
+		return droppedServices[id] && !usedServices[id]
+	})
+	feed.CalendarDateOrder = slices.DeleteFunc(feed.CalendarDateOrder, func(id gtfs.ServiceID) bool {
+		if !droppedServices[id] || usedServices[id] {
+			return false
+		}
+		res.removed["calendar_dates.txt"] += len(feed.CalendarDates[id])
+		delete(feed.CalendarDates, id)
+		return true
+	})
+	feed.ShapeOrder = slices.DeleteFunc(feed.ShapeOrder, func(id gtfs.ShapeID) bool {
+		if !droppedShapes[id] || usedShapes[id] {
+			return false
+		}
+		res.removed["shapes.txt"] += len(feed.Shapes[id])
+		delete(feed.Shapes, id)
+		return true
+	})
+
+	if !keepStops {
+		dropped := func(id gtfs.StopID) bool {
+			return droppedStops[id] && !usedStops[id]
+		}
+		parents := make(map[gtfs.StopID]bool)
+		for id, stop := range feed.Stops {
+			if dropped(id) {
+				continue
+			}
+			for p := stop.ParentStation; p != "" && !parents[p]; {
+				parents[p] = true
+				parent := feed.Stops[p]
+				if parent == nil {
+					break
+				}
+				p = parent.ParentStation
+			}
+		}
+		feed.StopOrder = dropEntities(feed.StopOrder, feed.Stops, "stops.txt", res.removed, func(id gtfs.StopID) bool {
+			return dropped(id) && !parents[id]
+		})
+	}
+
+	feed.Transfers = dropRows(feed.Transfers, "transfers.txt", res.removed, func(tr *gtfs.Transfer) bool {
+		return feed.Stops[tr.FromStopID] == nil && tr.FromStopID != "" ||
+			feed.Stops[tr.ToStopID] == nil && tr.ToStopID != "" ||
+			feed.Routes[tr.FromRouteID] == nil && tr.FromRouteID != "" ||
+			feed.Routes[tr.ToRouteID] == nil && tr.ToRouteID != "" ||
+			feed.Trips[tr.FromTripID] == nil && tr.FromTripID != "" ||
+			feed.Trips[tr.ToTripID] == nil && tr.ToTripID != ""
+	})
+	feed.Pathways = dropRows(feed.Pathways, "pathways.txt", res.removed, func(pw *gtfs.Pathway) bool {
+		return feed.Stops[pw.FromStopID] == nil || feed.Stops[pw.ToStopID] == nil
+	})
+	feed.RouteNetworks = dropRows(feed.RouteNetworks, "route_networks.txt", res.removed, func(rn *gtfs.RouteNetwork) bool {
+		return feed.Routes[rn.RouteID] == nil
+	})
+
+	// Networks go once no remaining route belongs to them, and with them
+	// the fare leg rules naming them and the transfer rules of leg groups
+	// left without a rule
+	remainingNetworks := routeNetworkIDs(feed)
+	droppedNetwork := func(id gtfs.NetworkID) bool {
+		return routeNetworks[id] && !remainingNetworks[id]
+	}
+	feed.NetworkOrder = dropEntities(feed.NetworkOrder, feed.Networks, "networks.txt", res.removed, droppedNetwork)
+	feed.FareLegRules = dropRows(feed.FareLegRules, "fare_leg_rules.txt", res.removed, func(rule *gtfs.FareLegRule) bool {
+		return rule.NetworkID != "" && droppedNetwork(rule.NetworkID)
+	})
+	remainingLegGroups := feed.LegGroupIDs()
+	droppedLegGroup := func(id gtfs.LegGroupID) bool {
+		return legGroups[id] && !remainingLegGroups[id]
+	}
+	feed.FareTransferRules = dropRows(feed.FareTransferRules, "fare_transfer_rules.txt", res.removed, func(rule *gtfs.FareTransferRule) bool {
+		return droppedLegGroup(rule.FromLegGroupID) || droppedLegGroup(rule.ToLegGroupID)
+	})
+
+	feed.FareAttrOrder = dropEntities(feed.FareAttrOrder, feed.FareAttributes, "fare_attributes.txt", res.removed, func(id gtfs.FareID) bool {
+		agency := feed.FareAttributes[id].AgencyID
+		return agency != "" && !kept[agency]
+	})
+	feed.FareRules = dropRows(feed.FareRules, "fare_rules.txt", res.removed, func(fr *gtfs.FareRule) bool {
+		return feed.FareAttributes[fr.FareID] == nil || (fr.RouteID != "" && feed.Routes[fr.RouteID] == nil)
+	})
+
+	return res
+}
+
+// routeNetworkIDs returns the networks the feed's routes belong to, by
+// routes.txt network_id or route_networks.txt
+func routeNetworkIDs(feed *gtfs.Feed) map[gtfs.NetworkID]bool {
+	networks := make(map[gtfs.NetworkID]bool)
+	for _, id := range feed.RouteOrder {
+		if route := feed.Routes[id]; route.NetworkID != "" {
+			networks[route.NetworkID] = true
+		}
+	}
+	for _, rn := range feed.RouteNetworks {
+		networks[rn.NetworkID] = true
+	}
+	return networks
+}
+
+// dropEntities deletes from entities, and from their order, the IDs for
+// which drop returns true, counting them in removed under filename
+func dropEntities[K comparable, V any](order []K, entities map[K]V, filename string, removed map[string]int, drop func(K) bool) []K {
+	return dropRows(order, filename, removed, func(id K) bool {
+		if !drop(id) {
+			return false
+		}
+		delete(entities, id)
+		return true
+	})
+}
+
+// warnings describes the agencies to keep that res found missing, for
+// Report.Warnings
+func (res agencyFilterResult) warnings(feedName string) []string {
+	var warnings []string
+	for _, id := range res.missing {
+		warnings = append(warnings, fmt.Sprintf("feed %s: agency filter names agency %q, which the feed does not have", feedName, id))
+	}
+	return warnings
+}
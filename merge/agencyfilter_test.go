@@ -0,0 +1,173 @@
+package merge
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfstest"
+)
+
+// twoAgencyFeed returns a feed whose agencies a1 and a2 each run one route
+// with one trip on a service of its own, the two trips sharing stop s2
+func twoAgencyFeed() *gtfs.Feed {
+	feed := gtfstest.NewFeedBuilder().
+		Agency("a1").
+		Agency("a2").
+		Stop("station", 47.6, -122.3, func(s *gtfs.Stop) { s.LocationType = 1 }).
+		Stop("s1", 47.6, -122.3, func(s *gtfs.Stop) { s.ParentStation = "station" }).
+		Route("r1", func(r *gtfs.Route) { r.AgencyID = "a1" }).
+		Route("r2", func(r *gtfs.Route) { r.AgencyID = "a2" }).
+		Trip("t1", "r1", "svc1").
+		Trip("t2", "r2", "svc2").
+		StopTimes("t1", "08:00", "s1", "s2").
+		StopTimes("t2", "09:00", "s2", "s3").
+		MustBuild()
+	feed.FareAttributes["f2"] = &gtfs.FareAttribute{FareID: "f2", Price: 2, CurrencyType: "USD", AgencyID: "a2"}
+	feed.FareAttrOrder = append(feed.FareAttrOrder, "f2")
+	feed.FareRules = append(feed.FareRules, &gtfs.FareRule{FareID: "f2", RouteID: "r2"})
+	return feed
+}
+
+func TestFilterAgencies(t *testing.T) {
+	t.Run("cascade", func(t *testing.T) {
+		// Given: a feed with two agencies
+		feed := twoAgencyFeed()
+
+		// When: filtered to a1
+		res := filterAgencies(feed, []gtfs.AgencyID{"a1"}, false)
+
+		// Then: a2's route, trip, service, stop times and fares are gone,
+		// as is the stop only its trip visited
+		want := map[string]int{
+			"agency.txt": 1, "routes.txt": 1, "trips.txt": 1, "stop_times.txt": 2,
+			"calendar.txt": 1, "stops.txt": 1, "fare_attributes.txt": 1, "fare_rules.txt": 1,
+		}
+		for filename, n := range want {
+			if res.removed[filename] != n {
+				t.Errorf("Expected %d %s rows removed, got %d", n, filename, res.removed[filename])
+			}
+		}
+		if len(res.removed) != len(want) {
+			t.Errorf("Expected removals from %d files, got %v", len(want), res.removed)
+		}
+
+		// And: the shared stop and the kept stop's parent station remain
+		if want := []gtfs.StopID{"station", "s1", "s2"}; !slices.Equal(feed.StopOrder, want) {
+			t.Errorf("Expected stops %v, got %v", want, feed.StopOrder)
+		}
+		if err := feed.ValidateAll(); err != nil {
+			t.Errorf("Expected a valid feed, got %v", err)
+		}
+	})
+
+	t.Run("keep stops", func(t *testing.T) {
+		// Given: a feed with two agencies
+		feed := twoAgencyFeed()
+
+		// When: filtered to a1, keeping stops
+		res := filterAgencies(feed, []gtfs.AgencyID{"a1"}, true)
+
+		// Then: no stop is removed
+		if res.removed["stops.txt"] != 0 || len(feed.StopOrder) != 4 {
+			t.Errorf("Expected all 4 stops kept, got %v", feed.StopOrder)
+		}
+	})
+
+	t.Run("missing agency", func(t *testing.T) {
+		// Given: a feed with two agencies
+		feed := twoAgencyFeed()
+
+		// When: filtered to a2 and an agency it does not have
+		res := filterAgencies(feed, []gtfs.AgencyID{"a2", "a9"}, false)
+
+		// Then: a1 is removed and the missing agency warned about
+		if want := []gtfs.AgencyID{"a2"}; !slices.Equal(feed.AgencyOrder, want) {
+			t.Errorf("Expected agencies %v, got %v", want, feed.AgencyOrder)
+		}
+		if len(res.warnings("feed")) != 1 {
+			t.Errorf("Expected one warning, got %q", res.warnings("feed"))
+		}
+	})
+}
+
+// twoAgencyFaresFeed returns twoAgencyFeed with a network and a Fares v2
+// leg group for each route, a transfer from each group to a2's, and a2's
+// peak timeframe on a2's service
+func twoAgencyFaresFeed() *gtfs.Feed {
+	feed := twoAgencyFeed()
+	for _, n := range []string{"1", "2"} {
+		feed.AddNetwork(&gtfs.Network{ID: gtfs.NetworkID("n" + n)})
+		feed.Routes[gtfs.RouteID("r"+n)].NetworkID = gtfs.NetworkID("n" + n)
+	}
+	feed.FareProducts = append(feed.FareProducts, &gtfs.FareProduct{ID: "single", Amount: "2.00", Currency: "USD"})
+	feed.Timeframes = append(feed.Timeframes, &gtfs.Timeframe{GroupID: "peak", StartTime: "06:00:00", EndTime: "09:00:00", ServiceID: "svc2"})
+	feed.FareLegRules = append(feed.FareLegRules,
+		&gtfs.FareLegRule{LegGroupID: "leg1", NetworkID: "n1", FareProductID: "single"},
+		&gtfs.FareLegRule{LegGroupID: "leg2", NetworkID: "n2", FromTimeframeGroupID: "peak", FareProductID: "single"})
+	feed.FareTransferRules = append(feed.FareTransferRules,
+		&gtfs.FareTransferRule{FromLegGroupID: "leg1", ToLegGroupID: "leg2"},
+		&gtfs.FareTransferRule{FromLegGroupID: "leg2", ToLegGroupID: "leg2"},
+		&gtfs.FareTransferRule{FromLegGroupID: "leg1", ToLegGroupID: "leg1"})
+	return feed
+}
+
+func TestFilterAgenciesFaresV2(t *testing.T) {
+	// Given: a feed with two agencies, each with its own network and leg
+	// group, and a timeframe on a2's service
+	feed := twoAgencyFaresFeed()
+
+	// When: filtered to a1
+	res := filterAgencies(feed, []gtfs.AgencyID{"a1"}, false)
+
+	// Then: a2's network goes, with its leg rule and the transfers into it
+	want := map[string]int{"networks.txt": 1, "fare_leg_rules.txt": 1, "fare_transfer_rules.txt": 2}
+	for filename, n := range want {
+		if res.removed[filename] != n {
+			t.Errorf("Expected %d %s rows removed, got %d", n, filename, res.removed[filename])
+		}
+	}
+	if len(feed.FareLegRules) != 1 || feed.FareLegRules[0].LegGroupID != "leg1" {
+		t.Errorf("Expected only leg1's rule to remain, got %d rules", len(feed.FareLegRules))
+	}
+
+	// And: the service the timeframe uses is kept, though a2's trip was
+	// its only trip
+	if res.removed["calendar.txt"] != 0 || feed.Calendars["svc2"] == nil {
+		t.Errorf("Expected svc2 kept for the timeframe, got %d calendars removed", res.removed["calendar.txt"])
+	}
+
+	// And: the merged result passes the strict output check
+	if _, err := New(WithAgencyFilter(0, []string{"a1"}), WithStrictOutput(true)).MergeFeeds([]*gtfs.Feed{twoAgencyFaresFeed()}); err != nil {
+		t.Errorf("Expected the filtered feed to merge strictly, got %v", err)
+	}
+}
+
+func TestWithAgencyFilter(t *testing.T) {
+	// Given: a two-agency feed and a second feed
+	other := gtfstest.NewFeedBuilder().
+		Agency("b1").
+		Trip("tb", "rb", "svcb").
+		StopTimes("tb", "10:00", "sb1", "sb2").
+		MustBuild()
+
+	// When: merged keeping only a1 of the first
+	m := New(WithAgencyFilter(0, []string{"a1"}))
+	merged, err := m.MergeFeeds([]*gtfs.Feed{twoAgencyFeed(), other})
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: a2 and its trip are not merged, and the removals reported for
+	// the first feed only
+	if merged.Agencies["a2"] != nil || merged.Trips["t2"] != nil {
+		t.Error("Expected a2 and its trip left out")
+	}
+	if len(merged.AgencyOrder) != 2 {
+		t.Errorf("Expected agencies a1 and b1, got %v", merged.AgencyOrder)
+	}
+	feeds := m.Report().Feeds
+	if feeds[0].AgencyFiltered["trips.txt"] != 1 || feeds[1].AgencyFiltered != nil {
+		t.Errorf("Expected 1 trip filtered from feed 0 only, got %v and %v", feeds[0].AgencyFiltered, feeds[1].AgencyFiltered)
+	}
+}
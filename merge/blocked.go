@@ -0,0 +1,270 @@
+package merge
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// ErrInvalidBlockedPair indicates a blocked pair that names no entity kind
+// or is missing a feed or ID
+var ErrInvalidBlockedPair = errors.New("invalid blocked duplicate pair")
+
+// BlockedPair names two entities that must never be merged as duplicates,
+// whether identity or fuzzy detection matches them. Each ID is qualified by
+// the name of the input feed it comes from (see FeedReport.Name), since raw
+// IDs may repeat across feeds.
+type BlockedPair struct {
+	// File is the GTFS file ("stops.txt") or entity kind ("stop") of both
+	// entities. Agencies, areas, networks, stops, services, routes, shapes,
+	// trips and fare attributes can be blocked.
+	File string
+
+	FeedA string
+	IDA   string
+	FeedB string
+	IDB   string
+}
+
+// String returns the pair as FILE FEED_A:ID_A FEED_B:ID_B
+func (p BlockedPair) String() string {
+	return fmt.Sprintf("%s %s:%s %s:%s", p.File, p.FeedA, p.IDA, p.FeedB, p.IDB)
+}
+
+// kind returns the entity kind the pair's File names
+func (p BlockedPair) kind() (gtfs.EntityKind, bool) {
+	if _, ok := entityKindFiles[gtfs.EntityKind(strings.ToLower(p.File))]; ok {
+		return gtfs.EntityKind(strings.ToLower(p.File)), true
+	}
+	for kind, files := range entityKindFiles {
+		for _, file := range files {
+			if file == p.File {
+				return kind, true
+			}
+		}
+	}
+	return "", false
+}
+
+// ReadBlockedPairs reads blocked pairs from CSV with the columns file, feed_a,
+// id_a, feed_b and id_b
+func ReadBlockedPairs(r io.Reader) ([]BlockedPair, error) {
+	reader := gtfs.NewCSVReader(r)
+	header, err := reader.ReadHeader()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, column := range []string{"file", "feed_a", "id_a", "feed_b", "id_b"} {
+		if !slices.Contains(header, column) {
+			return nil, fmt.Errorf("blocked pairs: missing column %q", column)
+		}
+	}
+
+	var pairs []BlockedPair
+	for {
+		record, err := reader.ReadRecord()
+		if err == io.EOF {
+			return pairs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := gtfs.NewCSVRow(header, record)
+		pair := BlockedPair{
+			File:  row.Get("file"),
+			FeedA: row.Get("feed_a"),
+			IDA:   row.Get("id_a"),
+			FeedB: row.Get("feed_b"),
+			IDB:   row.Get("id_b"),
+		}
+		if err := pair.validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", reader.Line(), err)
+		}
+		pairs = append(pairs, pair)
+	}
+}
+
+// LoadBlockedPairs reads blocked pairs from the CSV file at path; see
+// ReadBlockedPairs
+func LoadBlockedPairs(path string) ([]BlockedPair, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return ReadBlockedPairs(f)
+}
+
+// validate checks that the pair names an entity kind and both entities.
+// Agency IDs may be empty, since agency_id is optional in single-agency feeds.
+func (p BlockedPair) validate() error {
+	kind, ok := p.kind()
+	if !ok {
+		return fmt.Errorf("%w: unknown file %q", ErrInvalidBlockedPair, p.File)
+	}
+	if p.FeedA == "" || p.FeedB == "" {
+		return fmt.Errorf("%w: %s: feed names are required", ErrInvalidBlockedPair, p)
+	}
+	if kind != gtfs.KindAgency && (p.IDA == "" || p.IDB == "") {
+		return fmt.Errorf("%w: %s: IDs are required", ErrInvalidBlockedPair, p)
+	}
+	return nil
+}
+
+// feedEntity is an entity ID qualified by the name of its input feed
+type feedEntity struct {
+	kind gtfs.EntityKind
+	feed string
+	id   string
+}
+
+// blockedMatcher resolves blocked pairs against the entities merged so far
+type blockedMatcher struct {
+	pairs []BlockedPair
+	kinds []gtfs.EntityKind
+
+	// merged maps each entity merged so far to its ID in the target feed
+	merged map[feedEntity]string
+
+	// hit marks the pairs a strategy refused to merge
+	hit []bool
+}
+
+// newBlockedMatcher validates pairs and returns a matcher for them
+func newBlockedMatcher(pairs []BlockedPair) (*blockedMatcher, error) {
+	b := &blockedMatcher{
+		pairs:  pairs,
+		kinds:  make([]gtfs.EntityKind, len(pairs)),
+		merged: make(map[feedEntity]string),
+		hit:    make([]bool, len(pairs)),
+	}
+	for i, p := range pairs {
+		if err := p.validate(); err != nil {
+			return nil, err
+		}
+		b.kinds[i], _ = p.kind()
+	}
+	return b, nil
+}
+
+// matches returns the blocked matches for merging the named feed into the
+// entities merged so far, keyed to the index of their pair
+func (b *blockedMatcher) matches(feed string) map[strategy.BlockedMatch]int {
+	matches := make(map[strategy.BlockedMatch]int)
+	for i, p := range b.pairs {
+		kind := b.kinds[i]
+		if p.FeedA == feed {
+			if target, ok := b.merged[feedEntity{kind, p.FeedB, p.IDB}]; ok {
+				matches[strategy.BlockedMatch{Kind: kind, SourceID: p.IDA, TargetID: target}] = i
+			}
+		}
+		if p.FeedB == feed {
+			if target, ok := b.merged[feedEntity{kind, p.FeedA, p.IDA}]; ok {
+				matches[strategy.BlockedMatch{Kind: kind, SourceID: p.IDB, TargetID: target}] = i
+			}
+		}
+	}
+	return matches
+}
+
+// record notes the entities the named feed's merge context mapped into the
+// target and the blocked pairs its strategies refused
+func (b *blockedMatcher) record(feed string, ctx *strategy.MergeContext) {
+	for _, i := range ctx.BlockedHits {
+		b.hit[i] = true
+	}
+	recorded := make(map[gtfs.EntityKind]bool)
+	for _, kind := range b.kinds {
+		if recorded[kind] {
+			continue
+		}
+		recorded[kind] = true
+		for source, target := range idMapping(ctx, kind) {
+			b.merged[feedEntity{kind, feed, source}] = target
+		}
+	}
+}
+
+// unused returns the pairs no strategy ever refused to merge
+func (b *blockedMatcher) unused() []BlockedPair {
+	var unused []BlockedPair
+	for i, p := range b.pairs {
+		if !b.hit[i] {
+			unused = append(unused, p)
+		}
+	}
+	return unused
+}
+
+// idMapping returns ctx's source-to-target ID mapping for kind
+func idMapping(ctx *strategy.MergeContext, kind gtfs.EntityKind) map[string]string {
+	m := make(map[string]string)
+	switch kind {
+	case gtfs.KindAgency:
+		for source, target := range ctx.AgencyIDMapping {
+			m[string(source)] = string(target)
+		}
+	case gtfs.KindStop:
+		for source, target := range ctx.StopIDMapping {
+			m[string(source)] = string(target)
+		}
+	case gtfs.KindRoute:
+		for source, target := range ctx.RouteIDMapping {
+			m[string(source)] = string(target)
+		}
+	case gtfs.KindTrip:
+		for source, target := range ctx.TripIDMapping {
+			m[string(source)] = string(target)
+		}
+	case gtfs.KindService:
+		for source, target := range ctx.ServiceIDMapping {
+			m[string(source)] = string(target)
+		}
+	case gtfs.KindShape:
+		for source, target := range ctx.ShapeIDMapping {
+			m[string(source)] = string(target)
+		}
+	case gtfs.KindFare:
+		for source, target := range ctx.FareIDMapping {
+			m[string(source)] = string(target)
+		}
+	case gtfs.KindArea:
+		for source, target := range ctx.AreaIDMapping {
+			m[string(source)] = string(target)
+		}
+	case gtfs.KindNetwork:
+		for source, target := range ctx.NetworkIDMapping {
+			m[string(source)] = string(target)
+		}
+	case gtfs.KindLegGroup:
+		for source, target := range ctx.LegGroupIDMapping {
+			m[string(source)] = string(target)
+		}
+	case gtfs.KindFareProduct:
+		for source, target := range ctx.FareProductIDMapping {
+			m[string(source)] = string(target)
+		}
+	case gtfs.KindTimeframeGroup:
+		for source, target := range ctx.TimeframeGroupIDMapping {
+			m[string(source)] = string(target)
+		}
+	case gtfs.KindFareMedia:
+		for source, target := range ctx.FareMediaIDMapping {
+			m[string(source)] = string(target)
+		}
+	case gtfs.KindRiderCategory:
+		for source, target := range ctx.RiderCategoryIDMapping {
+			m[string(source)] = string(target)
+		}
+	}
+	return m
+}
@@ -0,0 +1,94 @@
+package merge
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+func TestWithBlockedDuplicatesFuzzy(t *testing.T) {
+	// Given: two same-named stops 70m apart that fuzzy detection would merge
+	m := New(
+		WithDetectionFor("stop", strategy.DetectionFuzzy),
+		WithBlockedDuplicates([]BlockedPair{{File: "stops.txt", FeedA: "a", IDA: "a1", FeedB: "b", IDB: "b1"}}),
+	)
+
+	// When: merged with the pair blocked
+	merged, err := m.MergeFeeds(grayZoneFeeds())
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: both stops are kept and the pair is not reported as unused
+	if len(merged.Stops) != 2 {
+		t.Errorf("Expected 2 stops, got %d", len(merged.Stops))
+	}
+	if unused := m.Report().UnusedBlockedPairs; len(unused) != 0 {
+		t.Errorf("Expected no unused pairs, got %v", unused)
+	}
+}
+
+func TestWithBlockedDuplicatesIdentity(t *testing.T) {
+	// Given: two feeds with the same stop ID
+	feedA := gtfs.NewFeed()
+	feedA.AddStop(&gtfs.Stop{ID: "tc", Name: "Transit Center"})
+	feedB := gtfs.NewFeed()
+	feedB.AddStop(&gtfs.Stop{ID: "tc", Name: "Transit Center"})
+
+	// When: merged by identity with the pair blocked, listed in either order,
+	// alongside a pair that never matches
+	m := New(
+		WithDefaultDetection(strategy.DetectionIdentity),
+		WithBlockedDuplicates([]BlockedPair{
+			{File: "stop", FeedA: "b", IDA: "tc", FeedB: "a", IDB: "tc"},
+			{File: "routes.txt", FeedA: "a", IDA: "r1", FeedB: "b", IDB: "r1"},
+		}),
+	)
+	merged, err := m.MergeFeeds([]*gtfs.Feed{feedA, feedB})
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: the first feed's stop falls back to a prefixed ID
+	if _, ok := merged.Stops["a-tc"]; !ok || len(merged.Stops) != 2 {
+		t.Errorf("Expected stops tc and a-tc, got %v", merged.StopOrder)
+	}
+
+	// And: the route pair is reported as unused
+	unused := m.Report().UnusedBlockedPairs
+	if len(unused) != 1 || unused[0].File != "routes.txt" {
+		t.Errorf("Expected the routes.txt pair unused, got %v", unused)
+	}
+}
+
+func TestWithBlockedDuplicatesInvalid(t *testing.T) {
+	m := New(WithBlockedDuplicates([]BlockedPair{{File: "colors.txt", FeedA: "a", IDA: "x", FeedB: "b", IDB: "y"}}))
+	if _, err := m.MergeFeeds(grayZoneFeeds()); !errors.Is(err, ErrInvalidBlockedPair) {
+		t.Errorf("Expected ErrInvalidBlockedPair, got %v", err)
+	}
+}
+
+func TestReadBlockedPairs(t *testing.T) {
+	input := "file,feed_a,id_a,feed_b,id_b\nstops.txt,north,tc,south,tc\n"
+	pairs, err := ReadBlockedPairs(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadBlockedPairs failed: %v", err)
+	}
+	want := BlockedPair{File: "stops.txt", FeedA: "north", IDA: "tc", FeedB: "south", IDB: "tc"}
+	if len(pairs) != 1 || pairs[0] != want {
+		t.Errorf("Expected [%v], got %v", want, pairs)
+	}
+
+	for _, bad := range []string{
+		"file,feed_a,id_a,feed_b\nstops.txt,north,tc,south\n",
+		"file,feed_a,id_a,feed_b,id_b\nstops.txt,north,,south,tc\n",
+		"file,feed_a,id_a,feed_b,id_b\nstops.txt,north,tc,south,tc\nbikes.txt,north,1,south,1\n",
+	} {
+		if _, err := ReadBlockedPairs(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
@@ -8,6 +8,9 @@ import (
 )
 
 // MergeContext provides context during merge operations
+//
+// Deprecated: Merger and the strategies it runs use strategy.MergeContext,
+// which custom strategies receive; this type is not used.
 type MergeContext struct {
 	// Source is the feed being merged into the target
 	Source *gtfs.Feed
@@ -30,23 +33,31 @@ type MergeContext struct {
 	ShapeIDMapping   map[gtfs.ShapeID]gtfs.ShapeID
 	FareIDMapping    map[gtfs.FareID]gtfs.FareID
 	AreaIDMapping    map[gtfs.AreaID]gtfs.AreaID
+	NetworkIDMapping map[gtfs.NetworkID]gtfs.NetworkID
+
+	// LegGroupIDMapping maps fare leg rule leg groups
+	LegGroupIDMapping map[gtfs.LegGroupID]gtfs.LegGroupID
 }
 
 // NewMergeContext creates a new merge context
+//
+// Deprecated: use strategy.NewMergeContext.
 func NewMergeContext(source, target *gtfs.Feed) *MergeContext {
 	return &MergeContext{
-		Source:           source,
-		Target:           target,
-		Prefix:           "",
-		EntityByRawID:    make(map[string]interface{}),
-		AgencyIDMapping:  make(map[gtfs.AgencyID]gtfs.AgencyID),
-		StopIDMapping:    make(map[gtfs.StopID]gtfs.StopID),
-		RouteIDMapping:   make(map[gtfs.RouteID]gtfs.RouteID),
-		TripIDMapping:    make(map[gtfs.TripID]gtfs.TripID),
-		ServiceIDMapping: make(map[gtfs.ServiceID]gtfs.ServiceID),
-		ShapeIDMapping:   make(map[gtfs.ShapeID]gtfs.ShapeID),
-		FareIDMapping:    make(map[gtfs.FareID]gtfs.FareID),
-		AreaIDMapping:    make(map[gtfs.AreaID]gtfs.AreaID),
+		Source:            source,
+		Target:            target,
+		Prefix:            "",
+		EntityByRawID:     make(map[string]interface{}),
+		AgencyIDMapping:   make(map[gtfs.AgencyID]gtfs.AgencyID),
+		StopIDMapping:     make(map[gtfs.StopID]gtfs.StopID),
+		RouteIDMapping:    make(map[gtfs.RouteID]gtfs.RouteID),
+		TripIDMapping:     make(map[gtfs.TripID]gtfs.TripID),
+		ServiceIDMapping:  make(map[gtfs.ServiceID]gtfs.ServiceID),
+		ShapeIDMapping:    make(map[gtfs.ShapeID]gtfs.ShapeID),
+		FareIDMapping:     make(map[gtfs.FareID]gtfs.FareID),
+		AreaIDMapping:     make(map[gtfs.AreaID]gtfs.AreaID),
+		NetworkIDMapping:  make(map[gtfs.NetworkID]gtfs.NetworkID),
+		LegGroupIDMapping: make(map[gtfs.LegGroupID]gtfs.LegGroupID),
 	}
 }
 
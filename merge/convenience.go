@@ -0,0 +1,19 @@
+package merge
+
+import "github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+
+// Files merges the GTFS feeds at inputs (zip files or directories) into a
+// feed written to output, configured by opts. It is shorthand for
+// New(opts...).MergeFiles(inputs, output): every missing input is reported
+// with ErrInputNotFound before any is read, and the read errors of all
+// inputs are joined rather than stopping at the first. Use a Merger directly
+// to inspect the Report afterwards.
+func Files(inputs []string, output string, opts ...Option) error {
+	return New(opts...).MergeFiles(inputs, output)
+}
+
+// Feeds merges feeds, configured by opts, and returns the merged feed. It is
+// shorthand for New(opts...).MergeFeeds(feeds).
+func Feeds(feeds []*gtfs.Feed, opts ...Option) (*gtfs.Feed, error) {
+	return New(opts...).MergeFeeds(feeds)
+}
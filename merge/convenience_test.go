@@ -0,0 +1,82 @@
+package merge
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+func TestFilesReportsEveryMissingInput(t *testing.T) {
+	// Given: two missing inputs around one that exists
+	inputs := []string{"missing_a.zip", "../testdata/simple_a", "missing_b.zip"}
+	output := filepath.Join(t.TempDir(), "merged.zip")
+
+	// When: merged
+	err := Files(inputs, output)
+
+	// Then: both missing inputs are named, and nothing is written
+	if !errors.Is(err, ErrInputNotFound) {
+		t.Fatalf("Expected ErrInputNotFound, got %v", err)
+	}
+	for _, path := range []string{"missing_a.zip", "missing_b.zip"} {
+		if !strings.Contains(err.Error(), path) {
+			t.Errorf("Expected %s in %v", path, err)
+		}
+	}
+	if _, statErr := os.Stat(output); !os.IsNotExist(statErr) {
+		t.Errorf("Expected no output, got %v", statErr)
+	}
+}
+
+func TestFilesJoinsReadErrors(t *testing.T) {
+	// Given: two inputs that exist but are not GTFS feeds
+	tmpDir := t.TempDir()
+	var inputs []string
+	for _, name := range []string{"bad_a.zip", "bad_b.zip"} {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte("not a zip"), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		inputs = append(inputs, path)
+	}
+
+	// When: merged
+	err := Files(inputs, filepath.Join(tmpDir, "merged.zip"))
+
+	// Then: the error describes both inputs
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	for _, path := range inputs {
+		if !strings.Contains(err.Error(), "reading "+path) {
+			t.Errorf("Expected an error reading %s, got %v", path, err)
+		}
+	}
+}
+
+func TestFeeds(t *testing.T) {
+	// Given: two feeds
+	a, err := gtfs.ReadFromPath("../testdata/simple_a")
+	if err != nil {
+		t.Fatalf("failed to read simple_a: %v", err)
+	}
+	b, err := gtfs.ReadFromPath("../testdata/simple_b")
+	if err != nil {
+		t.Fatalf("failed to read simple_b: %v", err)
+	}
+
+	// When: merged with an option
+	merged, err := Feeds([]*gtfs.Feed{a, b}, WithSanitizeInputs(true))
+
+	// Then: the merged feed has both feeds' stops
+	if err != nil {
+		t.Fatalf("Feeds failed: %v", err)
+	}
+	if len(merged.Stops) != 8 {
+		t.Errorf("Expected 8 stops, got %d", len(merged.Stops))
+	}
+}
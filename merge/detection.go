@@ -0,0 +1,92 @@
+package merge
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// entityKindFiles maps each gtfs.EntityKind to the GTFS files whose
+// strategies it configures
+var entityKindFiles = map[gtfs.EntityKind][]string{
+	gtfs.KindAgency:  {"agency.txt"},
+	gtfs.KindStop:    {"stops.txt"},
+	gtfs.KindRoute:   {"routes.txt"},
+	gtfs.KindTrip:    {"trips.txt"},
+	gtfs.KindService: {"calendar.txt", "calendar_dates.txt"},
+	gtfs.KindShape:   {"shapes.txt"},
+	gtfs.KindFare:    {"fare_attributes.txt"},
+	gtfs.KindArea:    {"areas.txt"},
+	gtfs.KindNetwork: {"networks.txt"},
+
+	gtfs.KindLegGroup:       {"fare_leg_rules.txt"},
+	gtfs.KindFareProduct:    {"fare_products.txt"},
+	gtfs.KindTimeframeGroup: {"timeframes.txt"},
+	gtfs.KindFareMedia:      {"fare_media.txt"},
+	gtfs.KindRiderCategory:  {"rider_categories.txt"},
+}
+
+// entityFiles resolves a file name ("stops.txt") or entity kind ("stop") to
+// the GTFS files it names, or nil if it names none
+func (m *Merger) entityFiles(entity string) []string {
+	if files, ok := entityKindFiles[gtfs.EntityKind(strings.ToLower(entity))]; ok {
+		return files
+	}
+	if m.GetStrategyForFile(entity) != nil {
+		return []string{entity}
+	}
+	return nil
+}
+
+// detectionReporter is implemented by strategies that can report their
+// duplicate detection mode, such as those embedding strategy.BaseStrategy
+type detectionReporter interface {
+	GetDuplicateDetection() strategy.DuplicateDetection
+}
+
+// DetectionFor returns the duplicate detection mode configured for entity,
+// a file name ("stops.txt") or entity kind ("stop"). The second result is
+// false if entity names no GTFS file, or its strategy cannot report its mode.
+// For an entity kind spanning several files, the first file's mode is returned.
+func (m *Merger) DetectionFor(entity string) (strategy.DuplicateDetection, bool) {
+	files := m.entityFiles(entity)
+	if len(files) == 0 {
+		return strategy.DetectionNone, false
+	}
+	r, ok := m.GetStrategyForFile(files[0]).(detectionReporter)
+	if !ok {
+		return strategy.DetectionNone, false
+	}
+	return r.GetDuplicateDetection(), true
+}
+
+// applyDetectionOverrides applies the modes set with WithDetectionFor on top
+// of whatever default detection the strategies have
+func (m *Merger) applyDetectionOverrides() {
+	for _, o := range m.detectionOverrides {
+		for _, filename := range m.entityFiles(o.entity) {
+			m.GetStrategyForFile(filename).SetDuplicateDetection(o.mode)
+		}
+	}
+}
+
+// checkDetectionEntities returns an error wrapping ErrUnknownEntity for each
+// name given WithDetectionFor that matches no file, joined, or nil
+func (m *Merger) checkDetectionEntities() error {
+	var errs []error
+	for _, o := range m.detectionOverrides {
+		if len(m.entityFiles(o.entity)) == 0 {
+			errs = append(errs, fmt.Errorf("%w: %q", ErrUnknownEntity, o.entity))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// detectionOverride is a duplicate detection mode set for one entity
+type detectionOverride struct {
+	entity string
+	mode   strategy.DuplicateDetection
+}
@@ -0,0 +1,208 @@
+package merge
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/geo"
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// DirectionConflict describes a merged route whose trips from two input
+// feeds use opposite direction_id conventions: the direction 0 trips of
+// Feed run the way the direction 1 trips of ReferenceFeed do
+type DirectionConflict struct {
+	RouteID gtfs.RouteID
+
+	// Feed names the input whose trips disagree
+	Feed string
+
+	// ReferenceFeed names the input whose convention is kept: of the
+	// inputs contributing trips to the route, the one merged first (the
+	// last input)
+	ReferenceFeed string
+
+	// Flipped reports whether Feed's trips on the route had their
+	// direction_id flipped (see WithHarmonizeDirections)
+	Flipped bool
+}
+
+// String describes the conflict for logs and warnings
+func (c DirectionConflict) String() string {
+	s := fmt.Sprintf("route %q: direction_id of trips from feed %s is opposite to feed %s", c.RouteID, c.Feed, c.ReferenceFeed)
+	if c.Flipped {
+		s += "; flipped to match"
+	}
+	return s
+}
+
+// terminals is the average position of the first and last stops of a set
+// of trips
+type terminals struct {
+	originLat, originLon float64
+	destLat, destLon     float64
+	trips                int
+}
+
+// add accumulates one trip running from origin to dest
+func (t *terminals) add(origin, dest *gtfs.Stop) {
+	t.originLat += origin.Lat
+	t.originLon += origin.Lon
+	t.destLat += dest.Lat
+	t.destLon += dest.Lon
+	t.trips++
+}
+
+// mean returns the average terminals, reversed when reverse is set
+func (t *terminals) mean(reverse bool) terminals {
+	n := float64(t.trips)
+	m := terminals{t.originLat / n, t.originLon / n, t.destLat / n, t.destLon / n, t.trips}
+	if reverse {
+		m.originLat, m.originLon, m.destLat, m.destLon = m.destLat, m.destLon, m.originLat, m.originLon
+	}
+	return m
+}
+
+// directionTerminals holds a feed's terminals on one route by direction_id
+type directionTerminals [2]terminals
+
+// forward returns the terminals of the feed's direction 0, derived from
+// its direction 1 trips when it has no direction 0 trips
+func (d *directionTerminals) forward() (terminals, bool) {
+	switch {
+	case d[0].trips > 0:
+		return d[0].mean(false), true
+	case d[1].trips > 0:
+		return d[1].mean(true), true
+	default:
+		return terminals{}, false
+	}
+}
+
+// checkDirections finds merged routes whose trips from different input
+// feeds use opposite direction_id conventions. For each such route, each
+// feed's direction 0 terminals (the average first and last stops of its
+// direction 0 trips) are compared with the reference feed's: when they
+// match the reference's reversed at less than half the distance they match
+// it as is, the feed's convention is opposite. With harmonize set, that
+// feed's trips on the route have their direction_id flipped. Trips merged
+// from more than one feed, without a direction_id, or whose terminal stops
+// have no coordinates are ignored.
+func checkDirections(feed *gtfs.Feed, names []string, harmonize bool) []DirectionConflict {
+	// Only routes with trips from more than one feed can conflict
+	candidates := make(map[gtfs.RouteID]bool)
+	for id, sources := range feed.Sources[gtfs.KindRoute] {
+		if len(sources) > 1 {
+			candidates[gtfs.RouteID(id)] = true
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	first, last := tripTerminalStops(feed)
+
+	byRoute := make(map[gtfs.RouteID]map[int]*directionTerminals)
+	for _, trip := range feed.Trips {
+		sources := feed.SourceOf(gtfs.KindTrip, string(trip.ID))
+		if !candidates[trip.RouteID] || len(sources) != 1 || trip.DirectionID == nil || (*trip.DirectionID != 0 && *trip.DirectionID != 1) {
+			continue
+		}
+		origin, dest := feed.Stops[first[trip.ID]], feed.Stops[last[trip.ID]]
+		if !hasCoordinates(origin) || !hasCoordinates(dest) {
+			continue
+		}
+		if byRoute[trip.RouteID] == nil {
+			byRoute[trip.RouteID] = make(map[int]*directionTerminals)
+		}
+		dt := byRoute[trip.RouteID][sources[0]]
+		if dt == nil {
+			dt = &directionTerminals{}
+			byRoute[trip.RouteID][sources[0]] = dt
+		}
+		dt[*trip.DirectionID].add(origin, dest)
+	}
+
+	var conflicts []DirectionConflict
+	flip := make(map[gtfs.RouteID]map[int]bool)
+	for _, routeID := range feed.RouteOrder {
+		feeds := byRoute[routeID]
+		if len(feeds) < 2 {
+			continue
+		}
+		indices := slices.Sorted(maps.Keys(feeds))
+		reference := indices[len(indices)-1]
+		ref, ok := feeds[reference].forward()
+		if !ok {
+			continue
+		}
+		for _, index := range slices.Backward(indices[:len(indices)-1]) {
+			fwd, ok := feeds[index].forward()
+			if !ok {
+				continue
+			}
+			same := geo.HaversineMeters(fwd.originLat, fwd.originLon, ref.originLat, ref.originLon) +
+				geo.HaversineMeters(fwd.destLat, fwd.destLon, ref.destLat, ref.destLon)
+			opposite := geo.HaversineMeters(fwd.originLat, fwd.originLon, ref.destLat, ref.destLon) +
+				geo.HaversineMeters(fwd.destLat, fwd.destLon, ref.originLat, ref.originLon)
+			if opposite*2 >= same {
+				continue
+			}
+			conflicts = append(conflicts, DirectionConflict{
+				RouteID:       routeID,
+				Feed:          names[index],
+				ReferenceFeed: names[reference],
+				Flipped:       harmonize,
+			})
+			if flip[routeID] == nil {
+				flip[routeID] = make(map[int]bool)
+			}
+			flip[routeID][index] = true
+		}
+	}
+
+	if harmonize && len(flip) > 0 {
+		for _, trip := range feed.Trips {
+			sources := feed.SourceOf(gtfs.KindTrip, string(trip.ID))
+			if len(sources) != 1 || !flip[trip.RouteID][sources[0]] || trip.DirectionID == nil {
+				continue
+			}
+			if d := *trip.DirectionID; d == 0 || d == 1 {
+				flipped := 1 - d
+				trip.DirectionID = &flipped
+			}
+		}
+	}
+
+	for _, c := range conflicts {
+		log.Printf("WARNING: %s", c)
+	}
+	return conflicts
+}
+
+// tripTerminalStops returns the stops with the lowest and highest
+// stop_sequence of each trip
+func tripTerminalStops(feed *gtfs.Feed) (first, last map[gtfs.TripID]gtfs.StopID) {
+	first = make(map[gtfs.TripID]gtfs.StopID)
+	last = make(map[gtfs.TripID]gtfs.StopID)
+	minSeq := make(map[gtfs.TripID]int)
+	maxSeq := make(map[gtfs.TripID]int)
+	for _, st := range feed.StopTimes {
+		if seq, seen := minSeq[st.TripID]; !seen || st.StopSequence < seq {
+			minSeq[st.TripID] = st.StopSequence
+			first[st.TripID] = st.StopID
+		}
+		if seq, seen := maxSeq[st.TripID]; !seen || st.StopSequence > seq {
+			maxSeq[st.TripID] = st.StopSequence
+			last[st.TripID] = st.StopID
+		}
+	}
+	return first, last
+}
+
+// hasCoordinates reports whether stop exists and has a position
+func hasCoordinates(stop *gtfs.Stop) bool {
+	return stop != nil && (stop.Lat != 0 || stop.Lon != 0)
+}
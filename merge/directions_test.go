@@ -0,0 +1,107 @@
+package merge
+
+import (
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// directionFeeds reads the corridor feeds whose direction 0 runs north and
+// south respectively
+func directionFeeds(t *testing.T) []*gtfs.Feed {
+	t.Helper()
+	var feeds []*gtfs.Feed
+	for _, path := range []string{"../testdata/direction_zero_north", "../testdata/direction_zero_south"} {
+		feed, err := gtfs.ReadFromPath(path)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", path, err)
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds
+}
+
+// directionOf returns the direction_id of a merged trip
+func directionOf(t *testing.T, feed *gtfs.Feed, id gtfs.TripID) int {
+	t.Helper()
+	trip := feed.Trips[id]
+	if trip == nil || trip.DirectionID == nil {
+		t.Fatalf("trip %s missing or without direction_id", id)
+	}
+	return *trip.DirectionID
+}
+
+func TestMergeDirectionConflict(t *testing.T) {
+	// Given: two feeds for the same corridor whose direction 0 runs
+	// opposite ways, merged with fuzzy detection
+	m := New(WithDefaultDetection(strategy.DetectionFuzzy))
+
+	// When: merged
+	merged, err := m.MergeFeeds(directionFeeds(t))
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: the route is reported against the feed merged first
+	conflicts := m.Report().DirectionConflicts
+	want := DirectionConflict{RouteID: "10", Feed: "a", ReferenceFeed: "b"}
+	if len(conflicts) != 1 || conflicts[0] != want {
+		t.Fatalf("Expected conflict %+v, got %+v", want, conflicts)
+	}
+	if len(m.Report().Warnings) != 1 || m.Report().Warnings[0] != want.String() {
+		t.Errorf("Expected the conflict as a warning, got %v", m.Report().Warnings)
+	}
+
+	// And: no direction_id is changed
+	if directionOf(t, merged, "nb_0800") != 0 || directionOf(t, merged, "sb_0800") != 0 {
+		t.Errorf("Expected direction_ids unchanged without WithHarmonizeDirections")
+	}
+}
+
+func TestWithHarmonizeDirections(t *testing.T) {
+	// Given: the same feeds, merged with direction harmonizing
+	m := New(WithDefaultDetection(strategy.DetectionFuzzy), WithHarmonizeDirections(true))
+
+	// When: merged
+	merged, err := m.MergeFeeds(directionFeeds(t))
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: the first feed's trips follow the second feed's convention,
+	// southbound as direction 0
+	for id, want := range map[gtfs.TripID]int{"nb_0800": 1, "nb_0900": 0, "sb_0800": 0, "sb_0900": 1} {
+		if got := directionOf(t, merged, id); got != want {
+			t.Errorf("Trip %s: expected direction_id %d, got %d", id, want, got)
+		}
+	}
+	if conflicts := m.Report().DirectionConflicts; len(conflicts) != 1 || !conflicts[0].Flipped {
+		t.Errorf("Expected one flipped conflict, got %+v", conflicts)
+	}
+}
+
+func TestMergeConsistentDirections(t *testing.T) {
+	// Given: the corridor feeds, with the second's direction_ids swapped so
+	// both use direction 0 for northbound
+	feeds := directionFeeds(t)
+	for _, trip := range feeds[1].Trips {
+		flipped := 1 - *trip.DirectionID
+		trip.DirectionID = &flipped
+	}
+
+	// When: merged with harmonizing
+	m := New(WithDefaultDetection(strategy.DetectionFuzzy), WithHarmonizeDirections(true))
+	merged, err := m.MergeFeeds(feeds)
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: nothing is reported or flipped
+	if conflicts := m.Report().DirectionConflicts; len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %+v", conflicts)
+	}
+	if directionOf(t, merged, "nb_0800") != 0 {
+		t.Errorf("Expected nb_0800 to stay direction 0")
+	}
+}
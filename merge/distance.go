@@ -0,0 +1,225 @@
+package merge
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/geo"
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/internal/enumtext"
+)
+
+// DistanceUnit is a unit of length used for shape_dist_traveled values
+type DistanceUnit int
+
+const (
+	// DistanceUnknown - the unit is unknown, or distances are left as-is
+	DistanceUnknown DistanceUnit = iota
+
+	// Meters - distances are in meters
+	Meters
+
+	// Kilometers - distances are in kilometers
+	Kilometers
+
+	// Miles - distances are in statute miles
+	Miles
+
+	// Feet - distances are in feet
+	Feet
+
+	// Auto - as a target, rescale every feed to the unit inferred for the
+	// first input feed whose unit can be inferred
+	Auto
+)
+
+// String returns the string representation of DistanceUnit
+func (u DistanceUnit) String() string {
+	switch u {
+	case DistanceUnknown:
+		return "unknown"
+	case Meters:
+		return "meters"
+	case Kilometers:
+		return "kilometers"
+	case Miles:
+		return "miles"
+	case Feet:
+		return "feet"
+	case Auto:
+		return "auto"
+	default:
+		return fmt.Sprintf("DistanceUnit(%d)", u)
+	}
+}
+
+// ParseDistanceUnit parses a string into a DistanceUnit value
+func ParseDistanceUnit(s string) (DistanceUnit, error) {
+	switch strings.ToLower(s) {
+	case "unknown":
+		return DistanceUnknown, nil
+	case "meters", "m":
+		return Meters, nil
+	case "kilometers", "km":
+		return Kilometers, nil
+	case "miles", "mi":
+		return Miles, nil
+	case "feet", "ft":
+		return Feet, nil
+	case "auto":
+		return Auto, nil
+	default:
+		return DistanceUnknown, fmt.Errorf("invalid distance unit: %q", s)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (u DistanceUnit) MarshalText() ([]byte, error) {
+	return enumtext.Marshal(u, ParseDistanceUnit)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (u *DistanceUnit) UnmarshalText(text []byte) error {
+	return enumtext.Unmarshal(u, text, ParseDistanceUnit)
+}
+
+// metersPerUnit is the length of one unit in meters
+var metersPerUnit = map[DistanceUnit]float64{
+	Meters:     1,
+	Kilometers: 1000,
+	Miles:      1609.344,
+	Feet:       0.3048,
+}
+
+// inferableUnits lists the candidate units, in tie-break order
+var inferableUnits = []DistanceUnit{Meters, Kilometers, Miles, Feet}
+
+// maxUnitRatioError is how far (as a factor) the observed distance-per-meter
+// ratio may be from a unit's expected ratio before the unit is rejected.
+// Shape geometry rarely follows the road exactly, so allow some slack.
+const maxUnitRatioError = 1.5
+
+// minShapeLengthMeters is the shortest shape used for unit inference;
+// shorter shapes are dominated by rounding in shape_dist_traveled
+const minShapeLengthMeters = 100.0
+
+// hasShapeDistances reports whether any shape point or stop time in the feed
+// carries a shape_dist_traveled value
+func hasShapeDistances(feed *gtfs.Feed) bool {
+	for _, points := range feed.Shapes {
+		for _, p := range points {
+			if p.DistTraveled != nil {
+				return true
+			}
+		}
+	}
+	for _, st := range feed.StopTimes {
+		if st.ShapeDistTraveled != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// inferDistanceUnit infers the unit of a feed's shape_dist_traveled values by
+// comparing, for each shape, the distance traveled between its first and last
+// points against the haversine length of its point sequence. The median ratio
+// across shapes is matched to the closest known unit. Returns DistanceUnknown
+// when no shape has usable distances or no unit is close enough.
+func inferDistanceUnit(feed *gtfs.Feed) DistanceUnit {
+	var ratios []float64
+	for _, points := range feed.Shapes {
+		if len(points) < 2 {
+			continue
+		}
+		sorted := make([]gtfs.ShapePoint, len(points))
+		copy(sorted, points)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].Sequence < sorted[j].Sequence
+		})
+
+		first, last := sorted[0], sorted[len(sorted)-1]
+		if first.DistTraveled == nil || last.DistTraveled == nil {
+			continue
+		}
+		traveled := *last.DistTraveled - *first.DistTraveled
+		if traveled <= 0 {
+			continue
+		}
+
+		length := 0.0
+		for i := 1; i < len(sorted); i++ {
+			length += geo.HaversineMeters(sorted[i-1].Lat, sorted[i-1].Lon, sorted[i].Lat, sorted[i].Lon)
+		}
+		if length < minShapeLengthMeters {
+			continue
+		}
+		ratios = append(ratios, traveled/length)
+	}
+	if len(ratios) == 0 {
+		return DistanceUnknown
+	}
+
+	sort.Float64s(ratios)
+	median := ratios[len(ratios)/2]
+
+	// A unit of u meters yields 1/u distance per meter; pick the unit whose
+	// expected ratio is closest on a log scale
+	best := DistanceUnknown
+	bestErr := math.Log(maxUnitRatioError)
+	for _, u := range inferableUnits {
+		if err := math.Abs(math.Log(median * metersPerUnit[u])); err < bestErr {
+			best, bestErr = u, err
+		}
+	}
+	return best
+}
+
+// distanceScales infers each feed's shape_dist_traveled unit and returns the
+// factor that converts it to target, recording both in the report. Feeds
+// without distances, or whose unit cannot be inferred, get a factor of 0
+// (left as-is).
+func distanceScales(feeds []*gtfs.Feed, target DistanceUnit, report *Report) []float64 {
+	units := make([]DistanceUnit, len(feeds))
+	for i, feed := range feeds {
+		if hasShapeDistances(feed) {
+			units[i] = inferDistanceUnit(feed)
+		}
+	}
+
+	if target == Auto {
+		target = DistanceUnknown
+		for _, u := range units {
+			if u != DistanceUnknown {
+				target = u
+				break
+			}
+		}
+	}
+
+	scales := make([]float64, len(feeds))
+	for i, feed := range feeds {
+		name := report.Feeds[i].Name
+		report.Feeds[i].ShapeDistanceUnit = units[i]
+
+		switch {
+		case !hasShapeDistances(feed):
+			continue
+		case units[i] == DistanceUnknown:
+			log.Printf("WARNING: Could not infer shape_dist_traveled unit for feed %q (leaving distances unchanged)", name)
+			continue
+		case target == DistanceUnknown || units[i] == target:
+			log.Printf("Feed %q: shape_dist_traveled inferred as %s (unchanged)", name, units[i])
+			continue
+		}
+
+		scales[i] = metersPerUnit[units[i]] / metersPerUnit[target]
+		report.Feeds[i].ShapeDistanceScale = scales[i]
+		log.Printf("Feed %q: shape_dist_traveled inferred as %s, rescaled to %s (x%g)", name, units[i], target, scales[i])
+	}
+
+	return scales
+}
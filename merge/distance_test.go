@@ -0,0 +1,213 @@
+package merge
+
+import (
+	"math"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/geo"
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// metersPerHundredthDegree is the haversine length of 0.01° of latitude, in meters
+var metersPerHundredthDegree = geo.HaversineMeters(47.0, -122.0, 47.01, -122.0)
+
+// distanceFeed builds a feed with one three-point shape running north and a
+// stop time at its end, with distances expressed in the given unit.
+// Pass DistanceUnknown to omit shape_dist_traveled entirely.
+func distanceFeed(shapeID string, unit DistanceUnit) *gtfs.Feed {
+	feed := gtfs.NewFeed()
+	var endDist *float64
+	for i := 0; i < 3; i++ {
+		var dist *float64
+		if unit != DistanceUnknown {
+			d := float64(i) * metersPerHundredthDegree / metersPerUnit[unit]
+			dist = &d
+			endDist = dist
+		}
+		feed.AddShape(gtfs.ShapePoint{
+			ShapeID:      gtfs.ShapeID(shapeID),
+			Lat:          47.0 + float64(i)*0.01,
+			Lon:          -122.0,
+			Sequence:     i + 1,
+			DistTraveled: dist,
+		})
+	}
+	feed.StopTimes = append(feed.StopTimes, &gtfs.StopTime{
+		TripID:            gtfs.TripID("trip-" + shapeID),
+		StopID:            gtfs.StopID("stop-" + shapeID),
+		StopSequence:      1,
+		ShapeDistTraveled: endDist,
+	})
+	return feed
+}
+
+func TestInferDistanceUnit(t *testing.T) {
+	tests := []struct {
+		name     string
+		unit     DistanceUnit
+		expected DistanceUnit
+	}{
+		{"meters", Meters, Meters},
+		{"kilometers", Kilometers, Kilometers},
+		{"miles", Miles, Miles},
+		{"feet", Feet, Feet},
+		{"no distances", DistanceUnknown, DistanceUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := inferDistanceUnit(distanceFeed("s", tt.unit))
+			if got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestInferDistanceUnitRejectsImplausibleRatio(t *testing.T) {
+	// Given: distances that are 10x the meter length, matching no unit
+	feed := distanceFeed("s", Meters)
+	points := feed.Shapes["s"]
+	for i := range points {
+		d := *points[i].DistTraveled * 10
+		points[i].DistTraveled = &d
+	}
+
+	// Then: the unit is unknown
+	if got := inferDistanceUnit(feed); got != DistanceUnknown {
+		t.Errorf("Expected unknown unit, got %s", got)
+	}
+}
+
+func TestMergeWithShapeDistanceUnitMeters(t *testing.T) {
+	// Given: one feed in meters and one in miles
+	feedA := distanceFeed("sa", Meters)
+	feedB := distanceFeed("sb", Miles)
+
+	// When: merged with normalization to meters
+	m := New(WithShapeDistanceUnit(Meters))
+	merged, err := m.MergeFeeds([]*gtfs.Feed{feedA, feedB})
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: both shapes end at the same distance in meters
+	expected := 2 * metersPerHundredthDegree
+	for _, id := range []gtfs.ShapeID{"sa", "sb"} {
+		points := merged.Shapes[id]
+		if len(points) != 3 {
+			t.Fatalf("Expected 3 points for shape %s, got %d", id, len(points))
+		}
+		got := *points[2].DistTraveled
+		if math.Abs(got-expected) > 1e-6 {
+			t.Errorf("Shape %s: expected end distance %.3f, got %.3f", id, expected, got)
+		}
+	}
+
+	// And: stop_times are rescaled too
+	for _, st := range merged.StopTimes {
+		if math.Abs(*st.ShapeDistTraveled-expected) > 1e-6 {
+			t.Errorf("Stop time for %s: expected %.3f, got %.3f", st.TripID, expected, *st.ShapeDistTraveled)
+		}
+	}
+
+	// And: the report records the inference and rescaling per feed
+	report := m.Report()
+	a, b := report.FeedByName("a"), report.FeedByName("b")
+	if a.ShapeDistanceUnit != Meters || a.ShapeDistanceScale != 0 {
+		t.Errorf("Expected feed a inferred as meters and unscaled, got %s x%g", a.ShapeDistanceUnit, a.ShapeDistanceScale)
+	}
+	if b.ShapeDistanceUnit != Miles || b.ShapeDistanceScale != metersPerUnit[Miles] {
+		t.Errorf("Expected feed b inferred as miles and scaled x%g, got %s x%g", metersPerUnit[Miles], b.ShapeDistanceUnit, b.ShapeDistanceScale)
+	}
+
+	// And: the source feeds are not modified
+	if got := *feedB.Shapes["sb"][2].DistTraveled; math.Abs(got-expected/metersPerUnit[Miles]) > 1e-9 {
+		t.Errorf("Expected source feed to keep miles, got %f", got)
+	}
+}
+
+func TestMergeWithShapeDistanceUnitAuto(t *testing.T) {
+	// Given: the first feed in miles and the second in kilometers
+	feedA := distanceFeed("sa", Miles)
+	feedB := distanceFeed("sb", Kilometers)
+
+	// When: merged with automatic normalization
+	m := New(WithShapeDistanceUnit(Auto))
+	merged, err := m.MergeFeeds([]*gtfs.Feed{feedA, feedB})
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: everything is expressed in the first feed's unit (miles)
+	expected := 2 * metersPerHundredthDegree / metersPerUnit[Miles]
+	for _, id := range []gtfs.ShapeID{"sa", "sb"} {
+		got := *merged.Shapes[id][2].DistTraveled
+		if math.Abs(got-expected) > 1e-9 {
+			t.Errorf("Shape %s: expected end distance %.6f miles, got %.6f", id, expected, got)
+		}
+	}
+}
+
+func TestMergeWithShapeDistanceUnitSkipsFeedsWithoutDistances(t *testing.T) {
+	// Given: one feed in miles and one without shape_dist_traveled
+	feedA := distanceFeed("sa", DistanceUnknown)
+	feedB := distanceFeed("sb", Miles)
+
+	m := New(WithShapeDistanceUnit(Meters))
+	merged, err := m.MergeFeeds([]*gtfs.Feed{feedA, feedB})
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: the feed without distances stays without distances
+	for _, p := range merged.Shapes["sa"] {
+		if p.DistTraveled != nil {
+			t.Errorf("Expected no distance for shape sa, got %f", *p.DistTraveled)
+		}
+	}
+	if fr := m.Report().FeedByName("a"); fr.ShapeDistanceUnit != DistanceUnknown || fr.ShapeDistanceScale != 0 {
+		t.Errorf("Expected feed a untouched, got %s x%g", fr.ShapeDistanceUnit, fr.ShapeDistanceScale)
+	}
+}
+
+func TestMergeWithoutShapeDistanceUnitLeavesDistances(t *testing.T) {
+	// Given: mixed units but no normalization requested
+	feedB := distanceFeed("sb", Miles)
+	merged, err := New().MergeFeeds([]*gtfs.Feed{distanceFeed("sa", Meters), feedB})
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: distances are copied as-is
+	if got, want := *merged.Shapes["sb"][2].DistTraveled, *feedB.Shapes["sb"][2].DistTraveled; got != want {
+		t.Errorf("Expected %f, got %f", want, got)
+	}
+}
+
+func TestParseDistanceUnit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected DistanceUnit
+		wantErr  bool
+	}{
+		{"meters", Meters, false},
+		{"KM", Kilometers, false},
+		{"mi", Miles, false},
+		{"feet", Feet, false},
+		{"auto", Auto, false},
+		{"furlongs", DistanceUnknown, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseDistanceUnit(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDistanceUnit(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("ParseDistanceUnit(%q) = %s, want %s", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package merge
+
+import (
+	"encoding"
+	"fmt"
+	"testing"
+)
+
+func TestEnumsText(t *testing.T) {
+	testEnumText(t, []DistanceUnit{DistanceUnknown, Meters, Kilometers, Miles, Feet, Auto})
+	testEnumText(t, []RouteSortOrderStrategy{SortOrderKeep, SortOrderOffsetPerFeed, SortOrderReassign})
+	testEnumText(t, []StopCodePolicy{StopCodesAllowDuplicates, StopCodesWarn, StopCodesPrefix, StopCodesError})
+}
+
+// testEnumText checks that each of values marshals to its String and
+// unmarshals back to itself, and that unknown values and names are rejected
+func testEnumText[T interface {
+	~int
+	fmt.Stringer
+	encoding.TextMarshaler
+}, PT interface {
+	*T
+	encoding.TextUnmarshaler
+}](t *testing.T, values []T) {
+	t.Helper()
+	for _, v := range values {
+		text, err := v.MarshalText()
+		if err != nil || string(text) != v.String() {
+			t.Errorf("%T(%d).MarshalText() = %q, %v, want %q", v, v, text, err, v.String())
+		}
+		var got T
+		if err := PT(&got).UnmarshalText(text); err != nil || got != v {
+			t.Errorf("%T.UnmarshalText(%q) = %v, %v, want %v", v, text, got, err, v)
+		}
+	}
+
+	if text, err := T(99).MarshalText(); err == nil {
+		t.Errorf("%T(99).MarshalText() = %q, want error", T(99), text)
+	}
+	var got T
+	if err := PT(&got).UnmarshalText([]byte("bogus")); err == nil {
+		t.Errorf("%T.UnmarshalText(%q) = %v, want error", got, "bogus", got)
+	}
+}
@@ -0,0 +1,151 @@
+package merge_test
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/merge"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// Merge two feeds on disk into a zip file with the default configuration:
+// entities are only merged when they share an ID.
+func ExampleFiles() {
+	dir, err := os.MkdirTemp("", "gtfs-merge-example")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "merged.zip")
+
+	inputs := []string{"../testdata/simple_a", "../testdata/simple_b"}
+	if err := merge.Files(inputs, output); err != nil {
+		log.Fatal(err)
+	}
+
+	merged, err := gtfs.ReadFromPath(output)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("agencies:", len(merged.Agencies))
+	fmt.Println("stops:", len(merged.Stops))
+	// Output:
+	// agencies: 3
+	// stops: 8
+}
+
+// Merge feeds already in memory, detecting duplicates by their attributes
+// rather than their IDs, so that the stops two agencies both publish under
+// different IDs appear once.
+func ExampleFeeds_withFuzzyDetection() {
+	a, err := gtfs.ReadFromPath("../testdata/simple_a")
+	if err != nil {
+		log.Fatal(err)
+	}
+	b, err := gtfs.ReadFromPath("../testdata/fuzzy_similar")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	merged, err := merge.Feeds([]*gtfs.Feed{a, b},
+		merge.WithDefaultDetection(strategy.DetectionFuzzy))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("stops:", len(merged.Stops))
+	// Output:
+	// stops: 5
+}
+
+// Use a Merger rather than Files to see what each input contributed.
+func ExampleMerger_report() {
+	dir, err := os.MkdirTemp("", "gtfs-merge-example")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := merge.New(merge.WithDefaultDetection(strategy.DetectionIdentity))
+	inputs := []string{"../testdata/simple_a", "../testdata/overlap"}
+	if err := m.MergeFiles(inputs, filepath.Join(dir, "merged.zip")); err != nil {
+		log.Fatal(err)
+	}
+
+	// The later input is merged first, so the stops simple_a shares with
+	// overlap are the ones not added
+	for _, fr := range m.Report().Feeds {
+		fmt.Printf("%s: read %d stops, added %d\n", fr.Name, fr.Read["stops.txt"], fr.Added["stops.txt"])
+	}
+	// Output:
+	// simple_a: read 5 stops, added 3
+	// overlap: read 2 stops, added 2
+}
+
+// stopAmenity is a row of stop_amenities.txt, a hypothetical vendor
+// extension the gtfs package does not read
+type stopAmenity struct {
+	StopID  gtfs.StopID
+	Amenity string
+}
+
+// amenityStrategy merges stop_amenities.txt after pathways.txt, whose
+// strategy it wraps, as it needs the stops merged: each row follows its stop
+// to its ID in the merged feed.
+type amenityStrategy struct {
+	strategy.EntityMergeStrategy
+	rows   map[string][]stopAmenity // by input name (MergeContext.SourceFeed)
+	merged []stopAmenity
+}
+
+func (s *amenityStrategy) Merge(ctx *strategy.MergeContext) error {
+	if err := s.EntityMergeStrategy.Merge(ctx); err != nil {
+		return err
+	}
+	for _, row := range s.rows[ctx.SourceFeed] {
+		stopID, ok := ctx.MapStopID(row.StopID)
+		if !ok {
+			continue // the input has no such stop
+		}
+		s.merged = append(s.merged, stopAmenity{StopID: stopID, Amenity: row.Amenity})
+	}
+	return nil
+}
+
+// Merge a file of a vendor extension with a custom strategy, which looks up
+// the merged IDs of the entities it references in the MergeContext. Two
+// copies of one feed are merged without duplicate detection, so the first
+// input's stops are renamed.
+func ExampleMerger_SetPathwayStrategy() {
+	a, err := gtfs.ReadFromPath("../testdata/simple_a")
+	if err != nil {
+		log.Fatal(err)
+	}
+	b, err := gtfs.ReadFromPath("../testdata/simple_a")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	amenities := &amenityStrategy{
+		EntityMergeStrategy: strategy.NewPathwayMergeStrategy(),
+		rows: map[string][]stopAmenity{
+			"a": {{StopID: "stop_a1", Amenity: "bike racks"}},
+			"b": {{StopID: "stop_a1", Amenity: "elevator"}, {StopID: "stop_z9", Amenity: "toilets"}},
+		},
+	}
+	m := merge.New()
+	m.SetPathwayStrategy(amenities)
+	if _, err := m.MergeFeeds([]*gtfs.Feed{a, b}); err != nil {
+		log.Fatal(err)
+	}
+
+	// The later input is merged first, keeping its IDs
+	for _, row := range amenities.merged {
+		fmt.Printf("%s: %s\n", row.StopID, row.Amenity)
+	}
+	// Output:
+	// stop_a1: elevator
+	// a-stop_a1: bike racks
+}
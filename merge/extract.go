@@ -0,0 +1,660 @@
+package merge
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// ErrEmptyPrefix indicates Extract was called without a prefix. The last
+// input feed of a merge keeps its IDs unprefixed, so it cannot be extracted
+// by prefix; use ExtractSource instead.
+var ErrEmptyPrefix = errors.New("extract prefix is empty")
+
+// ErrNoProvenance indicates ExtractSource was given a feed without
+// provenance, i.e. one that was read rather than merged
+var ErrNoProvenance = errors.New("feed has no provenance")
+
+// ErrExtractCollision indicates that stripping the prefix would give two
+// extracted entities of the same kind the same ID
+var ErrExtractCollision = errors.New("extracted IDs collide")
+
+// Extract returns the part of a merged feed that came from the input feed
+// renamed with prefix (e.g. "a-"): every entity whose ID carries the prefix,
+// plus the entities they reference (a trip's route, service, shape and stops,
+// a route's agency, a stop's parent station, a fare's rules and their routes,
+// a Fares v2 leg group's networks, areas, timeframes and fare products, and a
+// fare product's rider categories and fare media), with the prefix stripped
+// from all extracted IDs. Stop times, calendar dates, frequencies,
+// transfers, pathways and fare transfer rules follow the entities they
+// belong to.
+//
+// Because prefixes are only applied on ID collisions, an input's entities
+// that never collided are not found by prefix unless something prefixed
+// references them; ExtractSource uses provenance instead. feed_info.txt is
+// not extracted.
+func Extract(feed *gtfs.Feed, prefix string) (*gtfs.Feed, error) {
+	if prefix == "" {
+		return nil, ErrEmptyPrefix
+	}
+	seed := func(_ gtfs.EntityKind, id string) bool {
+		return strings.HasPrefix(id, prefix)
+	}
+	return extract(feed, seed, func(id string) string { return strings.TrimPrefix(id, prefix) })
+}
+
+// ExtractSource returns the part of report's merged feed (Report.Feed) that
+// came from the input feed at index (0-based, the position in
+// Report.Feeds), according to the feed's provenance (see
+// gtfs.Feed.SourceOf), along with the entities those reference. The
+// prefixes the report records for that input (see FeedReport.Prefixes)
+// are stripped, outermost first, as with Extract. Entities deduplicated
+// across feeds are included under their merged ID.
+func ExtractSource(report *Report, index int) (*gtfs.Feed, error) {
+	if report == nil || report.Feed == nil || report.Feed.Sources == nil {
+		return nil, ErrNoProvenance
+	}
+	if index < 0 || index >= len(report.Feeds) {
+		return nil, fmt.Errorf("input %d out of range: the merge had %d inputs", index, len(report.Feeds))
+	}
+	feed := report.Feed
+	prefixes := report.Feeds[index].Prefixes()
+	strip := func(id string) string {
+		for _, prefix := range prefixes {
+			id = strings.TrimPrefix(id, prefix)
+		}
+		return id
+	}
+	seed := func(kind gtfs.EntityKind, id string) bool {
+		for _, i := range feed.SourceOf(kind, id) {
+			if i == index {
+				return true
+			}
+		}
+		return false
+	}
+	return extract(feed, seed, strip)
+}
+
+// extractor collects the IDs of the entities selected for extraction
+type extractor struct {
+	feed     *gtfs.Feed
+	agencies map[gtfs.AgencyID]bool
+	stops    map[gtfs.StopID]bool
+	routes   map[gtfs.RouteID]bool
+	trips    map[gtfs.TripID]bool
+	services map[gtfs.ServiceID]bool
+	shapes   map[gtfs.ShapeID]bool
+	fares    map[gtfs.FareID]bool
+	areas    map[gtfs.AreaID]bool
+	networks map[gtfs.NetworkID]bool
+
+	legGroups       map[gtfs.LegGroupID]bool
+	fareProducts    map[gtfs.FareProductID]bool
+	timeframeGroups map[gtfs.TimeframeGroupID]bool
+	fareMedia       map[gtfs.FareMediaID]bool
+	riderCategories map[gtfs.RiderCategoryID]bool
+}
+
+// extract selects the entities seed accepts and everything they reference,
+// and copies them into a new feed with their IDs stripped by strip
+func extract(feed *gtfs.Feed, seed func(kind gtfs.EntityKind, id string) bool, strip func(string) string) (*gtfs.Feed, error) {
+	x := &extractor{
+		feed:     feed,
+		agencies: make(map[gtfs.AgencyID]bool),
+		stops:    make(map[gtfs.StopID]bool),
+		routes:   make(map[gtfs.RouteID]bool),
+		trips:    make(map[gtfs.TripID]bool),
+		services: make(map[gtfs.ServiceID]bool),
+		shapes:   make(map[gtfs.ShapeID]bool),
+		fares:    make(map[gtfs.FareID]bool),
+		areas:    make(map[gtfs.AreaID]bool),
+		networks: make(map[gtfs.NetworkID]bool),
+
+		legGroups:       make(map[gtfs.LegGroupID]bool),
+		fareProducts:    make(map[gtfs.FareProductID]bool),
+		timeframeGroups: make(map[gtfs.TimeframeGroupID]bool),
+		fareMedia:       make(map[gtfs.FareMediaID]bool),
+		riderCategories: make(map[gtfs.RiderCategoryID]bool),
+	}
+	x.seed(seed)
+	x.addReferences()
+	return x.build(strip)
+}
+
+// seed selects the entities accepted by seed
+func (x *extractor) seed(seed func(kind gtfs.EntityKind, id string) bool) {
+	f := x.feed
+	for _, id := range f.AgencyOrder {
+		x.agencies[id] = seed(gtfs.KindAgency, string(id))
+	}
+	for _, id := range f.StopOrder {
+		x.stops[id] = seed(gtfs.KindStop, string(id))
+	}
+	for _, id := range f.RouteOrder {
+		x.routes[id] = seed(gtfs.KindRoute, string(id))
+	}
+	for _, id := range f.TripOrder {
+		x.trips[id] = seed(gtfs.KindTrip, string(id))
+	}
+	for _, id := range f.CalendarOrder {
+		x.services[id] = seed(gtfs.KindService, string(id))
+	}
+	for _, id := range f.CalendarDateOrder {
+		x.services[id] = x.services[id] || seed(gtfs.KindService, string(id))
+	}
+	for _, id := range sortedShapeIDs(f) {
+		x.shapes[id] = seed(gtfs.KindShape, string(id))
+	}
+	for _, id := range f.FareAttrOrder {
+		x.fares[id] = seed(gtfs.KindFare, string(id))
+	}
+	for _, id := range f.AreaOrder {
+		x.areas[id] = seed(gtfs.KindArea, string(id))
+	}
+	for _, id := range f.NetworkOrder {
+		x.networks[id] = seed(gtfs.KindNetwork, string(id))
+	}
+	for _, rule := range f.FareLegRules {
+		if rule.LegGroupID != "" {
+			x.legGroups[rule.LegGroupID] = seed(gtfs.KindLegGroup, string(rule.LegGroupID))
+		}
+	}
+	for _, rule := range f.FareTransferRules {
+		for _, id := range []gtfs.LegGroupID{rule.FromLegGroupID, rule.ToLegGroupID} {
+			if id != "" {
+				x.legGroups[id] = seed(gtfs.KindLegGroup, string(id))
+			}
+		}
+	}
+	for _, product := range f.FareProducts {
+		x.fareProducts[product.ID] = seed(gtfs.KindFareProduct, string(product.ID))
+	}
+	for _, tf := range f.Timeframes {
+		x.timeframeGroups[tf.GroupID] = seed(gtfs.KindTimeframeGroup, string(tf.GroupID))
+	}
+	for _, id := range f.FareMediaOrder {
+		x.fareMedia[id] = seed(gtfs.KindFareMedia, string(id))
+	}
+	for _, id := range f.RiderCategoryOrder {
+		x.riderCategories[id] = seed(gtfs.KindRiderCategory, string(id))
+	}
+}
+
+// addReferences selects the entities referenced by the selected ones.
+// References only point from trips and fares down to routes, stops and
+// agencies, and from routes to networks, which Fares v2 leg rules without a
+// leg group are selected by, so a single pass in that order reaches them
+// all.
+func (x *extractor) addReferences() {
+	f := x.feed
+	for id, selected := range x.trips {
+		if !selected {
+			continue
+		}
+		trip := f.Trips[id]
+		x.routes[trip.RouteID] = true
+		x.services[trip.ServiceID] = true
+		if trip.ShapeID != "" {
+			x.shapes[trip.ShapeID] = true
+		}
+	}
+	for _, st := range f.StopTimes {
+		if x.trips[st.TripID] {
+			x.stops[st.StopID] = true
+		}
+	}
+	for _, rule := range f.FareRules {
+		if x.fares[rule.FareID] && rule.RouteID != "" {
+			x.routes[rule.RouteID] = true
+		}
+	}
+
+	for id, selected := range x.routes {
+		if route, ok := f.Routes[id]; selected && ok && route.AgencyID != "" {
+			x.agencies[route.AgencyID] = true
+		}
+		if route, ok := f.Routes[id]; selected && ok && route.NetworkID != "" {
+			x.networks[route.NetworkID] = true
+		}
+	}
+	for _, rn := range f.RouteNetworks {
+		if x.routes[rn.RouteID] {
+			x.networks[rn.NetworkID] = true
+		}
+	}
+	for id, selected := range x.fares {
+		if fare, ok := f.FareAttributes[id]; selected && ok && fare.AgencyID != "" {
+			x.agencies[fare.AgencyID] = true
+		}
+	}
+
+	for _, rule := range f.FareTransferRules {
+		if x.fareTransferRuleSelected(rule) && rule.FareProductID != "" {
+			x.fareProducts[rule.FareProductID] = true
+		}
+	}
+	for _, rule := range f.FareLegRules {
+		if !x.fareLegRuleSelected(rule) {
+			continue
+		}
+		for _, id := range []gtfs.AreaID{rule.FromAreaID, rule.ToAreaID} {
+			if id != "" {
+				x.areas[id] = true
+			}
+		}
+		for _, id := range []gtfs.TimeframeGroupID{rule.FromTimeframeGroupID, rule.ToTimeframeGroupID} {
+			if id != "" {
+				x.timeframeGroups[id] = true
+			}
+		}
+		if rule.NetworkID != "" {
+			x.networks[rule.NetworkID] = true
+		}
+		if rule.FareProductID != "" {
+			x.fareProducts[rule.FareProductID] = true
+		}
+	}
+	for _, product := range f.FareProducts {
+		if !x.fareProducts[product.ID] {
+			continue
+		}
+		if product.RiderCategoryID != "" {
+			x.riderCategories[product.RiderCategoryID] = true
+		}
+		if product.FareMediaID != "" {
+			x.fareMedia[product.FareMediaID] = true
+		}
+	}
+	for _, tf := range f.Timeframes {
+		if x.timeframeGroups[tf.GroupID] && tf.ServiceID != "" {
+			x.services[tf.ServiceID] = true
+		}
+	}
+
+	// Parent stations can nest (platform → station), so follow them up
+	for changed := true; changed; {
+		changed = false
+		for id, selected := range x.stops {
+			stop, ok := f.Stops[id]
+			if !selected || !ok || stop.ParentStation == "" || x.stops[stop.ParentStation] {
+				continue
+			}
+			x.stops[stop.ParentStation] = true
+			changed = true
+		}
+	}
+}
+
+// build copies the selected entities into a new feed, stripping their IDs
+// and references with strip
+func (x *extractor) build(strip func(string) string) (*gtfs.Feed, error) {
+	f := x.feed
+	out := gtfs.NewFeed()
+	for filename, cols := range f.ColumnSets {
+		for col := range cols {
+			out.AddColumn(filename, col)
+		}
+	}
+	collision := func(kind gtfs.EntityKind, id string) error {
+		return fmt.Errorf("%w: %s %q", ErrExtractCollision, kind, id)
+	}
+
+	for _, id := range f.AgencyOrder {
+		if !x.agencies[id] {
+			continue
+		}
+		a := *f.Agencies[id]
+		a.ID = gtfs.AgencyID(strip(string(a.ID)))
+		if _, exists := out.Agencies[a.ID]; exists {
+			return nil, collision(gtfs.KindAgency, string(a.ID))
+		}
+		out.AddAgency(&a)
+	}
+
+	for _, id := range f.StopOrder {
+		if !x.stops[id] {
+			continue
+		}
+		s := *f.Stops[id]
+		s.ID = gtfs.StopID(strip(string(s.ID)))
+		s.ParentStation = gtfs.StopID(strip(string(s.ParentStation)))
+		if _, exists := out.Stops[s.ID]; exists {
+			return nil, collision(gtfs.KindStop, string(s.ID))
+		}
+		out.AddStop(&s)
+	}
+
+	for _, id := range f.RouteOrder {
+		if !x.routes[id] {
+			continue
+		}
+		r := *f.Routes[id]
+		r.ID = gtfs.RouteID(strip(string(r.ID)))
+		r.AgencyID = gtfs.AgencyID(strip(string(r.AgencyID)))
+		r.NetworkID = gtfs.NetworkID(strip(string(r.NetworkID)))
+		if _, exists := out.Routes[r.ID]; exists {
+			return nil, collision(gtfs.KindRoute, string(r.ID))
+		}
+		out.AddRoute(&r)
+	}
+
+	for _, id := range f.TripOrder {
+		if !x.trips[id] {
+			continue
+		}
+		t := *f.Trips[id]
+		t.ID = gtfs.TripID(strip(string(t.ID)))
+		t.RouteID = gtfs.RouteID(strip(string(t.RouteID)))
+		t.ServiceID = gtfs.ServiceID(strip(string(t.ServiceID)))
+		t.ShapeID = gtfs.ShapeID(strip(string(t.ShapeID)))
+		if _, exists := out.Trips[t.ID]; exists {
+			return nil, collision(gtfs.KindTrip, string(t.ID))
+		}
+		out.AddTrip(&t)
+	}
+
+	for _, st := range f.StopTimes {
+		if !x.trips[st.TripID] {
+			continue
+		}
+		c := *st
+		c.TripID = gtfs.TripID(strip(string(c.TripID)))
+		c.StopID = gtfs.StopID(strip(string(c.StopID)))
+		out.StopTimes = append(out.StopTimes, &c)
+	}
+
+	for _, id := range f.CalendarOrder {
+		if !x.services[id] {
+			continue
+		}
+		c := *f.Calendars[id]
+		c.ServiceID = gtfs.ServiceID(strip(string(c.ServiceID)))
+		if _, exists := out.Calendars[c.ServiceID]; exists {
+			return nil, collision(gtfs.KindService, string(c.ServiceID))
+		}
+		out.AddCalendar(&c)
+	}
+
+	for _, id := range f.CalendarDateOrder {
+		if !x.services[id] {
+			continue
+		}
+		newID := gtfs.ServiceID(strip(string(id)))
+		if _, exists := out.CalendarDates[newID]; exists {
+			return nil, collision(gtfs.KindService, string(newID))
+		}
+		for _, cd := range f.CalendarDates[id] {
+			c := *cd
+			c.ServiceID = newID
+			out.AddCalendarDate(&c)
+		}
+	}
+
+	for _, id := range sortedShapeIDs(f) {
+		if !x.shapes[id] {
+			continue
+		}
+		newID := gtfs.ShapeID(strip(string(id)))
+		if _, exists := out.Shapes[newID]; exists {
+			return nil, collision(gtfs.KindShape, string(newID))
+		}
+		for _, p := range f.Shapes[id] {
+			p.ShapeID = newID
+			out.AddShape(p)
+		}
+	}
+
+	for _, freq := range f.Frequencies {
+		if !x.trips[freq.TripID] {
+			continue
+		}
+		c := *freq
+		c.TripID = gtfs.TripID(strip(string(c.TripID)))
+		out.Frequencies = append(out.Frequencies, &c)
+	}
+
+	for _, tr := range f.Transfers {
+		if !x.transferSelected(tr) {
+			continue
+		}
+		c := *tr
+		c.FromStopID = gtfs.StopID(strip(string(c.FromStopID)))
+		c.ToStopID = gtfs.StopID(strip(string(c.ToStopID)))
+		c.FromRouteID = gtfs.RouteID(strip(string(c.FromRouteID)))
+		c.ToRouteID = gtfs.RouteID(strip(string(c.ToRouteID)))
+		c.FromTripID = gtfs.TripID(strip(string(c.FromTripID)))
+		c.ToTripID = gtfs.TripID(strip(string(c.ToTripID)))
+		out.Transfers = append(out.Transfers, &c)
+	}
+
+	for _, id := range f.FareAttrOrder {
+		if !x.fares[id] {
+			continue
+		}
+		fa := *f.FareAttributes[id]
+		fa.FareID = gtfs.FareID(strip(string(fa.FareID)))
+		fa.AgencyID = gtfs.AgencyID(strip(string(fa.AgencyID)))
+		if _, exists := out.FareAttributes[fa.FareID]; exists {
+			return nil, collision(gtfs.KindFare, string(fa.FareID))
+		}
+		out.AddFareAttribute(&fa)
+	}
+
+	for _, rule := range f.FareRules {
+		if !x.fares[rule.FareID] {
+			continue
+		}
+		c := *rule
+		c.FareID = gtfs.FareID(strip(string(c.FareID)))
+		c.RouteID = gtfs.RouteID(strip(string(c.RouteID)))
+		out.FareRules = append(out.FareRules, &c)
+	}
+
+	for _, id := range f.AreaOrder {
+		if !x.areas[id] {
+			continue
+		}
+		a := *f.Areas[id]
+		a.ID = gtfs.AreaID(strip(string(a.ID)))
+		if _, exists := out.Areas[a.ID]; exists {
+			return nil, collision(gtfs.KindArea, string(a.ID))
+		}
+		out.AddArea(&a)
+	}
+
+	for _, id := range f.NetworkOrder {
+		if !x.networks[id] {
+			continue
+		}
+		n := *f.Networks[id]
+		n.ID = gtfs.NetworkID(strip(string(n.ID)))
+		if _, exists := out.Networks[n.ID]; exists {
+			return nil, collision(gtfs.KindNetwork, string(n.ID))
+		}
+		out.AddNetwork(&n)
+	}
+
+	for _, rn := range f.RouteNetworks {
+		if !x.routes[rn.RouteID] {
+			continue
+		}
+		c := *rn
+		c.NetworkID = gtfs.NetworkID(strip(string(c.NetworkID)))
+		c.RouteID = gtfs.RouteID(strip(string(c.RouteID)))
+		out.RouteNetworks = append(out.RouteNetworks, &c)
+	}
+
+	for _, p := range f.Pathways {
+		if !x.stops[p.FromStopID] || !x.stops[p.ToStopID] {
+			continue
+		}
+		c := *p
+		c.ID = strip(c.ID)
+		c.FromStopID = gtfs.StopID(strip(string(c.FromStopID)))
+		c.ToStopID = gtfs.StopID(strip(string(c.ToStopID)))
+		out.Pathways = append(out.Pathways, &c)
+	}
+
+	if err := x.buildFaresV2(out, strip, collision); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// buildFaresV2 copies the selected Fares v2 entities into out, stripping
+// their IDs and references with strip. Leg groups, fare products and
+// timeframe groups span several rows, so two of them collide only if they
+// had different IDs before stripping.
+func (x *extractor) buildFaresV2(out *gtfs.Feed, strip func(string) string, collision func(gtfs.EntityKind, string) error) error {
+	f := x.feed
+
+	for _, id := range f.FareMediaOrder {
+		if !x.fareMedia[id] {
+			continue
+		}
+		m := *f.FareMedia[id]
+		m.ID = gtfs.FareMediaID(strip(string(m.ID)))
+		if _, exists := out.FareMedia[m.ID]; exists {
+			return collision(gtfs.KindFareMedia, string(m.ID))
+		}
+		out.AddFareMedia(&m)
+	}
+
+	for _, id := range f.RiderCategoryOrder {
+		if !x.riderCategories[id] {
+			continue
+		}
+		c := *f.RiderCategories[id]
+		c.ID = gtfs.RiderCategoryID(strip(string(c.ID)))
+		if _, exists := out.RiderCategories[c.ID]; exists {
+			return collision(gtfs.KindRiderCategory, string(c.ID))
+		}
+		out.AddRiderCategory(&c)
+	}
+
+	// stripped maps each stripped ID of a kind to the ID it was stripped
+	// from, to catch two IDs stripping to the same one
+	stripped := make(map[gtfs.EntityKind]map[string]string)
+	stripGroup := func(kind gtfs.EntityKind, id string) (string, error) {
+		if id == "" {
+			return "", nil
+		}
+		if stripped[kind] == nil {
+			stripped[kind] = make(map[string]string)
+		}
+		newID := strip(id)
+		if from, ok := stripped[kind][newID]; ok && from != id {
+			return "", collision(kind, newID)
+		}
+		stripped[kind][newID] = id
+		return newID, nil
+	}
+
+	for _, product := range f.FareProducts {
+		if !x.fareProducts[product.ID] {
+			continue
+		}
+		c := *product
+		id, err := stripGroup(gtfs.KindFareProduct, string(c.ID))
+		if err != nil {
+			return err
+		}
+		c.ID = gtfs.FareProductID(id)
+		c.RiderCategoryID = gtfs.RiderCategoryID(strip(string(c.RiderCategoryID)))
+		c.FareMediaID = gtfs.FareMediaID(strip(string(c.FareMediaID)))
+		out.FareProducts = append(out.FareProducts, &c)
+	}
+
+	for _, tf := range f.Timeframes {
+		if !x.timeframeGroups[tf.GroupID] {
+			continue
+		}
+		c := *tf
+		id, err := stripGroup(gtfs.KindTimeframeGroup, string(c.GroupID))
+		if err != nil {
+			return err
+		}
+		c.GroupID = gtfs.TimeframeGroupID(id)
+		c.ServiceID = gtfs.ServiceID(strip(string(c.ServiceID)))
+		out.Timeframes = append(out.Timeframes, &c)
+	}
+
+	for _, rule := range f.FareLegRules {
+		if !x.fareLegRuleSelected(rule) {
+			continue
+		}
+		c := *rule
+		id, err := stripGroup(gtfs.KindLegGroup, string(c.LegGroupID))
+		if err != nil {
+			return err
+		}
+		c.LegGroupID = gtfs.LegGroupID(id)
+		c.NetworkID = gtfs.NetworkID(strip(string(c.NetworkID)))
+		c.FromAreaID = gtfs.AreaID(strip(string(c.FromAreaID)))
+		c.ToAreaID = gtfs.AreaID(strip(string(c.ToAreaID)))
+		c.FromTimeframeGroupID = gtfs.TimeframeGroupID(strip(string(c.FromTimeframeGroupID)))
+		c.ToTimeframeGroupID = gtfs.TimeframeGroupID(strip(string(c.ToTimeframeGroupID)))
+		c.FareProductID = gtfs.FareProductID(strip(string(c.FareProductID)))
+		out.FareLegRules = append(out.FareLegRules, &c)
+	}
+
+	for _, rule := range f.FareTransferRules {
+		if !x.fareTransferRuleSelected(rule) {
+			continue
+		}
+		c := *rule
+		c.FromLegGroupID = gtfs.LegGroupID(strip(string(c.FromLegGroupID)))
+		c.ToLegGroupID = gtfs.LegGroupID(strip(string(c.ToLegGroupID)))
+		c.FareProductID = gtfs.FareProductID(strip(string(c.FareProductID)))
+		out.FareTransferRules = append(out.FareTransferRules, &c)
+	}
+
+	return nil
+}
+
+// fareLegRuleSelected reports whether a fare leg rule was extracted: the
+// rules of a selected leg group are, and a rule without a leg group is if
+// its network or one of its areas was
+func (x *extractor) fareLegRuleSelected(rule *gtfs.FareLegRule) bool {
+	if rule.LegGroupID != "" {
+		return x.legGroups[rule.LegGroupID]
+	}
+	return (rule.NetworkID != "" && x.networks[rule.NetworkID]) ||
+		(rule.FromAreaID != "" && x.areas[rule.FromAreaID]) ||
+		(rule.ToAreaID != "" && x.areas[rule.ToAreaID])
+}
+
+// fareTransferRuleSelected reports whether every leg group a fare transfer
+// rule names was extracted
+func (x *extractor) fareTransferRuleSelected(rule *gtfs.FareTransferRule) bool {
+	return (rule.FromLegGroupID == "" || x.legGroups[rule.FromLegGroupID]) &&
+		(rule.ToLegGroupID == "" || x.legGroups[rule.ToLegGroupID]) &&
+		(rule.FromLegGroupID != "" || rule.ToLegGroupID != "")
+}
+
+// sortedShapeIDs returns the feed's shape IDs in sorted order. Merged feeds
+// don't track ShapeOrder, so the Shapes map is the only complete list.
+func sortedShapeIDs(f *gtfs.Feed) []gtfs.ShapeID {
+	ids := make([]gtfs.ShapeID, 0, len(f.Shapes))
+	for id := range f.Shapes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// transferSelected reports whether every entity a transfer references was
+// extracted
+func (x *extractor) transferSelected(tr *gtfs.Transfer) bool {
+	return (tr.FromStopID == "" || x.stops[tr.FromStopID]) &&
+		(tr.ToStopID == "" || x.stops[tr.ToStopID]) &&
+		(tr.FromRouteID == "" || x.routes[tr.FromRouteID]) &&
+		(tr.ToRouteID == "" || x.routes[tr.ToRouteID]) &&
+		(tr.FromTripID == "" || x.trips[tr.FromTripID]) &&
+		(tr.ToTripID == "" || x.trips[tr.ToTripID]) &&
+		(tr.FromStopID != "" || tr.ToStopID != "" || tr.FromRouteID != "" || tr.ToRouteID != "" || tr.FromTripID != "" || tr.ToTripID != "")
+}
@@ -0,0 +1,241 @@
+package merge
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// assertSameRowCounts compares two feeds' row counts, ignoring feed_info.txt
+// (which Extract does not carry over)
+func assertSameRowCounts(t *testing.T, expected, got *gtfs.Feed) {
+	t.Helper()
+	want, have := expected.RowCounts(), got.RowCounts()
+	delete(want, "feed_info.txt")
+	delete(have, "feed_info.txt")
+	for _, filename := range gtfs.FileNames() {
+		if want[filename] != have[filename] {
+			t.Errorf("%s: expected %d rows, got %d", filename, want[filename], have[filename])
+		}
+	}
+}
+
+func TestExtractRoundTrip(t *testing.T) {
+	// Given: a feed merged with a copy of itself, so every ID of the first
+	// copy collides and is prefixed with "a-"
+	original, err := gtfs.ReadFromPath("../testdata/all_optional_feed")
+	if err != nil {
+		t.Fatalf("failed to read all_optional_feed: %v", err)
+	}
+	copyFeed, err := gtfs.ReadFromPath("../testdata/all_optional_feed")
+	if err != nil {
+		t.Fatalf("failed to read all_optional_feed: %v", err)
+	}
+	merged, err := New().MergeFeeds([]*gtfs.Feed{original, copyFeed})
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// When: the "a-" part is extracted
+	extracted, err := Extract(merged, "a-")
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+
+	// Then: it has as many rows as the original feed
+	assertSameRowCounts(t, original, extracted)
+
+	// And: its IDs have the prefix stripped
+	for id := range original.Trips {
+		trip, ok := extracted.Trips[id]
+		if !ok {
+			t.Errorf("Expected trip %s in extracted feed", id)
+			continue
+		}
+		if trip.RouteID != original.Trips[id].RouteID {
+			t.Errorf("Trip %s: expected route %s, got %s", id, original.Trips[id].RouteID, trip.RouteID)
+		}
+	}
+}
+
+func TestExtractIncludesUnprefixedReferences(t *testing.T) {
+	// Given: a prefixed trip on an unprefixed route, service and stop
+	feed := gtfs.NewFeed()
+	feed.AddAgency(&gtfs.Agency{ID: "agency1", Name: "Agency"})
+	feed.AddRoute(&gtfs.Route{ID: "route1", AgencyID: "agency1"})
+	feed.AddRoute(&gtfs.Route{ID: "route2", AgencyID: "agency1"})
+	feed.AddCalendar(&gtfs.Calendar{ServiceID: "weekday"})
+	feed.AddStop(&gtfs.Stop{ID: "station", LocationType: 1})
+	feed.AddStop(&gtfs.Stop{ID: "platform", ParentStation: "station"})
+	feed.AddStop(&gtfs.Stop{ID: "elsewhere"})
+	feed.AddTrip(&gtfs.Trip{ID: "b-trip1", RouteID: "route1", ServiceID: "weekday"})
+	feed.StopTimes = append(feed.StopTimes, &gtfs.StopTime{TripID: "b-trip1", StopID: "platform", StopSequence: 1})
+
+	// When: the "b-" part is extracted
+	extracted, err := Extract(feed, "b-")
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+
+	// Then: the trip and everything it references is included
+	if _, ok := extracted.Trips["trip1"]; !ok {
+		t.Error("Expected trip1 with the prefix stripped")
+	}
+	for _, id := range []gtfs.StopID{"platform", "station"} {
+		if _, ok := extracted.Stops[id]; !ok {
+			t.Errorf("Expected stop %s", id)
+		}
+	}
+	if _, ok := extracted.Agencies["agency1"]; !ok {
+		t.Error("Expected agency1")
+	}
+
+	// And: unreferenced entities are not
+	if _, ok := extracted.Routes["route2"]; ok {
+		t.Error("Expected route2 to be left out")
+	}
+	if _, ok := extracted.Stops["elsewhere"]; ok {
+		t.Error("Expected stop elsewhere to be left out")
+	}
+}
+
+func TestExtractSourceRoundTrip(t *testing.T) {
+	// Given: two feeds merged with provenance
+	feedA, err := gtfs.ReadFromPath("../testdata/simple_a")
+	if err != nil {
+		t.Fatalf("failed to read simple_a: %v", err)
+	}
+	feedB, err := gtfs.ReadFromPath("../testdata/simple_b")
+	if err != nil {
+		t.Fatalf("failed to read simple_b: %v", err)
+	}
+	_, report, err := New().MergeFeedsWithReport(context.Background(), []*gtfs.Feed{feedA, feedB})
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// When/Then: each input is extracted with its original row counts
+	for i, original := range []*gtfs.Feed{feedA, feedB} {
+		extracted, err := ExtractSource(report, i)
+		if err != nil {
+			t.Fatalf("ExtractSource(%d) failed: %v", i, err)
+		}
+		assertSameRowCounts(t, original, extracted)
+	}
+}
+
+func TestExtractSourcePipeline(t *testing.T) {
+	// Given: a pipeline merging simple_a with itself, so the first copy's
+	// IDs are prefixed "a-", and then that result with a third copy, which
+	// prefixes the result's colliding IDs with "b-"
+	read := func() *gtfs.Feed {
+		feed, err := gtfs.ReadFromPath("../testdata/simple_a")
+		if err != nil {
+			t.Fatalf("failed to read simple_a: %v", err)
+		}
+		return feed
+	}
+	original := read()
+	_, report, err := Pipeline([]Stage{
+		{Feeds: []*gtfs.Feed{read(), read()}},
+		{Feeds: []*gtfs.Feed{read()}},
+	})
+	if err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+	if got := report.Feeds[0].Prefixes(); !slices.Equal(got, []string{"b-", "a-"}) {
+		t.Errorf("Expected the first input's prefixes [b- a-], got %v", got)
+	}
+
+	// When/Then: each input is extracted with its original IDs
+	for i := range report.Feeds {
+		extracted, err := ExtractSource(report, i)
+		if err != nil {
+			t.Fatalf("ExtractSource(%d) failed: %v", i, err)
+		}
+		assertSameRowCounts(t, original, extracted)
+		if got, want := slices.Sorted(maps.Keys(extracted.Stops)), slices.Sorted(maps.Keys(original.Stops)); !slices.Equal(got, want) {
+			t.Errorf("input %d: expected stops %v, got %v", i, want, got)
+		}
+	}
+
+	// And: an index past the inputs is an error
+	if _, err := ExtractSource(report, len(report.Feeds)); err == nil {
+		t.Error("Expected an error for an index out of range")
+	}
+}
+
+func TestExtractFaresV2(t *testing.T) {
+	// Given: a Fares v2 feed merged with a copy of itself, so every ID of
+	// the first copy collides and is prefixed with "a-"
+	read := func() *gtfs.Feed {
+		feed, err := gtfs.ReadFromPath("../testdata/fares_v2")
+		if err != nil {
+			t.Fatalf("failed to read fares_v2: %v", err)
+		}
+		return feed
+	}
+	original := read()
+	merged, report, err := New().MergeFeedsWithReport(context.Background(), []*gtfs.Feed{read(), read()})
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// When: the "a-" part is extracted, and each input by provenance
+	byPrefix, err := Extract(merged, "a-")
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	extracted := []*gtfs.Feed{byPrefix}
+	for i := range 2 {
+		feed, err := ExtractSource(report, i)
+		if err != nil {
+			t.Fatalf("ExtractSource(%d) failed: %v", i, err)
+		}
+		extracted = append(extracted, feed)
+	}
+
+	// Then: each has the original's Fares v2 rows, with their IDs and
+	// references stripped of the prefix
+	for _, feed := range extracted {
+		assertSameRowCounts(t, original, feed)
+		if err := feed.ValidateAll(); err != nil {
+			t.Errorf("Expected a valid feed, got %v", err)
+		}
+		for i, rule := range feed.FareLegRules {
+			if *rule != *original.FareLegRules[i] {
+				t.Errorf("Expected fare leg rule %+v, got %+v", *original.FareLegRules[i], *rule)
+			}
+		}
+		for i, product := range feed.FareProducts {
+			if *product != *original.FareProducts[i] {
+				t.Errorf("Expected fare product %+v, got %+v", *original.FareProducts[i], *product)
+			}
+		}
+	}
+}
+
+func TestExtractErrors(t *testing.T) {
+	feed := gtfs.NewFeed()
+
+	if _, err := Extract(feed, ""); !errors.Is(err, ErrEmptyPrefix) {
+		t.Errorf("Expected ErrEmptyPrefix, got %v", err)
+	}
+	if _, err := ExtractSource(&Report{Feed: feed}, 0); !errors.Is(err, ErrNoProvenance) {
+		t.Errorf("Expected ErrNoProvenance, got %v", err)
+	}
+
+	// Stripping "a-" from a-stop1 collides with an unprefixed stop1 that a
+	// prefixed trip also references
+	feed.AddStop(&gtfs.Stop{ID: "a-stop1"})
+	feed.AddStop(&gtfs.Stop{ID: "stop1"})
+	feed.AddTrip(&gtfs.Trip{ID: "a-trip1"})
+	feed.StopTimes = append(feed.StopTimes, &gtfs.StopTime{TripID: "a-trip1", StopID: "stop1"})
+	if _, err := Extract(feed, "a-"); !errors.Is(err, ErrExtractCollision) {
+		t.Errorf("Expected ErrExtractCollision, got %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package merge
+
+import (
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// readFaresV2Twice returns two copies of the Fares v2 fixture
+func readFaresV2Twice(t *testing.T) []*gtfs.Feed {
+	t.Helper()
+	var feeds []*gtfs.Feed
+	for range 2 {
+		feed, err := gtfs.ReadFromPath("../testdata/fares_v2")
+		if err != nil {
+			t.Fatalf("failed to read fares_v2: %v", err)
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds
+}
+
+func TestMergeFareTransferRulesFollowLegGroups(t *testing.T) {
+	// Given: two feeds with the same leg groups and transfer rules
+	feeds := readFaresV2Twice(t)
+
+	// When: merged without duplicate detection
+	merged, err := New(WithStrictOutput(true)).MergeFeeds(feeds)
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: the first feed's leg groups are prefixed, and its transfer rules
+	// point at them rather than at the other feed's groups
+	transfers := make(map[gtfs.LegGroupID]gtfs.LegGroupID)
+	for _, rule := range merged.FareTransferRules {
+		if rule.FromLegGroupID != rule.ToLegGroupID {
+			transfers[rule.FromLegGroupID] = rule.ToLegGroupID
+		}
+	}
+	if len(merged.FareLegRules) != 4 || len(merged.FareTransferRules) != 4 {
+		t.Errorf("Expected 4 fare_leg_rules and 4 fare_transfer_rules, got %d and %d", len(merged.FareLegRules), len(merged.FareTransferRules))
+	}
+	if transfers["local_leg"] != "express_leg" || transfers["a-local_leg"] != "a-express_leg" {
+		t.Errorf("Expected each feed's transfers within its own leg groups, got %v", transfers)
+	}
+	for _, rule := range merged.FareLegRules {
+		if rule.LegGroupID == "a-express_leg" && (rule.NetworkID != "a-premium" ||
+			rule.FromTimeframeGroupID != "a-peak" || rule.FareProductID != "a-express_ride") {
+			t.Errorf("Expected a-express_leg in a-premium at a-peak for a-express_ride, got %+v", rule)
+		}
+	}
+
+	// And: the fare products, timeframes, fare media and rider categories
+	// they name are carried through, the first feed's prefixed
+	if len(merged.FareProducts) != 6 || len(merged.Timeframes) != 2 ||
+		len(merged.FareMedia) != 2 || len(merged.RiderCategories) != 4 {
+		t.Errorf("Expected 6 fare_products, 2 timeframes, 2 fare_media and 4 rider_categories, got %d, %d, %d and %d",
+			len(merged.FareProducts), len(merged.Timeframes), len(merged.FareMedia), len(merged.RiderCategories))
+	}
+	for _, product := range merged.FareProducts {
+		if product.ID == "a-single_ride" && (product.FareMediaID != "a-card" || product.RiderCategoryID[:2] != "a-") {
+			t.Errorf("Expected a-single_ride on a-card for an a- rider category, got %+v", product)
+		}
+	}
+	for _, tf := range merged.Timeframes {
+		if tf.GroupID == "a-peak" && tf.ServiceID != "a-weekday" {
+			t.Errorf("Expected a-peak on service a-weekday, got %q", tf.ServiceID)
+		}
+	}
+	if errs := merged.Validate(); len(errs) > 0 {
+		t.Errorf("Expected a valid merged feed, got %v", errs)
+	}
+}
+
+func TestMergeFareTransferRulesIdentity(t *testing.T) {
+	// Given: two feeds with the same leg groups and transfer rules
+	feeds := readFaresV2Twice(t)
+
+	// When: merged with identity detection
+	merged, err := New(WithDefaultDetection(strategy.DetectionIdentity)).MergeFeeds(feeds)
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: the rules appear once
+	if len(merged.FareLegRules) != 2 || len(merged.FareTransferRules) != 2 {
+		t.Errorf("Expected 2 fare_leg_rules and 2 fare_transfer_rules, got %d and %d", len(merged.FareLegRules), len(merged.FareTransferRules))
+	}
+
+	// And: so do the fare products, timeframes, fare media and rider
+	// categories
+	if len(merged.FareProducts) != 3 || len(merged.Timeframes) != 1 ||
+		len(merged.FareMedia) != 1 || len(merged.RiderCategories) != 2 {
+		t.Errorf("Expected 3 fare_products, 1 timeframe, 1 fare_media and 2 rider_categories, got %d, %d, %d and %d",
+			len(merged.FareProducts), len(merged.Timeframes), len(merged.FareMedia), len(merged.RiderCategories))
+	}
+	if errs := merged.Validate(); len(errs) > 0 {
+		t.Errorf("Expected a valid merged feed, got %v", errs)
+	}
+}
+
+func TestFaresV2DefaultLoggingAndRenaming(t *testing.T) {
+	// Given: a merger with default logging and renaming
+	m := New(WithDefaultLogging(strategy.LogError), WithDefaultRenaming(strategy.RenameAgency))
+
+	// Then: they reach every Fares v2 strategy
+	for _, s := range []strategy.EntityMergeStrategy{
+		m.fareMediaStrategy,
+		m.riderCategoryStrategy,
+		m.fareProductStrategy,
+		m.timeframeStrategy,
+		m.fareLegRuleStrategy,
+		m.fareTransferStrategy,
+	} {
+		base := baseStrategy(t, s)
+		if base.DuplicateLogging != strategy.LogError || base.RenamingStrategy != strategy.RenameAgency {
+			t.Errorf("Expected %s to log errors and rename by agency, got %v and %v", s.Name(), base.DuplicateLogging, base.RenamingStrategy)
+		}
+	}
+}
+
+// baseStrategy returns the BaseStrategy a merge strategy embeds
+func baseStrategy(t *testing.T, s strategy.EntityMergeStrategy) *strategy.BaseStrategy {
+	t.Helper()
+	switch s := s.(type) {
+	case *strategy.NetworkMergeStrategy:
+		return &s.BaseStrategy
+	case *strategy.RouteNetworkMergeStrategy:
+		return &s.BaseStrategy
+	case *strategy.FareMediaMergeStrategy:
+		return &s.BaseStrategy
+	case *strategy.RiderCategoryMergeStrategy:
+		return &s.BaseStrategy
+	case *strategy.FareProductMergeStrategy:
+		return &s.BaseStrategy
+	case *strategy.TimeframeMergeStrategy:
+		return &s.BaseStrategy
+	case *strategy.FareLegRuleMergeStrategy:
+		return &s.BaseStrategy
+	case *strategy.FareTransferRuleMergeStrategy:
+		return &s.BaseStrategy
+	}
+	t.Fatalf("unexpected strategy %T", s)
+	return nil
+}
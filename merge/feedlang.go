@@ -0,0 +1,75 @@
+package merge
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// multipleLanguages is the feed_lang GTFS prescribes for feeds whose
+// content is in more than one language
+const multipleLanguages = "mul"
+
+// consolidateFeedLanguages reconciles the feed_lang and default_lang values
+// of the merged feed_info rows. When the rows declare different feed_lang
+// values, every row is set to "mul"; default_lang is kept only when all rows
+// agree on it. Returns a warning for each change, or nil when nothing changed.
+func consolidateFeedLanguages(feed *gtfs.Feed) []string {
+	if len(feed.FeedInfos) < 2 {
+		return nil
+	}
+
+	var warnings []string
+
+	if langs := distinctFeedInfoValues(feed, func(fi *gtfs.FeedInfo) string { return fi.Lang }); len(langs) > 1 {
+		for _, fi := range feed.FeedInfos {
+			fi.Lang = multipleLanguages
+		}
+		warnings = append(warnings, fmt.Sprintf("Merged feeds declare different feed_lang values (%s); setting feed_lang to %q",
+			strings.Join(langs, ", "), multipleLanguages))
+	}
+
+	defaults := distinctFeedInfoValues(feed, func(fi *gtfs.FeedInfo) string { return fi.DefaultLang })
+	if len(defaults) > 1 || (len(defaults) == 1 && anyFeedInfoValueEmpty(feed, func(fi *gtfs.FeedInfo) string { return fi.DefaultLang })) {
+		for _, fi := range feed.FeedInfos {
+			fi.DefaultLang = ""
+		}
+		warnings = append(warnings, fmt.Sprintf("Merged feeds do not agree on default_lang (%s); dropping default_lang",
+			strings.Join(defaults, ", ")))
+	}
+
+	for _, w := range warnings {
+		log.Printf("WARNING: %s", w)
+	}
+	return warnings
+}
+
+// distinctFeedInfoValues returns the sorted non-empty values of a feed_info
+// field, compared case-insensitively as language tags are
+func distinctFeedInfoValues(feed *gtfs.Feed, field func(*gtfs.FeedInfo) string) []string {
+	seen := make(map[string]bool)
+	var values []string
+	for _, fi := range feed.FeedInfos {
+		v := field(fi)
+		if v == "" || seen[strings.ToLower(v)] {
+			continue
+		}
+		seen[strings.ToLower(v)] = true
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// anyFeedInfoValueEmpty reports whether any feed_info row leaves a field blank
+func anyFeedInfoValueEmpty(feed *gtfs.Feed, field func(*gtfs.FeedInfo) string) bool {
+	for _, fi := range feed.FeedInfos {
+		if field(fi) == "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package merge
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// feedWithFeedInfo builds a feed with a single feed_info row
+func feedWithFeedInfo(feedID, lang, defaultLang string) *gtfs.Feed {
+	feed := gtfs.NewFeed()
+	feed.AddFeedInfo(&gtfs.FeedInfo{
+		FeedID:        feedID,
+		PublisherName: "Publisher " + feedID,
+		PublisherURL:  "http://example.com/" + feedID,
+		Lang:          lang,
+		DefaultLang:   defaultLang,
+	})
+	return feed
+}
+
+func TestConsolidateFeedLanguages(t *testing.T) {
+	tests := []struct {
+		name            string
+		langs           [2]string
+		defaults        [2]string
+		expectedLang    [2]string
+		expectedDefault string
+		expectWarnings  int
+	}{
+		{"same language", [2]string{"en", "en"}, [2]string{"", ""}, [2]string{"en", "en"}, "", 0},
+		{"case-insensitive match", [2]string{"en", "EN"}, [2]string{"", ""}, [2]string{"en", "EN"}, "", 0},
+		{"different languages", [2]string{"en", "es"}, [2]string{"", ""}, [2]string{"mul", "mul"}, "", 1},
+		{"agreeing default_lang kept", [2]string{"en", "es"}, [2]string{"en", "en"}, [2]string{"mul", "mul"}, "en", 1},
+		{"differing default_lang dropped", [2]string{"en", "es"}, [2]string{"en", "es"}, [2]string{"mul", "mul"}, "", 2},
+		{"partial default_lang dropped", [2]string{"en", "en"}, [2]string{"en", ""}, [2]string{"en", "en"}, "", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: two feeds with the given feed_info languages
+			feedA := feedWithFeedInfo("a", tt.langs[0], tt.defaults[0])
+			feedB := feedWithFeedInfo("b", tt.langs[1], tt.defaults[1])
+
+			// When: merged
+			m := New()
+			merged, err := m.MergeFeeds([]*gtfs.Feed{feedA, feedB})
+			if err != nil {
+				t.Fatalf("MergeFeeds failed: %v", err)
+			}
+
+			// Then: languages are consolidated
+			for i, id := range []string{"a", "b"} {
+				fi := merged.FeedInfos[id]
+				if fi.Lang != tt.expectedLang[i] {
+					t.Errorf("feed %s: expected feed_lang %q, got %q", id, tt.expectedLang[i], fi.Lang)
+				}
+				if fi.DefaultLang != tt.expectedDefault {
+					t.Errorf("feed %s: expected default_lang %q, got %q", id, tt.expectedDefault, fi.DefaultLang)
+				}
+			}
+			if got := len(m.Report().Warnings); got != tt.expectWarnings {
+				t.Errorf("Expected %d warnings, got %d: %v", tt.expectWarnings, got, m.Report().Warnings)
+			}
+		})
+	}
+}
+
+func TestConsolidateFeedLanguagesWarningListsLanguages(t *testing.T) {
+	m := New()
+	_, err := m.MergeFeeds([]*gtfs.Feed{
+		feedWithFeedInfo("a", "es", ""),
+		feedWithFeedInfo("b", "en", ""),
+		feedWithFeedInfo("c", "fr", ""),
+	})
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	warnings := m.Report().Warnings
+	if len(warnings) != 1 {
+		t.Fatalf("Expected 1 warning, got %v", warnings)
+	}
+	if !strings.Contains(warnings[0], "(en, es, fr)") {
+		t.Errorf("Expected warning to list source languages, got %q", warnings[0])
+	}
+}
+
+func TestConsolidateFeedLanguagesSingleFeed(t *testing.T) {
+	feed := feedWithFeedInfo("a", "en", "en")
+	if warnings := consolidateFeedLanguages(feed); warnings != nil {
+		t.Errorf("Expected no warnings for a single feed_info row, got %v", warnings)
+	}
+	if fi := feed.FeedInfos["a"]; fi.Lang != "en" || fi.DefaultLang != "en" {
+		t.Errorf("Expected single row unchanged, got %+v", fi)
+	}
+}
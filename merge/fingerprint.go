@@ -0,0 +1,217 @@
+package merge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"reflect"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// fingerprint identifies a MergeFiles run by the content of its inputs and
+// output and by the Merger's configuration; see WithFingerprintCache. It is
+// stored as JSON.
+type fingerprint struct {
+	// Config is the hash of the Merger's configuration (see configHash)
+	Config string `json:"config"`
+
+	// Inputs are the inputs, in order
+	Inputs []inputFingerprint `json:"inputs"`
+
+	// Output is the output path, and OutputHash its content hash once
+	// written
+	Output     string `json:"output"`
+	OutputHash string `json:"output_hash"`
+}
+
+// inputFingerprint is an input path and its content hash (see
+// gtfs.HashPath)
+type inputFingerprint struct {
+	Path string `json:"path"`
+	Hash string `json:"hash"`
+}
+
+// newFingerprint fingerprints a run of m merging inputPaths into
+// outputPath, hashing each input without parsing it. OutputHash is left
+// empty.
+func (m *Merger) newFingerprint(inputPaths []string, outputPath string) (*fingerprint, error) {
+	config, err := m.configHash()
+	if err != nil {
+		return nil, err
+	}
+	f := &fingerprint{Config: config, Output: outputPath}
+	for _, path := range inputPaths {
+		hash, err := gtfs.HashPath(path)
+		if err != nil {
+			return nil, fmt.Errorf("hashing %s: %w", path, err)
+		}
+		f.Inputs = append(f.Inputs, inputFingerprint{Path: path, Hash: hash})
+	}
+	return f, nil
+}
+
+// unchanged reports whether the run cached, read from the fingerprint
+// cache, had f's configuration, inputs and output, and its output is still
+// as it was written
+func (f *fingerprint) unchanged(cached *fingerprint) bool {
+	if cached == nil || cached.Config != f.Config || cached.Output != f.Output ||
+		!slices.Equal(cached.Inputs, f.Inputs) || cached.OutputHash == "" {
+		return false
+	}
+	hash, err := gtfs.HashPath(f.Output)
+	return err == nil && hash == cached.OutputHash
+}
+
+// loadFingerprint reads the fingerprint cached at path, or returns nil if
+// there is none
+func loadFingerprint(path string) (*fingerprint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f fingerprint
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// save records f, with the hash of the output it now names, at path
+func (f *fingerprint) save(path string) error {
+	hash, err := gtfs.HashPath(f.Output)
+	if err != nil {
+		return err
+	}
+	f.OutputHash = hash
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// fingerprintIgnored names the Merger fields that do not shape the merged
+// feed, and so are left out of its configuration hash
+var fingerprintIgnored = []string{"debug", "metrics", "fingerprintCache", "reportMu", "report"}
+
+// configHash returns the hex SHA-256 of the options and strategies that
+// shape the merged feed, and of the overrides files they name. Every
+// Merger field is included unless listed in fingerprintIgnored, so that an
+// option added later invalidates the cache without further changes here.
+func (m *Merger) configHash() (string, error) {
+	h := sha256.New()
+	v := reflect.ValueOf(m).Elem()
+	for i := range v.NumField() {
+		name := v.Type().Field(i).Name
+		if slices.Contains(fingerprintIgnored, name) {
+			continue
+		}
+		_, _ = fmt.Fprintf(h, "%s=", name)
+		if name == "serviceFrom" {
+			// A time.Time's location is lazily loaded, so its fields can
+			// differ for the same time
+			_, _ = io.WriteString(h, m.serviceFrom.Format(time.RFC3339))
+		} else if err := writeConfigValue(h, v.Field(i)); err != nil {
+			return "", err
+		}
+		_, _ = io.WriteString(h, "\n")
+	}
+	for _, index := range slices.Sorted(maps.Keys(m.overridePaths)) {
+		data, err := os.ReadFile(m.overridePaths[index])
+		if err != nil {
+			return "", fmt.Errorf("reading overrides: %w", err)
+		}
+		_, _ = fmt.Fprintf(h, "overrides %d=%x\n", index, sha256.Sum256(data))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// anonymousFunc matches the names the runtime gives function literals and
+// method values, e.g. "main.main.func1" and "strategy.T.Normalize-fm"
+var anonymousFunc = regexp.MustCompile(`\.func\d+(\.\d+)*$|-fm$`)
+
+// writeConfigValue writes v to w in a form that is the same for the same
+// configuration from run to run: pointers and interfaces are followed
+// rather than printed as addresses, map entries are sorted, and named
+// functions, such as a strategy.NormalizerFunc, are written as their
+// names. It returns an error for a function literal or method value, whose
+// behavior may depend on the variables it captures, so that a run
+// configured with one is not cached.
+func writeConfigValue(w io.Writer, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			_, _ = io.WriteString(w, "nil")
+			return nil
+		}
+		if v.Kind() == reflect.Interface {
+			_, _ = fmt.Fprintf(w, "%s:", v.Elem().Type())
+		}
+		return writeConfigValue(w, v.Elem())
+	case reflect.Struct:
+		_, _ = fmt.Fprintf(w, "%s{", v.Type())
+		for i := range v.NumField() {
+			_, _ = fmt.Fprintf(w, "%s:", v.Type().Field(i).Name)
+			if err := writeConfigValue(w, v.Field(i)); err != nil {
+				return err
+			}
+			_, _ = io.WriteString(w, " ")
+		}
+		_, _ = io.WriteString(w, "}")
+	case reflect.Slice, reflect.Array:
+		_, _ = io.WriteString(w, "[")
+		for i := range v.Len() {
+			if err := writeConfigValue(w, v.Index(i)); err != nil {
+				return err
+			}
+			_, _ = io.WriteString(w, " ")
+		}
+		_, _ = io.WriteString(w, "]")
+	case reflect.Map:
+		entries := make([]string, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			var entry strings.Builder
+			if err := writeConfigValue(&entry, iter.Key()); err != nil {
+				return err
+			}
+			_, _ = entry.WriteString(":")
+			if err := writeConfigValue(&entry, iter.Value()); err != nil {
+				return err
+			}
+			entries = append(entries, entry.String())
+		}
+		slices.Sort(entries)
+		_, _ = fmt.Fprintf(w, "map%v", entries)
+	case reflect.Func:
+		if v.IsNil() {
+			_, _ = fmt.Fprintf(w, "%s(nil)", v.Type())
+			return nil
+		}
+		name := runtime.FuncForPC(v.Pointer()).Name()
+		if anonymousFunc.MatchString(name) {
+			return fmt.Errorf("configuration includes the anonymous function %s", name)
+		}
+		_, _ = fmt.Fprintf(w, "%s(%s)", v.Type(), name)
+	case reflect.Chan, reflect.UnsafePointer:
+		_, _ = fmt.Fprintf(w, "%s(nil=%t)", v.Type(), v.IsNil())
+	default:
+		_, _ = fmt.Fprintf(w, "%v", v)
+	}
+	return nil
+}
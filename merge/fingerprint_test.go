@@ -0,0 +1,171 @@
+package merge
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// copyFixtures copies the named feeds from testdata into a temporary
+// directory and returns their paths there
+func copyFixtures(t *testing.T, dirs ...string) []string {
+	t.Helper()
+	root := t.TempDir()
+	paths := make([]string, len(dirs))
+	for i, dir := range dirs {
+		paths[i] = filepath.Join(root, dir)
+		if err := os.CopyFS(paths[i], os.DirFS(filepath.Join("..", "testdata", dir))); err != nil {
+			t.Fatalf("failed to copy %s: %v", dir, err)
+		}
+	}
+	return paths
+}
+
+func TestWithFingerprintCache(t *testing.T) {
+	inputs := copyFixtures(t, "simple_a", "overlap")
+	dir := t.TempDir()
+	output := filepath.Join(dir, "merged.zip")
+	cache := filepath.Join(dir, "fingerprint.json")
+
+	// merge runs MergeFiles with the cache and opts, and reports whether it
+	// was a cache hit
+	merge := func(opts ...Option) bool {
+		t.Helper()
+		m := New(append(opts, WithFingerprintCache(cache))...)
+		if err := m.MergeFiles(inputs, output); err != nil {
+			t.Fatalf("MergeFiles failed: %v", err)
+		}
+		return m.Report().CacheHit
+	}
+
+	// Given: a first merge, which has nothing cached
+	if merge() {
+		t.Fatal("Expected the first merge to miss the cache")
+	}
+	if _, err := os.Stat(cache); err != nil {
+		t.Fatalf("Expected the cache to be written: %v", err)
+	}
+
+	t.Run("hit", func(t *testing.T) {
+		// When: merging again, unchanged, with the options given anew
+		// Then: the merge is skipped
+		if !merge() {
+			t.Error("Expected a cache hit")
+		}
+	})
+
+	t.Run("miss due to option change", func(t *testing.T) {
+		// When: merging with other options
+		opts := []Option{WithDetectionFor("stop", strategy.DetectionFuzzy)}
+
+		// Then: the merge runs, and is cached for the new options
+		if merge(opts...) {
+			t.Error("Expected a cache miss")
+		}
+		if !merge(opts...) {
+			t.Error("Expected a cache hit repeating the new options")
+		}
+	})
+
+	t.Run("miss due to normalizer change", func(t *testing.T) {
+		// Given: a merge with stop names case folded
+		if merge(WithNormalizer("stop", strategy.CaseFold)) {
+			t.Error("Expected a cache miss")
+		}
+
+		// When: merging with another normalizer of the same type
+		// Then: the merge runs
+		if merge(WithNormalizer("stop", strategy.StripLeadingZeros)) {
+			t.Error("Expected a cache miss for a different normalizer")
+		}
+		if !merge(WithNormalizer("stop", strategy.StripLeadingZeros)) {
+			t.Error("Expected a cache hit repeating the normalizer")
+		}
+	})
+
+	t.Run("anonymous normalizer is not cached", func(t *testing.T) {
+		// When: merging twice with a function literal as normalizer, whose
+		// captured variables cannot be hashed
+		suffix := " station"
+		trim := strategy.NormalizerFunc(func(s string) string { return strings.TrimSuffix(s, suffix) })
+
+		// Then: neither merge is skipped
+		if merge(WithNormalizer("stop", trim)) || merge(WithNormalizer("stop", trim)) {
+			t.Error("Expected anonymous normalizers to skip the cache")
+		}
+	})
+
+	t.Run("miss due to input change", func(t *testing.T) {
+		// When: an input's stops change
+		stops := filepath.Join(inputs[1], "stops.txt")
+		data, err := os.ReadFile(stops)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(stops, append(data, "new_stop,New Stop,47.7,-122.4\n"...), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		// Then: the merge runs
+		if merge() {
+			t.Error("Expected a cache miss")
+		}
+		if !merge() {
+			t.Error("Expected a cache hit repeating the changed input")
+		}
+	})
+
+	t.Run("miss due to output change", func(t *testing.T) {
+		// When: the output is removed
+		if err := os.Remove(output); err != nil {
+			t.Fatal(err)
+		}
+
+		// Then: the merge runs, writing it again
+		if merge() {
+			t.Error("Expected a cache miss")
+		}
+		if _, err := os.Stat(output); err != nil {
+			t.Errorf("Expected the output to be written: %v", err)
+		}
+	})
+}
+
+func TestConfigHash(t *testing.T) {
+	hash := func(opts ...Option) string {
+		t.Helper()
+		h, err := New(opts...).configHash()
+		if err != nil {
+			t.Fatalf("configHash failed: %v", err)
+		}
+		return h
+	}
+
+	// Mergers configured alike hash alike, whatever their strategies'
+	// addresses, metrics or cache file
+	if a, b := hash(WithProfile(ProfileRegionalIntegration)), hash(WithProfile(ProfileRegionalIntegration), WithMetrics(NewMemoryMetrics()), WithFingerprintCache("other.json")); a != b {
+		t.Errorf("Expected equal configurations to hash the same: %s != %s", a, b)
+	}
+	if a, b := hash(), hash(WithUniqueStopCodes(StopCodesWarn)); a == b {
+		t.Error("Expected different options to hash differently")
+	}
+	if a, b := hash(WithNormalizer("stop", strategy.CaseFold)), hash(WithNormalizer("stop", strategy.StripLeadingZeros)); a == b {
+		t.Error("Expected different normalizers to hash differently")
+	}
+	if _, err := New(WithNormalizer("stop", strategy.NormalizerFunc(func(s string) string { return s }))).configHash(); err == nil {
+		t.Error("Expected an anonymous normalizer to fail the hash")
+	}
+
+	// Overrides are hashed by content
+	path := writeOverrides(t, "file,id,column,value\nstops.txt,s1,stop_name,Main St\n")
+	before := hash(WithOverrides(0, path))
+	if err := os.WriteFile(path, []byte("file,id,column,value\nstops.txt,s1,stop_name,Main Street\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if before == hash(WithOverrides(0, path)) {
+		t.Error("Expected changed overrides to hash differently")
+	}
+}
@@ -0,0 +1,249 @@
+package merge
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/internal/enumtext"
+)
+
+// FrequencyOverlapPolicy specifies how a frequency-based trip that runs the
+// same service as a set of exact-time trips is handled. One input may
+// publish a route as exact stop_times and another as frequencies; the trips
+// never match as duplicates, since their schedules differ, so without a
+// check the merged feed runs the service twice.
+type FrequencyOverlapPolicy int
+
+const (
+	// FrequencyOverlapIgnore - overlaps are not looked for
+	FrequencyOverlapIgnore FrequencyOverlapPolicy = iota
+
+	// FrequencyOverlapWarn - overlaps are listed in
+	// Report.FrequencyOverlaps, and both kinds of trip kept
+	FrequencyOverlapWarn
+
+	// FrequencyOverlapKeepExact - overlaps are listed, and each frequency
+	// trip is removed in favor of the exact-time trips
+	FrequencyOverlapKeepExact
+
+	// FrequencyOverlapKeepFrequencies - overlaps are listed, and the
+	// exact-time trips are removed in favor of the frequency trip
+	FrequencyOverlapKeepFrequencies
+)
+
+// String returns the string representation of FrequencyOverlapPolicy
+func (p FrequencyOverlapPolicy) String() string {
+	switch p {
+	case FrequencyOverlapIgnore:
+		return "ignore"
+	case FrequencyOverlapWarn:
+		return "warn"
+	case FrequencyOverlapKeepExact:
+		return "keep-exact"
+	case FrequencyOverlapKeepFrequencies:
+		return "keep-frequencies"
+	default:
+		return fmt.Sprintf("FrequencyOverlapPolicy(%d)", p)
+	}
+}
+
+// ParseFrequencyOverlapPolicy parses a string into a FrequencyOverlapPolicy
+// value
+func ParseFrequencyOverlapPolicy(s string) (FrequencyOverlapPolicy, error) {
+	switch strings.ToLower(s) {
+	case "ignore":
+		return FrequencyOverlapIgnore, nil
+	case "warn":
+		return FrequencyOverlapWarn, nil
+	case "keep-exact":
+		return FrequencyOverlapKeepExact, nil
+	case "keep-frequencies":
+		return FrequencyOverlapKeepFrequencies, nil
+	default:
+		return FrequencyOverlapIgnore, fmt.Errorf("invalid frequency overlap policy: %q", s)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (p FrequencyOverlapPolicy) MarshalText() ([]byte, error) {
+	return enumtext.Marshal(p, ParseFrequencyOverlapPolicy)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (p *FrequencyOverlapPolicy) UnmarshalText(text []byte) error {
+	return enumtext.Unmarshal(p, text, ParseFrequencyOverlapPolicy)
+}
+
+// FrequencyOverlap describes a frequency-based trip and the exact-time
+// trips of the merged feed that run the same service: trips on the same
+// route and service, serving the same stops in the same order, that start
+// within one of its frequency windows at about its headway
+type FrequencyOverlap struct {
+	RouteID   gtfs.RouteID
+	ServiceID gtfs.ServiceID
+
+	// FrequencyTrip is the trip frequencies.txt repeats
+	FrequencyTrip gtfs.TripID
+
+	// ExactTrips are the exact-time trips, by start time
+	ExactTrips []gtfs.TripID
+
+	// Policy is how the overlap was resolved
+	Policy FrequencyOverlapPolicy
+}
+
+// String describes the overlap for logs and warnings
+func (o FrequencyOverlap) String() string {
+	s := fmt.Sprintf("route %q, service %q: frequency trip %q runs the same service as %d exact-time trips", o.RouteID, o.ServiceID, o.FrequencyTrip, len(o.ExactTrips))
+	switch o.Policy {
+	case FrequencyOverlapKeepExact:
+		s += "; frequency trip removed"
+	case FrequencyOverlapKeepFrequencies:
+		s += "; exact-time trips removed"
+	}
+	return s
+}
+
+// tripPattern identifies the trips an exact-time trip may overlap: those
+// on its route and service serving its stops in order
+type tripPattern struct {
+	route   gtfs.RouteID
+	service gtfs.ServiceID
+	stops   string
+}
+
+// tripStart is an exact-time trip and the time it starts, in seconds
+type tripStart struct {
+	trip  gtfs.TripID
+	start int
+}
+
+// findFrequencyOverlaps returns the frequency-based trips of feed that run
+// the same service as a set of its exact-time trips, in trip order. Within
+// one of the frequency trip's windows, at least two exact-time trips of its
+// pattern must start, spaced on average within half to one and a half
+// times the window's headway. The feed's stop_times must be sorted by trip
+// (see gtfs.Feed.SortTripRows).
+func findFrequencyOverlaps(feed *gtfs.Feed) []FrequencyOverlap {
+	windows := make(map[gtfs.TripID][]*gtfs.Frequency)
+	for _, f := range feed.Frequencies {
+		windows[f.TripID] = append(windows[f.TripID], f)
+	}
+	if len(windows) == 0 {
+		return nil
+	}
+
+	// Each trip's stops, and the departure from its first
+	stops := make(map[gtfs.TripID][]string)
+	starts := make(map[gtfs.TripID]int)
+	for _, st := range feed.StopTimes {
+		if _, ok := stops[st.TripID]; !ok {
+			start := st.DepartureTime
+			if start == "" {
+				start = st.ArrivalTime
+			}
+			starts[st.TripID] = gtfs.TimeSeconds(start)
+		}
+		stops[st.TripID] = append(stops[st.TripID], string(st.StopID))
+	}
+	pattern := func(trip *gtfs.Trip) tripPattern {
+		return tripPattern{trip.RouteID, trip.ServiceID, strings.Join(stops[trip.ID], "\x00")}
+	}
+
+	exact := make(map[tripPattern][]tripStart)
+	for _, id := range feed.TripOrder {
+		trip := feed.Trips[id]
+		if windows[id] != nil || len(stops[id]) == 0 || starts[id] < 0 {
+			continue
+		}
+		p := pattern(trip)
+		exact[p] = append(exact[p], tripStart{id, starts[id]})
+	}
+
+	var overlaps []FrequencyOverlap
+	for _, id := range feed.TripOrder {
+		trip := feed.Trips[id]
+		if windows[id] == nil || len(stops[id]) == 0 {
+			continue
+		}
+		candidates := exact[pattern(trip)]
+		var matched []tripStart
+		for _, w := range windows[id] {
+			from, to := gtfs.TimeSeconds(w.StartTime), gtfs.TimeSeconds(w.EndTime)
+			var within []tripStart
+			for _, c := range candidates {
+				if c.start >= from && c.start <= to {
+					within = append(within, c)
+				}
+			}
+			if len(within) < 2 || w.HeadwaySecs <= 0 {
+				continue
+			}
+			slices.SortFunc(within, func(a, b tripStart) int { return a.start - b.start })
+			spacing := (within[len(within)-1].start - within[0].start) / (len(within) - 1)
+			if spacing*2 >= w.HeadwaySecs && spacing*2 <= w.HeadwaySecs*3 {
+				matched = append(matched, within...)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		slices.SortStableFunc(matched, func(a, b tripStart) int { return a.start - b.start })
+		o := FrequencyOverlap{RouteID: trip.RouteID, ServiceID: trip.ServiceID, FrequencyTrip: id}
+		for _, m := range matched {
+			if !slices.Contains(o.ExactTrips, m.trip) {
+				o.ExactTrips = append(o.ExactTrips, m.trip)
+			}
+		}
+		overlaps = append(overlaps, o)
+	}
+	return overlaps
+}
+
+// applyFrequencyOverlapPolicy finds the frequency overlaps of feed (see
+// findFrequencyOverlaps), lists them in the report and removes the trips
+// the policy does not keep
+func applyFrequencyOverlapPolicy(feed *gtfs.Feed, policy FrequencyOverlapPolicy, report *Report) {
+	if policy == FrequencyOverlapIgnore {
+		return
+	}
+	overlaps := findFrequencyOverlaps(feed)
+	remove := make(map[gtfs.TripID]bool)
+	for i := range overlaps {
+		o := &overlaps[i]
+		o.Policy = policy
+		switch policy {
+		case FrequencyOverlapKeepExact:
+			remove[o.FrequencyTrip] = true
+		case FrequencyOverlapKeepFrequencies:
+			for _, id := range o.ExactTrips {
+				remove[id] = true
+			}
+		}
+		log.Printf("WARNING: %s", o)
+		report.Warnings = append(report.Warnings, o.String())
+	}
+	report.FrequencyOverlaps = overlaps
+	removeTrips(feed, remove)
+}
+
+// removeTrips deletes the trips in ids from feed, with their stop_times,
+// frequencies, transfers and provenance
+func removeTrips(feed *gtfs.Feed, ids map[gtfs.TripID]bool) {
+	if len(ids) == 0 {
+		return
+	}
+	feed.TripOrder = slices.DeleteFunc(feed.TripOrder, func(id gtfs.TripID) bool { return ids[id] })
+	for id := range ids {
+		delete(feed.Trips, id)
+		deleteSource(feed, gtfs.KindTrip, string(id))
+	}
+	feed.StopTimes = slices.DeleteFunc(feed.StopTimes, func(st *gtfs.StopTime) bool { return ids[st.TripID] })
+	feed.Frequencies = slices.DeleteFunc(feed.Frequencies, func(f *gtfs.Frequency) bool { return ids[f.TripID] })
+	feed.Transfers = slices.DeleteFunc(feed.Transfers, func(t *gtfs.Transfer) bool {
+		return ids[t.FromTripID] || ids[t.ToTripID]
+	})
+}
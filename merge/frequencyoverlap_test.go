@@ -0,0 +1,120 @@
+package merge
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// frequencyOverlapFeeds returns two feeds running route r1 every 10 minutes
+// from 08:00 to 09:00: the first as exact-time trips, the second as one
+// trip repeated every headway seconds
+func frequencyOverlapFeeds(headway int) []*gtfs.Feed {
+	feeds := make([]*gtfs.Feed, 2)
+	for i := range feeds {
+		f := gtfs.NewFeed()
+		f.AddAgency(&gtfs.Agency{ID: "agency", Name: "Agency", URL: "http://example.com", Timezone: "UTC"})
+		for _, id := range []gtfs.StopID{"s1", "s2", "s3"} {
+			f.AddStop(&gtfs.Stop{ID: id, Name: string(id), Lat: 47.6, Lon: -122.3})
+		}
+		f.AddRoute(&gtfs.Route{ID: "r1", AgencyID: "agency", ShortName: "1", Type: 3})
+		f.AddCalendar(&gtfs.Calendar{ServiceID: "wkdy", Monday: true, StartDate: "20240101", EndDate: "20241231"})
+		feeds[i] = f
+	}
+
+	addTrip := func(f *gtfs.Feed, id gtfs.TripID, start int) {
+		f.AddTrip(&gtfs.Trip{ID: id, RouteID: "r1", ServiceID: "wkdy"})
+		for n, stop := range []gtfs.StopID{"s1", "s2", "s3"} {
+			minutes := start + n*5
+			t := fmt.Sprintf("%02d:%02d:00", minutes/60, minutes%60)
+			f.StopTimes = append(f.StopTimes, &gtfs.StopTime{TripID: id, StopID: stop, ArrivalTime: t, DepartureTime: t, StopSequence: n + 1})
+		}
+	}
+	for n := 0; n < 6; n++ {
+		addTrip(feeds[0], gtfs.TripID(fmt.Sprintf("exact%d", n)), 8*60+n*10)
+	}
+	addTrip(feeds[1], "template", 8*60)
+	feeds[1].Frequencies = append(feeds[1].Frequencies, &gtfs.Frequency{TripID: "template", StartTime: "08:00:00", EndTime: "09:00:00", HeadwaySecs: headway})
+	return feeds
+}
+
+func TestWithFrequencyOverlaps(t *testing.T) {
+	exactTrips := []gtfs.TripID{"exact0", "exact1", "exact2", "exact3", "exact4", "exact5"}
+	tests := []struct {
+		policy    FrequencyOverlapPolicy
+		headway   int
+		overlaps  int
+		wantTrips []gtfs.TripID
+	}{
+		{FrequencyOverlapIgnore, 600, 0, append([]gtfs.TripID{"template"}, exactTrips...)},
+		{FrequencyOverlapWarn, 600, 1, append([]gtfs.TripID{"template"}, exactTrips...)},
+		{FrequencyOverlapKeepExact, 600, 1, exactTrips},
+		{FrequencyOverlapKeepFrequencies, 600, 1, []gtfs.TripID{"template"}},
+		// Exact trips every 10 minutes are not the service of a trip
+		// repeated every half hour
+		{FrequencyOverlapKeepExact, 1800, 0, append([]gtfs.TripID{"template"}, exactTrips...)},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%ds", tt.policy, tt.headway), func(t *testing.T) {
+			// Given: the route as exact-time trips and as frequencies,
+			// whose routes, stops and services merge by identity
+			m := New(WithDefaultDetection(strategy.DetectionIdentity), WithFrequencyOverlaps(tt.policy))
+
+			// When: merged
+			merged, err := m.MergeFeeds(frequencyOverlapFeeds(tt.headway))
+			if err != nil {
+				t.Fatalf("MergeFeeds failed: %v", err)
+			}
+
+			// Then: the overlap is reported, even when no trip is removed
+			report := m.Report()
+			if len(report.FrequencyOverlaps) != tt.overlaps {
+				t.Fatalf("Expected %d overlaps, got %v", tt.overlaps, report.FrequencyOverlaps)
+			}
+			if tt.overlaps > 0 {
+				o := report.FrequencyOverlaps[0]
+				if o.FrequencyTrip != "template" || !slices.Equal(o.ExactTrips, exactTrips) || o.Policy != tt.policy {
+					t.Errorf("Unexpected overlap %+v", o)
+				}
+				if !slices.Contains(report.Warnings, o.String()) {
+					t.Errorf("Expected warning %q, got %q", o, report.Warnings)
+				}
+			}
+
+			// And: only the trips the policy keeps remain, with their
+			// stop_times and frequencies
+			trips := slices.Sorted(slices.Values(merged.TripOrder))
+			want := slices.Sorted(slices.Values(tt.wantTrips))
+			if !slices.Equal(trips, want) {
+				t.Errorf("Expected trips %v, got %v", want, trips)
+			}
+			if len(merged.StopTimes) != 3*len(want) {
+				t.Errorf("Expected %d stop_times, got %d", 3*len(want), len(merged.StopTimes))
+			}
+			if keepsTemplate := slices.Contains(want, "template"); keepsTemplate != (len(merged.Frequencies) == 1) {
+				t.Errorf("Expected frequencies only with the template trip, got %d", len(merged.Frequencies))
+			}
+		})
+	}
+}
+
+func TestParseFrequencyOverlapPolicy(t *testing.T) {
+	for input, want := range map[string]FrequencyOverlapPolicy{
+		"ignore":           FrequencyOverlapIgnore,
+		"Warn":             FrequencyOverlapWarn,
+		"keep-exact":       FrequencyOverlapKeepExact,
+		"keep-frequencies": FrequencyOverlapKeepFrequencies,
+	} {
+		if got, err := ParseFrequencyOverlapPolicy(input); err != nil || got != want {
+			t.Errorf("ParseFrequencyOverlapPolicy(%q) = %v, %v, want %v", input, got, err, want)
+		}
+	}
+	if _, err := ParseFrequencyOverlapPolicy("keep-both"); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+	testEnumText(t, []FrequencyOverlapPolicy{FrequencyOverlapIgnore, FrequencyOverlapWarn, FrequencyOverlapKeepExact, FrequencyOverlapKeepFrequencies})
+}
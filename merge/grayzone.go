@@ -0,0 +1,24 @@
+package merge
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// ErrAmbiguousMatches indicates fuzzy matches scored within the gray zone
+// under the GrayZoneAbort policy; see WithGrayZone
+var ErrAmbiguousMatches = errors.New("ambiguous fuzzy matches")
+
+// ambiguousMatchesError lists every gray zone match in an error wrapping
+// ErrAmbiguousMatches
+func ambiguousMatchesError(matches []strategy.GrayZoneMatch) error {
+	var b strings.Builder
+	for _, gm := range matches {
+		fmt.Fprintf(&b, "\n  %s %q (feed %s) ~ %q: score %.3f, threshold %.3f",
+			gm.Entity, gm.SourceID, gm.SourceFeed, gm.TargetID, gm.Score, gm.Threshold)
+	}
+	return fmt.Errorf("%w: %d pairs%s", ErrAmbiguousMatches, len(matches), b.String())
+}
@@ -0,0 +1,95 @@
+package merge
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// grayZoneFeeds returns two feeds whose only stops share a name and are
+// about 70m apart, so fuzzy stop matching scores them 0.75
+func grayZoneFeeds() []*gtfs.Feed {
+	a := gtfs.NewFeed()
+	a.AddStop(&gtfs.Stop{ID: "a1", Name: "Main St", Lat: 40.7128, Lon: -74.0060})
+	b := gtfs.NewFeed()
+	b.AddStop(&gtfs.Stop{ID: "b1", Name: "Main St", Lat: 40.71343, Lon: -74.0060})
+	return []*gtfs.Feed{a, b}
+}
+
+func TestWithGrayZoneFlag(t *testing.T) {
+	// Given: a gray zone of 0.3 around the default 0.5 stop threshold
+	m := New(
+		WithDetectionFor("stop", strategy.DetectionFuzzy),
+		WithGrayZone(0.3, strategy.GrayZoneFlag),
+	)
+
+	// When: two stops scoring 0.75 are merged
+	merged, err := m.MergeFeeds(grayZoneFeeds())
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: they are merged and the decision is reported
+	if len(merged.Stops) != 1 {
+		t.Errorf("Expected 1 stop, got %d", len(merged.Stops))
+	}
+	gz := m.Report().GrayZone
+	if len(gz) != 1 {
+		t.Fatalf("Expected 1 gray zone match, got %d", len(gz))
+	}
+	if gz[0].SourceFeed != "a" || gz[0].SourceID != "a1" || gz[0].TargetID != "b1" || !gz[0].Merged {
+		t.Errorf("Unexpected gray zone match %+v", gz[0])
+	}
+}
+
+func TestWithGrayZoneNearMiss(t *testing.T) {
+	m := New(
+		WithDetectionFor("stop", strategy.DetectionFuzzy),
+		WithGrayZone(0.3, strategy.GrayZoneNearMiss),
+	)
+	merged, err := m.MergeFeeds(grayZoneFeeds())
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: the stops are kept apart and recorded as a near miss
+	if len(merged.Stops) != 2 {
+		t.Errorf("Expected 2 stops, got %d", len(merged.Stops))
+	}
+	if gz := m.Report().GrayZone; len(gz) != 1 || gz[0].Merged {
+		t.Errorf("Expected 1 near miss, got %+v", gz)
+	}
+}
+
+func TestWithGrayZoneAbort(t *testing.T) {
+	m := New(
+		WithDetectionFor("stop", strategy.DetectionFuzzy),
+		WithGrayZone(0.3, strategy.GrayZoneAbort),
+	)
+	_, err := m.MergeFeeds(grayZoneFeeds())
+
+	// Then: the merge fails, naming the ambiguous pair
+	if !errors.Is(err, ErrAmbiguousMatches) {
+		t.Fatalf("Expected ErrAmbiguousMatches, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `stop "a1" (feed a) ~ "b1"`) {
+		t.Errorf("Expected the pair in the error, got %v", err)
+	}
+	if m.Report() != nil {
+		t.Error("Expected no report for a failed merge")
+	}
+}
+
+func TestWithoutGrayZone(t *testing.T) {
+	m := New(WithDetectionFor("stop", strategy.DetectionFuzzy))
+	merged, err := m.MergeFeeds(grayZoneFeeds())
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+	if len(merged.Stops) != 1 || len(m.Report().GrayZone) != 0 {
+		t.Errorf("Expected a plain fuzzy merge, got %d stops and %+v", len(merged.Stops), m.Report().GrayZone)
+	}
+}
@@ -0,0 +1,59 @@
+package merge
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// ErrIdenticalInputs indicates MergeFiles was given the same feed twice
+// under WithFailOnIdenticalInputs
+var ErrIdenticalInputs = errors.New("identical input feeds")
+
+// SkippedInput is an input left out of the merge because its files are
+// byte-for-byte the same as an earlier input's
+type SkippedInput struct {
+	// Path is the input skipped
+	Path string
+
+	// DuplicateOf is the earlier input it repeats
+	DuplicateOf string
+}
+
+// String returns the input as PATH (identical to DUPLICATE_OF)
+func (s SkippedInput) String() string {
+	return fmt.Sprintf("%s (identical to %s)", s.Path, s.DuplicateOf)
+}
+
+// skipIdenticalInputs drops each input whose content hash (see
+// gtfs.Feed.ContentHash) matches an earlier input's, returning the feeds and
+// paths kept and the inputs skipped. Under fail, an identical input is an
+// error wrapping ErrIdenticalInputs instead.
+func skipIdenticalInputs(feeds []*gtfs.Feed, paths []string, fail bool) ([]*gtfs.Feed, []string, []SkippedInput, error) {
+	firstPath := make(map[string]string)
+	keptFeeds := feeds[:0:0]
+	keptPaths := paths[:0:0]
+	var skipped []SkippedInput
+	for i, feed := range feeds {
+		if feed.ContentHash == "" {
+			keptFeeds = append(keptFeeds, feed)
+			keptPaths = append(keptPaths, paths[i])
+			continue
+		}
+		if first, ok := firstPath[feed.ContentHash]; ok {
+			s := SkippedInput{Path: paths[i], DuplicateOf: first}
+			if fail {
+				return nil, nil, nil, fmt.Errorf("%w: %s", ErrIdenticalInputs, s)
+			}
+			log.Printf("WARNING: skipping input %s", s)
+			skipped = append(skipped, s)
+			continue
+		}
+		firstPath[feed.ContentHash] = paths[i]
+		keptFeeds = append(keptFeeds, feed)
+		keptPaths = append(keptPaths, paths[i])
+	}
+	return keptFeeds, keptPaths, skipped, nil
+}
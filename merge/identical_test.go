@@ -0,0 +1,52 @@
+package merge
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeFilesSkipsIdenticalInputs(t *testing.T) {
+	// Given: simple_a passed twice, the second time under another name
+	tmpDir := t.TempDir()
+	copyPath := filepath.Join(tmpDir, "copy_of_a")
+	if err := os.CopyFS(copyPath, os.DirFS("../testdata/simple_a")); err != nil {
+		t.Fatalf("failed to copy simple_a: %v", err)
+	}
+	inputs := []string{"../testdata/simple_a", copyPath, "../testdata/simple_b"}
+	output := filepath.Join(tmpDir, "merged.zip")
+
+	// When: merged
+	m := New()
+	if err := m.MergeFiles(inputs, output); err != nil {
+		t.Fatalf("MergeFiles failed: %v", err)
+	}
+
+	// Then: the copy is skipped and reported, and only two feeds are merged
+	report := m.Report()
+	want := SkippedInput{Path: copyPath, DuplicateOf: "../testdata/simple_a"}
+	if len(report.SkippedInputs) != 1 || report.SkippedInputs[0] != want {
+		t.Errorf("Expected skipped inputs [%v], got %v", want, report.SkippedInputs)
+	}
+	if len(report.Feeds) != 2 || report.Feeds[0].Path != inputs[0] || report.Feeds[1].Path != inputs[2] {
+		t.Errorf("Expected simple_a and simple_b to be merged, got %+v", report.Feeds)
+	}
+}
+
+func TestMergeFilesFailOnIdenticalInputs(t *testing.T) {
+	// Given: the same input twice
+	output := filepath.Join(t.TempDir(), "merged.zip")
+	inputs := []string{"../testdata/simple_a", "../testdata/simple_a"}
+
+	// When: merged with identical inputs treated as an error
+	err := New(WithFailOnIdenticalInputs(true)).MergeFiles(inputs, output)
+
+	// Then: the merge fails without writing output
+	if !errors.Is(err, ErrIdenticalInputs) {
+		t.Fatalf("Expected ErrIdenticalInputs, got %v", err)
+	}
+	if _, statErr := os.Stat(output); !os.IsNotExist(statErr) {
+		t.Errorf("Expected no output to be written")
+	}
+}
@@ -0,0 +1,152 @@
+package merge
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// UninterpolatedStopTime identifies a stop_time left without times under
+// WithInterpolateStopTimes, as its trip lacks a timed stop before or after
+// it, or the times of those stops are malformed or out of order
+type UninterpolatedStopTime struct {
+	TripID       gtfs.TripID
+	StopSequence int
+}
+
+// String describes the stop_time for logs and warnings
+func (u UninterpolatedStopTime) String() string {
+	return fmt.Sprintf("trip %q, stop_sequence %d", u.TripID, u.StopSequence)
+}
+
+// interpolationResult is the outcome of interpolating a feed's stop_times
+type interpolationResult struct {
+	interpolated   int
+	uninterpolated []UninterpolatedStopTime
+}
+
+// interpolateStopTimes fills in the stop_times of feed that have neither an
+// arrival nor a departure time, interpolating linearly between the timed
+// stops on either side in the same trip, and marks them timepoint 0. Time
+// is shared out in proportion to shape_dist_traveled when every stop from
+// one timed stop to the next has one and distance increases between them,
+// and in proportion to the number of stops otherwise. Rows with either
+// time are left as they are. The feed's stop_times must be sorted by trip
+// (see gtfs.Feed.SortTripRows).
+func interpolateStopTimes(feed *gtfs.Feed) interpolationResult {
+	var result interpolationResult
+	for start := 0; start < len(feed.StopTimes); {
+		end := start + 1
+		for end < len(feed.StopTimes) && feed.StopTimes[end].TripID == feed.StopTimes[start].TripID {
+			end++
+		}
+		interpolateTrip(feed.StopTimes[start:end], &result)
+		start = end
+	}
+	if result.interpolated > 0 {
+		feed.AddColumn("stop_times.txt", "timepoint")
+	}
+	return result
+}
+
+// warnings describes the stop_times that could not be interpolated, as one
+// warning naming the first
+func (r interpolationResult) warnings() []string {
+	if len(r.uninterpolated) == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("%d stop_times without times could not be interpolated, lacking timed stops before and after them (first: %s)",
+		len(r.uninterpolated), r.uninterpolated[0])}
+}
+
+// interpolateTrip interpolates the untimed stop_times of rows, one trip's
+// in stop_sequence order (see interpolateStopTimes)
+func interpolateTrip(rows []*gtfs.StopTime, result *interpolationResult) {
+	prev := -1
+	for i := 0; i < len(rows); i++ {
+		if stopTimeTimed(rows[i]) {
+			prev = i
+			continue
+		}
+		next := i + 1
+		for next < len(rows) && !stopTimeTimed(rows[next]) {
+			next++
+		}
+		var from, to int
+		if prev >= 0 && next < len(rows) {
+			from, to = departureSeconds(rows[prev]), arrivalSeconds(rows[next])
+		}
+		if prev < 0 || next == len(rows) || from < 0 || to < from {
+			for _, st := range rows[i:next] {
+				result.uninterpolated = append(result.uninterpolated, UninterpolatedStopTime{st.TripID, st.StopSequence})
+			}
+			i = next - 1
+			continue
+		}
+
+		share := stopCountShare(prev, next)
+		if byDistance, ok := distanceShare(rows, prev, next); ok {
+			share = byDistance
+		}
+		for j := i; j < next; j++ {
+			t := gtfs.FormatTime(from + int(math.Round(share(j)*float64(to-from))))
+			rows[j].ArrivalTime, rows[j].DepartureTime = t, t
+			timepoint := 0
+			rows[j].Timepoint = &timepoint
+			result.interpolated++
+		}
+		i = next - 1
+	}
+}
+
+// stopCountShare returns the share of the time from stop prev to stop next
+// elapsed at each stop between them, in proportion to the stops passed
+func stopCountShare(prev, next int) func(int) float64 {
+	return func(j int) float64 {
+		return float64(j-prev) / float64(next-prev)
+	}
+}
+
+// distanceShare returns the share of the time from rows[prev] to rows[next]
+// elapsed at each stop between them, in proportion to shape_dist_traveled,
+// or false unless every one of the stops has a distance and distance never
+// decreases and increases overall
+func distanceShare(rows []*gtfs.StopTime, prev, next int) (func(int) float64, bool) {
+	for j := prev; j <= next; j++ {
+		if rows[j].ShapeDistTraveled == nil || j > prev && *rows[j].ShapeDistTraveled < *rows[j-1].ShapeDistTraveled {
+			return nil, false
+		}
+	}
+	start, end := *rows[prev].ShapeDistTraveled, *rows[next].ShapeDistTraveled
+	if end <= start {
+		return nil, false
+	}
+	return func(j int) float64 {
+		return (*rows[j].ShapeDistTraveled - start) / (end - start)
+	}, true
+}
+
+// stopTimeTimed reports whether st has an arrival or departure time
+func stopTimeTimed(st *gtfs.StopTime) bool {
+	return strings.TrimSpace(st.ArrivalTime) != "" || strings.TrimSpace(st.DepartureTime) != ""
+}
+
+// departureSeconds returns the departure time of a timed stop_time, or its
+// arrival time if it has no departure time; -1 if it is malformed
+func departureSeconds(st *gtfs.StopTime) int {
+	if t := gtfs.TimeSeconds(st.DepartureTime); t >= 0 {
+		return t
+	}
+	return gtfs.TimeSeconds(st.ArrivalTime)
+}
+
+// arrivalSeconds returns the arrival time of a timed stop_time, or its
+// departure time if it has no arrival time; -1 if it is malformed
+func arrivalSeconds(st *gtfs.StopTime) int {
+	if t := gtfs.TimeSeconds(st.ArrivalTime); t >= 0 {
+		return t
+	}
+	return gtfs.TimeSeconds(st.DepartureTime)
+}
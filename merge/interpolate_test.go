@@ -0,0 +1,154 @@
+package merge
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfstest"
+)
+
+// stopTimeTimes returns the arrival and departure times of feed's
+// stop_times, as "arrival/departure"
+func stopTimeTimes(feed *gtfs.Feed) []string {
+	var times []string
+	for _, st := range feed.StopTimes {
+		times = append(times, st.ArrivalTime+"/"+st.DepartureTime)
+	}
+	return times
+}
+
+func TestInterpolateStopTimes(t *testing.T) {
+	// untimed blanks the times of the stop_times of trip t1 other than the
+	// first and last, leaving them 08:00 and 08:20
+	untimed := func(dist ...float64) *gtfs.Feed {
+		feed := gtfstest.NewFeedBuilder().
+			Trip("t1", "r1", "svc1").
+			StopTimes("t1", "08:00", "s1", "s2", "s3", "s4", "s5").
+			MustBuild()
+		for i, st := range feed.StopTimes {
+			if i > 0 && i < len(feed.StopTimes)-1 {
+				st.ArrivalTime, st.DepartureTime = "", ""
+			}
+			if dist != nil {
+				st.ShapeDistTraveled = &dist[i]
+			}
+		}
+		return feed
+	}
+
+	t.Run("by stop count", func(t *testing.T) {
+		// Given: stops without distances
+		feed := untimed()
+
+		// When: interpolated
+		result := interpolateStopTimes(feed)
+
+		// Then: the 20 minutes are shared equally between the 4 gaps
+		want := []string{"08:00:00/08:00:00", "08:05:00/08:05:00", "08:10:00/08:10:00", "08:15:00/08:15:00", "08:20:00/08:20:00"}
+		if got := stopTimeTimes(feed); !slices.Equal(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+		if result.interpolated != 3 || len(result.uninterpolated) != 0 {
+			t.Errorf("Expected 3 interpolated, got %+v", result)
+		}
+
+		// And: interpolated rows are marked timepoint 0, and others kept
+		for i, st := range feed.StopTimes {
+			interpolated := i > 0 && i < 4
+			if interpolated != (st.Timepoint != nil && *st.Timepoint == 0) {
+				t.Errorf("Expected stop time %d timepoint 0 only if interpolated, got %v", i+1, st.Timepoint)
+			}
+		}
+	})
+
+	t.Run("by distance", func(t *testing.T) {
+		// Given: stops with distances, the second close to the first
+		feed := untimed(0, 100, 1000, 1500, 2000)
+
+		// When: interpolated
+		interpolateStopTimes(feed)
+
+		// Then: the 20 minutes are shared in proportion to distance
+		want := []string{"08:00:00/08:00:00", "08:01:00/08:01:00", "08:10:00/08:10:00", "08:15:00/08:15:00", "08:20:00/08:20:00"}
+		if got := stopTimeTimes(feed); !slices.Equal(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("distance decreasing", func(t *testing.T) {
+		// Given: distances that go backwards
+		feed := untimed(0, 1500, 1000, 1500, 2000)
+
+		// When: interpolated
+		interpolateStopTimes(feed)
+
+		// Then: the stop count is used instead
+		if got := feed.StopTimes[1].ArrivalTime; got != "08:05:00" {
+			t.Errorf("Expected 08:05:00, got %s", got)
+		}
+	})
+
+	t.Run("not bracketed", func(t *testing.T) {
+		// Given: a trip whose last stops are untimed, and a stop with only
+		// an arrival time
+		feed := untimed()
+		feed.StopTimes[4].ArrivalTime, feed.StopTimes[4].DepartureTime = "", ""
+		feed.StopTimes[0].DepartureTime = ""
+
+		// When: interpolated
+		result := interpolateStopTimes(feed)
+
+		// Then: the untimed stops after the last timed one are reported
+		// and left blank, as is the stop's missing departure
+		if result.interpolated != 0 || len(result.uninterpolated) != 4 {
+			t.Fatalf("Expected 4 stop times not interpolated, got %+v", result)
+		}
+		if u := result.uninterpolated[0]; u != (UninterpolatedStopTime{"t1", 2}) {
+			t.Errorf("Expected t1 stop_sequence 2 first, got %v", u)
+		}
+		want := []string{"08:00:00/", "/", "/", "/", "/"}
+		if got := stopTimeTimes(feed); !slices.Equal(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+		if len(result.warnings()) != 1 {
+			t.Errorf("Expected one warning, got %q", result.warnings())
+		}
+	})
+}
+
+func TestWithInterpolateStopTimes(t *testing.T) {
+	newFeeds := func() []*gtfs.Feed {
+		feed := gtfstest.NewFeedBuilder().
+			Trip("t1", "r1", "svc1").
+			StopTimes("t1", "08:00", "s1", "s2", "s3").
+			MustBuild()
+		feed.StopTimes[1].ArrivalTime, feed.StopTimes[1].DepartureTime = "", ""
+		return []*gtfs.Feed{feed}
+	}
+
+	// When: merged by default
+	merged, err := New().MergeFeeds(newFeeds())
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: the untimed stop is left untimed
+	if st := merged.StopTimes[1]; st.ArrivalTime != "" {
+		t.Errorf("Expected no time by default, got %q", st.ArrivalTime)
+	}
+
+	// When: merged interpolating stop_times
+	m := New(WithInterpolateStopTimes(true))
+	if merged, err = m.MergeFeeds(newFeeds()); err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: its time is interpolated and counted
+	if st := merged.StopTimes[1]; st.ArrivalTime != "08:05:00" {
+		t.Errorf("Expected 08:05:00, got %q", st.ArrivalTime)
+	}
+	if n := m.Report().StopTimesInterpolated; n != 1 {
+		t.Errorf("Expected 1 stop time interpolated, got %d", n)
+	}
+}
@@ -0,0 +1,53 @@
+package merge
+
+import (
+	"context"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// intraFeedResult is the outcome of collapsing a feed's internal duplicates
+type intraFeedResult struct {
+	// ids maps, by kind, the ID of each of the feed's entities to its ID in
+	// the deduplicated feed: its own, or its survivor's
+	ids map[gtfs.EntityKind]map[string]string
+	// collapsed is the number of entities, by kind, mapped to a survivor
+	collapsed map[gtfs.EntityKind]int
+	// deduplicated and dropped are the rows left out, by filename (see
+	// strategy.MergeContext.Deduplicated)
+	deduplicated, dropped map[string]int
+}
+
+// dedupFeed returns feed with its internal duplicates collapsed (see
+// WithIntraFeedDedup): feed merged by m's strategies into an empty feed,
+// matching each entity against those of feed already kept. IDs are kept as
+// they are; feed itself is not modified.
+func (m *Merger) dedupFeed(ctx context.Context, feed *gtfs.Feed, name string) (*gtfs.Feed, intraFeedResult, error) {
+	deduped := gtfs.NewFeed()
+	deduped.MergeColumnSets(feed)
+
+	shapeCounter := 0
+	mctx := strategy.NewMergeContext(feed, deduped, "")
+	mctx.SetSharedShapeCounter(&shapeCounter)
+	mctx.SetContext(ctx)
+	mctx.SourceFeed = name
+	mctx.IntraFeed = true
+	if err := m.mergeFeed(mctx); err != nil {
+		return nil, intraFeedResult{}, err
+	}
+	dropDeduplicatedOrphans(mctx)
+
+	result := intraFeedResult{ids: idMap(mctx), deduplicated: mctx.Deduplicated, dropped: mctx.Dropped}
+	for kind, ids := range result.ids {
+		for from, to := range ids {
+			if from != to {
+				if result.collapsed == nil {
+					result.collapsed = make(map[gtfs.EntityKind]int)
+				}
+				result.collapsed[kind]++
+			}
+		}
+	}
+	return deduped, result, nil
+}
@@ -0,0 +1,89 @@
+package merge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfstest"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// intraFeedFeed returns a feed with a duplicate stop: stop-1 and stop-2 are
+// the same stop under two IDs, served by trip-1 and trip-2 respectively
+func intraFeedFeed() *gtfs.Feed {
+	mainSt := func(s *gtfs.Stop) { s.Name = "Main St" }
+	return gtfstest.NewFeedBuilder().
+		Stop("stop-1", 47.6, -122.3, mainSt).
+		Stop("stop-2", 47.6, -122.3, mainSt).
+		Stop("stop-3", 47.7, -122.4, func(s *gtfs.Stop) { s.Name = "Pine St" }).
+		Trip("trip-1", "route", "wkdy").
+		Trip("trip-2", "route", "wkdy").
+		StopInterval(10*time.Minute).
+		StopTimes("trip-1", "08:00", "stop-1", "stop-3").
+		StopTimes("trip-2", "08:00", "stop-2", "stop-3").
+		MustBuild()
+}
+
+func TestWithIntraFeedDedup(t *testing.T) {
+	fuzzyStops := WithDetectionFor("stop", strategy.DetectionFuzzy)
+
+	t.Run("off", func(t *testing.T) {
+		// Given: a feed with a duplicate stop, merged by itself
+		merged, err := New(fuzzyStops).MergeFeeds([]*gtfs.Feed{intraFeedFeed()})
+		if err != nil {
+			t.Fatalf("MergeFeeds failed: %v", err)
+		}
+
+		// Then: stops of one feed are never matched, so both are kept
+		if len(merged.Stops) != 3 {
+			t.Errorf("Expected 3 stops, got %d", len(merged.Stops))
+		}
+	})
+
+	t.Run("on", func(t *testing.T) {
+		// Given: the feed, after another feed
+		feed := intraFeedFeed()
+		m := New(fuzzyStops, WithIntraFeedDedup(true))
+
+		// When: merging with intra-feed deduplication
+		merged, err := m.MergeFeeds([]*gtfs.Feed{overrideFeeds()[0], feed})
+		if err != nil {
+			t.Fatalf("MergeFeeds failed: %v", err)
+		}
+
+		// Then: the duplicate collapses into stop-1, and a-stop, at the same
+		// place with the same name, into it too
+		if merged.Stops["stop-2"] != nil || merged.Stops["a-stop"] != nil || merged.Stops["stop-1"] == nil {
+			t.Fatalf("Expected stop-2 and a-stop collapsed into stop-1, got %v", merged.Stops)
+		}
+
+		// And: both trips' stop_times reference the survivor
+		for _, st := range merged.StopTimes {
+			if st.StopSequence == 1 && st.StopID != "stop-1" {
+				t.Errorf("Expected %s to start at stop-1, got %s", st.TripID, st.StopID)
+			}
+		}
+		if len(merged.StopTimes) != 5 {
+			t.Errorf("Expected 5 stop_times, got %d", len(merged.StopTimes))
+		}
+
+		// And: the report maps the duplicate to the survivor and counts it,
+		// and counts the rows read from the feed as given
+		report := m.Report().Feeds[1]
+		if got := report.IDMap[gtfs.KindStop]["stop-2"]; got != "stop-1" {
+			t.Errorf("Expected stop-2 mapped to stop-1, got %q", got)
+		}
+		if got := report.IntraFeedDuplicates[gtfs.KindStop]; got != 1 {
+			t.Errorf("Expected 1 duplicate stop, got %d", got)
+		}
+		if got := report.Read["stops.txt"]; got != 3 {
+			t.Errorf("Expected 3 stops read, got %d", got)
+		}
+
+		// And: the input feed is left as it was
+		if len(feed.Stops) != 3 {
+			t.Errorf("Expected the input's 3 stops kept, got %d", len(feed.Stops))
+		}
+	})
+}
@@ -0,0 +1,25 @@
+package merge
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrTooFewValidInputs indicates that, under WithSkipInvalidInputs, fewer
+// than two of the MergeFiles inputs could be read
+var ErrTooFewValidInputs = errors.New("too few valid input feeds")
+
+// InvalidInput is an input left out of the merge under
+// WithSkipInvalidInputs because it could not be read
+type InvalidInput struct {
+	// Path is the input skipped
+	Path string
+
+	// Err is why it could not be read
+	Err error
+}
+
+// String returns the input as PATH: ERR
+func (i InvalidInput) String() string {
+	return fmt.Sprintf("%s: %v", i.Path, i.Err)
+}
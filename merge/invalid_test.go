@@ -0,0 +1,81 @@
+package merge
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// brokenFeed copies simple_b into dir without its stop_times.txt and
+// returns its path
+func brokenFeed(t *testing.T, dir string) string {
+	t.Helper()
+	path := filepath.Join(dir, "broken")
+	if err := os.CopyFS(path, os.DirFS("../testdata/simple_b")); err != nil {
+		t.Fatalf("failed to copy simple_b: %v", err)
+	}
+	if err := os.Remove(filepath.Join(path, "stop_times.txt")); err != nil {
+		t.Fatalf("failed to remove stop_times.txt: %v", err)
+	}
+	return path
+}
+
+func TestMergeFilesSkipInvalidInputs(t *testing.T) {
+	// Given: two valid inputs, one without stop_times.txt and one missing
+	tmpDir := t.TempDir()
+	broken := brokenFeed(t, tmpDir)
+	missing := filepath.Join(tmpDir, "missing.zip")
+	inputs := []string{"../testdata/simple_a", broken, missing, "../testdata/overlap"}
+	output := filepath.Join(tmpDir, "merged.zip")
+
+	t.Run("by default", func(t *testing.T) {
+		// When: merged
+		err := New().MergeFiles(inputs, output)
+
+		// Then: the merge fails
+		if !errors.Is(err, ErrInputNotFound) {
+			t.Errorf("Expected ErrInputNotFound, got %v", err)
+		}
+	})
+
+	t.Run("skipped", func(t *testing.T) {
+		// When: merged skipping invalid inputs
+		m := New(WithSkipInvalidInputs(true))
+		if err := m.MergeFiles(inputs, output); err != nil {
+			t.Fatalf("MergeFiles failed: %v", err)
+		}
+
+		// Then: the invalid inputs are reported with their errors
+		report := m.Report()
+		if len(report.InvalidInputs) != 2 {
+			t.Fatalf("Expected 2 invalid inputs, got %v", report.InvalidInputs)
+		}
+		if i := report.InvalidInputs[0]; i.Path != broken || !errors.Is(i.Err, gtfs.ErrMissingRequiredFile) {
+			t.Errorf("Expected %s missing a required file, got %v", broken, i)
+		}
+		if i := report.InvalidInputs[1]; i.Path != missing || !errors.Is(i.Err, ErrInputNotFound) {
+			t.Errorf("Expected %s not found, got %v", missing, i)
+		}
+
+		// And: the valid inputs are merged
+		if len(report.Feeds) != 2 || report.Feeds[0].Path != inputs[0] || report.Feeds[1].Path != inputs[3] {
+			t.Errorf("Expected simple_a and overlap to be merged, got %+v", report.Feeds)
+		}
+		if _, err := os.Stat(output); err != nil {
+			t.Errorf("Expected output to be written: %v", err)
+		}
+	})
+
+	t.Run("too few valid", func(t *testing.T) {
+		// When: merged with only one valid input
+		err := New(WithSkipInvalidInputs(true)).MergeFiles([]string{"../testdata/simple_a", broken}, output)
+
+		// Then: the merge fails, naming the invalid input's error
+		if !errors.Is(err, ErrTooFewValidInputs) || !errors.Is(err, gtfs.ErrMissingRequiredFile) {
+			t.Errorf("Expected ErrTooFewValidInputs and ErrMissingRequiredFile, got %v", err)
+		}
+	})
+}
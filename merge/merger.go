@@ -4,8 +4,19 @@
 package merge
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io/fs"
+	"iter"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
 	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
@@ -14,92 +25,628 @@ import (
 // ErrNoInputFeeds indicates no input feeds were provided
 var ErrNoInputFeeds = errors.New("at least one input feed is required")
 
-// Merger orchestrates the merging of multiple GTFS feeds
+// ErrNilFeed indicates a nil feed was passed to MergeFeeds
+var ErrNilFeed = errors.New("input feed is nil")
+
+// ErrPartialFeed indicates an input feed was read with some files skipped
+// (see gtfs.ReaderOptions.SkipFiles) and cannot be merged
+var ErrPartialFeed = errors.New("input feed was only partially read")
+
+// ErrInvalidFeed indicates an input feed failed validation; see
+// WithValidateInputs. The wrapped errors are *gtfs.ValidationError values.
+var ErrInvalidFeed = errors.New("input feed is invalid")
+
+// ErrOutputIsInput indicates the output path of MergeFiles names one of its
+// inputs; see WithOverwriteInput
+var ErrOutputIsInput = errors.New("output path is also an input")
+
+// ErrInputNotFound indicates an input path of MergeFiles does not exist
+var ErrInputNotFound = errors.New("input not found")
+
+// ErrNoSuchInput indicates an option set for one input, such as
+// WithOverrides, was given an index past the merge's inputs
+var ErrNoSuchInput = errors.New("option set for an input that does not exist")
+
+// ErrUnknownEntity indicates WithDetectionFor was given a name that is
+// neither a GTFS file nor an entity kind
+var ErrUnknownEntity = errors.New("detection set for an unknown entity")
+
+// Merger orchestrates the merging of multiple GTFS feeds.
+//
+// A Merger is safe for concurrent use once configured: merges only read its
+// options and strategies, keeping their own state in a
+// strategy.MergeContext, so a server may run many merges on one Merger. Its
+// Set methods, and changes to the strategies GetStrategyForFile returns,
+// must not overlap a merge. Merges that run at once must not share input
+// feeds if any of the options that modify them in place is set:
+// WithSanitizeInputs, WithFixStationStopTimes, WithOverrides,
+// WithAgencyFilter, WithSanitizeText, WithNormalizeColors and
+// WithBasicRouteTypes. A Metrics given to WithMetrics must be safe for
+// concurrent use.
 type Merger struct {
 	// Strategy configurations
-	agencyStrategy       strategy.EntityMergeStrategy
-	areaStrategy         strategy.EntityMergeStrategy
-	stopStrategy         strategy.EntityMergeStrategy
-	calendarStrategy     strategy.EntityMergeStrategy
-	calendarDateStrategy strategy.EntityMergeStrategy
-	routeStrategy        strategy.EntityMergeStrategy
-	shapeStrategy        strategy.EntityMergeStrategy
-	tripStrategy         strategy.EntityMergeStrategy
-	stopTimeStrategy     strategy.EntityMergeStrategy
-	frequencyStrategy    strategy.EntityMergeStrategy
-	transferStrategy     strategy.EntityMergeStrategy
-	pathwayStrategy      strategy.EntityMergeStrategy
-	fareAttrStrategy     strategy.EntityMergeStrategy
-	fareRuleStrategy     strategy.EntityMergeStrategy
-	feedInfoStrategy     strategy.EntityMergeStrategy
+	agencyStrategy        strategy.EntityMergeStrategy
+	areaStrategy          strategy.EntityMergeStrategy
+	networkStrategy       strategy.EntityMergeStrategy
+	routeNetworkStrategy  strategy.EntityMergeStrategy
+	stopStrategy          strategy.EntityMergeStrategy
+	calendarStrategy      strategy.EntityMergeStrategy
+	calendarDateStrategy  strategy.EntityMergeStrategy
+	routeStrategy         strategy.EntityMergeStrategy
+	shapeStrategy         strategy.EntityMergeStrategy
+	tripStrategy          strategy.EntityMergeStrategy
+	stopTimeStrategy      strategy.EntityMergeStrategy
+	frequencyStrategy     strategy.EntityMergeStrategy
+	transferStrategy      strategy.EntityMergeStrategy
+	pathwayStrategy       strategy.EntityMergeStrategy
+	fareAttrStrategy      strategy.EntityMergeStrategy
+	fareRuleStrategy      strategy.EntityMergeStrategy
+	fareMediaStrategy     strategy.EntityMergeStrategy
+	riderCategoryStrategy strategy.EntityMergeStrategy
+	fareProductStrategy   strategy.EntityMergeStrategy
+	timeframeStrategy     strategy.EntityMergeStrategy
+	fareLegRuleStrategy   strategy.EntityMergeStrategy
+	fareTransferStrategy  strategy.EntityMergeStrategy
+	feedInfoStrategy      strategy.EntityMergeStrategy
 
 	// Options
-	debug bool
+	debug         bool
+	writerOptions gtfs.WriterOptions
+	readerOptions gtfs.ReaderOptions
+	// inputReaderOptions overrides readerOptions by input index
+	inputReaderOptions map[int]gtfs.ReaderOptions
+	shapeDistanceUnit  DistanceUnit
+	routeSortOrder     RouteSortOrderStrategy
+	overwriteInput     bool
+	normalizeShapes    bool
+	normalizeOutput    bool
+	validateInputs     bool
+	sanitizeInputs     bool
+	// sanitizeText cleans control characters and invalid UTF-8 from text
+	// fields before merging
+	sanitizeText bool
+	// alwaysPrefix prefixes every input's IDs, the last input's included,
+	// not only those that collide
+	alwaysPrefix bool
+	// normalizeColors uppercases route colors and strips their '#' before
+	// merging
+	normalizeColors bool
+	// fixStationStopTimes moves stop_times from stations to their platform
+	fixStationStopTimes bool
+	// failOnIdenticalInputs fails MergeFiles on identical inputs instead
+	// of skipping them
+	failOnIdenticalInputs bool
+	// skipInvalidInputs leaves out MergeFiles inputs that cannot be read
+	// instead of failing
+	skipInvalidInputs bool
+	// fingerprintCache is the file recording the last MergeFiles run, which
+	// is skipped when repeated unchanged
+	fingerprintCache string
+	// strictOutput checks the merged feed's references before returning it
+	strictOutput bool
+	// accountingCheck fails the merge when a file's rows do not balance
+	accountingCheck bool
+	// harmonizeDirections flips direction_ids that are opposite to another
+	// input's on a merged route
+	harmonizeDirections bool
+	grayZone            strategy.GrayZone
+	blockedPairs        []BlockedPair
+	overridePaths       map[int]string   // overrides files, by input index
+	idNamespaces        map[int][]string // ID prefixes ignored by identity detection, by input index
+	feedPrefixes        []string         // prefixes by feed, replacing the default lettering (see Pipeline)
+	pruneKinds          []string
+	stopCodePolicy      StopCodePolicy
+	// agencyFilters are the agencies kept by input index; the others are
+	// removed before merging
+	agencyFilters map[int][]gtfs.AgencyID
+	// agencyFilterKeepStops keeps the stops agencyFilters would remove
+	agencyFilterKeepStops bool
+	// basicRouteTypes maps extended route types to basic ones before
+	// merging
+	basicRouteTypes bool
+	// interpolateStopTimes fills in missing stop_times times after merging
+	interpolateStopTimes bool
+	// intraFeedDedup collapses each feed's internal duplicates before
+	// merging it with the others
+	intraFeedDedup bool
+	// frequencyOverlaps resolves frequency trips running the same service
+	// as exact-time trips
+	frequencyOverlaps FrequencyOverlapPolicy
+	// serviceDays is the number of days from serviceFrom checked for
+	// active service after the merge; 0 skips the check
+	serviceFrom time.Time
+	serviceDays int
+	// maxServiceGapDays is the longest run of days a route may go without
+	// trips before the merge warns about it; negative skips the check
+	maxServiceGapDays int
+	// readableTripIDs is the pattern merged trips are renamed after; empty
+	// keeps their IDs
+	readableTripIDs string
+	// detectionOverrides are per-entity detection modes, in the order set;
+	// they take precedence over the default detection
+	detectionOverrides []detectionOverride
+	// metrics receives stage timings and row counters, if set
+	metrics Metrics
+
+	// report describes the most recent merge, for the deprecated Report;
+	// reportMu guards it, as it is the only field merges write
+	reportMu sync.Mutex
+	report   *Report
 }
 
 // New creates a new Merger with default strategies
 func New(opts ...Option) *Merger {
 	m := &Merger{
-		agencyStrategy:       strategy.NewAgencyMergeStrategy(),
-		areaStrategy:         strategy.NewAreaMergeStrategy(),
-		stopStrategy:         strategy.NewStopMergeStrategy(),
-		calendarStrategy:     strategy.NewCalendarMergeStrategy(),
-		calendarDateStrategy: strategy.NewCalendarDateMergeStrategy(),
-		routeStrategy:        strategy.NewRouteMergeStrategy(),
-		shapeStrategy:        strategy.NewShapeMergeStrategy(),
-		tripStrategy:         strategy.NewTripMergeStrategy(),
-		stopTimeStrategy:     strategy.NewStopTimeMergeStrategy(),
-		frequencyStrategy:    strategy.NewFrequencyMergeStrategy(),
-		transferStrategy:     strategy.NewTransferMergeStrategy(),
-		pathwayStrategy:      strategy.NewPathwayMergeStrategy(),
-		fareAttrStrategy:     strategy.NewFareAttributeMergeStrategy(),
-		fareRuleStrategy:     strategy.NewFareRuleMergeStrategy(),
-		feedInfoStrategy:     strategy.NewFeedInfoMergeStrategy(),
+		agencyStrategy:        strategy.NewAgencyMergeStrategy(),
+		areaStrategy:          strategy.NewAreaMergeStrategy(),
+		networkStrategy:       strategy.NewNetworkMergeStrategy(),
+		routeNetworkStrategy:  strategy.NewRouteNetworkMergeStrategy(),
+		stopStrategy:          strategy.NewStopMergeStrategy(),
+		calendarStrategy:      strategy.NewCalendarMergeStrategy(),
+		calendarDateStrategy:  strategy.NewCalendarDateMergeStrategy(),
+		routeStrategy:         strategy.NewRouteMergeStrategy(),
+		shapeStrategy:         strategy.NewShapeMergeStrategy(),
+		tripStrategy:          strategy.NewTripMergeStrategy(),
+		stopTimeStrategy:      strategy.NewStopTimeMergeStrategy(),
+		frequencyStrategy:     strategy.NewFrequencyMergeStrategy(),
+		transferStrategy:      strategy.NewTransferMergeStrategy(),
+		pathwayStrategy:       strategy.NewPathwayMergeStrategy(),
+		fareAttrStrategy:      strategy.NewFareAttributeMergeStrategy(),
+		fareRuleStrategy:      strategy.NewFareRuleMergeStrategy(),
+		fareMediaStrategy:     strategy.NewFareMediaMergeStrategy(),
+		riderCategoryStrategy: strategy.NewRiderCategoryMergeStrategy(),
+		fareProductStrategy:   strategy.NewFareProductMergeStrategy(),
+		timeframeStrategy:     strategy.NewTimeframeMergeStrategy(),
+		fareLegRuleStrategy:   strategy.NewFareLegRuleMergeStrategy(),
+		fareTransferStrategy:  strategy.NewFareTransferRuleMergeStrategy(),
+		feedInfoStrategy:      strategy.NewFeedInfoMergeStrategy(),
+		maxServiceGapDays:     -1,
 	}
 	for _, opt := range opts {
 		opt(m)
 	}
+	m.applyDetectionOverrides()
 	return m
 }
 
 // MergeFiles merges multiple GTFS files into one output file.
 // Input feeds are processed in FORWARD order (first feed first) to match Java behavior.
 // The first feed gets no prefix, later feeds get prefixes (b-, c-, d-, etc.) when IDs collide.
+// An input identical to an earlier one is skipped (see WithFailOnIdenticalInputs).
+// Missing inputs fail the merge with ErrInputNotFound before any input is
+// read, and read errors are collected from every input and joined, unless
+// WithSkipInvalidInputs leaves such inputs out.
 func (m *Merger) MergeFiles(inputPaths []string, outputPath string) error {
+	return m.MergeFilesContext(context.Background(), inputPaths, outputPath)
+}
+
+// MergeFilesContext is like MergeFiles but can be canceled through ctx.
+// Cancellation is checked between input and output files and throughout the
+// merge; the returned error wraps ctx.Err() with the interrupted stage.
+func (m *Merger) MergeFilesContext(ctx context.Context, inputPaths []string, outputPath string) error {
+	_, err := m.MergeFilesWithReport(ctx, inputPaths, outputPath)
+	return err
+}
+
+// MergeFilesWithReport is like MergeFilesContext but returns the merge's
+// Report, which stays its own when merges run concurrently. The report is
+// returned once the merge succeeds, even if writing the output then fails.
+func (m *Merger) MergeFilesWithReport(ctx context.Context, inputPaths []string, outputPath string) (*Report, error) {
 	if len(inputPaths) == 0 {
-		return ErrNoInputFeeds
+		return nil, ErrNoInputFeeds
+	}
+	if err := m.checkOptions(len(inputPaths)); err != nil {
+		return nil, err
+	}
+
+	// Guard against truncating an input. Every input is read into memory
+	// before the output is written, and the output replaces the old file
+	// only once it is complete, so overwriting is safe once allowed.
+	if !m.overwriteInput {
+		for _, path := range inputPaths {
+			same, err := samePath(path, outputPath)
+			if err != nil {
+				return nil, err
+			}
+			if same {
+				return nil, fmt.Errorf("%w: %s", ErrOutputIsInput, outputPath)
+			}
+		}
+	}
+
+	// Report every missing input before spending time reading the others
+	if !m.skipInvalidInputs {
+		if err := checkInputsExist(inputPaths); err != nil {
+			return nil, err
+		}
+	}
+
+	// A run repeating the one cached, with the output it wrote untouched,
+	// would write the same output again
+	var fp *fingerprint
+	if m.fingerprintCache != "" {
+		var hit bool
+		fp, hit = m.checkFingerprintCache(inputPaths, outputPath)
+		if hit {
+			log.Printf("Inputs and options unchanged since %s was written; skipping the merge", outputPath)
+			report := &Report{CacheHit: true}
+			m.setReport(report)
+			return report, nil
+		}
 	}
 
-	// Read all feeds
+	// Read all feeds, collecting the errors of every input that fails
+	allPaths := inputPaths
 	feeds := make([]*gtfs.Feed, 0, len(inputPaths))
-	for _, path := range inputPaths {
-		feed, err := gtfs.ReadFromPath(path)
+	validPaths := make([]string, 0, len(inputPaths))
+	var readErrs []error
+	var invalid []InvalidInput
+	for i, path := range inputPaths {
+		if m.skipInvalidInputs {
+			if err := checkInputsExist([]string{path}); err != nil {
+				invalid = append(invalid, InvalidInput{Path: path, Err: ErrInputNotFound})
+				readErrs = append(readErrs, err)
+				continue
+			}
+		}
+		start := time.Now()
+		feed, err := gtfs.ReadFromPathContextWithOptions(ctx, path, m.readerOptionsFor(i))
+		m.observeStage(StageRead, start)
 		if err != nil {
-			return fmt.Errorf("reading %s: %w", path, err)
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("reading %s: %w", path, err)
+			}
+			invalid = append(invalid, InvalidInput{Path: path, Err: err})
+			readErrs = append(readErrs, fmt.Errorf("reading %s: %w", path, err))
+			continue
 		}
 		feeds = append(feeds, feed)
+		validPaths = append(validPaths, path)
+	}
+	if len(readErrs) > 0 {
+		if !m.skipInvalidInputs {
+			return nil, errors.Join(readErrs...)
+		}
+		if len(feeds) < 2 {
+			return nil, fmt.Errorf("%w: %d of %d inputs read: %w", ErrTooFewValidInputs, len(feeds), len(inputPaths), errors.Join(readErrs...))
+		}
+		for _, i := range invalid {
+			log.Printf("WARNING: skipping invalid input %s", i)
+		}
+	}
+
+	// The same feed passed twice would otherwise be merged into itself
+	// with every colliding ID prefixed
+	feeds, inputPaths, skipped, err := skipIdenticalInputs(feeds, validPaths, m.failOnIdenticalInputs)
+	if err != nil {
+		return nil, err
+	}
+
+	// An input skipped is identical to an earlier one, so each input kept
+	// is the first given with its path
+	inputs := make([]int, len(inputPaths))
+	for i, path := range inputPaths {
+		inputs[i] = slices.Index(allPaths, path)
+	}
+
+	// Name each feed after its input file so the report, and feed_info rows
+	// under WithFeedInfoIDFromSource, stay stable across runs even when the
+	// input order changes
+	names := make([]string, len(inputPaths))
+	for i, path := range inputPaths {
+		names[i] = feedNameForPath(path)
 	}
 
 	// Merge feeds
-	merged, err := m.MergeFeeds(feeds)
+	merged, report, err := m.mergeFeeds(ctx, feeds, names, inputs)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	for i, path := range inputPaths {
+		report.Feeds[i].Path = path
 	}
+	report.SkippedInputs = skipped
+	report.InvalidInputs = invalid
+	report.Feed = merged
+	m.setReport(report)
 
 	// Write output
-	return gtfs.WriteToPath(merged, outputPath)
+	start := time.Now()
+	err = gtfs.WriteToPathContext(ctx, merged, outputPath, m.writerOptions)
+	m.observeStage(StageWrite, start)
+	if err != nil {
+		return report, err
+	}
+
+	// A run that skipped invalid inputs is left uncached, so that the
+	// inputs are tried, and reported, again
+	if fp != nil && len(invalid) == 0 {
+		if err := fp.save(m.fingerprintCache); err != nil {
+			return report, fmt.Errorf("writing fingerprint cache: %w", err)
+		}
+	}
+	return report, nil
+}
+
+// checkFingerprintCache fingerprints a run merging inputPaths into
+// outputPath and reports whether the fingerprint cache records the same
+// run. The fingerprint is nil, and the run left uncached, if an input
+// cannot be hashed or the configuration includes an anonymous function.
+func (m *Merger) checkFingerprintCache(inputPaths []string, outputPath string) (*fingerprint, bool) {
+	fp, err := m.newFingerprint(inputPaths, outputPath)
+	if err != nil {
+		log.Printf("WARNING: not caching the merge: %v", err)
+		return nil, false
+	}
+	cached, err := loadFingerprint(m.fingerprintCache)
+	if err != nil {
+		log.Printf("WARNING: ignoring fingerprint cache: %v", err)
+		return fp, false
+	}
+	return fp, fp.unchanged(cached)
+}
+
+// checkInputsExist returns an error wrapping ErrInputNotFound for each
+// input path that does not exist, joined (see errors.Join), or nil
+func checkInputsExist(paths []string) error {
+	var errs []error
+	for _, path := range paths {
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrInputNotFound, path))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// samePath reports whether a and b name the same file, comparing absolute
+// paths and, when both exist, file identity (to catch links)
+func samePath(a, b string) (bool, error) {
+	absA, err := filepath.Abs(a)
+	if err != nil {
+		return false, err
+	}
+	absB, err := filepath.Abs(b)
+	if err != nil {
+		return false, err
+	}
+	if absA == absB {
+		return true, nil
+	}
+
+	infoA, errA := os.Stat(absA)
+	infoB, errB := os.Stat(absB)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB), nil
+}
+
+// checkOptions returns the errors of checkInputIndexes for n inputs and of
+// checkDetectionEntities, joined, or nil
+func (m *Merger) checkOptions(n int) error {
+	return errors.Join(m.checkInputIndexes(n), m.checkDetectionEntities())
+}
+
+// checkInputIndexes returns an error wrapping ErrNoSuchInput for each
+// option set for one input whose index is not that of one of n inputs,
+// joined (see errors.Join), or nil
+func (m *Merger) checkInputIndexes(n int) error {
+	var errs []error
+	check := func(option string, indexes iter.Seq[int]) {
+		for _, index := range slices.Sorted(indexes) {
+			if index < 0 || index >= n {
+				errs = append(errs, fmt.Errorf("%w: %s for input %d of %d", ErrNoSuchInput, option, index, n))
+			}
+		}
+	}
+	check("WithInputReaderOptions", maps.Keys(m.inputReaderOptions))
+	check("WithOverrides", maps.Keys(m.overridePaths))
+	check("WithAgencyFilter", maps.Keys(m.agencyFilters))
+	check("WithIDNamespaceStrip", maps.Keys(m.idNamespaces))
+	return errors.Join(errs...)
+}
+
+// readerOptionsFor returns the reader options for the input at index
+func (m *Merger) readerOptionsFor(index int) gtfs.ReaderOptions {
+	if opts, ok := m.inputReaderOptions[index]; ok {
+		return opts
+	}
+	return m.readerOptions
 }
 
 // MergeFeeds merges multiple Feed objects into a single Feed.
 // Feeds are processed in FORWARD order (first element first) to match Java behavior.
+// Each feed is named with a letter label ("a", "b", ...) in the merge Report.
 func (m *Merger) MergeFeeds(feeds []*gtfs.Feed) (*gtfs.Feed, error) {
+	return m.MergeFeedsContext(context.Background(), feeds)
+}
+
+// MergeFeedsContext is like MergeFeeds but can be canceled through ctx.
+// The returned error wraps ctx.Err() with the feed and entity type being
+// merged when cancellation was noticed.
+func (m *Merger) MergeFeedsContext(ctx context.Context, feeds []*gtfs.Feed) (*gtfs.Feed, error) {
+	merged, _, err := m.MergeFeedsWithReport(ctx, feeds)
+	return merged, err
+}
+
+// MergeFeedsWithReport is like MergeFeedsContext but also returns the
+// merge's Report, which stays its own when merges run concurrently.
+func (m *Merger) MergeFeedsWithReport(ctx context.Context, feeds []*gtfs.Feed) (*gtfs.Feed, *Report, error) {
+	if err := m.checkOptions(len(feeds)); err != nil {
+		return nil, nil, err
+	}
+	names := make([]string, len(feeds))
+	inputs := make([]int, len(feeds))
+	for i := range feeds {
+		names[i] = feedNameForIndex(i)
+		inputs[i] = i
+	}
+	merged, report, err := m.mergeFeeds(ctx, feeds, names, inputs)
+	if err != nil {
+		return nil, nil, err
+	}
+	report.Feed = merged
+	m.setReport(report)
+	return merged, report, nil
+}
+
+// mergeFeeds merges feeds, recording each feed under the matching name in
+// the returned report; inputs gives each feed's index among the inputs, to
+// which options set per input refer
+func (m *Merger) mergeFeeds(ctx context.Context, feeds []*gtfs.Feed, names []string, inputs []int) (*gtfs.Feed, *Report, error) {
 	if len(feeds) == 0 {
-		return nil, ErrNoInputFeeds
+		return nil, nil, ErrNoInputFeeds
+	}
+	readCounts := make([]map[string]int, len(feeds))
+	overridden := make([]overrideResult, len(feeds))
+	agencyFiltered := make([]agencyFilterResult, len(feeds))
+	sanitized := make([]sanitizeResult, len(feeds))
+	texts := make([]textResult, len(feeds))
+	stationFixes := make([]stationFixResult, len(feeds))
+	routeTypes := make([]routeTypeResult, len(feeds))
+	colors := make([]int, len(feeds))
+	for i, feed := range feeds {
+		if feed == nil {
+			return nil, nil, fmt.Errorf("%w: feed %d", ErrNilFeed, i)
+		}
+		if len(feed.PartialRead) > 0 {
+			return nil, nil, fmt.Errorf("%w: feed %d skipped %s", ErrPartialFeed, i, strings.Join(feed.PartialRead, ", "))
+		}
+		readCounts[i] = maps.Clone(feed.RowsRead)
+		if readCounts[i] == nil {
+			readCounts[i] = feed.RowCounts()
+		}
+		if path, ok := m.overridePaths[inputs[i]]; ok {
+			overrides, err := LoadOverrides(path)
+			if err != nil {
+				return nil, nil, fmt.Errorf("reading overrides %s: %w", path, err)
+			}
+			overridden[i] = applyOverrides(feed, overrides)
+		}
+		if ids, ok := m.agencyFilters[inputs[i]]; ok {
+			agencyFiltered[i] = filterAgencies(feed, ids, m.agencyFilterKeepStops)
+		}
+		if m.sanitizeText {
+			texts[i] = sanitizeText(feed)
+		}
+		if m.normalizeColors {
+			colors[i] = normalizeColors(feed)
+		}
+		if m.basicRouteTypes {
+			routeTypes[i] = mapRouteTypes(feed)
+		}
+		if m.sanitizeInputs {
+			sanitized[i] = sanitizeFeed(feed)
+		}
+		if m.fixStationStopTimes {
+			stationFixes[i] = fixStationStopTimes(feed, m.sanitizeInputs)
+			if n := stationFixes[i].removed(); n > 0 {
+				sanitized[i].removed["stop_times.txt"] += n
+			}
+		}
+		if m.validateInputs {
+			start := time.Now()
+			err := feed.ValidateAll()
+			m.observeStage(StageValidate, start)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: feed %d: %w", ErrInvalidFeed, i, err)
+			}
+		}
+	}
+
+	names = uniqueFeedNames(names)
+	report := &Report{Feeds: make([]FeedReport, len(feeds))}
+	for i := range feeds {
+		report.Feeds[i] = FeedReport{
+			Index:                 i,
+			Name:                  names[i],
+			Sanitized:             sanitized[i].removed,
+			StationStopTimesFixed: stationFixes[i].rewritten,
+			OverridesApplied:      overridden[i].applied,
+			RouteTypesMapped:      routeTypes[i].mapped,
+			ColorsNormalized:      colors[i],
+			AgencyFiltered:        agencyFiltered[i].removed,
+			TextSanitized:         texts[i].cleaned,
+			Read:                  readCounts[i],
+			Dropped:               addCounts(addCounts(nil, agencyFiltered[i].removed), sanitized[i].removed),
+		}
+		for _, w := range overridden[i].warnings(names[i]) {
+			log.Printf("WARNING: %s", w)
+			report.Warnings = append(report.Warnings, w)
+		}
+		for _, w := range agencyFiltered[i].warnings(names[i]) {
+			log.Printf("WARNING: %s", w)
+			report.Warnings = append(report.Warnings, w)
+		}
+		for _, w := range routeTypes[i].warnings(names[i]) {
+			log.Printf("WARNING: %s", w)
+			report.Warnings = append(report.Warnings, w)
+		}
+		for _, filename := range slices.Sorted(maps.Keys(texts[i].cleaned)) {
+			log.Printf("WARNING: feed %s: cleaned %d %s text values with control characters, invalid UTF-8 or surrounding whitespace", names[i], texts[i].cleaned[filename], filename)
+		}
+		for _, filename := range slices.Sorted(maps.Keys(sanitized[i].removed)) {
+			log.Printf("WARNING: feed %s: dropped %d %s rows with dangling references", names[i], sanitized[i].removed[filename], filename)
+		}
+		report.Warnings = append(report.Warnings, sanitized[i].warnings(names[i])...)
+		for _, w := range stationFixes[i].warnings(names[i]) {
+			log.Printf("WARNING: %s", w)
+			report.Warnings = append(report.Warnings, w)
+		}
+	}
+
+	// Each feed's own duplicates are collapsed before it is merged with the
+	// others, and counted as deduplicated
+	var intraFeed []intraFeedResult
+	if m.intraFeedDedup {
+		feeds = slices.Clone(feeds)
+		intraFeed = make([]intraFeedResult, len(feeds))
+		for i := range feeds {
+			var err error
+			if feeds[i], intraFeed[i], err = m.dedupFeed(ctx, feeds[i], names[i]); err != nil {
+				return nil, nil, fmt.Errorf("deduplicating feed %d: %w", i, err)
+			}
+			report.Feeds[i].IntraFeedDuplicates = intraFeed[i].collapsed
+			report.Feeds[i].Deduplicated = addCounts(report.Feeds[i].Deduplicated, intraFeed[i].deduplicated)
+			report.Feeds[i].Dropped = addCounts(report.Feeds[i].Dropped, intraFeed[i].dropped)
+		}
+	}
+
+	var tripIDParts []tripIDPart
+	if m.readableTripIDs != "" {
+		var err error
+		if tripIDParts, err = parseTripIDPattern(m.readableTripIDs); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var blocked *blockedMatcher
+	if len(m.blockedPairs) > 0 {
+		var err error
+		if blocked, err = newBlockedMatcher(m.blockedPairs); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var namespaced namespaceIndex
+	if len(m.idNamespaces) > 0 {
+		namespaced = make(namespaceIndex)
+	}
+
+	pruneKinds, err := parsePruneKinds(m.pruneKinds)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var distanceScale []float64
+	if m.shapeDistanceUnit != DistanceUnknown {
+		distanceScale = distanceScales(feeds, m.shapeDistanceUnit, report)
 	}
 
 	// Start with an empty target feed
 	target := gtfs.NewFeed()
 
+	// The editions each merged agency's fields came from, for
+	// WithNewestAgencyEdition
+	agencyEditions := make(map[gtfs.AgencyID]map[string]strategy.FeedEdition)
+
 	// Shared counter for shape sequences - persists across all feeds to match Java behavior
 	sharedShapeCounter := 0
 
@@ -109,102 +656,256 @@ func (m *Merger) MergeFeeds(feeds []*gtfs.Feed) (*gtfs.Feed, error) {
 	// The last feed (first processed) gets no prefix.
 	// The prefix is only applied when there's an ID collision during merge.
 	for i := len(feeds) - 1; i >= 0; i-- {
-		// Last feed (first processed) gets no prefix, others get prefix based on original index
+		// Last feed (first processed) gets no prefix, unless every ID is
+		// prefixed, others get prefix based on original index
 		var prefix string
-		if i == len(feeds)-1 {
+		if i == len(feeds)-1 && !m.alwaysPrefix {
 			prefix = ""
 		} else {
 			prefix = GetPrefixForIndex(i + 1)
 		}
+		if m.feedPrefixes != nil {
+			prefix = m.feedPrefixes[i]
+		}
 
-		ctx := strategy.NewMergeContext(feeds[i], target, prefix)
-		ctx.SetSharedShapeCounter(&sharedShapeCounter)
+		mctx := strategy.NewMergeContext(feeds[i], target, prefix)
+		mctx.SetSharedShapeCounter(&sharedShapeCounter)
+		mctx.SetContext(ctx)
+		mctx.SourceFeed = names[i]
+		mctx.SourceEdition = strategy.NewFeedEdition(feeds[i])
+		mctx.AgencyEditions = agencyEditions
+		mctx.AlwaysPrefix = m.alwaysPrefix
+		mctx.NormalizeShapes = m.normalizeShapes
+		mctx.NormalizeOutput = m.normalizeOutput
+		mctx.GrayZone = m.grayZone
+		if blocked != nil {
+			mctx.BlockedMatches = blocked.matches(names[i])
+		}
+		if namespaced != nil {
+			mctx.StripIDPrefixes = m.idNamespaces[inputs[i]]
+			mctx.NamespacedIDs = namespaced
+		}
+		report.Feeds[i].Prefix = prefix
+		report.Feeds[i].Edition = mctx.SourceEdition
+		if distanceScale != nil {
+			mctx.DistanceScale = distanceScale[i]
+		}
 
 		// Merge column sets from source feed to track which columns were present
 		target.MergeColumnSets(feeds[i])
 
-		if err := m.mergeFeed(ctx); err != nil {
-			return nil, fmt.Errorf("merging feed %d: %w", i, err)
+		before := target.RowCounts()
+		if err := m.mergeFeed(mctx); err != nil {
+			return nil, nil, fmt.Errorf("merging feed %d: %w", i, err)
 		}
+		orphans := removedRows(target, func() { dropDeduplicatedOrphans(mctx) })
+		recordSources(target, mctx, i)
+		report.Feeds[i].IDMap = idMap(mctx)
+		if intraFeed != nil {
+			report.Feeds[i].IDMap = composeIDMaps(intraFeed[i].ids, report.Feeds[i].IDMap)
+		}
+		report.GrayZone = append(report.GrayZone, mctx.GrayZoneMatches...)
+		report.TripSubsetMatches = append(report.TripSubsetMatches, mctx.TripSubsetMatches...)
+		for _, d := range mctx.BoardingDifferences {
+			log.Printf("WARNING: %s", d)
+			report.Warnings = append(report.Warnings, d.String())
+		}
+		report.BoardingDifferences = append(report.BoardingDifferences, mctx.BoardingDifferences...)
+		for _, c := range mctx.AgencyFieldChanges {
+			log.Printf("WARNING: %s", c)
+			report.Warnings = append(report.Warnings, c.String())
+		}
+		report.AgencyFieldChanges = append(report.AgencyFieldChanges, mctx.AgencyFieldChanges...)
+		for _, u := range mctx.UnresolvedParentStations {
+			log.Printf("WARNING: %s", u)
+			report.Warnings = append(report.Warnings, u.String())
+		}
+		report.UnresolvedParentStations = append(report.UnresolvedParentStations, mctx.UnresolvedParentStations...)
+		for _, h := range mctx.FuzzyLimitHits {
+			log.Printf("WARNING: %s", h)
+			report.Warnings = append(report.Warnings, h.String())
+		}
+		report.FuzzyLimitHits = append(report.FuzzyLimitHits, mctx.FuzzyLimitHits...)
+		if m.debug {
+			for _, match := range mctx.TripSubsetMatches {
+				log.Printf("DEBUG: %s", match)
+			}
+		}
+		if blocked != nil {
+			blocked.record(names[i], mctx)
+		}
+		if namespaced != nil {
+			namespaced.record(mctx)
+		}
+		report.Feeds[i].Added = rowCountDelta(before, target.RowCounts())
+		report.Feeds[i].Deduplicated = addCounts(report.Feeds[i].Deduplicated, mctx.Deduplicated)
+		report.Feeds[i].Dropped = addCounts(addCounts(report.Feeds[i].Dropped, mctx.Dropped), orphans)
 	}
 
-	return target, nil
-}
-
-// mergeFeed merges a single source feed into the target
-func (m *Merger) mergeFeed(ctx *strategy.MergeContext) error {
-	// Merge entities in dependency order:
-	// 1. Agencies (no dependencies)
-	if err := m.agencyStrategy.Merge(ctx); err != nil {
-		return fmt.Errorf("merging agencies: %w", err)
+	if m.grayZone.Policy == strategy.GrayZoneAbort && len(report.GrayZone) > 0 {
+		return nil, nil, ambiguousMatchesError(report.GrayZone)
 	}
 
-	// 2. Areas (no dependencies)
-	if err := m.areaStrategy.Merge(ctx); err != nil {
-		return fmt.Errorf("merging areas: %w", err)
+	// Trips are renamed before the checks below, so that their reports
+	// name the trips as written
+	if tripIDParts != nil {
+		report.ReadableTripIDs = rewriteTripIDs(target, tripIDParts)
+		report.renameTripIDs(report.ReadableTripIDs)
 	}
 
-	// 3. Stops (references: parent_station)
-	if err := m.stopStrategy.Merge(ctx); err != nil {
-		return fmt.Errorf("merging stops: %w", err)
+	report.DirectionConflicts = checkDirections(target, names, m.harmonizeDirections)
+	for _, c := range report.DirectionConflicts {
+		report.Warnings = append(report.Warnings, c.String())
 	}
 
-	// 4. Service Calendars (no dependencies)
-	if err := m.calendarStrategy.Merge(ctx); err != nil {
-		return fmt.Errorf("merging calendars: %w", err)
+	if !m.basicRouteTypes {
+		checkRouteTypes(target, report)
 	}
-	if err := m.calendarDateStrategy.Merge(ctx); err != nil {
-		return fmt.Errorf("merging calendar_dates: %w", err)
+	checkRouteColors(target, report)
+	applyRouteSortOrder(target, m.routeSortOrder)
+	// Consumers expect each trip's stop_times contiguous and in
+	// stop_sequence order, which interleaving feeds can break
+	target.SortTripRows()
+	if m.interpolateStopTimes {
+		interpolated := interpolateStopTimes(target)
+		report.StopTimesInterpolated = interpolated.interpolated
+		report.UninterpolatedStopTimes = interpolated.uninterpolated
+		for _, w := range interpolated.warnings() {
+			log.Printf("WARNING: %s", w)
+			report.Warnings = append(report.Warnings, w)
+		}
 	}
-
-	// 5. Routes (references: agency_id)
-	if err := m.routeStrategy.Merge(ctx); err != nil {
-		return fmt.Errorf("merging routes: %w", err)
+	report.Dropped = addCounts(report.Dropped, removedRows(target, func() {
+		applyFrequencyOverlapPolicy(target, m.frequencyOverlaps, report)
+	}))
+	if len(pruneKinds) > 0 {
+		report.Dropped = addCounts(report.Dropped, removedRows(target, func() {
+			report.Pruned = pruneUnreferenced(target, pruneKinds)
+		}))
 	}
-
-	// 6. Shapes (no dependencies)
-	if err := m.shapeStrategy.Merge(ctx); err != nil {
-		return fmt.Errorf("merging shapes: %w", err)
+	if err := applyStopCodePolicy(target, m.stopCodePolicy, report); err != nil {
+		return nil, nil, err
 	}
-
-	// 7. Trips (references: route_id, service_id, shape_id)
-	if err := m.tripStrategy.Merge(ctx); err != nil {
-		return fmt.Errorf("merging trips: %w", err)
+	report.Warnings = append(report.Warnings, consolidateFeedLanguages(target)...)
+	if m.serviceDays > 0 {
+		report.ServiceCoverage = checkServiceCoverage(target, m.serviceFrom, m.serviceDays)
+		report.Warnings = append(report.Warnings, report.ServiceCoverage.Warnings()...)
 	}
-
-	// 8. Stop Times (references: trip_id, stop_id)
-	if err := m.stopTimeStrategy.Merge(ctx); err != nil {
-		return fmt.Errorf("merging stop_times: %w", err)
+	if m.maxServiceGapDays >= 0 {
+		checkServiceGaps(target, m.maxServiceGapDays, report)
 	}
-
-	// 9. Frequencies (references: trip_id)
-	if err := m.frequencyStrategy.Merge(ctx); err != nil {
-		return fmt.Errorf("merging frequencies: %w", err)
+	report.recordFeedInfos(target)
+	report.Merged = target.RowCounts()
+	if m.accountingCheck {
+		if err := checkAccounting(report); err != nil {
+			return nil, nil, err
+		}
 	}
-
-	// 10. Transfers (references: from_stop_id, to_stop_id)
-	if err := m.transferStrategy.Merge(ctx); err != nil {
-		return fmt.Errorf("merging transfers: %w", err)
+	report.Sources = target.Sources
+	report.dropDeletedIDs(target.Sources)
+	if blocked != nil {
+		report.UnusedBlockedPairs = blocked.unused()
 	}
-
-	// 11. Pathways (references: from_stop_id, to_stop_id)
-	if err := m.pathwayStrategy.Merge(ctx); err != nil {
-		return fmt.Errorf("merging pathways: %w", err)
+	if m.strictOutput {
+		start := time.Now()
+		refs, total := findBrokenReferences(target, maxBrokenReferences)
+		m.observeStage(StageValidate, start)
+		if total > 0 {
+			return nil, nil, brokenReferencesError(target, names, refs, total)
+		}
 	}
-
-	// 12. Fare Attributes (references: agency_id)
-	if err := m.fareAttrStrategy.Merge(ctx); err != nil {
-		return fmt.Errorf("merging fare_attributes: %w", err)
+	if m.metrics != nil {
+		reportCounts(m.metrics, report)
 	}
 
-	// 13. Fare Rules (references: fare_id, route_id)
-	if err := m.fareRuleStrategy.Merge(ctx); err != nil {
-		return fmt.Errorf("merging fare_rules: %w", err)
+	return target, report, nil
+}
+
+// Report returns a summary of the most recent successful merge,
+// or nil if no merge has completed. When merges run concurrently, it is
+// the report of whichever finished last.
+//
+// Deprecated: Use MergeFilesWithReport or MergeFeedsWithReport, which
+// return each merge's own report.
+func (m *Merger) Report() *Report {
+	m.reportMu.Lock()
+	defer m.reportMu.Unlock()
+	return m.report
+}
+
+// setReport records report as the most recent merge's
+func (m *Merger) setReport(report *Report) {
+	m.reportMu.Lock()
+	defer m.reportMu.Unlock()
+	m.report = report
+}
+
+// mergeFeed merges a single source feed into the target
+func (m *Merger) mergeFeed(ctx *strategy.MergeContext) error {
+	// Merge entities in dependency order
+	steps := []struct {
+		file     string
+		what     string
+		strategy strategy.EntityMergeStrategy
+	}{
+		// 1. Agencies (no dependencies)
+		{"agency.txt", "agencies", m.agencyStrategy},
+		// 2. Areas (no dependencies)
+		{"areas.txt", "areas", m.areaStrategy},
+		// 3. Networks (no dependencies; also maps network IDs used only by routes)
+		{"networks.txt", "networks", m.networkStrategy},
+		// 4. Stops (references: parent_station)
+		{"stops.txt", "stops", m.stopStrategy},
+		// 5. Service Calendars (no dependencies)
+		{"calendar.txt", "calendars", m.calendarStrategy},
+		{"calendar_dates.txt", "calendar_dates", m.calendarDateStrategy},
+		// 6. Routes (references: agency_id, network_id)
+		{"routes.txt", "routes", m.routeStrategy},
+		// 7. Route Networks (references: network_id, route_id)
+		{"route_networks.txt", "route_networks", m.routeNetworkStrategy},
+		// 8. Shapes (no dependencies)
+		{"shapes.txt", "shapes", m.shapeStrategy},
+		// 9. Trips (references: route_id, service_id, shape_id)
+		{"trips.txt", "trips", m.tripStrategy},
+		// 10. Stop Times (references: trip_id, stop_id)
+		{"stop_times.txt", "stop_times", m.stopTimeStrategy},
+		// 11. Frequencies (references: trip_id)
+		{"frequencies.txt", "frequencies", m.frequencyStrategy},
+		// 12. Transfers (references: from_stop_id, to_stop_id)
+		{"transfers.txt", "transfers", m.transferStrategy},
+		// 13. Pathways (references: from_stop_id, to_stop_id)
+		{"pathways.txt", "pathways", m.pathwayStrategy},
+		// 14. Fare Attributes (references: agency_id)
+		{"fare_attributes.txt", "fare_attributes", m.fareAttrStrategy},
+		// 15. Fare Rules (references: fare_id, route_id)
+		{"fare_rules.txt", "fare_rules", m.fareRuleStrategy},
+		// 16. Fare Media, Rider Categories (no dependencies)
+		{"fare_media.txt", "fare_media", m.fareMediaStrategy},
+		{"rider_categories.txt", "rider_categories", m.riderCategoryStrategy},
+		// 17. Fare Products (references: rider_category_id, fare_media_id;
+		// also maps fare product IDs used only by fare leg and transfer rules)
+		{"fare_products.txt", "fare_products", m.fareProductStrategy},
+		// 18. Timeframes (references: service_id; also maps timeframe groups
+		// used only by fare leg rules)
+		{"timeframes.txt", "timeframes", m.timeframeStrategy},
+		// 19. Fare Leg Rules (references: network_id, from_area_id, to_area_id,
+		// timeframe groups, fare_product_id; also maps leg group IDs used only
+		// by transfer rules)
+		{"fare_leg_rules.txt", "fare_leg_rules", m.fareLegRuleStrategy},
+		// 20. Fare Transfer Rules (references: from_leg_group_id,
+		// to_leg_group_id, fare_product_id)
+		{"fare_transfer_rules.txt", "fare_transfer_rules", m.fareTransferStrategy},
+		// 21. Feed Info (no dependencies)
+		{"feed_info.txt", "feed_info", m.feedInfoStrategy},
 	}
 
-	// 14. Feed Info (no dependencies)
-	if err := m.feedInfoStrategy.Merge(ctx); err != nil {
-		return fmt.Errorf("merging feed_info: %w", err)
+	for _, step := range steps {
+		start := time.Now()
+		err := step.strategy.Merge(ctx)
+		m.observeStage(StageMergePrefix+step.file, start)
+		if err != nil {
+			return fmt.Errorf("merging %s: %w", step.what, err)
+		}
 	}
 
 	return nil
@@ -237,11 +938,21 @@ func (m *Merger) SetCalendarStrategy(s strategy.EntityMergeStrategy) {
 	m.calendarStrategy = s
 }
 
+// SetCalendarDateStrategy sets the calendar date merge strategy
+func (m *Merger) SetCalendarDateStrategy(s strategy.EntityMergeStrategy) {
+	m.calendarDateStrategy = s
+}
+
 // SetShapeStrategy sets the shape merge strategy
 func (m *Merger) SetShapeStrategy(s strategy.EntityMergeStrategy) {
 	m.shapeStrategy = s
 }
 
+// SetStopTimeStrategy sets the stop time merge strategy
+func (m *Merger) SetStopTimeStrategy(s strategy.EntityMergeStrategy) {
+	m.stopTimeStrategy = s
+}
+
 // SetFrequencyStrategy sets the frequency merge strategy
 func (m *Merger) SetFrequencyStrategy(s strategy.EntityMergeStrategy) {
 	m.frequencyStrategy = s
@@ -252,6 +963,11 @@ func (m *Merger) SetTransferStrategy(s strategy.EntityMergeStrategy) {
 	m.transferStrategy = s
 }
 
+// SetPathwayStrategy sets the pathway merge strategy
+func (m *Merger) SetPathwayStrategy(s strategy.EntityMergeStrategy) {
+	m.pathwayStrategy = s
+}
+
 // SetFareAttributeStrategy sets the fare attribute merge strategy
 func (m *Merger) SetFareAttributeStrategy(s strategy.EntityMergeStrategy) {
 	m.fareAttrStrategy = s
@@ -262,6 +978,36 @@ func (m *Merger) SetFareRuleStrategy(s strategy.EntityMergeStrategy) {
 	m.fareRuleStrategy = s
 }
 
+// SetFareMediaStrategy sets the fare media merge strategy
+func (m *Merger) SetFareMediaStrategy(s strategy.EntityMergeStrategy) {
+	m.fareMediaStrategy = s
+}
+
+// SetRiderCategoryStrategy sets the rider category merge strategy
+func (m *Merger) SetRiderCategoryStrategy(s strategy.EntityMergeStrategy) {
+	m.riderCategoryStrategy = s
+}
+
+// SetFareProductStrategy sets the fare product merge strategy
+func (m *Merger) SetFareProductStrategy(s strategy.EntityMergeStrategy) {
+	m.fareProductStrategy = s
+}
+
+// SetTimeframeStrategy sets the timeframe merge strategy
+func (m *Merger) SetTimeframeStrategy(s strategy.EntityMergeStrategy) {
+	m.timeframeStrategy = s
+}
+
+// SetFareLegRuleStrategy sets the fare leg rule merge strategy
+func (m *Merger) SetFareLegRuleStrategy(s strategy.EntityMergeStrategy) {
+	m.fareLegRuleStrategy = s
+}
+
+// SetFareTransferRuleStrategy sets the fare transfer rule merge strategy
+func (m *Merger) SetFareTransferRuleStrategy(s strategy.EntityMergeStrategy) {
+	m.fareTransferStrategy = s
+}
+
 // SetFeedInfoStrategy sets the feed info merge strategy
 func (m *Merger) SetFeedInfoStrategy(s strategy.EntityMergeStrategy) {
 	m.feedInfoStrategy = s
@@ -279,6 +1025,16 @@ func (m *Merger) SetAreaStrategy(s strategy.EntityMergeStrategy) {
 	m.areaStrategy = s
 }
 
+// SetNetworkStrategy sets the network merge strategy
+func (m *Merger) SetNetworkStrategy(s strategy.EntityMergeStrategy) {
+	m.networkStrategy = s
+}
+
+// SetRouteNetworkStrategy sets the route network merge strategy
+func (m *Merger) SetRouteNetworkStrategy(s strategy.EntityMergeStrategy) {
+	m.routeNetworkStrategy = s
+}
+
 // GetStrategyForFile returns the strategy for a specific GTFS file
 func (m *Merger) GetStrategyForFile(filename string) strategy.EntityMergeStrategy {
 	switch filename {
@@ -286,6 +1042,10 @@ func (m *Merger) GetStrategyForFile(filename string) strategy.EntityMergeStrateg
 		return m.agencyStrategy
 	case "areas.txt":
 		return m.areaStrategy
+	case "networks.txt":
+		return m.networkStrategy
+	case "route_networks.txt":
+		return m.routeNetworkStrategy
 	case "stops.txt":
 		return m.stopStrategy
 	case "calendar.txt":
@@ -310,6 +1070,18 @@ func (m *Merger) GetStrategyForFile(filename string) strategy.EntityMergeStrateg
 		return m.fareAttrStrategy
 	case "fare_rules.txt":
 		return m.fareRuleStrategy
+	case "fare_media.txt":
+		return m.fareMediaStrategy
+	case "rider_categories.txt":
+		return m.riderCategoryStrategy
+	case "fare_products.txt":
+		return m.fareProductStrategy
+	case "timeframes.txt":
+		return m.timeframeStrategy
+	case "fare_leg_rules.txt":
+		return m.fareLegRuleStrategy
+	case "fare_transfer_rules.txt":
+		return m.fareTransferStrategy
 	case "feed_info.txt":
 		return m.feedInfoStrategy
 	default:
@@ -318,9 +1090,12 @@ func (m *Merger) GetStrategyForFile(filename string) strategy.EntityMergeStrateg
 }
 
 // SetDuplicateDetectionForAll sets duplicate detection for all strategies
+// except those given their own mode with WithDetectionFor
 func (m *Merger) SetDuplicateDetectionForAll(d strategy.DuplicateDetection) {
 	m.agencyStrategy.SetDuplicateDetection(d)
 	m.areaStrategy.SetDuplicateDetection(d)
+	m.networkStrategy.SetDuplicateDetection(d)
+	m.routeNetworkStrategy.SetDuplicateDetection(d)
 	m.stopStrategy.SetDuplicateDetection(d)
 	m.calendarStrategy.SetDuplicateDetection(d)
 	m.calendarDateStrategy.SetDuplicateDetection(d)
@@ -333,5 +1108,12 @@ func (m *Merger) SetDuplicateDetectionForAll(d strategy.DuplicateDetection) {
 	m.pathwayStrategy.SetDuplicateDetection(d)
 	m.fareAttrStrategy.SetDuplicateDetection(d)
 	m.fareRuleStrategy.SetDuplicateDetection(d)
+	m.fareMediaStrategy.SetDuplicateDetection(d)
+	m.riderCategoryStrategy.SetDuplicateDetection(d)
+	m.fareProductStrategy.SetDuplicateDetection(d)
+	m.timeframeStrategy.SetDuplicateDetection(d)
+	m.fareLegRuleStrategy.SetDuplicateDetection(d)
+	m.fareTransferStrategy.SetDuplicateDetection(d)
 	m.feedInfoStrategy.SetDuplicateDetection(d)
+	m.applyDetectionOverrides()
 }
@@ -1,11 +1,22 @@
 package merge
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"maps"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfstest"
 	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
 )
 
@@ -411,6 +422,125 @@ func TestMergeFilesEndToEnd(t *testing.T) {
 	}
 }
 
+func TestMergeFilesWritesNoEmptyFiles(t *testing.T) {
+	// Given: a feed with a header-only transfers.txt and one with a
+	// zero-byte calendar_dates.txt
+	inputs := []string{"../testdata/header_only_transfers", "../testdata/zero_byte_calendar_dates"}
+	outputPath := filepath.Join(t.TempDir(), "merged.zip")
+
+	// When: merged
+	if err := New().MergeFiles(inputs, outputPath); err != nil {
+		t.Fatalf("MergeFiles failed: %v", err)
+	}
+
+	// Then: neither file is written without rows
+	zr, err := zip.OpenReader(outputPath)
+	if err != nil {
+		t.Fatalf("failed to open merged output: %v", err)
+	}
+	defer func() { _ = zr.Close() }()
+	for _, f := range zr.File {
+		if f.Name == "transfers.txt" || f.Name == "calendar_dates.txt" {
+			t.Errorf("Expected no %s in the merged output", f.Name)
+		}
+	}
+}
+
+func TestMergeFilesNestedZip(t *testing.T) {
+	// Given: simple_a published as a zip of a zip, and simple_b as a directory
+	tmpDir := t.TempDir()
+	feedA, err := gtfs.ReadFromPath("../testdata/simple_a")
+	if err != nil {
+		t.Fatalf("failed to read simple_a: %v", err)
+	}
+	var inner bytes.Buffer
+	if err := gtfs.WriteToZip(feedA, &inner); err != nil {
+		t.Fatalf("failed to zip simple_a: %v", err)
+	}
+	var outer bytes.Buffer
+	zw := zip.NewWriter(&outer)
+	w, err := zw.Create("feed.zip")
+	if err != nil {
+		t.Fatalf("failed to add feed.zip: %v", err)
+	}
+	if _, err := w.Write(inner.Bytes()); err != nil {
+		t.Fatalf("failed to write feed.zip: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to close zip: %v", err)
+	}
+	feedAPath := filepath.Join(tmpDir, "gtfs.zip")
+	if err := os.WriteFile(feedAPath, outer.Bytes(), 0644); err != nil {
+		t.Fatalf("failed to write gtfs.zip: %v", err)
+	}
+
+	// When: MergeFiles() called
+	outputPath := filepath.Join(tmpDir, "merged.zip")
+	if err := New().MergeFiles([]string{feedAPath, "../testdata/simple_b"}, outputPath); err != nil {
+		t.Fatalf("MergeFiles failed: %v", err)
+	}
+
+	// Then: the nested feed is merged like any other
+	merged, err := gtfs.ReadFromPath(outputPath)
+	if err != nil {
+		t.Fatalf("failed to read merged output: %v", err)
+	}
+	feedB, err := gtfs.ReadFromPath("../testdata/simple_b")
+	if err != nil {
+		t.Fatalf("failed to read simple_b: %v", err)
+	}
+	if want := len(feedA.Agencies) + len(feedB.Agencies); len(merged.Agencies) != want {
+		t.Errorf("expected %d agencies in merged output, got %d", want, len(merged.Agencies))
+	}
+}
+
+func TestMergeFilesRefusesToOverwriteInput(t *testing.T) {
+	// Given: an input zip that is also named as the output, by another path
+	tmpDir := t.TempDir()
+	feedA, err := gtfs.ReadFromPath("../testdata/simple_a")
+	if err != nil {
+		t.Fatalf("failed to read simple_a: %v", err)
+	}
+	feedAPath := filepath.Join(tmpDir, "feed_a.zip")
+	if err := gtfs.WriteToPath(feedA, feedAPath); err != nil {
+		t.Fatalf("failed to create feed_a.zip: %v", err)
+	}
+	before, err := os.ReadFile(feedAPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(tmpDir, "sub", "..", "feed_a.zip")
+
+	// When: merged without WithOverwriteInput
+	err = New().MergeFiles([]string{feedAPath, "../testdata/simple_b"}, output)
+
+	// Then: the merge is refused and the input is untouched
+	if !errors.Is(err, ErrOutputIsInput) {
+		t.Fatalf("Expected ErrOutputIsInput, got %v", err)
+	}
+	after, err := os.ReadFile(feedAPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Error("Expected input to be unchanged")
+	}
+
+	// When: merged with WithOverwriteInput
+	if err := New(WithOverwriteInput(true)).MergeFiles([]string{feedAPath, "../testdata/simple_b"}, output); err != nil {
+		t.Fatalf("MergeFiles failed: %v", err)
+	}
+
+	// Then: the input is replaced by the merged feed
+	merged, err := gtfs.ReadFromPath(feedAPath)
+	if err != nil {
+		t.Fatalf("failed to read merged output: %v", err)
+	}
+	if len(merged.Agencies) <= len(feedA.Agencies) {
+		t.Errorf("Expected merged agencies, got %d", len(merged.Agencies))
+	}
+}
+
 func TestMergeNoInputFeeds(t *testing.T) {
 	merger := New()
 	_, err := merger.MergeFeeds([]*gtfs.Feed{})
@@ -435,6 +565,123 @@ func TestMergeSingleFeed(t *testing.T) {
 	}
 }
 
+func TestMergeZeroValueFeeds(t *testing.T) {
+	// Feeds built without gtfs.NewFeed have nil maps
+	empty := &gtfs.Feed{}
+	withData := &gtfs.Feed{
+		Agencies: map[gtfs.AgencyID]*gtfs.Agency{
+			"A1": {ID: "A1", Name: "Test", URL: "http://test.com", Timezone: "UTC"},
+		},
+		Areas: map[gtfs.AreaID]*gtfs.Area{
+			"area1": {ID: "area1", Name: "Downtown"},
+		},
+	}
+
+	for _, detection := range []strategy.DuplicateDetection{
+		strategy.DetectionNone, strategy.DetectionIdentity, strategy.DetectionFuzzy,
+	} {
+		merger := New(WithDefaultDetection(detection))
+		merged, err := merger.MergeFeeds([]*gtfs.Feed{empty, withData, &gtfs.Feed{}})
+		if err != nil {
+			t.Fatalf("%s: merge failed: %v", detection, err)
+		}
+		if len(merged.Agencies) != 1 {
+			t.Errorf("%s: expected 1 agency, got %d", detection, len(merged.Agencies))
+		}
+		if len(merged.Areas) != 1 {
+			t.Errorf("%s: expected 1 area, got %d", detection, len(merged.Areas))
+		}
+	}
+}
+
+func TestMergeNilFeed(t *testing.T) {
+	merger := New()
+	_, err := merger.MergeFeeds([]*gtfs.Feed{gtfs.NewFeed(), nil})
+	if !errors.Is(err, ErrNilFeed) {
+		t.Errorf("expected ErrNilFeed, got %v", err)
+	}
+}
+
+func TestMergeOptionForMissingInput(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Option
+	}{
+		{"overrides", WithOverrides(2, "overrides.csv")},
+		{"agency filter", WithAgencyFilter(5, []string{"agency_a1"})},
+		{"namespace strip", WithIDNamespaceStrip(-1, []string{"KCM:"})},
+		{"reader options", WithInputReaderOptions(2, gtfs.ReaderOptions{})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: an option set for an input that two inputs lack
+			m := New(tt.opt)
+
+			// When/Then: merging feeds or files fails with ErrNoSuchInput
+			if _, err := m.MergeFeeds(readFixtures(t, "simple_a", "simple_b")); !errors.Is(err, ErrNoSuchInput) {
+				t.Errorf("Expected ErrNoSuchInput from MergeFeeds, got %v", err)
+			}
+			output := filepath.Join(t.TempDir(), "merged.zip")
+			if err := m.MergeFiles([]string{"../testdata/simple_a", "../testdata/simple_b"}, output); !errors.Is(err, ErrNoSuchInput) {
+				t.Errorf("Expected ErrNoSuchInput from MergeFiles, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMergeRefusesPartialFeed(t *testing.T) {
+	partial, err := gtfs.ReadMetadataOnly("../testdata/simple_b")
+	if err != nil {
+		t.Fatalf("ReadMetadataOnly failed: %v", err)
+	}
+	full, err := gtfs.ReadFromPath("../testdata/simple_a")
+	if err != nil {
+		t.Fatalf("ReadFromPath failed: %v", err)
+	}
+
+	_, err = New().MergeFeeds([]*gtfs.Feed{full, partial})
+	if !errors.Is(err, ErrPartialFeed) {
+		t.Errorf("expected ErrPartialFeed, got %v", err)
+	}
+}
+
+func TestMergeFilesValidateInputs(t *testing.T) {
+	// Given: an input whose trip references a route it lacks
+	tmpDir := t.TempDir()
+	broken, err := gtfs.ReadFromPath("../testdata/simple_a")
+	if err != nil {
+		t.Fatalf("failed to read simple_a: %v", err)
+	}
+	for _, trip := range broken.Trips {
+		trip.RouteID = "missing"
+		break
+	}
+	brokenPath := filepath.Join(tmpDir, "broken.zip")
+	if err := gtfs.WriteToPath(broken, brokenPath); err != nil {
+		t.Fatalf("failed to write broken.zip: %v", err)
+	}
+	inputs := []string{brokenPath, "../testdata/simple_b"}
+	output := filepath.Join(tmpDir, "merged.zip")
+
+	// When: merged with input validation
+	err = New(WithValidateInputs(true)).MergeFiles(inputs, output)
+
+	// Then: the validation error is reachable through the error chain
+	if !errors.Is(err, ErrInvalidFeed) || !errors.Is(err, gtfs.ErrBrokenReference) {
+		t.Fatalf("Expected ErrInvalidFeed wrapping ErrBrokenReference, got %v", err)
+	}
+	var ve *gtfs.ValidationError
+	if !errors.As(err, &ve) || ve.Code != gtfs.ValidationBrokenReference || ve.Field != "route_id" {
+		t.Errorf("Expected a broken route_id reference, got %+v", ve)
+	}
+
+	// And: without validation the merge goes ahead
+	if err := New().MergeFiles(inputs, output); err != nil {
+		t.Errorf("Expected MergeFiles to succeed without validation, got %v", err)
+	}
+}
+
 // Tests for 5.3 - ID Prefixing
 
 func TestMergeAppliesPrefixToSecondFeed(t *testing.T) {
@@ -636,6 +883,70 @@ func TestMergeWithOverlapTestData(t *testing.T) {
 	}
 }
 
+func TestMergeDetectionNonePrefixesOnlyCollisions(t *testing.T) {
+	// Given: simple_a and overlap, which share some but not all IDs
+	feedA, err := gtfs.ReadFromPath("../testdata/simple_a")
+	if err != nil {
+		t.Fatalf("failed to read simple_a: %v", err)
+	}
+	feedOverlap, err := gtfs.ReadFromPath("../testdata/overlap")
+	if err != nil {
+		t.Fatalf("failed to read overlap: %v", err)
+	}
+
+	// When: merged without duplicate detection
+	merged, err := New(WithDefaultDetection(strategy.DetectionNone)).MergeFeeds([]*gtfs.Feed{feedA, feedOverlap})
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+
+	// Then: overlap, merged first, keeps its IDs, and simple_a's are
+	// prefixed only where overlap already has them
+	expectedStops := []gtfs.StopID{"a-stop_a1", "a-stop_a2", "stop_a1", "stop_a2", "stop_a3", "stop_a4", "stop_a5"}
+	if got := slices.Sorted(maps.Keys(merged.Stops)); !slices.Equal(got, expectedStops) {
+		t.Errorf("Expected stops %v, got %v", expectedStops, got)
+	}
+	expectedAgencies := []gtfs.AgencyID{"a-agency_a1", "agency_a1", "agency_a2"}
+	if got := slices.Sorted(maps.Keys(merged.Agencies)); !slices.Equal(got, expectedAgencies) {
+		t.Errorf("Expected agencies %v, got %v", expectedAgencies, got)
+	}
+	expectedTrips := []gtfs.TripID{"a-trip_a1", "trip_a1", "trip_a2", "trip_a3", "trip_a4"}
+	if got := slices.Sorted(maps.Keys(merged.Trips)); !slices.Equal(got, expectedTrips) {
+		t.Errorf("Expected trips %v, got %v", expectedTrips, got)
+	}
+	expectedServices := []gtfs.ServiceID{"a-service_a1", "service_a1"}
+	if got := slices.Sorted(maps.Keys(merged.Calendars)); !slices.Equal(got, expectedServices) {
+		t.Errorf("Expected services %v, got %v", expectedServices, got)
+	}
+}
+
+func TestMergeDetectionNonePrefixedIDTaken(t *testing.T) {
+	// Given: feed A with stop s1, and a last feed with s1 and its own a-s1
+	feedA := gtfstest.NewFeedBuilder().Stop("s1", 47.6, -122.3).MustBuild()
+	feedB := gtfstest.NewFeedBuilder().
+		Stop("s1", 47.6, -122.3).
+		Stop("a-s1", 47.7, -122.4).
+		MustBuild()
+
+	// When: merged without duplicate detection
+	merged, err := New(WithDefaultDetection(strategy.DetectionNone)).MergeFeeds([]*gtfs.Feed{feedA, feedB})
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+
+	// Then: A's s1 is prefixed again rather than overwriting a-s1
+	expected := []gtfs.StopID{"a-a-s1", "a-s1", "s1"}
+	if got := slices.Sorted(maps.Keys(merged.Stops)); !slices.Equal(got, expected) {
+		t.Errorf("Expected stops %v, got %v", expected, got)
+	}
+	if got := slices.Sorted(slices.Values(merged.StopOrder)); !slices.Equal(got, expected) {
+		t.Errorf("Expected stop order of %v, got %v", expected, merged.StopOrder)
+	}
+	if lat := merged.Stops["a-s1"].Lat; lat != 47.7 {
+		t.Errorf("Expected a-s1 to keep its latitude 47.7, got %v", lat)
+	}
+}
+
 // Tests for Milestone 8 - Identity-Based Duplicate Detection
 
 func TestMergeWithIdentityDetection(t *testing.T) {
@@ -803,6 +1114,66 @@ func TestMergeGetStrategyForFile(t *testing.T) {
 	}
 }
 
+// recordingStrategy is a strategy that only records that it was called
+type recordingStrategy struct {
+	strategy.BaseStrategy
+	calls *[]string
+}
+
+func (r *recordingStrategy) Merge(*strategy.MergeContext) error {
+	*r.calls = append(*r.calls, r.Name())
+	return nil
+}
+
+func TestMergeDispatchesEveryFileToItsStrategy(t *testing.T) {
+	// Given: every strategy replaced by one that records its calls
+	merger := New()
+	var calls []string
+	setters := []struct {
+		filename string
+		set      func(strategy.EntityMergeStrategy)
+	}{
+		{"agency.txt", merger.SetAgencyStrategy},
+		{"areas.txt", merger.SetAreaStrategy},
+		{"stops.txt", merger.SetStopStrategy},
+		{"calendar.txt", merger.SetCalendarStrategy},
+		{"calendar_dates.txt", merger.SetCalendarDateStrategy},
+		{"routes.txt", merger.SetRouteStrategy},
+		{"shapes.txt", merger.SetShapeStrategy},
+		{"trips.txt", merger.SetTripStrategy},
+		{"stop_times.txt", merger.SetStopTimeStrategy},
+		{"frequencies.txt", merger.SetFrequencyStrategy},
+		{"transfers.txt", merger.SetTransferStrategy},
+		{"pathways.txt", merger.SetPathwayStrategy},
+		{"fare_attributes.txt", merger.SetFareAttributeStrategy},
+		{"fare_rules.txt", merger.SetFareRuleStrategy},
+		{"feed_info.txt", merger.SetFeedInfoStrategy},
+	}
+	var order []string
+	for _, st := range setters {
+		st.set(&recordingStrategy{BaseStrategy: strategy.NewBaseStrategy(st.filename), calls: &calls})
+		order = append(order, st.filename)
+	}
+
+	// When: two feeds are merged
+	if _, err := merger.MergeFeeds([]*gtfs.Feed{gtfs.NewFeed(), gtfs.NewFeed()}); err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+
+	// Then: each feed is merged by exactly those strategies, in dependency order
+	want := strings.Join(append(order, order...), ",")
+	if got := strings.Join(calls, ","); got != want {
+		t.Errorf("Expected strategy calls %s, got %s", want, got)
+	}
+
+	// And: GetStrategyForFile returns the replaced strategies
+	for _, st := range setters {
+		if got := merger.GetStrategyForFile(st.filename); got.Name() != st.filename {
+			t.Errorf("GetStrategyForFile(%q) returned %q", st.filename, got.Name())
+		}
+	}
+}
+
 func TestMergeWithOverlapAndIdentityDetection(t *testing.T) {
 	// Test with actual overlap test data and identity detection
 	feedA, err := gtfs.ReadFromPath("../testdata/simple_a")
@@ -854,3 +1225,713 @@ func TestMergeWithOverlapAndIdentityDetection(t *testing.T) {
 		}
 	}
 }
+
+func TestMergeFeedsContextCanceled(t *testing.T) {
+	// Given: two feeds and a canceled context
+	feedA := gtfs.NewFeed()
+	feedA.AddAgency(&gtfs.Agency{ID: "a1", Name: "Agency A", Timezone: "UTC"})
+	feedB := gtfs.NewFeed()
+	feedB.AddAgency(&gtfs.Agency{ID: "b1", Name: "Agency B", Timezone: "UTC"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// When: merged
+	_, err := New().MergeFeedsContext(ctx, []*gtfs.Feed{feedA, feedB})
+
+	// Then: the cancellation is returned with the interrupted stage
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "merging agencies") {
+		t.Errorf("expected error to name the agency stage, got %v", err)
+	}
+}
+
+func TestMergeFilesContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	output := filepath.Join(t.TempDir(), "merged.zip")
+	err := New().MergeFilesContext(ctx, []string{"../testdata/simple_a", "../testdata/simple_b"}, output)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "reading ../testdata/simple_a") {
+		t.Errorf("expected error to name the input being read, got %v", err)
+	}
+}
+
+func TestMergeFeedsContextDeadline(t *testing.T) {
+	// A live context behaves exactly like MergeFeeds
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	feedA, err := gtfs.ReadFromPath("../testdata/simple_a")
+	if err != nil {
+		t.Fatalf("failed to read simple_a: %v", err)
+	}
+	merged, err := New().MergeFeedsContext(ctx, []*gtfs.Feed{feedA})
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	if len(merged.Stops) != len(feedA.Stops) {
+		t.Errorf("expected %d stops, got %d", len(feedA.Stops), len(merged.Stops))
+	}
+}
+
+func TestMergeFeedsWithoutAgencyIDs(t *testing.T) {
+	// Given: two single-agency feeds that omit agency_id everywhere
+	inputs := []string{"../testdata/no_agency_id_a", "../testdata/no_agency_id_b"}
+	output := filepath.Join(t.TempDir(), "merged.zip")
+
+	// When: merged and read back
+	if err := New().MergeFiles(inputs, output); err != nil {
+		t.Fatalf("MergeFiles failed: %v", err)
+	}
+	merged, err := gtfs.ReadFromPath(output)
+	if err != nil {
+		t.Fatalf("failed to read merged output: %v", err)
+	}
+
+	// Then: each agency has its own synthetic ID
+	if len(merged.Agencies) != 2 {
+		t.Fatalf("expected 2 agencies, got %d", len(merged.Agencies))
+	}
+	for _, id := range []gtfs.AgencyID{"agency", "a-agency"} {
+		if merged.Agencies[id] == nil {
+			t.Errorf("expected agency %q, got %v", id, merged.AgencyOrder)
+		}
+	}
+
+	// And: routes and fares point at their own feed's agency
+	if r := merged.Routes["20"]; r == nil || r.AgencyID != "agency" {
+		t.Errorf("expected route 20 to reference agency, got %+v", r)
+	}
+	if r := merged.Routes["a-10"]; r == nil || r.AgencyID != "a-agency" {
+		t.Errorf("expected route a-10 to reference a-agency, got %+v", r)
+	}
+	if fa := merged.FareAttributes["a-regular"]; fa == nil || fa.AgencyID != "a-agency" {
+		t.Errorf("expected fare a-regular to reference a-agency, got %+v", fa)
+	}
+
+	// And: the merged feed validates
+	if errs := merged.Validate(); len(errs) > 0 {
+		t.Errorf("expected merged feed to validate, got %v", errs)
+	}
+}
+
+func TestMergeSingleAgencyFeedWithMultiAgencyFeed(t *testing.T) {
+	for _, inputs := range [][]string{
+		{"../testdata/minimal_single_agency", "../testdata/simple_a"},
+		{"../testdata/simple_a", "../testdata/minimal_single_agency"},
+	} {
+		t.Run(filepath.Base(inputs[0]), func(t *testing.T) {
+			// Given: a feed with one agency whose routes and fares omit
+			// agency_id, and a feed with two agencies
+			var feeds []*gtfs.Feed
+			for _, path := range inputs {
+				feed, err := gtfs.ReadFromPath(path)
+				if err != nil {
+					t.Fatalf("failed to read %s: %v", path, err)
+				}
+				feeds = append(feeds, feed)
+			}
+
+			// When: merged
+			merged, err := New().MergeFeeds(feeds)
+			if err != nil {
+				t.Fatalf("MergeFeeds failed: %v", err)
+			}
+
+			// Then: the single-agency feed's route and fare reference its
+			// agency
+			if r := merged.Routes["route1"]; r == nil || r.AgencyID != "agency1" {
+				t.Errorf("expected route1 to reference agency1, got %+v", r)
+			}
+			if fa := merged.FareAttributes["base"]; fa == nil || fa.AgencyID != "agency1" {
+				t.Errorf("expected fare base to reference agency1, got %+v", fa)
+			}
+
+			// And: no agency_id fails validation (simple_a's stop_times
+			// reference stations, which is reported regardless)
+			for _, err := range merged.Validate() {
+				var verr *gtfs.ValidationError
+				if errors.As(err, &verr) && verr.Field == "agency_id" {
+					t.Errorf("expected agency_ids to validate, got %v", err)
+				}
+			}
+
+			// And: no row of the written routes.txt has an empty agency_id
+			var buf bytes.Buffer
+			if err := gtfs.WriteFile(merged, "routes.txt", &buf); err != nil {
+				t.Fatalf("failed to write routes.txt: %v", err)
+			}
+			rows, err := csv.NewReader(&buf).ReadAll()
+			if err != nil {
+				t.Fatalf("failed to parse routes.txt: %v", err)
+			}
+			col := slices.Index(rows[0], "agency_id")
+			if col < 0 {
+				t.Fatalf("expected an agency_id column, got %v", rows[0])
+			}
+			for _, row := range rows[1:] {
+				if row[col] == "" {
+					t.Errorf("expected an agency_id for route %s", row[slices.Index(rows[0], "route_id")])
+				}
+			}
+		})
+	}
+}
+
+func TestMergeFareAgencyFollowsDedupedAgency(t *testing.T) {
+	// agencyFeed returns a feed with a bus agency and a ferry agency under
+	// ferryID, each owning a fare
+	agencyFeed := func(ferryID gtfs.AgencyID, fareIDs [2]gtfs.FareID, price float64) *gtfs.Feed {
+		feed := gtfs.NewFeed()
+		feed.AddAgency(&gtfs.Agency{ID: "metro", Name: "Metro Transit", URL: "http://metro.example.com", Timezone: "America/Los_Angeles"})
+		feed.AddAgency(&gtfs.Agency{ID: ferryID, Name: "City Ferry", URL: "http://ferry.example.com", Timezone: "America/Los_Angeles"})
+		feed.AddFareAttribute(&gtfs.FareAttribute{FareID: fareIDs[0], Price: price, CurrencyType: "USD", AgencyID: "metro"})
+		feed.AddFareAttribute(&gtfs.FareAttribute{FareID: fareIDs[1], Price: price * 2, CurrencyType: "USD", AgencyID: ferryID})
+		return feed
+	}
+
+	tests := []struct {
+		name      string
+		detection strategy.DuplicateDetection
+		wantFerry gtfs.AgencyID
+	}{
+		{"identity keeps the differently named ferry", strategy.DetectionIdentity, "ferry"},
+		{"fuzzy merges the ferry by name", strategy.DetectionFuzzy, "ferry_co"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: two feeds sharing the metro agency, and a ferry agency
+			// with a different ID in each, all owning fares
+			feedA := agencyFeed("ferry", [2]gtfs.FareID{"a_bus", "a_boat"}, 1.50)
+			feedB := agencyFeed("ferry_co", [2]gtfs.FareID{"bus", "boat"}, 2.75)
+
+			// When: merged
+			merged, err := New(WithDefaultDetection(tt.detection)).MergeFeeds([]*gtfs.Feed{feedA, feedB})
+			if err != nil {
+				t.Fatalf("merge failed: %v", err)
+			}
+
+			// Then: the first feed's fares reference the agencies their own
+			// agencies were merged into
+			if fa := merged.FareAttributes["a_bus"]; fa == nil || fa.AgencyID != "metro" {
+				t.Errorf("expected fare a_bus to reference metro, got %+v", fa)
+			}
+			if fa := merged.FareAttributes["a_boat"]; fa == nil || fa.AgencyID != tt.wantFerry {
+				t.Errorf("expected fare a_boat to reference %s, got %+v", tt.wantFerry, fa)
+			}
+
+			// And: no fare references a missing agency
+			for _, fa := range merged.FareAttributes {
+				if merged.Agencies[fa.AgencyID] == nil {
+					t.Errorf("expected fare %s's agency %q in the merged feed", fa.FareID, fa.AgencyID)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeFuzzyTripsWithDifferingBoarding(t *testing.T) {
+	// readFeeds returns two copies of simple_a, the first marking trip_a1's
+	// last stop drop-off only
+	readFeeds := func() []*gtfs.Feed {
+		var feeds []*gtfs.Feed
+		for range 2 {
+			feed, err := gtfs.ReadFromPath("../testdata/simple_a")
+			if err != nil {
+				t.Fatalf("failed to read simple_a: %v", err)
+			}
+			feeds = append(feeds, feed)
+		}
+		for _, st := range feeds[0].StopTimes {
+			if st.TripID == "trip_a1" && st.StopSequence == 3 {
+				st.PickupType = 1
+			}
+		}
+		return feeds
+	}
+
+	// When: merged with fuzzy detection
+	m := New(WithDefaultDetection(strategy.DetectionFuzzy))
+	merged, err := m.MergeFeeds(readFeeds())
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: the trips merge, keeping one set of stop_times, the last
+	// input's
+	feedA := readFeeds()[1]
+	if len(merged.Trips) != len(feedA.Trips) || len(merged.StopTimes) != len(feedA.StopTimes) {
+		t.Errorf("Expected %d trips and %d stop_times, got %d and %d",
+			len(feedA.Trips), len(feedA.StopTimes), len(merged.Trips), len(merged.StopTimes))
+	}
+	for _, st := range merged.StopTimes {
+		if st.PickupType != 0 {
+			t.Errorf("Expected the last input's pickup_type on %s stop %d, got %d", st.TripID, st.StopSequence, st.PickupType)
+		}
+	}
+
+	// And: the dropped pickup_type is reported and warned about
+	diffs := m.Report().BoardingDifferences
+	if len(diffs) != 1 || diffs[0].SourceID != "trip_a1" || diffs[0].Field != "pickup_type" || diffs[0].SourceValue != "1" {
+		t.Fatalf("Expected trip_a1's pickup_type difference, got %+v", diffs)
+	}
+	if !slices.Contains(m.Report().Warnings, diffs[0].String()) {
+		t.Errorf("Expected the difference as a warning, got %v", m.Report().Warnings)
+	}
+
+	// When: merged again with WithStrictTripBoarding
+	m = New(WithDefaultDetection(strategy.DetectionFuzzy), WithStrictTripBoarding(true))
+	merged, err = m.MergeFeeds(readFeeds())
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: trip_a1 is kept twice and nothing is reported
+	if len(merged.Trips) != len(feedA.Trips)+1 || merged.Trips["a-trip_a1"] == nil {
+		t.Errorf("Expected trip_a1 kept apart as a-trip_a1, got %v", merged.TripOrder)
+	}
+	if len(m.Report().BoardingDifferences) != 0 {
+		t.Errorf("Expected no differences, got %+v", m.Report().BoardingDifferences)
+	}
+}
+
+func TestMergeFuzzyTripsFillStopTimes(t *testing.T) {
+	// Given: two copies of simple_a, only the first measuring
+	// shape_dist_traveled and marking timepoints
+	var feeds []*gtfs.Feed
+	for range 2 {
+		feed, err := gtfs.ReadFromPath("../testdata/simple_a")
+		if err != nil {
+			t.Fatalf("failed to read simple_a: %v", err)
+		}
+		feeds = append(feeds, feed)
+	}
+	feeds[0].AddColumn("stop_times.txt", "shape_dist_traveled")
+	feeds[0].AddColumn("stop_times.txt", "timepoint")
+	for _, st := range feeds[0].StopTimes {
+		dist, timepoint := float64(st.StopSequence)*1.5, 1
+		st.ShapeDistTraveled, st.Timepoint = &dist, &timepoint
+	}
+
+	// When: merged with fuzzy detection, which keeps the last input's
+	// stop_times, and written
+	merged, err := New(WithDefaultDetection(strategy.DetectionFuzzy)).MergeFeeds(feeds)
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+	output := filepath.Join(t.TempDir(), "merged.zip")
+	if err := gtfs.WriteToPath(merged, output); err != nil {
+		t.Fatalf("WriteToPath failed: %v", err)
+	}
+
+	// Then: the written stop_times take the first input's values
+	written, err := gtfs.ReadFromPath(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if len(written.StopTimes) != len(feeds[1].StopTimes) {
+		t.Fatalf("Expected %d stop_times, got %d", len(feeds[1].StopTimes), len(written.StopTimes))
+	}
+	for _, st := range written.StopTimes {
+		want := float64(st.StopSequence) * 1.5
+		if st.ShapeDistTraveled == nil || *st.ShapeDistTraveled != want {
+			t.Errorf("Expected shape_dist_traveled %v on %s stop %d, got %v", want, st.TripID, st.StopSequence, st.ShapeDistTraveled)
+		}
+		if st.Timepoint == nil || *st.Timepoint != 1 {
+			t.Errorf("Expected timepoint 1 on %s stop %d, got %v", st.TripID, st.StopSequence, st.Timepoint)
+		}
+	}
+}
+
+func TestMergeFuzzyIsDeterministic(t *testing.T) {
+	// Given: the fuzzy_similar fixtures, which match simple_a by properties
+	// rather than IDs
+	mergeOnce := func() (*gtfs.Feed, *Report) {
+		feedA, err := gtfs.ReadFromPath("../testdata/simple_a")
+		if err != nil {
+			t.Fatalf("Failed to read simple_a: %v", err)
+		}
+		feedB, err := gtfs.ReadFromPath("../testdata/fuzzy_similar")
+		if err != nil {
+			t.Fatalf("Failed to read fuzzy_similar: %v", err)
+		}
+		m := New(WithDefaultDetection(strategy.DetectionFuzzy))
+		merged, err := m.MergeFeeds([]*gtfs.Feed{feedA, feedB})
+		if err != nil {
+			t.Fatalf("MergeFeeds failed: %v", err)
+		}
+		return merged, m.Report()
+	}
+
+	// When: merged 20 times
+	first, firstReport := mergeOnce()
+	for run := 1; run < 20; run++ {
+		merged, report := mergeOnce()
+
+		// Then: every run keeps the same entities and maps the same
+		// inputs onto them
+		if !reflect.DeepEqual(report.Sources, firstReport.Sources) {
+			t.Fatalf("Run %d: expected sources %v, got %v", run, firstReport.Sources, report.Sources)
+		}
+		if !slices.Equal(merged.StopOrder, first.StopOrder) ||
+			!slices.Equal(merged.RouteOrder, first.RouteOrder) ||
+			!slices.Equal(merged.TripOrder, first.TripOrder) ||
+			!slices.Equal(merged.CalendarOrder, first.CalendarOrder) {
+			t.Fatalf("Run %d: expected the same stops, routes, trips and calendars as the first run", run)
+		}
+	}
+}
+
+func TestMergeOrdersStopTimesWithinTrips(t *testing.T) {
+	// Given: an input with shuffled stop_times and frequencies
+	inputs := []string{"../testdata/shuffled_stop_times", "../testdata/simple_b"}
+	output := filepath.Join(t.TempDir(), "merged.zip")
+
+	// When: merged and written
+	if err := New().MergeFiles(inputs, output); err != nil {
+		t.Fatalf("MergeFiles failed: %v", err)
+	}
+	merged, err := gtfs.ReadFromPath(output)
+	if err != nil {
+		t.Fatalf("Failed to read merged feed: %v", err)
+	}
+
+	// Then: each trip's stop_times are contiguous and ordered by stop_sequence
+	done := make(map[gtfs.TripID]bool)
+	for i, st := range merged.StopTimes {
+		if i > 0 && merged.StopTimes[i-1].TripID == st.TripID {
+			if prev := merged.StopTimes[i-1]; prev.StopSequence >= st.StopSequence {
+				t.Errorf("Trip %s: stop_sequence %d follows %d", st.TripID, st.StopSequence, prev.StopSequence)
+			}
+			continue
+		}
+		if done[st.TripID] {
+			t.Errorf("Trip %s: stop_times are not contiguous", st.TripID)
+		}
+		done[st.TripID] = true
+	}
+
+	// And: frequencies of a trip are ordered by start_time
+	var starts []string
+	for _, f := range merged.Frequencies {
+		if f.TripID == "loop_am" {
+			starts = append(starts, f.StartTime)
+		}
+	}
+	if !slices.Equal(starts, []string{"6:00:00", "10:00:00"}) {
+		t.Errorf("Expected loop_am frequencies from 6:00 then 10:00, got %v", starts)
+	}
+}
+
+func TestMergeOptionalColumnsFromSomeInputs(t *testing.T) {
+	// Given: one input whose transfers and fares have min_transfer_time 0
+	// and a youth_price, and one input without those columns
+	newFeed := func(withColumns bool) *gtfs.Feed {
+		feed := gtfs.NewFeed()
+		feed.AddStop(&gtfs.Stop{ID: "s1", Name: "Stop 1"})
+		feed.AddStop(&gtfs.Stop{ID: "s2", Name: "Stop 2"})
+		transfer := &gtfs.Transfer{FromStopID: "s1", ToStopID: "s2", TransferType: 2}
+		fare := &gtfs.FareAttribute{FareID: "adult", Price: 2.5, CurrencyType: "USD"}
+		transferCols := []string{"from_stop_id", "to_stop_id", "transfer_type"}
+		fareCols := []string{"fare_id", "price", "currency_type", "payment_method", "transfers"}
+		if withColumns {
+			transfer.MinTransferTime = intPtr(0)
+			fare.YouthPrice = new(float64)
+			transferCols = append(transferCols, "min_transfer_time")
+			fareCols = append(fareCols, "youth_price")
+		}
+		feed.Transfers = append(feed.Transfers, transfer)
+		feed.AddFareAttribute(fare)
+		feed.AddColumnSet("transfers.txt", transferCols)
+		feed.AddColumnSet("fare_attributes.txt", fareCols)
+		return feed
+	}
+
+	// When: merged and its transfers and fares written
+	merged, err := New().MergeFeeds([]*gtfs.Feed{newFeed(true), newFeed(false)})
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+	var transfers, fares strings.Builder
+	if err := gtfs.WriteFile(merged, "transfers.txt", &transfers); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if err := gtfs.WriteFile(merged, "fare_attributes.txt", &fares); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	// Then: the explicit zeros are written, and rows from the input without
+	// the columns are empty rather than 0
+	for _, want := range []string{"min_transfer_time", "a-s1,a-s2,2,0\n", "\ns1,s2,2,\n"} {
+		if !strings.Contains(transfers.String(), want) {
+			t.Errorf("Expected transfers.txt to contain %q, got:\n%s", want, transfers.String())
+		}
+	}
+	for _, want := range []string{"youth_price", "a-adult,2.500000,USD,0,,0.000000\n", "\nadult,2.500000,USD,0,,\n"} {
+		if !strings.Contains(fares.String(), want) {
+			t.Errorf("Expected fare_attributes.txt to contain %q, got:\n%s", want, fares.String())
+		}
+	}
+}
+
+func TestWithFuzzyLimits(t *testing.T) {
+	// Given: simple_a merged with itself under fuzzy detection, with a
+	// budget of one comparison per file
+	var feeds []*gtfs.Feed
+	for range 2 {
+		feed, err := gtfs.ReadFromPath("../testdata/simple_a")
+		if err != nil {
+			t.Fatalf("failed to read simple_a: %v", err)
+		}
+		feeds = append(feeds, feed)
+	}
+	m := New(WithDefaultDetection(strategy.DetectionFuzzy), WithFuzzyLimits(strategy.FuzzyLimits{MaxComparisons: 1}))
+
+	// When: merged
+	merged, err := m.MergeFeeds(feeds)
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: the entities past the budget fall back to identity detection,
+	// which still finds the duplicates
+	if len(merged.Stops) != len(feeds[1].Stops) || len(merged.Routes) != len(feeds[1].Routes) || len(merged.Trips) != len(feeds[1].Trips) {
+		t.Errorf("Expected %d stops, %d routes and %d trips, got %d, %d and %d",
+			len(feeds[1].Stops), len(feeds[1].Routes), len(feeds[1].Trips), len(merged.Stops), len(merged.Routes), len(merged.Trips))
+	}
+
+	// And: the limit is reported for each entity of the feed matched
+	// against the other, and warned about
+	hits := m.Report().FuzzyLimitHits
+	var entities []string
+	for _, h := range hits {
+		if h.Limit != strategy.FuzzyLimitComparisons || h.Max != 1 || h.Entities == 0 {
+			t.Errorf("Unexpected hit %+v", h)
+		}
+		if !slices.Contains(m.Report().Warnings, h.String()) {
+			t.Errorf("Expected %q as a warning, got %v", h, m.Report().Warnings)
+		}
+		if h.SourceFeed == "a" {
+			entities = append(entities, h.Entity)
+		}
+	}
+	if want := []string{"stop", "route", "trip"}; !reflect.DeepEqual(entities, want) {
+		t.Errorf("Expected hits for %v, got %+v", want, hits)
+	}
+
+	// When: merged again with the default limits
+	m = New(WithDefaultDetection(strategy.DetectionFuzzy))
+	if _, err := m.MergeFeeds(feeds); err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: no limit is reached
+	if len(m.Report().FuzzyLimitHits) != 0 {
+		t.Errorf("Expected no hits, got %+v", m.Report().FuzzyLimitHits)
+	}
+}
+
+func TestMergerConcurrentMerges(t *testing.T) {
+	// Given: one Merger with fuzzy detection, concurrent matching and metrics
+	metrics := NewMemoryMetrics()
+	m := New(WithDefaultDetection(strategy.DetectionFuzzy), WithMetrics(metrics))
+	if s, ok := m.GetStrategyForFile("stops.txt").(*strategy.StopMergeStrategy); ok {
+		s.SetConcurrent(true)
+	}
+	readFeeds := func() []*gtfs.Feed {
+		var feeds []*gtfs.Feed
+		for _, dir := range []string{"simple_a", "fuzzy_similar"} {
+			feed, err := gtfs.ReadFromPath(filepath.Join("..", "testdata", dir))
+			if err != nil {
+				t.Errorf("failed to read %s: %v", dir, err)
+				return nil
+			}
+			feeds = append(feeds, feed)
+		}
+		return feeds
+	}
+	want, err := m.MergeFeeds(readFeeds())
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// When: it runs several merges at once, each of its own feeds
+	const merges = 4
+	results := make([]*gtfs.Feed, merges)
+	errs := make([]error, merges)
+	done := make(chan struct{})
+	for i := range merges {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			if feeds := readFeeds(); feeds != nil {
+				results[i], errs[i] = m.MergeFeeds(feeds)
+				_ = m.Report()
+			}
+		}()
+	}
+	for range merges {
+		<-done
+	}
+
+	// Then: each merge matches the merge run alone (run with -race to check
+	// they share no state)
+	for i := range merges {
+		if errs[i] != nil {
+			t.Fatalf("merge %d failed: %v", i, errs[i])
+		}
+		if !reflect.DeepEqual(results[i].RowCounts(), want.RowCounts()) {
+			t.Errorf("merge %d: expected %v, got %v", i, want.RowCounts(), results[i].RowCounts())
+		}
+	}
+	if report := m.Report(); report == nil || !reflect.DeepEqual(report.Merged, want.RowCounts()) {
+		t.Errorf("Expected the report of a completed merge, got %+v", report)
+	}
+}
+
+func TestMergerConcurrentReports(t *testing.T) {
+	// Given: one Merger and two different pairs of inputs
+	m := New()
+	inputs := [][]string{{"simple_a", "simple_b"}, {"simple_a", "overlap"}}
+
+	// When: both pairs are merged at once
+	merged := make([]*gtfs.Feed, len(inputs))
+	reports := make([]*Report, len(inputs))
+	errs := make([]error, len(inputs))
+	done := make(chan struct{})
+	for i, dirs := range inputs {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			var feeds []*gtfs.Feed
+			for _, dir := range dirs {
+				feed, err := gtfs.ReadFromPath(filepath.Join("..", "testdata", dir))
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				feeds = append(feeds, feed)
+			}
+			merged[i], reports[i], errs[i] = m.MergeFeedsWithReport(context.Background(), feeds)
+		}()
+	}
+	for range inputs {
+		<-done
+	}
+
+	// Then: each merge's report describes that merge
+	for i := range inputs {
+		if errs[i] != nil {
+			t.Fatalf("merge %d failed: %v", i, errs[i])
+		}
+		if reports[i].Feed != merged[i] {
+			t.Errorf("merge %d: expected the report to hold its merged feed", i)
+		}
+		if !reflect.DeepEqual(reports[i].Merged, merged[i].RowCounts()) {
+			t.Errorf("merge %d: expected merged counts %v, got %v", i, merged[i].RowCounts(), reports[i].Merged)
+		}
+	}
+}
+
+func TestMergeDropsTransfersBetweenDeduplicatedStops(t *testing.T) {
+	// Given: a feed with a transfer between the two halves of a station,
+	// each of which fuzzy matches the other feed's single stop there
+	first := gtfs.NewFeed()
+	first.AddAgency(&gtfs.Agency{ID: "agency", Name: "Agency", URL: "http://example.com", Timezone: "UTC"})
+	first.AddStop(&gtfs.Stop{ID: "north", Name: "Central Station", Lat: 47.6, Lon: -122.3})
+	first.AddStop(&gtfs.Stop{ID: "south", Name: "Central Station", Lat: 47.6, Lon: -122.3})
+	first.Transfers = append(first.Transfers, &gtfs.Transfer{FromStopID: "north", ToStopID: "south", TransferType: 2, MinTransferTime: intPtr(180)})
+	second := gtfs.NewFeed()
+	second.AddAgency(&gtfs.Agency{ID: "agency", Name: "Agency", URL: "http://example.com", Timezone: "UTC"})
+	second.AddStop(&gtfs.Stop{ID: "central", Name: "Central Station", Lat: 47.6, Lon: -122.3})
+
+	// When: merged with fuzzy stop detection
+	merged, err := New(WithDetectionFor("stop", strategy.DetectionFuzzy)).MergeFeeds([]*gtfs.Feed{first, second})
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: both stops merge into central, and the transfer, which would
+	// now lead from central to itself, is dropped
+	if len(merged.Stops) != 1 || merged.Stops["central"] == nil {
+		t.Fatalf("Expected only central, got %v", merged.Stops)
+	}
+	if len(merged.Transfers) != 0 {
+		t.Errorf("Expected no transfers, got %+v", merged.Transfers[0])
+	}
+	if errs := merged.Validate(); len(errs) > 0 {
+		t.Errorf("Expected a valid merged feed, got %v", errs)
+	}
+}
+
+func TestMergeStationHierarchy(t *testing.T) {
+	// Both feeds hold a station with a platform, an entrance, a generic
+	// node and a boarding area; the first adds a boarding area named, and
+	// placed, exactly as the station
+	countTypes := func(feed *gtfs.Feed) map[int]int {
+		counts := make(map[int]int)
+		for _, stop := range feed.Stops {
+			counts[stop.LocationType]++
+		}
+		return counts
+	}
+
+	t.Run("prefixed", func(t *testing.T) {
+		// When: merged without duplicate detection
+		merged, err := New().MergeFeeds(readFixtures(t, "station_hierarchy_a", "station_hierarchy_b"))
+		if err != nil {
+			t.Fatalf("MergeFeeds failed: %v", err)
+		}
+
+		// Then: every stop is kept, each with its parent renamed alongside
+		// it, so the hierarchy stays valid
+		want := map[int]int{0: 4, 1: 2, 2: 2, 3: 2, 4: 3}
+		if got := countTypes(merged); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected location types %v, got %v", want, got)
+		}
+		for _, id := range []gtfs.StopID{"union_ba1", "a-union_ba1", "union_mezzanine", "a-union_mezzanine"} {
+			stop := merged.Stops[id]
+			if stop == nil {
+				t.Errorf("Expected stop %s", id)
+				continue
+			}
+			if parent := merged.Stops[stop.ParentStation]; parent == nil || strings.HasPrefix(string(id), "a-") != strings.HasPrefix(string(parent.ID), "a-") {
+				t.Errorf("Expected %s's parent from its own feed, got %q", id, stop.ParentStation)
+			}
+		}
+		if errs := merged.Validate(); len(errs) > 0 {
+			t.Errorf("Expected a valid merged feed, got %v", errs)
+		}
+	})
+
+	t.Run("fuzzy", func(t *testing.T) {
+		// When: merged with fuzzy stop detection
+		m := New(WithDetectionFor("stop", strategy.DetectionFuzzy))
+		merged, err := m.MergeFeeds(readFixtures(t, "station_hierarchy_a", "station_hierarchy_b"))
+		if err != nil {
+			t.Fatalf("MergeFeeds failed: %v", err)
+		}
+
+		// Then: each stop merges with the other feed's of its location
+		// type, and the boarding area named as the station stays apart
+		want := map[int]int{0: 2, 1: 1, 2: 1, 3: 1, 4: 2}
+		if got := countTypes(merged); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected location types %v, got %v", want, got)
+		}
+		if station := merged.Stops["union"]; station == nil || station.LocationType != 1 {
+			t.Errorf("Expected station union, got %+v", station)
+		}
+		if ba := merged.Stops["union_ba2"]; ba == nil || ba.ParentStation != "union_p1" {
+			t.Errorf("Expected boarding area union_ba2 on platform union_p1, got %+v", ba)
+		}
+		if errs := merged.Validate(); len(errs) > 0 {
+			t.Errorf("Expected a valid merged feed, got %v", errs)
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package merge
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// Stages reported to Metrics.ObserveStageDuration. Each entity strategy is
+// reported as StageMergePrefix followed by its file, e.g. "merge:stops.txt",
+// once per input feed.
+const (
+	// StageRead is reading and parsing one input (MergeFiles only)
+	StageRead = "read"
+
+	// StageValidate is validating one input (WithValidateInputs) or the
+	// merged feed's references (WithStrictOutput)
+	StageValidate = "validate"
+
+	// StageWrite is writing the merged feed (MergeFiles only)
+	StageWrite = "write"
+
+	// StageMergePrefix prefixes the file merged by one entity strategy
+	StageMergePrefix = "merge:"
+)
+
+// Operations reported to Metrics.AddEntityCount
+const (
+	// OpRead counts rows read from the inputs
+	OpRead = "read"
+
+	// OpAdded counts rows the inputs contributed to the merged feed
+	OpAdded = "added"
+
+	// OpDuplicate counts rows read but merged into existing entities as
+	// duplicates (see FeedReport.Duplicates)
+	OpDuplicate = "duplicate"
+
+	// OpDropped counts rows read but left out or removed for other reasons
+	// (see FeedReport.Dropped and Report.Dropped)
+	OpDropped = "dropped"
+
+	// OpMerged counts rows in the merged feed
+	OpMerged = "merged"
+)
+
+// Metrics receives stage timings and row counters from a merge, so they can
+// be exported to any metrics system; see WithMetrics. Counters are reported
+// once a merge succeeds, from the same numbers as its Report.
+type Metrics interface {
+	// ObserveStageDuration records how long one run of stage took
+	ObserveStageDuration(stage string, d time.Duration)
+
+	// AddEntityCount adds n to the counter for op on file
+	AddEntityCount(file, op string, n int)
+}
+
+// MemoryMetrics is a Metrics that keeps totals in memory. It is safe for
+// concurrent use.
+type MemoryMetrics struct {
+	mu     sync.Mutex
+	stages []string // in the order first observed
+	timing map[string]StageTiming
+	counts map[[2]string]int // keyed by file and op
+}
+
+// StageTiming totals the observations of one stage
+type StageTiming struct {
+	// Count is the number of times the stage ran
+	Count int
+
+	// Total is the time spent in the stage across all runs
+	Total time.Duration
+}
+
+// NewMemoryMetrics creates an empty MemoryMetrics
+func NewMemoryMetrics() *MemoryMetrics {
+	return &MemoryMetrics{
+		timing: make(map[string]StageTiming),
+		counts: make(map[[2]string]int),
+	}
+}
+
+// ObserveStageDuration adds d to the stage's total
+func (mm *MemoryMetrics) ObserveStageDuration(stage string, d time.Duration) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	t, seen := mm.timing[stage]
+	if !seen {
+		mm.stages = append(mm.stages, stage)
+	}
+	t.Count++
+	t.Total += d
+	mm.timing[stage] = t
+}
+
+// AddEntityCount adds n to the counter for op on file
+func (mm *MemoryMetrics) AddEntityCount(file, op string, n int) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.counts[[2]string{file, op}] += n
+}
+
+// Stages returns the stages observed, in the order first observed
+func (mm *MemoryMetrics) Stages() []string {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	return append([]string(nil), mm.stages...)
+}
+
+// Stage returns the totals observed for stage
+func (mm *MemoryMetrics) Stage(stage string) StageTiming {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	return mm.timing[stage]
+}
+
+// EntityCount returns the counter for op on file
+func (mm *MemoryMetrics) EntityCount(file, op string) int {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	return mm.counts[[2]string{file, op}]
+}
+
+// observeStage reports the time since start for stage, if metrics are set
+func (m *Merger) observeStage(stage string, start time.Time) {
+	if m.metrics != nil {
+		m.metrics.ObserveStageDuration(stage, time.Since(start))
+	}
+}
+
+// reportCounts reports the row counters of a successful merge
+func reportCounts(metrics Metrics, report *Report) {
+	for _, filename := range gtfs.FileNames() {
+		for i := range report.Feeds {
+			fr := &report.Feeds[i]
+			addCount(metrics, filename, OpRead, fr.Read[filename])
+			addCount(metrics, filename, OpAdded, fr.Added[filename])
+			addCount(metrics, filename, OpDuplicate, fr.Duplicates(filename))
+			addCount(metrics, filename, OpDropped, fr.Dropped[filename])
+		}
+		addCount(metrics, filename, OpDropped, report.Dropped[filename])
+		addCount(metrics, filename, OpMerged, report.Merged[filename])
+	}
+}
+
+// addCount reports n for op on filename, skipping zeros
+func addCount(metrics Metrics, filename, op string, n int) {
+	if n != 0 {
+		metrics.AddEntityCount(filename, op, n)
+	}
+}
@@ -0,0 +1,83 @@
+package merge
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+func TestMemoryMetrics(t *testing.T) {
+	mm := NewMemoryMetrics()
+	mm.ObserveStageDuration("write", time.Second)
+	mm.ObserveStageDuration("read", time.Second)
+	mm.ObserveStageDuration("write", 2*time.Second)
+	mm.AddEntityCount("stops.txt", OpRead, 3)
+	mm.AddEntityCount("stops.txt", OpRead, 4)
+
+	if got := mm.Stages(); !slices.Equal(got, []string{"write", "read"}) {
+		t.Errorf("Expected stages in first-observed order, got %v", got)
+	}
+	if got := mm.Stage("write"); got != (StageTiming{Count: 2, Total: 3 * time.Second}) {
+		t.Errorf("Expected 2 writes totaling 3s, got %+v", got)
+	}
+	if got := mm.EntityCount("stops.txt", OpRead); got != 7 {
+		t.Errorf("Expected 7 stops read, got %d", got)
+	}
+	if got := mm.EntityCount("stops.txt", OpMerged); got != 0 {
+		t.Errorf("Expected no merged count, got %d", got)
+	}
+}
+
+func TestWithMetrics(t *testing.T) {
+	// Given: a merger reporting to in-memory metrics
+	metrics := NewMemoryMetrics()
+	merger := New(WithDefaultDetection(strategy.DetectionIdentity), WithMetrics(metrics))
+
+	// When: two feeds are merged
+	output := filepath.Join(t.TempDir(), "merged.zip")
+	if err := merger.MergeFiles([]string{"../testdata/simple_a", "../testdata/simple_b"}, output); err != nil {
+		t.Fatalf("MergeFiles failed: %v", err)
+	}
+
+	// Then: reading and each strategy are timed once per input, and
+	// writing once
+	for stage, want := range map[string]int{
+		StageRead:                          2,
+		StageWrite:                         1,
+		StageMergePrefix + "stops.txt":     2,
+		StageMergePrefix + "feed_info.txt": 2,
+	} {
+		if got := metrics.Stage(stage).Count; got != want {
+			t.Errorf("Expected %d observations of %s, got %d", want, stage, got)
+		}
+	}
+	if metrics.Stage(StageValidate).Count != 0 {
+		t.Errorf("Expected no validation without WithValidateInputs or WithStrictOutput")
+	}
+
+	// And: the counters match the report
+	report := merger.Report()
+	for _, filename := range []string{"agency.txt", "stops.txt", "trips.txt"} {
+		var read, added, duplicates int
+		for i := range report.Feeds {
+			read += report.Feeds[i].Read[filename]
+			added += report.Feeds[i].Added[filename]
+			duplicates += report.Feeds[i].Duplicates(filename)
+		}
+		if got := metrics.EntityCount(filename, OpRead); got != read {
+			t.Errorf("%s: expected %d read, got %d", filename, read, got)
+		}
+		if got := metrics.EntityCount(filename, OpAdded); got != added {
+			t.Errorf("%s: expected %d added, got %d", filename, added, got)
+		}
+		if got := metrics.EntityCount(filename, OpDuplicate); got != duplicates {
+			t.Errorf("%s: expected %d duplicates, got %d", filename, duplicates, got)
+		}
+		if got := metrics.EntityCount(filename, OpMerged); got != report.Merged[filename] {
+			t.Errorf("%s: expected %d merged, got %d", filename, report.Merged[filename], got)
+		}
+	}
+}
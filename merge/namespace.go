@@ -0,0 +1,32 @@
+package merge
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// namespaceIndex maps, by kind, the source ID of each entity merged so far,
+// stripped of its feed's namespace prefix (see WithIDNamespaceStrip), to
+// its ID in the target; identity detection looks up the stripped IDs of
+// later feeds in it
+type namespaceIndex map[gtfs.EntityKind]map[string]string
+
+// record indexes the entities ctx mapped into the target, stripped of
+// ctx.StripIDPrefixes. An ID already indexed keeps its earlier target.
+func (n namespaceIndex) record(ctx *strategy.MergeContext) {
+	for _, kind := range gtfs.EntityKinds {
+		if n[kind] == nil {
+			n[kind] = make(map[string]string)
+		}
+		mapping := idMapping(ctx, kind)
+		for _, source := range slices.Sorted(maps.Keys(mapping)) {
+			key := strategy.StripIDPrefix(source, ctx.StripIDPrefixes)
+			if _, ok := n[kind][key]; !ok {
+				n[kind][key] = mapping[source]
+			}
+		}
+	}
+}
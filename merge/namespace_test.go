@@ -0,0 +1,112 @@
+package merge
+
+import (
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// namespaceFeed returns a feed whose IDs all start with ns, with a trip
+// on a route, service and stop, plus a second trip named extra if not empty
+func namespaceFeed(ns, extra string) *gtfs.Feed {
+	f := gtfs.NewFeed()
+	f.AddAgency(&gtfs.Agency{ID: gtfs.AgencyID(ns + "metro"), Name: "Metro", URL: "http://example.com", Timezone: "UTC"})
+	f.AddStop(&gtfs.Stop{ID: gtfs.StopID(ns + "1000"), Name: "Main St", Lat: 47.6, Lon: -122.3})
+	f.AddRoute(&gtfs.Route{ID: gtfs.RouteID(ns + "R1"), AgencyID: gtfs.AgencyID(ns + "metro"), ShortName: "1", Type: 3})
+	f.AddCalendar(&gtfs.Calendar{ServiceID: gtfs.ServiceID(ns + "WKDY"), Monday: true, StartDate: "20240101", EndDate: "20241231"})
+	trips := []string{ns + "T1"}
+	if extra != "" {
+		trips = append(trips, extra)
+	}
+	for _, id := range trips {
+		f.AddTrip(&gtfs.Trip{ID: gtfs.TripID(id), RouteID: gtfs.RouteID(ns + "R1"), ServiceID: gtfs.ServiceID(ns + "WKDY")})
+		f.StopTimes = append(f.StopTimes, &gtfs.StopTime{TripID: gtfs.TripID(id), StopID: gtfs.StopID(ns + "1000"), StopSequence: 1})
+	}
+	return f
+}
+
+func TestWithIDNamespaceStrip(t *testing.T) {
+	identity := WithDefaultDetection(strategy.DetectionIdentity)
+
+	t.Run("without stripping", func(t *testing.T) {
+		// Given: the same service, namespaced "KCM:" in only one input
+		merged, err := New(identity).MergeFeeds([]*gtfs.Feed{namespaceFeed("KCM:", ""), namespaceFeed("", "")})
+
+		// Then: nothing is a duplicate by identity
+		if err != nil {
+			t.Fatalf("MergeFeeds failed: %v", err)
+		}
+		if len(merged.Stops) != 2 || len(merged.Trips) != 2 {
+			t.Errorf("Expected 2 stops and 2 trips, got %d and %d", len(merged.Stops), len(merged.Trips))
+		}
+	})
+
+	for _, tc := range []struct {
+		name       string
+		feeds      []*gtfs.Feed
+		stripIndex int
+		// survivor is the namespace of the surviving entities, and extra the
+		// trip only the other input has
+		survivor string
+		extra    gtfs.TripID
+	}{
+		{
+			name:       "non-surviving input namespaced",
+			feeds:      []*gtfs.Feed{namespaceFeed("KCM:", "KCM:T2"), namespaceFeed("", "")},
+			stripIndex: 0,
+			survivor:   "",
+			extra:      "KCM:T2",
+		},
+		{
+			name:       "surviving input namespaced",
+			feeds:      []*gtfs.Feed{namespaceFeed("", "T2"), namespaceFeed("KCM:", "")},
+			stripIndex: 1,
+			survivor:   "KCM:",
+			extra:      "T2",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// When: merging with "KCM:" stripped from the namespaced input
+			merged, err := New(identity, WithIDNamespaceStrip(tc.stripIndex, []string{"KCM:"})).MergeFeeds(tc.feeds)
+			if err != nil {
+				t.Fatalf("MergeFeeds failed: %v", err)
+			}
+
+			// Then: each entity is kept once, with the survivor's ID as written
+			ns := tc.survivor
+			if len(merged.Agencies) != 1 || merged.Agencies[gtfs.AgencyID(ns+"metro")] == nil {
+				t.Errorf("Expected only agency %smetro, got %v", ns, merged.AgencyOrder)
+			}
+			if len(merged.Stops) != 1 || merged.Stops[gtfs.StopID(ns+"1000")] == nil {
+				t.Errorf("Expected only stop %s1000, got %v", ns, merged.StopOrder)
+			}
+			if len(merged.Routes) != 1 || merged.Routes[gtfs.RouteID(ns+"R1")] == nil {
+				t.Errorf("Expected only route %sR1, got %v", ns, merged.RouteOrder)
+			}
+			if len(merged.Calendars) != 1 || merged.Calendars[gtfs.ServiceID(ns+"WKDY")] == nil {
+				t.Errorf("Expected only service %sWKDY, got %v", ns, merged.CalendarOrder)
+			}
+			if len(merged.Trips) != 2 || merged.Trips[gtfs.TripID(ns+"T1")] == nil {
+				t.Fatalf("Expected trips %sT1 and %s, got %v", ns, tc.extra, merged.TripOrder)
+			}
+
+			// And: the other input's trip refers to the survivors
+			extra := merged.Trips[tc.extra]
+			if extra == nil {
+				t.Fatalf("Expected trip %s to be kept, got %v", tc.extra, merged.TripOrder)
+			}
+			if extra.RouteID != gtfs.RouteID(ns+"R1") || extra.ServiceID != gtfs.ServiceID(ns+"WKDY") {
+				t.Errorf("Expected %s on route %sR1 and service %sWKDY, got %s and %s", tc.extra, ns, ns, extra.RouteID, extra.ServiceID)
+			}
+			if len(merged.StopTimes) != 2 {
+				t.Fatalf("Expected 2 stop times, got %d", len(merged.StopTimes))
+			}
+			for _, st := range merged.StopTimes {
+				if st.StopID != gtfs.StopID(ns+"1000") {
+					t.Errorf("Expected stop time of %s at %s1000, got %s", st.TripID, ns, st.StopID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package merge
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// readNetworksTwice returns two copies of the networks fixture
+func readNetworksTwice(t *testing.T) []*gtfs.Feed {
+	t.Helper()
+	var feeds []*gtfs.Feed
+	for range 2 {
+		feed, err := gtfs.ReadFromPath("../testdata/networks")
+		if err != nil {
+			t.Fatalf("failed to read networks: %v", err)
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds
+}
+
+func TestMergeNetworksPrefixedOnCollision(t *testing.T) {
+	// Given: two feeds with the same networks and routes
+	feeds := readNetworksTwice(t)
+
+	// When: merged without duplicate detection
+	merged, err := New().MergeFeeds(feeds)
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: the first feed's networks are prefixed, and its route_networks
+	// follow both its renamed routes and networks
+	if len(merged.Networks) != 4 || merged.Networks["a-premium"] == nil {
+		t.Errorf("Expected 4 networks including a-premium, got %v", merged.NetworkOrder)
+	}
+	assigned := make(map[gtfs.RouteID]gtfs.NetworkID)
+	for _, rn := range merged.RouteNetworks {
+		assigned[rn.RouteID] = rn.NetworkID
+	}
+	expected := map[gtfs.RouteID]gtfs.NetworkID{
+		"local":     "standard",
+		"express":   "premium",
+		"a-local":   "a-standard",
+		"a-express": "a-premium",
+	}
+	if len(assigned) != len(expected) || len(merged.RouteNetworks) != len(expected) {
+		t.Errorf("Expected route_networks %v, got %v", expected, assigned)
+	}
+	for route, network := range expected {
+		if assigned[route] != network {
+			t.Errorf("Expected %s in %s, got %q", route, network, assigned[route])
+		}
+	}
+	if errs := merged.Validate(); len(errs) > 0 {
+		t.Errorf("Expected a valid merged feed, got %v", errs)
+	}
+	if got := merged.SourceOf(gtfs.KindNetwork, "a-standard"); len(got) != 1 || got[0] != 0 {
+		t.Errorf("Expected a-standard from feed 0, got %v", got)
+	}
+}
+
+func TestMergeNetworksIdentity(t *testing.T) {
+	// Given: two feeds with the same networks and routes
+	feeds := readNetworksTwice(t)
+
+	// When: merged with identity detection
+	merged, err := New(WithDefaultDetection(strategy.DetectionIdentity)).MergeFeeds(feeds)
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: networks and route_networks appear once
+	if len(merged.Networks) != 2 || len(merged.RouteNetworks) != 2 {
+		t.Errorf("Expected 2 networks and 2 route_networks, got %v and %d", merged.NetworkOrder, len(merged.RouteNetworks))
+	}
+}
+
+func TestMergeRouteNetworkIDColumn(t *testing.T) {
+	// Given: feeds whose routes.txt carries network_id instead of
+	// route_networks.txt
+	feeds := readNetworksTwice(t)
+	for _, feed := range feeds {
+		for _, rn := range feed.RouteNetworks {
+			feed.Routes[rn.RouteID].NetworkID = rn.NetworkID
+		}
+		feed.RouteNetworks = nil
+		feed.AddColumn("routes.txt", "network_id")
+	}
+
+	// When: merged without duplicate detection
+	merged, err := New().MergeFeeds(feeds)
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: network_id is remapped with networks.txt and still written
+	if got := merged.Routes["a-local"].NetworkID; got != "a-standard" {
+		t.Errorf("Expected a-local in a-standard, got %q", got)
+	}
+	if !merged.HasColumn("routes.txt", "network_id") {
+		t.Error("Expected routes.txt to keep its network_id column")
+	}
+}
+
+func TestNetworkDefaultLoggingAndRenaming(t *testing.T) {
+	// Given: a merger with default logging and renaming
+	m := New(WithDefaultLogging(strategy.LogError), WithDefaultRenaming(strategy.RenameAgency))
+
+	// Then: they reach both network strategies
+	for _, s := range []strategy.EntityMergeStrategy{m.networkStrategy, m.routeNetworkStrategy} {
+		base := baseStrategy(t, s)
+		if base.DuplicateLogging != strategy.LogError || base.RenamingStrategy != strategy.RenameAgency {
+			t.Errorf("Expected %s to log errors and rename by agency, got %v and %v", s.Name(), base.DuplicateLogging, base.RenamingStrategy)
+		}
+	}
+
+	// When: networks alone are merged by identity
+	m.networkStrategy.SetDuplicateDetection(strategy.DetectionIdentity)
+	_, err := m.MergeFeeds(readNetworksTwice(t))
+
+	// Then: the duplicate network fails the merge
+	if err == nil || !strings.Contains(err.Error(), "duplicate network") {
+		t.Errorf("Expected a duplicate network error, got %v", err)
+	}
+}
@@ -1,6 +1,11 @@
 package merge
 
-import "github.com/aaronbrethorst/gtfs-merge-go/strategy"
+import (
+	"time"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
 
 // Option configures a Merger
 type Option func(*Merger)
@@ -24,6 +29,8 @@ func WithDefaultLogging(l strategy.DuplicateLogging) Option {
 	return func(m *Merger) {
 		m.agencyStrategy.SetDuplicateLogging(l)
 		m.areaStrategy.SetDuplicateLogging(l)
+		m.networkStrategy.SetDuplicateLogging(l)
+		m.routeNetworkStrategy.SetDuplicateLogging(l)
 		m.stopStrategy.SetDuplicateLogging(l)
 		m.calendarStrategy.SetDuplicateLogging(l)
 		m.calendarDateStrategy.SetDuplicateLogging(l)
@@ -36,6 +43,12 @@ func WithDefaultLogging(l strategy.DuplicateLogging) Option {
 		m.pathwayStrategy.SetDuplicateLogging(l)
 		m.fareAttrStrategy.SetDuplicateLogging(l)
 		m.fareRuleStrategy.SetDuplicateLogging(l)
+		m.fareMediaStrategy.SetDuplicateLogging(l)
+		m.riderCategoryStrategy.SetDuplicateLogging(l)
+		m.fareProductStrategy.SetDuplicateLogging(l)
+		m.timeframeStrategy.SetDuplicateLogging(l)
+		m.fareLegRuleStrategy.SetDuplicateLogging(l)
+		m.fareTransferStrategy.SetDuplicateLogging(l)
 		m.feedInfoStrategy.SetDuplicateLogging(l)
 	}
 }
@@ -45,6 +58,8 @@ func WithDefaultRenaming(r strategy.RenamingStrategy) Option {
 	return func(m *Merger) {
 		m.agencyStrategy.SetRenamingStrategy(r)
 		m.areaStrategy.SetRenamingStrategy(r)
+		m.networkStrategy.SetRenamingStrategy(r)
+		m.routeNetworkStrategy.SetRenamingStrategy(r)
 		m.stopStrategy.SetRenamingStrategy(r)
 		m.calendarStrategy.SetRenamingStrategy(r)
 		m.calendarDateStrategy.SetRenamingStrategy(r)
@@ -57,6 +72,12 @@ func WithDefaultRenaming(r strategy.RenamingStrategy) Option {
 		m.pathwayStrategy.SetRenamingStrategy(r)
 		m.fareAttrStrategy.SetRenamingStrategy(r)
 		m.fareRuleStrategy.SetRenamingStrategy(r)
+		m.fareMediaStrategy.SetRenamingStrategy(r)
+		m.riderCategoryStrategy.SetRenamingStrategy(r)
+		m.fareProductStrategy.SetRenamingStrategy(r)
+		m.timeframeStrategy.SetRenamingStrategy(r)
+		m.fareLegRuleStrategy.SetRenamingStrategy(r)
+		m.fareTransferStrategy.SetRenamingStrategy(r)
 		m.feedInfoStrategy.SetRenamingStrategy(r)
 	}
 }
@@ -64,8 +85,631 @@ func WithDefaultRenaming(r strategy.RenamingStrategy) Option {
 // WithFileDetection sets duplicate detection for a specific GTFS file.
 // This matches the Java CLI behavior where --file and --duplicateDetection
 // are paired by index to apply detection mode to specific entity types.
+// It is equivalent to WithDetectionFor with a file name.
 func WithFileDetection(filename string, d strategy.DuplicateDetection) Option {
+	return WithDetectionFor(filename, d)
+}
+
+// WithDetectionFor sets duplicate detection for one entity, named either by
+// GTFS file ("stops.txt") or by gtfs.EntityKind ("stop"; "service" covers
+// both calendar files). Per-entity modes take precedence over
+// WithDefaultDetection regardless of option order; when several are given
+// for the same file, the last wins. A name that matches no file fails the
+// merge with ErrUnknownEntity.
+func WithDetectionFor(entity string, d strategy.DuplicateDetection) Option {
+	return func(m *Merger) {
+		m.detectionOverrides = append(m.detectionOverrides, detectionOverride{entity: entity, mode: d})
+	}
+}
+
+// WithWriterOptions sets the options used when MergeFiles writes the merged feed.
+// Use this to force-include or exclude specific output columns.
+func WithWriterOptions(opts gtfs.WriterOptions) Option {
+	return func(m *Merger) {
+		m.writerOptions = opts
+	}
+}
+
+// WithReaderOptions sets the options MergeFiles uses to read every input feed,
+// e.g. to read legacy-encoded feeds
+func WithReaderOptions(opts gtfs.ReaderOptions) Option {
+	return func(m *Merger) {
+		m.readerOptions = opts
+	}
+}
+
+// WithInputReaderOptions sets the options MergeFiles uses to read the input
+// at index (0-based, in input order), overriding WithReaderOptions for it.
+// An index past the inputs fails the merge with ErrNoSuchInput.
+func WithInputReaderOptions(index int, opts gtfs.ReaderOptions) Option {
+	return func(m *Merger) {
+		if m.inputReaderOptions == nil {
+			m.inputReaderOptions = make(map[int]gtfs.ReaderOptions)
+		}
+		m.inputReaderOptions[index] = opts
+	}
+}
+
+// WithShapeDistanceUnit normalizes shape_dist_traveled in shapes.txt and
+// stop_times.txt to a single unit. Each feed's unit is inferred from its
+// shapes; Auto rescales to the unit inferred for the first input feed.
+// Feeds without shape_dist_traveled, or whose unit cannot be inferred,
+// are left unchanged.
+func WithShapeDistanceUnit(u DistanceUnit) Option {
+	return func(m *Merger) {
+		m.shapeDistanceUnit = u
+	}
+}
+
+// WithRouteSortOrderStrategy sets how route_sort_order values are reconciled
+// after merging, so routes from feeds that each number from 1 don't
+// interleave. The default, SortOrderKeep, leaves them unchanged.
+func WithRouteSortOrderStrategy(s RouteSortOrderStrategy) Option {
+	return func(m *Merger) {
+		m.routeSortOrder = s
+	}
+}
+
+// WithOverwriteInput allows MergeFiles to write its output over one of its
+// inputs. Without it, MergeFiles returns ErrOutputIsInput in that case.
+func WithOverwriteInput(overwrite bool) Option {
+	return func(m *Merger) {
+		m.overwriteInput = overwrite
+	}
+}
+
+// WithGrayZone makes fuzzy stop, route and trip matching treat scores within
+// margin of the fuzzy threshold as ambiguous and resolve them by policy:
+// GrayZoneNearMiss keeps the pair apart, GrayZoneFlag merges it, and
+// GrayZoneAbort fails the merge with ErrAmbiguousMatches. Either way the
+// pair is listed in Report.GrayZone. A margin of 0 disables the gray zone.
+func WithGrayZone(margin float64, policy strategy.GrayZonePolicy) Option {
+	return func(m *Merger) {
+		m.grayZone = strategy.GrayZone{Margin: margin, Policy: policy}
+	}
+}
+
+// WithBlockedDuplicates prevents each pair of entities from being merged as
+// duplicates, by identity or fuzzy detection; the later entity is added
+// instead, prefixed on collision. Pairs that never matched are listed in
+// Report.UnusedBlockedPairs. May be given more than once.
+func WithBlockedDuplicates(pairs []BlockedPair) Option {
+	return func(m *Merger) {
+		m.blockedPairs = append(m.blockedPairs, pairs...)
+	}
+}
+
+// WithOverrides applies the overrides in the CSV file at path (see
+// ReadOverrides) to the input feed at feedIndex (0-based, in input order)
+// before it is merged, so duplicate detection sees the corrected values.
+// The file is read at each merge, which fails if it cannot be. Overrides
+// naming an unknown entity or column, or with an unparsable value, are
+// skipped and listed in Report.Warnings; the number applied is reported in
+// FeedReport.OverridesApplied. Input feeds are modified in place. Given
+// again for the same input, the later path replaces the earlier. An index
+// past the inputs fails the merge with ErrNoSuchInput.
+func WithOverrides(feedIndex int, path string) Option {
+	return func(m *Merger) {
+		if m.overridePaths == nil {
+			m.overridePaths = make(map[int]string)
+		}
+		m.overridePaths[feedIndex] = path
+	}
+}
+
+// WithAgencyFilter merges only the agencies agencyIDs of the input feed at
+// feedIndex (0-based, in input order). The other agencies are removed before
+// the input is merged, with their routes, those routes' trips, stop_times
+// and frequencies, the shapes and stops only those trips used, the services
+// only they and no timeframe used, the networks only those routes belonged
+// to with the fare leg and transfer rules that named them, and their
+// fare_attributes and fare_rules (see WithAgencyFilterKeepStops to keep the
+// stops). Rows removed are reported in FeedReport.AgencyFiltered,
+// and agencyIDs the input lacks are listed in Report.Warnings. Input feeds
+// are modified in place. Given again for the same input, the later list
+// replaces the earlier. An index past the inputs fails the merge with
+// ErrNoSuchInput.
+func WithAgencyFilter(feedIndex int, agencyIDs []string) Option {
+	return func(m *Merger) {
+		if m.agencyFilters == nil {
+			m.agencyFilters = make(map[int][]gtfs.AgencyID)
+		}
+		ids := make([]gtfs.AgencyID, len(agencyIDs))
+		for i, id := range agencyIDs {
+			ids[i] = gtfs.AgencyID(id)
+		}
+		m.agencyFilters[feedIndex] = ids
+	}
+}
+
+// WithAgencyFilterKeepStops keeps the stops that only the trips
+// WithAgencyFilter removes visited, instead of removing them with the
+// trips. Off by default.
+func WithAgencyFilterKeepStops(keep bool) Option {
+	return func(m *Merger) {
+		m.agencyFilterKeepStops = keep
+	}
+}
+
+// WithIDNamespaceStrip makes identity detection compare the IDs of the
+// input feed at feedIndex (0-based, in input order) without the first of
+// prefixes each starts with, so that "KCM:1000" in one input duplicates
+// "1000" in another. Only the comparison is affected: the surviving entity
+// keeps its ID as written, and the other input's references are remapped
+// to it. Given again for the same input, the prefixes are added to the
+// earlier ones. An index past the inputs fails the merge with
+// ErrNoSuchInput.
+func WithIDNamespaceStrip(feedIndex int, prefixes []string) Option {
+	return func(m *Merger) {
+		if m.idNamespaces == nil {
+			m.idNamespaces = make(map[int][]string)
+		}
+		m.idNamespaces[feedIndex] = append(m.idNamespaces[feedIndex], prefixes...)
+	}
+}
+
+// WithBasicRouteTypes replaces, before merging, each input route's extended
+// route_type (e.g. 702, express bus) with its basic GTFS equivalent (3,
+// bus) from ExtendedRouteTypes, for consumers that only accept basic types;
+// fuzzy route matching then compares the basic types. Routes whose type has
+// no equivalent are kept as they are and listed in Report.Warnings; the
+// number mapped is reported in FeedReport.RouteTypesMapped. Input feeds
+// are modified in place. Without it, a merged feed mixing basic and
+// extended types is warned about.
+func WithBasicRouteTypes(basic bool) Option {
+	return func(m *Merger) {
+		m.basicRouteTypes = basic
+	}
+}
+
+// newestEditionSetter is implemented by strategies that can resolve
+// duplicates by feed edition, such as strategy.AgencyMergeStrategy
+type newestEditionSetter interface {
+	SetNewestEditionWins(newest bool)
+}
+
+// WithNewestAgencyEdition resolves duplicate agencies field by field in
+// favor of the newest edition: each field takes the value of the newest
+// input that has one, judged by feed_info.txt's feed_start_date, or by
+// feed_version where dates are absent or equal (see
+// strategy.FeedEdition), whatever order the inputs are merged in. An empty
+// value never replaces a populated one. Each value replaced is listed in
+// Report.AgencyFieldChanges and Report.Warnings. By default the agency
+// merged first, from the last input, is kept as it is.
+func WithNewestAgencyEdition(newest bool) Option {
+	return func(m *Merger) {
+		if s, ok := m.agencyStrategy.(newestEditionSetter); ok {
+			s.SetNewestEditionWins(newest)
+		}
+	}
+}
+
+// collideAcrossFilesSetter is implemented by strategies whose services can
+// collide across calendar.txt and calendar_dates.txt, such as
+// strategy.CalendarMergeStrategy
+type collideAcrossFilesSetter interface {
+	SetCollideAcrossFiles(across bool)
+}
+
+// WithServiceCollisionsAcrossFiles makes a service_id collide with one the
+// merged feed already defines in either calendar.txt or calendar_dates.txt,
+// so that it is prefixed, or matched by identity detection only if both
+// define it in calendar.txt. By default services are compared only with
+// those from the same file, so a service defined in calendar_dates.txt alone
+// is combined with a same-named service of another input defined in
+// calendar.txt alone, even without duplicate detection.
+func WithServiceCollisionsAcrossFiles(across bool) Option {
+	return func(m *Merger) {
+		for _, s := range []strategy.EntityMergeStrategy{m.calendarStrategy, m.calendarDateStrategy} {
+			if s, ok := s.(collideAcrossFilesSetter); ok {
+				s.SetCollideAcrossFiles(across)
+			}
+		}
+	}
+}
+
+// feedIDFromSourceSetter is implemented by strategies that can name
+// feed_info rows without a feed_id after their input, such as
+// strategy.FeedInfoMergeStrategy
+type feedIDFromSourceSetter interface {
+	SetFeedIDFromSource(fromSource bool)
+}
+
+// WithFeedInfoIDFromSource assigns each feed_info row without a feed_id the
+// name of its input (see FeedReport.Name), so that every input keeps its
+// own row and repeated merges of the same inputs write the same
+// feed_info.txt. By default such rows get Java's feed_id "1", and the last
+// one merged replaces the others.
+func WithFeedInfoIDFromSource(fromSource bool) Option {
+	return func(m *Merger) {
+		if s, ok := m.feedInfoStrategy.(feedIDFromSourceSetter); ok {
+			s.SetFeedIDFromSource(fromSource)
+		}
+	}
+}
+
+// WithIntraFeedDedup collapses the duplicates within each input before it
+// is merged with the others: the feed's entities are first merged into an
+// empty feed with the configured strategies, and fuzzy detection matches
+// each stop, route, agency, area and fare against those of the same feed
+// already kept, which it otherwise never does. References to a collapsed
+// entity, such as a duplicate stop's stop_times, are rewritten to the
+// survivor, and FeedReport.IDMap maps it to the survivor's merged ID. The
+// number collapsed is reported in FeedReport.IntraFeedDuplicates. Identity
+// detection finds nothing to collapse, since IDs are unique within a feed.
+func WithIntraFeedDedup(dedup bool) Option {
+	return func(m *Merger) {
+		m.intraFeedDedup = dedup
+	}
+}
+
+// WithInterpolateStopTimes fills in, after merging, the arrival and
+// departure times of stop_times that have neither, as GTFS allows between
+// timepoints, for consumers that require every time. Times are
+// interpolated linearly between the timed stops before and after in the
+// same trip, in proportion to shape_dist_traveled where the stops have it
+// and to the number of stops otherwise, and the rows are marked timepoint
+// 0. Rows with times are never changed. The number interpolated is
+// reported in Report.StopTimesInterpolated; rows that cannot be, lacking
+// timed stops on both sides, are listed in Report.UninterpolatedStopTimes
+// and warned about. Off by default.
+func WithInterpolateStopTimes(interpolate bool) Option {
+	return func(m *Merger) {
+		m.interpolateStopTimes = interpolate
+	}
+}
+
+// WithPruneUnreferenced deletes entities of the given kinds (stops, shapes,
+// services, agencies or areas) that nothing in the merged feed references,
+// once every input has been merged; parent stations of kept stops are kept.
+// The number deleted per kind is reported in Report.Pruned. An unknown kind
+// makes the merge fail with ErrUnknownPruneKind. May be given more than once.
+func WithPruneUnreferenced(kinds ...string) Option {
+	return func(m *Merger) {
+		m.pruneKinds = append(m.pruneKinds, kinds...)
+	}
+}
+
+// WithUniqueStopCodes checks, once every input has been merged, that no two
+// stops share a non-empty stop_code (compared case-sensitively), handling
+// collisions by policy: StopCodesWarn lists them in
+// Report.StopCodeCollisions, StopCodesPrefix also prefixes the colliding
+// codes with their feed's prefix, listing any still shared in
+// Report.StopCodesUnresolved, and StopCodesError fails the merge with
+// ErrDuplicateStopCodes.
+func WithUniqueStopCodes(p StopCodePolicy) Option {
+	return func(m *Merger) {
+		m.stopCodePolicy = p
+	}
+}
+
+// WithFrequencyOverlaps looks, once every input has been merged, for
+// frequency-based trips running the same service as a set of exact-time
+// trips (see FrequencyOverlap), such as one input publishing a route as
+// stop_times and another as frequencies. Overlaps are listed in
+// Report.FrequencyOverlaps and Report.Warnings, and resolved by policy:
+// FrequencyOverlapWarn keeps both, FrequencyOverlapKeepExact removes the
+// frequency trip and FrequencyOverlapKeepFrequencies the exact-time trips.
+// Off (FrequencyOverlapIgnore) by default.
+func WithFrequencyOverlaps(p FrequencyOverlapPolicy) Option {
+	return func(m *Merger) {
+		m.frequencyOverlaps = p
+	}
+}
+
+// WithValidateInputs validates each input feed (see gtfs.Feed.Validate)
+// before merging. An invalid feed fails the merge with an error wrapping
+// ErrInvalidFeed and each *gtfs.ValidationError found, so callers can tell
+// missing fields from broken references with errors.Is and errors.As.
+func WithValidateInputs(validate bool) Option {
+	return func(m *Merger) {
+		m.validateInputs = validate
+	}
+}
+
+// WithSanitizeInputs drops the rows of each input feed whose references
+// name entities missing from that feed, such as trips whose service_id is in
+// neither calendar.txt nor calendar_dates.txt, before merging (and before
+// WithValidateInputs checks the feed). Input feeds are modified in place;
+// the rows removed are reported in FeedReport.Sanitized. Off by default.
+func WithSanitizeInputs(sanitize bool) Option {
+	return func(m *Merger) {
+		m.sanitizeInputs = sanitize
+	}
+}
+
+// WithAccountingCheck makes the merge fail with ErrAccountingMismatch
+// unless, for every file, the rows read from the inputs less those
+// deduplicated and dropped are the rows merged (see Report.Unaccounted), so
+// that rows lost or duplicated by a bug do not go unnoticed. Off by
+// default.
+func WithAccountingCheck(check bool) Option {
+	return func(m *Merger) {
+		m.accountingCheck = check
+	}
+}
+
+// WithSanitizeText cleans the free-text fields of each input feed, such as
+// names, descriptions and headsigns, before merging, so duplicate detection
+// compares the cleaned values: C0 control characters (tab and line breaks
+// included) become spaces, invalid UTF-8 sequences become U+FFFD, and names
+// and headsigns are trimmed of leading and trailing whitespace. IDs and
+// coded values are left as they are. Input feeds are modified in place; the
+// values changed are reported in FeedReport.TextSanitized. Off by default.
+func WithSanitizeText(sanitize bool) Option {
+	return func(m *Merger) {
+		m.sanitizeText = sanitize
+	}
+}
+
+// WithNormalizeColors normalizes, before merging, each input route's
+// route_color and route_text_color (see gtfs.NormalizeColor): a leading
+// '#' is removed and hex digits are uppercased, so "#ff0000" is written as
+// "FF0000", which strict consumers require. Input feeds are modified in
+// place; the values changed are reported in FeedReport.ColorsNormalized.
+// Fuzzy route matching compares normalized colors either way. Off by
+// default.
+func WithNormalizeColors(normalize bool) Option {
+	return func(m *Merger) {
+		m.normalizeColors = normalize
+	}
+}
+
+// WithFixStationStopTimes rewrites each input's stop_times that reference a
+// station (location_type 1) to reference the station's platform, when it has
+// exactly one; the number rewritten is reported in
+// FeedReport.StationStopTimesFixed. Stop times referencing a station with no
+// or several platforms, or another non-boarding location type, are listed in
+// Report.Warnings and kept, or dropped under WithSanitizeInputs. Input feeds
+// are modified in place. Off by default.
+func WithFixStationStopTimes(fix bool) Option {
+	return func(m *Merger) {
+		m.fixStationStopTimes = fix
+	}
+}
+
+// WithFailOnIdenticalInputs makes MergeFiles fail with ErrIdenticalInputs
+// when two inputs are byte-for-byte the same. By default the later copy is
+// skipped with a warning and listed in Report.SkippedInputs.
+func WithFailOnIdenticalInputs(fail bool) Option {
+	return func(m *Merger) {
+		m.failOnIdenticalInputs = fail
+	}
+}
+
+// WithSkipInvalidInputs makes MergeFiles leave out, with a warning, each
+// input that is missing or fails to read or parse, rather than failing the
+// merge, and list it in Report.InvalidInputs. The merge still fails, with
+// ErrTooFewValidInputs, if fewer than two inputs can be read.
+func WithSkipInvalidInputs(skip bool) Option {
+	return func(m *Merger) {
+		m.skipInvalidInputs = skip
+	}
+}
+
+// WithFingerprintCache makes MergeFiles record, in the file at path, the
+// content hash of each input (see gtfs.HashPath), of its configuration and
+// of the output it writes. When a later MergeFiles finds the same inputs,
+// in the same order, merged with the same options into an output that
+// still has the recorded hash, it returns without merging and sets
+// Report.CacheHit. Inputs are hashed before they are read, so a merge that
+// does run reads each input twice. A merge that skips invalid inputs (see
+// WithSkipInvalidInputs) is not recorded, nor is one configured with a
+// function literal, such as an anonymous strategy.NormalizerFunc, whose
+// behavior cannot be hashed; named functions are hashed by name.
+func WithFingerprintCache(path string) Option {
+	return func(m *Merger) {
+		m.fingerprintCache = path
+	}
+}
+
+// WithStrictOutput checks, once the merge completes and before the merged
+// feed is returned or written, that every reference Validate checks
+// resolves. If any does not, the merge fails with ErrBrokenOutputReferences,
+// listing the first offending references with the input feeds they came
+// from. The check is a single pass over the merged feed. Off by default.
+func WithStrictOutput(strict bool) Option {
+	return func(m *Merger) {
+		m.strictOutput = strict
+	}
+}
+
+// WithAlwaysPrefix prefixes the IDs of every input, the last input's
+// included, with the input's letter (see GetPrefixForIndex), rather than
+// prefixing only IDs that collide. ProfileConcatenate turns it on.
+func WithAlwaysPrefix(always bool) Option {
+	return func(m *Merger) {
+		m.alwaysPrefix = always
+	}
+}
+
+// WithNormalizeShapes cleans up shapes as they are merged: consecutive points
+// at the same coordinates (to 6 decimal places) are collapsed and each
+// shape's shape_pt_sequence is renumbered from 1. Off by default.
+func WithNormalizeShapes(normalize bool) Option {
+	return func(m *Merger) {
+		m.normalizeShapes = normalize
+	}
+}
+
+// stopSubsetSetter is implemented by strategies that can match trips with
+// different numbers of stops, such as strategy.TripMergeStrategy
+type stopSubsetSetter interface {
+	SetStopSubset(maxStopDifference int, tolerance time.Duration)
+}
+
+// WithTripStopSubset lets fuzzy trip matching pair trips whose stop
+// sequences differ by up to maxStopDifference inserted stops, provided the
+// shorter trip's stops appear in order in the longer trip and the times at
+// shared stops are within tolerance. The trip with more stops keeps its
+// stop_times. Each such match is listed in Report.TripSubsetMatches and
+// logged under WithDebug. The default, 0 and 0, requires identical stops
+// and times.
+func WithTripStopSubset(maxStopDifference int, tolerance time.Duration) Option {
+	return func(m *Merger) {
+		if s, ok := m.tripStrategy.(stopSubsetSetter); ok {
+			s.SetStopSubset(maxStopDifference, tolerance)
+		}
+	}
+}
+
+// strictBoardingSetter is implemented by strategies that can refuse fuzzy
+// trip matches whose stop_times differ in boarding rules, such as
+// strategy.TripMergeStrategy
+type strictBoardingSetter interface {
+	SetStrictBoarding(strict bool)
+}
+
+// WithStrictTripBoarding makes fuzzy trip matching refuse to pair trips
+// whose stop_times differ in pickup_type, drop_off_type or timepoint at a
+// shared stop, keeping both trips. By default such trips are merged, the
+// surviving trip's values are kept, and each difference is listed in
+// Report.BoardingDifferences and Report.Warnings.
+func WithStrictTripBoarding(strict bool) Option {
+	return func(m *Merger) {
+		if s, ok := m.tripStrategy.(strictBoardingSetter); ok {
+			s.SetStrictBoarding(strict)
+		}
+	}
+}
+
+// normalizerSetter is implemented by strategies that accept a
+// strategy.Normalizer, such as those embedding strategy.BaseStrategy
+type normalizerSetter interface {
+	SetNormalizer(n strategy.Normalizer)
+}
+
+// WithNormalizer sets the normalizer applied to the fields one entity's
+// strategy compares during duplicate detection, so that "Main St " matches
+// "Main St". The entity is named by GTFS file or gtfs.EntityKind as in
+// WithDetectionFor; strategy.DefaultNormalizer suggests one per kind, and
+// nil turns normalization off. The merged feed keeps the original values
+// unless WithNormalizeOutput is set. Names that match no file are ignored.
+func WithNormalizer(entity string, n strategy.Normalizer) Option {
+	return func(m *Merger) {
+		for _, filename := range m.entityFiles(entity) {
+			if s, ok := m.GetStrategyForFile(filename).(normalizerSetter); ok {
+				s.SetNormalizer(n)
+			}
+		}
+	}
+}
+
+// WithNormalizeOutput writes the normalized values of compared fields (see
+// WithNormalizer) to the merged feed, instead of only comparing them
+// normalized. Off by default.
+func WithNormalizeOutput(normalize bool) Option {
+	return func(m *Merger) {
+		m.normalizeOutput = normalize
+	}
+}
+
+// WithMetrics reports stage timings and row counters to metrics: how long
+// each input took to read, each entity strategy took per input, validation
+// and writing took, and, once the merge succeeds, the rows read, added,
+// merged as duplicates and written for each file. See the Stage and Op
+// constants. Nil, the default, reports nothing.
+func WithMetrics(metrics Metrics) Option {
+	return func(m *Merger) {
+		m.metrics = metrics
+	}
+}
+
+// WithHarmonizeDirections flips the direction_id of trips whose direction
+// convention is opposite to another input's on the same merged route, e.g.
+// one agency using 0 for northbound and the other 0 for southbound. Each
+// input's direction 0 terminals are compared with those of the input merged
+// first (the last input), which keeps its convention. Conflicts are listed
+// in Report.DirectionConflicts and logged as warnings whether or not this
+// is set; off by default.
+func WithHarmonizeDirections(harmonize bool) Option {
+	return func(m *Merger) {
+		m.harmonizeDirections = harmonize
+	}
+}
+
+// fuzzyLimitsSetter is implemented by strategies whose fuzzy matching can
+// be bounded, such as strategy.StopMergeStrategy
+type fuzzyLimitsSetter interface {
+	SetFuzzyLimits(limits strategy.FuzzyLimits)
+}
+
+// WithFuzzyLimits bounds the fuzzy matching of stops, routes and trips, so
+// that a pathological feed can't make a merge run for hours: each source
+// entity is scored against at most limits.MaxCandidatesPerEntity
+// candidates, and once limits.MaxComparisons candidates have been scored
+// for one file of one input, its remaining entities are matched by
+// identity. Each limit reached is listed in Report.FuzzyLimitHits and
+// Report.Warnings. The default is strategy.DefaultFuzzyLimits; zero
+// limits are unlimited.
+func WithFuzzyLimits(limits strategy.FuzzyLimits) Option {
+	return func(m *Merger) {
+		for _, s := range []any{m.stopStrategy, m.routeStrategy, m.tripStrategy} {
+			if s, ok := s.(fuzzyLimitsSetter); ok {
+				s.SetFuzzyLimits(limits)
+			}
+		}
+	}
+}
+
+// WithServiceCheck checks, once the merge completes, that the merged feed
+// has active service (see gtfs.Feed.ActiveServiceOn) on each of the days
+// days starting on from's date, such as the 7 days from time.Now(). Days
+// without service, and service ending before the last of them, are
+// described in Report.ServiceCoverage and logged as warnings; the merge
+// still succeeds. Off by default; days of 0 or less turn it off.
+func WithServiceCheck(from time.Time, days int) Option {
+	return func(m *Merger) {
+		m.serviceFrom = from
+		m.serviceDays = days
+	}
+}
+
+// WithServiceGapCheck checks, once the merge completes, that no route of
+// the merged feed goes more than maxGapDays consecutive days without trips
+// between days it has some (see gtfs.FindServiceGaps), such as the days
+// between one edition of a feed ending and the next starting. Gaps found
+// are listed in Report.ServiceGaps and logged as a warning; the merge still
+// succeeds. Off by default; a negative maxGapDays turns it off.
+func WithServiceGapCheck(maxGapDays int) Option {
+	return func(m *Merger) {
+		m.maxServiceGapDays = maxGapDays
+	}
+}
+
+// WithReadableTripIDs renames every merged trip after pattern, such as
+// "{route_short}-{direction}-{start_time}", from fields of the trip, its
+// route and its first stop time (see TripIDFields), so that support tools
+// show IDs like "10-0-0815" rather than "c-1029384756". A trip whose ID
+// would repeat another's takes the next {seq}, or a "-2", "-3"... suffix
+// if pattern has none. Stop times, frequencies, transfers and provenance
+// follow the renamed trips; Report.ReadableTripIDs maps the old IDs to the
+// new, and FeedReport.IDMap maps each input's trips to the new IDs, though
+// reports of matches made while merging, such as Report.GrayZone, name
+// trips by their old IDs. An invalid pattern fails the merge with
+// ErrInvalidTripIDPattern. Off by default; an empty pattern turns it off.
+func WithReadableTripIDs(pattern string) Option {
+	return func(m *Merger) {
+		m.readableTripIDs = pattern
+	}
+}
+
+// routeStopScoringSetter is implemented by strategies whose fuzzy route
+// matching can score stops in more than one way, such as
+// strategy.RouteMergeStrategy
+type routeStopScoringSetter interface {
+	SetStopScoring(scoring strategy.RouteStopScoring)
+}
+
+// WithRouteStopScoring selects how fuzzy route matching scores the stops
+// two routes serve. The default, strategy.RouteStopsOverlap, counts every
+// shared stop equally, so branches sharing a long trunk can be merged;
+// strategy.RouteStopsTerminals also requires the routes to share the stops
+// at both ends of a trip.
+func WithRouteStopScoring(scoring strategy.RouteStopScoring) Option {
 	return func(m *Merger) {
-		m.SetDuplicateDetectionForFile(filename, d)
+		if s, ok := m.routeStrategy.(routeStopScoringSetter); ok {
+			s.SetStopScoring(scoring)
+		}
 	}
 }
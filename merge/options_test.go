@@ -1,9 +1,12 @@
 package merge
 
 import (
+	"errors"
+	"slices"
 	"testing"
 
 	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfstest"
 	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
 )
 
@@ -402,3 +405,332 @@ func TestMixedStrategyConfiguration(t *testing.T) {
 		t.Errorf("expected 2 stops (global none), got %d", len(merged.Stops))
 	}
 }
+
+func TestWithWriterOptions(t *testing.T) {
+	opts := gtfs.WriterOptions{
+		ForceIncludeColumns: map[string][]string{"stops.txt": {"wheelchair_boarding"}},
+	}
+	m := New(WithWriterOptions(opts))
+
+	cols := m.writerOptions.ForceIncludeColumns["stops.txt"]
+	if len(cols) != 1 || cols[0] != "wheelchair_boarding" {
+		t.Errorf("Expected writer options to be stored on merger, got %v", m.writerOptions)
+	}
+}
+
+func TestWithNormalizeShapes(t *testing.T) {
+	// Given: the same feed twice, so shape IDs collide and get prefixed
+	feedA, err := gtfs.ReadFromPath("../testdata/all_optional_feed")
+	if err != nil {
+		t.Fatalf("failed to read all_optional_feed: %v", err)
+	}
+	feedB, err := gtfs.ReadFromPath("../testdata/all_optional_feed")
+	if err != nil {
+		t.Fatalf("failed to read all_optional_feed: %v", err)
+	}
+
+	// When: merged with shape normalization
+	merged, err := New(WithNormalizeShapes(true)).MergeFeeds([]*gtfs.Feed{feedA, feedB})
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: every shape is numbered from 1
+	for id, points := range merged.Shapes {
+		for i, p := range points {
+			if p.Sequence != i+1 {
+				t.Errorf("Shape %s point %d: expected sequence %d, got %d", id, i, i+1, p.Sequence)
+			}
+		}
+	}
+
+	// And: every trip still references a shape in the merged feed
+	for id, trip := range merged.Trips {
+		if trip.ShapeID == "" {
+			continue
+		}
+		if _, ok := merged.Shapes[trip.ShapeID]; !ok {
+			t.Errorf("Trip %s references missing shape %s", id, trip.ShapeID)
+		}
+	}
+}
+
+func TestWithDetectionFor(t *testing.T) {
+	// Given: per-entity modes given both before and after the default
+	m := New(
+		WithDetectionFor("stops.txt", strategy.DetectionFuzzy),
+		WithDefaultDetection(strategy.DetectionIdentity),
+		WithDetectionFor(string(gtfs.KindService), strategy.DetectionNone),
+		WithDetectionFor("route", strategy.DetectionFuzzy),
+	)
+
+	// Then: per-entity modes win over the default, whatever the order
+	tests := []struct {
+		entity   string
+		expected strategy.DuplicateDetection
+	}{
+		{"stops.txt", strategy.DetectionFuzzy},
+		{"stop", strategy.DetectionFuzzy},
+		{"calendar.txt", strategy.DetectionNone},
+		{"calendar_dates.txt", strategy.DetectionNone},
+		{"routes.txt", strategy.DetectionFuzzy},
+		{"trips.txt", strategy.DetectionIdentity},
+		{"agency", strategy.DetectionIdentity},
+	}
+	for _, tt := range tests {
+		got, ok := m.DetectionFor(tt.entity)
+		if !ok {
+			t.Errorf("DetectionFor(%q) not found", tt.entity)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("DetectionFor(%q) = %s, want %s", tt.entity, got, tt.expected)
+		}
+	}
+
+	// And: a later default for everything still leaves the overrides in place
+	m.SetDuplicateDetectionForAll(strategy.DetectionNone)
+	if got, _ := m.DetectionFor("stops.txt"); got != strategy.DetectionFuzzy {
+		t.Errorf("Expected stops.txt to stay fuzzy, got %s", got)
+	}
+	if got, _ := m.DetectionFor("trips.txt"); got != strategy.DetectionNone {
+		t.Errorf("Expected trips.txt to follow the new default, got %s", got)
+	}
+}
+
+func TestDetectionForUnknownEntity(t *testing.T) {
+	m := New(WithDetectionFor("bogus.txt", strategy.DetectionFuzzy))
+
+	if _, ok := m.DetectionFor("bogus.txt"); ok {
+		t.Error("Expected DetectionFor to report an unknown entity")
+	}
+
+	// And: merging fails rather than ignoring the mode
+	if _, err := m.MergeFeeds(readFixtures(t, "simple_a", "simple_b")); !errors.Is(err, ErrUnknownEntity) {
+		t.Errorf("Expected ErrUnknownEntity, got %v", err)
+	}
+}
+
+func TestWithNormalizer(t *testing.T) {
+	newFeeds := func() []*gtfs.Feed {
+		feedA := gtfs.NewFeed()
+		feedA.AddStop(&gtfs.Stop{ID: "a1", Name: "Main St ", Lat: 47.6, Lon: -122.3})
+		feedB := gtfs.NewFeed()
+		feedB.AddStop(&gtfs.Stop{ID: "b1", Name: "MAIN ST", Lat: 47.6, Lon: -122.3})
+		return []*gtfs.Feed{feedA, feedB}
+	}
+
+	// Given: fuzzy stop detection with the default stop normalizer
+	opts := []Option{
+		WithDetectionFor("stop", strategy.DetectionFuzzy),
+		WithNormalizer("stop", strategy.DefaultNormalizer(gtfs.KindStop)),
+	}
+
+	// When: merging stops whose names differ in spacing and case
+	merged, err := New(opts...).MergeFeeds(newFeeds())
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: they are merged, keeping the original name
+	if len(merged.Stops) != 1 || merged.Stops["b1"] == nil || merged.Stops["b1"].Name != "MAIN ST" {
+		t.Errorf("Expected only stop b1 named %q, got %v", "MAIN ST", merged.StopOrder)
+	}
+
+	// And: with WithNormalizeOutput the merged name is normalized
+	merged, err = New(append(opts, WithNormalizeOutput(true))...).MergeFeeds(newFeeds())
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+	if got := merged.Stops["b1"].Name; got != "main st" {
+		t.Errorf("Expected normalized name %q, got %q", "main st", got)
+	}
+}
+
+func TestWithTripStopSubset(t *testing.T) {
+	newFeeds := func() []*gtfs.Feed {
+		var feeds []*gtfs.Feed
+		for _, trip := range []struct {
+			id    gtfs.TripID
+			stops []gtfs.StopID
+		}{
+			{"local", []gtfs.StopID{"s1", "s2", "s3"}},
+			{"express", []gtfs.StopID{"s1", "s3"}},
+		} {
+			feed := gtfs.NewFeed()
+			feed.AddRoute(&gtfs.Route{ID: "r1", ShortName: "1", Type: 3})
+			feed.AddCalendar(&gtfs.Calendar{ServiceID: "svc1", Monday: true, StartDate: "20240101", EndDate: "20241231"})
+			feed.AddTrip(&gtfs.Trip{ID: trip.id, RouteID: "r1", ServiceID: "svc1"})
+			times := map[gtfs.StopID]string{"s1": "08:00:00", "s2": "08:10:00", "s3": "08:20:00"}
+			for i, stopID := range trip.stops {
+				feed.AddStop(&gtfs.Stop{ID: stopID, Name: string(stopID), Lat: 47.6, Lon: -122.3})
+				feed.StopTimes = append(feed.StopTimes, &gtfs.StopTime{
+					TripID: trip.id, StopID: stopID, StopSequence: i + 1, ArrivalTime: times[stopID], DepartureTime: times[stopID],
+				})
+			}
+			feeds = append(feeds, feed)
+		}
+		return feeds
+	}
+	opts := []Option{
+		WithDefaultDetection(strategy.DetectionIdentity),
+		WithDetectionFor("trip", strategy.DetectionFuzzy),
+	}
+
+	// Given/When: merging a trip with one stop fewer than its duplicate,
+	// by default
+	merged, err := New(opts...).MergeFeeds(newFeeds())
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: both trips are kept
+	if len(merged.Trips) != 2 {
+		t.Errorf("Expected 2 trips by default, got %v", merged.TripOrder)
+	}
+
+	// When: allowing one inserted stop
+	m := New(append(opts, WithTripStopSubset(1, 0))...)
+	merged, err = m.MergeFeeds(newFeeds())
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: the trips are merged, keeping the stop_times of the local trip
+	if len(merged.Trips) != 1 || len(merged.StopTimes) != 3 {
+		t.Errorf("Expected 1 trip with 3 stop_times, got %v and %d stop_times", merged.TripOrder, len(merged.StopTimes))
+	}
+
+	// And: the match is reported
+	matches := m.Report().TripSubsetMatches
+	if len(matches) != 1 || matches[0].SourceID != "local" || !matches[0].SourceSurvives || matches[0].MaxStopDifference != 1 {
+		t.Errorf("Expected local reported as the surviving trip, got %+v", matches)
+	}
+}
+
+func TestWithRouteStopScoring(t *testing.T) {
+	// Given: two feeds, each with one branch of a line sharing a trunk
+	newFeeds := func() []*gtfs.Feed {
+		var feeds []*gtfs.Feed
+		for _, branch := range []string{"north", "south"} {
+			feed := gtfs.NewFeed()
+			feed.AddRoute(&gtfs.Route{ID: gtfs.RouteID(branch), ShortName: "Blue", Type: 1})
+			feed.AddCalendar(&gtfs.Calendar{ServiceID: "svc1", Monday: true, StartDate: "20240101", EndDate: "20241231"})
+			feed.AddTrip(&gtfs.Trip{ID: gtfs.TripID(branch + "_trip"), RouteID: gtfs.RouteID(branch), ServiceID: "svc1"})
+			stops := []gtfs.StopID{"t1", "t2", "t3", "t4", "t5", gtfs.StopID(branch)}
+			for i, stopID := range stops {
+				feed.AddStop(&gtfs.Stop{ID: stopID, Name: string(stopID), Lat: 47.6, Lon: -122.3})
+				feed.StopTimes = append(feed.StopTimes, &gtfs.StopTime{TripID: gtfs.TripID(branch + "_trip"), StopID: stopID, StopSequence: i + 1})
+			}
+			feeds = append(feeds, feed)
+		}
+		return feeds
+	}
+	opts := []Option{
+		WithDefaultDetection(strategy.DetectionIdentity),
+		WithDetectionFor("route", strategy.DetectionFuzzy),
+	}
+
+	// When: merged by default
+	merged, err := New(opts...).MergeFeeds(newFeeds())
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: the branches are merged into one route
+	if len(merged.Routes) != 1 {
+		t.Errorf("Expected the branches merged by default, got %v", merged.RouteOrder)
+	}
+
+	// When: merged requiring shared terminals
+	merged, err = New(append(opts, WithRouteStopScoring(strategy.RouteStopsTerminals))...).MergeFeeds(newFeeds())
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: both branches are kept
+	if len(merged.Routes) != 2 {
+		t.Errorf("Expected both branches kept, got %v", merged.RouteOrder)
+	}
+}
+
+func TestWithNewestAgencyEdition(t *testing.T) {
+	// Given: a newer edition of an agency's feed, with a new phone number,
+	// listed before the older edition, which is merged first
+	newFeeds := func() []*gtfs.Feed {
+		var feeds []*gtfs.Feed
+		for _, edition := range []struct{ date, phone string }{{"20250101", "555-0199"}, {"20240101", "555-0100"}} {
+			feed := gtfstest.NewFeedBuilder().
+				Agency("metro", func(a *gtfs.Agency) { a.Phone = edition.phone }).
+				MustBuild()
+			feed.AddFeedInfo(&gtfs.FeedInfo{FeedID: edition.date, PublisherName: "Metro", PublisherURL: "http://example.com", Lang: "en", StartDate: edition.date})
+			feeds = append(feeds, feed)
+		}
+		return feeds
+	}
+	identity := WithDefaultDetection(strategy.DetectionIdentity)
+
+	// When: merged by default
+	merged, err := New(identity).MergeFeeds(newFeeds())
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: the older edition's phone, merged first, is kept
+	if phone := merged.Agencies["metro"].Phone; phone != "555-0100" {
+		t.Errorf("Expected the phone merged first kept, got %q", phone)
+	}
+
+	// When: merged preferring the newest edition
+	m := New(identity, WithNewestAgencyEdition(true))
+	if merged, err = m.MergeFeeds(newFeeds()); err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: the newer edition's phone wins, and the change is reported
+	if phone := merged.Agencies["metro"].Phone; phone != "555-0199" {
+		t.Errorf("Expected the newer phone, got %q", phone)
+	}
+	report := m.Report()
+	if len(report.AgencyFieldChanges) != 1 || report.AgencyFieldChanges[0].Field != "agency_phone" {
+		t.Fatalf("Expected the phone change reported, got %+v", report.AgencyFieldChanges)
+	}
+	if !slices.Contains(report.Warnings, report.AgencyFieldChanges[0].String()) {
+		t.Errorf("Expected a warning for the change, got %q", report.Warnings)
+	}
+	if report.Feeds[0].Edition.StartDate != "20250101" {
+		t.Errorf("Expected feed a's edition 20250101, got %+v", report.Feeds[0].Edition)
+	}
+}
+
+func TestWithServiceCollisionsAcrossFiles(t *testing.T) {
+	// Given: a feed defining svc only in calendar_dates.txt, merged after
+	// one defining it only in calendar.txt
+	feeds := func() []*gtfs.Feed {
+		feedA := gtfs.NewFeed()
+		feedA.AddCalendarDate(&gtfs.CalendarDate{ServiceID: "svc", Date: "20240704", ExceptionType: 1})
+		feedB := gtfs.NewFeed()
+		feedB.AddCalendar(&gtfs.Calendar{ServiceID: "svc", Monday: true, StartDate: "20240101", EndDate: "20241231"})
+		return []*gtfs.Feed{feedA, feedB}
+	}
+
+	for _, tt := range []struct {
+		across bool
+		want   gtfs.ServiceID
+	}{
+		{false, "svc"},
+		{true, "a-svc"},
+	} {
+		// When: merged without duplicate detection
+		merged, err := New(WithServiceCollisionsAcrossFiles(tt.across)).MergeFeeds(feeds())
+		if err != nil {
+			t.Fatalf("merge failed: %v", err)
+		}
+
+		// Then: the first feed's dates are combined with the calendar, or
+		// kept apart under a prefixed ID when services collide across files
+		if len(merged.CalendarDates[tt.want]) != 1 {
+			t.Errorf("Expected the date under %s with across=%v, got %v", tt.want, tt.across, merged.CalendarDateOrder)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package merge
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// ErrInvalidOverride indicates an override that names a file overrides
+// cannot be applied to, or no column
+var ErrInvalidOverride = errors.New("invalid override")
+
+// Override sets one column of one entity of an input feed before the feed
+// is merged, correcting known-bad upstream data so that duplicate detection
+// sees the corrected value
+type Override struct {
+	// File is the GTFS file of the entity: agency.txt, stops.txt,
+	// routes.txt, trips.txt, or the Fares v2 fare_leg_rules.txt,
+	// fare_products.txt, timeframes.txt, fare_media.txt or
+	// rider_categories.txt
+	File string
+
+	// ID is the entity's agency_id, stop_id, route_id, trip_id,
+	// leg_group_id, fare_product_id, timeframe_group_id, fare_media_id or
+	// rider_category_id. A leg group, fare product or timeframe group may
+	// have several rows, and the override applies to each of them.
+	ID string
+
+	// Column is the column set, e.g. stop_name; the ID column itself
+	// cannot be overridden
+	Column string
+
+	// Value is the column's new value, as it would be written in File
+	Value string
+}
+
+// String returns the override as FILE ID COLUMN
+func (o Override) String() string {
+	return fmt.Sprintf("%s %s %s", o.File, o.ID, o.Column)
+}
+
+// ReadOverrides reads overrides from CSV with the columns file, id, column
+// and value
+func ReadOverrides(r io.Reader) ([]Override, error) {
+	reader := gtfs.NewCSVReader(r)
+	header, err := reader.ReadHeader()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, column := range []string{"file", "id", "column", "value"} {
+		if !slices.Contains(header, column) {
+			return nil, fmt.Errorf("overrides: missing column %q", column)
+		}
+	}
+
+	var overrides []Override
+	for {
+		record, err := reader.ReadRecord()
+		if err == io.EOF {
+			return overrides, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := gtfs.NewCSVRow(header, record)
+		o := Override{
+			File:   row.Get("file"),
+			ID:     row.Get("id"),
+			Column: row.Get("column"),
+			Value:  row.Get("value"),
+		}
+		if _, ok := overrideColumns[o.File]; !ok {
+			return nil, fmt.Errorf("line %d: %w: %s: overrides do not apply to this file", reader.Line(), ErrInvalidOverride, o)
+		}
+		if o.Column == "" {
+			return nil, fmt.Errorf("line %d: %w: %s: column is required", reader.Line(), ErrInvalidOverride, o)
+		}
+		overrides = append(overrides, o)
+	}
+}
+
+// LoadOverrides reads overrides from the CSV file at path; see
+// ReadOverrides
+func LoadOverrides(path string) ([]Override, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return ReadOverrides(f)
+}
+
+// overrideResult is the outcome of applying overrides to a feed
+type overrideResult struct {
+	applied int
+	// skipped describes each override not applied, and why
+	skipped []string
+}
+
+// warnings describes the overrides skipped in the feed named feedName
+func (r overrideResult) warnings(feedName string) []string {
+	warnings := make([]string, len(r.skipped))
+	for i, s := range r.skipped {
+		warnings[i] = fmt.Sprintf("feed %s: override %s", feedName, s)
+	}
+	return warnings
+}
+
+// applyOverrides applies each override to feed in order, skipping those
+// whose entity or column is unknown or whose value cannot be parsed
+func applyOverrides(feed *gtfs.Feed, overrides []Override) overrideResult {
+	var result overrideResult
+	for _, o := range overrides {
+		if err := applyOverride(feed, o); err != nil {
+			result.skipped = append(result.skipped, fmt.Sprintf("%s: %v", o, err))
+			continue
+		}
+		result.applied++
+	}
+	return result
+}
+
+// applyOverride sets o's column of its entity in feed, on every row of an
+// entity with several
+func applyOverride(feed *gtfs.Feed, o Override) error {
+	var entities []any
+	var idColumn string
+	switch o.File {
+	case "agency.txt":
+		idColumn = "agency_id"
+		if a := feed.Agencies[gtfs.AgencyID(o.ID)]; a != nil {
+			entities = append(entities, a)
+		}
+	case "stops.txt":
+		idColumn = "stop_id"
+		if s := feed.Stops[gtfs.StopID(o.ID)]; s != nil {
+			entities = append(entities, s)
+		}
+	case "routes.txt":
+		idColumn = "route_id"
+		if r := feed.Routes[gtfs.RouteID(o.ID)]; r != nil {
+			entities = append(entities, r)
+		}
+	case "trips.txt":
+		idColumn = "trip_id"
+		if t := feed.Trips[gtfs.TripID(o.ID)]; t != nil {
+			entities = append(entities, t)
+		}
+	case "fare_leg_rules.txt":
+		idColumn = "leg_group_id"
+		for _, rule := range feed.FareLegRules {
+			if string(rule.LegGroupID) == o.ID {
+				entities = append(entities, rule)
+			}
+		}
+	case "fare_products.txt":
+		idColumn = "fare_product_id"
+		for _, product := range feed.FareProducts {
+			if string(product.ID) == o.ID {
+				entities = append(entities, product)
+			}
+		}
+	case "timeframes.txt":
+		idColumn = "timeframe_group_id"
+		for _, tf := range feed.Timeframes {
+			if string(tf.GroupID) == o.ID {
+				entities = append(entities, tf)
+			}
+		}
+	case "fare_media.txt":
+		idColumn = "fare_media_id"
+		if m := feed.FareMedia[gtfs.FareMediaID(o.ID)]; m != nil {
+			entities = append(entities, m)
+		}
+	case "rider_categories.txt":
+		idColumn = "rider_category_id"
+		if c := feed.RiderCategories[gtfs.RiderCategoryID(o.ID)]; c != nil {
+			entities = append(entities, c)
+		}
+	}
+	if len(entities) == 0 {
+		return fmt.Errorf("unknown %s %q", idColumn, o.ID)
+	}
+	set, ok := overrideColumns[o.File][o.Column]
+	if !ok {
+		return fmt.Errorf("unknown column %q", o.Column)
+	}
+	for _, entity := range entities {
+		if err := set(entity, o.Value); err != nil {
+			return fmt.Errorf("invalid value %q: %w", o.Value, err)
+		}
+	}
+	// The column may be absent from the input, which would leave it out
+	// of the written file
+	feed.AddColumn(o.File, o.Column)
+	return nil
+}
+
+// columnSetter parses a value into a column of an entity
+type columnSetter func(entity any, value string) error
+
+// overrideColumns maps each file overrides apply to, and each of its
+// columns other than the ID, to the setter of that column
+var overrideColumns = map[string]map[string]columnSetter{
+	"agency.txt": {
+		"agency_name":     setText(func(a *gtfs.Agency) *string { return &a.Name }),
+		"agency_url":      setText(func(a *gtfs.Agency) *string { return &a.URL }),
+		"agency_timezone": setText(func(a *gtfs.Agency) *string { return &a.Timezone }),
+		"agency_lang":     setText(func(a *gtfs.Agency) *string { return &a.Lang }),
+		"agency_phone":    setText(func(a *gtfs.Agency) *string { return &a.Phone }),
+		"agency_fare_url": setText(func(a *gtfs.Agency) *string { return &a.FareURL }),
+		"agency_email":    setText(func(a *gtfs.Agency) *string { return &a.Email }),
+	},
+	"stops.txt": {
+		"stop_code":           setText(func(s *gtfs.Stop) *string { return &s.Code }),
+		"stop_name":           setText(func(s *gtfs.Stop) *string { return &s.Name }),
+		"stop_desc":           setText(func(s *gtfs.Stop) *string { return &s.Desc }),
+		"stop_lat":            setFloat(func(s *gtfs.Stop) *float64 { return &s.Lat }),
+		"stop_lon":            setFloat(func(s *gtfs.Stop) *float64 { return &s.Lon }),
+		"zone_id":             setText(func(s *gtfs.Stop) *string { return &s.ZoneID }),
+		"stop_url":            setText(func(s *gtfs.Stop) *string { return &s.URL }),
+		"location_type":       setInt(func(s *gtfs.Stop) *int { return &s.LocationType }),
+		"parent_station":      setText(func(s *gtfs.Stop) *string { return (*string)(&s.ParentStation) }),
+		"stop_timezone":       setText(func(s *gtfs.Stop) *string { return &s.Timezone }),
+		"wheelchair_boarding": setInt(func(s *gtfs.Stop) *int { return &s.WheelchairBoarding }),
+		"level_id":            setText(func(s *gtfs.Stop) *string { return &s.LevelID }),
+		"platform_code":       setText(func(s *gtfs.Stop) *string { return &s.PlatformCode }),
+	},
+	"routes.txt": {
+		"agency_id":           setText(func(r *gtfs.Route) *string { return (*string)(&r.AgencyID) }),
+		"route_short_name":    setText(func(r *gtfs.Route) *string { return &r.ShortName }),
+		"route_long_name":     setText(func(r *gtfs.Route) *string { return &r.LongName }),
+		"route_desc":          setText(func(r *gtfs.Route) *string { return &r.Desc }),
+		"route_type":          setInt(func(r *gtfs.Route) *int { return &r.Type }),
+		"route_url":           setText(func(r *gtfs.Route) *string { return &r.URL }),
+		"route_color":         setText(func(r *gtfs.Route) *string { return &r.Color }),
+		"route_text_color":    setText(func(r *gtfs.Route) *string { return &r.TextColor }),
+		"route_sort_order":    setIntPtr(func(r *gtfs.Route) **int { return &r.SortOrder }),
+		"continuous_pickup":   setIntPtr(func(r *gtfs.Route) **int { return &r.ContinuousPickup }),
+		"continuous_drop_off": setIntPtr(func(r *gtfs.Route) **int { return &r.ContinuousDropOff }),
+		"network_id":          setText(func(r *gtfs.Route) *string { return (*string)(&r.NetworkID) }),
+	},
+	"trips.txt": {
+		"route_id":              setText(func(t *gtfs.Trip) *string { return (*string)(&t.RouteID) }),
+		"service_id":            setText(func(t *gtfs.Trip) *string { return (*string)(&t.ServiceID) }),
+		"trip_headsign":         setText(func(t *gtfs.Trip) *string { return &t.Headsign }),
+		"trip_short_name":       setText(func(t *gtfs.Trip) *string { return &t.ShortName }),
+		"direction_id":          setIntPtr(func(t *gtfs.Trip) **int { return &t.DirectionID }),
+		"block_id":              setText(func(t *gtfs.Trip) *string { return &t.BlockID }),
+		"shape_id":              setText(func(t *gtfs.Trip) *string { return (*string)(&t.ShapeID) }),
+		"wheelchair_accessible": setInt(func(t *gtfs.Trip) *int { return &t.WheelchairAccessible }),
+		"bikes_allowed":         setInt(func(t *gtfs.Trip) *int { return &t.BikesAllowed }),
+	},
+	"fare_leg_rules.txt": {
+		"network_id":              setText(func(r *gtfs.FareLegRule) *string { return (*string)(&r.NetworkID) }),
+		"from_area_id":            setText(func(r *gtfs.FareLegRule) *string { return (*string)(&r.FromAreaID) }),
+		"to_area_id":              setText(func(r *gtfs.FareLegRule) *string { return (*string)(&r.ToAreaID) }),
+		"from_timeframe_group_id": setText(func(r *gtfs.FareLegRule) *string { return (*string)(&r.FromTimeframeGroupID) }),
+		"to_timeframe_group_id":   setText(func(r *gtfs.FareLegRule) *string { return (*string)(&r.ToTimeframeGroupID) }),
+		"fare_product_id":         setText(func(r *gtfs.FareLegRule) *string { return (*string)(&r.FareProductID) }),
+		"rule_priority":           setIntPtr(func(r *gtfs.FareLegRule) **int { return &r.RulePriority }),
+	},
+	"fare_products.txt": {
+		"fare_product_name": setText(func(p *gtfs.FareProduct) *string { return &p.Name }),
+		"rider_category_id": setText(func(p *gtfs.FareProduct) *string { return (*string)(&p.RiderCategoryID) }),
+		"fare_media_id":     setText(func(p *gtfs.FareProduct) *string { return (*string)(&p.FareMediaID) }),
+		"amount":            setText(func(p *gtfs.FareProduct) *string { return &p.Amount }),
+		"currency":          setText(func(p *gtfs.FareProduct) *string { return &p.Currency }),
+	},
+	"timeframes.txt": {
+		"start_time": setText(func(tf *gtfs.Timeframe) *string { return &tf.StartTime }),
+		"end_time":   setText(func(tf *gtfs.Timeframe) *string { return &tf.EndTime }),
+		"service_id": setText(func(tf *gtfs.Timeframe) *string { return (*string)(&tf.ServiceID) }),
+	},
+	"fare_media.txt": {
+		"fare_media_name": setText(func(m *gtfs.FareMedia) *string { return &m.Name }),
+		"fare_media_type": setInt(func(m *gtfs.FareMedia) *int { return &m.Type }),
+	},
+	"rider_categories.txt": {
+		"rider_category_name":      setText(func(c *gtfs.RiderCategory) *string { return &c.Name }),
+		"is_default_fare_category": setIntPtr(func(c *gtfs.RiderCategory) **int { return &c.IsDefaultFareCategory }),
+		"eligibility_url":          setText(func(c *gtfs.RiderCategory) *string { return &c.EligibilityURL }),
+	},
+}
+
+// setText sets the text column field returns
+func setText[T any](field func(*T) *string) columnSetter {
+	return func(entity any, value string) error {
+		*field(entity.(*T)) = value
+		return nil
+	}
+}
+
+// setFloat sets the decimal column field returns
+func setFloat[T any](field func(*T) *float64) columnSetter {
+	return func(entity any, value string) error {
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return err
+		}
+		*field(entity.(*T)) = v
+		return nil
+	}
+}
+
+// setInt sets the integer column field returns; empty means 0
+func setInt[T any](field func(*T) *int) columnSetter {
+	return func(entity any, value string) error {
+		v := 0
+		if value = strings.TrimSpace(value); value != "" {
+			var err error
+			if v, err = strconv.Atoi(value); err != nil {
+				return err
+			}
+		}
+		*field(entity.(*T)) = v
+		return nil
+	}
+}
+
+// setIntPtr sets the optional integer column field returns; empty means
+// unset
+func setIntPtr[T any](field func(*T) **int) columnSetter {
+	return func(entity any, value string) error {
+		if value = strings.TrimSpace(value); value == "" {
+			*field(entity.(*T)) = nil
+			return nil
+		}
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*field(entity.(*T)) = &v
+		return nil
+	}
+}
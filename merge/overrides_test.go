@@ -0,0 +1,239 @@
+package merge
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfstest"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// overrideFeeds returns two feeds serving the same stop, named "Main St" in
+// the first and misspelled "Mian St" in the second
+func overrideFeeds() []*gtfs.Feed {
+	feeds := make([]*gtfs.Feed, 2)
+	for i, p := range []string{"a", "b"} {
+		name := "Main St"
+		if p == "b" {
+			name = "Mian St"
+		}
+		feeds[i] = gtfstest.NewFeedBuilder().
+			Agency(p+"-agency").
+			Stop(p+"-stop", 47.6, -122.3, func(s *gtfs.Stop) { s.Name = name }).
+			Route(p+"-route", func(r *gtfs.Route) { r.ShortName = p }).
+			Trip(p+"-trip", p+"-route", p+"-wkdy").
+			StopTimes(p+"-trip", "08:00", p+"-stop").
+			MustBuild()
+	}
+	return feeds
+}
+
+// writeOverrides writes content to an overrides file and returns its path
+func writeOverrides(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "overrides.csv")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write overrides: %v", err)
+	}
+	return path
+}
+
+func TestWithOverrides(t *testing.T) {
+	fuzzyStops := WithDetectionFor("stop", strategy.DetectionFuzzy)
+
+	t.Run("without overrides", func(t *testing.T) {
+		// Given: the misspelled name, which fuzzy matching requires to be equal
+		merged, err := New(fuzzyStops).MergeFeeds(overrideFeeds())
+		if err != nil {
+			t.Fatalf("MergeFeeds failed: %v", err)
+		}
+
+		// Then: the stops are kept apart
+		if len(merged.Stops) != 2 {
+			t.Errorf("Expected 2 stops, got %d", len(merged.Stops))
+		}
+	})
+
+	t.Run("with overrides", func(t *testing.T) {
+		// Given: overrides for the second feed correcting the name, plus
+		// ones naming a stop and a column that don't exist and one with a
+		// value that doesn't parse
+		path := writeOverrides(t, "file,id,column,value\n"+
+			"stops.txt,b-stop,stop_name,Main St\n"+
+			"stops.txt,b-nowhere,stop_name,Nowhere\n"+
+			"stops.txt,b-stop,stop_colour,red\n"+
+			"routes.txt,b-route,route_sort_order,not a number\n")
+		m := New(fuzzyStops, WithOverrides(1, path))
+
+		// When: merging
+		merged, err := m.MergeFeeds(overrideFeeds())
+		if err != nil {
+			t.Fatalf("MergeFeeds failed: %v", err)
+		}
+
+		// Then: the corrected stop and the first feed's are fuzzy matched,
+		// keeping the corrected name
+		if len(merged.Stops) != 1 || merged.Stops["b-stop"] == nil || merged.Stops["b-stop"].Name != "Main St" {
+			t.Fatalf("Expected only b-stop, named Main St, got %v", merged.Stops)
+		}
+		for _, st := range merged.StopTimes {
+			if st.StopID != "b-stop" {
+				t.Errorf("Expected stop time of %s at b-stop, got %s", st.TripID, st.StopID)
+			}
+		}
+
+		// And: the overrides applied are counted, and the others warned about
+		report := m.Report()
+		if report.Feeds[0].OverridesApplied != 0 || report.Feeds[1].OverridesApplied != 1 {
+			t.Errorf("Expected 0 and 1 overrides applied, got %d and %d", report.Feeds[0].OverridesApplied, report.Feeds[1].OverridesApplied)
+		}
+		want := []string{
+			`feed b: override stops.txt b-nowhere stop_name: unknown stop_id "b-nowhere"`,
+			`feed b: override stops.txt b-stop stop_colour: unknown column "stop_colour"`,
+			`feed b: override routes.txt b-route route_sort_order: invalid value "not a number": strconv.Atoi: parsing "not a number": invalid syntax`,
+		}
+		for _, w := range want {
+			if !slices.Contains(report.Warnings, w) {
+				t.Errorf("Expected warning %q, got %q", w, report.Warnings)
+			}
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := New(WithOverrides(0, filepath.Join(t.TempDir(), "missing.csv"))).MergeFeeds(overrideFeeds())
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected a not-exist error, got %v", err)
+		}
+	})
+}
+
+func TestApplyOverridesColumns(t *testing.T) {
+	// Given: one override of each kind of column
+	feed := overrideFeeds()[0]
+	result := applyOverrides(feed, []Override{
+		{File: "agency.txt", ID: "a-agency", Column: "agency_phone", Value: "555-0100"},
+		{File: "stops.txt", ID: "a-stop", Column: "stop_lat", Value: "47.61"},
+		{File: "stops.txt", ID: "a-stop", Column: "wheelchair_boarding", Value: "1"},
+		{File: "routes.txt", ID: "a-route", Column: "route_sort_order", Value: "3"},
+		{File: "trips.txt", ID: "a-trip", Column: "direction_id", Value: "1"},
+		{File: "trips.txt", ID: "a-trip", Column: "direction_id", Value: ""},
+	})
+
+	// Then: each is applied in order
+	if result.applied != 6 || len(result.skipped) != 0 {
+		t.Fatalf("Expected 6 overrides applied, got %+v", result)
+	}
+	if feed.Agencies["a-agency"].Phone != "555-0100" {
+		t.Errorf("Expected agency_phone 555-0100, got %q", feed.Agencies["a-agency"].Phone)
+	}
+	if stop := feed.Stops["a-stop"]; stop.Lat != 47.61 || stop.WheelchairBoarding != 1 {
+		t.Errorf("Expected stop_lat 47.61 and wheelchair_boarding 1, got %+v", stop)
+	}
+	if order := feed.Routes["a-route"].SortOrder; order == nil || *order != 3 {
+		t.Errorf("Expected route_sort_order 3, got %v", order)
+	}
+	if dir := feed.Trips["a-trip"].DirectionID; dir != nil {
+		t.Errorf("Expected direction_id unset by the later override, got %d", *dir)
+	}
+}
+
+func TestApplyOverridesFaresV2(t *testing.T) {
+	// Given: overrides of each Fares v2 file
+	feed, err := gtfs.ReadFromPath("../testdata/fares_v2")
+	if err != nil {
+		t.Fatalf("failed to read fares_v2: %v", err)
+	}
+	result := applyOverrides(feed, []Override{
+		{File: "fare_leg_rules.txt", ID: "express_leg", Column: "rule_priority", Value: "2"},
+		{File: "fare_products.txt", ID: "single_ride", Column: "fare_product_name", Value: "Single"},
+		{File: "timeframes.txt", ID: "peak", Column: "end_time", Value: "10:00:00"},
+		{File: "fare_media.txt", ID: "card", Column: "fare_media_type", Value: "3"},
+		{File: "rider_categories.txt", ID: "senior", Column: "eligibility_url", Value: "https://example.com/senior"},
+	})
+
+	// Then: each is applied, to every row of a fare product
+	if result.applied != 5 || len(result.skipped) != 0 {
+		t.Fatalf("Expected 5 overrides applied, got %+v", result)
+	}
+	if p := feed.FareLegRules[1].RulePriority; p == nil || *p != 2 {
+		t.Errorf("Expected rule_priority 2, got %v", p)
+	}
+	for _, product := range feed.FareProducts[:2] {
+		if product.Name != "Single" {
+			t.Errorf("Expected both single_ride rows renamed, got %q", product.Name)
+		}
+	}
+	if feed.FareProducts[2].Name != "Express Ride" {
+		t.Errorf("Expected express_ride to keep its name, got %q", feed.FareProducts[2].Name)
+	}
+	if feed.Timeframes[0].EndTime != "10:00:00" || feed.FareMedia["card"].Type != 3 {
+		t.Errorf("Expected end_time 10:00:00 and fare_media_type 3, got %q and %d", feed.Timeframes[0].EndTime, feed.FareMedia["card"].Type)
+	}
+	if url := feed.RiderCategories["senior"].EligibilityURL; url != "https://example.com/senior" {
+		t.Errorf("Expected the senior eligibility_url set, got %q", url)
+	}
+
+	// And: an unknown leg group is skipped
+	result = applyOverrides(feed, []Override{{File: "fare_leg_rules.txt", ID: "night_leg", Column: "rule_priority", Value: "1"}})
+	if result.applied != 0 || len(result.skipped) != 1 || !strings.Contains(result.skipped[0], `unknown leg_group_id "night_leg"`) {
+		t.Errorf("Expected the unknown leg group skipped, got %+v", result)
+	}
+}
+
+func TestOverrideColumnAbsentFromInputIsWritten(t *testing.T) {
+	// Given: a feed read from disk whose routes.txt has no route_sort_order
+	path := writeOverrides(t, "file,id,column,value\n"+
+		"routes.txt,route_a1,route_sort_order,7\n")
+	feed, err := gtfs.ReadFromPath("../testdata/simple_a")
+	if err != nil {
+		t.Fatalf("failed to read simple_a: %v", err)
+	}
+
+	// When: an override sets route_sort_order and the result is written
+	merged, err := New(WithOverrides(0, path)).MergeFeeds([]*gtfs.Feed{feed})
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+	output := filepath.Join(t.TempDir(), "merged.zip")
+	if err := gtfs.WriteToPath(merged, output); err != nil {
+		t.Fatalf("WriteToPath failed: %v", err)
+	}
+
+	// Then: reading it back finds the overridden value
+	written, err := gtfs.ReadFromPath(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if order := written.Routes["route_a1"].SortOrder; order == nil || *order != 7 {
+		t.Errorf("Expected route_sort_order 7 after writing, got %v", order)
+	}
+}
+
+func TestReadOverrides(t *testing.T) {
+	overrides, err := ReadOverrides(strings.NewReader("file,id,column,value\nroutes.txt,r1,route_long_name,\"Downtown, via 5th\"\n"))
+	if err != nil {
+		t.Fatalf("ReadOverrides failed: %v", err)
+	}
+	want := []Override{{File: "routes.txt", ID: "r1", Column: "route_long_name", Value: "Downtown, via 5th"}}
+	if !slices.Equal(overrides, want) {
+		t.Errorf("Expected %v, got %v", want, overrides)
+	}
+
+	for name, input := range map[string]string{
+		"missing column":   "file,id,column\nstops.txt,s1,stop_name\n",
+		"unsupported file": "file,id,column,value\nstop_times.txt,t1,stop_headsign,Downtown\n",
+		"no column":        "file,id,column,value\nstops.txt,s1,,Main St\n",
+	} {
+		if _, err := ReadOverrides(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := ReadOverrides(strings.NewReader("file,id,column,value\nstops.txt,s1,,x\n")); !errors.Is(err, ErrInvalidOverride) {
+		t.Errorf("Expected ErrInvalidOverride, got %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package merge
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// Stage is one merge of a Pipeline
+type Stage struct {
+	// Feeds are the feeds the stage merges after the previous stage's
+	// result, in input order
+	Feeds []*gtfs.Feed
+
+	// Names name the feeds in the report, by position; a feed without a
+	// name is lettered by its position among all the pipeline's inputs
+	Names []string
+
+	// Options configure the stage's merge. Options set per input, such as
+	// WithOverrides, index the stage's inputs, the first of which is the
+	// previous stage's result in every stage but the first.
+	Options []Option
+}
+
+// Pipeline merges the feeds of each stage in turn, under the stage's own
+// options, merging each stage's result as the first input of the next: for
+// example, two editions of one operator's feed merged with fuzzy detection,
+// and their result merged with another operator's feed with none. Results
+// are passed on in memory.
+//
+// IDs are prefixed on collision as in a single merge of all the pipeline's
+// inputs, by each input's position among them (see GetPrefixForIndex), the
+// last input of each stage unprefixed; a stage's result is prefixed in the
+// next stage as that last input would be.
+//
+// The report describes the last stage, except that Feeds describes every
+// input of the pipeline, in order, with IDMap taking each of its IDs to the
+// final feed's and StagePrefixes the prefixes later stages gave them;
+// Feed is the final feed, and Sources, which is also the final feed's,
+// indexes the same inputs; and Warnings collects every stage's. Stages holds each stage's
+// own report, in which the previous stage's result is named stageN.
+func Pipeline(stages []Stage) (*gtfs.Feed, *Report, error) {
+	return PipelineContext(context.Background(), stages)
+}
+
+// PipelineContext is like Pipeline but can be canceled through ctx
+func PipelineContext(ctx context.Context, stages []Stage) (*gtfs.Feed, *Report, error) {
+	if len(stages) == 0 {
+		return nil, nil, ErrNoInputFeeds
+	}
+
+	var merged *gtfs.Feed
+	var inputs []FeedReport
+	var stageReports []*Report
+	var warnings []string
+	for n, stage := range stages {
+		if len(stage.Feeds) == 0 {
+			return nil, nil, fmt.Errorf("%w: stage %d", ErrNoInputFeeds, n+1)
+		}
+
+		// offset is the position among the pipeline's inputs of the
+		// stage's first feed
+		offset := len(inputs)
+		var feeds []*gtfs.Feed
+		var names, prefixes []string
+		if merged != nil {
+			feeds = append(feeds, merged)
+			names = append(names, fmt.Sprintf("stage%d", n))
+			prefixes = append(prefixes, GetPrefixForIndex(offset))
+		}
+		for i, feed := range stage.Feeds {
+			name := feedNameForIndex(offset + i)
+			if i < len(stage.Names) && stage.Names[i] != "" {
+				name = stage.Names[i]
+			}
+			feeds = append(feeds, feed)
+			names = append(names, name)
+			prefixes = append(prefixes, GetPrefixForIndex(offset+i+1))
+		}
+		prefixes[len(prefixes)-1] = ""
+
+		indexes := make([]int, len(feeds))
+		for i := range indexes {
+			indexes[i] = i
+		}
+		m := New(append(slices.Clone(stage.Options), withFeedPrefixes(prefixes))...)
+		if err := m.checkOptions(len(feeds)); err != nil {
+			return nil, nil, fmt.Errorf("stage %d: %w", n+1, err)
+		}
+		result, report, err := m.mergeFeeds(ctx, feeds, names, indexes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("stage %d: %w", n+1, err)
+		}
+
+		first := 0
+		if merged != nil {
+			first = 1
+			through := report.Feeds[0].IDMap
+			for i := range inputs {
+				inputs[i].IDMap = composeIDMaps(inputs[i].IDMap, through)
+				inputs[i].StagePrefixes = append(inputs[i].StagePrefixes, report.Feeds[0].Prefix)
+			}
+			result.Sources = pipelineSources(result.Sources, merged.Sources, through, offset)
+		}
+		for _, fr := range report.Feeds[first:] {
+			fr.Index = offset + fr.Index - first
+			inputs = append(inputs, fr)
+		}
+		merged = result
+		stageReports = append(stageReports, report)
+		warnings = append(warnings, report.Warnings...)
+	}
+
+	report := *stageReports[len(stageReports)-1]
+	report.Feeds = inputs
+	report.Sources = merged.Sources
+	report.Feed = merged
+	report.Warnings = warnings
+	report.Stages = stageReports
+	return merged, &report, nil
+}
+
+// withFeedPrefixes sets the prefix applied on collision to each feed
+// merged, by position, in place of the default lettering
+func withFeedPrefixes(prefixes []string) Option {
+	return func(m *Merger) {
+		m.feedPrefixes = prefixes
+	}
+}
+
+// composeIDMaps returns the ID map taking each ID of ids through then, by
+// kind; IDs then leaves out, whose entities did not survive, are dropped
+func composeIDMaps(ids, then map[gtfs.EntityKind]map[string]string) map[gtfs.EntityKind]map[string]string {
+	composed := make(map[gtfs.EntityKind]map[string]string, len(ids))
+	for kind, byID := range ids {
+		composed[kind] = make(map[string]string, len(byID))
+		for from, via := range byID {
+			if to, ok := then[kind][via]; ok {
+				composed[kind][from] = to
+			}
+		}
+	}
+	return composed
+}
+
+// pipelineSources returns the provenance of a stage's result by pipeline
+// input, from sources, its provenance by stage input. Stage input 0 is the
+// previous stage's result, whose own provenance is previous and whose IDs
+// through maps to the result's; stage input i > 0 is pipeline input
+// offset+i-1.
+func pipelineSources(sources, previous map[gtfs.EntityKind]map[string][]int, through map[gtfs.EntityKind]map[string]string, offset int) map[gtfs.EntityKind]map[string][]int {
+	var feed gtfs.Feed
+	for kind, ids := range through {
+		for from, to := range ids {
+			for _, index := range previous[kind][from] {
+				feed.AddSource(kind, to, index)
+			}
+		}
+	}
+	for kind, ids := range sources {
+		for id, indexes := range ids {
+			for _, index := range indexes {
+				if index > 0 {
+					feed.AddSource(kind, id, offset+index-1)
+				}
+			}
+		}
+	}
+	return feed.Sources
+}
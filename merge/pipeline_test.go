@@ -0,0 +1,77 @@
+package merge
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+func TestPipeline(t *testing.T) {
+	// Given: two editions of one operator's feed, fuzzy_similar and
+	// simple_a, to be merged with fuzzy detection, then another operator's
+	// feed, overlap, whose IDs collide with simple_a's, with none
+	feeds := readFixtures(t, "fuzzy_similar", "simple_a", "overlap")
+	stages := []Stage{
+		{Feeds: feeds[:2], Options: []Option{WithDefaultDetection(strategy.DetectionFuzzy)}},
+		{Feeds: feeds[2:], Names: []string{"other"}, Options: []Option{WithDefaultDetection(strategy.DetectionNone)}},
+	}
+
+	// When: run as a pipeline
+	merged, report, err := Pipeline(stages)
+	if err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+
+	// Then: the editions' stops merge in the first stage, and the other
+	// operator's colliding stops are kept apart in the second, the first
+	// stage's result prefixed as simple_a would be
+	if merged.Stops["b-stop_a1"] == nil || merged.Stops["stop_a1"] == nil || merged.Stops["fuzzy_stop1"] != nil {
+		t.Fatalf("Expected b-stop_a1 and stop_a1, got %v", merged.StopOrder)
+	}
+
+	// And: every input is reported, each stop traced to the final feed
+	if len(report.Feeds) != 3 || report.Feeds[2].Name != "other" || report.Feeds[2].Index != 2 {
+		t.Fatalf("Expected 3 inputs, the last named other, got %+v", report.Feeds)
+	}
+	traces := []struct {
+		input    int
+		from, to string
+	}{
+		{0, "fuzzy_stop1", "b-stop_a1"},
+		{0, "fuzzy_stop3", "stop_a3"},
+		{1, "stop_a1", "b-stop_a1"},
+		{1, "stop_a3", "stop_a3"},
+		{2, "stop_a1", "stop_a1"},
+	}
+	for _, tr := range traces {
+		if got := report.Feeds[tr.input].IDMap[gtfs.KindStop][tr.from]; got != tr.to {
+			t.Errorf("Expected input %d's stop %s to map to %s, got %q", tr.input, tr.from, tr.to, got)
+		}
+	}
+
+	// And: provenance indexes the pipeline's inputs
+	if got := merged.SourceOf(gtfs.KindStop, "b-stop_a1"); !slices.Equal(got, []int{0, 1}) {
+		t.Errorf("Expected b-stop_a1 from inputs 0 and 1, got %v", got)
+	}
+	if got := report.Sources[gtfs.KindStop]["stop_a1"]; !slices.Equal(got, []int{2}) {
+		t.Errorf("Expected stop_a1 from input 2, got %v", got)
+	}
+	if got := report.Sources[gtfs.KindTrip]["b-trip_a1"]; !slices.Equal(got, []int{1}) {
+		t.Errorf("Expected b-trip_a1 from input 1, got %v", got)
+	}
+
+	// And: each stage's own report is kept, the first stage's result
+	// named stage1 in the second
+	if len(report.Stages) != 2 || report.Stages[1].Feeds[0].Name != "stage1" || report.Stages[1].Feeds[0].Prefix != "b-" {
+		t.Errorf("Expected 2 stage reports, the second merging stage1 prefixed b-, got %+v", report.Stages)
+	}
+
+	t.Run("empty stage", func(t *testing.T) {
+		if _, _, err := Pipeline([]Stage{{Feeds: feeds[:1]}, {}}); !errors.Is(err, ErrNoInputFeeds) {
+			t.Errorf("Expected ErrNoInputFeeds, got %v", err)
+		}
+	})
+}
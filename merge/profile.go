@@ -0,0 +1,105 @@
+package merge
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/internal/enumtext"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// Profile is a preset of duplicate detection modes for a common kind of
+// merge; see WithProfile
+type Profile int
+
+const (
+	// ProfileConcatenate - nothing is treated as a duplicate, so every
+	// input's entities are kept, and every ID is prefixed with its input's
+	// letter (see WithAlwaysPrefix)
+	ProfileConcatenate Profile = iota
+
+	// ProfileSameAgencyEditions - everything is matched by identity, for
+	// merging editions of one agency's feed that share IDs
+	ProfileSameAgencyEditions
+
+	// ProfileRegionalIntegration - agencies, stops and routes are matched
+	// fuzzily, services by identity, and everything else, trips included,
+	// is kept, for combining the feeds of neighboring agencies
+	ProfileRegionalIntegration
+)
+
+// String returns the string representation of Profile
+func (p Profile) String() string {
+	switch p {
+	case ProfileConcatenate:
+		return "concatenate"
+	case ProfileSameAgencyEditions:
+		return "same-agency-editions"
+	case ProfileRegionalIntegration:
+		return "regional-integration"
+	default:
+		return fmt.Sprintf("Profile(%d)", p)
+	}
+}
+
+// ParseProfile parses a string into a Profile value. "editions" and
+// "regional" are accepted as short names.
+func ParseProfile(s string) (Profile, error) {
+	switch strings.ToLower(s) {
+	case "concatenate":
+		return ProfileConcatenate, nil
+	case "same-agency-editions", "editions":
+		return ProfileSameAgencyEditions, nil
+	case "regional-integration", "regional":
+		return ProfileRegionalIntegration, nil
+	default:
+		return ProfileConcatenate, fmt.Errorf("invalid profile: %q", s)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (p Profile) MarshalText() ([]byte, error) {
+	return enumtext.Marshal(p, ParseProfile)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (p *Profile) UnmarshalText(text []byte) error {
+	return enumtext.Unmarshal(p, text, ParseProfile)
+}
+
+// detection returns the default detection mode of p and the entity kinds
+// it gives other modes
+func (p Profile) detection() (strategy.DuplicateDetection, map[gtfs.EntityKind]strategy.DuplicateDetection) {
+	switch p {
+	case ProfileSameAgencyEditions:
+		return strategy.DetectionIdentity, nil
+	case ProfileRegionalIntegration:
+		return strategy.DetectionNone, map[gtfs.EntityKind]strategy.DuplicateDetection{
+			gtfs.KindAgency:  strategy.DetectionFuzzy,
+			gtfs.KindStop:    strategy.DetectionFuzzy,
+			gtfs.KindRoute:   strategy.DetectionFuzzy,
+			gtfs.KindService: strategy.DetectionIdentity,
+		}
+	default:
+		return strategy.DetectionNone, nil
+	}
+}
+
+// WithProfile sets the duplicate detection mode of every file to p's, and
+// WithAlwaysPrefix to whether p is ProfileConcatenate. Options after it
+// override it as they would any earlier option, e.g. WithDefaultDetection
+// replaces its detection outright; WithDetectionFor takes precedence
+// wherever it is given.
+func WithProfile(p Profile) Option {
+	return func(m *Merger) {
+		m.alwaysPrefix = p == ProfileConcatenate
+		d, kinds := p.detection()
+		m.SetDuplicateDetectionForAll(d)
+		for kind, d := range kinds {
+			for _, filename := range entityKindFiles[kind] {
+				m.SetDuplicateDetectionForFile(filename, d)
+			}
+		}
+	}
+}
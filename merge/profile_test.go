@@ -0,0 +1,163 @@
+package merge
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// readFixtures reads the named feeds from testdata
+func readFixtures(t *testing.T, dirs ...string) []*gtfs.Feed {
+	t.Helper()
+	var feeds []*gtfs.Feed
+	for _, dir := range dirs {
+		feed, err := gtfs.ReadFromPath(filepath.Join("..", "testdata", dir))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", dir, err)
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds
+}
+
+func TestWithProfile(t *testing.T) {
+	// Merging simple_a with itself tells identity from no detection, and
+	// simple_a with fuzzy_similar, whose stops and agency match simple_a's
+	// by properties under other IDs, tells fuzzy from identity
+	tests := []struct {
+		profile Profile
+		inputs  []string
+		want    map[string]int
+	}{
+		{ProfileConcatenate, []string{"simple_a", "simple_a"}, map[string]int{
+			"agency.txt": 4, "calendar.txt": 2, "routes.txt": 4, "stops.txt": 10, "trips.txt": 8, "stop_times.txt": 20,
+		}},
+		{ProfileConcatenate, []string{"simple_a", "fuzzy_similar"}, map[string]int{
+			"agency.txt": 3, "calendar.txt": 2, "routes.txt": 3, "stops.txt": 8, "trips.txt": 5, "stop_times.txt": 13,
+		}},
+		{ProfileSameAgencyEditions, []string{"simple_a", "simple_a"}, map[string]int{
+			"agency.txt": 2, "calendar.txt": 1, "routes.txt": 2, "stops.txt": 5, "trips.txt": 4, "stop_times.txt": 10,
+		}},
+		{ProfileSameAgencyEditions, []string{"simple_a", "fuzzy_similar"}, map[string]int{
+			"agency.txt": 3, "calendar.txt": 2, "routes.txt": 3, "stops.txt": 8, "trips.txt": 5, "stop_times.txt": 13,
+		}},
+		{ProfileRegionalIntegration, []string{"simple_a", "simple_a"}, map[string]int{
+			"agency.txt": 2, "calendar.txt": 1, "routes.txt": 2, "stops.txt": 5, "trips.txt": 8, "stop_times.txt": 20,
+		}},
+		{ProfileRegionalIntegration, []string{"simple_a", "fuzzy_similar"}, map[string]int{
+			"agency.txt": 2, "calendar.txt": 2, "routes.txt": 3, "stops.txt": 5, "trips.txt": 5, "stop_times.txt": 13,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile.String()+"/"+tt.inputs[1], func(t *testing.T) {
+			merged, err := New(WithProfile(tt.profile)).MergeFeeds(readFixtures(t, tt.inputs...))
+			if err != nil {
+				t.Fatalf("MergeFeeds failed: %v", err)
+			}
+			if got := merged.RowCounts(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestProfileConcatenateAlwaysPrefixes(t *testing.T) {
+	// Given: simple_a and fuzzy_similar, whose IDs don't collide
+	feeds := readFixtures(t, "simple_a", "fuzzy_similar")
+
+	// When: merged with the concatenate profile
+	merged, err := New(WithProfile(ProfileConcatenate)).MergeFeeds(feeds)
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: every ID carries its input's letter, the last input's too
+	for _, id := range merged.StopOrder {
+		if !strings.HasPrefix(string(id), "a-") && !strings.HasPrefix(string(id), "b-") {
+			t.Errorf("Expected stop %q to be prefixed", id)
+		}
+	}
+	for id, trip := range merged.Trips {
+		if !strings.HasPrefix(string(id), "a-") && !strings.HasPrefix(string(id), "b-") {
+			t.Errorf("Expected trip %q to be prefixed", id)
+		}
+		if _, ok := merged.Routes[trip.RouteID]; !ok {
+			t.Errorf("Expected trip %q's route %q to be merged", id, trip.RouteID)
+		}
+	}
+
+	// And: a later profile turns it off
+	merged, err = New(WithProfile(ProfileConcatenate), WithProfile(ProfileSameAgencyEditions)).MergeFeeds(readFixtures(t, "simple_a", "fuzzy_similar"))
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+	if _, ok := merged.Stops["stop_a1"]; !ok {
+		t.Errorf("Expected stop_a1 unprefixed, got %v", merged.StopOrder)
+	}
+}
+
+func TestWithProfileOverrides(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want map[string]strategy.DuplicateDetection
+	}{
+		{
+			name: "profile alone",
+			opts: []Option{WithProfile(ProfileRegionalIntegration)},
+			want: map[string]strategy.DuplicateDetection{
+				"agency": strategy.DetectionFuzzy, "stop": strategy.DetectionFuzzy, "route": strategy.DetectionFuzzy,
+				"calendar.txt": strategy.DetectionIdentity, "calendar_dates.txt": strategy.DetectionIdentity,
+				"trip": strategy.DetectionNone, "shape": strategy.DetectionNone,
+			},
+		},
+		{
+			name: "later default detection wins",
+			opts: []Option{WithProfile(ProfileRegionalIntegration), WithDefaultDetection(strategy.DetectionIdentity)},
+			want: map[string]strategy.DuplicateDetection{"stop": strategy.DetectionIdentity, "trip": strategy.DetectionIdentity},
+		},
+		{
+			name: "later profile wins",
+			opts: []Option{WithDefaultDetection(strategy.DetectionFuzzy), WithProfile(ProfileSameAgencyEditions)},
+			want: map[string]strategy.DuplicateDetection{"stop": strategy.DetectionIdentity, "trip": strategy.DetectionIdentity},
+		},
+		{
+			name: "per-entity detection wins",
+			opts: []Option{WithDetectionFor("trip", strategy.DetectionIdentity), WithProfile(ProfileRegionalIntegration)},
+			want: map[string]strategy.DuplicateDetection{"stop": strategy.DetectionFuzzy, "trip": strategy.DetectionIdentity},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(tt.opts...)
+			for entity, want := range tt.want {
+				if got, _ := m.DetectionFor(entity); got != want {
+					t.Errorf("Expected %s detection %v, got %v", entity, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestParseProfile(t *testing.T) {
+	for input, want := range map[string]Profile{
+		"concatenate":          ProfileConcatenate,
+		"editions":             ProfileSameAgencyEditions,
+		"Regional":             ProfileRegionalIntegration,
+		"regional-integration": ProfileRegionalIntegration,
+	} {
+		if got, err := ParseProfile(input); err != nil || got != want {
+			t.Errorf("ParseProfile(%q) = %v, %v, want %v", input, got, err, want)
+		}
+	}
+	if _, err := ParseProfile("merge-everything"); err == nil {
+		t.Error("expected an error for an unknown profile")
+	}
+	testEnumText(t, []Profile{ProfileConcatenate, ProfileSameAgencyEditions, ProfileRegionalIntegration})
+}
@@ -0,0 +1,186 @@
+package merge
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// recordSources records the input feed at index as a source of every entity
+// its merge context mapped into the target, including duplicates merged
+// into existing entities
+func recordSources(target *gtfs.Feed, ctx *strategy.MergeContext, index int) {
+	for _, id := range ctx.AgencyIDMapping {
+		target.AddSource(gtfs.KindAgency, string(id), index)
+	}
+	for _, id := range ctx.StopIDMapping {
+		target.AddSource(gtfs.KindStop, string(id), index)
+	}
+	for _, id := range ctx.RouteIDMapping {
+		target.AddSource(gtfs.KindRoute, string(id), index)
+	}
+	for _, id := range ctx.TripIDMapping {
+		target.AddSource(gtfs.KindTrip, string(id), index)
+	}
+	for _, id := range ctx.ServiceIDMapping {
+		target.AddSource(gtfs.KindService, string(id), index)
+	}
+	for _, id := range ctx.ShapeIDMapping {
+		target.AddSource(gtfs.KindShape, string(id), index)
+	}
+	for _, id := range ctx.FareIDMapping {
+		target.AddSource(gtfs.KindFare, string(id), index)
+	}
+	for _, id := range ctx.AreaIDMapping {
+		target.AddSource(gtfs.KindArea, string(id), index)
+	}
+	for _, id := range ctx.NetworkIDMapping {
+		target.AddSource(gtfs.KindNetwork, string(id), index)
+	}
+	for _, id := range ctx.LegGroupIDMapping {
+		target.AddSource(gtfs.KindLegGroup, string(id), index)
+	}
+	for _, id := range ctx.FareProductIDMapping {
+		target.AddSource(gtfs.KindFareProduct, string(id), index)
+	}
+	for _, id := range ctx.TimeframeGroupIDMapping {
+		target.AddSource(gtfs.KindTimeframeGroup, string(id), index)
+	}
+	for _, id := range ctx.FareMediaIDMapping {
+		target.AddSource(gtfs.KindFareMedia, string(id), index)
+	}
+	for _, id := range ctx.RiderCategoryIDMapping {
+		target.AddSource(gtfs.KindRiderCategory, string(id), index)
+	}
+}
+
+// idMap returns, by kind, the ID in the target of each ID-keyed entity of
+// ctx's source feed, keyed by its ID in the source
+func idMap(ctx *strategy.MergeContext) map[gtfs.EntityKind]map[string]string {
+	ids := make(map[gtfs.EntityKind]map[string]string, len(gtfs.EntityKinds))
+	add := func(kind gtfs.EntityKind, from, to string) {
+		if ids[kind] == nil {
+			ids[kind] = make(map[string]string)
+		}
+		ids[kind][from] = to
+	}
+	for from, to := range ctx.AgencyIDMapping {
+		add(gtfs.KindAgency, string(from), string(to))
+	}
+	for from, to := range ctx.StopIDMapping {
+		add(gtfs.KindStop, string(from), string(to))
+	}
+	for from, to := range ctx.RouteIDMapping {
+		add(gtfs.KindRoute, string(from), string(to))
+	}
+	for from, to := range ctx.TripIDMapping {
+		add(gtfs.KindTrip, string(from), string(to))
+	}
+	for from, to := range ctx.ServiceIDMapping {
+		add(gtfs.KindService, string(from), string(to))
+	}
+	for from, to := range ctx.ShapeIDMapping {
+		add(gtfs.KindShape, string(from), string(to))
+	}
+	for from, to := range ctx.FareIDMapping {
+		add(gtfs.KindFare, string(from), string(to))
+	}
+	for from, to := range ctx.AreaIDMapping {
+		add(gtfs.KindArea, string(from), string(to))
+	}
+	for from, to := range ctx.NetworkIDMapping {
+		add(gtfs.KindNetwork, string(from), string(to))
+	}
+	for from, to := range ctx.LegGroupIDMapping {
+		add(gtfs.KindLegGroup, string(from), string(to))
+	}
+	for from, to := range ctx.FareProductIDMapping {
+		add(gtfs.KindFareProduct, string(from), string(to))
+	}
+	for from, to := range ctx.TimeframeGroupIDMapping {
+		add(gtfs.KindTimeframeGroup, string(from), string(to))
+	}
+	for from, to := range ctx.FareMediaIDMapping {
+		add(gtfs.KindFareMedia, string(from), string(to))
+	}
+	for from, to := range ctx.RiderCategoryIDMapping {
+		add(gtfs.KindRiderCategory, string(from), string(to))
+	}
+	return ids
+}
+
+// dropDeletedIDs removes from each feed's ID map the entities deleted from
+// the merged feed after they were mapped, e.g. by pruning, which are
+// those no longer in its provenance
+func (r *Report) dropDeletedIDs(sources map[gtfs.EntityKind]map[string][]int) {
+	for _, feed := range r.Feeds {
+		for kind, ids := range feed.IDMap {
+			for from, to := range ids {
+				if _, ok := sources[kind][to]; !ok {
+					delete(ids, from)
+				}
+			}
+		}
+	}
+}
+
+// WriteIDMapCSV writes the feed's ID map for kind (see FeedReport.IDMap) as
+// CSV with the columns original_id and merged_id, sorted by original ID
+func (f *FeedReport) WriteIDMapCSV(w io.Writer, kind gtfs.EntityKind) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"original_id", "merged_id"}); err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(f.IDMap[kind]))
+	for id := range f.IDMap[kind] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if err := cw.Write([]string{id, f.IDMap[kind][id]}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteProvenanceCSV writes the provenance of the merged feed (see
+// gtfs.Feed.SourceOf) as CSV with the columns entity_kind, entity_id,
+// feed_index and feed_name: one row per contributing input feed, sorted by
+// kind, ID and feed index
+func (r *Report) WriteProvenanceCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"entity_kind", "entity_id", "feed_index", "feed_name"}); err != nil {
+		return err
+	}
+
+	for _, kind := range gtfs.EntityKinds {
+		ids := make([]string, 0, len(r.Sources[kind]))
+		for id := range r.Sources[kind] {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			for _, index := range r.Sources[kind][id] {
+				var name string
+				if index < len(r.Feeds) {
+					name = r.Feeds[index].Name
+				}
+				if err := cw.Write([]string{string(kind), id, strconv.Itoa(index), name}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
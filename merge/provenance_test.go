@@ -0,0 +1,161 @@
+package merge
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+func TestMergeRecordsProvenance(t *testing.T) {
+	// Given: simple_a and overlap, which share stop_a1 and trip_a1
+	feedA, err := gtfs.ReadFromPath("../testdata/simple_a")
+	if err != nil {
+		t.Fatalf("failed to read simple_a: %v", err)
+	}
+	feedB, err := gtfs.ReadFromPath("../testdata/overlap")
+	if err != nil {
+		t.Fatalf("failed to read overlap: %v", err)
+	}
+
+	// When: merged with identity detection
+	m := New(WithDefaultDetection(strategy.DetectionIdentity))
+	merged, err := m.MergeFeeds([]*gtfs.Feed{feedA, feedB})
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: deduplicated entities list both feeds
+	if got := merged.SourceOf(gtfs.KindStop, "stop_a1"); !reflect.DeepEqual(got, []int{0, 1}) {
+		t.Errorf("expected stop_a1 from feeds [0 1], got %v", got)
+	}
+	if got := merged.SourceOf(gtfs.KindTrip, "trip_a1"); !reflect.DeepEqual(got, []int{0, 1}) {
+		t.Errorf("expected trip_a1 from feeds [0 1], got %v", got)
+	}
+
+	// And: entities from one feed list only it
+	if got := merged.SourceOf(gtfs.KindStop, "stop_a3"); !reflect.DeepEqual(got, []int{0}) {
+		t.Errorf("expected stop_a3 from feed [0], got %v", got)
+	}
+
+	// And: every merged stop has a source
+	for id := range merged.Stops {
+		if len(merged.SourceOf(gtfs.KindStop, string(id))) == 0 {
+			t.Errorf("stop %s has no recorded source", id)
+		}
+	}
+}
+
+func TestMergeProvenancePrefixedEntities(t *testing.T) {
+	// Given: two feeds that collide without detection
+	merged, err := New().MergeFeeds([]*gtfs.Feed{minimalFeed(t), minimalFeed(t)})
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: the unprefixed copy came from the last feed and the prefixed
+	// copy from the first
+	if got := merged.SourceOf(gtfs.KindRoute, "route1"); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("expected route1 from feed [1], got %v", got)
+	}
+	if got := merged.SourceOf(gtfs.KindRoute, "a-route1"); !reflect.DeepEqual(got, []int{0}) {
+		t.Errorf("expected a-route1 from feed [0], got %v", got)
+	}
+}
+
+func TestReportWriteProvenanceCSV(t *testing.T) {
+	m := New()
+	if _, err := m.MergeFeeds([]*gtfs.Feed{minimalFeed(t), minimalFeed(t)}); err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := m.Report().WriteProvenanceCSV(&buf); err != nil {
+		t.Fatalf("WriteProvenanceCSV failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	if lines[0] != "entity_kind,entity_id,feed_index,feed_name" {
+		t.Errorf("unexpected header: %q", lines[0])
+	}
+	// Agencies come first, sorted by ID
+	if lines[1] != "agency,a-agency1,0,a" || lines[2] != "agency,agency1,1,b" {
+		t.Errorf("unexpected agency rows: %q, %q", lines[1], lines[2])
+	}
+}
+
+func TestReportIDMap(t *testing.T) {
+	// Given: simple_a and fuzzy_similar, whose first three stops match
+	// simple_a's by properties under other IDs
+	m := New(WithProfile(ProfileRegionalIntegration))
+	merged, err := m.MergeFeeds(readFixtures(t, "simple_a", "fuzzy_similar"))
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+	report := m.Report()
+
+	// Then: duplicates map to the entity they were merged into, and other
+	// entities to themselves
+	stops := report.Feeds[0].IDMap[gtfs.KindStop]
+	want := map[string]string{"stop_a1": "fuzzy_stop1", "stop_a2": "fuzzy_stop2", "stop_a3": "fuzzy_stop3", "stop_a4": "stop_a4", "stop_a5": "stop_a5"}
+	if !reflect.DeepEqual(stops, want) {
+		t.Errorf("Expected stop IDs %v, got %v", want, stops)
+	}
+	if got := report.Feeds[1].IDMap[gtfs.KindStop]["fuzzy_stop1"]; got != "fuzzy_stop1" {
+		t.Errorf("Expected fuzzy_stop1 to keep its ID, got %q", got)
+	}
+
+	// And: every feed's trips map to merged trips
+	for _, feed := range report.Feeds {
+		if len(feed.IDMap[gtfs.KindTrip]) == 0 {
+			t.Errorf("feed %s: expected trip IDs", feed.Name)
+		}
+		for from, to := range feed.IDMap[gtfs.KindTrip] {
+			if merged.Trips[gtfs.TripID(to)] == nil {
+				t.Errorf("feed %s: trip %s maps to %s, which is not merged", feed.Name, from, to)
+			}
+		}
+	}
+}
+
+func TestReportIDMapPrefixedAndPruned(t *testing.T) {
+	// Given: two copies of the minimal feed, the first with a stop nothing
+	// references
+	first := minimalFeed(t)
+	first.AddStop(&gtfs.Stop{ID: "unused", Name: "Unused", Lat: 37.7, Lon: -122.4})
+
+	// When: merged without detection, pruning stops
+	m := New(WithPruneUnreferenced("stop"))
+	if _, err := m.MergeFeeds([]*gtfs.Feed{first, minimalFeed(t)}); err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: the first feed's colliding stop maps to its prefixed ID, and
+	// the pruned stop is left out
+	want := map[string]string{"stop1": "a-stop1"}
+	if got := m.Report().Feeds[0].IDMap[gtfs.KindStop]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected stop IDs %v, got %v", want, got)
+	}
+
+	// And: the map is written as CSV sorted by original ID
+	var buf bytes.Buffer
+	if err := m.Report().Feeds[0].WriteIDMapCSV(&buf, gtfs.KindRoute); err != nil {
+		t.Fatalf("WriteIDMapCSV failed: %v", err)
+	}
+	if got := buf.String(); got != "original_id,merged_id\nroute1,a-route1\n" {
+		t.Errorf("Unexpected CSV: %q", got)
+	}
+}
+
+// minimalFeed reads the minimal fixture
+func minimalFeed(t *testing.T) *gtfs.Feed {
+	t.Helper()
+	feed, err := gtfs.ReadFromPath("../testdata/minimal")
+	if err != nil {
+		t.Fatalf("failed to read minimal: %v", err)
+	}
+	return feed
+}
@@ -0,0 +1,281 @@
+package merge
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// ErrUnknownPruneKind indicates WithPruneUnreferenced was given a kind of
+// entity that cannot be pruned
+var ErrUnknownPruneKind = errors.New("unknown prune kind")
+
+// pruneKinds maps the names accepted by ParsePruneKind to entity kinds
+var pruneKinds = map[string]gtfs.EntityKind{
+	"stops":    gtfs.KindStop,
+	"shapes":   gtfs.KindShape,
+	"services": gtfs.KindService,
+	"agencies": gtfs.KindAgency,
+	"areas":    gtfs.KindArea,
+}
+
+// ParsePruneKind parses the name of a kind of entity that can be pruned:
+// stops, shapes, services, agencies or areas (or the singular entity kind,
+// e.g. "stop")
+func ParsePruneKind(s string) (gtfs.EntityKind, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if kind, ok := pruneKinds[s]; ok {
+		return kind, nil
+	}
+	for _, kind := range pruneKinds {
+		if string(kind) == s {
+			return kind, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q (must be stops, shapes, services, agencies, or areas)", ErrUnknownPruneKind, s)
+}
+
+// parsePruneKinds parses each name with ParsePruneKind
+func parsePruneKinds(names []string) ([]gtfs.EntityKind, error) {
+	kinds := make([]gtfs.EntityKind, 0, len(names))
+	for _, name := range names {
+		kind, err := ParsePruneKind(name)
+		if err != nil {
+			return nil, err
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, nil
+}
+
+// pruneUnreferenced deletes the entities of the given kinds that nothing in
+// the feed references, and their provenance, returning the number deleted
+// by kind. Stops are kept when a stop time, transfer or pathway uses them,
+// along with their parent stations; shapes when a trip uses them; services
+// when a trip or timeframe uses them; agencies when a route or fare
+// attribute names them; areas when a fare leg rule names them.
+func pruneUnreferenced(feed *gtfs.Feed, kinds []gtfs.EntityKind) map[gtfs.EntityKind]int {
+	pruned := make(map[gtfs.EntityKind]int)
+	for _, kind := range kinds {
+		if _, done := pruned[kind]; done {
+			continue
+		}
+		var n int
+		switch kind {
+		case gtfs.KindStop:
+			n = pruneStops(feed)
+		case gtfs.KindShape:
+			n = pruneShapes(feed)
+		case gtfs.KindService:
+			n = pruneServices(feed)
+		case gtfs.KindAgency:
+			n = pruneAgencies(feed)
+		case gtfs.KindArea:
+			n = pruneAreas(feed)
+		}
+		pruned[kind] = n
+	}
+	return pruned
+}
+
+// pruneStops deletes stops no stop time, transfer or pathway uses, other
+// than the parent stations of the stops kept
+func pruneStops(feed *gtfs.Feed) int {
+	used := make(map[gtfs.StopID]bool)
+	for _, st := range feed.StopTimes {
+		used[st.StopID] = true
+	}
+	for _, tr := range feed.Transfers {
+		used[tr.FromStopID] = true
+		used[tr.ToStopID] = true
+	}
+	for _, pw := range feed.Pathways {
+		used[pw.FromStopID] = true
+		used[pw.ToStopID] = true
+	}
+	for _, id := range feed.StopOrder {
+		if !used[id] {
+			continue
+		}
+		// Walk up the hierarchy, stopping at a parent already kept (or a
+		// cycle back to one)
+		for stop := feed.Stops[id]; stop != nil && stop.ParentStation != ""; {
+			parent := stop.ParentStation
+			if used[parent] {
+				break
+			}
+			used[parent] = true
+			stop = feed.Stops[parent]
+		}
+	}
+
+	before := len(feed.StopOrder)
+	feed.StopOrder = slices.DeleteFunc(feed.StopOrder, func(id gtfs.StopID) bool {
+		if used[id] {
+			return false
+		}
+		delete(feed.Stops, id)
+		deleteSource(feed, gtfs.KindStop, string(id))
+		return true
+	})
+	return before - len(feed.StopOrder)
+}
+
+// pruneShapes deletes shapes no trip uses
+func pruneShapes(feed *gtfs.Feed) int {
+	used := make(map[gtfs.ShapeID]bool)
+	for _, trip := range feed.Trips {
+		used[trip.ShapeID] = true
+	}
+
+	// Merged feeds don't track ShapeOrder, so go by the Shapes map
+	var n int
+	for _, id := range sortedShapeIDs(feed) {
+		if !used[id] {
+			delete(feed.Shapes, id)
+			deleteSource(feed, gtfs.KindShape, string(id))
+			n++
+		}
+	}
+	feed.ShapeOrder = slices.DeleteFunc(feed.ShapeOrder, func(id gtfs.ShapeID) bool {
+		return !used[id]
+	})
+	return n
+}
+
+// pruneServices deletes services no trip or timeframe uses, both their
+// calendar and their calendar dates
+func pruneServices(feed *gtfs.Feed) int {
+	used := make(map[gtfs.ServiceID]bool)
+	for _, trip := range feed.Trips {
+		used[trip.ServiceID] = true
+	}
+	for _, tf := range feed.Timeframes {
+		used[tf.ServiceID] = true
+	}
+
+	unused := make(map[gtfs.ServiceID]bool)
+	feed.CalendarOrder = slices.DeleteFunc(feed.CalendarOrder, func(id gtfs.ServiceID) bool {
+		if used[id] {
+			return false
+		}
+		delete(feed.Calendars, id)
+		unused[id] = true
+		return true
+	})
+	feed.CalendarDateOrder = slices.DeleteFunc(feed.CalendarDateOrder, func(id gtfs.ServiceID) bool {
+		if used[id] {
+			return false
+		}
+		delete(feed.CalendarDates, id)
+		unused[id] = true
+		return true
+	})
+	for id := range unused {
+		deleteSource(feed, gtfs.KindService, string(id))
+	}
+	return len(unused)
+}
+
+// pruneAgencies deletes agencies no route or fare attribute names. A route
+// or fare without an agency_id refers to the feed's only agency, so when
+// there is one nothing is deleted.
+func pruneAgencies(feed *gtfs.Feed) int {
+	used := make(map[gtfs.AgencyID]bool)
+	for _, route := range feed.Routes {
+		used[route.AgencyID] = true
+	}
+	for _, fare := range feed.FareAttributes {
+		if fare.AgencyID != "" {
+			used[fare.AgencyID] = true
+		}
+	}
+	if used[""] {
+		return 0
+	}
+
+	before := len(feed.AgencyOrder)
+	feed.AgencyOrder = slices.DeleteFunc(feed.AgencyOrder, func(id gtfs.AgencyID) bool {
+		if used[id] {
+			return false
+		}
+		delete(feed.Agencies, id)
+		deleteSource(feed, gtfs.KindAgency, string(id))
+		return true
+	})
+	return before - len(feed.AgencyOrder)
+}
+
+// pruneAreas deletes the areas no fare leg rule names; see
+// pruneUnreferenced
+func pruneAreas(feed *gtfs.Feed) int {
+	used := make(map[gtfs.AreaID]bool)
+	for _, rule := range feed.FareLegRules {
+		used[rule.FromAreaID] = true
+		used[rule.ToAreaID] = true
+	}
+
+	before := len(feed.AreaOrder)
+	feed.AreaOrder = slices.DeleteFunc(feed.AreaOrder, func(id gtfs.AreaID) bool {
+		if used[id] {
+			return false
+		}
+		delete(feed.Areas, id)
+		deleteSource(feed, gtfs.KindArea, string(id))
+		return true
+	})
+	return before - len(feed.AreaOrder)
+}
+
+// deleteSource removes the provenance of a deleted entity
+func deleteSource(feed *gtfs.Feed, kind gtfs.EntityKind, id string) {
+	delete(feed.Sources[kind], id)
+}
+
+// dropDeduplicatedOrphans deletes the shapes and services the feed being
+// merged added that only its deduplicated trips used: once a trip is
+// mapped onto an existing one, its stop_times and frequencies are skipped,
+// but its shape and service were copied before trips were merged. Services
+// the feed's timeframes use are kept, as timeframes are merged later.
+// Shapes and services the feed's trips never used are left to
+// WithPruneUnreferenced.
+func dropDeduplicatedOrphans(ctx *strategy.MergeContext) {
+	usedShapes := make(map[gtfs.ShapeID]bool)
+	usedServices := make(map[gtfs.ServiceID]bool)
+	for _, trip := range ctx.Target.Trips {
+		usedShapes[trip.ShapeID] = true
+		usedServices[trip.ServiceID] = true
+	}
+	for _, tf := range ctx.Source.Timeframes {
+		if id, ok := ctx.ServiceIDMapping[tf.ServiceID]; ok {
+			usedServices[id] = true
+		}
+	}
+
+	orphanServices := make(map[gtfs.ServiceID]bool)
+	for _, trip := range ctx.Source.Trips {
+		if id, ok := ctx.ShapeIDMapping[trip.ShapeID]; ok && !usedShapes[id] {
+			if _, added := ctx.JustAddedShapes[id]; added {
+				delete(ctx.Target.Shapes, id)
+				delete(ctx.ShapeIDMapping, trip.ShapeID)
+			}
+		}
+		if id, ok := ctx.ServiceIDMapping[trip.ServiceID]; ok && !usedServices[id] {
+			if _, added := ctx.JustAddedServices[id]; added {
+				delete(ctx.Target.Calendars, id)
+				delete(ctx.Target.CalendarDates, id)
+				delete(ctx.ServiceIDMapping, trip.ServiceID)
+				orphanServices[id] = true
+			}
+		}
+	}
+	if len(orphanServices) > 0 {
+		isOrphan := func(id gtfs.ServiceID) bool { return orphanServices[id] }
+		ctx.Target.CalendarOrder = slices.DeleteFunc(ctx.Target.CalendarOrder, isOrphan)
+		ctx.Target.CalendarDateOrder = slices.DeleteFunc(ctx.Target.CalendarDateOrder, isOrphan)
+	}
+}
@@ -0,0 +1,195 @@
+package merge
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// pruneFeeds returns two feeds that each have a trip through a platform of
+// a station, plus a stop, shape, service and agency nothing uses
+func pruneFeeds() []*gtfs.Feed {
+	feeds := make([]*gtfs.Feed, 2)
+	for i, p := range []string{"a", "b"} {
+		f := gtfs.NewFeed()
+		f.AddAgency(&gtfs.Agency{ID: gtfs.AgencyID(p + "-agency"), Name: "Agency", URL: "http://example.com", Timezone: "UTC"})
+		f.AddAgency(&gtfs.Agency{ID: gtfs.AgencyID(p + "-idle"), Name: "Idle", URL: "http://example.com", Timezone: "UTC"})
+		f.AddStop(&gtfs.Stop{ID: gtfs.StopID(p + "-station"), Name: "Station", LocationType: 1})
+		f.AddStop(&gtfs.Stop{ID: gtfs.StopID(p + "-platform"), Name: "Platform", ParentStation: gtfs.StopID(p + "-station")})
+		f.AddStop(&gtfs.Stop{ID: gtfs.StopID(p + "-orphan"), Name: "Orphan"})
+		f.AddRoute(&gtfs.Route{ID: gtfs.RouteID(p + "-route"), AgencyID: gtfs.AgencyID(p + "-agency"), ShortName: "1", Type: 3})
+		f.AddCalendar(&gtfs.Calendar{ServiceID: gtfs.ServiceID(p + "-wkdy"), Monday: true, StartDate: "20240101", EndDate: "20241231"})
+		f.AddCalendar(&gtfs.Calendar{ServiceID: gtfs.ServiceID(p + "-holiday"), Sunday: true, StartDate: "20240101", EndDate: "20241231"})
+		f.AddCalendarDate(&gtfs.CalendarDate{ServiceID: gtfs.ServiceID(p + "-holiday"), Date: "20240704", ExceptionType: 1})
+		f.AddShape(gtfs.ShapePoint{ShapeID: gtfs.ShapeID(p + "-shape"), Lat: 1, Lon: 1, Sequence: 1})
+		f.AddShape(gtfs.ShapePoint{ShapeID: gtfs.ShapeID(p + "-unused"), Lat: 2, Lon: 2, Sequence: 1})
+		f.AddTrip(&gtfs.Trip{ID: gtfs.TripID(p + "-trip"), RouteID: gtfs.RouteID(p + "-route"), ServiceID: gtfs.ServiceID(p + "-wkdy"), ShapeID: gtfs.ShapeID(p + "-shape")})
+		f.StopTimes = append(f.StopTimes, &gtfs.StopTime{TripID: gtfs.TripID(p + "-trip"), StopID: gtfs.StopID(p + "-platform"), StopSequence: 1})
+		feeds[i] = f
+	}
+	return feeds
+}
+
+func TestWithPruneUnreferenced(t *testing.T) {
+	// Given: pruning of every kind
+	m := New(WithPruneUnreferenced("stops", "shapes", "services", "agencies"))
+
+	// When: merging feeds with unused entities
+	merged, err := m.MergeFeeds(pruneFeeds())
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: only the unused entities are deleted, with their provenance
+	for _, p := range []string{"a", "b"} {
+		if _, ok := merged.Stops[gtfs.StopID(p+"-orphan")]; ok {
+			t.Errorf("Expected %s-orphan to be pruned", p)
+		}
+		if _, ok := merged.Stops[gtfs.StopID(p+"-station")]; !ok {
+			t.Errorf("Expected parent station %s-station to be kept", p)
+		}
+		if _, ok := merged.Shapes[gtfs.ShapeID(p+"-unused")]; ok {
+			t.Errorf("Expected shape %s-unused to be pruned", p)
+		}
+		if _, ok := merged.Calendars[gtfs.ServiceID(p+"-holiday")]; ok {
+			t.Errorf("Expected calendar %s-holiday to be pruned", p)
+		}
+		if _, ok := merged.CalendarDates[gtfs.ServiceID(p+"-holiday")]; ok {
+			t.Errorf("Expected calendar dates of %s-holiday to be pruned", p)
+		}
+		if _, ok := merged.Agencies[gtfs.AgencyID(p+"-idle")]; ok {
+			t.Errorf("Expected agency %s-idle to be pruned", p)
+		}
+		if merged.SourceOf(gtfs.KindStop, p+"-orphan") != nil {
+			t.Errorf("Expected provenance of %s-orphan to be removed", p)
+		}
+	}
+	if len(merged.StopOrder) != 4 || len(merged.Shapes) != 2 || len(merged.CalendarOrder) != 2 || len(merged.AgencyOrder) != 2 {
+		t.Errorf("Unexpected order lengths: %v %v %v %v", merged.StopOrder, merged.ShapeOrder, merged.CalendarOrder, merged.AgencyOrder)
+	}
+
+	report := m.Report()
+	want := map[gtfs.EntityKind]int{gtfs.KindStop: 2, gtfs.KindShape: 2, gtfs.KindService: 2, gtfs.KindAgency: 2}
+	for kind, n := range want {
+		if report.Pruned[kind] != n {
+			t.Errorf("Expected %d %s pruned, got %d", n, kind, report.Pruned[kind])
+		}
+	}
+	if report.Merged["stops.txt"] != 4 {
+		t.Errorf("Expected 4 stops merged, got %d", report.Merged["stops.txt"])
+	}
+}
+
+func TestWithPruneUnreferencedSelectedKinds(t *testing.T) {
+	// Given: pruning of shapes only
+	m := New(WithPruneUnreferenced("shapes"))
+	merged, err := m.MergeFeeds(pruneFeeds())
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: unused stops are left alone
+	if len(merged.Stops) != 6 {
+		t.Errorf("Expected 6 stops, got %d", len(merged.Stops))
+	}
+	if len(merged.Shapes) != 2 {
+		t.Errorf("Expected 2 shapes, got %d", len(merged.Shapes))
+	}
+	if _, ok := m.Report().Pruned[gtfs.KindStop]; ok {
+		t.Errorf("Expected no stop count, got %v", m.Report().Pruned)
+	}
+}
+
+func TestPruneAgenciesKeptWhenRouteOmitsAgencyID(t *testing.T) {
+	// Given: a route without an agency_id, which refers to the only agency
+	feed := gtfs.NewFeed()
+	feed.AddAgency(&gtfs.Agency{Name: "Agency", URL: "http://example.com", Timezone: "UTC"})
+	feed.AddRoute(&gtfs.Route{ID: "r1", ShortName: "1", Type: 3})
+
+	// When/Then: nothing is pruned
+	if n := pruneAgencies(feed); n != 0 || len(feed.Agencies) != 1 {
+		t.Errorf("Expected the agency to be kept, pruned %d", n)
+	}
+}
+
+func TestPruneAreasKeptWhenFareLegRuleNamesThem(t *testing.T) {
+	// Given: an area a fare leg rule names and one nothing names
+	feed := gtfs.NewFeed()
+	feed.AddArea(&gtfs.Area{ID: "zone1"})
+	feed.AddArea(&gtfs.Area{ID: "zone2"})
+	feed.FareLegRules = []*gtfs.FareLegRule{{LegGroupID: "local", ToAreaID: "zone1"}}
+
+	// When/Then: only the unnamed area is pruned
+	if n := pruneAreas(feed); n != 1 || feed.Areas["zone1"] == nil || len(feed.AreaOrder) != 1 {
+		t.Errorf("Expected only zone2 to be pruned, pruned %d leaving %v", n, feed.AreaOrder)
+	}
+}
+
+func TestPruneServicesKeptWhenTimeframeUsesThem(t *testing.T) {
+	// Given: a service only a timeframe uses and one nothing uses
+	feed := gtfs.NewFeed()
+	feed.AddCalendar(&gtfs.Calendar{ServiceID: "weekday", StartDate: "20240101", EndDate: "20241231"})
+	feed.AddCalendar(&gtfs.Calendar{ServiceID: "holiday", StartDate: "20240101", EndDate: "20241231"})
+	feed.Timeframes = []*gtfs.Timeframe{{GroupID: "peak", ServiceID: "weekday"}}
+
+	// When/Then: only the unused service is pruned
+	if n := pruneServices(feed); n != 1 || feed.Calendars["weekday"] == nil || len(feed.CalendarOrder) != 1 {
+		t.Errorf("Expected only holiday to be pruned, pruned %d leaving %v", n, feed.CalendarOrder)
+	}
+}
+
+func TestWithPruneUnreferencedUnknownKind(t *testing.T) {
+	m := New(WithPruneUnreferenced("routes"))
+	if _, err := m.MergeFeeds(pruneFeeds()); !errors.Is(err, ErrUnknownPruneKind) {
+		t.Errorf("Expected ErrUnknownPruneKind, got %v", err)
+	}
+}
+
+func TestDeduplicatedTripsLeaveNoOrphans(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		shapes   int
+		services int
+	}{
+		// Only trips are deduplicated, so the second copy's shape and
+		// weekday service are added and then left unused
+		{"trips by identity", []Option{WithDetectionFor("trip", strategy.DetectionIdentity)}, 3, 3},
+		{"everything by identity", []Option{WithDefaultDetection(strategy.DetectionIdentity)}, 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: two identical feeds
+			feeds := []*gtfs.Feed{pruneFeeds()[0], pruneFeeds()[0]}
+
+			// When: merging them without pruning
+			merged, err := New(tt.opts...).MergeFeeds(feeds)
+			if err != nil {
+				t.Fatalf("MergeFeeds failed: %v", err)
+			}
+
+			// Then: the one trip is kept, and no shape or service is left
+			// orphaned by the dropped copy; only the feeds' own unused
+			// a-unused and a-holiday are kept
+			if len(merged.Trips) != 1 {
+				t.Fatalf("Expected 1 trip, got %d", len(merged.Trips))
+			}
+			for id := range merged.Shapes {
+				if id != "a-shape" && !strings.HasSuffix(string(id), "a-unused") {
+					t.Errorf("Expected no orphan shapes, got %s", id)
+				}
+			}
+			for _, id := range merged.CalendarOrder {
+				if id != "a-wkdy" && !strings.HasSuffix(string(id), "a-holiday") {
+					t.Errorf("Expected no orphan services, got %s", id)
+				}
+			}
+			if len(merged.Shapes) != tt.shapes || len(merged.CalendarOrder) != tt.services {
+				t.Errorf("Expected %d shapes and %d services, got %d and %v", tt.shapes, tt.services, len(merged.Shapes), merged.CalendarOrder)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package merge
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// ErrInvalidTripIDPattern indicates a WithReadableTripIDs pattern with an
+// unknown or unclosed field
+var ErrInvalidTripIDPattern = errors.New("invalid trip ID pattern")
+
+// TripIDFields lists the fields a WithReadableTripIDs pattern may use, each
+// written in braces, e.g. {route_short}:
+//
+//   - route_short: the route's route_short_name, or its route_id if it has
+//     none
+//   - route_id: the route's route_id
+//   - direction: the trip's direction_id, or nothing if it has none
+//   - start_time: the departure (or arrival) time of the trip's first stop
+//     time as HHMM, e.g. 0815 or 2530 for a trip after midnight, or nothing
+//     if it has none
+//   - headsign: the trip's trip_headsign
+//   - service_id: the trip's service_id
+//   - trip_id: the trip's ID before rewriting
+//   - seq: the trip's number, from 1, among those whose IDs are otherwise
+//     the same
+var TripIDFields = []string{"route_short", "route_id", "direction", "start_time", "headsign", "service_id", "trip_id", "seq"}
+
+// tripIDPart is a piece of a parsed trip ID pattern: literal text, or the
+// field named
+type tripIDPart struct {
+	literal string
+	field   string
+}
+
+// ValidateTripIDPattern returns an error wrapping ErrInvalidTripIDPattern
+// if pattern is not a valid WithReadableTripIDs pattern
+func ValidateTripIDPattern(pattern string) error {
+	_, err := parseTripIDPattern(pattern)
+	return err
+}
+
+// parseTripIDPattern splits pattern into literal text and fields
+func parseTripIDPattern(pattern string) ([]tripIDPart, error) {
+	var parts []tripIDPart
+	rest := pattern
+	for rest != "" {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			parts = append(parts, tripIDPart{literal: rest})
+			break
+		}
+		if open > 0 {
+			parts = append(parts, tripIDPart{literal: rest[:open]})
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("%w: %q: unclosed field", ErrInvalidTripIDPattern, pattern)
+		}
+		field := rest[open+1 : open+end]
+		if !slices.Contains(TripIDFields, field) {
+			return nil, fmt.Errorf("%w: %q: unknown field {%s} (must be one of %s)",
+				ErrInvalidTripIDPattern, pattern, field, strings.Join(TripIDFields, ", "))
+		}
+		parts = append(parts, tripIDPart{field: field})
+		rest = rest[open+end+1:]
+	}
+	return parts, nil
+}
+
+// rewriteTripIDs renames every trip of feed after the pattern parts, in
+// trips.txt order, and updates the stop_times, frequencies, transfers and
+// provenance referencing them. A trip whose ID would repeat an earlier one
+// takes the next free {seq}, or, if the pattern has none, a "-2", "-3"...
+// suffix. It returns the new ID of each trip by its old.
+func rewriteTripIDs(feed *gtfs.Feed, parts []tripIDPart) map[gtfs.TripID]gtfs.TripID {
+	first := make(map[gtfs.TripID]*gtfs.StopTime)
+	for _, st := range feed.StopTimes {
+		if f := first[st.TripID]; f == nil || st.StopSequence < f.StopSequence {
+			first[st.TripID] = st
+		}
+	}
+	renamed := make(map[gtfs.TripID]gtfs.TripID, len(feed.TripOrder))
+	used := make(map[gtfs.TripID]bool, len(feed.TripOrder))
+	counts := make(map[string]int)
+	for _, id := range feed.TripOrder {
+		trip := feed.Trips[id]
+		if trip == nil {
+			continue
+		}
+		// The ID is rendered with a placeholder for {seq}, so trips
+		// differing only in it are numbered together
+		key := renderTripID(parts, feed, trip, first[id], "\x00")
+		if strings.Trim(key, "\x00") == "" {
+			key = string(trip.ID)
+		}
+		hasSeq := strings.Contains(key, "\x00")
+		var newID gtfs.TripID
+		for {
+			counts[key]++
+			n := strconv.Itoa(counts[key])
+			switch {
+			case hasSeq:
+				newID = gtfs.TripID(strings.ReplaceAll(key, "\x00", n))
+			case counts[key] == 1:
+				newID = gtfs.TripID(key)
+			default:
+				newID = gtfs.TripID(key + "-" + n)
+			}
+			if !used[newID] {
+				break
+			}
+		}
+		used[newID] = true
+		renamed[id] = newID
+	}
+
+	trips := make(map[gtfs.TripID]*gtfs.Trip, len(feed.Trips))
+	for i, id := range feed.TripOrder {
+		trip := feed.Trips[id]
+		if trip == nil {
+			continue
+		}
+		trip.ID = renamed[id]
+		trips[trip.ID] = trip
+		feed.TripOrder[i] = trip.ID
+	}
+	feed.Trips = trips
+	rename := func(id gtfs.TripID) gtfs.TripID {
+		if to, ok := renamed[id]; ok {
+			return to
+		}
+		return id
+	}
+	for _, st := range feed.StopTimes {
+		st.TripID = rename(st.TripID)
+	}
+	for _, f := range feed.Frequencies {
+		f.TripID = rename(f.TripID)
+	}
+	for _, t := range feed.Transfers {
+		t.FromTripID = rename(t.FromTripID)
+		t.ToTripID = rename(t.ToTripID)
+	}
+	if sources := feed.Sources[gtfs.KindTrip]; sources != nil {
+		renamedSources := make(map[string][]int, len(sources))
+		for id, indices := range sources {
+			renamedSources[string(rename(gtfs.TripID(id)))] = indices
+		}
+		feed.Sources[gtfs.KindTrip] = renamedSources
+	}
+	return renamed
+}
+
+// renderTripID returns the pattern parts rendered for trip, whose first stop
+// time is first (nil if it has none), with seq for {seq}. Whitespace in
+// field values is replaced with underscores.
+func renderTripID(parts []tripIDPart, feed *gtfs.Feed, trip *gtfs.Trip, first *gtfs.StopTime, seq string) string {
+	var b strings.Builder
+	for _, p := range parts {
+		if p.field == "" {
+			b.WriteString(p.literal)
+			continue
+		}
+		var value string
+		switch p.field {
+		case "route_short":
+			value = string(trip.RouteID)
+			if route := feed.Routes[trip.RouteID]; route != nil && route.ShortName != "" {
+				value = route.ShortName
+			}
+		case "route_id":
+			value = string(trip.RouteID)
+		case "direction":
+			if trip.DirectionID != nil {
+				value = strconv.Itoa(*trip.DirectionID)
+			}
+		case "start_time":
+			if first != nil {
+				value = first.DepartureTime
+				if value == "" {
+					value = first.ArrivalTime
+				}
+				value = compactTime(value)
+			}
+		case "headsign":
+			value = trip.Headsign
+		case "service_id":
+			value = string(trip.ServiceID)
+		case "trip_id":
+			value = string(trip.ID)
+		case "seq":
+			value = seq
+		}
+		b.WriteString(strings.Join(strings.Fields(value), "_"))
+	}
+	return b.String()
+}
+
+// compactTime returns a GTFS time (H:MM:SS) as HHMM
+func compactTime(t string) string {
+	h, m, ok := strings.Cut(strings.TrimSpace(t), ":")
+	if !ok {
+		return t
+	}
+	if len(h) == 1 {
+		h = "0" + h
+	}
+	m, _, _ = strings.Cut(m, ":")
+	return h + m
+}
+
+// renameTripIDs applies the trip renames to each feed's ID map, so that it
+// maps input trips to their rewritten IDs
+func (r *Report) renameTripIDs(renamed map[gtfs.TripID]gtfs.TripID) {
+	for _, feed := range r.Feeds {
+		for from, to := range feed.IDMap[gtfs.KindTrip] {
+			if newID, ok := renamed[gtfs.TripID(to)]; ok {
+				feed.IDMap[gtfs.KindTrip][from] = string(newID)
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package merge
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfstest"
+)
+
+// readableTripFeeds returns two feeds each with a trip t1 of route 10,
+// direction 0, leaving at 08:15, with a frequency and a transfer to their
+// trip t2
+func readableTripFeeds() []*gtfs.Feed {
+	feed := func() *gtfs.Feed {
+		direction := 0
+		f := gtfstest.NewFeedBuilder().
+			Route("r1", func(r *gtfs.Route) { r.ShortName = "10" }).
+			Trip("t1", "r1", "wk", func(t *gtfs.Trip) { t.DirectionID = &direction }).
+			StopTimes("t1", "08:15", "s1", "s2").
+			Frequency("t1", "08:15", "10:15", 30*time.Minute).
+			Trip("t2", "r1", "wk", func(t *gtfs.Trip) { t.Headsign = "Downtown" }).
+			StopTimes("t2", "9:05", "s2", "s1").
+			MustBuild()
+		f.Transfers = append(f.Transfers, &gtfs.Transfer{
+			FromStopID: "s2", ToStopID: "s1", TransferType: 1, FromTripID: "t1", ToTripID: "t2",
+		})
+		return f
+	}
+	return []*gtfs.Feed{feed(), feed()}
+}
+
+func TestWithReadableTripIDs(t *testing.T) {
+	// Given: two feeds whose trips t1 would both be named 10-0-0815
+	feeds := readableTripFeeds()
+
+	// When: merged without duplicate detection, renaming trips
+	m := New(WithReadableTripIDs("{route_short}-{direction}-{start_time}"), WithStrictOutput(true))
+	merged, err := m.MergeFeeds(feeds)
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: the trip merged first keeps the readable ID, and the other
+	// takes a suffix; trips without a direction leave it empty
+	want := []gtfs.TripID{"10-0-0815", "10--0905", "10-0-0815-2", "10--0905-2"}
+	if len(merged.TripOrder) != len(want) {
+		t.Fatalf("Expected trips %v, got %v", want, merged.TripOrder)
+	}
+	for i, id := range want {
+		if merged.TripOrder[i] != id || merged.Trips[id] == nil || merged.Trips[id].ID != id {
+			t.Errorf("Expected trip %d to be %q, got %q", i, id, merged.TripOrder[i])
+		}
+	}
+
+	// And: every reference follows its trip
+	for _, st := range merged.StopTimes {
+		if merged.Trips[st.TripID] == nil {
+			t.Errorf("Expected stop time of trip %q to reference a merged trip", st.TripID)
+		}
+	}
+	for _, f := range merged.Frequencies {
+		if f.TripID != "10-0-0815" && f.TripID != "10-0-0815-2" {
+			t.Errorf("Expected frequencies to follow trip t1, got %q", f.TripID)
+		}
+	}
+	for _, tr := range merged.Transfers {
+		if merged.Trips[tr.FromTripID] == nil || merged.Trips[tr.ToTripID] == nil {
+			t.Errorf("Expected transfer to reference merged trips, got %q to %q", tr.FromTripID, tr.ToTripID)
+		}
+	}
+	if got := merged.SourceOf(gtfs.KindTrip, "10-0-0815-2"); len(got) != 1 || got[0] != 0 {
+		t.Errorf("Expected 10-0-0815-2 to come from the first input, got %v", got)
+	}
+
+	// And: the ID maps lead from each input's trips to the new IDs
+	report := m.Report()
+	if got := report.Feeds[0].IDMap[gtfs.KindTrip]["t1"]; got != "10-0-0815-2" {
+		t.Errorf("Expected the first input's t1 to map to 10-0-0815-2, got %q", got)
+	}
+	if got := report.Feeds[1].IDMap[gtfs.KindTrip]["t1"]; got != "10-0-0815" {
+		t.Errorf("Expected the second input's t1 to map to 10-0-0815, got %q", got)
+	}
+	if got := report.ReadableTripIDs["a-t1"]; got != "10-0-0815-2" {
+		t.Errorf("Expected a-t1 to be renamed 10-0-0815-2, got %q", got)
+	}
+
+	// And: the written feed is valid
+	output := filepath.Join(t.TempDir(), "merged.zip")
+	if err := gtfs.WriteToPath(merged, output); err != nil {
+		t.Fatalf("WriteToPath failed: %v", err)
+	}
+	written, err := gtfs.ReadFromPath(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if err := written.ValidateAll(); err != nil {
+		t.Errorf("Expected a valid feed, got %v", err)
+	}
+}
+
+func TestWithReadableTripIDsSeq(t *testing.T) {
+	// Given: a pattern numbering the trips of each route
+	m := New(WithReadableTripIDs("{route_short}-{seq}"))
+
+	// When: the feeds are merged
+	merged, err := m.MergeFeeds(readableTripFeeds())
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: the trips are numbered in order
+	want := []gtfs.TripID{"10-1", "10-2", "10-3", "10-4"}
+	for i, id := range want {
+		if i >= len(merged.TripOrder) || merged.TripOrder[i] != id {
+			t.Fatalf("Expected trips %v, got %v", want, merged.TripOrder)
+		}
+	}
+}
+
+func TestWithReadableTripIDsInvalidPattern(t *testing.T) {
+	for _, pattern := range []string{"{route}-{seq}", "{route_short"} {
+		t.Run(pattern, func(t *testing.T) {
+			// Given: a pattern with an unknown or unclosed field
+			m := New(WithReadableTripIDs(pattern))
+
+			// Then: the merge fails
+			if _, err := m.MergeFeeds(readableTripFeeds()); !errors.Is(err, ErrInvalidTripIDPattern) {
+				t.Errorf("Expected ErrInvalidTripIDPattern, got %v", err)
+			}
+			if err := ValidateTripIDPattern(pattern); !errors.Is(err, ErrInvalidTripIDPattern) {
+				t.Errorf("Expected ValidateTripIDPattern to fail, got %v", err)
+			}
+		})
+	}
+}
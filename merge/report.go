@@ -0,0 +1,352 @@
+package merge
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// Report summarizes the outcome of the most recent merge
+type Report struct {
+	// Feeds describes each input feed, in input order
+	Feeds []FeedReport
+
+	// Merged is the number of rows in each file of the merged feed,
+	// keyed by filename
+	Merged map[string]int
+
+	// Sources is the provenance of the merged feed's entities; it is the
+	// same map as the merged gtfs.Feed's Sources
+	Sources map[gtfs.EntityKind]map[string][]int
+
+	// Feed is the merged feed, as MergeFiles wrote it or MergeFeeds or
+	// Pipeline returned it; nil when MergeFiles skipped the merge on a
+	// cache hit
+	Feed *gtfs.Feed
+
+	// GrayZone lists the fuzzy matches whose scores fell within the gray
+	// zone around the fuzzy threshold (see WithGrayZone), in merge order
+	GrayZone []strategy.GrayZoneMatch
+
+	// TripSubsetMatches lists the fuzzy trip matches between trips with
+	// different numbers of stops (see WithTripStopSubset), in merge order
+	TripSubsetMatches []strategy.TripSubsetMatch
+
+	// BoardingDifferences lists the stops at which fuzzy matched trips'
+	// stop_times differ in pickup_type, drop_off_type or timepoint, so one
+	// trip's values were dropped (see WithStrictTripBoarding), in merge
+	// order
+	BoardingDifferences []strategy.BoardingDifference
+
+	// AgencyFieldChanges lists the fields of merged agencies replaced with
+	// a newer edition's value (see WithNewestAgencyEdition), in merge order
+	AgencyFieldChanges []strategy.AgencyFieldChange
+
+	// UnresolvedParentStations lists the stops whose parent_station named a
+	// stop their input lacks, and was cleared, in merge order
+	UnresolvedParentStations []strategy.UnresolvedParentStation
+
+	// FuzzyLimitHits lists the fuzzy matching limits (see WithFuzzyLimits)
+	// reached, in merge order. Entities affected may have been kept as
+	// separate entities where unlimited matching would have merged them.
+	FuzzyLimitHits []strategy.FuzzyLimitHit
+
+	// DirectionConflicts lists the merged routes whose trips from different
+	// inputs use opposite direction_id conventions (see
+	// WithHarmonizeDirections), in route order
+	DirectionConflicts []DirectionConflict
+
+	// FrequencyOverlaps lists the frequency-based trips found running the
+	// same service as exact-time trips (see WithFrequencyOverlaps), in trip
+	// order
+	FrequencyOverlaps []FrequencyOverlap
+
+	// StopTimesInterpolated is the number of stop_times whose times were
+	// interpolated under WithInterpolateStopTimes
+	StopTimesInterpolated int
+
+	// UninterpolatedStopTimes lists the stop_times left without times
+	// under WithInterpolateStopTimes, in trip order
+	UninterpolatedStopTimes []UninterpolatedStopTime
+
+	// ServiceCoverage describes the merged feed's service over the days
+	// checked by WithServiceCheck; nil when not checked
+	ServiceCoverage *ServiceCoverage
+
+	// ServiceGaps lists the runs of days on which a merged route has no
+	// trips found by WithServiceGapCheck, in routes.txt order
+	ServiceGaps []gtfs.ServiceGap
+
+	// ReadableTripIDs maps the ID each merged trip had before
+	// WithReadableTripIDs renamed it to the ID written; nil when trips keep
+	// their IDs. FeedReport.IDMap maps input trips to the IDs written.
+	ReadableTripIDs map[gtfs.TripID]gtfs.TripID
+
+	// UnusedBlockedPairs lists the pairs given to WithBlockedDuplicates that
+	// no duplicate detection ever matched, so stale entries can be removed
+	UnusedBlockedPairs []BlockedPair
+
+	// StopCodeCollisions lists the stop codes shared by more than one
+	// merged stop, as found before any were prefixed (see
+	// WithUniqueStopCodes)
+	StopCodeCollisions []StopCodeCollision
+
+	// StopCodesPrefixed is the number of stop codes rewritten under
+	// StopCodesPrefix
+	StopCodesPrefixed int
+
+	// StopCodesUnresolved lists the stop codes still shared by more than
+	// one merged stop after StopCodesPrefix rewrote them, such as those of
+	// stops from the same input or from the last input, which has no prefix
+	StopCodesUnresolved []StopCodeCollision
+
+	// Dropped is the number of rows of each file removed from the merged
+	// feed after all inputs were merged, e.g. by WithPruneUnreferenced,
+	// keyed by filename
+	Dropped map[string]int
+
+	// Pruned is the number of unreferenced entities deleted after the
+	// merge, by kind, for each kind given to WithPruneUnreferenced
+	Pruned map[gtfs.EntityKind]int
+
+	// SkippedInputs lists the MergeFiles inputs left out because they are
+	// identical to an earlier input (see WithFailOnIdenticalInputs); Feeds
+	// describes only the inputs merged
+	SkippedInputs []SkippedInput
+
+	// InvalidInputs lists the MergeFiles inputs left out because they could
+	// not be read (see WithSkipInvalidInputs)
+	InvalidInputs []InvalidInput
+
+	// CacheHit is set when MergeFiles found its inputs, options and output
+	// unchanged since the run recorded in the fingerprint cache (see
+	// WithFingerprintCache) and returned without merging; the report
+	// describes nothing else
+	CacheHit bool
+
+	// Warnings lists problems the merge resolved on its own that may still
+	// need attention, such as conflicting feed_info languages
+	Warnings []string
+
+	// Stages are the reports of each stage of a Pipeline, in order; nil
+	// for other merges
+	Stages []*Report
+}
+
+// FeedReport describes how a single input feed contributed to the merge
+type FeedReport struct {
+	// Index is the position of the feed in the input list
+	Index int
+
+	// Name is the stable name assigned to the feed: the input file basename
+	// (without extension) for MergeFiles, or a letter label for MergeFeeds
+	Name string
+
+	// Path is the input path for MergeFiles, or empty for MergeFeeds
+	Path string
+
+	// Prefix is the prefix applied to this feed's IDs on collision
+	Prefix string
+
+	// StagePrefixes are, in a Pipeline report, the prefixes applied on
+	// collision to the results holding this feed's IDs by the stages after
+	// the feed's own, in stage order
+	StagePrefixes []string
+
+	// Edition is the feed's edition, from its feed_info.txt
+	Edition strategy.FeedEdition
+
+	// Read is the number of rows in each file of this input feed, keyed by
+	// filename: the data rows read (see gtfs.Feed.RowsRead), or for a feed
+	// that was not read, the rows it held when the merge began
+	Read map[string]int
+
+	// Added is the number of rows this feed contributed to each file of the
+	// merged feed; rows read but not added were merged into existing
+	// entities as duplicates
+	Added map[string]int
+
+	// Deduplicated is the number of rows of each file of this feed left
+	// out as duplicates of rows kept, keyed by filename
+	Deduplicated map[string]int
+
+	// Dropped is the number of rows of each file of this feed left out for
+	// other reasons, such as WithSanitizeInputs or WithAgencyFilter
+	// removing them, keyed by filename. The rows read less those
+	// deduplicated and dropped are the rows added, unless a later feed's
+	// rows replace them (see Report.Unaccounted).
+	Dropped map[string]int
+
+	// IDMap maps the ID of each of this feed's agencies, stops, routes,
+	// trips, services, shapes, fares, areas, networks and Fares v2 leg
+	// groups, fare products, timeframe groups, fare media and rider
+	// categories, by kind, to its ID in the merged feed, for rewriting references held elsewhere. A
+	// duplicate maps to the ID of the entity it was merged into; entities
+	// not in the merged feed, e.g. pruned ones, are left out.
+	IDMap map[gtfs.EntityKind]map[string]string
+
+	// FeedInfoIDs lists the feed_id values in the merged feed_info.txt
+	// that came from this feed
+	FeedInfoIDs []string
+
+	// ShapeDistanceUnit is the unit inferred for this feed's
+	// shape_dist_traveled values when WithShapeDistanceUnit is set
+	ShapeDistanceUnit DistanceUnit
+
+	// ShapeDistanceScale is the factor applied to this feed's
+	// shape_dist_traveled values, or 0 if they were not rescaled
+	ShapeDistanceScale float64
+
+	// Sanitized is the number of rows with dangling references dropped from
+	// each file of this input feed before merging, keyed by filename; nil
+	// unless WithSanitizeInputs is set
+	Sanitized map[string]int
+
+	// StationStopTimesFixed is the number of this feed's stop_times moved
+	// from a station to its only platform under WithFixStationStopTimes
+	StationStopTimesFixed int
+
+	// TextSanitized is the number of this input feed's text values cleaned
+	// of control characters, invalid UTF-8 or surrounding whitespace, keyed
+	// by filename; nil unless WithSanitizeText is set
+	TextSanitized map[string]int
+
+	// OverridesApplied is the number of overrides applied to this feed
+	// under WithOverrides
+	OverridesApplied int
+
+	// AgencyFiltered is the number of rows removed from each file of this
+	// input feed with the agencies WithAgencyFilter leaves out, keyed by
+	// filename; nil unless a filter is set for this input
+	AgencyFiltered map[string]int
+
+	// RouteTypesMapped is the number of this feed's routes whose extended
+	// route_type was replaced with a basic one under WithBasicRouteTypes
+	RouteTypesMapped int
+
+	// ColorsNormalized is the number of this feed's route_color and
+	// route_text_color values normalized under WithNormalizeColors
+	ColorsNormalized int
+
+	// IntraFeedDuplicates is the number of this feed's entities, by kind,
+	// collapsed into another of the same feed under WithIntraFeedDedup
+	IntraFeedDuplicates map[gtfs.EntityKind]int
+}
+
+// Prefixes returns the prefixes this feed's IDs may carry in the merged
+// feed, outermost first: the StagePrefixes in reverse, then Prefix
+func (fr FeedReport) Prefixes() []string {
+	prefixes := make([]string, 0, len(fr.StagePrefixes)+1)
+	for _, prefix := range slices.Backward(fr.StagePrefixes) {
+		if prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	if fr.Prefix != "" {
+		prefixes = append(prefixes, fr.Prefix)
+	}
+	return prefixes
+}
+
+// Duplicates returns the number of rows of filename read from this feed that
+// were left out as duplicates of rows kept
+func (fr *FeedReport) Duplicates(filename string) int {
+	return fr.Deduplicated[filename]
+}
+
+// FeedByName returns the report for the feed with the given name, or nil
+func (r *Report) FeedByName(name string) *FeedReport {
+	if r == nil {
+		return nil
+	}
+	for i := range r.Feeds {
+		if r.Feeds[i].Name == name {
+			return &r.Feeds[i]
+		}
+	}
+	return nil
+}
+
+// FeedByPath returns the report for the feed read from path by MergeFiles,
+// or nil
+func (r *Report) FeedByPath(path string) *FeedReport {
+	if r == nil || path == "" {
+		return nil
+	}
+	for i := range r.Feeds {
+		if r.Feeds[i].Path == path {
+			return &r.Feeds[i]
+		}
+	}
+	return nil
+}
+
+// feedNameForPath derives a feed name from an input path
+func feedNameForPath(path string) string {
+	base := filepath.Base(filepath.Clean(path))
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// feedNameForIndex derives a feed name from its input position,
+// following the same lettering as GetPrefixForIndex
+func feedNameForIndex(index int) string {
+	return strings.TrimSuffix(GetPrefixForIndex(index+1), "-")
+}
+
+// uniqueFeedNames disambiguates repeated names (e.g. two inputs both named
+// gtfs.zip) by appending the 1-based input position
+func uniqueFeedNames(names []string) []string {
+	counts := make(map[string]int, len(names))
+	for _, name := range names {
+		counts[name]++
+	}
+	unique := make([]string, len(names))
+	for i, name := range names {
+		if counts[name] > 1 {
+			name = fmt.Sprintf("%s-%d", name, i+1)
+		}
+		unique[i] = name
+	}
+	return unique
+}
+
+// recordFeedInfos associates each merged feed_info row with its source feed
+func (r *Report) recordFeedInfos(merged *gtfs.Feed) {
+	byName := make(map[string]*FeedReport, len(r.Feeds))
+	for i := range r.Feeds {
+		byName[r.Feeds[i].Name] = &r.Feeds[i]
+	}
+
+	ids := make([]string, 0, len(merged.FeedInfos))
+	for id := range merged.FeedInfos {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		fi := merged.FeedInfos[id]
+		if fi == nil {
+			continue
+		}
+		if fr := byName[fi.SourceFeed]; fr != nil {
+			fr.FeedInfoIDs = append(fr.FeedInfoIDs, id)
+		}
+	}
+}
+
+// rowCountDelta returns the per-file growth from before to after,
+// omitting files that did not grow
+func rowCountDelta(before, after map[string]int) map[string]int {
+	delta := make(map[string]int)
+	for filename, n := range after {
+		if d := n - before[filename]; d > 0 {
+			delta[filename] = d
+		}
+	}
+	return delta
+}
@@ -0,0 +1,220 @@
+package merge
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+func TestMergeReportFeedInfoSources(t *testing.T) {
+	// Given: two feeds whose feed_info rows have no feed_id
+	feedA := gtfs.NewFeed()
+	feedA.AddFeedInfo(&gtfs.FeedInfo{PublisherName: "Agency A", PublisherURL: "http://a.com", Lang: "en"})
+	feedB := gtfs.NewFeed()
+	feedB.AddFeedInfo(&gtfs.FeedInfo{PublisherName: "Agency B", PublisherURL: "http://b.com", Lang: "en"})
+
+	// When: merged, naming such rows after their input
+	m := New(WithFeedInfoIDFromSource(true))
+	merged, err := m.MergeFeeds([]*gtfs.Feed{feedA, feedB})
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+
+	// Then: each row is kept under its feed's name and records its source
+	if len(merged.FeedInfos) != 2 {
+		t.Fatalf("Expected 2 feed infos, got %d", len(merged.FeedInfos))
+	}
+	for name, publisher := range map[string]string{"a": "Agency A", "b": "Agency B"} {
+		fi := merged.FeedInfos[name]
+		if fi == nil {
+			t.Fatalf("Expected feed info keyed %q", name)
+		}
+		if fi.FeedID != name || fi.SourceFeed != name {
+			t.Errorf("Expected FeedID and SourceFeed %q, got %q and %q", name, fi.FeedID, fi.SourceFeed)
+		}
+		if fi.PublisherName != publisher {
+			t.Errorf("Expected publisher %q for %q, got %q", publisher, name, fi.PublisherName)
+		}
+
+		fr := m.Report().FeedByName(name)
+		if fr == nil {
+			t.Fatalf("Expected report entry for %q", name)
+		}
+		if len(fr.FeedInfoIDs) != 1 || fr.FeedInfoIDs[0] != name {
+			t.Errorf("Expected report to list feed_id %q for %q, got %v", name, name, fr.FeedInfoIDs)
+		}
+	}
+
+	// And: input feeds are not modified
+	if feedA.FeedInfos[""].SourceFeed != "" {
+		t.Errorf("Expected input feed info to be left untouched")
+	}
+}
+
+func TestMergeReportFeedInfoJavaDefault(t *testing.T) {
+	// Given: two feeds whose feed_info rows have no feed_id
+	feedA := gtfs.NewFeed()
+	feedA.AddFeedInfo(&gtfs.FeedInfo{PublisherName: "Agency A", PublisherURL: "http://a.com", Lang: "en"})
+	feedB := gtfs.NewFeed()
+	feedB.AddFeedInfo(&gtfs.FeedInfo{PublisherName: "Agency B", PublisherURL: "http://b.com", Lang: "en"})
+
+	// When: merged with the default options
+	merged, err := New().MergeFeeds([]*gtfs.Feed{feedA, feedB})
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+
+	// Then: as in Java, both get feed_id "1", and the row merged last,
+	// from the first input, replaces the other
+	if len(merged.FeedInfos) != 1 {
+		t.Fatalf("Expected 1 feed info, got %d", len(merged.FeedInfos))
+	}
+	if fi := merged.FeedInfos["1"]; fi == nil || fi.PublisherName != "Agency A" {
+		t.Errorf("Expected feed_id 1 from Agency A, got %+v", fi)
+	}
+}
+
+func TestMergeFilesFeedInfoStableAcrossInputOrder(t *testing.T) {
+	// Given: the same inputs in both orders
+	orders := [][]string{
+		{"../testdata/all_optional_feed", "../testdata/simple_a"},
+		{"../testdata/simple_a", "../testdata/all_optional_feed"},
+	}
+
+	for _, inputs := range orders {
+		// When: merged, naming feed_info rows after their input
+		m := New(WithFeedInfoIDFromSource(true))
+		output := filepath.Join(t.TempDir(), "merged.zip")
+		if err := m.MergeFiles(inputs, output); err != nil {
+			t.Fatalf("MergeFiles failed: %v", err)
+		}
+
+		// Then: the feed_info row is attributed to the input by basename
+		fr := m.Report().FeedByName("all_optional_feed")
+		if fr == nil {
+			t.Fatalf("Expected report entry for all_optional_feed with inputs %v", inputs)
+		}
+		if len(fr.FeedInfoIDs) != 1 || fr.FeedInfoIDs[0] != "all_optional_feed" {
+			t.Errorf("Expected feed_id all_optional_feed, got %v", fr.FeedInfoIDs)
+		}
+		if other := m.Report().FeedByName("simple_a"); other == nil || len(other.FeedInfoIDs) != 0 {
+			t.Errorf("Expected simple_a to contribute no feed_info rows, got %+v", other)
+		}
+	}
+}
+
+func TestMergeFilesReportFeed(t *testing.T) {
+	// Given: two inputs
+	m := New()
+	output := filepath.Join(t.TempDir(), "merged.zip")
+
+	// When: merged to a file
+	if err := m.MergeFiles([]string{"../testdata/simple_a", "../testdata/simple_b"}, output); err != nil {
+		t.Fatalf("MergeFiles failed: %v", err)
+	}
+
+	// Then: the report holds the merged feed that was written
+	merged := m.Report().Feed
+	if merged == nil {
+		t.Fatal("Expected the report to hold the merged feed")
+	}
+	written, err := gtfs.ReadFromPath(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if len(merged.Stops) != len(written.Stops) || len(merged.StopTimes) != len(written.StopTimes) {
+		t.Errorf("Expected %d stops and %d stop_times, got %d and %d",
+			len(written.Stops), len(written.StopTimes), len(merged.Stops), len(merged.StopTimes))
+	}
+}
+
+func TestMergeFilesWithReport(t *testing.T) {
+	// Given: two inputs
+	m := New()
+	output := filepath.Join(t.TempDir(), "merged.zip")
+
+	// When: merged to a file, asking for the report
+	report, err := m.MergeFilesWithReport(context.Background(), []string{"../testdata/simple_a", "../testdata/simple_b"}, output)
+	if err != nil {
+		t.Fatalf("MergeFilesWithReport failed: %v", err)
+	}
+
+	// Then: the report describes the merge of both inputs
+	if report == nil || report.Feed == nil || len(report.Feeds) != 2 {
+		t.Fatalf("Expected a report of two inputs holding the merged feed, got %+v", report)
+	}
+	if report.Feeds[1].Path != "../testdata/simple_b" {
+		t.Errorf("Expected the second input's path, got %q", report.Feeds[1].Path)
+	}
+
+	// And: the deprecated Report returns the same report
+	if m.Report() != report {
+		t.Error("Expected Report to return the merge's report")
+	}
+}
+
+func TestUniqueFeedNames(t *testing.T) {
+	got := uniqueFeedNames([]string{"gtfs", "other", "gtfs"})
+	want := []string{"gtfs-1", "other", "gtfs-3"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, got)
+			break
+		}
+	}
+}
+
+func TestMergeReportRowCounts(t *testing.T) {
+	// Given: simple_a and overlap, which share some IDs
+	feedA, err := gtfs.ReadFromPath("../testdata/simple_a")
+	if err != nil {
+		t.Fatalf("failed to read simple_a: %v", err)
+	}
+	feedB, err := gtfs.ReadFromPath("../testdata/overlap")
+	if err != nil {
+		t.Fatalf("failed to read overlap: %v", err)
+	}
+
+	// When: merged with identity detection
+	m := New(WithDefaultDetection(strategy.DetectionIdentity))
+	merged, err := m.MergeFeeds([]*gtfs.Feed{feedA, feedB})
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	report := m.Report()
+
+	// Then: read counts match the inputs and merged counts match the output
+	for i, feed := range []*gtfs.Feed{feedA, feedB} {
+		fr := report.Feeds[i]
+		if got, want := fr.Read["stops.txt"], len(feed.Stops); got != want {
+			t.Errorf("feed %s: expected %d stops read, got %d", fr.Name, want, got)
+		}
+	}
+	if got, want := report.Merged["stops.txt"], len(merged.Stops); got != want {
+		t.Errorf("Expected %d merged stops, got %d", want, got)
+	}
+
+	// And: rows added plus duplicates account for every row read
+	for _, fr := range report.Feeds {
+		for filename, read := range fr.Read {
+			if got := fr.Added[filename] + fr.Duplicates(filename); got != read {
+				t.Errorf("feed %s %s: added %d + duplicates %d != read %d",
+					fr.Name, filename, fr.Added[filename], fr.Duplicates(filename), read)
+			}
+		}
+	}
+
+	// And: the added rows sum to the merged total
+	for filename, total := range report.Merged {
+		sum := 0
+		for _, fr := range report.Feeds {
+			sum += fr.Added[filename]
+		}
+		if sum != total {
+			t.Errorf("%s: feeds added %d rows, merged has %d", filename, sum, total)
+		}
+	}
+}
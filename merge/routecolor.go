@@ -0,0 +1,60 @@
+package merge
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// MinRouteColorContrast is the lowest contrast ratio (see
+// gtfs.ContrastRatio) between a route's route_color and route_text_color
+// not warned about: 3, WCAG's minimum for large text such as route badges
+const MinRouteColorContrast = 3.0
+
+// normalizeColors normalizes each route_color and route_text_color of feed
+// (see gtfs.NormalizeColor) and returns the number of values changed
+func normalizeColors(feed *gtfs.Feed) int {
+	changed := 0
+	for _, id := range feed.RouteOrder {
+		route := feed.Routes[id]
+		for _, color := range []*string{&route.Color, &route.TextColor} {
+			if normalized := gtfs.NormalizeColor(*color); normalized != *color {
+				*color = normalized
+				changed++
+			}
+		}
+	}
+	return changed
+}
+
+// checkRouteColors warns, in the report, about the routes of merged whose
+// route_text_color has too little contrast with their route_color (see
+// MinRouteColorContrast) to be read, taking the GTFS defaults for an empty
+// color. Routes setting neither color, or an invalid one, are skipped.
+func checkRouteColors(merged *gtfs.Feed, report *Report) {
+	var low []string
+	for _, id := range merged.RouteOrder {
+		route := merged.Routes[id]
+		if route.Color == "" && route.TextColor == "" {
+			continue
+		}
+		color, text := gtfs.NormalizeColor(route.Color), gtfs.NormalizeColor(route.TextColor)
+		if color == "" {
+			color = gtfs.DefaultRouteColor
+		}
+		if text == "" {
+			text = gtfs.DefaultRouteTextColor
+		}
+		if ratio, ok := gtfs.ContrastRatio(color, text); ok && ratio < MinRouteColorContrast {
+			low = append(low, fmt.Sprintf("%s (%s on %s, %.1f:1)", id, text, color, ratio))
+		}
+	}
+	if len(low) == 0 {
+		return
+	}
+	w := fmt.Sprintf("%d routes have route_text_color with too little contrast against route_color (below %.0f:1): %s",
+		len(low), MinRouteColorContrast, listRoutes(low))
+	log.Printf("WARNING: %s", w)
+	report.Warnings = append(report.Warnings, w)
+}
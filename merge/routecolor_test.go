@@ -0,0 +1,111 @@
+package merge
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfstest"
+)
+
+// routeColorFeeds returns a feed whose route r1 is colored "#ff0000" with
+// text "ffffff", and a feed whose route r2 is white text on yellow
+func routeColorFeeds() []*gtfs.Feed {
+	a := gtfstest.NewFeedBuilder().Service("s1").Route("r1").MustBuild()
+	// Set after building, as the builder only accepts valid colors
+	a.Routes["r1"].Color, a.Routes["r1"].TextColor = "#ff0000", "ffffff"
+	b := gtfstest.NewFeedBuilder().
+		Agency("b").
+		Service("s2").
+		Route("r2", func(r *gtfs.Route) { r.Color, r.TextColor = "FFFF00", "FFFFFF" }).
+		MustBuild()
+	return []*gtfs.Feed{a, b}
+}
+
+func TestWithNormalizeColors(t *testing.T) {
+	// Given: a route whose colors are lowercase, one with a leading '#'
+	feeds := routeColorFeeds()
+
+	// When: merged with color normalization and written
+	m := New(WithNormalizeColors(true))
+	merged, err := m.MergeFeeds(feeds)
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+	output := filepath.Join(t.TempDir(), "merged.zip")
+	if err := gtfs.WriteToPath(merged, output); err != nil {
+		t.Fatalf("WriteToPath failed: %v", err)
+	}
+
+	// Then: the colors are written as uppercase hex without '#'
+	written, err := gtfs.ReadFromPath(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if r := written.Routes["r1"]; r.Color != "FF0000" || r.TextColor != "FFFFFF" {
+		t.Errorf("Expected FFFFFF on FF0000, got %q on %q", r.TextColor, r.Color)
+	}
+	if errs := written.Validate(); len(errs) > 0 {
+		t.Errorf("Expected a valid feed, got %v", errs)
+	}
+
+	// And: the values changed are counted for that input only
+	if got := m.Report().Feeds[0].ColorsNormalized; got != 2 {
+		t.Errorf("Expected 2 colors normalized, got %d", got)
+	}
+	if got := m.Report().Feeds[1].ColorsNormalized; got != 0 {
+		t.Errorf("Expected no colors normalized in the second feed, got %d", got)
+	}
+}
+
+func TestCheckRouteColors(t *testing.T) {
+	// Given: white text on red, and white text on yellow
+	feeds := routeColorFeeds()
+
+	// When: merged
+	m := New()
+	if _, err := m.MergeFeeds(feeds); err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: only white on yellow is warned about
+	var warnings []string
+	for _, w := range m.Report().Warnings {
+		if strings.Contains(w, "contrast") {
+			warnings = append(warnings, w)
+		}
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "r2 (FFFFFF on FFFF00, 1.1:1)") || strings.Contains(warnings[0], "r1") {
+		t.Errorf("Expected one contrast warning naming r2, got %v", warnings)
+	}
+}
+
+func TestCheckRouteColorsDefaults(t *testing.T) {
+	tests := []struct {
+		name, color, textColor string
+		warn                   bool
+	}{
+		{"neither set", "", "", false},
+		{"black default text on dark blue", "000080", "", true},
+		{"white text on default white", "", "FFFFFF", true},
+		{"black default text on white", "FFFFFF", "", false},
+		{"invalid color skipped", "navy", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: a merged route with the colors
+			feed := gtfstest.NewFeedBuilder().Route("r1").MustBuild()
+			feed.Routes["r1"].Color, feed.Routes["r1"].TextColor = tt.color, tt.textColor
+
+			// When: its colors are checked
+			report := &Report{}
+			checkRouteColors(feed, report)
+
+			// Then: the defaults stand in for empty colors
+			if warned := len(report.Warnings) > 0; warned != tt.warn {
+				t.Errorf("Expected warning %v, got %v", tt.warn, report.Warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package merge
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// ExtendedRouteTypes maps the extended route_type codes that have a basic
+// GTFS equivalent to that basic type; see WithBasicRouteTypes. Codes not
+// listed, such as 1100 (air service), have none and are left unmapped.
+// Entries may be added or replaced before merging.
+var ExtendedRouteTypes = map[int]int{
+	// Railway service
+	100: 2, 101: 2, 102: 2, 103: 2, 104: 2, 105: 2, 106: 2, 107: 2, 108: 2,
+	109: 2, 110: 2, 111: 2, 112: 2, 113: 2, 114: 2, 115: 2, 116: 2, 117: 2,
+	// Coach service
+	200: 3, 201: 3, 202: 3, 203: 3, 204: 3, 205: 3, 206: 3, 207: 3, 208: 3,
+	209: 3,
+	// Urban railway service; 405 is monorail
+	400: 1, 401: 1, 402: 1, 403: 1, 404: 1, 405: 12,
+	// Bus service
+	700: 3, 701: 3, 702: 3, 703: 3, 704: 3, 705: 3, 706: 3, 707: 3, 708: 3,
+	709: 3, 710: 3, 711: 3, 712: 3, 713: 3, 714: 3, 715: 3, 716: 3,
+	// Trolleybus service
+	800: 11,
+	// Tram service
+	900: 0, 901: 0, 902: 0, 903: 0, 904: 0, 905: 0, 906: 0,
+	// Water transport and ferry service
+	1000: 4, 1200: 4,
+	// Aerial lift service
+	1300: 6, 1301: 6, 1302: 6, 1304: 6, 1305: 6, 1306: 6, 1307: 6,
+	// Funicular service
+	1400: 7,
+}
+
+// IsBasicRouteType reports whether t is one of the basic GTFS route types,
+// 0 to 7, 11 and 12
+func IsBasicRouteType(t int) bool {
+	return (t >= 0 && t <= 7) || t == 11 || t == 12
+}
+
+// BasicRouteType returns the basic GTFS route type equivalent to t: t
+// itself if basic, or its entry in ExtendedRouteTypes. It reports false if
+// t has no basic equivalent.
+func BasicRouteType(t int) (int, bool) {
+	if IsBasicRouteType(t) {
+		return t, true
+	}
+	basic, ok := ExtendedRouteTypes[t]
+	return basic, ok
+}
+
+// routeTypeResult is the outcome of mapping a feed's route types to basic
+// ones
+type routeTypeResult struct {
+	mapped int
+	// unmapped lists the routes whose extended types have no basic
+	// equivalent
+	unmapped []string
+}
+
+// warnings describes the routes of the feed named feedName left with
+// extended types
+func (r routeTypeResult) warnings(feedName string) []string {
+	if len(r.unmapped) == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("feed %s: %d routes have extended route types with no basic equivalent: %s",
+		feedName, len(r.unmapped), listRoutes(r.unmapped))}
+}
+
+// mapRouteTypes replaces each extended route_type of feed that has a basic
+// equivalent (see BasicRouteType) with it
+func mapRouteTypes(feed *gtfs.Feed) routeTypeResult {
+	var result routeTypeResult
+	for _, id := range feed.RouteOrder {
+		route := feed.Routes[id]
+		if IsBasicRouteType(route.Type) {
+			continue
+		}
+		basic, ok := BasicRouteType(route.Type)
+		if !ok {
+			result.unmapped = append(result.unmapped, fmt.Sprintf("%s (%d)", id, route.Type))
+			continue
+		}
+		route.Type = basic
+		result.mapped++
+	}
+	return result
+}
+
+// checkRouteTypes warns, in the report, when merged mixes basic and
+// extended route types, which consumers that only accept basic types
+// cannot read, listing the routes using extended types
+func checkRouteTypes(merged *gtfs.Feed, report *Report) {
+	var basic bool
+	var extended []string
+	for _, id := range merged.RouteOrder {
+		route := merged.Routes[id]
+		if IsBasicRouteType(route.Type) {
+			basic = true
+		} else {
+			extended = append(extended, fmt.Sprintf("%s (%d)", id, route.Type))
+		}
+	}
+	if !basic || len(extended) == 0 {
+		return
+	}
+	w := fmt.Sprintf("merged feed mixes basic and extended route types; %d routes have extended types (see WithBasicRouteTypes): %s",
+		len(extended), listRoutes(extended))
+	log.Printf("WARNING: %s", w)
+	report.Warnings = append(report.Warnings, w)
+}
+
+// maxListedRoutes is the most routes a route type warning names
+const maxListedRoutes = 10
+
+// listRoutes joins routes for a warning, naming at most maxListedRoutes
+func listRoutes(routes []string) string {
+	if len(routes) <= maxListedRoutes {
+		return strings.Join(routes, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(routes[:maxListedRoutes], ", "), len(routes)-maxListedRoutes)
+}
@@ -0,0 +1,127 @@
+package merge
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+func TestBasicRouteType(t *testing.T) {
+	tests := []struct {
+		routeType int
+		want      int
+		ok        bool
+	}{
+		{3, 3, true},
+		{12, 12, true},
+		{702, 3, true},
+		{109, 2, true},
+		{401, 1, true},
+		{405, 12, true},
+		{800, 11, true},
+		{900, 0, true},
+		{1200, 4, true},
+		{1400, 7, true},
+		{1100, 0, false},
+		{8, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := BasicRouteType(tt.routeType)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("BasicRouteType(%d) = %d, %v; expected %d, %v", tt.routeType, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	// The table can be extended
+	ExtendedRouteTypes[1500] = 3
+	defer delete(ExtendedRouteTypes, 1500)
+	if got, ok := BasicRouteType(1500); !ok || got != 3 {
+		t.Errorf("Expected an added entry to map 1500 to 3, got %d, %v", got, ok)
+	}
+}
+
+func TestMapRouteTypes(t *testing.T) {
+	// Given: routes of basic, mappable and unmappable extended types
+	feed := gtfs.NewFeed()
+	for id, routeType := range map[gtfs.RouteID]int{"bus": 3, "express": 702, "suburban": 109, "air": 1100} {
+		feed.AddRoute(&gtfs.Route{ID: id, ShortName: string(id), Type: routeType})
+	}
+
+	// When: mapped
+	result := mapRouteTypes(feed)
+
+	// Then: the mappable types are replaced and the others listed
+	if result.mapped != 2 || !slices.Equal(result.unmapped, []string{"air (1100)"}) {
+		t.Errorf("Expected 2 mapped and air unmapped, got %+v", result)
+	}
+	for id, want := range map[gtfs.RouteID]int{"bus": 3, "express": 3, "suburban": 2, "air": 1100} {
+		if got := feed.Routes[id].Type; got != want {
+			t.Errorf("Expected %s to have type %d, got %d", id, want, got)
+		}
+	}
+}
+
+func TestWithBasicRouteTypes(t *testing.T) {
+	// routeTypeFeeds returns two feeds of the same agency's express route,
+	// serving the same stop, typed 702 (express bus) in the first and 3
+	// (bus) in the second
+	routeTypeFeeds := func() []*gtfs.Feed {
+		feeds := make([]*gtfs.Feed, 2)
+		for i, routeType := range []int{702, 3} {
+			p := GetPrefixForIndex(i + 1)
+			f := gtfs.NewFeed()
+			f.AddAgency(&gtfs.Agency{ID: gtfs.AgencyID(p + "metro"), Name: "Metro", URL: "http://example.com", Timezone: "UTC"})
+			f.AddStop(&gtfs.Stop{ID: "main", Name: "Main St", Lat: 47.6, Lon: -122.3})
+			f.AddRoute(&gtfs.Route{ID: gtfs.RouteID(p + "express"), AgencyID: gtfs.AgencyID(p + "metro"), ShortName: "E", Type: routeType})
+			f.AddCalendar(&gtfs.Calendar{ServiceID: gtfs.ServiceID(p + "wkdy"), Monday: true, StartDate: "20240101", EndDate: "20241231"})
+			f.AddTrip(&gtfs.Trip{ID: gtfs.TripID(p + "trip"), RouteID: gtfs.RouteID(p + "express"), ServiceID: gtfs.ServiceID(p + "wkdy")})
+			f.StopTimes = append(f.StopTimes, &gtfs.StopTime{TripID: gtfs.TripID(p + "trip"), StopID: "main", StopSequence: 1})
+			feeds[i] = f
+		}
+		return feeds
+	}
+	fuzzy := WithDefaultDetection(strategy.DetectionFuzzy)
+
+	t.Run("off", func(t *testing.T) {
+		// When: merged without mapping
+		m := New(fuzzy)
+		merged, err := m.MergeFeeds(routeTypeFeeds())
+		if err != nil {
+			t.Fatalf("MergeFeeds failed: %v", err)
+		}
+
+		// Then: the routes are kept apart and the mix is warned about
+		if len(merged.Routes) != 2 {
+			t.Errorf("Expected 2 routes, got %d", len(merged.Routes))
+		}
+		want := "merged feed mixes basic and extended route types; 1 routes have extended types (see WithBasicRouteTypes): a-express (702)"
+		if !slices.Contains(m.Report().Warnings, want) {
+			t.Errorf("Expected warning %q, got %q", want, m.Report().Warnings)
+		}
+	})
+
+	t.Run("on", func(t *testing.T) {
+		// When: merged mapping extended types to basic ones
+		m := New(fuzzy, WithBasicRouteTypes(true))
+		merged, err := m.MergeFeeds(routeTypeFeeds())
+		if err != nil {
+			t.Fatalf("MergeFeeds failed: %v", err)
+		}
+
+		// Then: the express route matches its basic-typed counterpart
+		if len(merged.Routes) != 1 || merged.Routes["b-express"] == nil || merged.Routes["b-express"].Type != 3 {
+			t.Errorf("Expected only b-express, of type 3, got %v", merged.RouteOrder)
+		}
+		if n := m.Report().Feeds[0].RouteTypesMapped; n != 1 {
+			t.Errorf("Expected 1 route type mapped, got %d", n)
+		}
+		for _, w := range m.Report().Warnings {
+			if strings.Contains(w, "route types") {
+				t.Errorf("Expected no route type warning, got %q", w)
+			}
+		}
+	})
+}
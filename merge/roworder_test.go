@@ -0,0 +1,127 @@
+package merge
+
+import (
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// shuffledFeedDir copies the feed at src to a temporary directory with the
+// data rows of each file shuffled, leaving the headers in place. The
+// fixtures have no quoted line breaks, so rows are lines.
+func shuffledFeedDir(t *testing.T, src string, seed uint64) string {
+	t.Helper()
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", src, err)
+	}
+	rng := rand.New(rand.NewPCG(seed, seed))
+	dir := t.TempDir()
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(src, e.Name()))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", e.Name(), err)
+		}
+		lines := strings.Split(strings.TrimRight(string(data), "\r\n"), "\n")
+		rows := lines[1:]
+		rng.Shuffle(len(rows), func(i, j int) { rows[i], rows[j] = rows[j], rows[i] })
+		if err := os.WriteFile(filepath.Join(dir, e.Name()), []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", e.Name(), err)
+		}
+	}
+	return dir
+}
+
+// rowOrderOutcome is what a merge must produce regardless of the order of
+// rows within its input files
+type rowOrderOutcome struct {
+	Sources  map[gtfs.EntityKind]map[string][]int
+	Merged   map[string]int
+	Added    []map[string]int
+	Agencies map[gtfs.AgencyID]*gtfs.Agency
+	Stops    map[gtfs.StopID]*gtfs.Stop
+	Routes   map[gtfs.RouteID]*gtfs.Route
+	Trips    map[gtfs.TripID]*gtfs.Trip
+}
+
+// mergeRowOrder merges the feeds at paths and returns the outcome
+func mergeRowOrder(t *testing.T, detection strategy.DuplicateDetection, paths []string) rowOrderOutcome {
+	t.Helper()
+	var feeds []*gtfs.Feed
+	for _, path := range paths {
+		feed, err := gtfs.ReadFromPath(path)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", path, err)
+		}
+		feeds = append(feeds, feed)
+	}
+	m := New(WithDefaultDetection(detection))
+	merged, err := m.MergeFeeds(feeds)
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+	outcome := rowOrderOutcome{
+		Sources:  merged.Sources,
+		Merged:   m.Report().Merged,
+		Agencies: merged.Agencies,
+		Stops:    merged.Stops,
+		Routes:   merged.Routes,
+		Trips:    merged.Trips,
+	}
+	for _, fr := range m.Report().Feeds {
+		outcome.Added = append(outcome.Added, fr.Added)
+	}
+	return outcome
+}
+
+func TestMergeIgnoresRowOrder(t *testing.T) {
+	inputs := [][]string{
+		{"simple_a", "simple_b"},
+		{"simple_a", "fuzzy_similar"},
+		{"simple_a", "simple_a"},
+		{"overlap", "simple_a"},
+		{"all_optional_feed", "all_optional_feed"},
+		{"direction_zero_north", "direction_zero_south"},
+		{"tied_candidates", "tied_candidates"},
+	}
+	for _, detection := range []strategy.DuplicateDetection{strategy.DetectionIdentity, strategy.DetectionFuzzy} {
+		for _, names := range inputs {
+			t.Run(detection.String()+"/"+strings.Join(names, "+"), func(t *testing.T) {
+				// Given: the fixture feeds, as is and with their rows shuffled
+				var paths []string
+				for _, name := range names {
+					paths = append(paths, filepath.Join("..", "testdata", name))
+				}
+				want := mergeRowOrder(t, detection, paths)
+
+				for seed := uint64(1); seed <= 5; seed++ {
+					var shuffled []string
+					for i, path := range paths {
+						shuffled = append(shuffled, shuffledFeedDir(t, path, seed*10+uint64(i)))
+					}
+
+					// When: the shuffled feeds are merged
+					got := mergeRowOrder(t, detection, shuffled)
+
+					// Then: the same entities come from the same inputs
+					if !reflect.DeepEqual(got.Sources, want.Sources) {
+						t.Errorf("seed %d: expected sources %v, got %v", seed, want.Sources, got.Sources)
+					}
+					if !reflect.DeepEqual(got.Merged, want.Merged) || !reflect.DeepEqual(got.Added, want.Added) {
+						t.Errorf("seed %d: expected counts %v %v, got %v %v", seed, want.Merged, want.Added, got.Merged, got.Added)
+					}
+					if !reflect.DeepEqual(got.Agencies, want.Agencies) || !reflect.DeepEqual(got.Stops, want.Stops) ||
+						!reflect.DeepEqual(got.Routes, want.Routes) || !reflect.DeepEqual(got.Trips, want.Trips) {
+						t.Errorf("seed %d: expected the same merged agencies, stops, routes and trips", seed)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package merge
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// sanitizeResult describes what sanitizeFeed changed in one input feed
+type sanitizeResult struct {
+	// removed is the number of rows dropped, keyed by filename
+	removed map[string]int
+
+	// cleared is the number of optional references cleared, keyed by
+	// filename and field (e.g. "trips.txt shape_id")
+	cleared map[string]int
+}
+
+// sanitizeFeed drops the rows of feed whose required references name
+// entities the feed does not have, cascading to the rows that referenced
+// them: routes with an unknown agency_id, trips with an unknown route_id or
+// a service_id in neither calendar.txt nor calendar_dates.txt, stop times
+// with an unknown trip or stop, frequencies with an unknown trip, transfers
+// and pathways with an unknown stop, fare attributes with an unknown agency,
+// fare rules with an unknown fare or route, route networks with an unknown
+// network or route, timeframes with an unknown service, fare products with
+// an unknown rider category or fare media, fare leg rules with an unknown
+// fare product or timeframe group, and fare transfer rules with an unknown
+// fare product or a leg group no fare leg rule defines. An unknown shape_id
+// on a trip or parent_station on a stop is optional, so it is cleared
+// instead.
+func sanitizeFeed(feed *gtfs.Feed) sanitizeResult {
+	res := sanitizeResult{removed: make(map[string]int), cleared: make(map[string]int)}
+
+	for _, id := range feed.StopOrder {
+		stop := feed.Stops[id]
+		if stop.ParentStation != "" && feed.Stops[stop.ParentStation] == nil {
+			stop.ParentStation = ""
+			res.cleared["stops.txt parent_station"]++
+		}
+	}
+
+	feed.RouteOrder = slices.DeleteFunc(feed.RouteOrder, func(id gtfs.RouteID) bool {
+		route := feed.Routes[id]
+		if route.AgencyID == "" || feed.Agencies[route.AgencyID] != nil {
+			return false
+		}
+		delete(feed.Routes, id)
+		res.removed["routes.txt"]++
+		return true
+	})
+
+	feed.TripOrder = slices.DeleteFunc(feed.TripOrder, func(id gtfs.TripID) bool {
+		trip := feed.Trips[id]
+		_, inCalendar := feed.Calendars[trip.ServiceID]
+		_, inCalendarDates := feed.CalendarDates[trip.ServiceID]
+		if feed.Routes[trip.RouteID] == nil || (!inCalendar && !inCalendarDates) {
+			delete(feed.Trips, id)
+			res.removed["trips.txt"]++
+			return true
+		}
+		if _, ok := feed.Shapes[trip.ShapeID]; trip.ShapeID != "" && !ok {
+			trip.ShapeID = ""
+			res.cleared["trips.txt shape_id"]++
+		}
+		return false
+	})
+
+	feed.StopTimes = dropRows(feed.StopTimes, "stop_times.txt", res.removed, func(st *gtfs.StopTime) bool {
+		return feed.Trips[st.TripID] == nil || feed.Stops[st.StopID] == nil
+	})
+	feed.Frequencies = dropRows(feed.Frequencies, "frequencies.txt", res.removed, func(f *gtfs.Frequency) bool {
+		return feed.Trips[f.TripID] == nil
+	})
+	feed.Transfers = dropRows(feed.Transfers, "transfers.txt", res.removed, func(tr *gtfs.Transfer) bool {
+		return feed.Stops[tr.FromStopID] == nil || feed.Stops[tr.ToStopID] == nil
+	})
+	feed.Pathways = dropRows(feed.Pathways, "pathways.txt", res.removed, func(pw *gtfs.Pathway) bool {
+		return feed.Stops[pw.FromStopID] == nil || feed.Stops[pw.ToStopID] == nil
+	})
+
+	feed.RouteNetworks = dropRows(feed.RouteNetworks, "route_networks.txt", res.removed, func(rn *gtfs.RouteNetwork) bool {
+		return feed.Networks[rn.NetworkID] == nil || feed.Routes[rn.RouteID] == nil
+	})
+
+	feed.Timeframes = dropRows(feed.Timeframes, "timeframes.txt", res.removed, func(tf *gtfs.Timeframe) bool {
+		_, inCalendar := feed.Calendars[tf.ServiceID]
+		_, inCalendarDates := feed.CalendarDates[tf.ServiceID]
+		return !inCalendar && !inCalendarDates
+	})
+	feed.FareProducts = dropRows(feed.FareProducts, "fare_products.txt", res.removed, func(product *gtfs.FareProduct) bool {
+		return (product.RiderCategoryID != "" && feed.RiderCategories[product.RiderCategoryID] == nil) ||
+			(product.FareMediaID != "" && feed.FareMedia[product.FareMediaID] == nil)
+	})
+	products := feed.FareProductIDs()
+	timeframes := feed.TimeframeGroupIDs()
+	feed.FareLegRules = dropRows(feed.FareLegRules, "fare_leg_rules.txt", res.removed, func(rule *gtfs.FareLegRule) bool {
+		return (rule.FareProductID != "" && !products[rule.FareProductID]) ||
+			(rule.FromTimeframeGroupID != "" && !timeframes[rule.FromTimeframeGroupID]) ||
+			(rule.ToTimeframeGroupID != "" && !timeframes[rule.ToTimeframeGroupID])
+	})
+	legGroups := feed.LegGroupIDs()
+	feed.FareTransferRules = dropRows(feed.FareTransferRules, "fare_transfer_rules.txt", res.removed, func(rule *gtfs.FareTransferRule) bool {
+		return (rule.FromLegGroupID != "" && !legGroups[rule.FromLegGroupID]) || (rule.ToLegGroupID != "" && !legGroups[rule.ToLegGroupID]) ||
+			(rule.FareProductID != "" && !products[rule.FareProductID])
+	})
+
+	feed.FareAttrOrder = slices.DeleteFunc(feed.FareAttrOrder, func(id gtfs.FareID) bool {
+		fare := feed.FareAttributes[id]
+		if fare.AgencyID == "" || feed.Agencies[fare.AgencyID] != nil {
+			return false
+		}
+		delete(feed.FareAttributes, id)
+		res.removed["fare_attributes.txt"]++
+		return true
+	})
+	feed.FareRules = dropRows(feed.FareRules, "fare_rules.txt", res.removed, func(fr *gtfs.FareRule) bool {
+		return feed.FareAttributes[fr.FareID] == nil || (fr.RouteID != "" && feed.Routes[fr.RouteID] == nil)
+	})
+
+	return res
+}
+
+// dropRows deletes the rows for which drop returns true, counting them in
+// removed under filename
+func dropRows[T any](rows []T, filename string, removed map[string]int, drop func(T) bool) []T {
+	before := len(rows)
+	rows = slices.DeleteFunc(rows, drop)
+	if n := before - len(rows); n > 0 {
+		removed[filename] += n
+	}
+	return rows
+}
+
+// warnings describes the references res cleared, for Report.Warnings
+func (res sanitizeResult) warnings(feedName string) []string {
+	var warnings []string
+	for _, field := range []string{"stops.txt parent_station", "trips.txt shape_id"} {
+		if n := res.cleared[field]; n > 0 {
+			warnings = append(warnings, fmt.Sprintf("feed %s: cleared %d dangling %s references", feedName, n, field))
+		}
+	}
+	return warnings
+}
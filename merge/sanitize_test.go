@@ -0,0 +1,152 @@
+package merge
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// winterFeed returns a feed whose "winter" trips have a service_id in
+// neither calendar.txt nor calendar_dates.txt, along with other dangling
+// references
+func winterFeed() *gtfs.Feed {
+	f := gtfs.NewFeed()
+	f.AddAgency(&gtfs.Agency{ID: "agency", Name: "Agency", URL: "http://example.com", Timezone: "UTC"})
+	f.AddStop(&gtfs.Stop{ID: "s1", Name: "One", ParentStation: "gone"})
+	f.AddStop(&gtfs.Stop{ID: "s2", Name: "Two"})
+	f.AddRoute(&gtfs.Route{ID: "r1", AgencyID: "agency", ShortName: "1", Type: 3})
+	f.AddRoute(&gtfs.Route{ID: "r2", AgencyID: "other", ShortName: "2", Type: 3})
+	f.AddCalendar(&gtfs.Calendar{ServiceID: "wkdy", Monday: true, StartDate: "20240101", EndDate: "20241231"})
+	f.AddTrip(&gtfs.Trip{ID: "t1", RouteID: "r1", ServiceID: "wkdy", ShapeID: "gone"})
+	f.AddTrip(&gtfs.Trip{ID: "winter", RouteID: "r1", ServiceID: "winter"})
+	f.AddTrip(&gtfs.Trip{ID: "t2", RouteID: "r2", ServiceID: "wkdy"})
+	for _, trip := range []gtfs.TripID{"t1", "winter", "t2"} {
+		f.StopTimes = append(f.StopTimes,
+			&gtfs.StopTime{TripID: trip, StopID: "s1", StopSequence: 1, ArrivalTime: "08:00:00", DepartureTime: "08:00:00"},
+			&gtfs.StopTime{TripID: trip, StopID: "s2", StopSequence: 2, ArrivalTime: "08:10:00", DepartureTime: "08:10:00"})
+	}
+	f.Frequencies = append(f.Frequencies, &gtfs.Frequency{TripID: "winter", StartTime: "06:00:00", EndTime: "09:00:00", HeadwaySecs: 600})
+	f.Transfers = append(f.Transfers, &gtfs.Transfer{FromStopID: "s1", ToStopID: "gone"})
+	f.AddFareAttribute(&gtfs.FareAttribute{FareID: "f1", Price: 2.50, CurrencyType: "USD"})
+	f.FareRules = append(f.FareRules, &gtfs.FareRule{FareID: "f1", RouteID: "r2"}, &gtfs.FareRule{FareID: "f1", RouteID: "r1"})
+	return f
+}
+
+func TestSanitizeFeed(t *testing.T) {
+	// Given: a feed with dangling references
+	feed := winterFeed()
+
+	// When: sanitized
+	res := sanitizeFeed(feed)
+
+	// Then: rows with dangling required references are dropped, cascading
+	expected := map[string]int{
+		"routes.txt":      1,
+		"trips.txt":       2,
+		"stop_times.txt":  4,
+		"frequencies.txt": 1,
+		"transfers.txt":   1,
+		"fare_rules.txt":  1,
+	}
+	if len(res.removed) != len(expected) {
+		t.Errorf("Expected removals %v, got %v", expected, res.removed)
+	}
+	for file, n := range expected {
+		if res.removed[file] != n {
+			t.Errorf("Expected %d rows removed from %s, got %d", n, file, res.removed[file])
+		}
+	}
+	if len(feed.TripOrder) != 1 || feed.Trips["t1"] == nil || len(feed.Trips) != 1 {
+		t.Errorf("Expected only trip t1 to remain, got %v", feed.TripOrder)
+	}
+
+	// And: dangling optional references are cleared instead
+	if feed.Trips["t1"].ShapeID != "" || feed.Stops["s1"].ParentStation != "" {
+		t.Errorf("Expected shape_id and parent_station to be cleared")
+	}
+	if res.cleared["trips.txt shape_id"] != 1 || res.cleared["stops.txt parent_station"] != 1 {
+		t.Errorf("Expected one of each reference cleared, got %v", res.cleared)
+	}
+	if errs := feed.Validate(); len(errs) != 0 {
+		t.Errorf("Expected a valid feed after sanitizing, got %v", errs)
+	}
+}
+
+func TestSanitizeFeedFaresV2(t *testing.T) {
+	// Given: a Fares v2 feed whose express fares name a rider category, a
+	// timeframe's service and a fare product it does not have
+	feed, err := gtfs.ReadFromPath("../testdata/fares_v2")
+	if err != nil {
+		t.Fatalf("failed to read fares_v2: %v", err)
+	}
+	feed.FareProducts[2].RiderCategoryID = "student"
+	feed.Timeframes[0].ServiceID = "winter"
+	feed.FareTransferRules[1].FareProductID = "gone"
+
+	// When: sanitized
+	res := sanitizeFeed(feed)
+
+	// Then: the rows with dangling references are dropped, cascading to the
+	// express leg group and the transfer into it
+	expected := map[string]int{
+		"timeframes.txt":          1,
+		"fare_products.txt":       1,
+		"fare_leg_rules.txt":      1,
+		"fare_transfer_rules.txt": 2,
+	}
+	if len(res.removed) != len(expected) {
+		t.Errorf("Expected removals %v, got %v", expected, res.removed)
+	}
+	for file, n := range expected {
+		if res.removed[file] != n {
+			t.Errorf("Expected %d rows removed from %s, got %d", n, file, res.removed[file])
+		}
+	}
+	if len(feed.FareLegRules) != 1 || feed.FareLegRules[0].LegGroupID != "local_leg" {
+		t.Errorf("Expected only the local_leg rule to remain, got %d rules", len(feed.FareLegRules))
+	}
+	if errs := feed.Validate(); len(errs) != 0 {
+		t.Errorf("Expected a valid feed after sanitizing, got %v", errs)
+	}
+}
+
+func TestMergeFilesSanitizeInputs(t *testing.T) {
+	// Given: a winter input with trips on a service it zeroed out, merged
+	// with a clean feed
+	tmpDir := t.TempDir()
+	winterPath := filepath.Join(tmpDir, "winter.zip")
+	if err := gtfs.WriteToPath(winterFeed(), winterPath); err != nil {
+		t.Fatalf("failed to write winter.zip: %v", err)
+	}
+	inputs := []string{winterPath, "../testdata/simple_b"}
+	output := filepath.Join(tmpDir, "merged.zip")
+
+	// When: merged with validation alone, it fails
+	if err := New(WithValidateInputs(true)).MergeFiles(inputs, output); !errors.Is(err, ErrInvalidFeed) {
+		t.Fatalf("Expected ErrInvalidFeed without sanitizing, got %v", err)
+	}
+
+	// When: merged with sanitizing as well
+	m := New(WithSanitizeInputs(true), WithValidateInputs(true))
+	if err := m.MergeFiles(inputs, output); err != nil {
+		t.Fatalf("MergeFiles failed: %v", err)
+	}
+
+	// Then: the removals are reported for the winter input only
+	report := m.Report()
+	winter := report.FeedByPath(winterPath)
+	if winter == nil {
+		t.Fatalf("Expected a report for %s", winterPath)
+	}
+	if winter.Sanitized["trips.txt"] != 2 {
+		t.Errorf("Expected 2 trips dropped, got %v", winter.Sanitized)
+	}
+	if clean := report.FeedByPath("../testdata/simple_b"); clean == nil || len(clean.Sanitized) != 0 {
+		t.Errorf("Expected nothing dropped from simple_b, got %+v", clean)
+	}
+	if len(report.Warnings) != 2 {
+		t.Errorf("Expected warnings for the cleared references, got %v", report.Warnings)
+	}
+}
@@ -0,0 +1,100 @@
+package merge
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// textResult is the outcome of cleaning one feed's text fields
+type textResult struct {
+	// cleaned is the number of values changed, keyed by filename
+	cleaned map[string]int
+}
+
+// clean cleans each of fields (see cleanText), counting those changed
+// under filename; name fields are also trimmed
+func (res textResult) clean(filename string, name bool, fields ...*string) {
+	for _, field := range fields {
+		if v, changed := cleanText(*field, name); changed {
+			*field = v
+			res.cleaned[filename]++
+		}
+	}
+}
+
+// sanitizeText cleans the free-text fields of feed: names, descriptions,
+// headsigns, signposts and contact details. IDs, times, dates, colors and
+// other coded values are left as they are.
+func sanitizeText(feed *gtfs.Feed) textResult {
+	res := textResult{cleaned: make(map[string]int)}
+	for _, id := range feed.AgencyOrder {
+		a := feed.Agencies[id]
+		res.clean("agency.txt", true, &a.Name)
+		res.clean("agency.txt", false, &a.URL, &a.Phone, &a.FareURL, &a.Email)
+	}
+	for _, id := range feed.StopOrder {
+		s := feed.Stops[id]
+		res.clean("stops.txt", true, &s.Name)
+		res.clean("stops.txt", false, &s.Code, &s.Desc, &s.URL, &s.PlatformCode)
+	}
+	for _, id := range feed.RouteOrder {
+		r := feed.Routes[id]
+		res.clean("routes.txt", true, &r.ShortName, &r.LongName)
+		res.clean("routes.txt", false, &r.Desc, &r.URL)
+	}
+	for _, id := range feed.TripOrder {
+		t := feed.Trips[id]
+		res.clean("trips.txt", true, &t.Headsign, &t.ShortName)
+	}
+	for _, st := range feed.StopTimes {
+		res.clean("stop_times.txt", true, &st.StopHeadsign)
+	}
+	for _, p := range feed.Pathways {
+		res.clean("pathways.txt", false, &p.SignpostedAs, &p.ReversedSignpostedAs)
+	}
+	for _, id := range feed.AreaOrder {
+		res.clean("areas.txt", true, &feed.Areas[id].Name)
+	}
+	for _, id := range feed.NetworkOrder {
+		res.clean("networks.txt", true, &feed.Networks[id].Name)
+	}
+	for _, p := range feed.FareProducts {
+		res.clean("fare_products.txt", true, &p.Name)
+	}
+	for _, id := range feed.FareMediaOrder {
+		res.clean("fare_media.txt", true, &feed.FareMedia[id].Name)
+	}
+	for _, id := range feed.RiderCategoryOrder {
+		c := feed.RiderCategories[id]
+		res.clean("rider_categories.txt", true, &c.Name)
+		res.clean("rider_categories.txt", false, &c.EligibilityURL)
+	}
+	for _, fi := range feed.FeedInfos {
+		res.clean("feed_info.txt", true, &fi.PublisherName)
+		res.clean("feed_info.txt", false, &fi.PublisherURL, &fi.ContactEmail, &fi.ContactURL)
+	}
+	return res
+}
+
+// cleanText replaces each C0 control character in s, tab and line breaks
+// included, with a space and each invalid UTF-8 sequence with U+FFFD, and
+// with trim removes leading and trailing whitespace. It reports whether s
+// changed.
+func cleanText(s string, trim bool) (string, bool) {
+	cleaned := s
+	if !utf8.ValidString(cleaned) {
+		cleaned = strings.ToValidUTF8(cleaned, string(utf8.RuneError))
+	}
+	cleaned = strings.Map(func(r rune) rune {
+		if r < 0x20 {
+			return ' '
+		}
+		return r
+	}, cleaned)
+	if trim {
+		cleaned = strings.TrimSpace(cleaned)
+	}
+	return cleaned, cleaned != s
+}
@@ -0,0 +1,161 @@
+package merge
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfstest"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+func TestCleanText(t *testing.T) {
+	tests := []struct {
+		name, in string
+		trim     bool
+		want     string
+		changed  bool
+	}{
+		{"clean", "Main St", true, "Main St", false},
+		{"vertical tab", "Upper\x0Blevel", false, "Upper level", true},
+		{"tab and line break", "a\tb\r\nc", false, "a b  c", true},
+		{"NUL", "x\x00", false, "x ", true},
+		{"invalid UTF-8", "Caf\xe9 Line", false, "Caf� Line", true},
+		{"valid UTF-8 kept", "Café — Línea", false, "Café — Línea", false},
+		{"name trimmed", "  Main St\x0B", true, "Main St", true},
+		{"other field not trimmed", " desc ", false, " desc ", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := cleanText(tt.in, tt.trim)
+			if got != tt.want || changed != tt.changed {
+				t.Errorf("Expected %q (changed %v), got %q (changed %v)", tt.want, tt.changed, got, changed)
+			}
+		})
+	}
+}
+
+// writeDirtyFeed writes to a new directory a feed whose stop_desc holds a
+// vertical tab, whose route_long_name holds an invalid UTF-8 byte, and
+// whose stop_name has surrounding whitespace, and returns the directory
+func writeDirtyFeed(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"agency.txt":     "agency_id,agency_name,agency_url,agency_timezone\ndirty,Dirty Transit,http://dirty.example.com,America/Los_Angeles\n",
+		"stops.txt":      "stop_id,stop_name,stop_desc,stop_lat,stop_lon\nd1,\" Dirty Stop \",Upper\x0Blevel,47.6,-122.3\nd2,Clean Stop,,47.61,-122.31\n",
+		"routes.txt":     "route_id,agency_id,route_short_name,route_long_name,route_type\ndr,dirty,D,Caf\xe9 Line,3\n",
+		"trips.txt":      "route_id,service_id,trip_id\ndr,dsvc,dt\n",
+		"stop_times.txt": "trip_id,arrival_time,departure_time,stop_id,stop_sequence\ndt,08:00:00,08:00:00,d1,1\ndt,08:10:00,08:10:00,d2,2\n",
+		"calendar.txt":   "service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date\ndsvc,1,1,1,1,1,0,0,20240101,20241231\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestWithSanitizeText(t *testing.T) {
+	// Given: an input with a control character, an invalid UTF-8 byte and
+	// a padded name
+	dirty := writeDirtyFeed(t)
+	output := filepath.Join(t.TempDir(), "merged.zip")
+
+	// When: merged with text sanitizing
+	m := New(WithSanitizeText(true))
+	if err := m.MergeFiles([]string{dirty, "../testdata/simple_b"}, output); err != nil {
+		t.Fatalf("MergeFiles failed: %v", err)
+	}
+
+	// Then: the merged values are clean
+	merged, err := gtfs.ReadFromPath(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if got := merged.Stops["d1"].Desc; got != "Upper level" {
+		t.Errorf("Expected stop_desc %q, got %q", "Upper level", got)
+	}
+	if got := merged.Stops["d1"].Name; got != "Dirty Stop" {
+		t.Errorf("Expected stop_name %q, got %q", "Dirty Stop", got)
+	}
+	if got := merged.Routes["dr"].LongName; got != "Caf� Line" {
+		t.Errorf("Expected route_long_name %q, got %q", "Caf� Line", got)
+	}
+
+	// And: the cleaned values are counted by file for that input only
+	feeds := m.Report().Feeds
+	if got := feeds[0].TextSanitized; got["stops.txt"] != 2 || got["routes.txt"] != 1 || len(got) != 2 {
+		t.Errorf("Expected 2 stops.txt and 1 routes.txt values cleaned, got %v", got)
+	}
+	if got := feeds[1].TextSanitized; len(got) != 0 {
+		t.Errorf("Expected nothing cleaned in simple_b, got %v", got)
+	}
+}
+
+func TestWithSanitizeTextBeforeDetection(t *testing.T) {
+	// Given: two feeds with the same stop under different IDs, one's name
+	// ending in a vertical tab
+	newFeeds := func() []*gtfs.Feed {
+		a := gtfstest.NewFeedBuilder().Stop("s1", 47.6, -122.3, func(s *gtfs.Stop) { s.Name = "Main St" }).MustBuild()
+		b := gtfstest.NewFeedBuilder().Stop("x1", 47.6, -122.3, func(s *gtfs.Stop) { s.Name = "Main St\x0B" }).MustBuild()
+		return []*gtfs.Feed{a, b}
+	}
+
+	// When: merged by fuzzy detection without text sanitizing
+	merged, err := New(WithDefaultDetection(strategy.DetectionFuzzy)).MergeFeeds(newFeeds())
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: the names differ and both stops are kept
+	if len(merged.StopOrder) != 2 {
+		t.Fatalf("Expected 2 stops without sanitizing, got %v", merged.StopOrder)
+	}
+
+	// When: merged by fuzzy detection with text sanitizing
+	merged, err = New(WithDefaultDetection(strategy.DetectionFuzzy), WithSanitizeText(true)).MergeFeeds(newFeeds())
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: the cleaned names match and one stop is kept
+	if len(merged.StopOrder) != 1 {
+		t.Errorf("Expected 1 stop with sanitizing, got %v", merged.StopOrder)
+	}
+}
+
+func TestSanitizeTextFaresV2(t *testing.T) {
+	// Given: a Fares v2 feed whose product, media and rider category names
+	// hold control characters and padding
+	feed, err := gtfs.ReadFromPath("../testdata/fares_v2")
+	if err != nil {
+		t.Fatalf("failed to read fares_v2: %v", err)
+	}
+	feed.FareProducts[0].Name = " Single\x0BRide "
+	feed.FareMedia["card"].Name = "Transit\tCard"
+	feed.RiderCategories["senior"].Name = "Senior\r\n"
+
+	// When: its text is sanitized
+	res := sanitizeText(feed)
+
+	// Then: the names are clean
+	if got := feed.FareProducts[0].Name; got != "Single Ride" {
+		t.Errorf("Expected fare_product_name %q, got %q", "Single Ride", got)
+	}
+	if got := feed.FareMedia["card"].Name; got != "Transit Card" {
+		t.Errorf("Expected fare_media_name %q, got %q", "Transit Card", got)
+	}
+	if got := feed.RiderCategories["senior"].Name; got != "Senior" {
+		t.Errorf("Expected rider_category_name %q, got %q", "Senior", got)
+	}
+
+	// And: each is counted under its file
+	want := map[string]int{"fare_products.txt": 1, "fare_media.txt": 1, "rider_categories.txt": 1}
+	if !maps.Equal(res.cleaned, want) {
+		t.Errorf("Expected %v cleaned, got %v", want, res.cleaned)
+	}
+}
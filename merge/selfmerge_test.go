@@ -0,0 +1,44 @@
+package merge
+
+import (
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// TestSelfMergeIdempotent guards that merging a feed with a copy of itself
+// under identity detection reproduces the feed: every file keeps its row
+// count, including files like stop_times.txt that have no IDs of their own.
+// Feeds whose agency omits agency_id are excluded, since each feed's agency
+// is given a feed-specific ID.
+func TestSelfMergeIdempotent(t *testing.T) {
+	for _, fixture := range []string{"simple_a", "simple_b", "overlap", "minimal", "all_optional_feed", "unicode_feed", "special_chars_feed", "large_ids_feed"} {
+		t.Run(fixture, func(t *testing.T) {
+			// Given: a feed and an identical copy
+			path := "../testdata/" + fixture
+			original, err := gtfs.ReadFromPath(path)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", fixture, err)
+			}
+			copied, err := gtfs.ReadFromPath(path)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", fixture, err)
+			}
+
+			// When: merged with identity detection
+			merged, err := New(WithDefaultDetection(strategy.DetectionIdentity)).MergeFeeds([]*gtfs.Feed{original, copied})
+			if err != nil {
+				t.Fatalf("MergeFeeds failed: %v", err)
+			}
+
+			// Then: every file has the original's row count
+			want, got := original.RowCounts(), merged.RowCounts()
+			for _, filename := range gtfs.FileNames() {
+				if got[filename] != want[filename] {
+					t.Errorf("%s: expected %d rows, got %d", filename, want[filename], got[filename])
+				}
+			}
+		})
+	}
+}
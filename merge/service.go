@@ -0,0 +1,96 @@
+package merge
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// ServiceCoverage describes the merged feed's service over the days
+// checked by WithServiceCheck, so that a feed built only from stale inputs
+// is noticed before it is published
+type ServiceCoverage struct {
+	// From is the first day checked, at midnight, and Days the number of
+	// days checked
+	From time.Time
+	Days int
+
+	// DatesWithoutService lists the days checked on which no service is
+	// active, in order
+	DatesWithoutService []time.Time
+
+	// LastServiceDate is the last day on which any service is active, at
+	// midnight UTC; zero if no service ever is
+	LastServiceDate time.Time
+}
+
+// EndsWithin reports whether service ends before the last day checked
+func (c *ServiceCoverage) EndsWithin() bool {
+	return c.LastServiceDate.Format(serviceDateFormat) < c.From.AddDate(0, 0, c.Days-1).Format(serviceDateFormat)
+}
+
+// Warnings describes the gaps in service found, if any
+func (c *ServiceCoverage) Warnings() []string {
+	var warnings []string
+	if n := len(c.DatesWithoutService); n > 0 {
+		dates := make([]string, n)
+		for i, d := range c.DatesWithoutService {
+			dates[i] = d.Format(serviceDateFormat)
+		}
+		warnings = append(warnings, fmt.Sprintf("merged feed has no active service on %d of the %d days from %s: %s",
+			n, c.Days, c.From.Format(serviceDateFormat), strings.Join(dates, ", ")))
+	}
+	if c.EndsWithin() {
+		if c.LastServiceDate.IsZero() {
+			warnings = append(warnings, "merged feed has no active service on any date")
+		} else {
+			warnings = append(warnings, fmt.Sprintf("merged feed's service ends on %s, within the %d days from %s",
+				c.LastServiceDate.Format(serviceDateFormat), c.Days, c.From.Format(serviceDateFormat)))
+		}
+	}
+	return warnings
+}
+
+// serviceDateFormat is the layout of dates in service warnings, as in GTFS
+const serviceDateFormat = "20060102"
+
+// checkServiceCoverage finds the days from from, for days days, on which
+// feed has no active service, and the last day it has any, logging a
+// warning for each problem found
+func checkServiceCoverage(feed *gtfs.Feed, from time.Time, days int) *ServiceCoverage {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	coverage := &ServiceCoverage{From: from, Days: days}
+	for i := range days {
+		day := from.AddDate(0, 0, i)
+		if len(feed.ActiveServiceOn(day)) == 0 {
+			coverage.DatesWithoutService = append(coverage.DatesWithoutService, day)
+		}
+	}
+	coverage.LastServiceDate, _ = feed.LastServiceDate()
+	for _, w := range coverage.Warnings() {
+		log.Printf("WARNING: %s", w)
+	}
+	return coverage
+}
+
+// checkServiceGaps records in the report, and warns about, the runs of more
+// than maxGapDays days on which a route of merged has no trips
+func checkServiceGaps(merged *gtfs.Feed, maxGapDays int, report *Report) {
+	report.ServiceGaps = gtfs.FindServiceGaps(merged, maxGapDays)
+	if len(report.ServiceGaps) == 0 {
+		return
+	}
+	routes := make(map[gtfs.RouteID]bool)
+	gaps := make([]string, len(report.ServiceGaps))
+	for i, gap := range report.ServiceGaps {
+		routes[gap.RouteID] = true
+		gaps[i] = fmt.Sprintf("%s (%s to %s)", gap.RouteID, gap.Start.Format(serviceDateFormat), gap.End.Format(serviceDateFormat))
+	}
+	w := fmt.Sprintf("%d routes have no trips for more than %d consecutive days: %s",
+		len(routes), maxGapDays, listRoutes(gaps))
+	log.Printf("WARNING: %s", w)
+	report.Warnings = append(report.Warnings, w)
+}
@@ -0,0 +1,196 @@
+package merge
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfstest"
+	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
+)
+
+// mergeSimpleFeeds merges simple_a, with weekday service through 2024, and
+// simple_b, with daily service through 2024, with opts
+func mergeSimpleFeeds(t *testing.T, opts ...Option) *Merger {
+	t.Helper()
+	var feeds []*gtfs.Feed
+	for _, path := range []string{"../testdata/simple_a", "../testdata/simple_b"} {
+		feed, err := gtfs.ReadFromPath(path)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", path, err)
+		}
+		feeds = append(feeds, feed)
+	}
+	m := New(opts...)
+	if _, err := m.MergeFeeds(feeds); err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+	return m
+}
+
+func TestWithServiceCheck(t *testing.T) {
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	t.Run("service every day", func(t *testing.T) {
+		// Given: a week within both feeds' service, starting mid-morning
+		m := mergeSimpleFeeds(t, WithServiceCheck(day(2024, 6, 3).Add(10*time.Hour), 7))
+
+		// Then: no day lacks service and no warning is given
+		coverage := m.Report().ServiceCoverage
+		if coverage == nil || !coverage.From.Equal(day(2024, 6, 3)) || coverage.Days != 7 {
+			t.Fatalf("Expected 7 days from 2024-06-03, got %+v", coverage)
+		}
+		if len(coverage.DatesWithoutService) != 0 || coverage.EndsWithin() {
+			t.Errorf("Expected service on every day, got %+v", coverage)
+		}
+		if !coverage.LastServiceDate.Equal(day(2024, 12, 31)) {
+			t.Errorf("Expected service through 2024-12-31, got %v", coverage.LastServiceDate)
+		}
+		if len(m.Report().Warnings) != 0 {
+			t.Errorf("Expected no warnings, got %v", m.Report().Warnings)
+		}
+	})
+
+	t.Run("service ending", func(t *testing.T) {
+		// Given: a week straddling the end of both feeds' service
+		m := mergeSimpleFeeds(t, WithServiceCheck(day(2024, 12, 29), 7))
+
+		// Then: the days after 2024-12-31 lack service, and service is
+		// reported as ending
+		coverage := m.Report().ServiceCoverage
+		want := []time.Time{day(2025, 1, 1), day(2025, 1, 2), day(2025, 1, 3), day(2025, 1, 4)}
+		if !slices.EqualFunc(coverage.DatesWithoutService, want, time.Time.Equal) {
+			t.Errorf("Expected no service on %v, got %v", want, coverage.DatesWithoutService)
+		}
+		if !coverage.EndsWithin() {
+			t.Errorf("Expected service to end within the days checked")
+		}
+
+		// And: both are warned about
+		if got := m.Report().Warnings; !slices.Equal(got, coverage.Warnings()) || len(got) != 2 {
+			t.Errorf("Expected the two coverage warnings, got %v", got)
+		}
+	})
+
+	t.Run("not requested", func(t *testing.T) {
+		// Given: no service check
+		m := mergeSimpleFeeds(t)
+
+		// Then: no coverage is reported
+		if m.Report().ServiceCoverage != nil {
+			t.Errorf("Expected no coverage, got %+v", m.Report().ServiceCoverage)
+		}
+	})
+}
+
+func TestServiceCoverageWarnings(t *testing.T) {
+	from := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		coverage ServiceCoverage
+		want     []string
+	}{
+		{
+			name:     "covered",
+			coverage: ServiceCoverage{From: from, Days: 7, LastServiceDate: from.AddDate(0, 0, 6)},
+		},
+		{
+			name:     "gap",
+			coverage: ServiceCoverage{From: from, Days: 7, DatesWithoutService: []time.Time{from.AddDate(0, 0, 1)}, LastServiceDate: from.AddDate(0, 1, 0)},
+			want:     []string{"merged feed has no active service on 1 of the 7 days from 20261017: 20261018"},
+		},
+		{
+			name:     "no service",
+			coverage: ServiceCoverage{From: from, Days: 2, DatesWithoutService: []time.Time{from, from.AddDate(0, 0, 1)}},
+			want: []string{
+				"merged feed has no active service on 2 of the 2 days from 20261017: 20261017, 20261018",
+				"merged feed has no active service on any date",
+			},
+		},
+		{
+			name:     "ending",
+			coverage: ServiceCoverage{From: from, Days: 7, LastServiceDate: from.AddDate(0, 0, 5)},
+			want:     []string{"merged feed's service ends on 20261022, within the 7 days from 20261017"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.coverage.Warnings(); !slices.Equal(got, tt.want) {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// editionFeeds returns two editions of a feed running route r1 daily, the
+// first through 2026-03-31 and the second from 2026-04-03
+func editionFeeds() []*gtfs.Feed {
+	edition := func(service, start, end, trip string) *gtfs.Feed {
+		return gtfstest.NewFeedBuilder().
+			Service(service, func(c *gtfs.Calendar) { c.StartDate, c.EndDate = start, end }).
+			Trip(trip, "r1", service).
+			StopTimes(trip, "08:00", "s1", "s2").
+			MustBuild()
+	}
+	return []*gtfs.Feed{
+		edition("q1", "20260101", "20260331", "t1"),
+		edition("q2", "20260403", "20260630", "t2"),
+	}
+}
+
+func TestWithServiceGapCheck(t *testing.T) {
+	t.Run("gap between editions", func(t *testing.T) {
+		// Given: two editions merged with their routes matched, with two
+		// days between them
+		m := New(WithDefaultDetection(strategy.DetectionIdentity), WithServiceGapCheck(1))
+
+		// When: merged, checking for gaps longer than a day
+		if _, err := m.MergeFeeds(editionFeeds()); err != nil {
+			t.Fatalf("MergeFeeds failed: %v", err)
+		}
+
+		// Then: the gap is reported and warned about
+		want := []gtfs.ServiceGap{{
+			RouteID: "r1",
+			Start:   time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+			End:     time.Date(2026, 4, 2, 0, 0, 0, 0, time.UTC),
+			Days:    2,
+		}}
+		if got := m.Report().ServiceGaps; !slices.Equal(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+		wantWarning := "1 routes have no trips for more than 1 consecutive days: r1 (20260401 to 20260402)"
+		if !slices.Contains(m.Report().Warnings, wantWarning) {
+			t.Errorf("Expected warning %q, got %v", wantWarning, m.Report().Warnings)
+		}
+	})
+
+	t.Run("gap allowed", func(t *testing.T) {
+		// Given: the same editions, allowing gaps of two days
+		m := New(WithDefaultDetection(strategy.DetectionIdentity), WithServiceGapCheck(2))
+		if _, err := m.MergeFeeds(editionFeeds()); err != nil {
+			t.Fatalf("MergeFeeds failed: %v", err)
+		}
+
+		// Then: no gap is reported
+		if got := m.Report().ServiceGaps; len(got) != 0 {
+			t.Errorf("Expected no gaps, got %v", got)
+		}
+	})
+
+	t.Run("not requested", func(t *testing.T) {
+		// Given: no gap check
+		m := New(WithDefaultDetection(strategy.DetectionIdentity))
+		if _, err := m.MergeFeeds(editionFeeds()); err != nil {
+			t.Fatalf("MergeFeeds failed: %v", err)
+		}
+
+		// Then: no gap is reported
+		if got := m.Report().ServiceGaps; got != nil {
+			t.Errorf("Expected no gaps, got %v", got)
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package merge
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/internal/enumtext"
+)
+
+// RouteSortOrderStrategy specifies how route_sort_order values from
+// different feeds are reconciled in the merged feed
+type RouteSortOrderStrategy int
+
+const (
+	// SortOrderKeep - route_sort_order values are copied unchanged
+	SortOrderKeep RouteSortOrderStrategy = iota
+
+	// SortOrderOffsetPerFeed - each feed's route_sort_order values are
+	// shifted by routeSortOrderFeedOffset times the feed's input index, so
+	// routes stay grouped by feed; unset values stay unset
+	SortOrderOffsetPerFeed
+
+	// SortOrderReassign - every route is renumbered sequentially from 1,
+	// ordered by agency_id, then route_short_name, then route_id
+	SortOrderReassign
+)
+
+// String returns the string representation of RouteSortOrderStrategy
+func (s RouteSortOrderStrategy) String() string {
+	switch s {
+	case SortOrderKeep:
+		return "keep"
+	case SortOrderOffsetPerFeed:
+		return "offset"
+	case SortOrderReassign:
+		return "reassign"
+	default:
+		return fmt.Sprintf("RouteSortOrderStrategy(%d)", s)
+	}
+}
+
+// ParseRouteSortOrderStrategy parses a string into a RouteSortOrderStrategy value
+func ParseRouteSortOrderStrategy(s string) (RouteSortOrderStrategy, error) {
+	switch strings.ToLower(s) {
+	case "keep":
+		return SortOrderKeep, nil
+	case "offset", "offsetperfeed":
+		return SortOrderOffsetPerFeed, nil
+	case "reassign":
+		return SortOrderReassign, nil
+	default:
+		return SortOrderKeep, fmt.Errorf("invalid route sort order strategy: %q", s)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (s RouteSortOrderStrategy) MarshalText() ([]byte, error) {
+	return enumtext.Marshal(s, ParseRouteSortOrderStrategy)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (s *RouteSortOrderStrategy) UnmarshalText(text []byte) error {
+	return enumtext.Unmarshal(s, text, ParseRouteSortOrderStrategy)
+}
+
+// routeSortOrderFeedOffset is the per-feed base added under SortOrderOffsetPerFeed
+const routeSortOrderFeedOffset = 10000
+
+// applyRouteSortOrder rewrites the merged routes' SortOrder values according
+// to s. OffsetPerFeed attributes a route deduplicated across feeds to the
+// highest-indexed source, whose row is the one kept in the merged feed.
+func applyRouteSortOrder(feed *gtfs.Feed, s RouteSortOrderStrategy) {
+	switch s {
+	case SortOrderOffsetPerFeed:
+		for _, id := range feed.RouteOrder {
+			route := feed.Routes[id]
+			sources := feed.SourceOf(gtfs.KindRoute, string(id))
+			if route.SortOrder == nil || len(sources) == 0 {
+				continue
+			}
+			order := *route.SortOrder + routeSortOrderFeedOffset*sources[len(sources)-1]
+			route.SortOrder = &order
+		}
+	case SortOrderReassign:
+		ids := make([]gtfs.RouteID, len(feed.RouteOrder))
+		copy(ids, feed.RouteOrder)
+		sort.SliceStable(ids, func(i, j int) bool {
+			a, b := feed.Routes[ids[i]], feed.Routes[ids[j]]
+			if a.AgencyID != b.AgencyID {
+				return a.AgencyID < b.AgencyID
+			}
+			if a.ShortName != b.ShortName {
+				return a.ShortName < b.ShortName
+			}
+			return a.ID < b.ID
+		})
+		for i, id := range ids {
+			order := i + 1
+			feed.Routes[id].SortOrder = &order
+		}
+	}
+}
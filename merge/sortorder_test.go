@@ -0,0 +1,124 @@
+package merge
+
+import (
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// sortOrderFeed builds a feed with one agency and a route per short name,
+// numbered by route_sort_order from 1. A short name of "" gets no sort order.
+func sortOrderFeed(agencyID string, shortNames ...string) *gtfs.Feed {
+	feed := gtfs.NewFeed()
+	feed.AddAgency(&gtfs.Agency{ID: gtfs.AgencyID(agencyID), Name: agencyID, URL: "http://example.com", Timezone: "America/Los_Angeles"})
+	for i, name := range shortNames {
+		route := &gtfs.Route{
+			ID:        gtfs.RouteID(agencyID + "-r" + string(rune('1'+i))),
+			AgencyID:  gtfs.AgencyID(agencyID),
+			ShortName: name,
+			Type:      3,
+		}
+		if name != "" {
+			order := i + 1
+			route.SortOrder = &order
+		}
+		feed.AddRoute(route)
+	}
+	return feed
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+// sortOrderOf returns the sort order of a merged route
+func sortOrderOf(t *testing.T, feed *gtfs.Feed, id string) *int {
+	t.Helper()
+	route, ok := feed.Routes[gtfs.RouteID(id)]
+	if !ok {
+		t.Fatalf("Expected route %s in merged feed", id)
+	}
+	return route.SortOrder
+}
+
+func TestRouteSortOrderStrategies(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy RouteSortOrderStrategy
+		expected map[string]*int
+	}{
+		{
+			name:     "keep",
+			strategy: SortOrderKeep,
+			expected: map[string]*int{"x-r1": intPtr(1), "x-r2": intPtr(2), "x-r3": nil, "y-r1": intPtr(1), "y-r2": intPtr(2)},
+		},
+		{
+			name:     "offset per feed",
+			strategy: SortOrderOffsetPerFeed,
+			expected: map[string]*int{"x-r1": intPtr(1), "x-r2": intPtr(2), "x-r3": nil, "y-r1": intPtr(10001), "y-r2": intPtr(10002)},
+		},
+		{
+			name:     "reassign",
+			strategy: SortOrderReassign,
+			// Short names compare as strings, so "" < "10" < "2"
+			expected: map[string]*int{"x-r3": intPtr(1), "x-r1": intPtr(2), "x-r2": intPtr(3), "y-r2": intPtr(4), "y-r1": intPtr(5)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: two feeds that both number their routes from 1
+			feedX := sortOrderFeed("x", "10", "2", "")
+			feedY := sortOrderFeed("y", "B", "A")
+
+			// When: merged with the strategy
+			merged, err := New(WithRouteSortOrderStrategy(tt.strategy)).MergeFeeds([]*gtfs.Feed{feedX, feedY})
+			if err != nil {
+				t.Fatalf("MergeFeeds failed: %v", err)
+			}
+
+			// Then: each route has the expected sort order
+			for id, want := range tt.expected {
+				got := sortOrderOf(t, merged, id)
+				switch {
+				case want == nil && got != nil:
+					t.Errorf("Route %s: expected no sort order, got %d", id, *got)
+				case want != nil && got == nil:
+					t.Errorf("Route %s: expected sort order %d, got none", id, *want)
+				case want != nil && *got != *want:
+					t.Errorf("Route %s: expected sort order %d, got %d", id, *want, *got)
+				}
+			}
+
+			// And: the source feeds are not modified
+			if *feedY.Routes["y-r1"].SortOrder != 1 {
+				t.Errorf("Expected source route y-r1 to keep sort order 1, got %d", *feedY.Routes["y-r1"].SortOrder)
+			}
+		})
+	}
+}
+
+func TestParseRouteSortOrderStrategy(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected RouteSortOrderStrategy
+		wantErr  bool
+	}{
+		{"keep", SortOrderKeep, false},
+		{"Offset", SortOrderOffsetPerFeed, false},
+		{"reassign", SortOrderReassign, false},
+		{"shuffle", SortOrderKeep, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseRouteSortOrderStrategy(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRouteSortOrderStrategy(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("ParseRouteSortOrderStrategy(%q) = %s, want %s", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package merge
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// stationFixResult describes what fixStationStopTimes changed in one input
+// feed
+type stationFixResult struct {
+	// rewritten is the number of stop_times moved from a station to its
+	// only platform
+	rewritten int
+
+	// unresolved is the number of stop_times left referencing each stop
+	// whose location_type is not 0, keyed by stop_id
+	unresolved map[gtfs.StopID]int
+
+	// dropped is true if the unresolved rows were removed from the feed
+	dropped bool
+}
+
+// fixStationStopTimes rewrites each stop_time that references a station
+// (location_type 1) with exactly one child platform (location_type 0) to
+// reference that platform instead. Stop times referencing a station with no
+// or several platforms, or a stop of another non-boarding location_type, are
+// left for the caller to report, and removed from the feed under drop.
+func fixStationStopTimes(feed *gtfs.Feed, drop bool) stationFixResult {
+	res := stationFixResult{unresolved: make(map[gtfs.StopID]int), dropped: drop}
+
+	platforms := make(map[gtfs.StopID][]gtfs.StopID)
+	for id, stop := range feed.Stops {
+		if stop.LocationType == 0 && stop.ParentStation != "" {
+			platforms[stop.ParentStation] = append(platforms[stop.ParentStation], id)
+		}
+	}
+
+	feed.StopTimes = slices.DeleteFunc(feed.StopTimes, func(st *gtfs.StopTime) bool {
+		stop := feed.Stops[st.StopID]
+		if stop == nil || stop.LocationType == 0 {
+			return false
+		}
+		if children := platforms[st.StopID]; stop.LocationType == 1 && len(children) == 1 {
+			st.StopID = children[0]
+			res.rewritten++
+			return false
+		}
+		res.unresolved[st.StopID]++
+		return drop
+	})
+
+	return res
+}
+
+// removed returns the number of stop_times rows res dropped
+func (res stationFixResult) removed() int {
+	if !res.dropped {
+		return 0
+	}
+	n := 0
+	for _, count := range res.unresolved {
+		n += count
+	}
+	return n
+}
+
+// warnings describes the stop_times res could not fix, for Report.Warnings
+func (res stationFixResult) warnings(feedName string) []string {
+	action := "kept"
+	if res.dropped {
+		action = "dropped"
+	}
+	var warnings []string
+	for _, id := range slices.Sorted(maps.Keys(res.unresolved)) {
+		warnings = append(warnings, fmt.Sprintf("feed %s: %s %d stop_times referencing %s, which is not a single-platform station",
+			feedName, action, res.unresolved[id], id))
+	}
+	return warnings
+}
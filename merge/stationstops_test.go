@@ -0,0 +1,100 @@
+package merge
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+func TestFixStationStopTimes(t *testing.T) {
+	for _, drop := range []bool{false, true} {
+		// Given: a trip stopping at North Station, which has one platform,
+		// and at Central Station, which has two
+		feed, err := gtfs.ReadFromPath("../testdata/station_stop_times")
+		if err != nil {
+			t.Fatalf("failed to read feed: %v", err)
+		}
+
+		// When: fixed
+		res := fixStationStopTimes(feed, drop)
+
+		// Then: the single-platform station is replaced by its platform
+		if res.rewritten != 1 || feed.StopTimes[0].StopID != "north_1" {
+			t.Errorf("drop=%v: Expected north rewritten to north_1, got %d rewritten, stop %s", drop, res.rewritten, feed.StopTimes[0].StopID)
+		}
+
+		// And: the multi-platform station is reported, and kept or dropped
+		if len(res.unresolved) != 1 || res.unresolved["central"] != 1 {
+			t.Errorf("drop=%v: Expected central unresolved, got %v", drop, res.unresolved)
+		}
+		expectedRows, expectedRemoved := 6, 0
+		if drop {
+			expectedRows, expectedRemoved = 5, 1
+		}
+		if len(feed.StopTimes) != expectedRows || res.removed() != expectedRemoved {
+			t.Errorf("drop=%v: Expected %d stop_times and %d removed, got %d and %d",
+				drop, expectedRows, expectedRemoved, len(feed.StopTimes), res.removed())
+		}
+		for _, st := range feed.StopTimes {
+			if drop && st.StopID == "central" {
+				t.Errorf("Expected the central stop_time to be dropped")
+			}
+		}
+	}
+}
+
+func TestFixStationStopTimesNoPlatforms(t *testing.T) {
+	// Given: a stop_time at a station without platforms
+	feed := gtfs.NewFeed()
+	feed.AddStop(&gtfs.Stop{ID: "station", Name: "Station", LocationType: 1})
+	feed.StopTimes = append(feed.StopTimes, &gtfs.StopTime{TripID: "t1", StopID: "station", StopSequence: 1})
+
+	// When: fixed
+	res := fixStationStopTimes(feed, false)
+
+	// Then: it is left alone and reported
+	if res.rewritten != 0 || res.unresolved["station"] != 1 || feed.StopTimes[0].StopID != "station" {
+		t.Errorf("Expected the stop_time unresolved, got %+v", res)
+	}
+}
+
+func TestMergeFilesFixStationStopTimes(t *testing.T) {
+	// Given: an input whose stop_times reference stations
+	inputs := []string{"../testdata/station_stop_times", "../testdata/simple_b"}
+	output := filepath.Join(t.TempDir(), "merged.zip")
+
+	// When: merged with the fixer and sanitizing
+	m := New(WithFixStationStopTimes(true), WithSanitizeInputs(true))
+	if err := m.MergeFiles(inputs, output); err != nil {
+		t.Fatalf("MergeFiles failed: %v", err)
+	}
+
+	// Then: the rewrite and the dropped row are reported
+	report := m.Report()
+	fr := report.FeedByPath("../testdata/station_stop_times")
+	if fr == nil {
+		t.Fatalf("Expected a report for station_stop_times")
+	}
+	if fr.StationStopTimesFixed != 1 {
+		t.Errorf("Expected 1 stop_time fixed, got %d", fr.StationStopTimesFixed)
+	}
+	if fr.Sanitized["stop_times.txt"] != 1 {
+		t.Errorf("Expected 1 stop_time dropped, got %v", fr.Sanitized)
+	}
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "dropped 1 stop_times referencing central") {
+		t.Errorf("Expected a warning for central, got %v", report.Warnings)
+	}
+
+	// And: no merged stop_time references a station
+	merged, err := gtfs.ReadFromPath(output)
+	if err != nil {
+		t.Fatalf("failed to read merged feed: %v", err)
+	}
+	for _, st := range merged.StopTimes {
+		if merged.Stops[st.StopID].LocationType != 0 {
+			t.Errorf("Expected only platforms in stop_times, got %s", st.StopID)
+		}
+	}
+}
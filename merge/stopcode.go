@@ -0,0 +1,165 @@
+package merge
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+	"github.com/aaronbrethorst/gtfs-merge-go/internal/enumtext"
+)
+
+// ErrDuplicateStopCodes indicates the merged feed has stops sharing a
+// stop_code under StopCodesError
+var ErrDuplicateStopCodes = errors.New("duplicate stop codes")
+
+// StopCodePolicy specifies how stops sharing a stop_code in the merged feed
+// are handled. GTFS does not require stop codes to be unique, but riders
+// and trip planners often look stops up by them.
+type StopCodePolicy int
+
+const (
+	// StopCodesAllowDuplicates - stop codes are not checked
+	StopCodesAllowDuplicates StopCodePolicy = iota
+
+	// StopCodesWarn - shared codes are listed in Report.StopCodeCollisions
+	StopCodesWarn
+
+	// StopCodesPrefix - shared codes are listed, and each stop sharing one
+	// has its code prefixed with the prefix of the input feed it came from.
+	// Stops from the last input, which has no prefix, keep their codes.
+	// Codes still shared afterwards, as when stops from one input share a
+	// code, are listed in Report.StopCodesUnresolved.
+	StopCodesPrefix
+
+	// StopCodesError - the merge fails with ErrDuplicateStopCodes
+	StopCodesError
+)
+
+// String returns the string representation of StopCodePolicy
+func (p StopCodePolicy) String() string {
+	switch p {
+	case StopCodesAllowDuplicates:
+		return "allow"
+	case StopCodesWarn:
+		return "warn"
+	case StopCodesPrefix:
+		return "prefix"
+	case StopCodesError:
+		return "error"
+	default:
+		return fmt.Sprintf("StopCodePolicy(%d)", p)
+	}
+}
+
+// ParseStopCodePolicy parses a string into a StopCodePolicy value
+func ParseStopCodePolicy(s string) (StopCodePolicy, error) {
+	switch strings.ToLower(s) {
+	case "allow":
+		return StopCodesAllowDuplicates, nil
+	case "warn":
+		return StopCodesWarn, nil
+	case "prefix":
+		return StopCodesPrefix, nil
+	case "error":
+		return StopCodesError, nil
+	default:
+		return StopCodesAllowDuplicates, fmt.Errorf("invalid stop code policy: %q", s)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (p StopCodePolicy) MarshalText() ([]byte, error) {
+	return enumtext.Marshal(p, ParseStopCodePolicy)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (p *StopCodePolicy) UnmarshalText(text []byte) error {
+	return enumtext.Unmarshal(p, text, ParseStopCodePolicy)
+}
+
+// StopCodeCollision lists the merged stops that shared a stop_code
+type StopCodeCollision struct {
+	Code    string
+	StopIDs []gtfs.StopID
+}
+
+// String returns the collision as CODE (STOP_ID, ...)
+func (c StopCodeCollision) String() string {
+	ids := make([]string, len(c.StopIDs))
+	for i, id := range c.StopIDs {
+		ids[i] = string(id)
+	}
+	return fmt.Sprintf("%q (stops %s)", c.Code, strings.Join(ids, ", "))
+}
+
+// findStopCodeCollisions returns the non-empty stop codes shared by more
+// than one stop, compared case-sensitively, in order of first use
+func findStopCodeCollisions(feed *gtfs.Feed) []StopCodeCollision {
+	byCode := make(map[string][]gtfs.StopID)
+	var codes []string
+	for _, id := range feed.StopOrder {
+		code := feed.Stops[id].Code
+		if code == "" {
+			continue
+		}
+		if _, seen := byCode[code]; !seen {
+			codes = append(codes, code)
+		}
+		byCode[code] = append(byCode[code], id)
+	}
+
+	var collisions []StopCodeCollision
+	for _, code := range codes {
+		if ids := byCode[code]; len(ids) > 1 {
+			collisions = append(collisions, StopCodeCollision{Code: code, StopIDs: ids})
+		}
+	}
+	return collisions
+}
+
+// applyStopCodePolicy checks the merged feed's stop codes according to p,
+// recording collisions in the report. Under StopCodesPrefix a stop is
+// attributed to its highest-indexed source, whose row is the one kept, and
+// the codes are checked again once prefixed, since stops from one input
+// keep sharing a code and a prefixed code may be one another stop has.
+func applyStopCodePolicy(feed *gtfs.Feed, p StopCodePolicy, report *Report) error {
+	if p == StopCodesAllowDuplicates {
+		return nil
+	}
+	collisions := findStopCodeCollisions(feed)
+	report.StopCodeCollisions = collisions
+
+	switch p {
+	case StopCodesError:
+		if len(collisions) == 0 {
+			return nil
+		}
+		lines := make([]string, len(collisions))
+		for i, c := range collisions {
+			lines[i] = "\n  " + c.String()
+		}
+		return fmt.Errorf("%w:%s", ErrDuplicateStopCodes, strings.Join(lines, ""))
+	case StopCodesPrefix:
+		for _, c := range collisions {
+			for _, id := range c.StopIDs {
+				sources := feed.SourceOf(gtfs.KindStop, string(id))
+				if len(sources) == 0 {
+					continue
+				}
+				prefix := report.Feeds[sources[len(sources)-1]].Prefix
+				if prefix == "" {
+					continue
+				}
+				feed.Stops[id].Code = prefix + c.Code
+				report.StopCodesPrefixed++
+			}
+		}
+		report.StopCodesUnresolved = findStopCodeCollisions(feed)
+		for _, c := range report.StopCodesUnresolved {
+			log.Printf("WARNING: stop code %s still shared after prefixing", c)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package merge
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// stopCodeFeeds returns two feeds whose stops reuse codes: "1001" in both,
+// "A1" in one and "a1" in the other, and an empty code in both
+func stopCodeFeeds() []*gtfs.Feed {
+	a := gtfs.NewFeed()
+	a.AddStop(&gtfs.Stop{ID: "a1", Name: "First", Code: "1001"})
+	a.AddStop(&gtfs.Stop{ID: "a2", Name: "Second", Code: "A1"})
+	a.AddStop(&gtfs.Stop{ID: "a3", Name: "Third"})
+	b := gtfs.NewFeed()
+	b.AddStop(&gtfs.Stop{ID: "b1", Name: "First", Code: "1001"})
+	b.AddStop(&gtfs.Stop{ID: "b2", Name: "Second", Code: "a1"})
+	b.AddStop(&gtfs.Stop{ID: "b3", Name: "Third"})
+	return []*gtfs.Feed{a, b}
+}
+
+func TestWithUniqueStopCodesWarn(t *testing.T) {
+	// Given: feeds reusing stop code 1001
+	m := New(WithUniqueStopCodes(StopCodesWarn))
+
+	// When: merged
+	merged, err := m.MergeFeeds(stopCodeFeeds())
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: only 1001 collides, since codes are case-sensitive and empty
+	// codes are ignored, and no code is changed
+	collisions := m.Report().StopCodeCollisions
+	if len(collisions) != 1 || collisions[0].Code != "1001" || len(collisions[0].StopIDs) != 2 {
+		t.Fatalf("Expected one collision on 1001, got %+v", collisions)
+	}
+	if merged.Stops["a1"].Code != "1001" || merged.Stops["b1"].Code != "1001" {
+		t.Errorf("Expected codes unchanged, got %q and %q", merged.Stops["a1"].Code, merged.Stops["b1"].Code)
+	}
+}
+
+func TestWithUniqueStopCodesPrefix(t *testing.T) {
+	m := New(WithUniqueStopCodes(StopCodesPrefix))
+	merged, err := m.MergeFeeds(stopCodeFeeds())
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: the first feed's colliding code gets its prefix; the last
+	// feed's and the non-colliding codes are kept
+	if got := merged.Stops["a1"].Code; got != "a-1001" {
+		t.Errorf("Expected a1's code to be a-1001, got %q", got)
+	}
+	if got := merged.Stops["b1"].Code; got != "1001" {
+		t.Errorf("Expected b1's code to be kept, got %q", got)
+	}
+	if got := merged.Stops["a2"].Code; got != "A1" {
+		t.Errorf("Expected a2's code to be kept, got %q", got)
+	}
+	if m.Report().StopCodesPrefixed != 1 {
+		t.Errorf("Expected 1 code prefixed, got %d", m.Report().StopCodesPrefixed)
+	}
+}
+
+func TestWithUniqueStopCodesPrefixUnresolved(t *testing.T) {
+	// Given: a first feed with two stops sharing code 7, and a last feed
+	// with two stops sharing code 1001 and one whose code is a-9, the code
+	// the first feed's stop with code 9 would be given
+	a := gtfs.NewFeed()
+	a.AddStop(&gtfs.Stop{ID: "a1", Name: "First", Code: "7"})
+	a.AddStop(&gtfs.Stop{ID: "a2", Name: "Second", Code: "7"})
+	a.AddStop(&gtfs.Stop{ID: "a3", Name: "Third", Code: "9"})
+	b := gtfs.NewFeed()
+	b.AddStop(&gtfs.Stop{ID: "b1", Name: "First", Code: "1001"})
+	b.AddStop(&gtfs.Stop{ID: "b2", Name: "Second", Code: "1001"})
+	b.AddStop(&gtfs.Stop{ID: "b3", Name: "Third", Code: "9"})
+	b.AddStop(&gtfs.Stop{ID: "b4", Name: "Fourth", Code: "a-9"})
+
+	// When: merged, prefixing colliding codes
+	m := New(WithUniqueStopCodes(StopCodesPrefix))
+	merged, err := m.MergeFeeds([]*gtfs.Feed{a, b})
+	if err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+
+	// Then: 9 is resolved, but 1001 is still shared within the last feed,
+	// a-9 between b4 and a3, and a-7 within the first feed, in the order
+	// the merged stops are written
+	if got := merged.Stops["a3"].Code; got != "a-9" {
+		t.Errorf("Expected a3's code to be a-9, got %q", got)
+	}
+	var got []string
+	for _, c := range m.Report().StopCodesUnresolved {
+		got = append(got, c.String())
+	}
+	want := []string{`"1001" (stops b1, b2)`, `"a-9" (stops b4, a3)`, `"a-7" (stops a1, a2)`}
+	if strings.Join(got, "; ") != strings.Join(want, "; ") {
+		t.Errorf("Expected unresolved %v, got %v", want, got)
+	}
+}
+
+func TestWithUniqueStopCodesError(t *testing.T) {
+	_, err := New(WithUniqueStopCodes(StopCodesError)).MergeFeeds(stopCodeFeeds())
+
+	// Then: the merge fails, naming the colliding stops
+	if !errors.Is(err, ErrDuplicateStopCodes) {
+		t.Fatalf("Expected ErrDuplicateStopCodes, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `"1001" (stops b1, a1)`) {
+		t.Errorf("Expected the collision in the error, got %v", err)
+	}
+}
+
+func TestWithUniqueStopCodesDefaultUnchecked(t *testing.T) {
+	m := New()
+	if _, err := m.MergeFeeds(stopCodeFeeds()); err != nil {
+		t.Fatalf("MergeFeeds failed: %v", err)
+	}
+	if len(m.Report().StopCodeCollisions) != 0 {
+		t.Errorf("Expected no check by default, got %+v", m.Report().StopCodeCollisions)
+	}
+}
+
+func TestParseStopCodePolicy(t *testing.T) {
+	for _, p := range []StopCodePolicy{StopCodesAllowDuplicates, StopCodesWarn, StopCodesPrefix, StopCodesError} {
+		parsed, err := ParseStopCodePolicy(p.String())
+		if err != nil || parsed != p {
+			t.Errorf("Expected %v to round-trip, got %v, %v", p, parsed, err)
+		}
+	}
+	if _, err := ParseStopCodePolicy("ignore"); err == nil {
+		t.Error("Expected an error for an invalid policy")
+	}
+}
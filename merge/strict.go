@@ -0,0 +1,190 @@
+package merge
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// ErrBrokenOutputReferences indicates the merged feed failed the strict
+// output check: some foreign key names an entity the feed lacks; see
+// WithStrictOutput
+var ErrBrokenOutputReferences = errors.New("merged feed has broken references")
+
+// maxBrokenReferences is how many broken references a strict output error
+// lists
+const maxBrokenReferences = 20
+
+// brokenReference locates a foreign key in the merged feed that does not
+// resolve
+type brokenReference struct {
+	file  string
+	field string
+	value string
+
+	// owner and ownerID name the ID-keyed entity the row belongs to, whose
+	// provenance names the input feed it came from; owner is empty for rows
+	// without one
+	owner   gtfs.EntityKind
+	ownerID string
+}
+
+// findBrokenReferences checks every foreign key Validate checks for
+// existence, returning the first limit broken references and how many there
+// are in all. It only tests map membership, so a consistent feed costs no
+// allocations beyond the pass itself.
+func findBrokenReferences(feed *gtfs.Feed, limit int) ([]brokenReference, int) {
+	var refs []brokenReference
+	total := 0
+	check := func(ok bool, ref brokenReference) {
+		if ok {
+			return
+		}
+		total++
+		if len(refs) < limit {
+			refs = append(refs, ref)
+		}
+	}
+
+	for _, id := range feed.StopOrder {
+		if parent := feed.Stops[id].ParentStation; parent != "" {
+			_, ok := feed.Stops[parent]
+			check(ok, brokenReference{"stops.txt", "parent_station", string(parent), gtfs.KindStop, string(id)})
+		}
+	}
+	for _, id := range feed.RouteOrder {
+		route := feed.Routes[id]
+		if route.AgencyID != "" {
+			_, ok := feed.Agencies[route.AgencyID]
+			check(ok, brokenReference{"routes.txt", "agency_id", string(route.AgencyID), gtfs.KindRoute, string(id)})
+		}
+		if route.NetworkID != "" && len(feed.Networks) > 0 {
+			_, ok := feed.Networks[route.NetworkID]
+			check(ok, brokenReference{"routes.txt", "network_id", string(route.NetworkID), gtfs.KindRoute, string(id)})
+		}
+	}
+	for _, id := range feed.TripOrder {
+		trip := feed.Trips[id]
+		_, ok := feed.Routes[trip.RouteID]
+		check(ok, brokenReference{"trips.txt", "route_id", string(trip.RouteID), gtfs.KindTrip, string(id)})
+		_, inCalendar := feed.Calendars[trip.ServiceID]
+		_, inCalendarDates := feed.CalendarDates[trip.ServiceID]
+		check(inCalendar || inCalendarDates, brokenReference{"trips.txt", "service_id", string(trip.ServiceID), gtfs.KindTrip, string(id)})
+		if trip.ShapeID != "" {
+			_, ok := feed.Shapes[trip.ShapeID]
+			check(ok, brokenReference{"trips.txt", "shape_id", string(trip.ShapeID), gtfs.KindTrip, string(id)})
+		}
+	}
+	for _, st := range feed.StopTimes {
+		_, ok := feed.Trips[st.TripID]
+		check(ok, brokenReference{"stop_times.txt", "trip_id", string(st.TripID), gtfs.KindTrip, string(st.TripID)})
+		_, ok = feed.Stops[st.StopID]
+		check(ok, brokenReference{"stop_times.txt", "stop_id", string(st.StopID), gtfs.KindTrip, string(st.TripID)})
+	}
+	for _, f := range feed.Frequencies {
+		_, ok := feed.Trips[f.TripID]
+		check(ok, brokenReference{"frequencies.txt", "trip_id", string(f.TripID), gtfs.KindTrip, string(f.TripID)})
+	}
+	for _, t := range feed.Transfers {
+		_, ok := feed.Stops[t.FromStopID]
+		check(ok, brokenReference{"transfers.txt", "from_stop_id", string(t.FromStopID), "", ""})
+		_, ok = feed.Stops[t.ToStopID]
+		check(ok, brokenReference{"transfers.txt", "to_stop_id", string(t.ToStopID), "", ""})
+	}
+	for _, id := range feed.FareAttrOrder {
+		if agencyID := feed.FareAttributes[id].AgencyID; agencyID != "" {
+			_, ok := feed.Agencies[agencyID]
+			check(ok, brokenReference{"fare_attributes.txt", "agency_id", string(agencyID), gtfs.KindFare, string(id)})
+		}
+	}
+	for _, rule := range feed.FareRules {
+		_, ok := feed.FareAttributes[rule.FareID]
+		check(ok, brokenReference{"fare_rules.txt", "fare_id", string(rule.FareID), gtfs.KindFare, string(rule.FareID)})
+		if rule.RouteID != "" {
+			_, ok := feed.Routes[rule.RouteID]
+			check(ok, brokenReference{"fare_rules.txt", "route_id", string(rule.RouteID), gtfs.KindFare, string(rule.FareID)})
+		}
+	}
+	for _, p := range feed.Pathways {
+		_, ok := feed.Stops[p.FromStopID]
+		check(ok, brokenReference{"pathways.txt", "from_stop_id", string(p.FromStopID), "", ""})
+		_, ok = feed.Stops[p.ToStopID]
+		check(ok, brokenReference{"pathways.txt", "to_stop_id", string(p.ToStopID), "", ""})
+	}
+	for _, rn := range feed.RouteNetworks {
+		_, ok := feed.Networks[rn.NetworkID]
+		check(ok, brokenReference{"route_networks.txt", "network_id", string(rn.NetworkID), gtfs.KindRoute, string(rn.RouteID)})
+		_, ok = feed.Routes[rn.RouteID]
+		check(ok, brokenReference{"route_networks.txt", "route_id", string(rn.RouteID), "", ""})
+	}
+	if len(feed.FareTransferRules) > 0 || len(feed.FareLegRules) > 0 {
+		legGroups := feed.LegGroupIDs()
+		products := feed.FareProductIDs()
+		timeframes := feed.TimeframeGroupIDs()
+		for _, rule := range feed.FareLegRules {
+			if rule.FareProductID != "" {
+				check(products[rule.FareProductID], brokenReference{"fare_leg_rules.txt", "fare_product_id", string(rule.FareProductID), "", ""})
+			}
+			if rule.FromTimeframeGroupID != "" {
+				check(timeframes[rule.FromTimeframeGroupID], brokenReference{"fare_leg_rules.txt", "from_timeframe_group_id", string(rule.FromTimeframeGroupID), "", ""})
+			}
+			if rule.ToTimeframeGroupID != "" {
+				check(timeframes[rule.ToTimeframeGroupID], brokenReference{"fare_leg_rules.txt", "to_timeframe_group_id", string(rule.ToTimeframeGroupID), "", ""})
+			}
+		}
+		for _, rule := range feed.FareTransferRules {
+			if rule.FromLegGroupID != "" {
+				check(legGroups[rule.FromLegGroupID], brokenReference{"fare_transfer_rules.txt", "from_leg_group_id", string(rule.FromLegGroupID), "", ""})
+			}
+			if rule.ToLegGroupID != "" {
+				check(legGroups[rule.ToLegGroupID], brokenReference{"fare_transfer_rules.txt", "to_leg_group_id", string(rule.ToLegGroupID), "", ""})
+			}
+			if rule.FareProductID != "" {
+				check(products[rule.FareProductID], brokenReference{"fare_transfer_rules.txt", "fare_product_id", string(rule.FareProductID), "", ""})
+			}
+		}
+	}
+	for _, product := range feed.FareProducts {
+		if product.RiderCategoryID != "" {
+			_, ok := feed.RiderCategories[product.RiderCategoryID]
+			check(ok, brokenReference{"fare_products.txt", "rider_category_id", string(product.RiderCategoryID), "", ""})
+		}
+		if product.FareMediaID != "" {
+			_, ok := feed.FareMedia[product.FareMediaID]
+			check(ok, brokenReference{"fare_products.txt", "fare_media_id", string(product.FareMediaID), "", ""})
+		}
+	}
+	for _, tf := range feed.Timeframes {
+		_, inCalendar := feed.Calendars[tf.ServiceID]
+		_, inCalendarDates := feed.CalendarDates[tf.ServiceID]
+		check(inCalendar || inCalendarDates, brokenReference{"timeframes.txt", "service_id", string(tf.ServiceID), "", ""})
+	}
+
+	return refs, total
+}
+
+// brokenReferencesError lists refs, out of total, in an error wrapping
+// ErrBrokenOutputReferences, naming the input feeds each row came from when
+// the merged feed's provenance records them
+func brokenReferencesError(feed *gtfs.Feed, names []string, refs []brokenReference, total int) error {
+	var b strings.Builder
+	for _, ref := range refs {
+		fmt.Fprintf(&b, "\n  %s %s %q not found", ref.file, ref.field, ref.value)
+		if ref.owner == "" {
+			continue
+		}
+		var from []string
+		for _, i := range feed.SourceOf(ref.owner, ref.ownerID) {
+			from = append(from, names[i])
+		}
+		if len(from) > 0 {
+			fmt.Fprintf(&b, " (%s %q from feed %s)", ref.owner, ref.ownerID, strings.Join(from, ", "))
+		}
+	}
+	if total > len(refs) {
+		fmt.Fprintf(&b, "\n  ... and %d more", total-len(refs))
+	}
+	return fmt.Errorf("%w: %d references%s", ErrBrokenOutputReferences, total, b.String())
+}
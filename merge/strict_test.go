@@ -0,0 +1,78 @@
+package merge
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// brokenRouteFeed returns a feed whose trips reference a route it lacks
+func brokenRouteFeed(trips ...gtfs.TripID) *gtfs.Feed {
+	feed := gtfs.NewFeed()
+	feed.AddStop(&gtfs.Stop{ID: "s1", Name: "Stop 1"})
+	feed.AddCalendar(&gtfs.Calendar{ServiceID: "svc1", Monday: true, StartDate: "20240101", EndDate: "20241231"})
+	for _, id := range trips {
+		feed.AddTrip(&gtfs.Trip{ID: id, RouteID: "missing", ServiceID: "svc1"})
+		feed.StopTimes = append(feed.StopTimes, &gtfs.StopTime{TripID: id, StopID: "s1", StopSequence: 1})
+	}
+	return feed
+}
+
+func TestFindBrokenReferences(t *testing.T) {
+	// Given: a consistent feed
+	feed, err := gtfs.ReadFromPath("../testdata/simple_a")
+	if err != nil {
+		t.Fatalf("failed to read feed: %v", err)
+	}
+
+	// When/Then: no broken references are found
+	if refs, total := findBrokenReferences(feed, maxBrokenReferences); total != 0 {
+		t.Errorf("Expected no broken references, got %d: %v", total, refs)
+	}
+
+	// Given: a feed with three trips on a missing route
+	feed = brokenRouteFeed("t1", "t2", "t3")
+
+	// When: checked with a limit of 2
+	refs, total := findBrokenReferences(feed, 2)
+
+	// Then: all are counted and the first 2 returned
+	if total != 3 || len(refs) != 2 {
+		t.Fatalf("Expected 3 broken references and 2 listed, got %d and %v", total, refs)
+	}
+	if refs[0].file != "trips.txt" || refs[0].field != "route_id" || refs[0].value != "missing" {
+		t.Errorf("Expected trips.txt route_id %q, got %+v", "missing", refs[0])
+	}
+}
+
+func TestWithStrictOutput(t *testing.T) {
+	newFeeds := func() []*gtfs.Feed {
+		good, err := gtfs.ReadFromPath("../testdata/simple_b")
+		if err != nil {
+			t.Fatalf("failed to read feed: %v", err)
+		}
+		return []*gtfs.Feed{brokenRouteFeed("orphan"), good}
+	}
+
+	// Given/When: an input with a dangling reference is merged by default
+	if _, err := New().MergeFeeds(newFeeds()); err != nil {
+		t.Fatalf("Expected the merge to succeed without strict output, got %v", err)
+	}
+
+	// When: merged with strict output
+	m := New(WithStrictOutput(true))
+	_, err := m.MergeFeeds(newFeeds())
+
+	// Then: the merge fails, naming the reference and the feed it came from
+	if !errors.Is(err, ErrBrokenOutputReferences) {
+		t.Fatalf("Expected ErrBrokenOutputReferences, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `trips.txt route_id "missing" not found (trip "orphan" from feed a)`) {
+		t.Errorf("Expected the broken trip and its feed in the error, got %v", err)
+	}
+	if m.Report() != nil {
+		t.Errorf("Expected no report for a failed merge")
+	}
+}
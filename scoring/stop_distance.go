@@ -1,8 +1,7 @@
 package scoring
 
 import (
-	"math"
-
+	"github.com/aaronbrethorst/gtfs-merge-go/geo"
 	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
 	"github.com/aaronbrethorst/gtfs-merge-go/strategy"
 )
@@ -32,28 +31,8 @@ func (s *StopDistanceScorer) Score(ctx *strategy.MergeContext, source, target *g
 	}
 }
 
-// earthRadiusKm is the mean radius of the Earth in kilometers
-const earthRadiusKm = 6371.0
-
-// haversineDistance calculates the great-circle distance between two points
-// on the Earth's surface using the Haversine formula.
-// Returns distance in kilometers.
+// haversineDistance returns the great-circle distance between two points
+// in kilometers
 func haversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
-	// Convert degrees to radians
-	lat1Rad := lat1 * math.Pi / 180
-	lat2Rad := lat2 * math.Pi / 180
-	lon1Rad := lon1 * math.Pi / 180
-	lon2Rad := lon2 * math.Pi / 180
-
-	// Haversine formula
-	dLat := lat2Rad - lat1Rad
-	dLon := lon2Rad - lon1Rad
-
-	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
-		math.Cos(lat1Rad)*math.Cos(lat2Rad)*
-			math.Sin(dLon/2)*math.Sin(dLon/2)
-
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
-
-	return earthRadiusKm * c
+	return geo.HaversineMeters(lat1, lon1, lat2, lon2) / 1000
 }
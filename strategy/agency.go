@@ -3,23 +3,43 @@ package strategy
 import (
 	"fmt"
 	"log"
+	"net/url"
 	"sort"
+	"strings"
+	"unicode"
 
 	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
 )
 
+// syntheticAgencyID is the ID (after the feed prefix) given to an agency
+// whose agency_id is empty, as single-agency feeds are allowed to omit it
+const syntheticAgencyID = "agency"
+
 // AgencyMergeStrategy handles merging of agencies between feeds
 type AgencyMergeStrategy struct {
 	BaseStrategy
+	// FuzzyThreshold is the minimum score for a fuzzy match (default 0.5)
+	FuzzyThreshold float64
+	// NewestEditionWins resolves a duplicate agency field by field: each
+	// field takes the value of the newest edition (see FeedEdition) that
+	// has one, whatever order the feeds are merged in, rather than the
+	// value of the agency merged first. Empty values never replace others.
+	NewestEditionWins bool
 }
 
 // NewAgencyMergeStrategy creates a new AgencyMergeStrategy
 func NewAgencyMergeStrategy() *AgencyMergeStrategy {
 	return &AgencyMergeStrategy{
-		BaseStrategy: NewBaseStrategy("agency"),
+		BaseStrategy:   NewBaseStrategy("agency"),
+		FuzzyThreshold: 0.5,
 	}
 }
 
+// SetNewestEditionWins sets NewestEditionWins
+func (s *AgencyMergeStrategy) SetNewestEditionWins(newest bool) {
+	s.NewestEditionWins = newest
+}
+
 // Merge performs the merge operation for agencies
 func (s *AgencyMergeStrategy) Merge(ctx *MergeContext) error {
 	// Sort source agency IDs to match Java output order
@@ -30,34 +50,69 @@ func (s *AgencyMergeStrategy) Merge(ctx *MergeContext) error {
 		return sortedAgencyIDs[i] < sortedAgencyIDs[j]
 	})
 
-	for _, agencyID := range sortedAgencyIDs {
+	// Agencies added from this source feed are not fuzzy-match candidates
+	justAdded := make(map[gtfs.AgencyID]struct{})
+
+	for i, agencyID := range sortedAgencyIDs {
+		if err := ctx.checkCanceled(i); err != nil {
+			return err
+		}
 		agency := ctx.Source.Agencies[agencyID]
+
+		// An empty agency_id would collide with every other feed's empty
+		// agency_id, so give it a feed-specific ID. Routes and fare_attributes
+		// with an empty agency_id follow through AgencyIDMapping[""].
+		id := agency.ID
+		if id == "" {
+			id = gtfs.AgencyID(ctx.Prefix + syntheticAgencyID)
+			ctx.Target.AddColumn("agency.txt", "agency_id")
+		}
+
 		// Check for duplicates based on detection mode
 		if s.DuplicateDetection == DetectionIdentity {
-			if existing, found := ctx.Target.Agencies[agency.ID]; found {
+			if existingID, found := identityMatch(ctx, gtfs.KindAgency, id, ctx.Target.Agencies); found && !ctx.blocked(gtfs.KindAgency, string(agency.ID), string(existingID)) {
 				// Duplicate detected - map source ID to existing target ID
-				ctx.AgencyIDMapping[agency.ID] = existing.ID
+				ctx.RegisterAgencyID(agency.ID, existingID)
+				s.takeNewerFields(ctx, ctx.Target.Agencies[existingID], agency)
 
 				// Handle logging based on configuration
 				switch s.DuplicateLogging {
 				case LogWarning:
-					log.Printf("WARNING: Duplicate agency detected with ID %q (keeping existing)", agency.ID)
+					log.Printf("WARNING: Duplicate agency detected with ID %q (keeping existing)", id)
 				case LogError:
-					return fmt.Errorf("duplicate agency detected with ID %q", agency.ID)
+					return fmt.Errorf("duplicate agency detected with ID %q", id)
 				}
 
 				// Skip adding this agency - use the existing one
+				ctx.countDeduplicated("agency.txt", 1)
 				continue
 			}
 		}
 
-		// Determine new ID - only apply prefix if there's a collision
-		newID := agency.ID
-		if _, exists := ctx.Target.Agencies[agency.ID]; exists {
-			// Collision detected - apply prefix
-			newID = gtfs.AgencyID(ctx.Prefix + string(agency.ID))
+		// Check for fuzzy duplicates
+		if s.DuplicateDetection == DetectionFuzzy {
+			if matchID, found := s.findFuzzyMatch(ctx, agency, justAdded); found && !ctx.blocked(gtfs.KindAgency, string(agency.ID), string(matchID)) {
+				// Fuzzy duplicate detected - map source ID to existing target ID
+				// so routes and fare_attributes follow
+				ctx.RegisterAgencyID(agency.ID, matchID)
+				s.takeNewerFields(ctx, ctx.Target.Agencies[matchID], agency)
+
+				switch s.DuplicateLogging {
+				case LogWarning:
+					log.Printf("WARNING: Fuzzy duplicate agency detected: %q matches %q (keeping existing)", agency.ID, matchID)
+				case LogError:
+					return fmt.Errorf("fuzzy duplicate agency detected: %q matches %q", agency.ID, matchID)
+				}
+
+				// Skip adding this agency - use the existing one
+				ctx.countDeduplicated("agency.txt", 1)
+				continue
+			}
 		}
-		ctx.AgencyIDMapping[agency.ID] = newID
+
+		// Determine new ID - only apply prefix if there's a collision
+		newID := UniqueID(ctx, ctx.Target.Agencies, id)
+		ctx.RegisterAgencyID(agency.ID, newID)
 
 		newAgency := &gtfs.Agency{
 			ID:       newID,
@@ -71,7 +126,190 @@ func (s *AgencyMergeStrategy) Merge(ctx *MergeContext) error {
 		}
 		ctx.Target.Agencies[newID] = newAgency
 		ctx.Target.AgencyOrder = append(ctx.Target.AgencyOrder, newID)
+		justAdded[newID] = struct{}{}
+		if s.NewestEditionWins && ctx.AgencyEditions != nil {
+			editions := ctx.agencyEditions(newID)
+			for _, f := range agencyFields {
+				if *f.field(newAgency) != "" {
+					editions[f.column] = ctx.SourceEdition
+				}
+			}
+		}
 	}
 
 	return nil
 }
+
+// agencyFields lists the agency.txt columns other than agency_id, and the
+// field of gtfs.Agency holding each
+var agencyFields = []struct {
+	column string
+	field  func(*gtfs.Agency) *string
+}{
+	{"agency_name", func(a *gtfs.Agency) *string { return &a.Name }},
+	{"agency_url", func(a *gtfs.Agency) *string { return &a.URL }},
+	{"agency_timezone", func(a *gtfs.Agency) *string { return &a.Timezone }},
+	{"agency_lang", func(a *gtfs.Agency) *string { return &a.Lang }},
+	{"agency_phone", func(a *gtfs.Agency) *string { return &a.Phone }},
+	{"agency_fare_url", func(a *gtfs.Agency) *string { return &a.FareURL }},
+	{"agency_email", func(a *gtfs.Agency) *string { return &a.Email }},
+}
+
+// takeNewerFields resolves source, a duplicate of the target agency
+// existing, under NewestEditionWins: each of existing's fields takes
+// source's value if it is empty or came from an older edition than the
+// source feed's. Source's empty fields are skipped. Each value replaced is
+// recorded in ctx.AgencyFieldChanges.
+func (s *AgencyMergeStrategy) takeNewerFields(ctx *MergeContext, existing, source *gtfs.Agency) {
+	if !s.NewestEditionWins {
+		return
+	}
+	editions := ctx.agencyEditions(existing.ID)
+	for _, f := range agencyFields {
+		value := *f.field(source)
+		if value == "" {
+			continue
+		}
+		current := f.field(existing)
+		newer := ctx.SourceEdition.Compare(editions[f.column]) > 0
+		if *current != "" && (!newer || *current == value) {
+			if newer && editions != nil {
+				// The newer edition agrees; later editions are compared
+				// with it
+				editions[f.column] = ctx.SourceEdition
+			}
+			continue
+		}
+		if *current != "" {
+			ctx.AgencyFieldChanges = append(ctx.AgencyFieldChanges, AgencyFieldChange{
+				AgencyID:   existing.ID,
+				Field:      f.column,
+				Old:        *current,
+				New:        value,
+				SourceFeed: ctx.SourceFeed,
+				Edition:    ctx.SourceEdition,
+			})
+		}
+		*current = value
+		if editions != nil {
+			editions[f.column] = ctx.SourceEdition
+		}
+	}
+}
+
+// agencyEditions returns the editions of the target agency id's fields
+// from ctx.AgencyEditions, adding an entry if it has none, or nil if
+// ctx.AgencyEditions is nil
+func (ctx *MergeContext) agencyEditions(id gtfs.AgencyID) map[string]FeedEdition {
+	if ctx.AgencyEditions == nil {
+		return nil
+	}
+	editions, ok := ctx.AgencyEditions[id]
+	if !ok {
+		editions = make(map[string]FeedEdition)
+		ctx.AgencyEditions[id] = editions
+	}
+	return editions
+}
+
+// findFuzzyMatch searches for a fuzzy duplicate in the target agencies.
+// Returns the ID of the best-scoring agency at or above FuzzyThreshold.
+// Ties go to the lowest agency ID (see betterMatch).
+func (s *AgencyMergeStrategy) findFuzzyMatch(ctx *MergeContext, source *gtfs.Agency, justAdded map[gtfs.AgencyID]struct{}) (gtfs.AgencyID, bool) {
+	var bestMatch gtfs.AgencyID
+	var bestScore float64
+	found := false
+
+	for _, id := range ctx.Target.AgencyOrder {
+		target := ctx.Target.Agencies[id]
+		if target == nil {
+			continue
+		}
+		if _, skip := justAdded[id]; skip && !ctx.IntraFeed {
+			continue
+		}
+
+		score := agencyFuzzyScore(source, target)
+		if score >= s.FuzzyThreshold && betterMatch(score, id, bestScore, bestMatch) {
+			bestScore = score
+			bestMatch = id
+			found = true
+		}
+	}
+
+	return bestMatch, found
+}
+
+// agencyFuzzyScore scores how likely two agencies are the same operator.
+// Differing timezones or differing agency_url hostnames veto the match, as
+// does a partial name match unless both hostnames are known and agree.
+// Otherwise the score is a weighted sum of agency_name token overlap (after
+// normalization) and hostname agreement, so an exact name match is enough
+// without URLs and a shared hostname lets partially matching names through.
+func agencyFuzzyScore(source, target *gtfs.Agency) float64 {
+	if !strings.EqualFold(strings.TrimSpace(source.Timezone), strings.TrimSpace(target.Timezone)) {
+		return 0.0
+	}
+
+	hostScore := agencyHostScore(source.URL, target.URL)
+	if hostScore == 0.0 {
+		return 0.0
+	}
+	nameScore := elementOverlapScore(agencyNameTokens(source.Name), agencyNameTokens(target.Name))
+	if nameScore < 1.0 && hostScore < 1.0 {
+		return 0.0
+	}
+	return 0.6*nameScore + 0.4*hostScore
+}
+
+// agencyNameTokens lowercases name and splits it on anything that is not a
+// letter or digit, so "King County Metro" and "king-county metro." compare equal
+func agencyNameTokens(name string) []string {
+	return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// agencyHostScore returns 1.0 when both URLs share a hostname, 0.0 when they
+// differ, and a neutral 0.5 when either hostname is unknown
+func agencyHostScore(source, target string) float64 {
+	sourceHost := agencyURLHost(source)
+	targetHost := agencyURLHost(target)
+	if sourceHost == "" || targetHost == "" {
+		return 0.5
+	}
+	if sourceHost == targetHost {
+		return 1.0
+	}
+	return 0.0
+}
+
+// agencyURLHost extracts the lowercased hostname from an agency URL,
+// ignoring a leading "www." and tolerating a missing scheme
+func agencyURLHost(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// sourceAgencyID returns the agency a source route or fare_attribute with
+// agency_id id belongs to. An empty agency_id in a feed with exactly one
+// agency, which GTFS allows, is that agency's ID, so it is mapped to the
+// agency's merged ID rather than left empty in a multi-agency feed.
+func (ctx *MergeContext) sourceAgencyID(id gtfs.AgencyID) gtfs.AgencyID {
+	if id == "" && len(ctx.Source.Agencies) == 1 {
+		for agencyID := range ctx.Source.Agencies {
+			return agencyID
+		}
+	}
+	return id
+}
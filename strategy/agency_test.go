@@ -142,6 +142,42 @@ func TestAgencyMergeUpdatesRouteRefs(t *testing.T) {
 	// This is verified at a higher level in merger_test.go
 }
 
+func TestAgencyMergeMapsEveryAgency(t *testing.T) {
+	for _, detection := range []DuplicateDetection{DetectionNone, DetectionIdentity, DetectionFuzzy} {
+		t.Run(detection.String(), func(t *testing.T) {
+			// Given: source agencies that are deduped, kept or prefixed
+			// depending on the detection mode, one without an agency_id
+			source := gtfs.NewFeed()
+			source.AddAgency(&gtfs.Agency{ID: "metro", Name: "Metro Transit", Timezone: "America/Los_Angeles"})
+			source.AddAgency(&gtfs.Agency{ID: "metro_transit", Name: "Metro Transit", Timezone: "America/Los_Angeles"})
+			source.AddAgency(&gtfs.Agency{ID: "ferry", Name: "City Ferry", Timezone: "America/Los_Angeles"})
+			source.AddAgency(&gtfs.Agency{ID: "", Name: "Shuttle", Timezone: "America/Los_Angeles"})
+
+			target := gtfs.NewFeed()
+			target.AddAgency(&gtfs.Agency{ID: "metro", Name: "Metro Transit", Timezone: "America/Los_Angeles"})
+
+			ctx := NewMergeContext(source, target, "a-")
+			strategy := NewAgencyMergeStrategy()
+			strategy.SetDuplicateDetection(detection)
+
+			// When: agencies are merged
+			if err := strategy.Merge(ctx); err != nil {
+				t.Fatalf("Merge failed: %v", err)
+			}
+
+			// Then: every source agency maps to an agency in the target
+			for _, id := range source.AgencyOrder {
+				mapped, ok := ctx.AgencyIDMapping[id]
+				if !ok {
+					t.Errorf("Expected a mapping for agency %q", id)
+				} else if target.Agencies[mapped] == nil {
+					t.Errorf("Expected agency %q to map to an agency in the target, got %q", id, mapped)
+				}
+			}
+		})
+	}
+}
+
 func TestAgencyMergeLogsWarning(t *testing.T) {
 	// Given: both feeds have agency with same ID and warning logging enabled
 	source := gtfs.NewFeed()
@@ -294,3 +330,226 @@ func TestAgencyMergeDetectionNone(t *testing.T) {
 		t.Error("Expected a_agency1 to be in target")
 	}
 }
+
+func TestAgencyMergeFuzzySameNameDifferentID(t *testing.T) {
+	// Given: the same agency published under different IDs and name styling
+	source := gtfs.NewFeed()
+	source.AddAgency(&gtfs.Agency{
+		ID:       "KCM",
+		Name:     "king county metro.",
+		URL:      "https://kingcounty.gov/metro",
+		Timezone: "America/Los_Angeles",
+	})
+	source.AddRoute(&gtfs.Route{ID: "route1", AgencyID: "KCM", ShortName: "1", Type: 3})
+
+	target := gtfs.NewFeed()
+	target.AddAgency(&gtfs.Agency{
+		ID:       "1",
+		Name:     "King County Metro",
+		URL:      "http://www.kingcounty.gov",
+		Timezone: "America/Los_Angeles",
+	})
+
+	ctx := NewMergeContext(source, target, "b-")
+	strategy := NewAgencyMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionFuzzy)
+
+	// When: agencies and routes are merged
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if err := NewRouteMergeStrategy().Merge(ctx); err != nil {
+		t.Fatalf("Route merge failed: %v", err)
+	}
+
+	// Then: one agency remains and the route follows the mapping
+	if len(target.Agencies) != 1 {
+		t.Errorf("Expected 1 agency, got %d", len(target.Agencies))
+	}
+	if ctx.AgencyIDMapping["KCM"] != "1" {
+		t.Errorf("Expected KCM to map to 1, got %q", ctx.AgencyIDMapping["KCM"])
+	}
+	if r := target.Routes["route1"]; r == nil || r.AgencyID != "1" {
+		t.Errorf("Expected route1 to reference agency 1, got %+v", r)
+	}
+}
+
+func TestAgencyMergeFuzzyDifferentTimezone(t *testing.T) {
+	// Given: agencies with the same name and URL but different timezones
+	source := gtfs.NewFeed()
+	source.AddAgency(&gtfs.Agency{ID: "metro_east", Name: "Metro Transit", URL: "http://metro.example.com", Timezone: "America/New_York"})
+
+	target := gtfs.NewFeed()
+	target.AddAgency(&gtfs.Agency{ID: "metro_west", Name: "Metro Transit", URL: "http://metro.example.com", Timezone: "America/Los_Angeles"})
+
+	ctx := NewMergeContext(source, target, "b-")
+	strategy := NewAgencyMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionFuzzy)
+
+	// When: merged
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: both agencies are kept
+	if len(target.Agencies) != 2 {
+		t.Errorf("Expected 2 agencies, got %d", len(target.Agencies))
+	}
+	if ctx.AgencyIDMapping["metro_east"] != "metro_east" {
+		t.Errorf("Expected metro_east to keep its ID, got %q", ctx.AgencyIDMapping["metro_east"])
+	}
+}
+
+func TestAgencyFuzzyScore(t *testing.T) {
+	tests := []struct {
+		name      string
+		source    gtfs.Agency
+		target    gtfs.Agency
+		wantMatch bool
+	}{
+		{
+			name:      "exact name without URLs",
+			source:    gtfs.Agency{Name: "Sound Transit", Timezone: "UTC"},
+			target:    gtfs.Agency{Name: "SOUND TRANSIT", Timezone: "UTC"},
+			wantMatch: true,
+		},
+		{
+			name:      "partial name with shared hostname",
+			source:    gtfs.Agency{Name: "KC Metro", URL: "kingcounty.gov", Timezone: "UTC"},
+			target:    gtfs.Agency{Name: "King County Metro", URL: "https://www.kingcounty.gov/metro", Timezone: "UTC"},
+			wantMatch: true,
+		},
+		{
+			name:      "partial name with different hostnames",
+			source:    gtfs.Agency{Name: "KC Metro", URL: "http://kcmetro.org", Timezone: "UTC"},
+			target:    gtfs.Agency{Name: "King County Metro", URL: "https://kingcounty.gov", Timezone: "UTC"},
+			wantMatch: false,
+		},
+		{
+			name:      "exact name with different hostnames",
+			source:    gtfs.Agency{Name: "Metro Transit", URL: "http://metrotransit.org", Timezone: "UTC"},
+			target:    gtfs.Agency{Name: "Metro Transit", URL: "https://metro.net", Timezone: "UTC"},
+			wantMatch: false,
+		},
+		{
+			name:      "half-overlapping names without URLs",
+			source:    gtfs.Agency{Name: "Metro Transit", Timezone: "UTC"},
+			target:    gtfs.Agency{Name: "Metro Ferries", Timezone: "UTC"},
+			wantMatch: false,
+		},
+		{
+			name:      "partial name with one hostname unknown",
+			source:    gtfs.Agency{Name: "KC Metro", Timezone: "UTC"},
+			target:    gtfs.Agency{Name: "King County Metro", URL: "https://kingcounty.gov", Timezone: "UTC"},
+			wantMatch: false,
+		},
+		{
+			name:      "shared hostname with unrelated names",
+			source:    gtfs.Agency{Name: "Streetcar", URL: "http://city.gov", Timezone: "UTC"},
+			target:    gtfs.Agency{Name: "Ferry Service", URL: "http://city.gov", Timezone: "UTC"},
+			wantMatch: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score := agencyFuzzyScore(&tt.source, &tt.target)
+			if got := score >= 0.5; got != tt.wantMatch {
+				t.Errorf("Expected match=%v, got score %.2f", tt.wantMatch, score)
+			}
+		})
+	}
+}
+
+func TestAgencyMergeEmptyIDGetsSyntheticID(t *testing.T) {
+	// Given: target already holds an agency from a feed without agency_id,
+	// and the source feed also omits agency_id
+	target := gtfs.NewFeed()
+	target.AddAgency(&gtfs.Agency{ID: "agency", Name: "Lakeside Transit", Timezone: "America/Chicago"})
+
+	source := gtfs.NewFeed()
+	source.AddAgency(&gtfs.Agency{ID: "", Name: "Prairie Bus Lines", Timezone: "America/Chicago"})
+	source.AddRoute(&gtfs.Route{ID: "20", ShortName: "20", Type: 3})
+	source.AddFareAttribute(&gtfs.FareAttribute{FareID: "regular", Price: 1.75, CurrencyType: "USD"})
+
+	ctx := NewMergeContext(source, target, "b-")
+	strategy := NewAgencyMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionIdentity)
+
+	// When: agencies, routes and fares are merged
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if err := NewRouteMergeStrategy().Merge(ctx); err != nil {
+		t.Fatalf("Route merge failed: %v", err)
+	}
+	if err := NewFareAttributeMergeStrategy().Merge(ctx); err != nil {
+		t.Fatalf("Fare merge failed: %v", err)
+	}
+
+	// Then: the source agency is kept under a feed-specific synthetic ID
+	if len(target.Agencies) != 2 {
+		t.Fatalf("Expected 2 agencies, got %d", len(target.Agencies))
+	}
+	if ctx.AgencyIDMapping[""] != "b-agency" {
+		t.Errorf("Expected empty agency_id to map to b-agency, got %q", ctx.AgencyIDMapping[""])
+	}
+	if target.Agencies["b-agency"] == nil {
+		t.Errorf("Expected agency b-agency in target, got %v", target.AgencyOrder)
+	}
+
+	// And: references with an empty agency_id follow it
+	if r := target.Routes["20"]; r == nil || r.AgencyID != "b-agency" {
+		t.Errorf("Expected route 20 to reference b-agency, got %+v", r)
+	}
+	if fa := target.FareAttributes["regular"]; fa == nil || fa.AgencyID != "b-agency" {
+		t.Errorf("Expected fare regular to reference b-agency, got %+v", fa)
+	}
+}
+
+func TestAgencyMergeNewestEditionWins(t *testing.T) {
+	// Given: the 2024 edition's agency, merged first
+	target := gtfs.NewFeed()
+	editions := make(map[gtfs.AgencyID]map[string]FeedEdition)
+	strategy := NewAgencyMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionIdentity)
+	strategy.SetNewestEditionWins(true)
+	merge := func(edition string, agency gtfs.Agency) *MergeContext {
+		t.Helper()
+		source := gtfs.NewFeed()
+		source.AddAgency(&agency)
+		ctx := NewMergeContext(source, target, "")
+		ctx.SourceFeed = edition
+		ctx.SourceEdition = FeedEdition{StartDate: edition}
+		ctx.AgencyEditions = editions
+		if err := strategy.Merge(ctx); err != nil {
+			t.Fatalf("Merge failed: %v", err)
+		}
+		return ctx
+	}
+	merge("20240101", gtfs.Agency{ID: "metro", Name: "Metro", URL: "http://old.example.com", Timezone: "UTC", Phone: "555-0100", Email: "info@example.com"})
+
+	// When: the newer 2025 edition is merged, with a new URL and phone and
+	// no email
+	ctx := merge("20250101", gtfs.Agency{ID: "metro", Name: "Metro", URL: "http://new.example.com", Timezone: "UTC", Phone: "555-0199"})
+
+	// Then: its values replace the older ones, but its empty email doesn't
+	agency := target.Agencies["metro"]
+	if agency.URL != "http://new.example.com" || agency.Phone != "555-0199" || agency.Email != "info@example.com" {
+		t.Errorf("Expected the newer URL and phone and the older email, got %+v", agency)
+	}
+	if len(ctx.AgencyFieldChanges) != 2 || ctx.AgencyFieldChanges[0].Field != "agency_url" || ctx.AgencyFieldChanges[1].Old != "555-0100" {
+		t.Errorf("Expected the URL and phone changes recorded, got %+v", ctx.AgencyFieldChanges)
+	}
+
+	// When: an edition between the two is merged
+	ctx = merge("20240601", gtfs.Agency{ID: "metro", Name: "Metro", URL: "http://mid.example.com", Timezone: "UTC", Phone: "555-0150", Lang: "en"})
+
+	// Then: only the field no edition had is taken
+	if agency.URL != "http://new.example.com" || agency.Phone != "555-0199" || agency.Lang != "en" {
+		t.Errorf("Expected the 2025 URL and phone kept and the language filled in, got %+v", agency)
+	}
+	if len(ctx.AgencyFieldChanges) != 0 {
+		t.Errorf("Expected no changes recorded, got %+v", ctx.AgencyFieldChanges)
+	}
+}
@@ -19,16 +19,25 @@ func NewAreaMergeStrategy() *AreaMergeStrategy {
 	}
 }
 
+// areaNameFold makes fuzzy area matching ignore spacing and case
+var areaNameFold = NormalizerChain{CollapseSpaces, CaseFold}
+
 // Merge performs the merge operation for areas
 func (s *AreaMergeStrategy) Merge(ctx *MergeContext) error {
+	// Areas added from this source feed are not fuzzy-match candidates
+	justAdded := make(map[gtfs.AreaID]struct{})
+
 	// Iterate in insertion order to match Java output
-	for _, areaID := range ctx.Source.AreaOrder {
+	for i, areaID := range ctx.Source.AreaOrder {
+		if err := ctx.checkCanceled(i); err != nil {
+			return err
+		}
 		area := ctx.Source.Areas[areaID]
 		// Check for duplicates based on detection mode
 		if s.DuplicateDetection == DetectionIdentity {
-			if existing, found := ctx.Target.Areas[area.ID]; found {
+			if existingID, found := identityMatch(ctx, gtfs.KindArea, area.ID, ctx.Target.Areas); found && !ctx.blocked(gtfs.KindArea, string(area.ID), string(existingID)) {
 				// Duplicate detected - map source ID to existing target ID
-				ctx.AreaIDMapping[area.ID] = existing.ID
+				ctx.RegisterAreaID(area.ID, existingID)
 
 				// Handle logging based on configuration
 				switch s.DuplicateLogging {
@@ -39,25 +48,73 @@ func (s *AreaMergeStrategy) Merge(ctx *MergeContext) error {
 				}
 
 				// Skip adding this area - use the existing one
+				ctx.countDeduplicated("areas.txt", 1)
+				continue
+			}
+		}
+
+		// Check for fuzzy duplicates
+		if s.DuplicateDetection == DetectionFuzzy {
+			if matchID, found := s.findFuzzyMatch(ctx, area, justAdded); found && !ctx.blocked(gtfs.KindArea, string(area.ID), string(matchID)) {
+				// Fuzzy duplicate detected - map source ID to existing target ID
+				ctx.RegisterAreaID(area.ID, matchID)
+
+				switch s.DuplicateLogging {
+				case LogWarning:
+					log.Printf("WARNING: Fuzzy duplicate area detected: %q matches %q (keeping existing)", area.ID, matchID)
+				case LogError:
+					return fmt.Errorf("fuzzy duplicate area detected: %q matches %q", area.ID, matchID)
+				}
+
+				// Skip adding this area - use the existing one
+				ctx.countDeduplicated("areas.txt", 1)
 				continue
 			}
 		}
 
 		// Determine new ID - only apply prefix if there's a collision
-		newID := area.ID
-		if _, exists := ctx.Target.Areas[area.ID]; exists {
-			// Collision detected - apply prefix
-			newID = gtfs.AreaID(ctx.Prefix + string(area.ID))
+		newID := UniqueID(ctx, ctx.Target.Areas, area.ID)
+		ctx.RegisterAreaID(area.ID, newID)
+
+		name := area.Name
+		if ctx.NormalizeOutput {
+			name = s.normalize(name)
 		}
-		ctx.AreaIDMapping[area.ID] = newID
 
 		newArea := &gtfs.Area{
 			ID:   newID,
-			Name: area.Name,
+			Name: name,
 		}
 		ctx.Target.Areas[newID] = newArea
 		ctx.Target.AreaOrder = append(ctx.Target.AreaOrder, newID)
+		justAdded[newID] = struct{}{}
 	}
 
 	return nil
 }
+
+// findFuzzyMatch returns the target area with the lowest area_id whose
+// area_name equals the source's once normalized and compared ignoring
+// spacing and case, so the match doesn't depend on the order of rows in
+// areas.txt. Areas without a name never match.
+func (s *AreaMergeStrategy) findFuzzyMatch(ctx *MergeContext, source *gtfs.Area, justAdded map[gtfs.AreaID]struct{}) (gtfs.AreaID, bool) {
+	name := areaNameFold.Normalize(s.normalize(source.Name))
+	if name == "" {
+		return "", false
+	}
+
+	var match gtfs.AreaID
+	var found bool
+	for id, target := range ctx.Target.Areas {
+		if found && id >= match {
+			continue
+		}
+		if _, skip := justAdded[id]; skip && !ctx.IntraFeed {
+			continue
+		}
+		if target != nil && areaNameFold.Normalize(s.normalize(target.Name)) == name {
+			match, found = id, true
+		}
+	}
+	return match, found
+}
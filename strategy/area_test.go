@@ -115,3 +115,107 @@ func TestAreaMergeWithPrefix(t *testing.T) {
 		t.Errorf("Expected mapping area1 -> a_area1, got %q", ctx.AreaIDMapping["area1"])
 	}
 }
+
+func TestAreaMergeErrorOnDuplicate(t *testing.T) {
+	// Given: both feeds have area with ID "area1" and error logging enabled
+	source := gtfs.NewFeed()
+	source.AddArea(&gtfs.Area{ID: "area1", Name: "Downtown"})
+
+	target := gtfs.NewFeed()
+	target.AddArea(&gtfs.Area{ID: "area1", Name: "Downtown"})
+
+	ctx := NewMergeContext(source, target, "")
+	strategy := NewAreaMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionIdentity)
+	strategy.SetDuplicateLogging(LogError)
+
+	// When: merged with LogError
+	err := strategy.Merge(ctx)
+
+	// Then: should return an error
+	if err == nil {
+		t.Fatal("Expected error when duplicate detected with LogError")
+	}
+}
+
+func TestAreaMergeFuzzyByName(t *testing.T) {
+	// Given: areas with different IDs whose names differ in spacing and case
+	source := gtfs.NewFeed()
+	source.AddArea(&gtfs.Area{ID: "dt", Name: "  DOWNTOWN  Core"})
+	source.AddArea(&gtfs.Area{ID: "airport", Name: "Airport"})
+
+	target := gtfs.NewFeed()
+	target.AddArea(&gtfs.Area{ID: "downtown", Name: "Downtown Core"})
+
+	ctx := NewMergeContext(source, target, "a_")
+	strategy := NewAreaMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionFuzzy)
+
+	// When: merged with DetectionFuzzy
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the matching area is mapped to the existing one
+	if ctx.AreaIDMapping["dt"] != "downtown" {
+		t.Errorf("Expected AreaIDMapping[dt] = downtown, got %q", ctx.AreaIDMapping["dt"])
+	}
+
+	// And: the other area is added
+	if len(target.Areas) != 2 || ctx.AreaIDMapping["airport"] != "airport" {
+		t.Errorf("Expected downtown and airport, got %v", target.AreaOrder)
+	}
+}
+
+func TestAreaMergeFuzzyNoMatch(t *testing.T) {
+	// Given: a colliding area_id with a different name, and two unnamed areas
+	source := gtfs.NewFeed()
+	source.AddArea(&gtfs.Area{ID: "area1", Name: "Uptown"})
+	source.AddArea(&gtfs.Area{ID: "unnamed_a"})
+
+	target := gtfs.NewFeed()
+	target.AddArea(&gtfs.Area{ID: "area1", Name: "Downtown"})
+	target.AddArea(&gtfs.Area{ID: "unnamed_b"})
+
+	ctx := NewMergeContext(source, target, "a_")
+	strategy := NewAreaMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionFuzzy)
+
+	// When: merged with DetectionFuzzy
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: nothing matches and the colliding ID is prefixed
+	if len(target.Areas) != 4 {
+		t.Errorf("Expected 4 areas, got %v", target.AreaOrder)
+	}
+	if ctx.AreaIDMapping["area1"] != "a_area1" || ctx.AreaIDMapping["unnamed_a"] != "unnamed_a" {
+		t.Errorf("Expected area1 prefixed and unnamed_a kept, got %v", ctx.AreaIDMapping)
+	}
+}
+
+func TestAreaMergeFuzzyTiesGoToLowestID(t *testing.T) {
+	// Given: the target has two areas with the same name, the higher
+	// area_id first
+	source := gtfs.NewFeed()
+	source.AddArea(&gtfs.Area{ID: "dt", Name: "Downtown"})
+
+	target := gtfs.NewFeed()
+	target.AddArea(&gtfs.Area{ID: "downtown_b", Name: "Downtown"})
+	target.AddArea(&gtfs.Area{ID: "downtown_a", Name: "Downtown"})
+
+	ctx := NewMergeContext(source, target, "a_")
+	strategy := NewAreaMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionFuzzy)
+
+	// When: merged with DetectionFuzzy
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the source area maps to the lowest area_id, not the first row
+	if got := ctx.AreaIDMapping["dt"]; got != "downtown_a" {
+		t.Errorf("Expected AreaIDMapping[dt] = downtown_a, got %q", got)
+	}
+}
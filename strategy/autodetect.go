@@ -3,6 +3,7 @@ package strategy
 import (
 	"strings"
 
+	"github.com/aaronbrethorst/gtfs-merge-go/geo"
 	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
 )
 
@@ -25,6 +26,11 @@ func DefaultAutoDetectConfig() AutoDetectConfig {
 
 // AutoDetectDuplicateDetection automatically chooses the best duplicate detection strategy
 // based on feed analysis using default thresholds.
+// The ID overlap of agencies, stops, routes, trips and calendar.txt services
+// is scored for identity detection, and the similarity of agencies, stops and
+// routes for fuzzy detection. Stop times and shapes are not examined, so
+// partial feeds read with gtfs.ReadMetadataOnly give the same result as fully
+// read ones.
 func AutoDetectDuplicateDetection(source, target *gtfs.Feed) DuplicateDetection {
 	return AutoDetectDuplicateDetectionWithConfig(source, target, DefaultAutoDetectConfig())
 }
@@ -172,8 +178,8 @@ func stopFuzzySimilarity(source, target *gtfs.Feed) float64 {
 			nameMatch := strings.EqualFold(srcStop.Name, tgtStop.Name)
 
 			// Check proximity (within 500m)
-			distance := haversineDistance(srcStop.Lat, srcStop.Lon, tgtStop.Lat, tgtStop.Lon)
-			proximityMatch := distance < 500
+			distance := geo.HaversineMeters(srcStop.Lat, srcStop.Lon, tgtStop.Lat, tgtStop.Lon)
+			proximityMatch := distance < stopMatchRadiusMeters
 
 			// Consider a fuzzy match if names match AND locations are close
 			if nameMatch && proximityMatch {
@@ -258,4 +264,3 @@ func getServiceIDs(feed *gtfs.Feed) []string {
 }
 
 // Note: elementOverlapScore[T comparable] is defined in route.go
-// Note: haversineDistance is defined in stop.go
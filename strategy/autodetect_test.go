@@ -323,3 +323,31 @@ func TestAutoDetectRouteOverlapNoStopOverlap(t *testing.T) {
 		t.Errorf("Expected DetectionIdentity when any entity type has significant ID overlap, got %v", detection)
 	}
 }
+
+// TestStopFuzzySimilarityProximityInMeters verifies that same-named stops
+// count as similar only within 500 meters of each other
+func TestStopFuzzySimilarityProximityInMeters(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat      float64
+		expected float64
+	}{
+		{"100 meters apart", 47.6106, 1},
+		{"10 kilometers apart", 47.7, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: stops with the same name at different distances
+			source := gtfs.NewFeed()
+			source.AddStop(&gtfs.Stop{ID: "s1", Name: "Pike Place Market", Lat: 47.6097, Lon: -122.3425})
+			target := gtfs.NewFeed()
+			target.AddStop(&gtfs.Stop{ID: "t1", Name: "Pike Place Market", Lat: tt.lat, Lon: -122.3425})
+
+			// When/Then: only the nearby stop counts
+			if got := stopFuzzySimilarity(source, target); got != tt.expected {
+				t.Errorf("Expected similarity %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package strategy
+
+import "github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+
+// BlockedMatch names a source entity and a target entity that must not be
+// merged as duplicates
+type BlockedMatch struct {
+	Kind     gtfs.EntityKind
+	SourceID string
+	TargetID string
+}
+
+// blocked reports whether the source entity sourceID must not be merged into
+// the target entity targetID, recording the refusal in BlockedHits. A
+// blocked entity falls back to being added, prefixed on collision.
+func (ctx *MergeContext) blocked(kind gtfs.EntityKind, sourceID, targetID string) bool {
+	index, ok := ctx.BlockedMatches[BlockedMatch{Kind: kind, SourceID: sourceID, TargetID: targetID}]
+	if ok {
+		ctx.BlockedHits = append(ctx.BlockedHits, index)
+	}
+	return ok
+}
@@ -0,0 +1,101 @@
+package strategy
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// BoardingDifference records a stop shared by two fuzzy matched trips whose
+// stop_times disagree on pickup_type, drop_off_type or timepoint. The
+// surviving trip's stop_times are kept, so the other trip's value is lost;
+// a timepoint set on only one side is not a difference, as the survivor
+// takes it (see StopTimeMergeStrategy).
+type BoardingDifference struct {
+	// SourceFeed names the feed the source trip came from
+	SourceFeed string
+
+	// SourceID is the ID of the trip being merged and TargetID the ID of
+	// the trip already merged that it matched
+	SourceID gtfs.TripID
+	TargetID gtfs.TripID
+
+	// StopID is the (merged) stop, and StopSequence its stop_sequence in
+	// the target trip
+	StopID       gtfs.StopID
+	StopSequence int
+
+	// Field is "pickup_type", "drop_off_type" or "timepoint"
+	Field string
+
+	// SourceValue and TargetValue are the field's values in each trip; an
+	// empty value is unset
+	SourceValue string
+	TargetValue string
+
+	// SourceSurvives is true if the source trip's stop_times were kept
+	// (see TripSubsetMatch), so TargetValue was dropped
+	SourceSurvives bool
+}
+
+// String describes the difference for logs and warnings
+func (d BoardingDifference) String() string {
+	kept, dropped := d.TargetValue, d.SourceValue
+	if d.SourceSurvives {
+		kept, dropped = d.SourceValue, d.TargetValue
+	}
+	return fmt.Sprintf("feed %s: trip %q matches %q but %s differs at stop %q (sequence %d): keeping %q over %q",
+		d.SourceFeed, d.SourceID, d.TargetID, d.Field, d.StopID, d.StopSequence, kept, dropped)
+}
+
+// stopTimePair is a stop shared by a source trip and the target trip it is
+// compared with
+type stopTimePair struct {
+	source, target *gtfs.StopTime
+}
+
+// boardingDifferences returns the fields on which the paired stop_times of
+// sourceID and targetID differ
+func boardingDifferences(pairs []stopTimePair, sourceID, targetID gtfs.TripID) []BoardingDifference {
+	var diffs []BoardingDifference
+	for _, p := range pairs {
+		add := func(field, source, target string) {
+			if source != target {
+				diffs = append(diffs, BoardingDifference{
+					SourceID:     sourceID,
+					TargetID:     targetID,
+					StopID:       p.target.StopID,
+					StopSequence: p.target.StopSequence,
+					Field:        field,
+					SourceValue:  source,
+					TargetValue:  target,
+				})
+			}
+		}
+		add("pickup_type", strconv.Itoa(p.source.PickupType), strconv.Itoa(p.target.PickupType))
+		add("drop_off_type", strconv.Itoa(p.source.DropOffType), strconv.Itoa(p.target.DropOffType))
+		if p.source.Timepoint != nil && p.target.Timepoint != nil {
+			add("timepoint", optionalInt(p.source.Timepoint), optionalInt(p.target.Timepoint))
+		}
+	}
+	return diffs
+}
+
+// optionalInt formats an optional integer, empty if unset
+func optionalInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+// recordBoardingDifferences records the differences of a fuzzy trip match
+// that was accepted, noting whose stop_times survive
+func (ctx *MergeContext) recordBoardingDifferences(diffs []BoardingDifference) {
+	for _, d := range diffs {
+		d.SourceFeed = ctx.SourceFeed
+		d.SourceSurvives = ctx.MatchedTrips[d.SourceID]
+		ctx.BoardingDifferences = append(ctx.BoardingDifferences, d)
+	}
+}
@@ -14,6 +14,9 @@ type CalendarMergeStrategy struct {
 	BaseStrategy
 	// FuzzyThreshold is the minimum score for a fuzzy match (default 0.5)
 	FuzzyThreshold float64
+	// CollideAcrossFiles makes a service collide with a target service
+	// defined only in calendar_dates.txt (default false)
+	CollideAcrossFiles bool
 }
 
 // NewCalendarMergeStrategy creates a new CalendarMergeStrategy
@@ -24,6 +27,12 @@ func NewCalendarMergeStrategy() *CalendarMergeStrategy {
 	}
 }
 
+// SetCollideAcrossFiles sets whether services collide across calendar.txt
+// and calendar_dates.txt (see targetHasService)
+func (s *CalendarMergeStrategy) SetCollideAcrossFiles(across bool) {
+	s.CollideAcrossFiles = across
+}
+
 // Merge performs the merge operation for calendars
 func (s *CalendarMergeStrategy) Merge(ctx *MergeContext) error {
 	// Sort source calendar IDs to match Java output order
@@ -34,13 +43,16 @@ func (s *CalendarMergeStrategy) Merge(ctx *MergeContext) error {
 		return sortedServiceIDs[i] < sortedServiceIDs[j]
 	})
 
-	for _, serviceID := range sortedServiceIDs {
+	for i, serviceID := range sortedServiceIDs {
+		if err := ctx.checkCanceled(i); err != nil {
+			return err
+		}
 		cal := ctx.Source.Calendars[serviceID]
 		// Check for duplicates based on detection mode
 		if s.DuplicateDetection == DetectionIdentity {
-			if existing, found := ctx.Target.Calendars[cal.ServiceID]; found {
+			if existingID, found := identityMatch(ctx, gtfs.KindService, cal.ServiceID, ctx.Target.Calendars); found && !ctx.blocked(gtfs.KindService, string(cal.ServiceID), string(existingID)) {
 				// Duplicate detected - map source ID to existing target ID
-				ctx.ServiceIDMapping[cal.ServiceID] = existing.ServiceID
+				ctx.RegisterServiceID(cal.ServiceID, existingID)
 
 				// Handle logging based on configuration
 				switch s.DuplicateLogging {
@@ -51,15 +63,16 @@ func (s *CalendarMergeStrategy) Merge(ctx *MergeContext) error {
 				}
 
 				// Skip adding this calendar - use the existing one
+				ctx.countDeduplicated("calendar.txt", 1)
 				continue
 			}
 		}
 
 		// Check for fuzzy duplicates
 		if s.DuplicateDetection == DetectionFuzzy {
-			if matchID := s.findFuzzyMatch(ctx, cal); matchID != "" {
+			if matchID := s.findFuzzyMatch(ctx, cal); matchID != "" && !ctx.blocked(gtfs.KindService, string(cal.ServiceID), string(matchID)) {
 				// Fuzzy duplicate detected - map source ID to existing target ID
-				ctx.ServiceIDMapping[cal.ServiceID] = matchID
+				ctx.RegisterServiceID(cal.ServiceID, matchID)
 
 				switch s.DuplicateLogging {
 				case LogWarning:
@@ -69,17 +82,16 @@ func (s *CalendarMergeStrategy) Merge(ctx *MergeContext) error {
 				}
 
 				// Skip adding this calendar - use the existing one
+				ctx.countDeduplicated("calendar.txt", 1)
 				continue
 			}
 		}
 
 		// Determine new ID - only apply prefix if there's a collision
-		newID := cal.ServiceID
-		if _, exists := ctx.Target.Calendars[cal.ServiceID]; exists {
-			// Collision detected - apply prefix
-			newID = gtfs.ServiceID(ctx.Prefix + string(cal.ServiceID))
-		}
-		ctx.ServiceIDMapping[cal.ServiceID] = newID
+		newID := UniqueIDFunc(ctx, cal.ServiceID, func(id gtfs.ServiceID) bool {
+			return targetHasService(ctx.Target, id, true, s.CollideAcrossFiles)
+		})
+		ctx.RegisterServiceID(cal.ServiceID, newID)
 
 		newCal := &gtfs.Calendar{
 			ServiceID: newID,
@@ -95,14 +107,33 @@ func (s *CalendarMergeStrategy) Merge(ctx *MergeContext) error {
 		}
 		ctx.Target.Calendars[newID] = newCal
 		ctx.Target.CalendarOrder = append(ctx.Target.CalendarOrder, newID)
+		ctx.JustAddedServices[newID] = struct{}{}
 	}
 
 	return nil
 }
 
+// targetHasService reports whether target already defines service id, so
+// that a source service with that ID collides with it. A service from
+// calendar.txt (inCalendar) is compared with the target's calendar.txt
+// services and one only in calendar_dates.txt with its calendar_dates.txt
+// services; with acrossFiles, each is compared with both.
+func targetHasService(target *gtfs.Feed, id gtfs.ServiceID, inCalendar, acrossFiles bool) bool {
+	_, inCalendars := target.Calendars[id]
+	inDates := len(target.CalendarDates[id]) > 0
+	switch {
+	case acrossFiles:
+		return inCalendars || inDates
+	case inCalendar:
+		return inCalendars
+	default:
+		return inDates
+	}
+}
+
 // findFuzzyMatch searches for a fuzzy duplicate in the target calendars.
 // Returns the ID of the matching calendar if found, or empty string if no match.
-// Uses date overlap scoring.
+// Uses date overlap scoring. Ties go to the lowest service ID (see betterMatch).
 func (s *CalendarMergeStrategy) findFuzzyMatch(ctx *MergeContext, source *gtfs.Calendar) gtfs.ServiceID {
 	var bestMatch gtfs.ServiceID
 	var bestScore float64
@@ -110,7 +141,7 @@ func (s *CalendarMergeStrategy) findFuzzyMatch(ctx *MergeContext, source *gtfs.C
 	for _, target := range ctx.Target.Calendars {
 		score := calendarDateOverlapScore(source, target)
 
-		if score >= s.FuzzyThreshold && score > bestScore {
+		if score >= s.FuzzyThreshold && betterMatch(score, target.ServiceID, bestScore, bestMatch) {
 			bestScore = score
 			bestMatch = target.ServiceID
 		}
@@ -185,16 +216,41 @@ func calendarIntervalOverlapScore(start1, end1, start2, end2 float64) float64 {
 // CalendarDateMergeStrategy handles merging of calendar dates between feeds
 type CalendarDateMergeStrategy struct {
 	BaseStrategy
+	// ConflictPolicy decides which exception_type survives when the source and
+	// target disagree for the same (service_id, date) (default ConflictPreferTarget).
+	// With LogError duplicate logging, a conflict returns an error instead.
+	ConflictPolicy ConflictPolicy
+	// CollideAcrossFiles makes a service defined only in calendar_dates.txt
+	// collide with a target service defined only in calendar.txt (default
+	// false)
+	CollideAcrossFiles bool
 }
 
 // NewCalendarDateMergeStrategy creates a new CalendarDateMergeStrategy
 func NewCalendarDateMergeStrategy() *CalendarDateMergeStrategy {
 	return &CalendarDateMergeStrategy{
-		BaseStrategy: NewBaseStrategy("calendar_dates"),
+		BaseStrategy:   NewBaseStrategy("calendar_dates"),
+		ConflictPolicy: ConflictPreferTarget,
 	}
 }
 
-// Merge performs the merge operation for calendar dates
+// SetConflictPolicy sets how conflicting exception types are resolved
+func (s *CalendarDateMergeStrategy) SetConflictPolicy(p ConflictPolicy) {
+	s.ConflictPolicy = p
+}
+
+// SetCollideAcrossFiles sets whether services collide across calendar.txt
+// and calendar_dates.txt (see targetHasService)
+func (s *CalendarDateMergeStrategy) SetCollideAcrossFiles(across bool) {
+	s.CollideAcrossFiles = across
+}
+
+// Merge performs the merge operation for calendar dates.
+// Rows are merged under the service ID chosen by ServiceIDMapping, so when a
+// source service was deduplicated onto an existing target service its exception
+// set is merged into the target's: identical (service_id, date, exception_type)
+// rows are skipped, and rows that conflict on exception_type are resolved
+// according to ConflictPolicy.
 func (s *CalendarDateMergeStrategy) Merge(ctx *MergeContext) error {
 	// Sort source calendar date IDs to match Java output order
 	// Java processes each feed's calendar dates in sorted order within that feed
@@ -204,41 +260,62 @@ func (s *CalendarDateMergeStrategy) Merge(ctx *MergeContext) error {
 		return sortedServiceIDs[i] < sortedServiceIDs[j]
 	})
 
-	for _, serviceID := range sortedServiceIDs {
+	for i, serviceID := range sortedServiceIDs {
+		if err := ctx.checkCanceled(i); err != nil {
+			return err
+		}
 		dates := ctx.Source.CalendarDates[serviceID]
-		newServiceID := ctx.ServiceIDMapping[serviceID]
-		if newServiceID == "" {
+		newServiceID, ok := ctx.MapServiceID(serviceID)
+		if !ok {
 			// Service may only be defined in calendar_dates, not calendar
 			// Only apply prefix if there's a collision
-			newServiceID = serviceID
-			if _, exists := ctx.Target.CalendarDates[serviceID]; exists {
-				newServiceID = gtfs.ServiceID(ctx.Prefix + string(serviceID))
+			newServiceID = UniqueIDFunc(ctx, serviceID, func(id gtfs.ServiceID) bool {
+				return targetHasService(ctx.Target, id, false, s.CollideAcrossFiles)
+			})
+			ctx.RegisterServiceID(serviceID, newServiceID)
+			if _, exists := ctx.Target.Calendars[newServiceID]; !exists && len(ctx.Target.CalendarDates[newServiceID]) == 0 {
+				ctx.JustAddedServices[newServiceID] = struct{}{}
 			}
-			ctx.ServiceIDMapping[serviceID] = newServiceID
 		}
 
 		// Track order for first occurrence of this service_id
 		isFirstForServiceID := len(ctx.Target.CalendarDates[newServiceID]) == 0
 
+		// Index existing rows for this service by date for O(1) lookups
+		existingByDate := make(map[string]*gtfs.CalendarDate, len(ctx.Target.CalendarDates[newServiceID]))
+		for _, existingDate := range ctx.Target.CalendarDates[newServiceID] {
+			existingByDate[existingDate.Date] = existingDate
+		}
+
 		for _, date := range dates {
-			// Check for exact duplicate calendar date (same service_id, date, exception_type)
-			isDuplicate := false
-			if s.DuplicateDetection == DetectionIdentity {
-				for _, existingDate := range ctx.Target.CalendarDates[newServiceID] {
-					if existingDate.Date == date.Date && existingDate.ExceptionType == date.ExceptionType {
-						isDuplicate = true
-						break
+			if existingDate, found := existingByDate[date.Date]; found {
+				if existingDate.ExceptionType == date.ExceptionType {
+					// Exact duplicate (same service_id, date, exception_type) - always skipped
+					if s.DuplicateDetection == DetectionIdentity {
+						switch s.DuplicateLogging {
+						case LogWarning:
+							log.Printf("WARNING: Duplicate calendar_date detected for service_id %q date %q (keeping existing)", serviceID, date.Date)
+						case LogError:
+							return fmt.Errorf("duplicate calendar_date detected for service_id %q date %q", serviceID, date.Date)
+						}
 					}
+					ctx.countDeduplicated("calendar_dates.txt", 1)
+					continue
 				}
-			}
 
-			if isDuplicate {
+				// Conflicting exception types for the same service and date
 				switch s.DuplicateLogging {
 				case LogWarning:
-					log.Printf("WARNING: Duplicate calendar_date detected for service_id %q date %q (keeping existing)", serviceID, date.Date)
+					log.Printf("WARNING: Conflicting calendar_date for service_id %q date %q: exception_type %d vs %d (%s)",
+						newServiceID, date.Date, existingDate.ExceptionType, date.ExceptionType, s.ConflictPolicy)
 				case LogError:
-					return fmt.Errorf("duplicate calendar_date detected for service_id %q date %q", serviceID, date.Date)
+					return fmt.Errorf("conflicting calendar_date for service_id %q date %q: exception_type %d vs %d",
+						newServiceID, date.Date, existingDate.ExceptionType, date.ExceptionType)
+				}
+				if s.ConflictPolicy == ConflictPreferSource {
+					existingDate.ExceptionType = date.ExceptionType
 				}
+				ctx.countDeduplicated("calendar_dates.txt", 1)
 				continue
 			}
 
@@ -248,6 +325,7 @@ func (s *CalendarDateMergeStrategy) Merge(ctx *MergeContext) error {
 				ExceptionType: date.ExceptionType,
 			}
 			ctx.Target.CalendarDates[newServiceID] = append(ctx.Target.CalendarDates[newServiceID], newDate)
+			existingByDate[date.Date] = newDate
 
 			// Track order only when first adding to this service_id
 			if isFirstForServiceID {
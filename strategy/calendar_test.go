@@ -1,6 +1,7 @@
 package strategy
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
@@ -206,6 +207,61 @@ func TestCalendarDatesWithNewServiceID(t *testing.T) {
 	}
 }
 
+func TestServiceCollisionAcrossCalendarFiles(t *testing.T) {
+	for _, across := range []bool{false, true} {
+		// Then: the source service is prefixed only when services collide
+		// across files, and otherwise keeps the target's ID
+		want := gtfs.ServiceID("svc")
+		if across {
+			want = "a_svc"
+		}
+
+		t.Run(fmt.Sprintf("source calendar_dates, target calendar, across=%v", across), func(t *testing.T) {
+			// Given: the target defines svc in calendar.txt, the source only in
+			// calendar_dates.txt
+			source := gtfs.NewFeed()
+			source.AddCalendarDate(&gtfs.CalendarDate{ServiceID: "svc", Date: "20240704", ExceptionType: 1})
+			target := gtfs.NewFeed()
+			target.AddCalendar(&gtfs.Calendar{ServiceID: "svc", Monday: true, StartDate: "20240101", EndDate: "20241231"})
+
+			ctx := NewMergeContext(source, target, "a_")
+			strategy := NewCalendarDateMergeStrategy()
+			strategy.SetCollideAcrossFiles(across)
+
+			// When: calendar dates are merged without duplicate detection
+			if err := strategy.Merge(ctx); err != nil {
+				t.Fatalf("Merge failed: %v", err)
+			}
+
+			if ctx.ServiceIDMapping["svc"] != want || len(target.CalendarDates[want]) != 1 {
+				t.Errorf("Expected svc mapped to %s with its date, got %q", want, ctx.ServiceIDMapping["svc"])
+			}
+		})
+
+		t.Run(fmt.Sprintf("source calendar, target calendar_dates, across=%v", across), func(t *testing.T) {
+			// Given: the target defines svc only in calendar_dates.txt, the
+			// source in calendar.txt
+			source := gtfs.NewFeed()
+			source.AddCalendar(&gtfs.Calendar{ServiceID: "svc", Monday: true, StartDate: "20240101", EndDate: "20241231"})
+			target := gtfs.NewFeed()
+			target.AddCalendarDate(&gtfs.CalendarDate{ServiceID: "svc", Date: "20240704", ExceptionType: 1})
+
+			ctx := NewMergeContext(source, target, "a_")
+			strategy := NewCalendarMergeStrategy()
+			strategy.SetCollideAcrossFiles(across)
+
+			// When: calendars are merged without duplicate detection
+			if err := strategy.Merge(ctx); err != nil {
+				t.Fatalf("Merge failed: %v", err)
+			}
+
+			if ctx.ServiceIDMapping["svc"] != want || target.Calendars[want] == nil {
+				t.Errorf("Expected svc mapped to %s, got %q", want, ctx.ServiceIDMapping["svc"])
+			}
+		})
+	}
+}
+
 func TestCalendarMergeErrorOnDuplicate(t *testing.T) {
 	// Given: both feeds have calendar with same service_id and error logging enabled
 	source := gtfs.NewFeed()
@@ -265,6 +321,148 @@ func TestCalendarDatesDuplicateDetection(t *testing.T) {
 	}
 }
 
+func TestCalendarDatesMergedIntoDedupedService(t *testing.T) {
+	// Given: source service WKDY was deduplicated onto target WKDY and both
+	// feeds carry the same exception row plus one unique row each
+	source := gtfs.NewFeed()
+	source.AddCalendarDate(&gtfs.CalendarDate{ServiceID: "WKDY", Date: "20240704", ExceptionType: 2})
+	source.AddCalendarDate(&gtfs.CalendarDate{ServiceID: "WKDY", Date: "20241128", ExceptionType: 2})
+
+	target := gtfs.NewFeed()
+	target.AddCalendarDate(&gtfs.CalendarDate{ServiceID: "WKDY", Date: "20240704", ExceptionType: 2})
+	target.AddCalendarDate(&gtfs.CalendarDate{ServiceID: "WKDY", Date: "20240527", ExceptionType: 2})
+
+	ctx := NewMergeContext(source, target, "a-")
+	ctx.ServiceIDMapping["WKDY"] = "WKDY"
+
+	strategy := NewCalendarDateMergeStrategy()
+
+	// When: merged (even without identity detection on calendar_dates)
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the exception sets are unioned without duplicate rows
+	dates := target.CalendarDates["WKDY"]
+	if len(dates) != 3 {
+		t.Fatalf("Expected 3 calendar dates, got %d", len(dates))
+	}
+	seen := make(map[string]int)
+	for _, d := range dates {
+		seen[d.Date]++
+	}
+	for _, date := range []string{"20240704", "20241128", "20240527"} {
+		if seen[date] != 1 {
+			t.Errorf("Expected exactly one row for %s, got %d", date, seen[date])
+		}
+	}
+	if len(target.CalendarDateOrder) != 1 {
+		t.Errorf("Expected service order to be tracked once, got %v", target.CalendarDateOrder)
+	}
+}
+
+func TestCalendarDatesConflictPolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       ConflictPolicy
+		expectedType int
+	}{
+		{"prefer target", ConflictPreferTarget, 1},
+		{"prefer source", ConflictPreferSource, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: target adds the date, source removes it
+			source := gtfs.NewFeed()
+			source.AddCalendarDate(&gtfs.CalendarDate{ServiceID: "WKDY", Date: "20240704", ExceptionType: 2})
+
+			target := gtfs.NewFeed()
+			target.AddCalendarDate(&gtfs.CalendarDate{ServiceID: "WKDY", Date: "20240704", ExceptionType: 1})
+
+			ctx := NewMergeContext(source, target, "a-")
+			ctx.ServiceIDMapping["WKDY"] = "WKDY"
+
+			strategy := NewCalendarDateMergeStrategy()
+			strategy.SetDuplicateDetection(DetectionIdentity)
+			strategy.SetConflictPolicy(tt.policy)
+
+			if err := strategy.Merge(ctx); err != nil {
+				t.Fatalf("Merge failed: %v", err)
+			}
+
+			// Then: a single row survives with the policy's exception type
+			dates := target.CalendarDates["WKDY"]
+			if len(dates) != 1 {
+				t.Fatalf("Expected 1 calendar date, got %d", len(dates))
+			}
+			if dates[0].ExceptionType != tt.expectedType {
+				t.Errorf("Expected exception_type %d, got %d", tt.expectedType, dates[0].ExceptionType)
+			}
+		})
+	}
+}
+
+func TestCalendarDatesConflictWithinSourceFeed(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       ConflictPolicy
+		expectedType int
+	}{
+		{"prefer target keeps first row", ConflictPreferTarget, 1},
+		{"prefer source keeps last row", ConflictPreferSource, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: one source feed both adds and removes the same date
+			source := gtfs.NewFeed()
+			source.AddCalendarDate(&gtfs.CalendarDate{ServiceID: "WKDY", Date: "20240704", ExceptionType: 1})
+			source.AddCalendarDate(&gtfs.CalendarDate{ServiceID: "WKDY", Date: "20240704", ExceptionType: 2})
+
+			target := gtfs.NewFeed()
+			ctx := NewMergeContext(source, target, "")
+
+			strategy := NewCalendarDateMergeStrategy()
+			strategy.SetConflictPolicy(tt.policy)
+
+			// When: merging
+			if err := strategy.Merge(ctx); err != nil {
+				t.Fatalf("Merge failed: %v", err)
+			}
+
+			// Then: the output has a single row for the date, per the policy
+			dates := target.CalendarDates["WKDY"]
+			if len(dates) != 1 {
+				t.Fatalf("Expected 1 calendar date, got %d", len(dates))
+			}
+			if dates[0].ExceptionType != tt.expectedType {
+				t.Errorf("Expected exception_type %d, got %d", tt.expectedType, dates[0].ExceptionType)
+			}
+		})
+	}
+}
+
+func TestCalendarDatesConflictErrorOnLogError(t *testing.T) {
+	// Given: conflicting exception types and error logging
+	source := gtfs.NewFeed()
+	source.AddCalendarDate(&gtfs.CalendarDate{ServiceID: "WKDY", Date: "20240704", ExceptionType: 2})
+
+	target := gtfs.NewFeed()
+	target.AddCalendarDate(&gtfs.CalendarDate{ServiceID: "WKDY", Date: "20240704", ExceptionType: 1})
+
+	ctx := NewMergeContext(source, target, "a-")
+	ctx.ServiceIDMapping["WKDY"] = "WKDY"
+
+	strategy := NewCalendarDateMergeStrategy()
+	strategy.SetDuplicateLogging(LogError)
+
+	// When/Then: merging returns an error
+	if err := strategy.Merge(ctx); err == nil {
+		t.Fatal("Expected error for conflicting calendar_date with LogError")
+	}
+}
+
 // Fuzzy detection tests for Milestone 10
 
 func TestCalendarMergeFuzzyByDateOverlap(t *testing.T) {
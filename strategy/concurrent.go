@@ -1,6 +1,8 @@
 package strategy
 
 import (
+	"cmp"
+	"context"
 	"runtime"
 	"sync"
 )
@@ -30,20 +32,32 @@ type scoredResult[ID comparable] struct {
 	Score float64
 }
 
+// betterMatch reports whether a candidate with the given score and ID beats
+// the best match so far. Higher scores win and equal scores go to the lower
+// ID, so the result never depends on the order candidates are visited in.
+// A score of 0 never matches.
+func betterMatch[ID cmp.Ordered](score float64, id ID, bestScore float64, bestID ID) bool {
+	return score > bestScore || (score == bestScore && score > 0 && id < bestID)
+}
+
 // findBestMatchConcurrent finds the best scoring match from a collection using concurrent processing.
-// It takes a slice of candidates and a scoring function, and returns the ID of the best match
-// (above threshold) or the zero value if no match is found.
-func findBestMatchConcurrent[T any, ID comparable](
+// It takes a slice of candidates and a scoring function, and returns the ID and score of the
+// best match (above threshold) or the zero value and 0 if no match is found.
+// Ties go to the lowest ID (see betterMatch), as in findBestMatchSequential.
+// Workers stop scoring once ctx is canceled; callers should check ctx.Err()
+// afterwards, since the result of a canceled search is incomplete.
+func findBestMatchConcurrent[T any, ID cmp.Ordered](
+	ctx context.Context,
 	candidates []T,
 	getID func(T) ID,
 	score func(T) float64,
 	threshold float64,
 	config ConcurrentConfig,
-) ID {
+) (ID, float64) {
 	var zeroID ID
 
 	if len(candidates) == 0 {
-		return zeroID
+		return zeroID, 0
 	}
 
 	// Fall back to sequential if concurrent is disabled or not enough items
@@ -72,6 +86,9 @@ func findBestMatchConcurrent[T any, ID comparable](
 		go func() {
 			defer wg.Done()
 			for candidate := range jobs {
+				if ctx.Err() != nil {
+					continue // Drain remaining jobs without scoring
+				}
 				s := score(candidate)
 				if s >= threshold {
 					results <- scoredResult[ID]{
@@ -101,38 +118,42 @@ func findBestMatchConcurrent[T any, ID comparable](
 	var bestID ID
 	var bestScore float64
 	for result := range results {
-		if result.Score > bestScore {
+		if betterMatch(result.Score, result.ID, bestScore, bestID) {
 			bestScore = result.Score
 			bestID = result.ID
 		}
 	}
 
-	return bestID
+	return bestID, bestScore
 }
 
-// findBestMatchSequential finds the best scoring match sequentially
-func findBestMatchSequential[T any, ID comparable](
+// findBestMatchSequential finds the best scoring match sequentially. Ties go
+// to the lowest ID (see betterMatch).
+func findBestMatchSequential[T any, ID cmp.Ordered](
 	candidates []T,
 	getID func(T) ID,
 	score func(T) float64,
 	threshold float64,
-) ID {
+) (ID, float64) {
 	var zeroID ID
 	var bestID ID
 	var bestScore float64
 
 	for _, candidate := range candidates {
 		s := score(candidate)
-		if s >= threshold && s > bestScore {
+		if s < threshold {
+			continue
+		}
+		if id := getID(candidate); betterMatch(s, id, bestScore, bestID) {
 			bestScore = s
-			bestID = getID(candidate)
+			bestID = id
 		}
 	}
 
 	if bestScore >= threshold {
-		return bestID
+		return bestID, bestScore
 	}
-	return zeroID
+	return zeroID, 0
 }
 
 // ConcurrentScorer provides concurrent scoring capabilities to strategies
@@ -1,6 +1,8 @@
 package strategy
 
 import (
+	"context"
+	"fmt"
 	"runtime"
 	"sync"
 	"testing"
@@ -66,25 +68,25 @@ func TestFindBestMatchSequential(t *testing.T) {
 	score := func(c testCandidate) float64 { return float64(c.value) / 100.0 }
 
 	// Test finding best match above threshold
-	result := findBestMatchSequential(candidates, getID, score, 0.5)
-	if result != "d" {
-		t.Errorf("Expected best match 'd' (score 0.9), got '%s'", result)
+	result, best := findBestMatchSequential(candidates, getID, score, 0.5)
+	if result != "d" || best != 0.9 {
+		t.Errorf("Expected best match 'd' (score 0.9), got '%s' (score %g)", result, best)
 	}
 
 	// Test with higher threshold
-	result = findBestMatchSequential(candidates, getID, score, 0.85)
+	result, _ = findBestMatchSequential(candidates, getID, score, 0.85)
 	if result != "d" {
 		t.Errorf("Expected best match 'd' (score 0.9), got '%s'", result)
 	}
 
 	// Test with threshold too high
-	result = findBestMatchSequential(candidates, getID, score, 0.95)
+	result, _ = findBestMatchSequential(candidates, getID, score, 0.95)
 	if result != "" {
 		t.Errorf("Expected no match with threshold 0.95, got '%s'", result)
 	}
 
 	// Test with empty candidates
-	result = findBestMatchSequential([]testCandidate{}, getID, score, 0.5)
+	result, _ = findBestMatchSequential([]testCandidate{}, getID, score, 0.5)
 	if result != "" {
 		t.Errorf("Expected empty result for empty candidates, got '%s'", result)
 	}
@@ -103,7 +105,7 @@ func TestFindBestMatchConcurrent_Disabled(t *testing.T) {
 	config := DefaultConcurrentConfig()
 	config.Enabled = false
 
-	result := findBestMatchConcurrent(candidates, getID, score, 0.5, config)
+	result, _ := findBestMatchConcurrent(context.Background(), candidates, getID, score, 0.5, config)
 	// The last candidate has value 199, so score = 0.995
 	// ID will be candidates[199].id
 	if result == "" {
@@ -128,7 +130,7 @@ func TestFindBestMatchConcurrent_Enabled(t *testing.T) {
 		MinItemsForConcurrency: 50,
 	}
 
-	result := findBestMatchConcurrent(candidates, getID, score, 0.5, config)
+	result, _ := findBestMatchConcurrent(context.Background(), candidates, getID, score, 0.5, config)
 	expectedID := candidates[150].id
 	if result != expectedID {
 		t.Errorf("Expected best match '%s', got '%s'", expectedID, result)
@@ -151,7 +153,7 @@ func TestFindBestMatchConcurrent_BelowMinItems(t *testing.T) {
 		MinItemsForConcurrency: 100,
 	}
 
-	result := findBestMatchConcurrent(candidates, getID, score, 0.5, config)
+	result, _ := findBestMatchConcurrent(context.Background(), candidates, getID, score, 0.5, config)
 	// Last candidate has score 1.0
 	if result != "j" {
 		t.Errorf("Expected best match 'j', got '%s'", result)
@@ -175,7 +177,7 @@ func TestConcurrentScoringCorrectness(t *testing.T) {
 	threshold := 0.5
 
 	// Sequential result
-	sequentialResult := findBestMatchSequential(candidates, getID, score, threshold)
+	sequentialResult, _ := findBestMatchSequential(candidates, getID, score, threshold)
 
 	// Concurrent result
 	config := ConcurrentConfig{
@@ -183,7 +185,7 @@ func TestConcurrentScoringCorrectness(t *testing.T) {
 		NumWorkers:             4,
 		MinItemsForConcurrency: 10,
 	}
-	concurrentResult := findBestMatchConcurrent(candidates, getID, score, threshold, config)
+	concurrentResult, _ := findBestMatchConcurrent(context.Background(), candidates, getID, score, threshold, config)
 
 	if sequentialResult != concurrentResult {
 		t.Errorf("Sequential result '%s' does not match concurrent result '%s'", sequentialResult, concurrentResult)
@@ -219,7 +221,7 @@ func TestConcurrentScoringPerformance(t *testing.T) {
 		wg.Add(1)
 		go func(idx int) {
 			defer wg.Done()
-			results[idx] = findBestMatchConcurrent(candidates, getID, score, threshold, config)
+			results[idx], _ = findBestMatchConcurrent(context.Background(), candidates, getID, score, threshold, config)
 		}(i)
 	}
 	wg.Wait()
@@ -243,7 +245,7 @@ func TestFindBestMatchConcurrent_EmptyCandidates(t *testing.T) {
 		MinItemsForConcurrency: 10,
 	}
 
-	result := findBestMatchConcurrent([]testCandidate{}, getID, score, 0.5, config)
+	result, _ := findBestMatchConcurrent(context.Background(), []testCandidate{}, getID, score, 0.5, config)
 	if result != "" {
 		t.Errorf("Expected empty result for empty candidates, got '%s'", result)
 	}
@@ -265,8 +267,60 @@ func TestFindBestMatchConcurrent_NoBestMatch(t *testing.T) {
 		MinItemsForConcurrency: 1,
 	}
 
-	result := findBestMatchConcurrent(candidates, getID, score, 0.5, config)
+	result, _ := findBestMatchConcurrent(context.Background(), candidates, getID, score, 0.5, config)
 	if result != "" {
 		t.Errorf("Expected no match (all below threshold), got '%s'", result)
 	}
 }
+
+func TestFindBestMatchConcurrent_Canceled(t *testing.T) {
+	candidates := make([]testCandidate, 200)
+	for i := 0; i < 200; i++ {
+		candidates[i] = testCandidate{id: string(rune('a' + i%26)), value: i}
+	}
+
+	getID := func(c testCandidate) string { return c.id }
+	var scored int
+	var mu sync.Mutex
+	score := func(c testCandidate) float64 {
+		mu.Lock()
+		scored++
+		mu.Unlock()
+		return 1.0
+	}
+
+	config := DefaultConcurrentConfig()
+	config.Enabled = true
+	config.MinItemsForConcurrency = 10
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Workers skip scoring once the context is canceled
+	result, _ := findBestMatchConcurrent(ctx, candidates, getID, score, 0.5, config)
+	if result != "" || scored != 0 {
+		t.Errorf("Expected no scoring after cancel, got result %q after %d scores", result, scored)
+	}
+}
+
+func TestFindBestMatchTiesGoToLowestID(t *testing.T) {
+	// Given: candidates with equal top scores, the lowest ID not first
+	candidates := make([]testCandidate, 0, 200)
+	for i := 199; i >= 0; i-- {
+		candidates = append(candidates, testCandidate{id: fmt.Sprintf("c%03d", i), value: 90})
+	}
+	getID := func(c testCandidate) string { return c.id }
+	score := func(c testCandidate) float64 { return float64(c.value) / 100 }
+
+	// Then: sequential and concurrent matching both pick the lowest ID,
+	// every time
+	if result, _ := findBestMatchSequential(candidates, getID, score, 0.5); result != "c000" {
+		t.Errorf("Expected sequential match c000, got %s", result)
+	}
+	config := ConcurrentConfig{Enabled: true, NumWorkers: 8, MinItemsForConcurrency: 10}
+	for run := 0; run < 20; run++ {
+		if result, _ := findBestMatchConcurrent(context.Background(), candidates, getID, score, 0.5, config); result != "c000" {
+			t.Fatalf("Run %d: expected concurrent match c000, got %s", run, result)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package strategy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// FeedEdition identifies an edition of a feed by its feed_info.txt, for
+// deciding which of two feeds is newer whatever order they are merged in
+type FeedEdition struct {
+	// StartDate is the latest feed_start_date (YYYYMMDD) of the feed's
+	// feed_info rows
+	StartDate string
+
+	// Version is the greatest feed_version of the feed's feed_info rows
+	// (see compareVersions)
+	Version string
+}
+
+// NewFeedEdition returns the edition of feed, which is zero if it has no
+// feed_info rows
+func NewFeedEdition(feed *gtfs.Feed) FeedEdition {
+	var e FeedEdition
+	for _, fi := range feed.FeedInfos {
+		if date := strings.TrimSpace(fi.StartDate); date > e.StartDate {
+			e.StartDate = date
+		}
+		if version := strings.TrimSpace(fi.Version); compareVersions(version, e.Version) > 0 {
+			e.Version = version
+		}
+	}
+	return e
+}
+
+// Compare returns +1 if e is newer than other, -1 if it is older, and 0 if
+// neither can be told newer. Editions are compared by feed_start_date when
+// both have one and the dates differ, and otherwise by feed_version when
+// both have one.
+func (e FeedEdition) Compare(other FeedEdition) int {
+	if e.StartDate != "" && other.StartDate != "" && e.StartDate != other.StartDate {
+		return strings.Compare(e.StartDate, other.StartDate)
+	}
+	if e.Version != "" && other.Version != "" {
+		return compareVersions(e.Version, other.Version)
+	}
+	return 0
+}
+
+// String describes the edition for logs, e.g. "20240301 (v2.1)"
+func (e FeedEdition) String() string {
+	switch {
+	case e.StartDate != "" && e.Version != "":
+		return fmt.Sprintf("%s (%s)", e.StartDate, e.Version)
+	case e.StartDate != "":
+		return e.StartDate
+	case e.Version != "":
+		return e.Version
+	default:
+		return "unknown edition"
+	}
+}
+
+// compareVersions compares two feed_version values, comparing runs of
+// digits by their numeric value so that "2024.10" sorts after "2024.9",
+// and other characters as text
+func compareVersions(a, b string) int {
+	for a != "" && b != "" {
+		runA, restA := versionRun(a)
+		runB, restB := versionRun(b)
+		numA, errA := strconv.ParseUint(runA, 10, 64)
+		numB, errB := strconv.ParseUint(runB, 10, 64)
+		switch {
+		case errA == nil && errB == nil && numA != numB:
+			if numA < numB {
+				return -1
+			}
+			return 1
+		case errA != nil || errB != nil:
+			if c := strings.Compare(runA, runB); c != 0 {
+				return c
+			}
+		}
+		a, b = restA, restB
+	}
+	return strings.Compare(a, b)
+}
+
+// versionRun splits s after its leading run of digits, or of other
+// characters
+func versionRun(s string) (string, string) {
+	digit := unicode.IsDigit(rune(s[0]))
+	for i, r := range s {
+		if unicode.IsDigit(r) != digit {
+			return s[:i], s[i:]
+		}
+	}
+	return s, ""
+}
+
+// AgencyFieldChange describes a field of a merged agency replaced with a
+// newer edition's value (see AgencyMergeStrategy.NewestEditionWins)
+type AgencyFieldChange struct {
+	AgencyID gtfs.AgencyID
+
+	// Field is the agency.txt column, e.g. agency_phone
+	Field string
+
+	// Old and New are the field's values before and after
+	Old, New string
+
+	// SourceFeed names the feed the new value came from, and Edition its
+	// edition
+	SourceFeed string
+	Edition    FeedEdition
+}
+
+// String describes the change for logs and warnings
+func (c AgencyFieldChange) String() string {
+	return fmt.Sprintf("agency %q: %s changed from %q to %q by feed %s, edition %s",
+		c.AgencyID, c.Field, c.Old, c.New, c.SourceFeed, c.Edition)
+}
@@ -0,0 +1,63 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+func TestFeedEditionCompare(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b FeedEdition
+		want int
+	}{
+		{"later start date", FeedEdition{StartDate: "20240301"}, FeedEdition{StartDate: "20240101"}, 1},
+		{"dates before versions", FeedEdition{StartDate: "20240101", Version: "9"}, FeedEdition{StartDate: "20240301", Version: "1"}, -1},
+		{"versions when dates are equal", FeedEdition{StartDate: "20240101", Version: "2"}, FeedEdition{StartDate: "20240101", Version: "1"}, 1},
+		{"versions when a date is absent", FeedEdition{Version: "2024.10"}, FeedEdition{StartDate: "20240101", Version: "2024.9"}, 1},
+		{"nothing to compare", FeedEdition{StartDate: "20240101"}, FeedEdition{Version: "1"}, 0},
+		{"unknown", FeedEdition{}, FeedEdition{}, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Compare(tt.b); got != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, got)
+		}
+		if got := tt.b.Compare(tt.a); got != -tt.want {
+			t.Errorf("%s reversed: expected %d, got %d", tt.name, -tt.want, got)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"2024.10", "2024.9", 1},
+		{"v2", "v10", -1},
+		{"1.0", "1.0", 0},
+		{"1.0", "1.0.1", -1},
+		{"1.0-beta", "1.0-alpha", 1},
+		{"007", "7", 0},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q): expected %d, got %d", tt.a, tt.b, tt.want, got)
+		}
+	}
+}
+
+func TestNewFeedEdition(t *testing.T) {
+	feed := gtfs.NewFeed()
+	feed.AddFeedInfo(&gtfs.FeedInfo{FeedID: "a", StartDate: "20240101", Version: "v9"})
+	feed.AddFeedInfo(&gtfs.FeedInfo{FeedID: "b", StartDate: "20240301", Version: "v10"})
+
+	want := FeedEdition{StartDate: "20240301", Version: "v10"}
+	if got := NewFeedEdition(feed); got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+	if got := NewFeedEdition(gtfs.NewFeed()); got != (FeedEdition{}) {
+		t.Errorf("Expected no edition without feed_info, got %+v", got)
+	}
+}
@@ -4,6 +4,8 @@ package strategy
 import (
 	"fmt"
 	"strings"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/internal/enumtext"
 )
 
 // DuplicateDetection specifies how duplicates are detected
@@ -48,6 +50,16 @@ func ParseDuplicateDetection(s string) (DuplicateDetection, error) {
 	}
 }
 
+// MarshalText implements encoding.TextMarshaler
+func (d DuplicateDetection) MarshalText() ([]byte, error) {
+	return enumtext.Marshal(d, ParseDuplicateDetection)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (d *DuplicateDetection) UnmarshalText(text []byte) error {
+	return enumtext.Unmarshal(d, text, ParseDuplicateDetection)
+}
+
 // DuplicateLogging specifies how to handle detected duplicates
 type DuplicateLogging int
 
@@ -76,6 +88,30 @@ func (l DuplicateLogging) String() string {
 	}
 }
 
+// ParseDuplicateLogging parses a string into a DuplicateLogging value
+func ParseDuplicateLogging(s string) (DuplicateLogging, error) {
+	switch strings.ToLower(s) {
+	case "none":
+		return LogNone, nil
+	case "warning":
+		return LogWarning, nil
+	case "error":
+		return LogError, nil
+	default:
+		return LogNone, fmt.Errorf("invalid duplicate logging mode: %q", s)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (l DuplicateLogging) MarshalText() ([]byte, error) {
+	return enumtext.Marshal(l, ParseDuplicateLogging)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (l *DuplicateLogging) UnmarshalText(text []byte) error {
+	return enumtext.Unmarshal(l, text, ParseDuplicateLogging)
+}
+
 // RenamingStrategy specifies how duplicate IDs are renamed
 type RenamingStrategy int
 
@@ -98,3 +134,220 @@ func (r RenamingStrategy) String() string {
 		return fmt.Sprintf("RenamingStrategy(%d)", r)
 	}
 }
+
+// ParseRenamingStrategy parses a string into a RenamingStrategy value
+func ParseRenamingStrategy(s string) (RenamingStrategy, error) {
+	switch strings.ToLower(s) {
+	case "context":
+		return RenameContext, nil
+	case "agency":
+		return RenameAgency, nil
+	default:
+		return RenameContext, fmt.Errorf("invalid renaming strategy: %q", s)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (r RenamingStrategy) MarshalText() ([]byte, error) {
+	return enumtext.Marshal(r, ParseRenamingStrategy)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (r *RenamingStrategy) UnmarshalText(text []byte) error {
+	return enumtext.Unmarshal(r, text, ParseRenamingStrategy)
+}
+
+// OverlapPolicy specifies what happens to a frequencies.txt window that
+// overlaps an existing window on the same trip with a different headway
+type OverlapPolicy int
+
+const (
+	// OverlapKeep - keep both windows unchanged
+	OverlapKeep OverlapPolicy = iota
+
+	// OverlapSplit - trim the incoming window to the parts the existing
+	// windows don't cover, so the existing headway holds where they overlap
+	OverlapSplit
+)
+
+// String returns the string representation of OverlapPolicy
+func (o OverlapPolicy) String() string {
+	switch o {
+	case OverlapKeep:
+		return "keep"
+	case OverlapSplit:
+		return "split"
+	default:
+		return fmt.Sprintf("OverlapPolicy(%d)", o)
+	}
+}
+
+// ParseOverlapPolicy parses a string into an OverlapPolicy value
+func ParseOverlapPolicy(s string) (OverlapPolicy, error) {
+	switch strings.ToLower(s) {
+	case "keep":
+		return OverlapKeep, nil
+	case "split":
+		return OverlapSplit, nil
+	default:
+		return OverlapKeep, fmt.Errorf("invalid overlap policy: %q", s)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (o OverlapPolicy) MarshalText() ([]byte, error) {
+	return enumtext.Marshal(o, ParseOverlapPolicy)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (o *OverlapPolicy) UnmarshalText(text []byte) error {
+	return enumtext.Unmarshal(o, text, ParseOverlapPolicy)
+}
+
+// ConflictPolicy specifies how conflicting values are resolved when a source
+// entity maps onto an existing target entity but disagrees with it
+type ConflictPolicy int
+
+const (
+	// ConflictPreferTarget - keep the existing target value (first-read wins)
+	ConflictPreferTarget ConflictPolicy = iota
+
+	// ConflictPreferSource - overwrite the target value with the source value
+	ConflictPreferSource
+)
+
+// String returns the string representation of ConflictPolicy
+func (c ConflictPolicy) String() string {
+	switch c {
+	case ConflictPreferTarget:
+		return "prefer_target"
+	case ConflictPreferSource:
+		return "prefer_source"
+	default:
+		return fmt.Sprintf("ConflictPolicy(%d)", c)
+	}
+}
+
+// ParseConflictPolicy parses a string into a ConflictPolicy value
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch strings.ToLower(s) {
+	case "prefer_target", "prefer-target":
+		return ConflictPreferTarget, nil
+	case "prefer_source", "prefer-source":
+		return ConflictPreferSource, nil
+	default:
+		return ConflictPreferTarget, fmt.Errorf("invalid conflict policy: %q", s)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (c ConflictPolicy) MarshalText() ([]byte, error) {
+	return enumtext.Marshal(c, ParseConflictPolicy)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (c *ConflictPolicy) UnmarshalText(text []byte) error {
+	return enumtext.Unmarshal(c, text, ParseConflictPolicy)
+}
+
+// GrayZonePolicy specifies how a fuzzy match whose score falls within the
+// gray zone around the fuzzy threshold is resolved
+type GrayZonePolicy int
+
+const (
+	// GrayZoneNearMiss - treat the pair as distinct and record it as a near miss
+	GrayZoneNearMiss GrayZonePolicy = iota
+
+	// GrayZoneFlag - treat the pair as duplicates and record it for review
+	GrayZoneFlag
+
+	// GrayZoneAbort - fail the merge, listing every ambiguous pair
+	GrayZoneAbort
+)
+
+// String returns the string representation of GrayZonePolicy
+func (g GrayZonePolicy) String() string {
+	switch g {
+	case GrayZoneNearMiss:
+		return "near_miss"
+	case GrayZoneFlag:
+		return "flag"
+	case GrayZoneAbort:
+		return "abort"
+	default:
+		return fmt.Sprintf("GrayZonePolicy(%d)", g)
+	}
+}
+
+// ParseGrayZonePolicy parses a string into a GrayZonePolicy value
+func ParseGrayZonePolicy(s string) (GrayZonePolicy, error) {
+	switch strings.ToLower(s) {
+	case "near_miss", "near-miss":
+		return GrayZoneNearMiss, nil
+	case "flag":
+		return GrayZoneFlag, nil
+	case "abort":
+		return GrayZoneAbort, nil
+	default:
+		return GrayZoneNearMiss, fmt.Errorf("invalid gray zone policy: %q", s)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (g GrayZonePolicy) MarshalText() ([]byte, error) {
+	return enumtext.Marshal(g, ParseGrayZonePolicy)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (g *GrayZonePolicy) UnmarshalText(text []byte) error {
+	return enumtext.Unmarshal(g, text, ParseGrayZonePolicy)
+}
+
+// RouteStopScoring specifies how fuzzy route matching scores the stops two
+// routes serve
+type RouteStopScoring int
+
+const (
+	// RouteStopsOverlap - score the share of each route's stops the other
+	// serves, every stop counting equally
+	RouteStopsOverlap RouteStopScoring = iota
+
+	// RouteStopsTerminals - as RouteStopsOverlap, but only if the routes
+	// share the stops at both ends of a trip, so branches sharing a trunk
+	// are kept apart
+	RouteStopsTerminals
+)
+
+// String returns the string representation of RouteStopScoring
+func (r RouteStopScoring) String() string {
+	switch r {
+	case RouteStopsOverlap:
+		return "overlap"
+	case RouteStopsTerminals:
+		return "terminals"
+	default:
+		return fmt.Sprintf("RouteStopScoring(%d)", r)
+	}
+}
+
+// ParseRouteStopScoring parses a string into a RouteStopScoring value
+func ParseRouteStopScoring(s string) (RouteStopScoring, error) {
+	switch strings.ToLower(s) {
+	case "overlap":
+		return RouteStopsOverlap, nil
+	case "terminals":
+		return RouteStopsTerminals, nil
+	default:
+		return RouteStopsOverlap, fmt.Errorf("invalid route stop scoring: %q", s)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (r RouteStopScoring) MarshalText() ([]byte, error) {
+	return enumtext.Marshal(r, ParseRouteStopScoring)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (r *RouteStopScoring) UnmarshalText(text []byte) error {
+	return enumtext.Unmarshal(r, text, ParseRouteStopScoring)
+}
@@ -1,6 +1,11 @@
 package strategy
 
-import "testing"
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
 
 func TestDuplicateDetectionValues(t *testing.T) {
 	// Verify enum has expected values
@@ -105,6 +110,67 @@ func TestRenamingStrategyString(t *testing.T) {
 	}
 }
 
+func TestConflictPolicyString(t *testing.T) {
+	tests := []struct {
+		value    ConflictPolicy
+		expected string
+	}{
+		{ConflictPreferTarget, "prefer_target"},
+		{ConflictPreferSource, "prefer_source"},
+		{ConflictPolicy(99), "ConflictPolicy(99)"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.value.String(); got != tt.expected {
+			t.Errorf("ConflictPolicy.String() = %q, want %q", got, tt.expected)
+		}
+	}
+}
+
+func TestOverlapPolicyString(t *testing.T) {
+	tests := []struct {
+		value    OverlapPolicy
+		expected string
+	}{
+		{OverlapKeep, "keep"},
+		{OverlapSplit, "split"},
+		{OverlapPolicy(99), "OverlapPolicy(99)"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.value.String(); got != tt.expected {
+			t.Errorf("OverlapPolicy.String() = %q, want %q", got, tt.expected)
+		}
+	}
+}
+
+func TestRouteStopScoringString(t *testing.T) {
+	tests := []struct {
+		value    RouteStopScoring
+		expected string
+	}{
+		{RouteStopsOverlap, "overlap"},
+		{RouteStopsTerminals, "terminals"},
+		{RouteStopScoring(99), "RouteStopScoring(99)"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.value.String(); got != tt.expected {
+			t.Errorf("RouteStopScoring.String() = %q, want %q", got, tt.expected)
+		}
+		if tt.value > RouteStopsTerminals {
+			continue
+		}
+		if got, err := ParseRouteStopScoring(tt.expected); err != nil || got != tt.value {
+			t.Errorf("ParseRouteStopScoring(%q) = %v, %v, want %v", tt.expected, got, err, tt.value)
+		}
+	}
+
+	if _, err := ParseRouteStopScoring("weighted"); err == nil {
+		t.Error("ParseRouteStopScoring(\"weighted\") expected error, got nil")
+	}
+}
+
 func TestParseDuplicateDetection(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -136,3 +202,94 @@ func TestParseDuplicateDetection(t *testing.T) {
 		}
 	}
 }
+
+func TestParseEnums(t *testing.T) {
+	tests := []struct {
+		input string
+		parse func(string) (fmt.Stringer, error)
+		want  fmt.Stringer
+	}{
+		{"Warning", func(s string) (fmt.Stringer, error) { return ParseDuplicateLogging(s) }, LogWarning},
+		{"agency", func(s string) (fmt.Stringer, error) { return ParseRenamingStrategy(s) }, RenameAgency},
+		{"SPLIT", func(s string) (fmt.Stringer, error) { return ParseOverlapPolicy(s) }, OverlapSplit},
+		{"prefer-source", func(s string) (fmt.Stringer, error) { return ParseConflictPolicy(s) }, ConflictPreferSource},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.parse(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("parsing %q = %v, %v, want %v", tt.input, got, err, tt.want)
+		}
+		if _, err := tt.parse("bogus"); err == nil {
+			t.Errorf("parsing %q as %T expected error, got nil", "bogus", tt.want)
+		}
+	}
+}
+
+func TestEnumsText(t *testing.T) {
+	testEnumText(t, []DuplicateDetection{DetectionNone, DetectionIdentity, DetectionFuzzy})
+	testEnumText(t, []DuplicateLogging{LogNone, LogWarning, LogError})
+	testEnumText(t, []RenamingStrategy{RenameContext, RenameAgency})
+	testEnumText(t, []OverlapPolicy{OverlapKeep, OverlapSplit})
+	testEnumText(t, []ConflictPolicy{ConflictPreferTarget, ConflictPreferSource})
+	testEnumText(t, []GrayZonePolicy{GrayZoneNearMiss, GrayZoneFlag, GrayZoneAbort})
+	testEnumText(t, []RouteStopScoring{RouteStopsOverlap, RouteStopsTerminals})
+}
+
+func TestEnumsJSON(t *testing.T) {
+	type config struct {
+		Detection DuplicateDetection `json:"detection"`
+		Logging   DuplicateLogging   `json:"logging"`
+	}
+
+	// Given: a config holding enums
+	data, err := json.Marshal(config{Detection: DetectionFuzzy, Logging: LogWarning})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	// Then: they are written by name and read back
+	if string(data) != `{"detection":"fuzzy","logging":"warning"}` {
+		t.Errorf("unexpected JSON: %s", data)
+	}
+	var got config
+	if err := json.Unmarshal(data, &got); err != nil || got.Detection != DetectionFuzzy || got.Logging != LogWarning {
+		t.Errorf("Unmarshal = %+v, %v", got, err)
+	}
+
+	// And: unknown names are rejected
+	if err := json.Unmarshal([]byte(`{"detection":"exact"}`), &got); err == nil {
+		t.Error("expected an error for an unknown detection mode")
+	}
+}
+
+// testEnumText checks that each of values marshals to its String and
+// unmarshals back to itself, and that unknown values and names are rejected
+func testEnumText[T interface {
+	~int
+	fmt.Stringer
+	encoding.TextMarshaler
+}, PT interface {
+	*T
+	encoding.TextUnmarshaler
+}](t *testing.T, values []T) {
+	t.Helper()
+	for _, v := range values {
+		text, err := v.MarshalText()
+		if err != nil || string(text) != v.String() {
+			t.Errorf("%T(%d).MarshalText() = %q, %v, want %q", v, v, text, err, v.String())
+		}
+		var got T
+		if err := PT(&got).UnmarshalText(text); err != nil || got != v {
+			t.Errorf("%T.UnmarshalText(%q) = %v, %v, want %v", v, text, got, err, v)
+		}
+	}
+
+	if text, err := T(99).MarshalText(); err == nil {
+		t.Errorf("%T(99).MarshalText() = %q, want error", T(99), text)
+	}
+	var got T
+	if err := PT(&got).UnmarshalText([]byte("bogus")); err == nil {
+		t.Errorf("%T.UnmarshalText(%q) = %v, want error", got, "bogus", got)
+	}
+}
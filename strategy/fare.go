@@ -21,14 +21,20 @@ func NewFareAttributeMergeStrategy() *FareAttributeMergeStrategy {
 
 // Merge performs the merge operation for fare attributes
 func (s *FareAttributeMergeStrategy) Merge(ctx *MergeContext) error {
+	// Fares added from this source feed are not fuzzy-match candidates
+	justAdded := make(map[gtfs.FareID]struct{})
+
 	// Iterate in insertion order to match Java output
-	for _, fareID := range ctx.Source.FareAttrOrder {
+	for i, fareID := range ctx.Source.FareAttrOrder {
+		if err := ctx.checkCanceled(i); err != nil {
+			return err
+		}
 		fare := ctx.Source.FareAttributes[fareID]
 		// Check for duplicates based on detection mode
 		if s.DuplicateDetection == DetectionIdentity {
-			if existing, found := ctx.Target.FareAttributes[fare.FareID]; found {
+			if existingID, found := identityMatch(ctx, gtfs.KindFare, fare.FareID, ctx.Target.FareAttributes); found && !ctx.blocked(gtfs.KindFare, string(fare.FareID), string(existingID)) {
 				// Duplicate detected - map source ID to existing target ID
-				ctx.FareIDMapping[fare.FareID] = existing.FareID
+				ctx.RegisterFareID(fare.FareID, existingID)
 
 				// Handle logging based on configuration
 				switch s.DuplicateLogging {
@@ -39,38 +45,107 @@ func (s *FareAttributeMergeStrategy) Merge(ctx *MergeContext) error {
 				}
 
 				// Skip adding this fare - use the existing one
+				ctx.countDeduplicated("fare_attributes.txt", 1)
+				continue
+			}
+		}
+
+		// Check for fuzzy duplicates
+		if s.DuplicateDetection == DetectionFuzzy {
+			if matchID, found := findFareAttributeMatch(ctx, fare, justAdded); found && !ctx.blocked(gtfs.KindFare, string(fare.FareID), string(matchID)) {
+				// Fuzzy duplicate detected - map source ID to existing target ID
+				ctx.RegisterFareID(fare.FareID, matchID)
+
+				switch s.DuplicateLogging {
+				case LogWarning:
+					log.Printf("WARNING: Fuzzy duplicate fare_attribute detected: %q matches %q (keeping existing)", fare.FareID, matchID)
+				case LogError:
+					return fmt.Errorf("fuzzy duplicate fare_attribute detected: %q matches %q", fare.FareID, matchID)
+				}
+
+				// Skip adding this fare - use the existing one
+				ctx.countDeduplicated("fare_attributes.txt", 1)
 				continue
 			}
 		}
 
 		// Determine new ID - only apply prefix if there's a collision
-		newID := fare.FareID
-		if _, exists := ctx.Target.FareAttributes[fare.FareID]; exists {
-			// Collision detected - apply prefix
-			newID = gtfs.FareID(ctx.Prefix + string(fare.FareID))
+		newID := UniqueID(ctx, ctx.Target.FareAttributes, fare.FareID)
+		ctx.RegisterFareID(fare.FareID, newID)
+
+		// Map agency reference, as for routes: the agency may have been
+		// prefixed or merged into another feed's. An empty agency_id is
+		// mapped too, to the ID its feed's only agency was given (see
+		// sourceAgencyID).
+		agencyID := ctx.sourceAgencyID(fare.AgencyID)
+		if mappedAgency, ok := ctx.MapAgencyID(agencyID); ok {
+			agencyID = mappedAgency
+			if fare.AgencyID == "" {
+				ctx.Target.AddColumn("fare_attributes.txt", "agency_id")
+			}
 		}
-		ctx.FareIDMapping[fare.FareID] = newID
 
-		// Note: agency_id is NOT remapped - Java doesn't remap this field,
-		// it only renames entity IDs (AgencyAndId primary keys)
 		newFare := &gtfs.FareAttribute{
 			FareID:           newID,
 			Price:            fare.Price,
 			CurrencyType:     fare.CurrencyType,
 			PaymentMethod:    fare.PaymentMethod,
 			Transfers:        fare.Transfers,
-			AgencyID:         fare.AgencyID, // Keep original, don't remap
+			AgencyID:         agencyID,
 			TransferDuration: fare.TransferDuration,
 			YouthPrice:       fare.YouthPrice,
 			SeniorPrice:      fare.SeniorPrice,
 		}
 		ctx.Target.FareAttributes[newID] = newFare
 		ctx.Target.FareAttrOrder = append(ctx.Target.FareAttrOrder, newID)
+		justAdded[newID] = struct{}{}
 	}
 
 	return nil
 }
 
+// findFareAttributeMatch returns the existing fare with the lowest fare_id
+// that is the same fare product as source: equal price, currency_type,
+// payment_method, transfers, transfer_duration, youth and senior prices, and
+// agency_id once the source agency is mapped into the target. Taking the
+// lowest ID keeps the match from depending on the order of rows in
+// fare_attributes.txt.
+func findFareAttributeMatch(ctx *MergeContext, source *gtfs.FareAttribute, justAdded map[gtfs.FareID]struct{}) (gtfs.FareID, bool) {
+	agencyID, _ := ctx.MapAgencyID(ctx.sourceAgencyID(source.AgencyID))
+
+	var match gtfs.FareID
+	var found bool
+	for id, target := range ctx.Target.FareAttributes {
+		if found && id >= match {
+			continue
+		}
+		if _, skip := justAdded[id]; skip && !ctx.IntraFeed {
+			continue
+		}
+
+		if source.Price == target.Price &&
+			source.CurrencyType == target.CurrencyType &&
+			source.PaymentMethod == target.PaymentMethod &&
+			equalPtr(source.Transfers, target.Transfers) &&
+			equalPtr(source.TransferDuration, target.TransferDuration) &&
+			equalPtr(source.YouthPrice, target.YouthPrice) &&
+			equalPtr(source.SeniorPrice, target.SeniorPrice) &&
+			agencyID == target.AgencyID {
+			match, found = id, true
+		}
+	}
+
+	return match, found
+}
+
+// equalPtr reports whether a and b are both unset, or both set to equal values
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
 // FareRuleMergeStrategy handles merging of fare rules between feeds
 type FareRuleMergeStrategy struct {
 	BaseStrategy
@@ -106,19 +181,14 @@ func (s *FareRuleMergeStrategy) Merge(ctx *MergeContext) error {
 		}
 	}
 
-	for _, rule := range ctx.Source.FareRules {
-		// Map references
-		fareID := rule.FareID
-		if mappedFare, ok := ctx.FareIDMapping[fareID]; ok {
-			fareID = mappedFare
+	for i, rule := range ctx.Source.FareRules {
+		if err := ctx.checkCanceled(i); err != nil {
+			return err
 		}
+		// Map references
+		fareID, _ := ctx.MapFareID(rule.FareID)
 
-		routeID := rule.RouteID
-		if routeID != "" {
-			if mappedRoute, ok := ctx.RouteIDMapping[routeID]; ok {
-				routeID = mappedRoute
-			}
-		}
+		routeID, _ := ctx.MapRouteID(rule.RouteID)
 
 		// Check for duplicates using O(1) lookup
 		if s.DuplicateDetection == DetectionIdentity {
@@ -130,6 +200,7 @@ func (s *FareRuleMergeStrategy) Merge(ctx *MergeContext) error {
 				rule.ContainsID,
 			}
 			if existingKeys[key] {
+				ctx.countDeduplicated("fare_rules.txt", 1)
 				continue
 			}
 			// Add to index for subsequent source items
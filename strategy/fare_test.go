@@ -144,3 +144,173 @@ func TestFareRuleMergeIdentical(t *testing.T) {
 		t.Errorf("Expected 1 fare rule (duplicate skipped), got %d", len(target.FareRules))
 	}
 }
+
+// adultFare returns the $2.75 USD pay-on-board adult fare used by the fuzzy tests
+func adultFare(id gtfs.FareID) *gtfs.FareAttribute {
+	return &gtfs.FareAttribute{
+		FareID:        id,
+		Price:         2.75,
+		CurrencyType:  "USD",
+		PaymentMethod: 0,
+		Transfers:     intPtr(0),
+		AgencyID:      "metro",
+	}
+}
+
+func TestFareAttributeMergeMapsAgencyID(t *testing.T) {
+	tests := []struct {
+		name   string
+		mapped gtfs.AgencyID
+	}{
+		{"deduped onto the same ID", "metro"},
+		{"prefixed on collision", "a-metro"},
+		{"merged into another agency", "metro_transit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: a source fare owned by an agency already merged as mapped
+			source := gtfs.NewFeed()
+			source.AddFareAttribute(adultFare("adult"))
+			target := gtfs.NewFeed()
+			ctx := NewMergeContext(source, target, "a-")
+			ctx.AgencyIDMapping["metro"] = tt.mapped
+
+			// When: fares are merged
+			if err := NewFareAttributeMergeStrategy().Merge(ctx); err != nil {
+				t.Fatalf("Merge failed: %v", err)
+			}
+
+			// Then: the fare follows its agency
+			if got := target.FareAttributes["adult"].AgencyID; got != tt.mapped {
+				t.Errorf("Expected agency_id %q, got %q", tt.mapped, got)
+			}
+		})
+	}
+}
+
+func TestFareAttributeMergeFuzzyDuplicate(t *testing.T) {
+	tests := []struct {
+		name        string
+		modify      func(fa *gtfs.FareAttribute)
+		expectMatch bool
+	}{
+		{"only fare_id differs", func(fa *gtfs.FareAttribute) {}, true},
+		{"price differs by a cent", func(fa *gtfs.FareAttribute) { fa.Price = 2.76 }, false},
+		{"youth price differs", func(fa *gtfs.FareAttribute) { fa.YouthPrice = floatPtr(1.00) }, false},
+		{"senior price differs", func(fa *gtfs.FareAttribute) { fa.SeniorPrice = floatPtr(1.00) }, false},
+		{"currency differs", func(fa *gtfs.FareAttribute) { fa.CurrencyType = "CAD" }, false},
+		{"transfers differ", func(fa *gtfs.FareAttribute) { fa.Transfers = intPtr(1) }, false},
+		{"agency differs", func(fa *gtfs.FareAttribute) { fa.AgencyID = "other" }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: the target has the adult fare, and the source has a
+			// variant of it under a different fare_id with a fare rule
+			target := gtfs.NewFeed()
+			target.AddFareAttribute(adultFare("adult"))
+			target.FareRules = append(target.FareRules, &gtfs.FareRule{FareID: "adult", RouteID: "r1"})
+
+			source := gtfs.NewFeed()
+			sourceFare := adultFare("ADULT_CASH")
+			tt.modify(sourceFare)
+			source.AddFareAttribute(sourceFare)
+			source.FareRules = append(source.FareRules, &gtfs.FareRule{FareID: "ADULT_CASH", RouteID: "r1"})
+
+			ctx := NewMergeContext(source, target, "a-")
+			ctx.AgencyIDMapping["metro"] = "metro"
+
+			fares := NewFareAttributeMergeStrategy()
+			fares.SetDuplicateDetection(DetectionFuzzy)
+			rules := NewFareRuleMergeStrategy()
+			rules.SetDuplicateDetection(DetectionIdentity)
+
+			// When: fares and fare rules are merged
+			if err := fares.Merge(ctx); err != nil {
+				t.Fatalf("Fare merge failed: %v", err)
+			}
+			if err := rules.Merge(ctx); err != nil {
+				t.Fatalf("Fare rule merge failed: %v", err)
+			}
+
+			if tt.expectMatch {
+				// Then: the source fare maps onto the existing one and its
+				// rule collapses into the existing rule
+				if len(target.FareAttributes) != 1 {
+					t.Errorf("Expected 1 fare, got %d", len(target.FareAttributes))
+				}
+				if got := ctx.FareIDMapping["ADULT_CASH"]; got != "adult" {
+					t.Errorf("Expected ADULT_CASH to map to adult, got %q", got)
+				}
+				if len(target.FareRules) != 1 {
+					t.Errorf("Expected 1 fare rule, got %d", len(target.FareRules))
+				}
+			} else {
+				// Then: both fares are kept
+				if len(target.FareAttributes) != 2 {
+					t.Errorf("Expected 2 fares, got %d", len(target.FareAttributes))
+				}
+				if got := ctx.FareIDMapping["ADULT_CASH"]; got != "ADULT_CASH" {
+					t.Errorf("Expected ADULT_CASH to keep its ID, got %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestFareAttributeMergeFuzzyIgnoresFaresFromSameFeed(t *testing.T) {
+	// Given: a source feed with two identical fares under different IDs
+	source := gtfs.NewFeed()
+	source.AddFareAttribute(adultFare("adult_cash"))
+	source.AddFareAttribute(adultFare("adult_card"))
+
+	target := gtfs.NewFeed()
+	ctx := NewMergeContext(source, target, "")
+
+	s := NewFareAttributeMergeStrategy()
+	s.SetDuplicateDetection(DetectionFuzzy)
+
+	// When: merged into an empty target
+	if err := s.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the feed's own fares are not merged with each other
+	if len(target.FareAttributes) != 2 {
+		t.Errorf("Expected 2 fares, got %d", len(target.FareAttributes))
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestFareAttributeMergeFuzzyTiesGoToLowestID(t *testing.T) {
+	// Given: the target has two identical fares, the higher fare_id first
+	target := gtfs.NewFeed()
+	target.AddFareAttribute(adultFare("zone_b"))
+	target.AddFareAttribute(adultFare("zone_a"))
+
+	source := gtfs.NewFeed()
+	source.AddFareAttribute(adultFare("ADULT_CASH"))
+
+	ctx := NewMergeContext(source, target, "a-")
+	ctx.AgencyIDMapping["metro"] = "metro"
+	fares := NewFareAttributeMergeStrategy()
+	fares.SetDuplicateDetection(DetectionFuzzy)
+
+	// When: merged
+	if err := fares.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the source fare maps to the lowest fare_id, not the first row
+	if got := ctx.FareIDMapping["ADULT_CASH"]; got != "zone_a" {
+		t.Errorf("Expected ADULT_CASH to map to zone_a, got %q", got)
+	}
+}
@@ -0,0 +1,206 @@
+package strategy
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// FareLegRuleMergeStrategy handles merging of Fares v2 fare leg rules
+// between feeds. Leg groups have no file of their own: a leg group ID the
+// target already uses is prefixed like a colliding entity's, unless
+// identity detection is enabled, which merges the two groups. Leg group IDs
+// used only by fare_transfer_rules are mapped the same way, and every
+// mapping is recorded in MergeContext.LegGroupIDMapping. Each row's network,
+// areas, timeframe groups and fare product are remapped, so those must be
+// merged first. A row identical to one already in the target is dropped.
+type FareLegRuleMergeStrategy struct {
+	BaseStrategy
+}
+
+// NewFareLegRuleMergeStrategy creates a new FareLegRuleMergeStrategy
+func NewFareLegRuleMergeStrategy() *FareLegRuleMergeStrategy {
+	return &FareLegRuleMergeStrategy{
+		BaseStrategy: NewBaseStrategy("fare_leg_rule"),
+	}
+}
+
+// Merge performs the merge operation for fare leg rules
+func (s *FareLegRuleMergeStrategy) Merge(ctx *MergeContext) error {
+	used := targetLegGroupIDs(ctx.Target)
+	mapGroup := func(id gtfs.LegGroupID) gtfs.LegGroupID {
+		if mapped, ok := ctx.MapLegGroupID(id); ok || id == "" {
+			return mapped
+		}
+		// Determine new ID - only apply prefix if there's a collision
+		newID := UniqueIDFunc(ctx, id, func(id gtfs.LegGroupID) bool {
+			return used[id] && s.DuplicateDetection != DetectionIdentity
+		})
+		ctx.RegisterLegGroupID(id, newID)
+		used[newID] = true
+		return newID
+	}
+
+	existing := make(map[fareLegRuleKey]bool, len(ctx.Target.FareLegRules))
+	for _, rule := range ctx.Target.FareLegRules {
+		existing[newFareLegRuleKey(rule)] = true
+	}
+
+	for i, rule := range ctx.Source.FareLegRules {
+		if err := ctx.checkCanceled(i); err != nil {
+			return err
+		}
+		newRule := *rule
+		newRule.LegGroupID = mapGroup(rule.LegGroupID)
+		newRule.NetworkID, _ = ctx.MapNetworkID(rule.NetworkID)
+		newRule.FromAreaID, _ = ctx.MapAreaID(rule.FromAreaID)
+		newRule.ToAreaID, _ = ctx.MapAreaID(rule.ToAreaID)
+		newRule.FromTimeframeGroupID, _ = ctx.MapTimeframeGroupID(rule.FromTimeframeGroupID)
+		newRule.ToTimeframeGroupID, _ = ctx.MapTimeframeGroupID(rule.ToTimeframeGroupID)
+		newRule.FareProductID, _ = ctx.MapFareProductID(rule.FareProductID)
+
+		key := newFareLegRuleKey(&newRule)
+		if existing[key] {
+			switch s.DuplicateLogging {
+			case LogWarning:
+				log.Printf("WARNING: Duplicate fare leg rule detected for leg group %q (keeping existing)", newRule.LegGroupID)
+			case LogError:
+				return fmt.Errorf("duplicate fare leg rule detected for leg group %q", newRule.LegGroupID)
+			}
+			ctx.countDeduplicated("fare_leg_rules.txt", 1)
+			continue
+		}
+		existing[key] = true
+		ctx.Target.FareLegRules = append(ctx.Target.FareLegRules, &newRule)
+	}
+
+	// Map the leg group IDs transfer rules use without a fare leg rule
+	for _, rule := range ctx.Source.FareTransferRules {
+		mapGroup(rule.FromLegGroupID)
+		mapGroup(rule.ToLegGroupID)
+	}
+
+	return nil
+}
+
+// targetLegGroupIDs returns the leg group IDs the target feed already uses,
+// in fare_leg_rules.txt or fare_transfer_rules.txt
+func targetLegGroupIDs(target *gtfs.Feed) map[gtfs.LegGroupID]bool {
+	used := make(map[gtfs.LegGroupID]bool)
+	for _, rule := range target.FareLegRules {
+		used[rule.LegGroupID] = true
+	}
+	for _, rule := range target.FareTransferRules {
+		used[rule.FromLegGroupID] = true
+		used[rule.ToLegGroupID] = true
+	}
+	delete(used, "")
+	return used
+}
+
+// fareLegRuleKey is a fare leg rule's fields, for O(1) duplicate detection
+type fareLegRuleKey struct {
+	legGroupID           gtfs.LegGroupID
+	networkID            gtfs.NetworkID
+	fromAreaID, toAreaID gtfs.AreaID
+	fromTimeframeGroupID gtfs.TimeframeGroupID
+	toTimeframeGroupID   gtfs.TimeframeGroupID
+	fareProductID        gtfs.FareProductID
+	rulePriority         string
+}
+
+func newFareLegRuleKey(rule *gtfs.FareLegRule) fareLegRuleKey {
+	return fareLegRuleKey{
+		legGroupID:           rule.LegGroupID,
+		networkID:            rule.NetworkID,
+		fromAreaID:           rule.FromAreaID,
+		toAreaID:             rule.ToAreaID,
+		fromTimeframeGroupID: rule.FromTimeframeGroupID,
+		toTimeframeGroupID:   rule.ToTimeframeGroupID,
+		fareProductID:        rule.FareProductID,
+		rulePriority:         intPtrKey(rule.RulePriority),
+	}
+}
+
+// FareTransferRuleMergeStrategy handles merging of Fares v2 fare transfer
+// rules between feeds. Each row's leg groups are remapped through
+// MergeContext.LegGroupIDMapping, so fare leg rules must be merged first, and
+// its fare product through MergeContext.FareProductIDMapping; a row
+// identical to one already in the target is dropped.
+type FareTransferRuleMergeStrategy struct {
+	BaseStrategy
+}
+
+// NewFareTransferRuleMergeStrategy creates a new FareTransferRuleMergeStrategy
+func NewFareTransferRuleMergeStrategy() *FareTransferRuleMergeStrategy {
+	return &FareTransferRuleMergeStrategy{
+		BaseStrategy: NewBaseStrategy("fare_transfer_rule"),
+	}
+}
+
+// Merge performs the merge operation for fare transfer rules
+func (s *FareTransferRuleMergeStrategy) Merge(ctx *MergeContext) error {
+	existing := make(map[fareTransferRuleKey]bool, len(ctx.Target.FareTransferRules))
+	for _, rule := range ctx.Target.FareTransferRules {
+		existing[newFareTransferRuleKey(rule)] = true
+	}
+
+	for i, rule := range ctx.Source.FareTransferRules {
+		if err := ctx.checkCanceled(i); err != nil {
+			return err
+		}
+		newRule := *rule
+		newRule.FromLegGroupID, _ = ctx.MapLegGroupID(rule.FromLegGroupID)
+		newRule.ToLegGroupID, _ = ctx.MapLegGroupID(rule.ToLegGroupID)
+		newRule.FareProductID, _ = ctx.MapFareProductID(rule.FareProductID)
+
+		key := newFareTransferRuleKey(&newRule)
+		if existing[key] {
+			switch s.DuplicateLogging {
+			case LogWarning:
+				log.Printf("WARNING: Duplicate fare transfer rule detected from leg group %q to %q (keeping existing)", newRule.FromLegGroupID, newRule.ToLegGroupID)
+			case LogError:
+				return fmt.Errorf("duplicate fare transfer rule detected from leg group %q to %q", newRule.FromLegGroupID, newRule.ToLegGroupID)
+			}
+			ctx.countDeduplicated("fare_transfer_rules.txt", 1)
+			continue
+		}
+		existing[key] = true
+		ctx.Target.FareTransferRules = append(ctx.Target.FareTransferRules, &newRule)
+	}
+
+	return nil
+}
+
+// fareTransferRuleKey is a fare transfer rule's fields, for O(1) duplicate
+// detection
+type fareTransferRuleKey struct {
+	fromLegGroupID, toLegGroupID gtfs.LegGroupID
+	transferCount                string
+	durationLimit                string
+	durationLimitType            string
+	fareTransferType             int
+	fareProductID                gtfs.FareProductID
+}
+
+func newFareTransferRuleKey(rule *gtfs.FareTransferRule) fareTransferRuleKey {
+	return fareTransferRuleKey{
+		fromLegGroupID:    rule.FromLegGroupID,
+		toLegGroupID:      rule.ToLegGroupID,
+		transferCount:     intPtrKey(rule.TransferCount),
+		durationLimit:     intPtrKey(rule.DurationLimit),
+		durationLimitType: intPtrKey(rule.DurationLimitType),
+		fareTransferType:  rule.FareTransferType,
+		fareProductID:     rule.FareProductID,
+	}
+}
+
+// intPtrKey returns p's value as text, or "" if it is nil
+func intPtrKey(p *int) string {
+	if p == nil {
+		return ""
+	}
+	return strconv.Itoa(*p)
+}
@@ -0,0 +1,118 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+func TestFareLegRuleMergeCollisionPrefixed(t *testing.T) {
+	// Given: both feeds have leg group "local", the source in two rows
+	source := gtfs.NewFeed()
+	source.FareLegRules = []*gtfs.FareLegRule{
+		{LegGroupID: "local", NetworkID: "standard", FareProductID: "single"},
+		{LegGroupID: "local", NetworkID: "standard", FromAreaID: "zone1", FareProductID: "single"},
+		{LegGroupID: "express", NetworkID: "premium", FareProductID: "express"},
+	}
+	target := gtfs.NewFeed()
+	target.FareLegRules = []*gtfs.FareLegRule{{LegGroupID: "local", FareProductID: "city"}}
+
+	ctx := NewMergeContext(source, target, "a-")
+	ctx.NetworkIDMapping["standard"] = "a-standard"
+	ctx.AreaIDMapping["zone1"] = "a-zone1"
+
+	// When: merged without duplicate detection
+	if err := NewFareLegRuleMergeStrategy().Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the colliding group is prefixed once, the other kept as is
+	if ctx.LegGroupIDMapping["local"] != "a-local" || ctx.LegGroupIDMapping["express"] != "express" {
+		t.Errorf("Expected local→a-local and express→express, got %v", ctx.LegGroupIDMapping)
+	}
+
+	// And: each rule's references are remapped
+	expected := []gtfs.FareLegRule{
+		{LegGroupID: "local", FareProductID: "city"},
+		{LegGroupID: "a-local", NetworkID: "a-standard", FareProductID: "single"},
+		{LegGroupID: "a-local", NetworkID: "a-standard", FromAreaID: "a-zone1", FareProductID: "single"},
+		{LegGroupID: "express", NetworkID: "premium", FareProductID: "express"},
+	}
+	if len(target.FareLegRules) != len(expected) {
+		t.Fatalf("Expected %d fare_leg_rules, got %d", len(expected), len(target.FareLegRules))
+	}
+	for i, rule := range target.FareLegRules {
+		if *rule != expected[i] {
+			t.Errorf("Expected %+v at %d, got %+v", expected[i], i, *rule)
+		}
+	}
+}
+
+func TestFareLegRuleMergeIdentityDuplicate(t *testing.T) {
+	// Given: both feeds have the same rule for leg group "local"
+	source := gtfs.NewFeed()
+	source.FareLegRules = []*gtfs.FareLegRule{{LegGroupID: "local", FareProductID: "single"}}
+	target := gtfs.NewFeed()
+	target.FareLegRules = []*gtfs.FareLegRule{{LegGroupID: "local", FareProductID: "single"}}
+
+	ctx := NewMergeContext(source, target, "a-")
+	strategy := NewFareLegRuleMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionIdentity)
+
+	// When: merged with DetectionIdentity
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the groups are merged and the identical row dropped
+	if len(target.FareLegRules) != 1 || ctx.LegGroupIDMapping["local"] != "local" {
+		t.Errorf("Expected one rule with local mapped to itself, got %d and %v", len(target.FareLegRules), ctx.LegGroupIDMapping)
+	}
+}
+
+func TestFareTransferRuleMergeRemapsLegGroups(t *testing.T) {
+	// Given: transfer rules between colliding leg groups, one repeated, and
+	// one naming a group only transfer rules use
+	limit := 5400
+	source := gtfs.NewFeed()
+	source.FareLegRules = []*gtfs.FareLegRule{{LegGroupID: "local"}}
+	source.FareTransferRules = []*gtfs.FareTransferRule{
+		{FromLegGroupID: "local", ToLegGroupID: "local", DurationLimit: &limit},
+		{FromLegGroupID: "local", ToLegGroupID: "local", DurationLimit: &limit},
+		{FromLegGroupID: "local", ToLegGroupID: "rail"},
+		{FareTransferType: 1},
+	}
+	target := gtfs.NewFeed()
+	target.FareLegRules = []*gtfs.FareLegRule{{LegGroupID: "local"}}
+	target.FareTransferRules = []*gtfs.FareTransferRule{
+		{FromLegGroupID: "local", ToLegGroupID: "rail"},
+		{FareTransferType: 1},
+	}
+
+	ctx := NewMergeContext(source, target, "a-")
+
+	// When: fare leg rules, then fare transfer rules, are merged
+	if err := NewFareLegRuleMergeStrategy().Merge(ctx); err != nil {
+		t.Fatalf("Fare leg rule merge failed: %v", err)
+	}
+	if err := NewFareTransferRuleMergeStrategy().Merge(ctx); err != nil {
+		t.Fatalf("Fare transfer rule merge failed: %v", err)
+	}
+
+	// Then: leg groups are remapped, including the one without a leg rule,
+	// and the repeated and already merged rows are dropped
+	expected := []struct{ from, to gtfs.LegGroupID }{
+		{"local", "rail"},
+		{"", ""},
+		{"a-local", "a-local"},
+		{"a-local", "a-rail"},
+	}
+	if len(target.FareTransferRules) != len(expected) {
+		t.Fatalf("Expected %d fare_transfer_rules, got %d", len(expected), len(target.FareTransferRules))
+	}
+	for i, rule := range target.FareTransferRules {
+		if rule.FromLegGroupID != expected[i].from || rule.ToLegGroupID != expected[i].to {
+			t.Errorf("Expected %v at %d, got %s -> %s", expected[i], i, rule.FromLegGroupID, rule.ToLegGroupID)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package strategy
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// FareMediaMergeStrategy handles merging of Fares v2 fare media between
+// feeds. With identity detection a fare media whose ID the target already
+// has is merged with it; otherwise a colliding ID is prefixed.
+type FareMediaMergeStrategy struct {
+	BaseStrategy
+}
+
+// NewFareMediaMergeStrategy creates a new FareMediaMergeStrategy
+func NewFareMediaMergeStrategy() *FareMediaMergeStrategy {
+	return &FareMediaMergeStrategy{
+		BaseStrategy: NewBaseStrategy("fare_media"),
+	}
+}
+
+// Merge performs the merge operation for fare media
+func (s *FareMediaMergeStrategy) Merge(ctx *MergeContext) error {
+	for i, id := range ctx.Source.FareMediaOrder {
+		if err := ctx.checkCanceled(i); err != nil {
+			return err
+		}
+		media := ctx.Source.FareMedia[id]
+		if _, found := ctx.Target.FareMedia[id]; found && s.DuplicateDetection == DetectionIdentity {
+			switch s.DuplicateLogging {
+			case LogWarning:
+				log.Printf("WARNING: Duplicate fare media detected with ID %q (keeping existing)", id)
+			case LogError:
+				return fmt.Errorf("duplicate fare media detected with ID %q", id)
+			}
+			ctx.RegisterFareMediaID(id, id)
+			ctx.countDeduplicated("fare_media.txt", 1)
+			continue
+		}
+
+		newID := UniqueID(ctx, ctx.Target.FareMedia, id)
+		ctx.RegisterFareMediaID(id, newID)
+		newMedia := *media
+		newMedia.ID = newID
+		ctx.Target.AddFareMedia(&newMedia)
+	}
+	return nil
+}
+
+// RiderCategoryMergeStrategy handles merging of Fares v2 rider categories
+// between feeds. With identity detection a rider category whose ID the
+// target already has is merged with it; otherwise a colliding ID is
+// prefixed.
+type RiderCategoryMergeStrategy struct {
+	BaseStrategy
+}
+
+// NewRiderCategoryMergeStrategy creates a new RiderCategoryMergeStrategy
+func NewRiderCategoryMergeStrategy() *RiderCategoryMergeStrategy {
+	return &RiderCategoryMergeStrategy{
+		BaseStrategy: NewBaseStrategy("rider_category"),
+	}
+}
+
+// Merge performs the merge operation for rider categories
+func (s *RiderCategoryMergeStrategy) Merge(ctx *MergeContext) error {
+	for i, id := range ctx.Source.RiderCategoryOrder {
+		if err := ctx.checkCanceled(i); err != nil {
+			return err
+		}
+		category := ctx.Source.RiderCategories[id]
+		if _, found := ctx.Target.RiderCategories[id]; found && s.DuplicateDetection == DetectionIdentity {
+			switch s.DuplicateLogging {
+			case LogWarning:
+				log.Printf("WARNING: Duplicate rider category detected with ID %q (keeping existing)", id)
+			case LogError:
+				return fmt.Errorf("duplicate rider category detected with ID %q", id)
+			}
+			ctx.RegisterRiderCategoryID(id, id)
+			ctx.countDeduplicated("rider_categories.txt", 1)
+			continue
+		}
+
+		newID := UniqueID(ctx, ctx.Target.RiderCategories, id)
+		ctx.RegisterRiderCategoryID(id, newID)
+		newCategory := *category
+		newCategory.ID = newID
+		ctx.Target.AddRiderCategory(&newCategory)
+	}
+	return nil
+}
+
+// FareProductMergeStrategy handles merging of Fares v2 fare products
+// between feeds. A fare product may have several rows, so its ID is mapped
+// once for all of them: with identity detection a product the target
+// already has is kept as it is and the source's rows for it dropped;
+// otherwise a colliding ID is prefixed. Product IDs that fare leg and
+// transfer rules use without a fare_products.txt row are mapped the same
+// way. Each row's rider category and fare media are remapped, so those must
+// be merged first.
+type FareProductMergeStrategy struct {
+	BaseStrategy
+}
+
+// NewFareProductMergeStrategy creates a new FareProductMergeStrategy
+func NewFareProductMergeStrategy() *FareProductMergeStrategy {
+	return &FareProductMergeStrategy{
+		BaseStrategy: NewBaseStrategy("fare_product"),
+	}
+}
+
+// Merge performs the merge operation for fare products
+func (s *FareProductMergeStrategy) Merge(ctx *MergeContext) error {
+	defined := ctx.Target.FareProductIDs()
+	used := targetFareProductIDs(ctx.Target)
+	mapProduct := func(id gtfs.FareProductID) gtfs.FareProductID {
+		if mapped, ok := ctx.MapFareProductID(id); ok || id == "" {
+			return mapped
+		}
+		newID := UniqueIDFunc(ctx, id, func(id gtfs.FareProductID) bool {
+			return used[id] && s.DuplicateDetection != DetectionIdentity
+		})
+		ctx.RegisterFareProductID(id, newID)
+		used[newID] = true
+		return newID
+	}
+
+	for i, product := range ctx.Source.FareProducts {
+		if err := ctx.checkCanceled(i); err != nil {
+			return err
+		}
+		newID := mapProduct(product.ID)
+		if newID == product.ID && defined[product.ID] {
+			// An identity duplicate: the target's rows for it are kept
+			switch s.DuplicateLogging {
+			case LogWarning:
+				log.Printf("WARNING: Duplicate fare product detected with ID %q (keeping existing)", product.ID)
+			case LogError:
+				return fmt.Errorf("duplicate fare product detected with ID %q", product.ID)
+			}
+			ctx.countDeduplicated("fare_products.txt", 1)
+			continue
+		}
+		newProduct := *product
+		newProduct.ID = newID
+		newProduct.RiderCategoryID, _ = ctx.MapRiderCategoryID(product.RiderCategoryID)
+		newProduct.FareMediaID, _ = ctx.MapFareMediaID(product.FareMediaID)
+		ctx.Target.FareProducts = append(ctx.Target.FareProducts, &newProduct)
+	}
+
+	// Map the product IDs rules use without a fare_products.txt row
+	for _, rule := range ctx.Source.FareLegRules {
+		mapProduct(rule.FareProductID)
+	}
+	for _, rule := range ctx.Source.FareTransferRules {
+		mapProduct(rule.FareProductID)
+	}
+
+	return nil
+}
+
+// targetFareProductIDs returns the fare product IDs the target feed already
+// uses, in fare_products.txt or its fare leg and transfer rules
+func targetFareProductIDs(target *gtfs.Feed) map[gtfs.FareProductID]bool {
+	used := target.FareProductIDs()
+	for _, rule := range target.FareLegRules {
+		used[rule.FareProductID] = true
+	}
+	for _, rule := range target.FareTransferRules {
+		used[rule.FareProductID] = true
+	}
+	delete(used, "")
+	return used
+}
+
+// TimeframeMergeStrategy handles merging of Fares v2 timeframes between
+// feeds. Rows sharing a timeframe group are mapped together: with identity
+// detection a group the target already has is kept as it is and the
+// source's rows for it dropped; otherwise a colliding ID is prefixed.
+// Timeframe groups that fare leg rules use without a timeframes.txt row are
+// mapped the same way. Each row's service is remapped, so calendars must be
+// merged first.
+type TimeframeMergeStrategy struct {
+	BaseStrategy
+}
+
+// NewTimeframeMergeStrategy creates a new TimeframeMergeStrategy
+func NewTimeframeMergeStrategy() *TimeframeMergeStrategy {
+	return &TimeframeMergeStrategy{
+		BaseStrategy: NewBaseStrategy("timeframe"),
+	}
+}
+
+// Merge performs the merge operation for timeframes
+func (s *TimeframeMergeStrategy) Merge(ctx *MergeContext) error {
+	defined := ctx.Target.TimeframeGroupIDs()
+	used := targetTimeframeGroupIDs(ctx.Target)
+	mapGroup := func(id gtfs.TimeframeGroupID) gtfs.TimeframeGroupID {
+		if mapped, ok := ctx.MapTimeframeGroupID(id); ok || id == "" {
+			return mapped
+		}
+		newID := UniqueIDFunc(ctx, id, func(id gtfs.TimeframeGroupID) bool {
+			return used[id] && s.DuplicateDetection != DetectionIdentity
+		})
+		ctx.RegisterTimeframeGroupID(id, newID)
+		used[newID] = true
+		return newID
+	}
+
+	for i, tf := range ctx.Source.Timeframes {
+		if err := ctx.checkCanceled(i); err != nil {
+			return err
+		}
+		newID := mapGroup(tf.GroupID)
+		if newID == tf.GroupID && defined[tf.GroupID] {
+			// An identity duplicate: the target's rows for it are kept
+			switch s.DuplicateLogging {
+			case LogWarning:
+				log.Printf("WARNING: Duplicate timeframe group detected with ID %q (keeping existing)", tf.GroupID)
+			case LogError:
+				return fmt.Errorf("duplicate timeframe group detected with ID %q", tf.GroupID)
+			}
+			ctx.countDeduplicated("timeframes.txt", 1)
+			continue
+		}
+		newTimeframe := *tf
+		newTimeframe.GroupID = newID
+		newTimeframe.ServiceID, _ = ctx.MapServiceID(tf.ServiceID)
+		ctx.Target.Timeframes = append(ctx.Target.Timeframes, &newTimeframe)
+	}
+
+	// Map the timeframe groups fare leg rules use without a timeframes.txt
+	// row
+	for _, rule := range ctx.Source.FareLegRules {
+		mapGroup(rule.FromTimeframeGroupID)
+		mapGroup(rule.ToTimeframeGroupID)
+	}
+
+	return nil
+}
+
+// targetTimeframeGroupIDs returns the timeframe group IDs the target feed
+// already uses, in timeframes.txt or its fare leg rules
+func targetTimeframeGroupIDs(target *gtfs.Feed) map[gtfs.TimeframeGroupID]bool {
+	used := target.TimeframeGroupIDs()
+	for _, rule := range target.FareLegRules {
+		used[rule.FromTimeframeGroupID] = true
+		used[rule.ToTimeframeGroupID] = true
+	}
+	delete(used, "")
+	return used
+}
@@ -0,0 +1,157 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+func TestFareMediaMergeCollisionPrefixed(t *testing.T) {
+	// Given: both feeds have fare media "card"
+	source := gtfs.NewFeed()
+	source.AddFareMedia(&gtfs.FareMedia{ID: "card", Name: "Card", Type: 2})
+	source.AddFareMedia(&gtfs.FareMedia{ID: "app", Name: "App", Type: 4})
+	target := gtfs.NewFeed()
+	target.AddFareMedia(&gtfs.FareMedia{ID: "card", Name: "Other Card", Type: 2})
+
+	ctx := NewMergeContext(source, target, "a-")
+
+	// When: merged without duplicate detection
+	if err := NewFareMediaMergeStrategy().Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the colliding media is prefixed, the other kept as is
+	if ctx.FareMediaIDMapping["card"] != "a-card" || ctx.FareMediaIDMapping["app"] != "app" {
+		t.Errorf("Expected card→a-card and app→app, got %v", ctx.FareMediaIDMapping)
+	}
+	if len(target.FareMedia) != 3 || target.FareMedia["a-card"].Name != "Card" {
+		t.Errorf("Expected 3 fare media with a-card named Card, got %d", len(target.FareMedia))
+	}
+}
+
+func TestRiderCategoryMergeIdentityDuplicate(t *testing.T) {
+	// Given: both feeds have rider category "adult"
+	isDefault := 1
+	source := gtfs.NewFeed()
+	source.AddRiderCategory(&gtfs.RiderCategory{ID: "adult", Name: "Adult", IsDefaultFareCategory: &isDefault})
+	target := gtfs.NewFeed()
+	target.AddRiderCategory(&gtfs.RiderCategory{ID: "adult", Name: "Adult", IsDefaultFareCategory: &isDefault})
+
+	ctx := NewMergeContext(source, target, "a-")
+	strategy := NewRiderCategoryMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionIdentity)
+
+	// When: merged with DetectionIdentity
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the category is merged with the target's
+	if len(target.RiderCategories) != 1 || ctx.RiderCategoryIDMapping["adult"] != "adult" {
+		t.Errorf("Expected one rider category with adult mapped to itself, got %d and %v", len(target.RiderCategories), ctx.RiderCategoryIDMapping)
+	}
+}
+
+func TestFareProductMergeCollisionPrefixed(t *testing.T) {
+	// Given: both feeds have product "single", the source in two rows, and
+	// the source's leg rules name a product without a row
+	source := gtfs.NewFeed()
+	source.FareProducts = []*gtfs.FareProduct{
+		{ID: "single", RiderCategoryID: "adult", FareMediaID: "card", Amount: "2.75", Currency: "USD"},
+		{ID: "single", RiderCategoryID: "senior", FareMediaID: "card", Amount: "1.00", Currency: "USD"},
+	}
+	source.FareLegRules = []*gtfs.FareLegRule{{LegGroupID: "local", FareProductID: "free"}}
+	target := gtfs.NewFeed()
+	target.FareProducts = []*gtfs.FareProduct{{ID: "single", Amount: "3.00", Currency: "USD"}}
+	target.FareLegRules = []*gtfs.FareLegRule{{LegGroupID: "local", FareProductID: "free"}}
+
+	ctx := NewMergeContext(source, target, "a-")
+	ctx.RiderCategoryIDMapping["adult"] = "a-adult"
+	ctx.RiderCategoryIDMapping["senior"] = "senior"
+	ctx.FareMediaIDMapping["card"] = "a-card"
+
+	// When: merged without duplicate detection
+	if err := NewFareProductMergeStrategy().Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the colliding products are prefixed once, rows and rule-only
+	// IDs alike
+	if ctx.FareProductIDMapping["single"] != "a-single" || ctx.FareProductIDMapping["free"] != "a-free" {
+		t.Errorf("Expected single→a-single and free→a-free, got %v", ctx.FareProductIDMapping)
+	}
+
+	// And: each row's references are remapped
+	expected := []gtfs.FareProduct{
+		{ID: "single", Amount: "3.00", Currency: "USD"},
+		{ID: "a-single", RiderCategoryID: "a-adult", FareMediaID: "a-card", Amount: "2.75", Currency: "USD"},
+		{ID: "a-single", RiderCategoryID: "senior", FareMediaID: "a-card", Amount: "1.00", Currency: "USD"},
+	}
+	if len(target.FareProducts) != len(expected) {
+		t.Fatalf("Expected %d fare_products, got %d", len(expected), len(target.FareProducts))
+	}
+	for i, product := range target.FareProducts {
+		if *product != expected[i] {
+			t.Errorf("Expected %+v at %d, got %+v", expected[i], i, *product)
+		}
+	}
+}
+
+func TestFareProductMergeIdentityDuplicate(t *testing.T) {
+	// Given: both feeds define product "single"
+	source := gtfs.NewFeed()
+	source.FareProducts = []*gtfs.FareProduct{{ID: "single", Amount: "2.75", Currency: "USD"}}
+	target := gtfs.NewFeed()
+	target.FareProducts = []*gtfs.FareProduct{{ID: "single", Amount: "2.75", Currency: "USD"}}
+
+	ctx := NewMergeContext(source, target, "a-")
+	strategy := NewFareProductMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionIdentity)
+
+	// When: merged with DetectionIdentity
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the target's product is kept and the source's row dropped
+	if len(target.FareProducts) != 1 || ctx.FareProductIDMapping["single"] != "single" {
+		t.Errorf("Expected one product with single mapped to itself, got %d and %v", len(target.FareProducts), ctx.FareProductIDMapping)
+	}
+}
+
+func TestTimeframeMergeCollisionPrefixed(t *testing.T) {
+	// Given: both feeds have timeframe group "peak", the source in two rows
+	source := gtfs.NewFeed()
+	source.Timeframes = []*gtfs.Timeframe{
+		{GroupID: "peak", StartTime: "06:00:00", EndTime: "09:00:00", ServiceID: "weekday"},
+		{GroupID: "peak", StartTime: "16:00:00", EndTime: "19:00:00", ServiceID: "weekday"},
+	}
+	source.FareLegRules = []*gtfs.FareLegRule{{LegGroupID: "local", ToTimeframeGroupID: "offpeak"}}
+	target := gtfs.NewFeed()
+	target.Timeframes = []*gtfs.Timeframe{{GroupID: "peak", ServiceID: "daily"}}
+
+	ctx := NewMergeContext(source, target, "a-")
+	ctx.ServiceIDMapping["weekday"] = "a-weekday"
+
+	// When: merged without duplicate detection
+	if err := NewTimeframeMergeStrategy().Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the colliding group is prefixed once, the rule-only group kept
+	// as is
+	if ctx.TimeframeGroupIDMapping["peak"] != "a-peak" || ctx.TimeframeGroupIDMapping["offpeak"] != "offpeak" {
+		t.Errorf("Expected peak→a-peak and offpeak→offpeak, got %v", ctx.TimeframeGroupIDMapping)
+	}
+
+	// And: each row's service is remapped
+	if len(target.Timeframes) != 3 {
+		t.Fatalf("Expected 3 timeframes, got %d", len(target.Timeframes))
+	}
+	for _, tf := range target.Timeframes[1:] {
+		if tf.GroupID != "a-peak" || tf.ServiceID != "a-weekday" {
+			t.Errorf("Expected a-peak on a-weekday, got %+v", *tf)
+		}
+	}
+}
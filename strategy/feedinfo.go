@@ -1,8 +1,23 @@
 package strategy
 
+import (
+	"fmt"
+	"log"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// defaultFeedInfoID is the feed_id Java assigns to feed_info rows without one
+const defaultFeedInfoID = "1"
+
 // FeedInfoMergeStrategy handles merging of feed info between feeds
 type FeedInfoMergeStrategy struct {
 	BaseStrategy
+
+	// FeedIDFromSource assigns rows without a feed_id the name of the feed
+	// they came from (MergeContext.SourceFeed), rather than Java's "1", so
+	// that each input keeps its own row
+	FeedIDFromSource bool
 }
 
 // NewFeedInfoMergeStrategy creates a new FeedInfoMergeStrategy
@@ -12,19 +27,75 @@ func NewFeedInfoMergeStrategy() *FeedInfoMergeStrategy {
 	}
 }
 
+// SetFeedIDFromSource sets FeedIDFromSource
+func (s *FeedInfoMergeStrategy) SetFeedIDFromSource(fromSource bool) {
+	s.FeedIDFromSource = fromSource
+}
+
 // Merge performs the merge operation for feed info.
 // FeedInfo entries are keyed by feed_id. When source and target have the same
 // feed_id, the source entry overwrites the target (last-read wins), matching
-// Java's behavior.
+// Java's behavior. Rows without a feed_id are assigned Java's "1", or under
+// FeedIDFromSource ctx.SourceFeed, and every merged row records its
+// SourceFeed. Under DetectionIdentity, a row without a feed_id that matches
+// an existing row field for field is a duplicate.
 func (s *FeedInfoMergeStrategy) Merge(ctx *MergeContext) error {
 	// Iterate in insertion order to match Java output
-	for _, id := range ctx.Source.FeedInfoOrder {
-		fi := ctx.Source.FeedInfos[id]
-		// Track order only for new entries
-		if _, exists := ctx.Target.FeedInfos[id]; !exists {
-			ctx.Target.FeedInfoOrder = append(ctx.Target.FeedInfoOrder, id)
+	for i, id := range ctx.Source.FeedInfoOrder {
+		if err := ctx.checkCanceled(i); err != nil {
+			return err
+		}
+		src := ctx.Source.FeedInfos[id]
+		if src == nil {
+			continue
+		}
+
+		if src.FeedID == "" && s.DuplicateDetection == DetectionIdentity {
+			if existing := findEqualFeedInfo(ctx, src); existing != nil {
+				switch s.DuplicateLogging {
+				case LogWarning:
+					log.Printf("WARNING: Duplicate feed_info detected: %q matches %q (keeping existing)", ctx.SourceFeed, existing.FeedID)
+				case LogError:
+					return fmt.Errorf("duplicate feed_info detected: %q matches %q", ctx.SourceFeed, existing.FeedID)
+				}
+				ctx.countDeduplicated("feed_info.txt", 1)
+				continue
+			}
+		}
+
+		fi := *src
+		if fi.SourceFeed == "" {
+			fi.SourceFeed = ctx.SourceFeed
+		}
+		if fi.FeedID == "" && s.FeedIDFromSource {
+			fi.FeedID = fi.SourceFeed
+		}
+		if fi.FeedID == "" {
+			fi.FeedID = defaultFeedInfoID
+		}
+
+		// Track order only for new entries; an existing entry is replaced
+		if _, exists := ctx.Target.FeedInfos[fi.FeedID]; !exists {
+			ctx.Target.FeedInfoOrder = append(ctx.Target.FeedInfoOrder, fi.FeedID)
+		} else {
+			ctx.countDeduplicated("feed_info.txt", 1)
+		}
+		ctx.Target.FeedInfos[fi.FeedID] = &fi
+	}
+	return nil
+}
+
+// findEqualFeedInfo returns the target feed_info row whose fields all equal
+// src's, ignoring feed_id and SourceFeed, or nil
+func findEqualFeedInfo(ctx *MergeContext, src *gtfs.FeedInfo) *gtfs.FeedInfo {
+	key := *src
+	key.FeedID, key.SourceFeed = "", ""
+	for _, id := range ctx.Target.FeedInfoOrder {
+		existing := *ctx.Target.FeedInfos[id]
+		existing.FeedID, existing.SourceFeed = "", ""
+		if existing == key {
+			return ctx.Target.FeedInfos[id]
 		}
-		ctx.Target.FeedInfos[id] = fi
 	}
 	return nil
 }
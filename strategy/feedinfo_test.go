@@ -189,3 +189,94 @@ func TestFeedInfoMergeNoSource(t *testing.T) {
 		t.Errorf("Expected target to keep its feed info")
 	}
 }
+
+func TestFeedInfoMergeBlankIDUsesJavaDefault(t *testing.T) {
+	// Given: a source feed_info row without a feed_id, from feed metro
+	source := gtfs.NewFeed()
+	source.AddFeedInfo(&gtfs.FeedInfo{PublisherName: "Transit Authority"})
+	target := gtfs.NewFeed()
+
+	ctx := NewMergeContext(source, target, "")
+	ctx.SourceFeed = "metro"
+
+	// When: merged
+	if err := NewFeedInfoMergeStrategy().Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the row gets Java's feed_id and records its source
+	fi := target.FeedInfos["1"]
+	if fi == nil {
+		t.Fatalf("Expected feed info keyed by default id 1, got %v", target.FeedInfos)
+	}
+	if fi.SourceFeed != "metro" {
+		t.Errorf("Expected SourceFeed metro, got %q", fi.SourceFeed)
+	}
+}
+
+func TestFeedInfoMergeBlankIDUsesSourceFeed(t *testing.T) {
+	// Given: a source feed_info row without a feed_id
+	source := gtfs.NewFeed()
+	source.AddFeedInfo(&gtfs.FeedInfo{PublisherName: "Transit Authority"})
+	target := gtfs.NewFeed()
+
+	ctx := NewMergeContext(source, target, "")
+	ctx.SourceFeed = "metro"
+
+	// When: merged with FeedIDFromSource
+	s := NewFeedInfoMergeStrategy()
+	s.SetFeedIDFromSource(true)
+	if err := s.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the row is keyed and identified by the source feed name
+	fi := target.FeedInfos["metro"]
+	if fi == nil {
+		t.Fatalf("Expected feed info keyed by source feed, got %v", target.FeedInfos)
+	}
+	if fi.FeedID != "metro" || fi.SourceFeed != "metro" {
+		t.Errorf("Expected FeedID and SourceFeed metro, got %q and %q", fi.FeedID, fi.SourceFeed)
+	}
+}
+
+func TestFeedInfoMergeBlankIDWithoutSourceFeed(t *testing.T) {
+	// Given: a source feed_info row without a feed_id and no source name
+	source := gtfs.NewFeed()
+	source.AddFeedInfo(&gtfs.FeedInfo{PublisherName: "Transit Authority"})
+	target := gtfs.NewFeed()
+
+	// When: merged
+	if err := NewFeedInfoMergeStrategy().Merge(NewMergeContext(source, target, "")); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the Java default feed_id is used
+	if target.FeedInfos["1"] == nil {
+		t.Errorf("Expected feed info keyed by default id 1, got %v", target.FeedInfos)
+	}
+}
+
+func TestFeedInfoMergeBlankIDIdentityDuplicate(t *testing.T) {
+	// Given: a target row from feed b and an identical row without a feed_id
+	target := gtfs.NewFeed()
+	target.AddFeedInfo(&gtfs.FeedInfo{FeedID: "b", SourceFeed: "b", PublisherName: "Transit Authority", Version: "1.0"})
+	source := gtfs.NewFeed()
+	source.AddFeedInfo(&gtfs.FeedInfo{PublisherName: "Transit Authority", Version: "1.0"})
+	source.AddFeedInfo(&gtfs.FeedInfo{FeedID: "other", PublisherName: "Transit Authority", Version: "2.0"})
+
+	ctx := NewMergeContext(source, target, "a-")
+	ctx.SourceFeed = "a"
+	s := NewFeedInfoMergeStrategy()
+	s.SetDuplicateDetection(DetectionIdentity)
+
+	// When: merged with identity detection
+	if err := s.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the identical row is merged into the existing one
+	if len(target.FeedInfos) != 2 || target.FeedInfos["a"] != nil {
+		t.Errorf("Expected feed infos b and other, got %v", target.FeedInfoOrder)
+	}
+}
@@ -1,22 +1,43 @@
 package strategy
 
 import (
+	"fmt"
+	"log"
+	"slices"
+
 	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
 )
 
 // FrequencyMergeStrategy handles merging of frequencies between feeds
 type FrequencyMergeStrategy struct {
 	BaseStrategy
+	// OverlapPolicy decides what happens to a source window that overlaps an
+	// existing window on the same trip with a different headway (default
+	// OverlapKeep). With LogError duplicate logging, such an overlap returns
+	// an error instead.
+	OverlapPolicy OverlapPolicy
 }
 
 // NewFrequencyMergeStrategy creates a new FrequencyMergeStrategy
 func NewFrequencyMergeStrategy() *FrequencyMergeStrategy {
 	return &FrequencyMergeStrategy{
-		BaseStrategy: NewBaseStrategy("frequency"),
+		BaseStrategy:  NewBaseStrategy("frequency"),
+		OverlapPolicy: OverlapKeep,
 	}
 }
 
-// Merge performs the merge operation for frequencies
+// SetOverlapPolicy sets how overlapping windows with different headways are resolved
+func (s *FrequencyMergeStrategy) SetOverlapPolicy(p OverlapPolicy) {
+	s.OverlapPolicy = p
+}
+
+// Merge performs the merge operation for frequencies.
+// Rows follow their trip through TripIDMapping, so when a source trip was
+// deduplicated onto an existing target trip its windows are checked against
+// the target trip's: an overlapping window with the same headway and
+// exact_times is merged with the existing rows it overlaps into one row,
+// spanning their union, and one with a different headway is resolved
+// according to OverlapPolicy.
 func (s *FrequencyMergeStrategy) Merge(ctx *MergeContext) error {
 	// Build index for O(1) duplicate detection (avoids O(n²) linear scan)
 	type frequencyKey struct {
@@ -32,32 +53,173 @@ func (s *FrequencyMergeStrategy) Merge(ctx *MergeContext) error {
 		}
 	}
 
-	for _, freq := range ctx.Source.Frequencies {
-		// Map trip reference
-		tripID := freq.TripID
-		if mappedTrip, ok := ctx.TripIDMapping[tripID]; ok {
-			tripID = mappedTrip
+	// Windows from earlier feeds by trip, for overlap checks
+	existingByTrip := make(map[gtfs.TripID][]*gtfs.Frequency)
+	for _, existing := range ctx.Target.Frequencies {
+		existingByTrip[existing.TripID] = append(existingByTrip[existing.TripID], existing)
+	}
+
+	for i, freq := range ctx.Source.Frequencies {
+		if err := ctx.checkCanceled(i); err != nil {
+			return err
 		}
+		// Map trip reference
+		tripID, _ := ctx.MapTripID(freq.TripID)
 
 		// Check for duplicates using O(1) lookup
 		if s.DuplicateDetection == DetectionIdentity {
 			key := frequencyKey{tripID, freq.StartTime, freq.EndTime, freq.HeadwaySecs}
 			if existingKeys[key] {
+				ctx.countDeduplicated("frequencies.txt", 1)
 				continue
 			}
 			// Add to index for subsequent source items
 			existingKeys[key] = true
 		}
 
-		newFreq := &gtfs.Frequency{
-			TripID:      tripID,
-			StartTime:   freq.StartTime,
-			EndTime:     freq.EndTime,
-			HeadwaySecs: freq.HeadwaySecs,
-			ExactTimes:  freq.ExactTimes,
+		windows, absorbed, err := s.resolveOverlaps(tripID, freq, existingByTrip[tripID])
+		if err != nil {
+			return err
+		}
+		if len(absorbed) > 0 {
+			// Existing windows the merged window now spans
+			isAbsorbed := func(f *gtfs.Frequency) bool { return slices.Contains(absorbed, f) }
+			ctx.Target.Frequencies = slices.DeleteFunc(ctx.Target.Frequencies, isAbsorbed)
+			existingByTrip[tripID] = slices.DeleteFunc(existingByTrip[tripID], isAbsorbed)
+			ctx.countDeduplicated("frequencies.txt", len(absorbed))
+		}
+		switch {
+		case len(windows) == 0:
+			ctx.countDeduplicated("frequencies.txt", 1)
+		case len(windows) > 1:
+			// Split around conflicting windows into several rows
+			ctx.countDropped("frequencies.txt", 1-len(windows))
+		}
+
+		for _, w := range windows {
+			newFreq := &gtfs.Frequency{
+				TripID:      tripID,
+				StartTime:   w.start,
+				EndTime:     w.end,
+				HeadwaySecs: freq.HeadwaySecs,
+				ExactTimes:  freq.ExactTimes,
+			}
+			ctx.Target.Frequencies = append(ctx.Target.Frequencies, newFreq)
 		}
-		ctx.Target.Frequencies = append(ctx.Target.Frequencies, newFreq)
 	}
 
 	return nil
 }
+
+// frequencyWindow is a frequencies.txt start_time/end_time pair
+type frequencyWindow struct {
+	start, end string
+}
+
+// resolveOverlaps checks a source window against the existing windows of the
+// trip it maps onto and returns the windows to add for it: none when it was
+// merged into an existing window, the source window itself, or, under
+// OverlapSplit, the parts of it no conflicting window covers. A source window
+// overlapping existing windows with its headway and exact_times is merged
+// with all of them, and with any the union then overlaps, into the first;
+// the others are returned as absorbed, to be removed. The merged window is
+// then checked for conflicts like a source window, but is not split.
+func (s *FrequencyMergeStrategy) resolveOverlaps(tripID gtfs.TripID, freq *gtfs.Frequency, existing []*gtfs.Frequency) ([]frequencyWindow, []*gtfs.Frequency, error) {
+	window := frequencyWindow{freq.StartTime, freq.EndTime}
+	start, end := parseGTFSTime(freq.StartTime), parseGTFSTime(freq.EndTime)
+
+	// Union the source window with the same-headway windows it overlaps,
+	// again as long as the union grows to overlap another
+	var merged []*gtfs.Frequency
+	for grown := true; grown; {
+		grown = false
+		for _, e := range existing {
+			eStart, eEnd := parseGTFSTime(e.StartTime), parseGTFSTime(e.EndTime)
+			if start >= eEnd || eStart >= end || e.HeadwaySecs != freq.HeadwaySecs ||
+				e.ExactTimes != freq.ExactTimes || slices.Contains(merged, e) {
+				continue
+			}
+			merged = append(merged, e)
+			if eStart < start {
+				start, window.start = eStart, e.StartTime
+			}
+			if eEnd > end {
+				end, window.end = eEnd, e.EndTime
+			}
+			grown = true
+		}
+	}
+	var absorbed []*gtfs.Frequency
+	if len(merged) > 0 {
+		// Keep the earliest row, in file order, spanning the union
+		kept := merged[0]
+		for _, e := range existing {
+			if slices.Contains(merged, e) {
+				kept = e
+				break
+			}
+		}
+		for _, e := range merged {
+			if e != kept {
+				absorbed = append(absorbed, e)
+			}
+		}
+		kept.StartTime, kept.EndTime = window.start, window.end
+		if s.DuplicateLogging == LogWarning {
+			log.Printf("WARNING: Overlapping frequencies for trip %q with headway %ds merged into %s-%s",
+				tripID, freq.HeadwaySecs, kept.StartTime, kept.EndTime)
+		}
+	}
+
+	var conflicts [][2]int
+	for _, e := range existing {
+		eStart, eEnd := parseGTFSTime(e.StartTime), parseGTFSTime(e.EndTime)
+		if start >= eEnd || eStart >= end || slices.Contains(merged, e) {
+			continue
+		}
+
+		switch s.DuplicateLogging {
+		case LogWarning:
+			log.Printf("WARNING: Overlapping frequencies for trip %q: %s-%s every %ds vs %s-%s every %ds (%s)",
+				tripID, e.StartTime, e.EndTime, e.HeadwaySecs, window.start, window.end, freq.HeadwaySecs, s.OverlapPolicy)
+		case LogError:
+			return nil, nil, fmt.Errorf("overlapping frequencies for trip %q: %s-%s every %ds vs %s-%s every %ds",
+				tripID, e.StartTime, e.EndTime, e.HeadwaySecs, window.start, window.end, freq.HeadwaySecs)
+		}
+		conflicts = append(conflicts, [2]int{eStart, eEnd})
+	}
+
+	switch {
+	case len(merged) > 0:
+		return nil, absorbed, nil
+	case len(conflicts) == 0 || s.OverlapPolicy != OverlapSplit:
+		return []frequencyWindow{window}, nil, nil
+	}
+	return subtractWindows(start, end, conflicts), nil, nil
+}
+
+// subtractWindows returns the parts of [start, end) outside every window in
+// cut, in time order
+func subtractWindows(start, end int, cut [][2]int) []frequencyWindow {
+	remaining := [][2]int{{start, end}}
+	for _, c := range cut {
+		var next [][2]int
+		for _, r := range remaining {
+			if c[0] > r[0] {
+				next = append(next, [2]int{r[0], min(r[1], c[0])})
+			}
+			if c[1] < r[1] {
+				next = append(next, [2]int{max(r[0], c[1]), r[1]})
+			}
+		}
+		remaining = next
+	}
+
+	windows := make([]frequencyWindow, 0, len(remaining))
+	for _, r := range remaining {
+		if r[0] < r[1] {
+			windows = append(windows, frequencyWindow{gtfs.FormatTime(r[0]), gtfs.FormatTime(r[1])})
+		}
+	}
+	return windows
+}
@@ -1,6 +1,11 @@
 package strategy
 
 import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
@@ -112,3 +117,160 @@ func TestFrequencyMergeMappedTripID(t *testing.T) {
 		t.Errorf("Expected TripID = a_trip1, got %q", target.Frequencies[0].TripID)
 	}
 }
+
+// overlappingFrequencies returns a target with trip_b running 06:00-09:00
+// every 600s and a source whose trip_a, deduplicated onto trip_b, runs
+// 06:30-09:30 with the given headway
+func overlappingFrequencies(headway int) (*MergeContext, *gtfs.Feed) {
+	target := gtfs.NewFeed()
+	target.Frequencies = append(target.Frequencies, &gtfs.Frequency{
+		TripID: "trip_b", StartTime: "06:00:00", EndTime: "09:00:00", HeadwaySecs: 600,
+	})
+
+	source := gtfs.NewFeed()
+	source.Frequencies = append(source.Frequencies, &gtfs.Frequency{
+		TripID: "trip_a", StartTime: "06:30:00", EndTime: "09:30:00", HeadwaySecs: headway,
+	})
+
+	ctx := NewMergeContext(source, target, "a-")
+	ctx.TripIDMapping["trip_a"] = "trip_b"
+	return ctx, target
+}
+
+func TestFrequencyMergeOverlappingEqualHeadway(t *testing.T) {
+	// Given: overlapping windows with the same headway on a deduplicated trip
+	ctx, target := overlappingFrequencies(600)
+
+	// When: merged
+	if err := NewFrequencyMergeStrategy().Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: one row spans the union of the windows
+	if len(target.Frequencies) != 1 {
+		t.Fatalf("Expected 1 frequency, got %d", len(target.Frequencies))
+	}
+	f := target.Frequencies[0]
+	if f.StartTime != "06:00:00" || f.EndTime != "09:30:00" || f.HeadwaySecs != 600 {
+		t.Errorf("Expected 06:00:00-09:30:00 every 600s, got %s-%s every %ds", f.StartTime, f.EndTime, f.HeadwaySecs)
+	}
+}
+
+func TestFrequencyMergeOverlappingSeveralWindows(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing [][2]string
+		source   [2]string
+	}{
+		{"source bridges two windows", [][2]string{{"06:00:00", "07:00:00"}, {"08:00:00", "09:00:00"}}, [2]string{"06:30:00", "08:30:00"}},
+		{"union reaches a third window", [][2]string{{"06:00:00", "07:00:00"}, {"07:10:00", "09:00:00"}}, [2]string{"06:30:00", "07:15:00"}},
+		{"union reaches an earlier window", [][2]string{{"07:10:00", "09:00:00"}, {"06:00:00", "07:00:00"}}, [2]string{"06:30:00", "07:15:00"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: two existing windows every 600s and a source window with
+			// the same headway overlapping one or both
+			target := gtfs.NewFeed()
+			for _, w := range tt.existing {
+				target.Frequencies = append(target.Frequencies, &gtfs.Frequency{
+					TripID: "trip_b", StartTime: w[0], EndTime: w[1], HeadwaySecs: 600,
+				})
+			}
+			source := gtfs.NewFeed()
+			source.Frequencies = append(source.Frequencies, &gtfs.Frequency{
+				TripID: "trip_a", StartTime: tt.source[0], EndTime: tt.source[1], HeadwaySecs: 600,
+			})
+			ctx := NewMergeContext(source, target, "a-")
+			ctx.TripIDMapping["trip_a"] = "trip_b"
+
+			// When: merged
+			if err := NewFrequencyMergeStrategy().Merge(ctx); err != nil {
+				t.Fatalf("Merge failed: %v", err)
+			}
+
+			// Then: all three windows become one row spanning their union
+			if len(target.Frequencies) != 1 {
+				t.Fatalf("Expected 1 frequency, got %d", len(target.Frequencies))
+			}
+			if f := target.Frequencies[0]; f.StartTime != "06:00:00" || f.EndTime != "09:00:00" {
+				t.Errorf("Expected 06:00:00-09:00:00, got %s-%s", f.StartTime, f.EndTime)
+			}
+
+			// And: the source row and the absorbed row are counted
+			if got := ctx.Deduplicated["frequencies.txt"]; got != 2 {
+				t.Errorf("Expected 2 rows deduplicated, got %d", got)
+			}
+		})
+	}
+}
+
+func TestFrequencyMergeOverlappingConflictingHeadway(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   OverlapPolicy
+		expected []string
+	}{
+		{"keep", OverlapKeep, []string{"06:00:00-09:00:00/600", "06:30:00-09:30:00/300"}},
+		{"split", OverlapSplit, []string{"06:00:00-09:00:00/600", "09:00:00-09:30:00/300"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: overlapping windows with different headways
+			ctx, target := overlappingFrequencies(300)
+			s := NewFrequencyMergeStrategy()
+			s.SetDuplicateLogging(LogWarning)
+			s.SetOverlapPolicy(tt.policy)
+
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			defer log.SetOutput(os.Stderr)
+
+			// When: merged
+			if err := s.Merge(ctx); err != nil {
+				t.Fatalf("Merge failed: %v", err)
+			}
+
+			// Then: the conflict is logged
+			if !strings.Contains(logs.String(), `WARNING: Overlapping frequencies for trip "trip_b"`) {
+				t.Errorf("Expected overlap warning, got %q", logs.String())
+			}
+
+			// And: the windows are resolved per the policy
+			var got []string
+			for _, f := range target.Frequencies {
+				got = append(got, fmt.Sprintf("%s-%s/%d", f.StartTime, f.EndTime, f.HeadwaySecs))
+			}
+			if strings.Join(got, " ") != strings.Join(tt.expected, " ") {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFrequencyMergeOverlappingConflictingHeadwayError(t *testing.T) {
+	// Given: overlapping windows with different headways and error logging
+	ctx, _ := overlappingFrequencies(300)
+	s := NewFrequencyMergeStrategy()
+	s.SetDuplicateLogging(LogError)
+
+	// Then: the merge fails
+	if err := s.Merge(ctx); err == nil {
+		t.Error("Expected an error for conflicting overlapping frequencies")
+	}
+}
+
+func TestSubtractWindows(t *testing.T) {
+	// A window cut in the middle leaves both ends
+	got := subtractWindows(6*3600, 10*3600, [][2]int{{7 * 3600, 8 * 3600}})
+	expected := []frequencyWindow{{"06:00:00", "07:00:00"}, {"08:00:00", "10:00:00"}}
+	if len(got) != len(expected) || got[0] != expected[0] || got[1] != expected[1] {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	// A fully covered window leaves nothing
+	if got := subtractWindows(7*3600, 8*3600, [][2]int{{6 * 3600, 9 * 3600}}); len(got) != 0 {
+		t.Errorf("Expected no windows, got %v", got)
+	}
+}
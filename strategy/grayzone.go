@@ -0,0 +1,66 @@
+package strategy
+
+// GrayZone configures how fuzzy matches scoring close to the fuzzy threshold
+// are resolved. A score within Margin of the threshold (on either side) is
+// ambiguous and is decided by Policy; a zero Margin disables the gray zone.
+type GrayZone struct {
+	Margin float64
+	Policy GrayZonePolicy
+}
+
+// GrayZoneMatch records a fuzzy match whose score fell in the gray zone
+type GrayZoneMatch struct {
+	// Entity is the kind of entity matched: "stop", "route" or "trip"
+	Entity string
+
+	// SourceFeed names the feed the source entity came from
+	SourceFeed string
+
+	// SourceID is the ID of the entity being merged
+	SourceID string
+
+	// TargetID is the ID of the best matching entity already merged
+	TargetID string
+
+	// Score is the match score and Threshold the fuzzy threshold it was
+	// compared against
+	Score     float64
+	Threshold float64
+
+	// Merged reports whether the pair was treated as a duplicate
+	Merged bool
+}
+
+// fuzzySearchThreshold returns the lowest score a fuzzy search must consider
+// so that candidates in the gray zone below threshold are still found
+func (ctx *MergeContext) fuzzySearchThreshold(threshold float64) float64 {
+	if ctx.GrayZone.Margin <= 0 {
+		return threshold
+	}
+	return threshold - ctx.GrayZone.Margin
+}
+
+// acceptFuzzyMatch decides whether the best fuzzy candidate targetID, found
+// with the given score, is a duplicate of sourceID. Scores in the gray zone
+// are resolved by the gray zone policy and recorded in GrayZoneMatches.
+func (ctx *MergeContext) acceptFuzzyMatch(entity, sourceID, targetID string, score, threshold float64) bool {
+	if targetID == "" {
+		return false
+	}
+	margin := ctx.GrayZone.Margin
+	if margin <= 0 || score >= threshold+margin {
+		return score >= threshold
+	}
+
+	match := GrayZoneMatch{
+		Entity:     entity,
+		SourceFeed: ctx.SourceFeed,
+		SourceID:   sourceID,
+		TargetID:   targetID,
+		Score:      score,
+		Threshold:  threshold,
+		Merged:     ctx.GrayZone.Policy == GrayZoneFlag,
+	}
+	ctx.GrayZoneMatches = append(ctx.GrayZoneMatches, match)
+	return match.Merged
+}
@@ -0,0 +1,88 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// grayZoneStops builds a source and target feed with one same-named stop
+// each, about 70m apart, so the pair scores 0.75
+func grayZoneStops() (source, target *gtfs.Feed) {
+	source = gtfs.NewFeed()
+	source.AddStop(&gtfs.Stop{ID: "stop_a", Name: "Main St", Lat: 40.7128, Lon: -74.0060})
+	target = gtfs.NewFeed()
+	target.AddStop(&gtfs.Stop{ID: "stop_b", Name: "Main St", Lat: 40.71343, Lon: -74.0060})
+	return source, target
+}
+
+func TestStopMergeGrayZone(t *testing.T) {
+	tests := []struct {
+		name       string
+		threshold  float64
+		grayZone   GrayZone
+		wantMerged bool
+		wantMatch  bool // whether the pair is recorded as a gray zone match
+	}{
+		{"no gray zone below threshold", 0.8, GrayZone{}, false, false},
+		{"no gray zone above threshold", 0.7, GrayZone{}, true, false},
+		{"near miss below threshold", 0.8, GrayZone{Margin: 0.1, Policy: GrayZoneNearMiss}, false, true},
+		{"near miss above threshold", 0.7, GrayZone{Margin: 0.1, Policy: GrayZoneNearMiss}, false, true},
+		{"flag below threshold", 0.8, GrayZone{Margin: 0.1, Policy: GrayZoneFlag}, true, true},
+		{"abort", 0.8, GrayZone{Margin: 0.1, Policy: GrayZoneAbort}, false, true},
+		{"outside gray zone", 0.6, GrayZone{Margin: 0.1, Policy: GrayZoneNearMiss}, true, false},
+		{"too far below threshold", 0.9, GrayZone{Margin: 0.1, Policy: GrayZoneFlag}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: two stops scoring 0.75
+			source, target := grayZoneStops()
+			ctx := NewMergeContext(source, target, "")
+			ctx.SourceFeed = "a"
+			ctx.GrayZone = tt.grayZone
+			s := NewStopMergeStrategy()
+			s.SetDuplicateDetection(DetectionFuzzy)
+			s.FuzzyThreshold = tt.threshold
+
+			// When: merged
+			if err := s.Merge(ctx); err != nil {
+				t.Fatalf("Merge failed: %v", err)
+			}
+
+			// Then: the pair is merged according to the policy
+			if merged := ctx.StopIDMapping["stop_a"] == "stop_b"; merged != tt.wantMerged {
+				t.Errorf("Expected merged=%v, got mapping %q", tt.wantMerged, ctx.StopIDMapping["stop_a"])
+			}
+
+			// And: gray zone decisions are recorded
+			if !tt.wantMatch {
+				if len(ctx.GrayZoneMatches) != 0 {
+					t.Errorf("Expected no gray zone matches, got %+v", ctx.GrayZoneMatches)
+				}
+				return
+			}
+			if len(ctx.GrayZoneMatches) != 1 {
+				t.Fatalf("Expected 1 gray zone match, got %d", len(ctx.GrayZoneMatches))
+			}
+			gm := ctx.GrayZoneMatches[0]
+			want := GrayZoneMatch{Entity: "stop", SourceFeed: "a", SourceID: "stop_a", TargetID: "stop_b",
+				Score: 0.75, Threshold: tt.threshold, Merged: tt.wantMerged}
+			if gm != want {
+				t.Errorf("Expected %+v, got %+v", want, gm)
+			}
+		})
+	}
+}
+
+func TestParseGrayZonePolicy(t *testing.T) {
+	for _, p := range []GrayZonePolicy{GrayZoneNearMiss, GrayZoneFlag, GrayZoneAbort} {
+		got, err := ParseGrayZonePolicy(p.String())
+		if err != nil || got != p {
+			t.Errorf("ParseGrayZonePolicy(%q) = %v, %v; want %v", p.String(), got, err, p)
+		}
+	}
+	if _, err := ParseGrayZonePolicy("maybe"); err == nil {
+		t.Error("Expected error for invalid policy")
+	}
+}
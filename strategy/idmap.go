@@ -0,0 +1,234 @@
+package strategy
+
+import (
+	"fmt"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// A strategy merging entities of one kind follows the same pattern for each
+// source entity: it maps the entity onto an existing target entity it
+// duplicates, or adds it under UniqueID, and in either case registers the
+// source ID's target ID (e.g. RegisterStopID). Strategies merging the
+// entities that reference it later, in the order merge.Merger runs them,
+// rewrite each reference with its Map method (e.g. MapStopID), which leaves
+// an ID the source feed does not define unchanged.
+
+// UniqueID returns the ID a source entity added to the target takes: id
+// itself if target, the target's entities of its kind, has no entity with
+// that ID, and otherwise id with ctx.Prefix, prefixed again for as long as
+// target has that ID too. Under ctx.AlwaysPrefix id is prefixed whether or
+// not it collides.
+func UniqueID[K ~string, V any](ctx *MergeContext, target map[K]V, id K) K {
+	return UniqueIDFunc(ctx, id, func(id K) bool {
+		_, taken := target[id]
+		return taken
+	})
+}
+
+// UniqueIDFunc is UniqueID for strategies whose IDs are not the keys of a
+// single map of the target, such as service IDs, defined by calendar.txt and
+// calendar_dates.txt: taken reports whether the target already has an ID.
+// Without a prefix, as for the first feed merged, a taken ID is given the
+// first free numeric suffix instead ("-2", "-3", ...).
+func UniqueIDFunc[K ~string](ctx *MergeContext, id K, taken func(K) bool) K {
+	if ctx.AlwaysPrefix {
+		id = K(ctx.Prefix + string(id))
+	}
+	if !taken(id) {
+		return id
+	}
+	if ctx.Prefix == "" {
+		for n := 2; ; n++ {
+			if suffixed := K(fmt.Sprintf("%s-%d", id, n)); !taken(suffixed) {
+				return suffixed
+			}
+		}
+	}
+	for taken(id) {
+		id = K(ctx.Prefix + string(id))
+	}
+	return id
+}
+
+// mapID returns the ID mapping maps id to and true, or id and false if it
+// has no mapping
+func mapID[K comparable](mapping map[K]K, id K) (K, bool) {
+	if mapped, ok := mapping[id]; ok {
+		return mapped, true
+	}
+	return id, false
+}
+
+// registerID maps from to to in *mapping, making the map if it is nil so
+// that a zero MergeContext can be used
+func registerID[K comparable](mapping *map[K]K, from, to K) {
+	if *mapping == nil {
+		*mapping = make(map[K]K)
+	}
+	(*mapping)[from] = to
+}
+
+// MapAgencyID returns the target ID of the source agency id and true, or id
+// unchanged and false if no agency of the source has been merged with it
+func (ctx *MergeContext) MapAgencyID(id gtfs.AgencyID) (gtfs.AgencyID, bool) {
+	return mapID(ctx.AgencyIDMapping, id)
+}
+
+// RegisterAgencyID records that the source agency from is to in the target
+func (ctx *MergeContext) RegisterAgencyID(from, to gtfs.AgencyID) {
+	registerID(&ctx.AgencyIDMapping, from, to)
+}
+
+// MapStopID returns the target ID of the source stop id and true, or id
+// unchanged and false if no stop of the source has been merged with it
+func (ctx *MergeContext) MapStopID(id gtfs.StopID) (gtfs.StopID, bool) {
+	return mapID(ctx.StopIDMapping, id)
+}
+
+// RegisterStopID records that the source stop from is to in the target
+func (ctx *MergeContext) RegisterStopID(from, to gtfs.StopID) {
+	registerID(&ctx.StopIDMapping, from, to)
+}
+
+// MapRouteID returns the target ID of the source route id and true, or id
+// unchanged and false if no route of the source has been merged with it
+func (ctx *MergeContext) MapRouteID(id gtfs.RouteID) (gtfs.RouteID, bool) {
+	return mapID(ctx.RouteIDMapping, id)
+}
+
+// RegisterRouteID records that the source route from is to in the target
+func (ctx *MergeContext) RegisterRouteID(from, to gtfs.RouteID) {
+	registerID(&ctx.RouteIDMapping, from, to)
+}
+
+// MapTripID returns the target ID of the source trip id and true, or id
+// unchanged and false if no trip of the source has been merged with it
+func (ctx *MergeContext) MapTripID(id gtfs.TripID) (gtfs.TripID, bool) {
+	return mapID(ctx.TripIDMapping, id)
+}
+
+// RegisterTripID records that the source trip from is to in the target
+func (ctx *MergeContext) RegisterTripID(from, to gtfs.TripID) {
+	registerID(&ctx.TripIDMapping, from, to)
+}
+
+// MapServiceID returns the target ID of the source service id and true, or
+// id unchanged and false if no service of the source has been merged with it
+func (ctx *MergeContext) MapServiceID(id gtfs.ServiceID) (gtfs.ServiceID, bool) {
+	return mapID(ctx.ServiceIDMapping, id)
+}
+
+// RegisterServiceID records that the source service from is to in the
+// target
+func (ctx *MergeContext) RegisterServiceID(from, to gtfs.ServiceID) {
+	registerID(&ctx.ServiceIDMapping, from, to)
+}
+
+// MapShapeID returns the target ID of the source shape id and true, or id
+// unchanged and false if no shape of the source has been merged with it
+func (ctx *MergeContext) MapShapeID(id gtfs.ShapeID) (gtfs.ShapeID, bool) {
+	return mapID(ctx.ShapeIDMapping, id)
+}
+
+// RegisterShapeID records that the source shape from is to in the target
+func (ctx *MergeContext) RegisterShapeID(from, to gtfs.ShapeID) {
+	registerID(&ctx.ShapeIDMapping, from, to)
+}
+
+// MapFareID returns the target ID of the source fare id and true, or id
+// unchanged and false if no fare of the source has been merged with it
+func (ctx *MergeContext) MapFareID(id gtfs.FareID) (gtfs.FareID, bool) {
+	return mapID(ctx.FareIDMapping, id)
+}
+
+// RegisterFareID records that the source fare from is to in the target
+func (ctx *MergeContext) RegisterFareID(from, to gtfs.FareID) {
+	registerID(&ctx.FareIDMapping, from, to)
+}
+
+// MapAreaID returns the target ID of the source area id and true, or id
+// unchanged and false if no area of the source has been merged with it
+func (ctx *MergeContext) MapAreaID(id gtfs.AreaID) (gtfs.AreaID, bool) {
+	return mapID(ctx.AreaIDMapping, id)
+}
+
+// RegisterAreaID records that the source area from is to in the target
+func (ctx *MergeContext) RegisterAreaID(from, to gtfs.AreaID) {
+	registerID(&ctx.AreaIDMapping, from, to)
+}
+
+// MapNetworkID returns the target ID of the source network id and true, or
+// id unchanged and false if no network of the source has been merged with it
+func (ctx *MergeContext) MapNetworkID(id gtfs.NetworkID) (gtfs.NetworkID, bool) {
+	return mapID(ctx.NetworkIDMapping, id)
+}
+
+// RegisterNetworkID records that the source network from is to in the
+// target
+func (ctx *MergeContext) RegisterNetworkID(from, to gtfs.NetworkID) {
+	registerID(&ctx.NetworkIDMapping, from, to)
+}
+
+// MapLegGroupID returns the target ID of the source leg group id and true,
+// or id unchanged and false if no leg group of the source has been merged
+// with it
+func (ctx *MergeContext) MapLegGroupID(id gtfs.LegGroupID) (gtfs.LegGroupID, bool) {
+	return mapID(ctx.LegGroupIDMapping, id)
+}
+
+// RegisterLegGroupID records that the source leg group from is to in the
+// target
+func (ctx *MergeContext) RegisterLegGroupID(from, to gtfs.LegGroupID) {
+	registerID(&ctx.LegGroupIDMapping, from, to)
+}
+
+// MapFareProductID returns the target ID of the source fare product id and
+// true, or id unchanged and false if it has not been mapped
+func (ctx *MergeContext) MapFareProductID(id gtfs.FareProductID) (gtfs.FareProductID, bool) {
+	return mapID(ctx.FareProductIDMapping, id)
+}
+
+// RegisterFareProductID records that the source fare product from is to in
+// the target
+func (ctx *MergeContext) RegisterFareProductID(from, to gtfs.FareProductID) {
+	registerID(&ctx.FareProductIDMapping, from, to)
+}
+
+// MapTimeframeGroupID returns the target ID of the source timeframe group
+// id and true, or id unchanged and false if it has not been mapped
+func (ctx *MergeContext) MapTimeframeGroupID(id gtfs.TimeframeGroupID) (gtfs.TimeframeGroupID, bool) {
+	return mapID(ctx.TimeframeGroupIDMapping, id)
+}
+
+// RegisterTimeframeGroupID records that the source timeframe group from is
+// to in the target
+func (ctx *MergeContext) RegisterTimeframeGroupID(from, to gtfs.TimeframeGroupID) {
+	registerID(&ctx.TimeframeGroupIDMapping, from, to)
+}
+
+// MapFareMediaID returns the target ID of the source fare media id and
+// true, or id unchanged and false if no fare media of the source has been
+// merged with it
+func (ctx *MergeContext) MapFareMediaID(id gtfs.FareMediaID) (gtfs.FareMediaID, bool) {
+	return mapID(ctx.FareMediaIDMapping, id)
+}
+
+// RegisterFareMediaID records that the source fare media from is to in the
+// target
+func (ctx *MergeContext) RegisterFareMediaID(from, to gtfs.FareMediaID) {
+	registerID(&ctx.FareMediaIDMapping, from, to)
+}
+
+// MapRiderCategoryID returns the target ID of the source rider category id
+// and true, or id unchanged and false if no rider category of the source
+// has been merged with it
+func (ctx *MergeContext) MapRiderCategoryID(id gtfs.RiderCategoryID) (gtfs.RiderCategoryID, bool) {
+	return mapID(ctx.RiderCategoryIDMapping, id)
+}
+
+// RegisterRiderCategoryID records that the source rider category from is
+// to in the target
+func (ctx *MergeContext) RegisterRiderCategoryID(from, to gtfs.RiderCategoryID) {
+	registerID(&ctx.RiderCategoryIDMapping, from, to)
+}
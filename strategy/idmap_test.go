@@ -0,0 +1,107 @@
+package strategy
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+func TestNewMergeContextMakesEveryMap(t *testing.T) {
+	// Given: a context from NewMergeContext
+	ctx := NewMergeContext(gtfs.NewFeed(), gtfs.NewFeed(), "a-")
+
+	// Then: every map field is made, but those whose nil value means
+	// something, which the merger sets
+	nilMeaning := map[string]bool{"AgencyEditions": true, "BlockedMatches": true, "NamespacedIDs": true}
+	v := reflect.ValueOf(ctx).Elem()
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		if field.Type.Kind() != reflect.Map || !field.IsExported() || nilMeaning[field.Name] {
+			continue
+		}
+		if v.Field(i).IsNil() {
+			t.Errorf("Expected MergeContext.%s to be made", field.Name)
+		}
+	}
+}
+
+func TestMapAndRegisterID(t *testing.T) {
+	// Given: a zero context, with no maps made
+	var ctx MergeContext
+
+	// When: a stop and a route are registered
+	ctx.RegisterStopID("s1", "a-s1")
+	ctx.RegisterRouteID("r1", "r2")
+
+	// Then: they map to their target IDs
+	if got, ok := ctx.MapStopID("s1"); !ok || got != "a-s1" {
+		t.Errorf("Expected s1 to map to a-s1, got %q, %v", got, ok)
+	}
+	if got, ok := ctx.MapRouteID("r1"); !ok || got != "r2" {
+		t.Errorf("Expected r1 to map to r2, got %q, %v", got, ok)
+	}
+
+	// And: IDs not registered are returned unchanged
+	if got, ok := ctx.MapStopID("s2"); ok || got != "s2" {
+		t.Errorf("Expected s2 to be unmapped, got %q, %v", got, ok)
+	}
+	if got, ok := ctx.MapTripID("t1"); ok || got != "t1" {
+		t.Errorf("Expected t1 to be unmapped, got %q, %v", got, ok)
+	}
+}
+
+func TestUniqueID(t *testing.T) {
+	// Given: a target with stop s1
+	target := gtfs.NewFeed()
+	target.Stops["s1"] = &gtfs.Stop{ID: "s1"}
+	ctx := NewMergeContext(gtfs.NewFeed(), target, "b-")
+
+	// Then: a colliding ID is prefixed, and others are kept
+	if got := UniqueID(ctx, ctx.Target.Stops, "s1"); got != "b-s1" {
+		t.Errorf("Expected b-s1, got %q", got)
+	}
+	if got := UniqueID(ctx, ctx.Target.Stops, "s2"); got != "s2" {
+		t.Errorf("Expected s2, got %q", got)
+	}
+	taken := func(id gtfs.ServiceID) bool { return id == "wk" }
+	if got := UniqueIDFunc(ctx, gtfs.ServiceID("wk"), taken); got != "b-wk" {
+		t.Errorf("Expected b-wk, got %q", got)
+	}
+}
+
+func TestUniqueIDAlwaysPrefix(t *testing.T) {
+	// Given: a target with stop s1, and a context prefixing every ID
+	target := gtfs.NewFeed()
+	target.Stops["s1"] = &gtfs.Stop{ID: "s1"}
+	ctx := NewMergeContext(gtfs.NewFeed(), target, "b-")
+	ctx.AlwaysPrefix = true
+
+	// Then: IDs are prefixed whether or not they collide
+	if got := UniqueID(ctx, ctx.Target.Stops, "s1"); got != "b-s1" {
+		t.Errorf("Expected b-s1, got %q", got)
+	}
+	if got := UniqueID(ctx, ctx.Target.Stops, "s2"); got != "b-s2" {
+		t.Errorf("Expected b-s2, got %q", got)
+	}
+}
+
+func TestUniqueIDPrefixedIDTaken(t *testing.T) {
+	// Given: a target with stop s1 and a stop already named b-s1
+	target := gtfs.NewFeed()
+	target.Stops["s1"] = &gtfs.Stop{ID: "s1"}
+	target.Stops["b-s1"] = &gtfs.Stop{ID: "b-s1"}
+	ctx := NewMergeContext(gtfs.NewFeed(), target, "b-")
+
+	// Then: s1 is prefixed until it is free
+	if got := UniqueID(ctx, ctx.Target.Stops, "s1"); got != "b-b-s1" {
+		t.Errorf("Expected b-b-s1, got %q", got)
+	}
+
+	// And: without a prefix, a taken ID gets the first free suffix
+	target.Stops["s1-2"] = &gtfs.Stop{ID: "s1-2"}
+	ctx.Prefix = ""
+	if got := UniqueID(ctx, ctx.Target.Stops, "s1"); got != "s1-3" {
+		t.Errorf("Expected s1-3, got %q", got)
+	}
+}
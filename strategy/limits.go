@@ -0,0 +1,143 @@
+package strategy
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// FuzzyLimits bounds the work fuzzy matching may do, so that a broken or
+// malicious feed (say, thousands of stops of the same name at the same
+// place) can't make a merge run for hours. A zero limit is unlimited.
+type FuzzyLimits struct {
+	// MaxCandidatesPerEntity is how many candidates are scored for one
+	// source entity; beyond it the best match so far is taken. Candidates
+	// are scored in ID order, so the ones kept don't depend on row order.
+	MaxCandidatesPerEntity int
+
+	// MaxComparisons is how many candidates may be scored in total while
+	// merging one file of one input feed; once it is reached, the file's
+	// remaining entities fall back to identity detection
+	MaxComparisons int
+}
+
+// DefaultFuzzyLimits returns limits well above what real feeds need: the
+// candidates of a stop are the stops within 500m, those of a trip the trips
+// on the same route and service
+func DefaultFuzzyLimits() FuzzyLimits {
+	return FuzzyLimits{
+		MaxCandidatesPerEntity: 10_000,
+		MaxComparisons:         50_000_000,
+	}
+}
+
+// Limits reported in FuzzyLimitHit
+const (
+	// FuzzyLimitCandidates is FuzzyLimits.MaxCandidatesPerEntity
+	FuzzyLimitCandidates = "candidates"
+
+	// FuzzyLimitComparisons is FuzzyLimits.MaxComparisons
+	FuzzyLimitComparisons = "comparisons"
+)
+
+// FuzzyLimitHit records that a fuzzy limit cut matching short while
+// merging one file of one input feed
+type FuzzyLimitHit struct {
+	// Entity is the kind of entity matched: "stop", "route" or "trip"
+	Entity string
+
+	// SourceFeed names the feed whose entities were being merged
+	SourceFeed string
+
+	// Limit is FuzzyLimitCandidates or FuzzyLimitComparisons, and Max its
+	// value
+	Limit string
+	Max   int
+
+	// Entities is the number of source entities affected: matched against
+	// only the first Max candidates, or matched by identity
+	Entities int
+}
+
+// String describes the hit for logs and warnings
+func (h FuzzyLimitHit) String() string {
+	if h.Limit == FuzzyLimitComparisons {
+		return fmt.Sprintf("feed %s: fuzzy %s matching reached %d comparisons; %d %ss matched by identity instead",
+			h.SourceFeed, h.Entity, h.Max, h.Entities, h.Entity)
+	}
+	return fmt.Sprintf("feed %s: %d %ss had more than %d fuzzy match candidates; only the first %d were scored",
+		h.SourceFeed, h.Entities, h.Entity, h.Max, h.Max)
+}
+
+// fuzzyBudget tracks the fuzzy comparisons made while merging one file of
+// one input feed against its FuzzyLimits
+type fuzzyBudget struct {
+	limits FuzzyLimits
+	used   int
+
+	capped   int // entities whose candidates were cut at MaxCandidatesPerEntity
+	fellBack int // entities matched by identity once MaxComparisons was reached
+}
+
+// newFuzzyBudget starts a budget for one file of one input feed
+func newFuzzyBudget(limits FuzzyLimits) *fuzzyBudget {
+	return &fuzzyBudget{limits: limits}
+}
+
+// exhausted reports whether MaxComparisons has been reached
+func (b *fuzzyBudget) exhausted() bool {
+	return b.limits.MaxComparisons > 0 && b.used >= b.limits.MaxComparisons
+}
+
+// detection returns the detection to use for the next source entity:
+// identity in place of fuzzy once the budget is exhausted
+func (b *fuzzyBudget) detection(d DuplicateDetection) DuplicateDetection {
+	if d == DetectionFuzzy && b.exhausted() {
+		b.fellBack++
+		return DetectionIdentity
+	}
+	return d
+}
+
+// limitCandidates returns the candidates of one source entity that fit the
+// budget, taking the lowest IDs when some must be dropped, and charges them
+// to it
+func limitCandidates[T any, ID cmp.Ordered](b *fuzzyBudget, candidates []T, id func(T) ID) []T {
+	n := len(candidates)
+	if limit := b.limits.MaxCandidatesPerEntity; limit > 0 && n > limit {
+		n = limit
+		b.capped++
+	}
+	if b.limits.MaxComparisons > 0 {
+		n = min(n, b.limits.MaxComparisons-b.used)
+	}
+	if n < len(candidates) {
+		candidates = slices.SortedStableFunc(slices.Values(candidates), func(x, y T) int {
+			return cmp.Compare(id(x), id(y))
+		})[:n]
+	}
+	b.used += n
+	return candidates
+}
+
+// recordFuzzyLimits records the limits the budget hit merging entity
+func (ctx *MergeContext) recordFuzzyLimits(entity string, b *fuzzyBudget) {
+	if b.capped > 0 {
+		ctx.FuzzyLimitHits = append(ctx.FuzzyLimitHits, FuzzyLimitHit{
+			Entity:     entity,
+			SourceFeed: ctx.SourceFeed,
+			Limit:      FuzzyLimitCandidates,
+			Max:        b.limits.MaxCandidatesPerEntity,
+			Entities:   b.capped,
+		})
+	}
+	if b.fellBack > 0 {
+		ctx.FuzzyLimitHits = append(ctx.FuzzyLimitHits, FuzzyLimitHit{
+			Entity:     entity,
+			SourceFeed: ctx.SourceFeed,
+			Limit:      FuzzyLimitComparisons,
+			Max:        b.limits.MaxComparisons,
+			Entities:   b.fellBack,
+		})
+	}
+}
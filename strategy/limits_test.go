@@ -0,0 +1,125 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+func TestStopMergeFuzzyCandidateLimit(t *testing.T) {
+	// Given: three target stops at the same place, only the highest ID with
+	// the source stop's name, and a limit of two candidates per stop
+	source := gtfs.NewFeed()
+	source.AddStop(&gtfs.Stop{ID: "src", Name: "Downtown Station", Lat: 40.7128, Lon: -74.0060})
+
+	target := gtfs.NewFeed()
+	target.AddStop(&gtfs.Stop{ID: "stop_c", Name: "Downtown Station", Lat: 40.7128, Lon: -74.0060})
+	target.AddStop(&gtfs.Stop{ID: "stop_a", Name: "Harbor Terminal", Lat: 40.7128, Lon: -74.0060})
+	target.AddStop(&gtfs.Stop{ID: "stop_b", Name: "Harbor Terminal", Lat: 40.7128, Lon: -74.0060})
+
+	ctx := NewMergeContext(source, target, "")
+	ctx.SourceFeed = "a"
+	strategy := NewStopMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionFuzzy)
+	strategy.SetFuzzyLimits(FuzzyLimits{MaxCandidatesPerEntity: 2})
+
+	// When: merged with DetectionFuzzy
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: only the two lowest IDs are scored, so the source stop is kept
+	if _, found := target.Stops["src"]; !found {
+		t.Errorf("Expected src to be added, got mapping %q", ctx.StopIDMapping["src"])
+	}
+
+	// And: the limit is recorded
+	want := FuzzyLimitHit{Entity: "stop", SourceFeed: "a", Limit: FuzzyLimitCandidates, Max: 2, Entities: 1}
+	if len(ctx.FuzzyLimitHits) != 1 || ctx.FuzzyLimitHits[0] != want {
+		t.Errorf("Expected hit %+v, got %+v", want, ctx.FuzzyLimitHits)
+	}
+}
+
+func TestStopMergeFuzzyComparisonLimit(t *testing.T) {
+	// Given: two source stops matching the same target stop, and a budget
+	// of one comparison
+	source := gtfs.NewFeed()
+	source.AddStop(&gtfs.Stop{ID: "src_1", Name: "Downtown Station", Lat: 40.7128, Lon: -74.0060})
+	source.AddStop(&gtfs.Stop{ID: "src_2", Name: "Downtown Station", Lat: 40.7128, Lon: -74.0060})
+
+	target := gtfs.NewFeed()
+	target.AddStop(&gtfs.Stop{ID: "stop", Name: "Downtown Station", Lat: 40.7128, Lon: -74.0060})
+
+	ctx := NewMergeContext(source, target, "")
+	ctx.SourceFeed = "a"
+	strategy := NewStopMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionFuzzy)
+	strategy.SetFuzzyLimits(FuzzyLimits{MaxComparisons: 1})
+
+	// When: merged with DetectionFuzzy
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the first stop is fuzzy matched and the second, past the
+	// budget, is matched by identity, so kept
+	if ctx.StopIDMapping["src_1"] != "stop" {
+		t.Errorf("Expected StopIDMapping[src_1] = stop, got %q", ctx.StopIDMapping["src_1"])
+	}
+	if _, found := target.Stops["src_2"]; !found {
+		t.Errorf("Expected src_2 to be added, got mapping %q", ctx.StopIDMapping["src_2"])
+	}
+
+	// And: the fallback is recorded
+	want := FuzzyLimitHit{Entity: "stop", SourceFeed: "a", Limit: FuzzyLimitComparisons, Max: 1, Entities: 1}
+	if len(ctx.FuzzyLimitHits) != 1 || ctx.FuzzyLimitHits[0] != want {
+		t.Errorf("Expected hit %+v, got %+v", want, ctx.FuzzyLimitHits)
+	}
+}
+
+func TestTripMergeFuzzyCandidatesShareRouteAndService(t *testing.T) {
+	// Given: a source trip and target trips on other routes and services,
+	// with a limit of one candidate per trip
+	source := gtfs.NewFeed()
+	source.AddTrip(&gtfs.Trip{ID: "src", RouteID: "r1", ServiceID: "wk"})
+
+	target := gtfs.NewFeed()
+	target.AddTrip(&gtfs.Trip{ID: "t1", RouteID: "r2", ServiceID: "wk"})
+	target.AddTrip(&gtfs.Trip{ID: "t2", RouteID: "r1", ServiceID: "sat"})
+	target.AddTrip(&gtfs.Trip{ID: "t3", RouteID: "r1", ServiceID: "wk"})
+
+	ctx := NewMergeContext(source, target, "")
+	strategy := NewTripMergeStrategy()
+	strategy.SetFuzzyLimits(FuzzyLimits{MaxCandidatesPerEntity: 1})
+
+	// When: the trip is matched
+	budget := newFuzzyBudget(strategy.FuzzyLimits)
+	strategy.findFuzzyMatch(ctx, source.Trips["src"], budget)
+
+	// Then: only the trip on the same route and service is a candidate, so
+	// the limit isn't reached
+	if budget.used != 1 || budget.capped != 0 {
+		t.Errorf("Expected 1 candidate and no cap, got %d and %d", budget.used, budget.capped)
+	}
+}
+
+func TestFuzzyLimitHitString(t *testing.T) {
+	tests := []struct {
+		hit  FuzzyLimitHit
+		want string
+	}{
+		{
+			FuzzyLimitHit{Entity: "stop", SourceFeed: "a", Limit: FuzzyLimitCandidates, Max: 2, Entities: 3},
+			"feed a: 3 stops had more than 2 fuzzy match candidates; only the first 2 were scored",
+		},
+		{
+			FuzzyLimitHit{Entity: "trip", SourceFeed: "b", Limit: FuzzyLimitComparisons, Max: 10, Entities: 4},
+			"feed b: fuzzy trip matching reached 10 comparisons; 4 trips matched by identity instead",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.hit.String(); got != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package strategy
+
+import (
+	"strings"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// StripIDPrefix returns id without the first of prefixes it starts with,
+// or id unchanged if it starts with none
+func StripIDPrefix(id string, prefixes []string) string {
+	for _, p := range prefixes {
+		if p != "" && strings.HasPrefix(id, p) {
+			return strings.TrimPrefix(id, p)
+		}
+	}
+	return id
+}
+
+// identityMatch returns the ID of the entity of kind in target, the target
+// feed's entities of that kind, that the source entity id duplicates by
+// identity: the one with the same ID or, failing that, the one whose ID is
+// the same once each side's namespace prefix is stripped (see
+// MergeContext.StripIDPrefixes and NamespacedIDs)
+func identityMatch[ID ~string, E any](ctx *MergeContext, kind gtfs.EntityKind, id ID, target map[ID]E) (ID, bool) {
+	if _, ok := target[id]; ok {
+		return id, true
+	}
+	if ctx.NamespacedIDs == nil {
+		return "", false
+	}
+	match, ok := ctx.NamespacedIDs[kind][StripIDPrefix(string(id), ctx.StripIDPrefixes)]
+	if !ok {
+		return "", false
+	}
+	if _, ok := target[ID(match)]; !ok {
+		return "", false
+	}
+	return ID(match), true
+}
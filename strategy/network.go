@@ -0,0 +1,141 @@
+package strategy
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// NetworkMergeStrategy handles merging of networks between feeds. Network IDs
+// that routes.txt uses without a networks.txt row are mapped the same way,
+// so that route network_id values stay consistent with networks.txt.
+type NetworkMergeStrategy struct {
+	BaseStrategy
+}
+
+// NewNetworkMergeStrategy creates a new NetworkMergeStrategy
+func NewNetworkMergeStrategy() *NetworkMergeStrategy {
+	return &NetworkMergeStrategy{
+		BaseStrategy: NewBaseStrategy("network"),
+	}
+}
+
+// Merge performs the merge operation for networks
+func (s *NetworkMergeStrategy) Merge(ctx *MergeContext) error {
+	// IDs used only by routes.network_id collide like networks.txt rows
+	used := targetNetworkIDs(ctx.Target)
+
+	// Iterate in insertion order to match Java output
+	for i, networkID := range ctx.Source.NetworkOrder {
+		if err := ctx.checkCanceled(i); err != nil {
+			return err
+		}
+		network := ctx.Source.Networks[networkID]
+		// Check for duplicates based on detection mode
+		if s.DuplicateDetection == DetectionIdentity {
+			if existingID, found := identityMatch(ctx, gtfs.KindNetwork, network.ID, ctx.Target.Networks); found && !ctx.blocked(gtfs.KindNetwork, string(network.ID), string(existingID)) {
+				// Duplicate detected - map source ID to existing target ID
+				ctx.RegisterNetworkID(network.ID, existingID)
+
+				// Handle logging based on configuration
+				switch s.DuplicateLogging {
+				case LogWarning:
+					log.Printf("WARNING: Duplicate network detected with ID %q (keeping existing)", network.ID)
+				case LogError:
+					return fmt.Errorf("duplicate network detected with ID %q", network.ID)
+				}
+
+				// Skip adding this network - use the existing one
+				ctx.countDeduplicated("networks.txt", 1)
+				continue
+			}
+		}
+
+		// Determine new ID - only apply prefix if there's a collision
+		newID := UniqueIDFunc(ctx, network.ID, func(id gtfs.NetworkID) bool { return used[id] })
+		ctx.RegisterNetworkID(network.ID, newID)
+		used[newID] = true
+
+		ctx.Target.Networks[newID] = &gtfs.Network{
+			ID:   newID,
+			Name: network.Name,
+		}
+		ctx.Target.NetworkOrder = append(ctx.Target.NetworkOrder, newID)
+	}
+
+	// Map the network IDs routes use without a networks.txt row
+	for _, routeID := range ctx.Source.RouteOrder {
+		id := ctx.Source.Routes[routeID].NetworkID
+		if _, mapped := ctx.MapNetworkID(id); id == "" || mapped {
+			continue
+		}
+		newID := UniqueIDFunc(ctx, id, func(id gtfs.NetworkID) bool {
+			return used[id] && s.DuplicateDetection != DetectionIdentity
+		})
+		ctx.RegisterNetworkID(id, newID)
+		used[newID] = true
+	}
+
+	return nil
+}
+
+// targetNetworkIDs returns the network IDs the target feed already uses, in
+// networks.txt or routes.txt
+func targetNetworkIDs(target *gtfs.Feed) map[gtfs.NetworkID]bool {
+	used := make(map[gtfs.NetworkID]bool, len(target.Networks))
+	for id := range target.Networks {
+		used[id] = true
+	}
+	for _, route := range target.Routes {
+		if route.NetworkID != "" {
+			used[route.NetworkID] = true
+		}
+	}
+	return used
+}
+
+// RouteNetworkMergeStrategy handles merging of route_networks between feeds.
+// Each row's route_id and network_id are remapped; a route already assigned
+// to a network in the target (because it was merged as a duplicate) keeps
+// that assignment.
+type RouteNetworkMergeStrategy struct {
+	BaseStrategy
+}
+
+// NewRouteNetworkMergeStrategy creates a new RouteNetworkMergeStrategy
+func NewRouteNetworkMergeStrategy() *RouteNetworkMergeStrategy {
+	return &RouteNetworkMergeStrategy{
+		BaseStrategy: NewBaseStrategy("route_network"),
+	}
+}
+
+// Merge performs the merge operation for route_networks
+func (s *RouteNetworkMergeStrategy) Merge(ctx *MergeContext) error {
+	// route_id is the primary key of route_networks.txt
+	assigned := make(map[gtfs.RouteID]bool, len(ctx.Target.RouteNetworks))
+	for _, rn := range ctx.Target.RouteNetworks {
+		assigned[rn.RouteID] = true
+	}
+
+	for i, rn := range ctx.Source.RouteNetworks {
+		if err := ctx.checkCanceled(i); err != nil {
+			return err
+		}
+		routeID, _ := ctx.MapRouteID(rn.RouteID)
+		networkID, _ := ctx.MapNetworkID(rn.NetworkID)
+
+		if assigned[routeID] {
+			ctx.countDeduplicated("route_networks.txt", 1)
+			continue
+		}
+		assigned[routeID] = true
+
+		ctx.Target.RouteNetworks = append(ctx.Target.RouteNetworks, &gtfs.RouteNetwork{
+			NetworkID: networkID,
+			RouteID:   routeID,
+		})
+	}
+
+	return nil
+}
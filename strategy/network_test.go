@@ -0,0 +1,120 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+func TestNetworkMergeCollisionPrefixed(t *testing.T) {
+	// Given: both feeds have a network with ID "standard"
+	source := gtfs.NewFeed()
+	source.AddNetwork(&gtfs.Network{ID: "standard", Name: "Suburban Standard"})
+	source.AddNetwork(&gtfs.Network{ID: "night", Name: "Night"})
+
+	target := gtfs.NewFeed()
+	target.AddNetwork(&gtfs.Network{ID: "standard", Name: "City Standard"})
+
+	ctx := NewMergeContext(source, target, "a-")
+	strategy := NewNetworkMergeStrategy()
+
+	// When: merged without duplicate detection
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the colliding network is prefixed and the other is kept as is
+	if target.Networks["a-standard"] == nil || target.Networks["a-standard"].Name != "Suburban Standard" {
+		t.Errorf("Expected a-standard in target, got %v", target.NetworkOrder)
+	}
+	if ctx.NetworkIDMapping["standard"] != "a-standard" || ctx.NetworkIDMapping["night"] != "night" {
+		t.Errorf("Expected standard→a-standard and night→night, got %v", ctx.NetworkIDMapping)
+	}
+}
+
+func TestNetworkMergeIdentityDuplicate(t *testing.T) {
+	// Given: both feeds have a network with ID "standard"
+	source := gtfs.NewFeed()
+	source.AddNetwork(&gtfs.Network{ID: "standard", Name: "Standard"})
+
+	target := gtfs.NewFeed()
+	target.AddNetwork(&gtfs.Network{ID: "standard", Name: "Standard"})
+
+	ctx := NewMergeContext(source, target, "a-")
+	strategy := NewNetworkMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionIdentity)
+
+	// When: merged with DetectionIdentity
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the existing network is kept
+	if len(target.Networks) != 1 || ctx.NetworkIDMapping["standard"] != "standard" {
+		t.Errorf("Expected one network mapped to itself, got %v and %v", target.NetworkOrder, ctx.NetworkIDMapping)
+	}
+}
+
+func TestNetworkMergeRouteNetworkIDWithoutNetworksFile(t *testing.T) {
+	// Given: routes in both feeds use network_id "1" without networks.txt
+	source := gtfs.NewFeed()
+	source.AddRoute(&gtfs.Route{ID: "r2", ShortName: "2", Type: 3, NetworkID: "1"})
+
+	target := gtfs.NewFeed()
+	target.AddRoute(&gtfs.Route{ID: "r1", ShortName: "1", Type: 3, NetworkID: "1"})
+
+	ctx := NewMergeContext(source, target, "a-")
+
+	// When: networks, then routes, are merged
+	if err := NewNetworkMergeStrategy().Merge(ctx); err != nil {
+		t.Fatalf("Network merge failed: %v", err)
+	}
+	if err := NewRouteMergeStrategy().Merge(ctx); err != nil {
+		t.Fatalf("Route merge failed: %v", err)
+	}
+
+	// Then: the source's network_id is prefixed like a colliding network
+	if got := target.Routes["r2"].NetworkID; got != "a-1" {
+		t.Errorf("Expected network_id a-1, got %q", got)
+	}
+	if len(target.Networks) != 0 {
+		t.Errorf("Expected no networks.txt rows, got %v", target.NetworkOrder)
+	}
+}
+
+func TestRouteNetworkMergeRemapsReferences(t *testing.T) {
+	// Given: route_networks whose route and network were renamed, and one
+	// whose route was merged into a route that already has a network
+	source := gtfs.NewFeed()
+	source.RouteNetworks = []*gtfs.RouteNetwork{
+		{NetworkID: "standard", RouteID: "local"},
+		{NetworkID: "standard", RouteID: "shared"},
+	}
+
+	target := gtfs.NewFeed()
+	target.RouteNetworks = []*gtfs.RouteNetwork{{NetworkID: "city", RouteID: "shared"}}
+
+	ctx := NewMergeContext(source, target, "a-")
+	ctx.RouteIDMapping["local"] = "a-local"
+	ctx.RouteIDMapping["shared"] = "shared"
+	ctx.NetworkIDMapping["standard"] = "a-standard"
+
+	// When: merged
+	if err := NewRouteNetworkMergeStrategy().Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: references are remapped and the merged route keeps its network
+	expected := []gtfs.RouteNetwork{
+		{NetworkID: "city", RouteID: "shared"},
+		{NetworkID: "a-standard", RouteID: "a-local"},
+	}
+	if len(target.RouteNetworks) != len(expected) {
+		t.Fatalf("Expected %d route_networks, got %d", len(expected), len(target.RouteNetworks))
+	}
+	for i, rn := range target.RouteNetworks {
+		if *rn != expected[i] {
+			t.Errorf("Expected %+v at %d, got %+v", expected[i], i, *rn)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package strategy
+
+import (
+	"strings"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// Normalizer rewrites a field value before duplicate detection compares it,
+// so cosmetic differences such as trailing whitespace don't prevent a match.
+// Normalizers are applied to copies of the values compared; the merged feed
+// keeps the original values unless MergeContext.NormalizeOutput is set.
+// Implementations must be safe for concurrent use, since fuzzy matching may
+// score candidates in parallel.
+type Normalizer interface {
+	Normalize(s string) string
+}
+
+// NormalizerFunc adapts an ordinary function to a Normalizer
+type NormalizerFunc func(string) string
+
+// Normalize returns f(s)
+func (f NormalizerFunc) Normalize(s string) string {
+	return f(s)
+}
+
+// NormalizerChain applies each of its normalizers in order
+type NormalizerChain []Normalizer
+
+// Normalize returns s after every normalizer in the chain
+func (c NormalizerChain) Normalize(s string) string {
+	for _, n := range c {
+		s = n.Normalize(s)
+	}
+	return s
+}
+
+var (
+	// TrimSpace removes leading and trailing whitespace: "Main St " → "Main St"
+	TrimSpace Normalizer = NormalizerFunc(strings.TrimSpace)
+
+	// CollapseSpaces replaces each run of whitespace with a single space and
+	// trims the ends: "Main  St" → "Main St"
+	CollapseSpaces Normalizer = NormalizerFunc(collapseSpaces)
+
+	// CaseFold lowercases the value: "MAIN ST" → "main st"
+	CaseFold Normalizer = NormalizerFunc(strings.ToLower)
+
+	// StripLeadingZeros removes the leading zeros of a value made only of
+	// digits, keeping one for zero: "05" → "5", "000" → "0". Other values,
+	// such as "05A", are unchanged.
+	StripLeadingZeros Normalizer = NormalizerFunc(stripLeadingZeros)
+)
+
+// collapseSpaces implements CollapseSpaces
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// stripLeadingZeros implements StripLeadingZeros
+func stripLeadingZeros(s string) string {
+	if s == "" {
+		return s
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return s
+		}
+	}
+	if trimmed := strings.TrimLeft(s, "0"); trimmed != "" {
+		return trimmed
+	}
+	return "0"
+}
+
+// DefaultNormalizer returns the suggested normalizer for the fields a
+// strategy compares, or nil for kinds whose strategies compare no text:
+//
+//   - stops (stop_name): CollapseSpaces, CaseFold
+//   - routes (route_short_name, route_long_name, route_desc): CollapseSpaces,
+//     StripLeadingZeros. Case is left alone; see
+//     RouteMergeStrategy.LongNameCaseInsensitive.
+//   - areas (area_name): CollapseSpaces, CaseFold. Fuzzy area matching
+//     ignores spacing and case regardless, so this only matters for
+//     MergeContext.NormalizeOutput.
+//
+// CollapseSpaces also trims, so TrimSpace is not needed alongside it.
+func DefaultNormalizer(kind gtfs.EntityKind) Normalizer {
+	switch kind {
+	case gtfs.KindStop, gtfs.KindArea:
+		return NormalizerChain{CollapseSpaces, CaseFold}
+	case gtfs.KindRoute:
+		return NormalizerChain{CollapseSpaces, StripLeadingZeros}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,47 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+func TestNormalizers(t *testing.T) {
+	tests := []struct {
+		name       string
+		normalizer Normalizer
+		in, want   string
+	}{
+		{"trim", TrimSpace, "  Main St ", "Main St"},
+		{"collapse", CollapseSpaces, " Main \t St  ", "Main St"},
+		{"case fold", CaseFold, "MAIN St", "main st"},
+		{"zeros", StripLeadingZeros, "05", "5"},
+		{"all zeros", StripLeadingZeros, "000", "0"},
+		{"not numeric", StripLeadingZeros, "05A", "05A"},
+		{"empty", StripLeadingZeros, "", ""},
+		{"chain", NormalizerChain{CollapseSpaces, CaseFold}, "Main  ST ", "main st"},
+		{"empty chain", NormalizerChain{}, "Main St ", "Main St "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.normalizer.Normalize(tt.in); got != tt.want {
+				t.Errorf("Normalize(%q): expected %q, got %q", tt.in, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDefaultNormalizer(t *testing.T) {
+	if got := DefaultNormalizer(gtfs.KindStop).Normalize(" Main  ST "); got != "main st" {
+		t.Errorf("Expected stop names trimmed, collapsed and folded, got %q", got)
+	}
+	if got := DefaultNormalizer(gtfs.KindRoute).Normalize(" 05 "); got != "5" {
+		t.Errorf("Expected route short names without leading zeros, got %q", got)
+	}
+	if got := DefaultNormalizer(gtfs.KindRoute).Normalize("Downtown"); got != "Downtown" {
+		t.Errorf("Expected route names to keep their case, got %q", got)
+	}
+	if DefaultNormalizer(gtfs.KindShape) != nil {
+		t.Error("Expected no default normalizer for shapes")
+	}
+}
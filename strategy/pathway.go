@@ -24,17 +24,14 @@ func (s *PathwayMergeStrategy) Merge(ctx *MergeContext) error {
 		existingIDs[existing.ID] = true
 	}
 
-	for _, pathway := range ctx.Source.Pathways {
-		// Map stop references
-		fromStopID := pathway.FromStopID
-		if mappedStop, ok := ctx.StopIDMapping[fromStopID]; ok {
-			fromStopID = mappedStop
+	for i, pathway := range ctx.Source.Pathways {
+		if err := ctx.checkCanceled(i); err != nil {
+			return err
 		}
+		// Map stop references
+		fromStopID, _ := ctx.MapStopID(pathway.FromStopID)
 
-		toStopID := pathway.ToStopID
-		if mappedStop, ok := ctx.StopIDMapping[toStopID]; ok {
-			toStopID = mappedStop
-		}
+		toStopID, _ := ctx.MapStopID(pathway.ToStopID)
 
 		// Check for duplicates/collisions using O(1) lookup
 		hasCollision := existingIDs[pathway.ID]
@@ -43,10 +40,7 @@ func (s *PathwayMergeStrategy) Merge(ctx *MergeContext) error {
 		}
 
 		// Only apply prefix if there's a collision
-		newID := pathway.ID
-		if hasCollision {
-			newID = ctx.Prefix + pathway.ID
-		}
+		newID := UniqueIDFunc(ctx, pathway.ID, func(id string) bool { return existingIDs[id] })
 
 		// Add to index for subsequent source items
 		existingIDs[newID] = true
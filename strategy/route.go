@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"sort"
+	"strings"
 
 	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
 )
@@ -13,8 +14,18 @@ type RouteMergeStrategy struct {
 	BaseStrategy
 	// FuzzyThreshold is the minimum score for a fuzzy match (default 0.5)
 	FuzzyThreshold float64
+	// LongNameCaseInsensitive relaxes the route_long_name comparison to ignore
+	// case. By default long names must match exactly, so "Downtown" and
+	// "Downtown Express" are never treated as the same route.
+	LongNameCaseInsensitive bool
+	// StopScoring selects how the stops two routes serve are scored
+	// (default RouteStopsOverlap)
+	StopScoring RouteStopScoring
 	// Concurrent controls concurrent processing for fuzzy matching
 	Concurrent ConcurrentConfig
+	// FuzzyLimits bounds the fuzzy matching done per input feed (default
+	// DefaultFuzzyLimits)
+	FuzzyLimits FuzzyLimits
 }
 
 // NewRouteMergeStrategy creates a new RouteMergeStrategy
@@ -23,9 +34,25 @@ func NewRouteMergeStrategy() *RouteMergeStrategy {
 		BaseStrategy:   NewBaseStrategy("route"),
 		FuzzyThreshold: 0.5,
 		Concurrent:     DefaultConcurrentConfig(),
+		FuzzyLimits:    DefaultFuzzyLimits(),
 	}
 }
 
+// SetFuzzyThreshold sets the minimum composite score for a fuzzy match
+func (s *RouteMergeStrategy) SetFuzzyThreshold(threshold float64) {
+	s.FuzzyThreshold = threshold
+}
+
+// SetStopScoring sets StopScoring
+func (s *RouteMergeStrategy) SetStopScoring(scoring RouteStopScoring) {
+	s.StopScoring = scoring
+}
+
+// SetFuzzyLimits sets FuzzyLimits
+func (s *RouteMergeStrategy) SetFuzzyLimits(limits FuzzyLimits) {
+	s.FuzzyLimits = limits
+}
+
 // SetConcurrent enables or disables concurrent fuzzy matching
 func (s *RouteMergeStrategy) SetConcurrent(enabled bool) {
 	s.Concurrent.Enabled = enabled
@@ -48,13 +75,18 @@ func (s *RouteMergeStrategy) Merge(ctx *MergeContext) error {
 		return sortedRouteIDs[i] < sortedRouteIDs[j]
 	})
 
-	for _, routeID := range sortedRouteIDs {
+	budget := newFuzzyBudget(s.FuzzyLimits)
+	for i, routeID := range sortedRouteIDs {
+		if err := ctx.checkCanceled(i); err != nil {
+			return err
+		}
 		route := ctx.Source.Routes[routeID]
+		detection := budget.detection(s.DuplicateDetection)
 		// Check for duplicates based on detection mode
-		if s.DuplicateDetection == DetectionIdentity {
-			if existing, found := ctx.Target.Routes[route.ID]; found {
+		if detection == DetectionIdentity {
+			if existingID, found := identityMatch(ctx, gtfs.KindRoute, route.ID, ctx.Target.Routes); found && !ctx.blocked(gtfs.KindRoute, string(route.ID), string(existingID)) {
 				// Duplicate detected - map source ID to existing target ID
-				ctx.RouteIDMapping[route.ID] = existing.ID
+				ctx.RegisterRouteID(route.ID, existingID)
 
 				// Handle logging based on configuration
 				switch s.DuplicateLogging {
@@ -65,15 +97,23 @@ func (s *RouteMergeStrategy) Merge(ctx *MergeContext) error {
 				}
 
 				// Skip adding this route - use the existing one
+				ctx.countDeduplicated("routes.txt", 1)
 				continue
 			}
 		}
 
 		// Check for fuzzy duplicates
-		if s.DuplicateDetection == DetectionFuzzy {
-			if matchID := s.findFuzzyMatch(ctx, route); matchID != "" {
+		if detection == DetectionFuzzy {
+			matchID, score := s.findFuzzyMatch(ctx, route, budget)
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if !ctx.acceptFuzzyMatch("route", string(route.ID), string(matchID), score, s.FuzzyThreshold) {
+				matchID = ""
+			}
+			if matchID != "" && !ctx.blocked(gtfs.KindRoute, string(route.ID), string(matchID)) {
 				// Fuzzy duplicate detected - map source ID to existing target ID
-				ctx.RouteIDMapping[route.ID] = matchID
+				ctx.RegisterRouteID(route.ID, matchID)
 
 				switch s.DuplicateLogging {
 				case LogWarning:
@@ -83,32 +123,39 @@ func (s *RouteMergeStrategy) Merge(ctx *MergeContext) error {
 				}
 
 				// Skip adding this route - use the existing one
+				ctx.countDeduplicated("routes.txt", 1)
 				continue
 			}
 		}
 
 		// Determine new ID - only apply prefix if there's a collision
-		newID := route.ID
-		if _, exists := ctx.Target.Routes[route.ID]; exists {
-			// Collision detected - apply prefix
-			newID = gtfs.RouteID(ctx.Prefix + string(route.ID))
+		newID := UniqueID(ctx, ctx.Target.Routes, route.ID)
+		ctx.RegisterRouteID(route.ID, newID)
+
+		// Map agency reference. An empty agency_id is mapped too, to the
+		// ID its feed's only agency was given (see sourceAgencyID).
+		agencyID := ctx.sourceAgencyID(route.AgencyID)
+		if mappedAgency, ok := ctx.MapAgencyID(agencyID); ok {
+			agencyID = mappedAgency
+			if route.AgencyID == "" {
+				ctx.Target.AddColumn("routes.txt", "agency_id")
+			}
 		}
-		ctx.RouteIDMapping[route.ID] = newID
 
-		// Map agency reference
-		agencyID := route.AgencyID
-		if agencyID != "" {
-			if mappedAgency, ok := ctx.AgencyIDMapping[agencyID]; ok {
-				agencyID = mappedAgency
-			}
+		// Map network reference
+		networkID, _ := ctx.MapNetworkID(route.NetworkID)
+
+		shortName, longName, desc := route.ShortName, route.LongName, route.Desc
+		if ctx.NormalizeOutput {
+			shortName, longName, desc = s.normalize(shortName), s.normalize(longName), s.normalize(desc)
 		}
 
 		newRoute := &gtfs.Route{
 			ID:                newID,
 			AgencyID:          agencyID,
-			ShortName:         route.ShortName,
-			LongName:          route.LongName,
-			Desc:              route.Desc,
+			ShortName:         shortName,
+			LongName:          longName,
+			Desc:              desc,
 			Type:              route.Type,
 			URL:               route.URL,
 			Color:             route.Color,
@@ -116,43 +163,47 @@ func (s *RouteMergeStrategy) Merge(ctx *MergeContext) error {
 			SortOrder:         route.SortOrder,
 			ContinuousPickup:  route.ContinuousPickup,
 			ContinuousDropOff: route.ContinuousDropOff,
+			NetworkID:         networkID,
 		}
 		ctx.Target.Routes[newID] = newRoute
 		ctx.Target.RouteOrder = append(ctx.Target.RouteOrder, newID)
 		ctx.JustAddedRoutes[newID] = struct{}{} // Track as just added for fuzzy matching
 	}
 
+	ctx.recordFuzzyLimits("route", budget)
 	return nil
 }
 
 // findFuzzyMatch searches for a fuzzy duplicate in the target routes.
-// Returns the ID of the matching route if found, or empty string if no match.
-// Uses agency, short_name, long_name matching combined with shared stops (multiplicative scoring).
-// Supports concurrent processing when enabled.
-func (s *RouteMergeStrategy) findFuzzyMatch(ctx *MergeContext, source *gtfs.Route) gtfs.RouteID {
-	// Convert map to slice for concurrent processing
+// Returns the ID and score of the best matching route, or empty string if no
+// route scores at least the search threshold (see fuzzySearchThreshold).
+// Ties go to the lowest route ID (see betterMatch). The routes scored are
+// charged to budget. Supports concurrent processing when enabled.
+func (s *RouteMergeStrategy) findFuzzyMatch(ctx *MergeContext, source *gtfs.Route, budget *fuzzyBudget) (gtfs.RouteID, float64) {
+	threshold := ctx.fuzzySearchThreshold(s.FuzzyThreshold)
+
+	// Skip routes added in this feed (matching Java behavior). Candidates
+	// are taken in map order: betterMatch breaks ties on the lowest ID, and
+	// limitCandidates keeps the lowest IDs, so the order doesn't change the
+	// match.
 	targets := make([]*gtfs.Route, 0, len(ctx.Target.Routes))
-	for _, route := range ctx.Target.Routes {
-		targets = append(targets, route)
+	for id, target := range ctx.Target.Routes {
+		if _, justAdded := ctx.JustAddedRoutes[id]; !justAdded || ctx.IntraFeed {
+			targets = append(targets, target)
+		}
 	}
+	targets = limitCandidates(budget, targets, func(route *gtfs.Route) gtfs.RouteID { return route.ID })
 
 	// Use concurrent matching if enabled and enough items
 	if s.Concurrent.Enabled && len(targets) >= s.Concurrent.MinItemsForConcurrency {
 		return findBestMatchConcurrent(
+			ctx.Context(),
 			targets,
 			func(route *gtfs.Route) gtfs.RouteID { return route.ID },
 			func(target *gtfs.Route) float64 {
-				// Skip routes added in this feed (matching Java behavior)
-				if _, justAdded := ctx.JustAddedRoutes[target.ID]; justAdded {
-					return 0.0
-				}
-				agencyScore := routeAgencyScore(ctx, source, target)
-				shortNameScore := routePropertyScore(source.ShortName, target.ShortName)
-				longNameScore := routePropertyScore(source.LongName, target.LongName)
-				stopsScore := routeStopsInCommonScore(ctx, source.ID, target.ID)
-				return agencyScore * shortNameScore * longNameScore * stopsScore
+				return s.fuzzyScore(ctx, source, target)
 			},
-			s.FuzzyThreshold,
+			threshold,
 			s.Concurrent,
 		)
 	}
@@ -162,27 +213,46 @@ func (s *RouteMergeStrategy) findFuzzyMatch(ctx *MergeContext, source *gtfs.Rout
 	var bestScore float64
 
 	for _, target := range targets {
-		// Skip routes added in this feed (matching Java behavior)
-		if _, justAdded := ctx.JustAddedRoutes[target.ID]; justAdded {
-			continue
+		score := s.fuzzyScore(ctx, source, target)
+		if score >= threshold && betterMatch(score, target.ID, bestScore, bestMatch) {
+			bestScore = score
+			bestMatch = target.ID
 		}
+	}
 
-		// Calculate combined score: agency * shortName * longName * stopsInCommon
-		agencyScore := routeAgencyScore(ctx, source, target)
-		shortNameScore := routePropertyScore(source.ShortName, target.ShortName)
-		longNameScore := routePropertyScore(source.LongName, target.LongName)
-		stopsScore := routeStopsInCommonScore(ctx, source.ID, target.ID)
+	return bestMatch, bestScore
+}
 
-		// Multiplicative scoring - any 0 fails the match
-		score := agencyScore * shortNameScore * longNameScore * stopsScore
+// fuzzyScore computes the composite score for two routes:
+// agency * route_type * short_name * long_name * route_desc * route_color * stopsInCommon.
+// Scoring is multiplicative, so any mismatching property vetoes the match.
+// route_type must always match; the remaining properties are neutral when
+// either side is empty. Under RouteStopsTerminals, routes that don't share
+// their terminals (see routesShareTerminals) score 0.
+func (s *RouteMergeStrategy) fuzzyScore(ctx *MergeContext, source, target *gtfs.Route) float64 {
+	if source.Type != target.Type {
+		return 0.0
+	}
 
-		if score >= s.FuzzyThreshold && score > bestScore {
-			bestScore = score
-			bestMatch = target.ID
-		}
+	sourceLong, targetLong := s.normalize(source.LongName), s.normalize(target.LongName)
+	if s.LongNameCaseInsensitive {
+		sourceLong, targetLong = strings.ToLower(sourceLong), strings.ToLower(targetLong)
 	}
 
-	return bestMatch
+	return routeAgencyScore(ctx, source, target) *
+		routePropertyScore(s.normalize(source.ShortName), s.normalize(target.ShortName)) *
+		routePropertyScore(sourceLong, targetLong) *
+		routePropertyScore(s.normalize(source.Desc), s.normalize(target.Desc)) *
+		routePropertyScore(gtfs.NormalizeColor(source.Color), gtfs.NormalizeColor(target.Color)) *
+		routeStopsScore(ctx, source.ID, target.ID, s.StopScoring)
+}
+
+// routeStopsScore scores the stops served by two routes under scoring
+func routeStopsScore(ctx *MergeContext, sourceRouteID, targetRouteID gtfs.RouteID, scoring RouteStopScoring) float64 {
+	if scoring == RouteStopsTerminals && !routesShareTerminals(ctx, sourceRouteID, targetRouteID) {
+		return 0.0
+	}
+	return routeStopsInCommonScore(ctx, sourceRouteID, targetRouteID)
 }
 
 // routeAgencyScore returns 1.0 if agencies match (considering mappings), 0.0 otherwise.
@@ -193,10 +263,7 @@ func routeAgencyScore(ctx *MergeContext, source, target *gtfs.Route) float64 {
 	}
 
 	// Get mapped agency ID for source
-	mappedSourceAgency := source.AgencyID
-	if mapped, ok := ctx.AgencyIDMapping[source.AgencyID]; ok {
-		mappedSourceAgency = mapped
-	}
+	mappedSourceAgency, _ := ctx.MapAgencyID(source.AgencyID)
 
 	if mappedSourceAgency == target.AgencyID {
 		return 1.0
@@ -251,6 +318,69 @@ func getStopsForRoute(feed *gtfs.Feed, routeID gtfs.RouteID) []gtfs.StopID {
 	return stops
 }
 
+// routesShareTerminals reports whether each route has a trip whose first
+// and last stops are both terminals of the other route, that is, stops at
+// which one of its trips starts or ends. Two branches sharing a trunk share
+// only the terminal at the trunk's end, so they don't.
+func routesShareTerminals(ctx *MergeContext, sourceRouteID, targetRouteID gtfs.RouteID) bool {
+	sourceEnds := getTripEndsForRoute(ctx.Source, sourceRouteID)
+	targetEnds := getTripEndsForRoute(ctx.Target, targetRouteID)
+	return tripEndsWithin(sourceEnds, targetEnds) && tripEndsWithin(targetEnds, sourceEnds)
+}
+
+// tripEndsWithin reports whether any of ends has both its stops among
+// the terminals of others
+func tripEndsWithin(ends, others [][2]gtfs.StopID) bool {
+	terminals := make(map[gtfs.StopID]struct{}, 2*len(others))
+	for _, e := range others {
+		terminals[e[0]] = struct{}{}
+		terminals[e[1]] = struct{}{}
+	}
+	for _, e := range ends {
+		_, first := terminals[e[0]]
+		_, last := terminals[e[1]]
+		if first && last {
+			return true
+		}
+	}
+	return false
+}
+
+// getTripEndsForRoute returns the first and last stop, by stop_sequence,
+// of each of a route's trips that has stop times.
+func getTripEndsForRoute(feed *gtfs.Feed, routeID gtfs.RouteID) [][2]gtfs.StopID {
+	type tripEnds struct {
+		first, last *gtfs.StopTime
+	}
+	trips := make(map[gtfs.TripID]*tripEnds)
+	for tripID, trip := range feed.Trips {
+		if trip.RouteID == routeID {
+			trips[tripID] = &tripEnds{}
+		}
+	}
+
+	for _, st := range feed.StopTimes {
+		ends, ok := trips[st.TripID]
+		if !ok {
+			continue
+		}
+		if ends.first == nil || st.StopSequence < ends.first.StopSequence {
+			ends.first = st
+		}
+		if ends.last == nil || st.StopSequence > ends.last.StopSequence {
+			ends.last = st
+		}
+	}
+
+	result := make([][2]gtfs.StopID, 0, len(trips))
+	for _, ends := range trips {
+		if ends.first != nil {
+			result = append(result, [2]gtfs.StopID{ends.first.StopID, ends.last.StopID})
+		}
+	}
+	return result
+}
+
 // elementOverlapScore calculates the overlap score between two sets.
 // Formula: (common_count / a.size + common_count / b.size) / 2
 // Returns 0.0 if either collection is empty.
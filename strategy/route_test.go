@@ -1,6 +1,7 @@
 package strategy
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
@@ -201,6 +202,46 @@ func TestRouteMergeWithAgencyRef(t *testing.T) {
 	}
 }
 
+func TestRouteMergeEmptyAgencyRefInSingleAgencyFeed(t *testing.T) {
+	tests := []struct {
+		name     string
+		agencies []gtfs.AgencyID
+		want     gtfs.AgencyID
+	}{
+		{"single agency", []gtfs.AgencyID{"agency1"}, "a_agency1"},
+		{"several agencies", []gtfs.AgencyID{"agency1", "agency2"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: a source route without agency_id, its agencies
+			// already merged under prefixed IDs
+			source := gtfs.NewFeed()
+			for _, id := range tt.agencies {
+				source.AddAgency(&gtfs.Agency{ID: id, Name: string(id)})
+			}
+			source.AddRoute(&gtfs.Route{ID: "route1", ShortName: "1", Type: 3})
+
+			target := gtfs.NewFeed()
+			ctx := NewMergeContext(source, target, "a_")
+			for _, id := range tt.agencies {
+				ctx.AgencyIDMapping[id] = "a_" + id
+			}
+
+			// When: merged
+			if err := NewRouteMergeStrategy().Merge(ctx); err != nil {
+				t.Fatalf("Merge failed: %v", err)
+			}
+
+			// Then: the route references the feed's only agency, and is
+			// left alone when the feed has several
+			if got := target.Routes["route1"].AgencyID; got != tt.want {
+				t.Errorf("Expected AgencyID = %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
 func TestRouteMergeErrorOnDuplicate(t *testing.T) {
 	// Given: both feeds have route with same ID and error logging enabled
 	source := gtfs.NewFeed()
@@ -490,3 +531,216 @@ func TestRouteMergeFuzzyNoMatch_NoSharedStops(t *testing.T) {
 		t.Errorf("Expected 2 routes (no fuzzy match - no shared stops), got %d", len(target.Routes))
 	}
 }
+
+// routeFuzzyFixture builds a merge context whose source and target each hold
+// one route served by a single trip over the given stops
+func routeFuzzyFixture(source, target *gtfs.Route, sourceStops, targetStops []gtfs.StopID) *MergeContext {
+	build := func(route *gtfs.Route, tripID gtfs.TripID, stops []gtfs.StopID) *gtfs.Feed {
+		feed := gtfs.NewFeed()
+		feed.Routes[route.ID] = route
+		feed.Trips[tripID] = &gtfs.Trip{ID: tripID, RouteID: route.ID, ServiceID: "svc1"}
+		for i, stopID := range stops {
+			feed.Stops[stopID] = &gtfs.Stop{ID: stopID, Name: string(stopID)}
+			feed.StopTimes = append(feed.StopTimes, &gtfs.StopTime{TripID: tripID, StopID: stopID, StopSequence: i + 1})
+		}
+		return feed
+	}
+
+	ctx := NewMergeContext(build(source, "source_trip", sourceStops), build(target, "target_trip", targetStops), "")
+	for _, stopID := range sourceStops {
+		ctx.StopIDMapping[stopID] = stopID
+	}
+	return ctx
+}
+
+func TestRouteMergeFuzzyLocalExpressNotMerged(t *testing.T) {
+	// Given: local and express variants sharing most stops
+	local := &gtfs.Route{ID: "10", ShortName: "10", LongName: "Downtown - Northgate", Type: 3, Color: "0000FF"}
+	express := &gtfs.Route{ID: "10X", ShortName: "10", LongName: "Downtown - Northgate Express", Type: 3, Color: "FF0000"}
+	ctx := routeFuzzyFixture(express, local,
+		[]gtfs.StopID{"s1", "s2", "s3", "s5"},
+		[]gtfs.StopID{"s1", "s2", "s3", "s4", "s5"})
+
+	strategy := NewRouteMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionFuzzy)
+
+	// When: merged
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: both routes are kept
+	if len(ctx.Target.Routes) != 2 {
+		t.Errorf("Expected local and express to stay separate, got %d routes", len(ctx.Target.Routes))
+	}
+	if ctx.RouteIDMapping["10X"] != "10X" {
+		t.Errorf("Expected 10X to keep its ID, got %q", ctx.RouteIDMapping["10X"])
+	}
+}
+
+func TestRouteMergeFuzzyPropertyVetoes(t *testing.T) {
+	stops := []gtfs.StopID{"s1", "s2", "s3"}
+	base := gtfs.Route{ID: "target", ShortName: "1", LongName: "Main St", Type: 3, Desc: "Frequent", Color: "00AA00"}
+
+	tests := []struct {
+		name      string
+		modify    func(r *gtfs.Route)
+		wantMerge bool
+	}{
+		{"identical properties", func(r *gtfs.Route) {}, true},
+		{"color case differs", func(r *gtfs.Route) { r.Color = "00aa00" }, true},
+		{"color has a leading #", func(r *gtfs.Route) { r.Color = "#00aa00" }, true},
+		{"empty desc and color are neutral", func(r *gtfs.Route) { r.Desc = ""; r.Color = "" }, true},
+		{"route_type differs", func(r *gtfs.Route) { r.Type = 0 }, false},
+		{"route_desc differs", func(r *gtfs.Route) { r.Desc = "Peak only" }, false},
+		{"route_color differs", func(r *gtfs.Route) { r.Color = "AA0000" }, false},
+		{"long name case differs", func(r *gtfs.Route) { r.LongName = "MAIN ST" }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := base
+			source := base
+			source.ID = "source"
+			tt.modify(&source)
+			ctx := routeFuzzyFixture(&source, &target, stops, stops)
+
+			strategy := NewRouteMergeStrategy()
+			strategy.SetDuplicateDetection(DetectionFuzzy)
+			if err := strategy.Merge(ctx); err != nil {
+				t.Fatalf("Merge failed: %v", err)
+			}
+
+			merged := ctx.RouteIDMapping["source"] == "target"
+			if merged != tt.wantMerge {
+				t.Errorf("Expected merge=%v, got mapping %q", tt.wantMerge, ctx.RouteIDMapping["source"])
+			}
+		})
+	}
+}
+
+func TestRouteMergeFuzzyLongNameCaseInsensitive(t *testing.T) {
+	// Given: long names that differ only in case
+	stops := []gtfs.StopID{"s1", "s2"}
+	source := &gtfs.Route{ID: "source", ShortName: "1", LongName: "MAIN ST", Type: 3}
+	target := &gtfs.Route{ID: "target", ShortName: "1", LongName: "Main St", Type: 3}
+	ctx := routeFuzzyFixture(source, target, stops, stops)
+
+	strategy := NewRouteMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionFuzzy)
+	strategy.LongNameCaseInsensitive = true
+
+	// When: merged with case-insensitive long names
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the routes are merged
+	if ctx.RouteIDMapping["source"] != "target" {
+		t.Errorf("Expected source to map to target, got %q", ctx.RouteIDMapping["source"])
+	}
+}
+
+func TestRouteMergeFuzzyNormalizedShortName(t *testing.T) {
+	// Given: a zero-padded short name and a long name with a trailing space
+	stops := []gtfs.StopID{"s1", "s2"}
+	source := &gtfs.Route{ID: "source", ShortName: "05", LongName: "Main St ", Type: 3}
+	target := &gtfs.Route{ID: "target", ShortName: "5", LongName: "Main St", Type: 3}
+	ctx := routeFuzzyFixture(source, target, stops, stops)
+
+	strategy := NewRouteMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionFuzzy)
+	strategy.SetNormalizer(DefaultNormalizer(gtfs.KindRoute))
+
+	// When: merged with the default route normalizer
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the routes are merged
+	if ctx.RouteIDMapping["source"] != "target" {
+		t.Errorf("Expected source to map to target, got %q", ctx.RouteIDMapping["source"])
+	}
+}
+
+func TestRouteMergeSetFuzzyThreshold(t *testing.T) {
+	// Given: matching routes sharing 3 of 4 stops (overlap score 0.75)
+	source := &gtfs.Route{ID: "source", ShortName: "1", LongName: "Main St", Type: 3}
+	target := &gtfs.Route{ID: "target", ShortName: "1", LongName: "Main St", Type: 3}
+	sourceStops := []gtfs.StopID{"s1", "s2", "s3", "s4"}
+	targetStops := []gtfs.StopID{"s1", "s2", "s3", "s5"}
+
+	tests := []struct {
+		threshold float64
+		wantMerge bool
+	}{
+		{0.5, true},
+		{0.9, false},
+	}
+
+	for _, tt := range tests {
+		sourceCopy, targetCopy := *source, *target
+		ctx := routeFuzzyFixture(&sourceCopy, &targetCopy, sourceStops, targetStops)
+
+		strategy := NewRouteMergeStrategy()
+		strategy.SetDuplicateDetection(DetectionFuzzy)
+		strategy.SetFuzzyThreshold(tt.threshold)
+
+		// When: merged at the given threshold
+		if err := strategy.Merge(ctx); err != nil {
+			t.Fatalf("Merge failed: %v", err)
+		}
+
+		// Then: the match is accepted only when the score clears the threshold
+		merged := ctx.RouteIDMapping["source"] == "target"
+		if merged != tt.wantMerge {
+			t.Errorf("threshold %.1f: expected merge=%v, got mapping %q", tt.threshold, tt.wantMerge, ctx.RouteIDMapping["source"])
+		}
+	}
+}
+
+func TestRouteMergeFuzzyBranchesSharingTrunk(t *testing.T) {
+	// Given: two branches of the Blue Line sharing a ten-stop trunk, so that
+	// each shares 10 of its 13 stops with the other
+	trunk := []gtfs.StopID{"t01", "t02", "t03", "t04", "t05", "t06", "t07", "t08", "t09", "t10"}
+	north := append(slices.Clone(trunk), "n1", "n2", "n3")
+	south := append(slices.Clone(trunk), "s1", "s2", "s3")
+	reversed := slices.Clone(north)
+	slices.Reverse(reversed)
+
+	tests := []struct {
+		name        string
+		scoring     RouteStopScoring
+		sourceStops []gtfs.StopID
+		wantMerge   bool
+	}{
+		// The trunk dominates the overlap, so the branches are merged
+		{"overlap merges branches", RouteStopsOverlap, south, true},
+		{"terminals keeps branches apart", RouteStopsTerminals, south, false},
+		{"terminals merges the same route", RouteStopsTerminals, north, true},
+		{"terminals merges the opposite direction", RouteStopsTerminals, reversed, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &gtfs.Route{ID: "blue_south", ShortName: "Blue", Type: 1}
+			target := &gtfs.Route{ID: "blue_north", ShortName: "Blue", Type: 1}
+			ctx := routeFuzzyFixture(source, target, tt.sourceStops, north)
+
+			strategy := NewRouteMergeStrategy()
+			strategy.SetDuplicateDetection(DetectionFuzzy)
+			strategy.SetStopScoring(tt.scoring)
+
+			// When: merged
+			if err := strategy.Merge(ctx); err != nil {
+				t.Fatalf("Merge failed: %v", err)
+			}
+
+			// Then: the source route is merged only when expected
+			merged := ctx.RouteIDMapping["blue_south"] == "blue_north"
+			if merged != tt.wantMerge {
+				t.Errorf("Expected merge=%v, got mapping %q", tt.wantMerge, ctx.RouteIDMapping["blue_south"])
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package strategy
 import (
 	"fmt"
 	"log"
+	"math"
+	"slices"
 	"sort"
 
 	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
@@ -33,13 +35,16 @@ func (s *ShapeMergeStrategy) Merge(ctx *MergeContext) error {
 		return string(shapeIDs[i]) < string(shapeIDs[j])
 	})
 
-	for _, shapeID := range shapeIDs {
+	for i, shapeID := range shapeIDs {
+		if err := ctx.checkCanceled(i); err != nil {
+			return err
+		}
 		points := ctx.Source.Shapes[shapeID]
 		// Check for duplicates based on detection mode
 		if s.DuplicateDetection == DetectionIdentity {
-			if _, found := ctx.Target.Shapes[shapeID]; found {
+			if existingID, found := identityMatch(ctx, gtfs.KindShape, shapeID, ctx.Target.Shapes); found && !ctx.blocked(gtfs.KindShape, string(shapeID), string(existingID)) {
 				// Duplicate detected - map source ID to existing target ID
-				ctx.ShapeIDMapping[shapeID] = shapeID
+				ctx.RegisterShapeID(shapeID, existingID)
 
 				// Handle logging based on configuration
 				switch s.DuplicateLogging {
@@ -50,29 +55,80 @@ func (s *ShapeMergeStrategy) Merge(ctx *MergeContext) error {
 				}
 
 				// Skip adding this shape - use the existing one
+				ctx.countDeduplicated("shapes.txt", len(points))
 				continue
 			}
 		}
 
 		// Determine new ID - only apply prefix if there's a collision
-		newID := shapeID
-		if _, exists := ctx.Target.Shapes[shapeID]; exists {
-			// Collision detected - apply prefix
-			newID = gtfs.ShapeID(ctx.Prefix + string(shapeID))
+		newID := UniqueID(ctx, ctx.Target.Shapes, shapeID)
+		ctx.RegisterShapeID(shapeID, newID)
+		if _, exists := ctx.Target.Shapes[newID]; !exists {
+			ctx.JustAddedShapes[newID] = struct{}{}
+		}
+		target := slices.Grow(ctx.Target.Shapes[newID], len(points))
+
+		if ctx.NormalizeShapes {
+			normalized := normalizeShapePoints(points)
+			ctx.countDropped("shapes.txt", len(points)-len(normalized))
+			for i, point := range normalized {
+				newPoint := gtfs.ShapePoint{
+					ShapeID:      newID,
+					Lat:          point.Lat,
+					Lon:          point.Lon,
+					Sequence:     i + 1,
+					DistTraveled: ctx.ScaleDistance(point.DistTraveled),
+				}
+				target = append(target, newPoint)
+			}
+			ctx.Target.Shapes[newID] = target
+			continue
 		}
-		ctx.ShapeIDMapping[shapeID] = newID
 
 		for _, point := range points {
-			newPoint := &gtfs.ShapePoint{
+			newPoint := gtfs.ShapePoint{
 				ShapeID:      newID,
 				Lat:          point.Lat,
 				Lon:          point.Lon,
 				Sequence:     ctx.NextShapeSequence(), // Use global counter for deterministic output
-				DistTraveled: point.DistTraveled,
+				DistTraveled: ctx.ScaleDistance(point.DistTraveled),
 			}
-			ctx.Target.Shapes[newID] = append(ctx.Target.Shapes[newID], newPoint)
+			target = append(target, newPoint)
 		}
+		ctx.Target.Shapes[newID] = target
 	}
 
 	return nil
 }
+
+// shapeCoordinateScale rounds coordinates to 6 decimal places (about 10 cm)
+// when comparing shape points
+const shapeCoordinateScale = 1e6
+
+// normalizeShapePoints returns a shape's points in shape_pt_sequence order
+// with consecutive points at the same coordinates (to 6 decimal places)
+// collapsed into the first of them. Since the dropped points add no length,
+// the kept points' shape_dist_traveled values still hold.
+func normalizeShapePoints(points []gtfs.ShapePoint) []gtfs.ShapePoint {
+	sorted := make([]gtfs.ShapePoint, len(points))
+	copy(sorted, points)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Sequence < sorted[j].Sequence
+	})
+
+	kept := make([]gtfs.ShapePoint, 0, len(sorted))
+	for _, point := range sorted {
+		if n := len(kept); n > 0 && sameShapeCoordinate(&kept[n-1], &point) {
+			continue
+		}
+		kept = append(kept, point)
+	}
+	return kept
+}
+
+// sameShapeCoordinate reports whether two points have the same coordinates
+// to 6 decimal places
+func sameShapeCoordinate(a, b *gtfs.ShapePoint) bool {
+	return math.Round(a.Lat*shapeCoordinateScale) == math.Round(b.Lat*shapeCoordinateScale) &&
+		math.Round(a.Lon*shapeCoordinateScale) == math.Round(b.Lon*shapeCoordinateScale)
+}
@@ -9,13 +9,13 @@ import (
 func TestShapeMergeNoDuplicates(t *testing.T) {
 	// Given: two feeds with non-overlapping shape IDs
 	source := gtfs.NewFeed()
-	source.Shapes[gtfs.ShapeID("shape1")] = []*gtfs.ShapePoint{
+	source.Shapes[gtfs.ShapeID("shape1")] = []gtfs.ShapePoint{
 		{ShapeID: "shape1", Lat: 40.7128, Lon: -74.0060, Sequence: 1},
 		{ShapeID: "shape1", Lat: 40.7580, Lon: -73.9855, Sequence: 2},
 	}
 
 	target := gtfs.NewFeed()
-	target.Shapes[gtfs.ShapeID("shape2")] = []*gtfs.ShapePoint{
+	target.Shapes[gtfs.ShapeID("shape2")] = []gtfs.ShapePoint{
 		{ShapeID: "shape2", Lat: 40.7831, Lon: -73.9712, Sequence: 1},
 	}
 
@@ -46,12 +46,12 @@ func TestShapeMergeNoDuplicates(t *testing.T) {
 func TestShapeMergeIdentityDuplicate(t *testing.T) {
 	// Given: both feeds have shape with ID "shape1"
 	source := gtfs.NewFeed()
-	source.Shapes[gtfs.ShapeID("shape1")] = []*gtfs.ShapePoint{
+	source.Shapes[gtfs.ShapeID("shape1")] = []gtfs.ShapePoint{
 		{ShapeID: "shape1", Lat: 39.7392, Lon: -104.9903, Sequence: 1},
 	}
 
 	target := gtfs.NewFeed()
-	target.Shapes[gtfs.ShapeID("shape1")] = []*gtfs.ShapePoint{
+	target.Shapes[gtfs.ShapeID("shape1")] = []gtfs.ShapePoint{
 		{ShapeID: "shape1", Lat: 40.7128, Lon: -74.0060, Sequence: 1},
 		{ShapeID: "shape1", Lat: 40.7580, Lon: -73.9855, Sequence: 2},
 	}
@@ -86,12 +86,12 @@ func TestShapeMergeIdentityDuplicate(t *testing.T) {
 func TestShapeMergeUpdatesTripRefs(t *testing.T) {
 	// Given: source feed has a shape
 	source := gtfs.NewFeed()
-	source.Shapes[gtfs.ShapeID("shape1")] = []*gtfs.ShapePoint{
+	source.Shapes[gtfs.ShapeID("shape1")] = []gtfs.ShapePoint{
 		{ShapeID: "shape1", Lat: 39.7392, Lon: -104.9903, Sequence: 1},
 	}
 
 	target := gtfs.NewFeed()
-	target.Shapes[gtfs.ShapeID("shape1")] = []*gtfs.ShapePoint{
+	target.Shapes[gtfs.ShapeID("shape1")] = []gtfs.ShapePoint{
 		{ShapeID: "shape1", Lat: 40.7128, Lon: -74.0060, Sequence: 1},
 	}
 
@@ -115,14 +115,14 @@ func TestShapeMergeUpdatesTripRefs(t *testing.T) {
 func TestShapeMergeWithPrefix(t *testing.T) {
 	// Given: source feed has a shape that collides with target
 	source := gtfs.NewFeed()
-	source.Shapes[gtfs.ShapeID("shape1")] = []*gtfs.ShapePoint{
+	source.Shapes[gtfs.ShapeID("shape1")] = []gtfs.ShapePoint{
 		{ShapeID: "shape1", Lat: 40.7128, Lon: -74.0060, Sequence: 1},
 		{ShapeID: "shape1", Lat: 40.7580, Lon: -73.9855, Sequence: 2},
 	}
 
 	target := gtfs.NewFeed()
 	// Add colliding shape to force prefixing
-	target.Shapes[gtfs.ShapeID("shape1")] = []*gtfs.ShapePoint{
+	target.Shapes[gtfs.ShapeID("shape1")] = []gtfs.ShapePoint{
 		{ShapeID: "shape1", Lat: 41.0, Lon: -75.0, Sequence: 1},
 	}
 
@@ -165,12 +165,12 @@ func TestShapeMergeWithPrefix(t *testing.T) {
 func TestShapeMergeErrorOnDuplicate(t *testing.T) {
 	// Given: both feeds have shape with same ID and error logging enabled
 	source := gtfs.NewFeed()
-	source.Shapes[gtfs.ShapeID("shape1")] = []*gtfs.ShapePoint{
+	source.Shapes[gtfs.ShapeID("shape1")] = []gtfs.ShapePoint{
 		{ShapeID: "shape1", Lat: 39.7392, Lon: -104.9903, Sequence: 1},
 	}
 
 	target := gtfs.NewFeed()
-	target.Shapes[gtfs.ShapeID("shape1")] = []*gtfs.ShapePoint{
+	target.Shapes[gtfs.ShapeID("shape1")] = []gtfs.ShapePoint{
 		{ShapeID: "shape1", Lat: 40.7128, Lon: -74.0060, Sequence: 1},
 	}
 
@@ -200,7 +200,7 @@ func TestShapeMergeDeterministicSequences(t *testing.T) {
 		source := gtfs.NewFeed()
 		// Use shape IDs that might iterate in different orders in a map
 		// to verify we're sorting them properly
-		shapes := map[gtfs.ShapeID][]*gtfs.ShapePoint{
+		shapes := map[gtfs.ShapeID][]gtfs.ShapePoint{
 			"zebra": {
 				{ShapeID: "zebra", Lat: 1.0, Lon: 1.0, Sequence: 1},
 				{ShapeID: "zebra", Lat: 1.1, Lon: 1.1, Sequence: 2},
@@ -284,13 +284,13 @@ func TestShapeMergeSharedCounterAcrossFeeds(t *testing.T) {
 
 	// Given: two feeds with shapes
 	feed1 := gtfs.NewFeed()
-	feed1.Shapes[gtfs.ShapeID("shape_a")] = []*gtfs.ShapePoint{
+	feed1.Shapes[gtfs.ShapeID("shape_a")] = []gtfs.ShapePoint{
 		{ShapeID: "shape_a", Lat: 1.0, Lon: 1.0, Sequence: 1},
 		{ShapeID: "shape_a", Lat: 1.1, Lon: 1.1, Sequence: 2},
 	}
 
 	feed2 := gtfs.NewFeed()
-	feed2.Shapes[gtfs.ShapeID("shape_b")] = []*gtfs.ShapePoint{
+	feed2.Shapes[gtfs.ShapeID("shape_b")] = []gtfs.ShapePoint{
 		{ShapeID: "shape_b", Lat: 2.0, Lon: 2.0, Sequence: 1},
 		{ShapeID: "shape_b", Lat: 2.1, Lon: 2.1, Sequence: 2},
 		{ShapeID: "shape_b", Lat: 2.2, Lon: 2.2, Sequence: 3},
@@ -348,3 +348,57 @@ func TestShapeMergeSharedCounterAcrossFeeds(t *testing.T) {
 		t.Errorf("Expected shared counter to be 5, got %d", sharedCounter)
 	}
 }
+
+func TestShapeMergeNormalizeShapes(t *testing.T) {
+	dist := func(d float64) *float64 { return &d }
+
+	// Given: a shape out of sequence order, with sparse sequence numbers and
+	// a run of repeated coordinates (one differing only past 6 decimals)
+	source := gtfs.NewFeed()
+	source.Shapes[gtfs.ShapeID("shape1")] = []gtfs.ShapePoint{
+		{ShapeID: "shape1", Lat: 47.6100, Lon: -122.3300, Sequence: 3000, DistTraveled: dist(200)},
+		{ShapeID: "shape1", Lat: 47.6000, Lon: -122.3300, Sequence: 1000, DistTraveled: dist(0)},
+		{ShapeID: "shape1", Lat: 47.6050, Lon: -122.3300, Sequence: 2000, DistTraveled: dist(100)},
+		{ShapeID: "shape1", Lat: 47.60500001, Lon: -122.3300, Sequence: 2001, DistTraveled: dist(100)},
+		{ShapeID: "shape1", Lat: 47.6050, Lon: -122.3300, Sequence: 2002, DistTraveled: dist(100)},
+		{ShapeID: "shape1", Lat: 47.6000, Lon: -122.3300, Sequence: 4000, DistTraveled: dist(300)},
+	}
+
+	target := gtfs.NewFeed()
+	ctx := NewMergeContext(source, target, "")
+	ctx.NormalizeShapes = true
+
+	// When: merged
+	if err := NewShapeMergeStrategy().Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the repeats are dropped, sequences run from 1, and distances are kept.
+	// The last point revisits the first point's coordinates but is not
+	// consecutive with it, so it stays.
+	expected := []struct {
+		lat  float64
+		dist float64
+	}{
+		{47.6000, 0},
+		{47.6050, 100},
+		{47.6100, 200},
+		{47.6000, 300},
+	}
+	points := target.Shapes["shape1"]
+	if len(points) != len(expected) {
+		t.Fatalf("Expected %d points, got %d", len(expected), len(points))
+	}
+	for i, want := range expected {
+		p := points[i]
+		if p.Sequence != i+1 || p.Lat != want.lat || p.DistTraveled == nil || *p.DistTraveled != want.dist {
+			t.Errorf("Point %d: expected seq %d lat %f dist %g, got seq %d lat %f dist %v",
+				i, i+1, want.lat, want.dist, p.Sequence, p.Lat, p.DistTraveled)
+		}
+	}
+
+	// And: the source feed is not modified
+	if len(source.Shapes["shape1"]) != 6 {
+		t.Errorf("Expected source shape to keep 6 points, got %d", len(source.Shapes["shape1"]))
+	}
+}
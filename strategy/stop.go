@@ -3,9 +3,10 @@ package strategy
 import (
 	"fmt"
 	"log"
-	"math"
+	"slices"
 	"sort"
 
+	"github.com/aaronbrethorst/gtfs-merge-go/geo"
 	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
 )
 
@@ -16,17 +17,40 @@ type StopMergeStrategy struct {
 	FuzzyThreshold float64
 	// Concurrent controls concurrent processing for fuzzy matching
 	Concurrent ConcurrentConfig
+	// StationAwareMatching controls fuzzy matches of a flat source stop
+	// (location_type 0, no parent_station) onto a station or one of its
+	// platforms. When true (default), the match is redirected to the
+	// station's only platform, or refused (keeping both stops) when the
+	// station has several, so passengers are never sent to an arbitrary
+	// platform. When false, a flat stop only matches other stops of
+	// location_type 0, as every stop only matches its own location_type.
+	StationAwareMatching bool
+	// FuzzyLimits bounds the fuzzy matching done per input feed (default
+	// DefaultFuzzyLimits)
+	FuzzyLimits FuzzyLimits
 }
 
 // NewStopMergeStrategy creates a new StopMergeStrategy
 func NewStopMergeStrategy() *StopMergeStrategy {
 	return &StopMergeStrategy{
-		BaseStrategy:   NewBaseStrategy("stop"),
-		FuzzyThreshold: 0.5,
-		Concurrent:     DefaultConcurrentConfig(),
+		BaseStrategy:         NewBaseStrategy("stop"),
+		FuzzyThreshold:       0.5,
+		Concurrent:           DefaultConcurrentConfig(),
+		StationAwareMatching: true,
+		FuzzyLimits:          DefaultFuzzyLimits(),
 	}
 }
 
+// SetStationAwareMatching enables or disables station-aware fuzzy matching
+func (s *StopMergeStrategy) SetStationAwareMatching(enabled bool) {
+	s.StationAwareMatching = enabled
+}
+
+// SetFuzzyLimits sets FuzzyLimits
+func (s *StopMergeStrategy) SetFuzzyLimits(limits FuzzyLimits) {
+	s.FuzzyLimits = limits
+}
+
 // SetConcurrent enables or disables concurrent fuzzy matching
 func (s *StopMergeStrategy) SetConcurrent(enabled bool) {
 	s.Concurrent.Enabled = enabled
@@ -49,13 +73,40 @@ func (s *StopMergeStrategy) Merge(ctx *MergeContext) error {
 		return sortedStopIDs[i] < sortedStopIDs[j]
 	})
 
-	for _, stopID := range sortedStopIDs {
+	// Platforms of each target station, for station-aware fuzzy matching
+	var platforms map[gtfs.StopID][]gtfs.StopID
+	if s.DuplicateDetection == DetectionFuzzy && s.StationAwareMatching {
+		platforms = stationPlatforms(ctx.Target)
+	}
+
+	// Fuzzy matching only considers stops from earlier feeds (matching Java
+	// behavior), so they are indexed once, before this feed's are added;
+	// with ctx.IntraFeed, each of this feed's is indexed once added
+	var index *geo.StopIndex
+	if s.DuplicateDetection == DetectionFuzzy {
+		targets := make([]*gtfs.Stop, 0, len(ctx.Target.StopOrder))
+		for _, id := range ctx.Target.StopOrder {
+			if _, justAdded := ctx.JustAddedStops[id]; !justAdded {
+				targets = append(targets, ctx.Target.Stops[id])
+			}
+		}
+		index = geo.NewStopIndex(targets)
+	}
+
+	budget := newFuzzyBudget(s.FuzzyLimits)
+	var added []*gtfs.Stop
+	for i, stopID := range sortedStopIDs {
+		if err := ctx.checkCanceled(i); err != nil {
+			return err
+		}
 		stop := ctx.Source.Stops[stopID]
+		detection := budget.detection(s.DuplicateDetection)
 		// Check for identity duplicates (same ID in target)
-		if s.DuplicateDetection == DetectionIdentity {
-			if _, found := ctx.Target.Stops[stop.ID]; found {
+		if detection == DetectionIdentity {
+			if existingID, found := identityMatch(ctx, gtfs.KindStop, stop.ID, ctx.Target.Stops); found && !ctx.blocked(gtfs.KindStop, string(stop.ID), string(existingID)) {
 				// Identity duplicate detected - map source ID to existing target ID
-				ctx.StopIDMapping[stop.ID] = stop.ID
+				ctx.RegisterStopID(stop.ID, existingID)
+				s.fillInheritedFields(ctx, ctx.Target.Stops[existingID], stop)
 
 				switch s.DuplicateLogging {
 				case LogWarning:
@@ -65,15 +116,33 @@ func (s *StopMergeStrategy) Merge(ctx *MergeContext) error {
 				}
 
 				// Skip adding this stop - use the existing one
+				ctx.countDeduplicated("stops.txt", 1)
 				continue
 			}
 		}
 
 		// Check for fuzzy duplicates (only applies to fuzzy mode)
-		if s.DuplicateDetection == DetectionFuzzy {
-			if matchID := s.findFuzzyMatch(ctx, stop); matchID != "" {
+		if detection == DetectionFuzzy {
+			matchID, score := s.findFuzzyMatch(ctx, index, stop, budget)
+			// A fuzzy scan can be long; don't let a canceled search fall through
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if !ctx.acceptFuzzyMatch("stop", string(stop.ID), string(matchID), score, s.FuzzyThreshold) {
+				matchID = ""
+			}
+			if matchID != "" && platforms != nil && isFlatStop(stop) {
+				resolved, station := resolveStationMatch(ctx.Target, platforms, matchID)
+				if resolved == "" && s.DuplicateLogging == LogWarning {
+					log.Printf("WARNING: Fuzzy match of stop %q to %q refused: station %q has %d platforms (keeping both)",
+						stop.ID, matchID, station, len(platforms[station]))
+				}
+				matchID = resolved
+			}
+			if matchID != "" && !ctx.blocked(gtfs.KindStop, string(stop.ID), string(matchID)) {
 				// Fuzzy duplicate detected - map source ID to existing target ID
-				ctx.StopIDMapping[stop.ID] = matchID
+				ctx.RegisterStopID(stop.ID, matchID)
+				s.fillInheritedFields(ctx, ctx.Target.Stops[matchID], stop)
 
 				switch s.DuplicateLogging {
 				case LogWarning:
@@ -83,41 +152,31 @@ func (s *StopMergeStrategy) Merge(ctx *MergeContext) error {
 				}
 
 				// Skip adding this stop - use the existing one
+				ctx.countDeduplicated("stops.txt", 1)
 				continue
 			}
 		}
 
 		// Determine new ID - only apply prefix if there's a collision
-		newID := stop.ID
-		if _, exists := ctx.Target.Stops[stop.ID]; exists {
-			// Collision detected - apply prefix
-			newID = gtfs.StopID(ctx.Prefix + string(stop.ID))
-		}
-		ctx.StopIDMapping[stop.ID] = newID
-
-		// Handle parent_station reference
-		parentStation := stop.ParentStation
-		if parentStation != "" {
-			if mappedParent, ok := ctx.StopIDMapping[parentStation]; ok {
-				parentStation = mappedParent
-			} else if _, exists := ctx.Target.Stops[parentStation]; exists {
-				// Parent exists in target with collision - would have been prefixed
-				parentStation = gtfs.StopID(ctx.Prefix + string(parentStation))
-			}
-			// Otherwise keep as-is (no collision)
+		newID := UniqueID(ctx, ctx.Target.Stops, stop.ID)
+		ctx.RegisterStopID(stop.ID, newID)
+
+		name := stop.Name
+		if ctx.NormalizeOutput {
+			name = s.normalize(name)
 		}
 
 		newStop := &gtfs.Stop{
 			ID:                 newID,
 			Code:               stop.Code,
-			Name:               stop.Name,
+			Name:               name,
 			Desc:               stop.Desc,
 			Lat:                stop.Lat,
 			Lon:                stop.Lon,
 			ZoneID:             stop.ZoneID,
 			URL:                stop.URL,
 			LocationType:       stop.LocationType,
-			ParentStation:      parentStation,
+			ParentStation:      stop.ParentStation, // renamed below
 			Timezone:           stop.Timezone,
 			WheelchairBoarding: stop.WheelchairBoarding,
 			LevelID:            stop.LevelID,
@@ -126,37 +185,62 @@ func (s *StopMergeStrategy) Merge(ctx *MergeContext) error {
 		ctx.Target.Stops[newID] = newStop
 		ctx.Target.StopOrder = append(ctx.Target.StopOrder, newID)
 		ctx.JustAddedStops[newID] = struct{}{} // Track as just added for fuzzy matching
+		added = append(added, newStop)
+		if ctx.IntraFeed && index != nil {
+			index.Add(newStop)
+		}
+	}
+
+	// Parents are renamed once every stop is mapped, as a child may sort
+	// before its parent, and the parent may have merged into a stop of an
+	// earlier feed. A parent_station the feed lacks can't be renamed, and
+	// kept as it is could name another feed's stop, so it is cleared.
+	for _, stop := range added {
+		if stop.ParentStation == "" {
+			continue
+		}
+		mapped, ok := ctx.MapStopID(stop.ParentStation)
+		if !ok {
+			ctx.UnresolvedParentStations = append(ctx.UnresolvedParentStations, UnresolvedParentStation{
+				SourceFeed:    ctx.SourceFeed,
+				StopID:        stop.ID,
+				ParentStation: stop.ParentStation,
+			})
+			mapped = ""
+		}
+		stop.ParentStation = mapped
 	}
 
+	ctx.recordFuzzyLimits("stop", budget)
 	return nil
 }
 
-// findFuzzyMatch searches for a fuzzy duplicate in the target stops.
-// Returns the ID of the matching stop if found, or empty string if no match.
-// Uses name matching combined with geographic distance (multiplicative scoring).
+// findFuzzyMatch searches index for a fuzzy duplicate of source. Returns
+// the ID and score of the best matching stop, or empty string if no stop
+// scores at least the search threshold (see fuzzySearchThreshold). Uses name
+// matching combined with geographic distance (multiplicative scoring), so
+// only stops within stopMatchRadiusMeters can match. Ties go to the lowest
+// stop ID (see betterMatch). The stops scored are charged to budget.
 // Supports concurrent processing when enabled.
-func (s *StopMergeStrategy) findFuzzyMatch(ctx *MergeContext, source *gtfs.Stop) gtfs.StopID {
-	// Convert map to slice for concurrent processing
-	targets := make([]*gtfs.Stop, 0, len(ctx.Target.Stops))
-	for _, stop := range ctx.Target.Stops {
-		targets = append(targets, stop)
-	}
+func (s *StopMergeStrategy) findFuzzyMatch(ctx *MergeContext, index *geo.StopIndex, source *gtfs.Stop, budget *fuzzyBudget) (gtfs.StopID, float64) {
+	threshold := ctx.fuzzySearchThreshold(s.FuzzyThreshold)
+	targets := index.Nearby(source.Lat, source.Lon, stopMatchRadiusMeters)
+	targets = slices.DeleteFunc(targets, func(target *gtfs.Stop) bool { return !s.canFuzzyMatch(source, target) })
+	targets = limitCandidates(budget, targets, func(stop *gtfs.Stop) gtfs.StopID { return stop.ID })
+	sourceName := s.normalize(source.Name)
 
 	// Use concurrent matching if enabled and enough items
 	if s.Concurrent.Enabled && len(targets) >= s.Concurrent.MinItemsForConcurrency {
 		return findBestMatchConcurrent(
+			ctx.Context(),
 			targets,
 			func(stop *gtfs.Stop) gtfs.StopID { return stop.ID },
 			func(target *gtfs.Stop) float64 {
-				// Skip stops added in this feed (matching Java behavior)
-				if _, justAdded := ctx.JustAddedStops[target.ID]; justAdded {
-					return 0.0
-				}
-				nameScore := stopNameScore(source, target)
+				nameScore := stopNameScore(sourceName, s.normalize(target.Name))
 				distScore := stopDistanceScore(source, target)
 				return nameScore * distScore
 			},
-			s.FuzzyThreshold,
+			threshold,
 			s.Concurrent,
 		)
 	}
@@ -166,73 +250,163 @@ func (s *StopMergeStrategy) findFuzzyMatch(ctx *MergeContext, source *gtfs.Stop)
 	var bestScore float64
 
 	for _, target := range targets {
-		// Skip stops added in this feed (matching Java behavior)
-		if _, justAdded := ctx.JustAddedStops[target.ID]; justAdded {
-			continue
-		}
-
 		// Calculate combined score: name match * distance score
-		nameScore := stopNameScore(source, target)
+		nameScore := stopNameScore(sourceName, s.normalize(target.Name))
 		distScore := stopDistanceScore(source, target)
 		score := nameScore * distScore
 
-		if score >= s.FuzzyThreshold && score > bestScore {
+		if score >= threshold && betterMatch(score, target.ID, bestScore, bestMatch) {
 			bestScore = score
 			bestMatch = target.ID
 		}
 	}
 
-	return bestMatch
+	return bestMatch, bestScore
+}
+
+// canFuzzyMatch reports whether source may fuzzy match target: only stops
+// of the same location_type match, so a boarding area is never merged into
+// a station however alike their names and positions. Under
+// StationAwareMatching a flat stop may also match a station, as the match
+// is then redirected to the station's platform (see resolveStationMatch).
+func (s *StopMergeStrategy) canFuzzyMatch(source, target *gtfs.Stop) bool {
+	if source.LocationType == target.LocationType {
+		return true
+	}
+	return s.StationAwareMatching && isFlatStop(source) && target.LocationType == 1
+}
+
+// fillInheritedFields carries wheelchair_boarding and stop_timezone from a
+// duplicate stop onto the target stop it was merged into. Either field may be
+// left empty to inherit the parent station's value, and the duplicate's parent
+// doesn't follow it into the merged feed, so the duplicate's effective value
+// (its own or its parent's) fills the survivor when the survivor has no
+// effective value of its own. Conflicting values are kept and logged.
+func (s *StopMergeStrategy) fillInheritedFields(ctx *MergeContext, survivor, duplicate *gtfs.Stop) {
+	wheelchair := func(st *gtfs.Stop) int { return st.WheelchairBoarding }
+	if value := inheritedStopValue(ctx.Source, duplicate, wheelchair); value != 0 {
+		switch current := inheritedStopValue(ctx.Target, survivor, wheelchair); {
+		case current == 0:
+			survivor.WheelchairBoarding = value
+		case current != value && s.DuplicateLogging == LogWarning:
+			log.Printf("WARNING: Duplicate stop %q has wheelchair_boarding %d but %q has %d (keeping existing)",
+				duplicate.ID, value, survivor.ID, current)
+		}
+	}
+
+	timezone := func(st *gtfs.Stop) string { return st.Timezone }
+	if value := inheritedStopValue(ctx.Source, duplicate, timezone); value != "" {
+		switch current := inheritedStopValue(ctx.Target, survivor, timezone); {
+		case current == "":
+			survivor.Timezone = value
+		case current != value && s.DuplicateLogging == LogWarning:
+			log.Printf("WARNING: Duplicate stop %q has stop_timezone %q but %q has %q (keeping existing)",
+				duplicate.ID, value, survivor.ID, current)
+		}
+	}
+}
+
+// inheritedStopValue returns field of stop, or of its parent station in feed
+// when the stop leaves it empty
+func inheritedStopValue[T comparable](feed *gtfs.Feed, stop *gtfs.Stop, field func(*gtfs.Stop) T) T {
+	var zero T
+	if value := field(stop); value != zero || stop.ParentStation == "" {
+		return value
+	}
+	if parent := feed.Stops[stop.ParentStation]; parent != nil {
+		return field(parent)
+	}
+	return zero
+}
+
+// isFlatStop reports whether a stop is a plain stop outside any station
+func isFlatStop(stop *gtfs.Stop) bool {
+	return stop.LocationType == 0 && stop.ParentStation == ""
+}
+
+// stationPlatforms maps each station in the feed to its child stops and
+// platforms (location_type 0). Entrances, generic nodes and boarding areas
+// are not counted since stop_times cannot reference them.
+func stationPlatforms(feed *gtfs.Feed) map[gtfs.StopID][]gtfs.StopID {
+	platforms := make(map[gtfs.StopID][]gtfs.StopID)
+	for _, id := range feed.StopOrder {
+		stop := feed.Stops[id]
+		if stop != nil && stop.LocationType == 0 && stop.ParentStation != "" {
+			platforms[stop.ParentStation] = append(platforms[stop.ParentStation], id)
+		}
+	}
+	return platforms
+}
+
+// resolveStationMatch redirects a flat stop's fuzzy match that landed on a
+// station (location_type 1) or one of its platforms. The match resolves to
+// the station's platform when it has exactly one; otherwise it is refused
+// and "" is returned along with the station ID. Matches outside any station
+// are returned unchanged.
+func resolveStationMatch(feed *gtfs.Feed, platforms map[gtfs.StopID][]gtfs.StopID, matchID gtfs.StopID) (gtfs.StopID, gtfs.StopID) {
+	match := feed.Stops[matchID]
+	if match == nil {
+		return matchID, ""
+	}
+
+	var station gtfs.StopID
+	switch {
+	case match.LocationType == 1:
+		station = matchID
+	case match.ParentStation != "":
+		station = match.ParentStation
+	default:
+		return matchID, ""
+	}
+
+	if children := platforms[station]; len(children) == 1 {
+		return children[0], station
+	}
+	return "", station
 }
 
-// stopNameScore returns 1.0 if names match, 0.0 otherwise.
-func stopNameScore(source, target *gtfs.Stop) float64 {
-	if source.Name == target.Name {
+// stopNameScore returns 1.0 if the (normalized) names match, 0.0 otherwise.
+func stopNameScore(source, target string) float64 {
+	if source == target {
 		return 1.0
 	}
 	return 0.0
 }
 
+// stopMatchRadiusMeters is the distance beyond which stops score 0
+const stopMatchRadiusMeters = 500
+
 // stopDistanceScore returns a score based on geographic distance.
 // Uses tiered thresholds: <50m → 1.0, <100m → 0.75, <500m → 0.5, >=500m → 0.0
 func stopDistanceScore(source, target *gtfs.Stop) float64 {
-	distanceKm := haversineDistance(source.Lat, source.Lon, target.Lat, target.Lon)
-	distanceM := distanceKm * 1000
+	distanceM := geo.HaversineMeters(source.Lat, source.Lon, target.Lat, target.Lon)
 
 	switch {
 	case distanceM < 50:
 		return 1.0
 	case distanceM < 100:
 		return 0.75
-	case distanceM < 500:
+	case distanceM < stopMatchRadiusMeters:
 		return 0.5
 	default:
 		return 0.0
 	}
 }
 
-// earthRadiusKm is the mean radius of the Earth in kilometers
-const earthRadiusKm = 6371.0
-
-// haversineDistance calculates the great-circle distance between two points
-// on the Earth's surface using the Haversine formula.
-// Returns distance in kilometers.
-func haversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
-	// Convert degrees to radians
-	lat1Rad := lat1 * math.Pi / 180
-	lat2Rad := lat2 * math.Pi / 180
-	lon1Rad := lon1 * math.Pi / 180
-	lon2Rad := lon2 * math.Pi / 180
-
-	// Haversine formula
-	dLat := lat2Rad - lat1Rad
-	dLon := lon2Rad - lon1Rad
-
-	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
-		math.Cos(lat1Rad)*math.Cos(lat2Rad)*
-			math.Sin(dLon/2)*math.Sin(dLon/2)
-
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+// UnresolvedParentStation records a stop whose parent_station names a stop
+// its feed lacks, so the reference could not be renamed into the target and
+// was cleared
+type UnresolvedParentStation struct {
+	// SourceFeed names the feed the stop came from
+	SourceFeed string
+
+	// StopID is the stop's ID in the target, and ParentStation the
+	// parent_station it had in its feed
+	StopID        gtfs.StopID
+	ParentStation gtfs.StopID
+}
 
-	return earthRadiusKm * c
+// String describes the stop for logs and warnings
+func (u UnresolvedParentStation) String() string {
+	return fmt.Sprintf("feed %s: stop %q has parent_station %q, which the feed lacks; parent_station cleared", u.SourceFeed, u.StopID, u.ParentStation)
 }
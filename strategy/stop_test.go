@@ -1,6 +1,11 @@
 package strategy
 
 import (
+	"bytes"
+	"log"
+	"os"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
@@ -96,6 +101,11 @@ func TestStopMergeIdentityDuplicate(t *testing.T) {
 	if ctx.StopIDMapping["stop1"] != "stop1" {
 		t.Errorf("Expected StopIDMapping[stop1] = stop1, got %q", ctx.StopIDMapping["stop1"])
 	}
+
+	// And: the source row is counted as deduplicated
+	if got := ctx.Deduplicated["stops.txt"]; got != 1 || len(ctx.Dropped) != 0 {
+		t.Errorf("Expected 1 stops.txt row deduplicated and none dropped, got %v and %v", ctx.Deduplicated, ctx.Dropped)
+	}
 }
 
 func TestStopMergeUpdatesStopTimeRefs(t *testing.T) {
@@ -344,6 +354,92 @@ func TestStopMergeFuzzyByName(t *testing.T) {
 	}
 }
 
+func TestStopMergeFuzzyTieGoesToLowestID(t *testing.T) {
+	// Given: two identical target stops, the higher ID added first
+	source := gtfs.NewFeed()
+	source.AddStop(&gtfs.Stop{ID: "src", Name: "Downtown Station", Lat: 40.7128, Lon: -74.0060})
+
+	target := gtfs.NewFeed()
+	target.AddStop(&gtfs.Stop{ID: "stop_z", Name: "Downtown Station", Lat: 40.7128, Lon: -74.0060})
+	target.AddStop(&gtfs.Stop{ID: "stop_m", Name: "Downtown Station", Lat: 40.7128, Lon: -74.0060})
+
+	ctx := NewMergeContext(source, target, "")
+	strategy := NewStopMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionFuzzy)
+
+	// When: merged with DetectionFuzzy
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the equally scored candidate with the lower ID wins
+	if ctx.StopIDMapping["src"] != "stop_m" {
+		t.Errorf("Expected StopIDMapping[src] = stop_m, got %q", ctx.StopIDMapping["src"])
+	}
+}
+
+func TestStopMergeFuzzyNormalizedName(t *testing.T) {
+	newFeeds := func() (*gtfs.Feed, *gtfs.Feed) {
+		source := gtfs.NewFeed()
+		source.AddStop(&gtfs.Stop{ID: "stop_a", Name: "Main St ", Lat: 40.7128, Lon: -74.0060})
+		target := gtfs.NewFeed()
+		target.AddStop(&gtfs.Stop{ID: "stop_b", Name: "Main St", Lat: 40.7128, Lon: -74.0060})
+		return source, target
+	}
+
+	// Given: a trailing space in the source stop's name
+	source, target := newFeeds()
+	ctx := NewMergeContext(source, target, "")
+	strategy := NewStopMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionFuzzy)
+
+	// When: merged without a normalizer, the names don't match
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if len(target.Stops) != 2 {
+		t.Errorf("Expected 2 stops without normalization, got %d", len(target.Stops))
+	}
+
+	// When: merged with the default stop normalizer
+	source, target = newFeeds()
+	ctx = NewMergeContext(source, target, "")
+	strategy.SetNormalizer(DefaultNormalizer(gtfs.KindStop))
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: "Main St " matches "Main St", and the stored name is untouched
+	if ctx.StopIDMapping["stop_a"] != "stop_b" {
+		t.Errorf("Expected StopIDMapping[stop_a] = stop_b, got %q", ctx.StopIDMapping["stop_a"])
+	}
+	if source.Stops["stop_a"].Name != "Main St " {
+		t.Errorf("Expected the source name to be unchanged, got %q", source.Stops["stop_a"].Name)
+	}
+}
+
+func TestStopMergeNormalizeOutput(t *testing.T) {
+	// Given: a stop with untidy spacing and nothing to match
+	source := gtfs.NewFeed()
+	source.AddStop(&gtfs.Stop{ID: "stop_a", Name: "  Main   St ", Lat: 40.7128, Lon: -74.0060})
+	target := gtfs.NewFeed()
+
+	ctx := NewMergeContext(source, target, "")
+	ctx.NormalizeOutput = true
+	strategy := NewStopMergeStrategy()
+	strategy.SetNormalizer(CollapseSpaces)
+
+	// When: merged with output normalization
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the merged stop has the normalized name
+	if got := target.Stops["stop_a"].Name; got != "Main St" {
+		t.Errorf("Expected name %q, got %q", "Main St", got)
+	}
+}
+
 func TestStopMergeFuzzyByDistance(t *testing.T) {
 	// Given: stops with different IDs, same name, but within threshold distance
 	source := gtfs.NewFeed()
@@ -630,3 +726,337 @@ func TestStopMergeSetConcurrentWorkers(t *testing.T) {
 		t.Errorf("Expected NumWorkers to remain 8, got %d", strategy.Concurrent.NumWorkers)
 	}
 }
+
+// stationFixture builds a target feed with station "st" named "Central" and
+// the given number of platforms, all within a few meters of each other
+func stationFixture(numPlatforms int) *gtfs.Feed {
+	target := gtfs.NewFeed()
+	target.AddStop(&gtfs.Stop{
+		ID:           "st",
+		Name:         "Central",
+		Lat:          47.6000,
+		Lon:          -122.3300,
+		LocationType: 1,
+	})
+	for i := 0; i < numPlatforms; i++ {
+		target.AddStop(&gtfs.Stop{
+			ID:            gtfs.StopID("p" + string(rune('1'+i))),
+			Name:          "Central",
+			Lat:           47.6000 + float64(i)*0.00005,
+			Lon:           -122.3300,
+			ParentStation: "st",
+		})
+	}
+	// An entrance doesn't count as a platform
+	target.AddStop(&gtfs.Stop{
+		ID:            "entrance",
+		Name:          "Central Entrance",
+		Lat:           47.6001,
+		Lon:           -122.3301,
+		LocationType:  2,
+		ParentStation: "st",
+	})
+	return target
+}
+
+// flatCentralStop is a flat source stop matching stationFixture by name and location
+func flatCentralStop() *gtfs.Feed {
+	source := gtfs.NewFeed()
+	source.AddStop(&gtfs.Stop{
+		ID:   "flat",
+		Name: "Central",
+		Lat:  47.6000,
+		Lon:  -122.3300,
+	})
+	return source
+}
+
+func TestStopMergeFuzzyFlatStopOneChildStation(t *testing.T) {
+	// Given: a station with a single platform and a flat stop at the same place
+	target := stationFixture(1)
+	ctx := NewMergeContext(flatCentralStop(), target, "b-")
+	strategy := NewStopMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionFuzzy)
+
+	// When: merged with fuzzy detection
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the flat stop maps to the station's only platform
+	if got := ctx.StopIDMapping["flat"]; got != "p1" {
+		t.Errorf("Expected flat stop to map to p1, got %q", got)
+	}
+	if _, ok := target.Stops["flat"]; ok {
+		t.Error("Expected flat stop not to be added")
+	}
+}
+
+func TestStopMergeFuzzyFlatStopMultiChildStation(t *testing.T) {
+	// Given: a station with four platforms and a flat stop at the same place
+	target := stationFixture(4)
+	ctx := NewMergeContext(flatCentralStop(), target, "b-")
+	strategy := NewStopMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionFuzzy)
+
+	// When: merged with fuzzy detection
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the match is refused and both are kept
+	if got := ctx.StopIDMapping["flat"]; got != "flat" {
+		t.Errorf("Expected flat stop to keep its own ID, got %q", got)
+	}
+	if _, ok := target.Stops["flat"]; !ok {
+		t.Error("Expected flat stop to be added")
+	}
+}
+
+func TestStopMergeFuzzyFlatStopChildlessStation(t *testing.T) {
+	// Given: a station alone (no platforms) matching a flat stop
+	target := stationFixture(0)
+	ctx := NewMergeContext(flatCentralStop(), target, "b-")
+	strategy := NewStopMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionFuzzy)
+
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the flat stop is never mapped onto the station itself
+	if got := ctx.StopIDMapping["flat"]; got != "flat" {
+		t.Errorf("Expected flat stop to keep its own ID, got %q", got)
+	}
+}
+
+func TestStopMergeFuzzyStationAwareMatchingDisabled(t *testing.T) {
+	// Given: a multi-platform station and station-aware matching turned off
+	target := stationFixture(4)
+	ctx := NewMergeContext(flatCentralStop(), target, "b-")
+	strategy := NewStopMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionFuzzy)
+	strategy.SetStationAwareMatching(false)
+
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the flat stop fuzzy-matches one of the existing stops
+	got := ctx.StopIDMapping["flat"]
+	if got == "flat" || got == "" {
+		t.Errorf("Expected flat stop to match an existing stop, got %q", got)
+	}
+	if _, ok := target.Stops["flat"]; ok {
+		t.Error("Expected flat stop not to be added")
+	}
+}
+
+func TestStopMergeFuzzyPlatformSourceUnaffected(t *testing.T) {
+	// Given: a source platform (with its own parent) matching a multi-platform station
+	source := gtfs.NewFeed()
+	source.AddStop(&gtfs.Stop{ID: "src-st", Name: "Elsewhere", Lat: 10, Lon: 10, LocationType: 1})
+	source.AddStop(&gtfs.Stop{ID: "src-p", Name: "Central", Lat: 47.6000, Lon: -122.3300, ParentStation: "src-st"})
+	target := stationFixture(4)
+	ctx := NewMergeContext(source, target, "b-")
+	strategy := NewStopMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionFuzzy)
+
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the rule applies only to flat stops, so the platform still matches
+	if got := ctx.StopIDMapping["src-p"]; got == "src-p" {
+		t.Errorf("Expected source platform to fuzzy-match, got %q", got)
+	}
+}
+
+func TestStopMergeFuzzyRequiresSameLocationType(t *testing.T) {
+	// Given: a source boarding area and entrance, named and placed as the
+	// target's station and entrance
+	source := gtfs.NewFeed()
+	source.AddStop(&gtfs.Stop{ID: "src-st", Name: "Elsewhere", Lat: 10, Lon: 10, LocationType: 1})
+	source.AddStop(&gtfs.Stop{ID: "src-p", Name: "Elsewhere Platform", Lat: 10, Lon: 10, ParentStation: "src-st"})
+	source.AddStop(&gtfs.Stop{ID: "src-ba", Name: "Central", Lat: 47.6000, Lon: -122.3300, LocationType: 4, ParentStation: "src-p"})
+	source.AddStop(&gtfs.Stop{ID: "src-entrance", Name: "Central Entrance", Lat: 47.6001, Lon: -122.3301, LocationType: 2, ParentStation: "src-st"})
+	target := stationFixture(0)
+	ctx := NewMergeContext(source, target, "b-")
+	strategy := NewStopMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionFuzzy)
+
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the boarding area is kept apart from the station, and the
+	// entrance matches the entrance
+	if got := ctx.StopIDMapping["src-ba"]; got != "src-ba" {
+		t.Errorf("Expected the boarding area to keep its own ID, got %q", got)
+	}
+	if got := ctx.StopIDMapping["src-entrance"]; got != "entrance" {
+		t.Errorf("Expected the entrance to match entrance, got %q", got)
+	}
+}
+
+func TestStopMergeParentMatchedAfterChild(t *testing.T) {
+	// Given: a source station whose ID sorts after its platform's, and
+	// which fuzzy matches the target's station
+	source := gtfs.NewFeed()
+	source.AddStop(&gtfs.Stop{ID: "z-st", Name: "Central", Lat: 47.6000, Lon: -122.3300, LocationType: 1})
+	source.AddStop(&gtfs.Stop{ID: "a-p", Name: "Central Platform 9", Lat: 47.6000, Lon: -122.3300, ParentStation: "z-st"})
+	target := stationFixture(1)
+	ctx := NewMergeContext(source, target, "b-")
+	strategy := NewStopMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionFuzzy)
+
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the platform, added, belongs to the station its parent merged into
+	platform := target.Stops["a-p"]
+	if platform == nil {
+		t.Fatal("Expected a-p to be added")
+	}
+	if platform.ParentStation != "st" {
+		t.Errorf("Expected parent_station st, got %q", platform.ParentStation)
+	}
+}
+
+func TestStopMergeParentIdentityDuplicate(t *testing.T) {
+	// Given: a source station that identity-dedupes onto the target's, and
+	// a new platform of it, which sorts, and so is merged, first
+	source := gtfs.NewFeed()
+	source.AddStop(&gtfs.Stop{ID: "st", Name: "Central", Lat: 47.6000, Lon: -122.3300, LocationType: 1})
+	source.AddStop(&gtfs.Stop{ID: "p2", Name: "Central Platform 2", Lat: 47.6000, Lon: -122.3300, ParentStation: "st"})
+	target := gtfs.NewFeed()
+	target.AddStop(&gtfs.Stop{ID: "st", Name: "Central", Lat: 47.6000, Lon: -122.3300, LocationType: 1})
+	ctx := NewMergeContext(source, target, "b-")
+	strategy := NewStopMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionIdentity)
+
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the platform belongs to the surviving station, not to a
+	// prefixed one
+	platform := target.Stops["p2"]
+	if platform == nil {
+		t.Fatalf("Expected p2 to be added, got %v", target.StopOrder)
+	}
+	if platform.ParentStation != "st" {
+		t.Errorf("Expected parent_station st, got %q", platform.ParentStation)
+	}
+	if len(ctx.UnresolvedParentStations) != 0 {
+		t.Errorf("Expected no unresolved parents, got %v", ctx.UnresolvedParentStations)
+	}
+}
+
+func TestStopMergeUnresolvedParent(t *testing.T) {
+	// Given: a source platform whose parent_station its feed lacks, but
+	// which names a stop of the target
+	source := gtfs.NewFeed()
+	source.AddStop(&gtfs.Stop{ID: "p1", Name: "Platform", Lat: 47.6, Lon: -122.3, ParentStation: "st"})
+	target := gtfs.NewFeed()
+	target.AddStop(&gtfs.Stop{ID: "st", Name: "Another Feed's Station", Lat: 10, Lon: 10, LocationType: 1})
+	ctx := NewMergeContext(source, target, "b-")
+	ctx.SourceFeed = "b"
+	strategy := NewStopMergeStrategy()
+	strategy.SetDuplicateDetection(DetectionNone)
+
+	if err := strategy.Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: the reference is cleared rather than pointing at the other
+	// feed's station, and recorded
+	if parent := target.Stops["p1"].ParentStation; parent != "" {
+		t.Errorf("Expected parent_station cleared, got %q", parent)
+	}
+	want := []UnresolvedParentStation{{SourceFeed: "b", StopID: "p1", ParentStation: "st"}}
+	if !slices.Equal(ctx.UnresolvedParentStations, want) {
+		t.Errorf("Expected %v, got %v", want, ctx.UnresolvedParentStations)
+	}
+}
+
+func TestStopMergeDuplicateFillsInheritedFields(t *testing.T) {
+	tests := []struct {
+		name string
+		// target platform and its station
+		targetStop, targetStation gtfs.Stop
+		// source platform and its station
+		sourceStop, sourceStation gtfs.Stop
+		wantWheelchair            int
+		wantTimezone              string
+		wantLog                   string
+	}{
+		{
+			name:           "explicit values fill an empty survivor",
+			sourceStop:     gtfs.Stop{WheelchairBoarding: 1, Timezone: "America/Los_Angeles"},
+			wantWheelchair: 1,
+			wantTimezone:   "America/Los_Angeles",
+		},
+		{
+			name:           "values inherited from the duplicate's station fill an empty survivor",
+			sourceStation:  gtfs.Stop{WheelchairBoarding: 2, Timezone: "America/Chicago"},
+			wantWheelchair: 2,
+			wantTimezone:   "America/Chicago",
+		},
+		{
+			name:           "survivor inheriting from its own station is kept",
+			targetStation:  gtfs.Stop{WheelchairBoarding: 1},
+			sourceStop:     gtfs.Stop{WheelchairBoarding: 1},
+			wantWheelchair: 0,
+		},
+		{
+			name:           "conflicting explicit values are kept and logged",
+			targetStop:     gtfs.Stop{WheelchairBoarding: 1, Timezone: "America/New_York"},
+			sourceStop:     gtfs.Stop{WheelchairBoarding: 2, Timezone: "America/Chicago"},
+			wantWheelchair: 1,
+			wantTimezone:   "America/New_York",
+			wantLog:        `WARNING: Duplicate stop "p1" has wheelchair_boarding 2 but "p1" has 1`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: the same platform, each under its own station, in both feeds
+			build := func(platform, station gtfs.Stop, stationID gtfs.StopID) *gtfs.Feed {
+				feed := gtfs.NewFeed()
+				station.ID, station.Name, station.LocationType = stationID, "Station", 1
+				platform.ID, platform.Name, platform.ParentStation = "p1", "Platform", stationID
+				feed.AddStop(&station)
+				feed.AddStop(&platform)
+				return feed
+			}
+			target := build(tt.targetStop, tt.targetStation, "station_t")
+			source := build(tt.sourceStop, tt.sourceStation, "station_s")
+
+			ctx := NewMergeContext(source, target, "a-")
+			s := NewStopMergeStrategy()
+			s.SetDuplicateDetection(DetectionIdentity)
+			s.SetDuplicateLogging(LogWarning)
+
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			defer log.SetOutput(os.Stderr)
+
+			// When: merged by identity
+			if err := s.Merge(ctx); err != nil {
+				t.Fatalf("Merge failed: %v", err)
+			}
+
+			// Then: the surviving platform carries the effective values
+			p1 := target.Stops["p1"]
+			if p1.WheelchairBoarding != tt.wantWheelchair || p1.Timezone != tt.wantTimezone {
+				t.Errorf("Expected wheelchair_boarding %d and stop_timezone %q, got %d and %q",
+					tt.wantWheelchair, tt.wantTimezone, p1.WheelchairBoarding, p1.Timezone)
+			}
+			if tt.wantLog != "" && !strings.Contains(logs.String(), tt.wantLog) {
+				t.Errorf("Expected log %q, got %q", tt.wantLog, logs.String())
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package strategy
 
 import (
+	"slices"
+
 	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
 )
 
@@ -16,40 +18,93 @@ func NewStopTimeMergeStrategy() *StopTimeMergeStrategy {
 	}
 }
 
-// Merge performs the merge operation for stop times
+// Merge performs the merge operation for stop times. Where a source trip
+// was deduplicated onto a target trip, the surviving trip's stop_times take
+// shape_dist_traveled and timepoint, where unset, from the other trip's
+// stop_time at the same stop and stop_sequence (see fillStopTime).
 func (s *StopTimeMergeStrategy) Merge(ctx *MergeContext) error {
+	// Fuzzy matched trips keep the stop_times of whichever trip has more
+	// stops, the target's when they have as many (see
+	// TripMergeStrategy.MaxStopDifference)
+	var matchedRows map[stopTimeRow]*gtfs.StopTime
+	if len(ctx.MatchedTrips) > 0 {
+		matched := make(map[gtfs.TripID]bool)
+		replaced := make(map[gtfs.TripID]bool)
+		for sourceID, sourceSurvives := range ctx.MatchedTrips {
+			targetID, _ := ctx.MapTripID(sourceID)
+			matched[targetID] = true
+			if sourceSurvives {
+				replaced[targetID] = true
+			}
+		}
+		matchedRows = make(map[stopTimeRow]*gtfs.StopTime)
+		for _, st := range ctx.Target.StopTimes {
+			if matched[st.TripID] {
+				matchedRows[stopTimeRow{st.TripID, st.StopSequence, st.StopID}] = st
+			}
+		}
+		// The replaced trips' stop times give way to the source's
+		kept := len(ctx.Target.StopTimes)
+		ctx.Target.StopTimes = slices.DeleteFunc(ctx.Target.StopTimes, func(st *gtfs.StopTime) bool {
+			return replaced[st.TripID]
+		})
+		ctx.countDeduplicated("stop_times.txt", kept-len(ctx.Target.StopTimes))
+	}
+
 	// Build index for O(1) duplicate detection (avoids O(n²) linear scan)
 	type stopTimeKey struct {
 		tripID       gtfs.TripID
 		stopSequence int
 	}
-	existingKeys := make(map[stopTimeKey]bool)
+	existingKeys := make(map[stopTimeKey]*gtfs.StopTime)
 	if s.DuplicateDetection == DetectionIdentity {
 		for _, existing := range ctx.Target.StopTimes {
-			existingKeys[stopTimeKey{existing.TripID, existing.StopSequence}] = true
+			existingKeys[stopTimeKey{existing.TripID, existing.StopSequence}] = existing
 		}
 	}
 
-	for _, st := range ctx.Source.StopTimes {
-		// Map references
-		tripID := st.TripID
-		if mappedTrip, ok := ctx.TripIDMapping[tripID]; ok {
-			tripID = mappedTrip
+	// sameShape reports whether a source trip and the trip it was
+	// deduplicated onto follow the same shape, caching by source trip
+	sameShapes := make(map[gtfs.TripID]bool)
+	sameShape := func(sourceID gtfs.TripID) bool {
+		same, ok := sameShapes[sourceID]
+		if !ok {
+			targetID, _ := ctx.MapTripID(sourceID)
+			same = ctx.sameShape(sourceID, targetID)
+			sameShapes[sourceID] = same
+		}
+		return same
+	}
+
+	for i, st := range ctx.Source.StopTimes {
+		if err := ctx.checkCanceled(i); err != nil {
+			return err
 		}
 
-		stopID := st.StopID
-		if mappedStop, ok := ctx.StopIDMapping[stopID]; ok {
-			stopID = mappedStop
+		// Map references
+		tripID, _ := ctx.MapTripID(st.TripID)
+
+		stopID, _ := ctx.MapStopID(st.StopID)
+
+		sourceSurvives, matched := ctx.MatchedTrips[st.TripID]
+		if matched && !sourceSurvives {
+			if survivor := matchedRows[stopTimeRow{tripID, st.StopSequence, stopID}]; survivor != nil {
+				fillStopTime(survivor, ctx.ScaleDistance(st.ShapeDistTraveled), st.Timepoint, sameShape(st.TripID))
+			}
+			ctx.countDeduplicated("stop_times.txt", 1)
+			continue
 		}
 
 		// Check for duplicates (same trip_id, stop_sequence) using O(1) lookup
 		if s.DuplicateDetection == DetectionIdentity {
 			key := stopTimeKey{tripID, st.StopSequence}
-			if existingKeys[key] {
+			if existing := existingKeys[key]; existing != nil {
+				if existing.StopID == stopID {
+					fillStopTime(existing, ctx.ScaleDistance(st.ShapeDistTraveled), st.Timepoint, sameShape(st.TripID))
+				}
+				ctx.countDeduplicated("stop_times.txt", 1)
 				continue
 			}
-			// Add to index for subsequent source items
-			existingKeys[key] = true
 		}
 
 		newST := &gtfs.StopTime{
@@ -63,11 +118,52 @@ func (s *StopTimeMergeStrategy) Merge(ctx *MergeContext) error {
 			DropOffType:       st.DropOffType,
 			ContinuousPickup:  st.ContinuousPickup,
 			ContinuousDropOff: st.ContinuousDropOff,
-			ShapeDistTraveled: st.ShapeDistTraveled,
+			ShapeDistTraveled: ctx.ScaleDistance(st.ShapeDistTraveled),
 			Timepoint:         st.Timepoint,
 		}
+		if matched {
+			if replaced := matchedRows[stopTimeRow{tripID, st.StopSequence, stopID}]; replaced != nil {
+				fillStopTime(newST, replaced.ShapeDistTraveled, replaced.Timepoint, sameShape(st.TripID))
+			}
+		}
+		if s.DuplicateDetection == DetectionIdentity {
+			// Add to index for subsequent source items
+			existingKeys[stopTimeKey{tripID, st.StopSequence}] = newST
+		}
 		ctx.Target.StopTimes = append(ctx.Target.StopTimes, newST)
 	}
 
 	return nil
 }
+
+// stopTimeRow identifies a stop_time by its trip, stop_sequence and stop
+type stopTimeRow struct {
+	tripID       gtfs.TripID
+	stopSequence int
+	stopID       gtfs.StopID
+}
+
+// fillStopTime sets survivor's shape_dist_traveled and timepoint, where
+// unset, to dist and timepoint, the values of the matching stop_time of the
+// trip deduplicated with survivor's. The distance is only taken when both
+// trips follow the same shape, as distances are measured along it.
+func fillStopTime(survivor *gtfs.StopTime, dist *float64, timepoint *int, sameShape bool) {
+	if survivor.ShapeDistTraveled == nil && sameShape {
+		survivor.ShapeDistTraveled = dist
+	}
+	if survivor.Timepoint == nil {
+		survivor.Timepoint = timepoint
+	}
+}
+
+// sameShape reports whether the source trip sourceID and the target trip
+// targetID follow the same shape once the source's shape_id is mapped, or
+// neither has a shape
+func (ctx *MergeContext) sameShape(sourceID, targetID gtfs.TripID) bool {
+	source, target := ctx.Source.Trips[sourceID], ctx.Target.Trips[targetID]
+	if source == nil || target == nil {
+		return false
+	}
+	shapeID, _ := ctx.MapShapeID(source.ShapeID)
+	return shapeID == target.ShapeID
+}
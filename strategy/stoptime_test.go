@@ -123,3 +123,76 @@ func TestStopTimeMergeMappedRefs(t *testing.T) {
 		t.Errorf("Expected StopID = a_stop1, got %q", target.StopTimes[0].StopID)
 	}
 }
+
+func TestStopTimeMergeFillsDeduplicatedTrip(t *testing.T) {
+	// stopTimesFeed returns a feed with trip on shape, stopping at stop1
+	// and stop2 with the given distances and timepoints
+	stopTimesFeed := func(trip gtfs.TripID, shape gtfs.ShapeID, dists []*float64, timepoints []*int) *gtfs.Feed {
+		feed := gtfs.NewFeed()
+		feed.Trips[trip] = &gtfs.Trip{ID: trip, RouteID: "route1", ServiceID: "svc1", ShapeID: shape}
+		for i, stop := range []gtfs.StopID{"stop1", "stop2"} {
+			feed.StopTimes = append(feed.StopTimes, &gtfs.StopTime{
+				TripID: trip, StopID: stop, StopSequence: i + 1,
+				ShapeDistTraveled: dists[i], Timepoint: timepoints[i],
+			})
+		}
+		return feed
+	}
+	measured := []*float64{floatPtr(0), floatPtr(1.5)}
+	timed := []*int{intPtr(1), intPtr(0)}
+	unset := []*int{nil, nil}
+
+	for _, tc := range []struct {
+		name           string
+		detection      DuplicateDetection
+		sourceSurvives bool
+		targetShape    gtfs.ShapeID
+		wantDists      bool
+	}{
+		{"identity", DetectionIdentity, false, "shape1", true},
+		{"fuzzy, target survives", DetectionFuzzy, false, "shape1", true},
+		{"fuzzy, source survives", DetectionFuzzy, true, "shape1", true},
+		{"different shapes", DetectionIdentity, false, "shape2", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given: a deduplicated trip whose surviving stop_times lack the
+			// distances and timepoints the other trip's have
+			source := stopTimesFeed("trip_a", "shape1", measured, timed)
+			target := stopTimesFeed("trip_b", tc.targetShape, []*float64{nil, nil}, unset)
+			if tc.sourceSurvives {
+				source = stopTimesFeed("trip_a", "shape1", []*float64{nil, nil}, unset)
+				target = stopTimesFeed("trip_b", tc.targetShape, measured, timed)
+			}
+			ctx := NewMergeContext(source, target, "")
+			ctx.TripIDMapping["trip_a"] = "trip_b"
+			ctx.ShapeIDMapping["shape1"] = "shape1"
+			if tc.detection == DetectionFuzzy {
+				ctx.MatchedTrips = map[gtfs.TripID]bool{"trip_a": tc.sourceSurvives}
+			}
+
+			strategy := NewStopTimeMergeStrategy()
+			strategy.SetDuplicateDetection(tc.detection)
+
+			// When: merged
+			if err := strategy.Merge(ctx); err != nil {
+				t.Fatalf("Merge failed: %v", err)
+			}
+
+			// Then: one set of stop_times survives, filled in from the other;
+			// distances only when both trips follow the same shape
+			if len(target.StopTimes) != 2 {
+				t.Fatalf("Expected 2 stop times, got %d", len(target.StopTimes))
+			}
+			for i, st := range target.StopTimes {
+				if st.Timepoint == nil || *st.Timepoint != *timed[i] {
+					t.Errorf("Expected timepoint %d at sequence %d, got %v", *timed[i], st.StopSequence, st.Timepoint)
+				}
+				if got := st.ShapeDistTraveled != nil; got != tc.wantDists {
+					t.Errorf("Expected shape_dist_traveled set %v at sequence %d, got %v", tc.wantDists, st.StopSequence, st.ShapeDistTraveled)
+				} else if got && *st.ShapeDistTraveled != *measured[i] {
+					t.Errorf("Expected shape_dist_traveled %v at sequence %d, got %v", *measured[i], st.StopSequence, *st.ShapeDistTraveled)
+				}
+			}
+		})
+	}
+}
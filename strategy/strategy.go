@@ -5,10 +5,23 @@
 package strategy
 
 import (
+	"context"
+
 	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
 )
 
-// MergeContext provides context during merge operations
+// cancelCheckInterval is how many entities a strategy processes between
+// cancellation checks, keeping ctx.Err() off the hot path for large files
+const cancelCheckInterval = 64
+
+// MergeContext holds the state of merging one source feed into the target:
+// merge.Merger makes one with NewMergeContext for each input feed and passes
+// it to each strategy in turn, in dependency order (agencies, areas,
+// networks, stops, calendars, routes, shapes, trips, then the files
+// referencing them), so that a strategy finds the ID mappings of the
+// entities it references already recorded. Custom strategies read and write
+// the ID mappings through the Map and Register methods (e.g. MapStopID and
+// RegisterStopID) and name the entities they add with UniqueID.
 type MergeContext struct {
 	// Source is the feed being merged into the target
 	Source *gtfs.Feed
@@ -16,16 +29,124 @@ type MergeContext struct {
 	// Target is the output feed being built
 	Target *gtfs.Feed
 
-	// Prefix is the current feed's prefix (e.g., "a_", "b_")
+	// Prefix is the current feed's prefix (e.g., "a-", "b-"), prepended to
+	// the IDs of the entities it adds that collide with the target's (see
+	// UniqueID)
 	Prefix string
 
-	// EntityByRawID tracks entities by their original IDs
+	// SourceFeed is a stable, human-readable name for the source feed
+	// (e.g. the input file basename). Used to key feed_info rows that have
+	// no feed_id of their own.
+	SourceFeed string
+
+	// SourceEdition is the edition of the source feed, from its
+	// feed_info.txt (see NewFeedEdition)
+	SourceEdition FeedEdition
+
+	// AgencyEditions records, for each target agency and each of its
+	// agency.txt columns, the edition of the feed its value came from, so
+	// that AgencyMergeStrategy.NewestEditionWins can compare editions field
+	// by field. It is shared by the contexts of one merge; nil treats every
+	// value's edition as unknown.
+	AgencyEditions map[gtfs.AgencyID]map[string]FeedEdition
+
+	// AgencyFieldChanges collects the merged agencies' fields replaced with
+	// a newer edition's value while merging this feed
+	AgencyFieldChanges []AgencyFieldChange
+
+	// DistanceScale multiplies every shape_dist_traveled value copied from
+	// the source feed. Zero leaves values unchanged.
+	DistanceScale float64
+
+	// AlwaysPrefix makes UniqueID prepend Prefix to every ID added, not
+	// only to those that collide
+	AlwaysPrefix bool
+
+	// NormalizeShapes makes the shape strategy drop consecutive duplicate
+	// points and renumber each shape's shape_pt_sequence from 1
+	NormalizeShapes bool
+
+	// NormalizeOutput makes strategies with a Normalizer write the
+	// normalized values of the fields it compares to the entities they add,
+	// rather than only comparing them normalized
+	NormalizeOutput bool
+
+	// GrayZone configures how the stop, route and trip strategies resolve
+	// fuzzy matches scoring close to their fuzzy threshold
+	GrayZone GrayZone
+
+	// GrayZoneMatches collects the fuzzy matches resolved by the gray zone
+	// policy while merging this feed
+	GrayZoneMatches []GrayZoneMatch
+
+	// TripSubsetMatches collects the fuzzy trip matches between trips with
+	// different numbers of stops made while merging this feed
+	TripSubsetMatches []TripSubsetMatch
+
+	// BoardingDifferences collects the stop_times fields on which fuzzy
+	// matched trips differed while merging this feed
+	BoardingDifferences []BoardingDifference
+
+	// MatchedTrips maps each source trip fuzzy matched onto a target trip
+	// to whether its stop_times replace the target trip's (true, when it
+	// has more stops; see TripSubsetMatches) or are dropped (false), so
+	// the merged trip keeps a single set of stop_times
+	MatchedTrips map[gtfs.TripID]bool
+
+	// UnresolvedParentStations collects the stops added while merging this
+	// feed whose parent_station could not be resolved
+	UnresolvedParentStations []UnresolvedParentStation
+
+	// FuzzyLimitHits collects the fuzzy limits (see FuzzyLimits) that cut
+	// matching short while merging this feed
+	FuzzyLimitHits []FuzzyLimitHit
+
+	// Deduplicated counts the source rows not added to the target because
+	// they duplicate a row kept, e.g. an identity duplicate stop or the
+	// stop_times of a trip matched onto another; Dropped counts those left
+	// out for any other reason, less any extra rows made by splitting one,
+	// as an overlapping frequency may be. Both are keyed by filename, and
+	// with the rows added account for every source row.
+	Deduplicated map[string]int
+	Dropped      map[string]int
+
+	// BlockedMatches lists source and target entities that must never be
+	// merged as duplicates, whatever the duplicate detection mode; the value
+	// identifies the pair to the caller
+	BlockedMatches map[BlockedMatch]int
+
+	// BlockedHits collects the BlockedMatches values of pairs that a
+	// strategy refused to merge while merging this feed
+	BlockedHits []int
+
+	// StripIDPrefixes are namespace prefixes (e.g. "KCM:") stripped from
+	// the source feed's IDs when identity detection compares them with the
+	// target's; the IDs written keep them
+	StripIDPrefixes []string
+
+	// NamespacedIDs maps, by kind, the ID of each target entity, stripped of
+	// its own feed's namespace prefix, to its ID in the target. Identity
+	// detection falls back to it when a source ID is not in the target; nil
+	// disables the fallback.
+	NamespacedIDs map[gtfs.EntityKind]map[string]string
+
+	// EntityByRawID is not used by the built-in strategies.
+	//
+	// Deprecated: keep a custom strategy's own state in the strategy.
 	EntityByRawID map[string]interface{}
 
 	// ResolvedDetection is the auto-detected or configured strategy
 	ResolvedDetection DuplicateDetection
 
-	// ID mappings from source IDs to target IDs
+	// ID mappings from source IDs to target IDs. The strategy merging an
+	// entity kind maps every source ID it processes: to the ID of the target
+	// entity it was merged into as a duplicate, or to the ID it was added
+	// under, itself or prefixed (see UniqueID). Strategies merging entities
+	// that reference the kind rewrite each reference through the mapping,
+	// leaving an ID it lacks, one the source does not define, unchanged.
+	// NewMergeContext makes every map; use MapStopID, RegisterStopID and
+	// the like rather than the maps, which also work with a zero
+	// MergeContext.
 	AgencyIDMapping  map[gtfs.AgencyID]gtfs.AgencyID
 	StopIDMapping    map[gtfs.StopID]gtfs.StopID
 	RouteIDMapping   map[gtfs.RouteID]gtfs.RouteID
@@ -34,6 +155,18 @@ type MergeContext struct {
 	ShapeIDMapping   map[gtfs.ShapeID]gtfs.ShapeID
 	FareIDMapping    map[gtfs.FareID]gtfs.FareID
 	AreaIDMapping    map[gtfs.AreaID]gtfs.AreaID
+	NetworkIDMapping map[gtfs.NetworkID]gtfs.NetworkID
+
+	// LegGroupIDMapping maps the leg groups of the source's fare leg rules,
+	// which have no file of their own, to their IDs in the target
+	LegGroupIDMapping map[gtfs.LegGroupID]gtfs.LegGroupID
+
+	// Fares v2 ID mappings. Fare products and timeframe groups may each
+	// have several rows, so their mappings are of the IDs the rows share.
+	FareProductIDMapping    map[gtfs.FareProductID]gtfs.FareProductID
+	TimeframeGroupIDMapping map[gtfs.TimeframeGroupID]gtfs.TimeframeGroupID
+	FareMediaIDMapping      map[gtfs.FareMediaID]gtfs.FareMediaID
+	RiderCategoryIDMapping  map[gtfs.RiderCategoryID]gtfs.RiderCategoryID
 
 	// JustAddedStops tracks stop IDs added in the current feed.
 	// Used to prevent within-feed fuzzy matching (matches Java behavior).
@@ -43,6 +176,19 @@ type MergeContext struct {
 	// Used to prevent within-feed fuzzy matching (matches Java behavior).
 	JustAddedRoutes map[gtfs.RouteID]struct{}
 
+	// IntraFeed lifts that restriction: fuzzy detection also matches the
+	// source feed's stops, routes, agencies, areas and fares against those
+	// it has already added, collapsing duplicates within the feed. Used when
+	// merging a feed into an empty target (see merge.WithIntraFeedDedup).
+	IntraFeed bool
+
+	// JustAddedShapes and JustAddedServices track the shape and service IDs
+	// added to the target by the current feed, rather than mapped onto
+	// existing ones. Used to drop those left unused when the feed's trips
+	// are deduplicated.
+	JustAddedShapes   map[gtfs.ShapeID]struct{}
+	JustAddedServices map[gtfs.ServiceID]struct{}
+
 	// ShapeSequenceCounter is a counter for shape point sequences within this context.
 	// Deprecated: Use sharedShapeCounter for multi-feed merges to match Java behavior.
 	ShapeSequenceCounter int
@@ -51,6 +197,70 @@ type MergeContext struct {
 	// in a single merge operation. Used for shape point sequence numbering
 	// to match Java's behavior of globally incrementing sequences.
 	sharedShapeCounter *int
+
+	// cancel is the context.Context governing the merge; nil means the merge
+	// cannot be canceled
+	cancel context.Context
+}
+
+// SetContext sets the context.Context used to cancel the merge
+func (ctx *MergeContext) SetContext(c context.Context) {
+	ctx.cancel = c
+}
+
+// Context returns the context.Context governing the merge, or
+// context.Background() if none was set
+func (ctx *MergeContext) Context() context.Context {
+	if ctx.cancel == nil {
+		return context.Background()
+	}
+	return ctx.cancel
+}
+
+// Err returns the error from the merge's context.Context once it is canceled
+// or its deadline passes, and nil otherwise. Custom strategies should check it
+// periodically and return it unchanged.
+func (ctx *MergeContext) Err() error {
+	if ctx.cancel == nil {
+		return nil
+	}
+	return ctx.cancel.Err()
+}
+
+// checkCanceled returns Err() every cancelCheckInterval iterations of a
+// strategy's entity loop, starting with the first
+func (ctx *MergeContext) checkCanceled(i int) error {
+	if i%cancelCheckInterval != 0 {
+		return nil
+	}
+	return ctx.Err()
+}
+
+// countDeduplicated counts n source rows of filename as duplicates of rows
+// kept (see Deduplicated)
+func (ctx *MergeContext) countDeduplicated(filename string, n int) {
+	if ctx.Deduplicated == nil {
+		ctx.Deduplicated = make(map[string]int)
+	}
+	ctx.Deduplicated[filename] += n
+}
+
+// countDropped counts n source rows of filename as left out (see Dropped)
+func (ctx *MergeContext) countDropped(filename string, n int) {
+	if ctx.Dropped == nil {
+		ctx.Dropped = make(map[string]int)
+	}
+	ctx.Dropped[filename] += n
+}
+
+// ScaleDistance applies DistanceScale to a shape_dist_traveled value,
+// returning a new pointer when the value is rescaled
+func (ctx *MergeContext) ScaleDistance(d *float64) *float64 {
+	if d == nil || ctx.DistanceScale == 0 || ctx.DistanceScale == 1 {
+		return d
+	}
+	scaled := *d * ctx.DistanceScale
+	return &scaled
 }
 
 // NextShapeSequence returns the next shape point sequence number.
@@ -73,10 +283,17 @@ func (ctx *MergeContext) SetSharedShapeCounter(counter *int) {
 	ctx.sharedShapeCounter = counter
 }
 
-// NewMergeContext creates a new merge context.
+// NewMergeContext creates a merge context for merging source into target,
+// prefixing the IDs of source entities that collide with target's with
+// prefix, with every map field made and no duplicate detection resolved.
+// Nil maps on the source and target feeds are lazily initialized so that
+// zero-value feeds (not built via gtfs.NewFeed) can be merged without panicking.
 // If the source feed has empty order slices but non-empty maps, SyncOrderSlices
 // is called to populate them. This supports test code that uses direct map assignments.
 func NewMergeContext(source, target *gtfs.Feed, prefix string) *MergeContext {
+	source.EnsureInitialized()
+	target.EnsureInitialized()
+
 	// Sync order slices if they're empty but maps have data.
 	// This supports test code that populates maps directly.
 	if len(source.AgencyOrder) == 0 && len(source.Agencies) > 0 ||
@@ -87,26 +304,38 @@ func NewMergeContext(source, target *gtfs.Feed, prefix string) *MergeContext {
 		len(source.CalendarDateOrder) == 0 && len(source.CalendarDates) > 0 ||
 		len(source.FareAttrOrder) == 0 && len(source.FareAttributes) > 0 ||
 		len(source.FeedInfoOrder) == 0 && len(source.FeedInfos) > 0 ||
-		len(source.AreaOrder) == 0 && len(source.Areas) > 0 {
+		len(source.AreaOrder) == 0 && len(source.Areas) > 0 ||
+		len(source.NetworkOrder) == 0 && len(source.Networks) > 0 {
 		source.SyncOrderSlices()
 	}
 
 	return &MergeContext{
-		Source:            source,
-		Target:            target,
-		Prefix:            prefix,
-		EntityByRawID:     make(map[string]interface{}),
-		ResolvedDetection: DetectionNone,
-		AgencyIDMapping:   make(map[gtfs.AgencyID]gtfs.AgencyID),
-		StopIDMapping:     make(map[gtfs.StopID]gtfs.StopID),
-		RouteIDMapping:    make(map[gtfs.RouteID]gtfs.RouteID),
-		TripIDMapping:     make(map[gtfs.TripID]gtfs.TripID),
-		ServiceIDMapping:  make(map[gtfs.ServiceID]gtfs.ServiceID),
-		ShapeIDMapping:    make(map[gtfs.ShapeID]gtfs.ShapeID),
-		FareIDMapping:     make(map[gtfs.FareID]gtfs.FareID),
-		AreaIDMapping:     make(map[gtfs.AreaID]gtfs.AreaID),
-		JustAddedStops:    make(map[gtfs.StopID]struct{}),
-		JustAddedRoutes:   make(map[gtfs.RouteID]struct{}),
+		Source:                  source,
+		Target:                  target,
+		Prefix:                  prefix,
+		EntityByRawID:           make(map[string]interface{}),
+		ResolvedDetection:       DetectionNone,
+		Deduplicated:            make(map[string]int),
+		Dropped:                 make(map[string]int),
+		AgencyIDMapping:         make(map[gtfs.AgencyID]gtfs.AgencyID),
+		StopIDMapping:           make(map[gtfs.StopID]gtfs.StopID),
+		RouteIDMapping:          make(map[gtfs.RouteID]gtfs.RouteID),
+		TripIDMapping:           make(map[gtfs.TripID]gtfs.TripID),
+		ServiceIDMapping:        make(map[gtfs.ServiceID]gtfs.ServiceID),
+		ShapeIDMapping:          make(map[gtfs.ShapeID]gtfs.ShapeID),
+		FareIDMapping:           make(map[gtfs.FareID]gtfs.FareID),
+		AreaIDMapping:           make(map[gtfs.AreaID]gtfs.AreaID),
+		NetworkIDMapping:        make(map[gtfs.NetworkID]gtfs.NetworkID),
+		LegGroupIDMapping:       make(map[gtfs.LegGroupID]gtfs.LegGroupID),
+		FareProductIDMapping:    make(map[gtfs.FareProductID]gtfs.FareProductID),
+		TimeframeGroupIDMapping: make(map[gtfs.TimeframeGroupID]gtfs.TimeframeGroupID),
+		FareMediaIDMapping:      make(map[gtfs.FareMediaID]gtfs.FareMediaID),
+		RiderCategoryIDMapping:  make(map[gtfs.RiderCategoryID]gtfs.RiderCategoryID),
+		JustAddedStops:          make(map[gtfs.StopID]struct{}),
+		JustAddedRoutes:         make(map[gtfs.RouteID]struct{}),
+		JustAddedShapes:         make(map[gtfs.ShapeID]struct{}),
+		JustAddedServices:       make(map[gtfs.ServiceID]struct{}),
+		MatchedTrips:            make(map[gtfs.TripID]bool),
 	}
 }
 
@@ -135,6 +364,11 @@ type BaseStrategy struct {
 	DuplicateDetection DuplicateDetection
 	DuplicateLogging   DuplicateLogging
 	RenamingStrategy   RenamingStrategy
+
+	// Normalizer, when set, rewrites the text fields the strategy compares
+	// during duplicate detection (see DefaultNormalizer). The stop and
+	// route strategies use it; nil compares values as they are.
+	Normalizer Normalizer
 }
 
 // NewBaseStrategy creates a new BaseStrategy with the given name
@@ -152,11 +386,30 @@ func (b *BaseStrategy) Name() string {
 	return b.name
 }
 
+// SetNormalizer sets the normalizer applied to compared fields, or nil for
+// none
+func (b *BaseStrategy) SetNormalizer(n Normalizer) {
+	b.Normalizer = n
+}
+
+// normalize applies the strategy's normalizer to s, if it has one
+func (b *BaseStrategy) normalize(s string) string {
+	if b.Normalizer == nil {
+		return s
+	}
+	return b.Normalizer.Normalize(s)
+}
+
 // SetDuplicateDetection configures duplicate detection
 func (b *BaseStrategy) SetDuplicateDetection(d DuplicateDetection) {
 	b.DuplicateDetection = d
 }
 
+// GetDuplicateDetection returns the configured duplicate detection
+func (b *BaseStrategy) GetDuplicateDetection() DuplicateDetection {
+	return b.DuplicateDetection
+}
+
 // SetDuplicateLogging configures duplicate logging behavior
 func (b *BaseStrategy) SetDuplicateLogging(l DuplicateLogging) {
 	b.DuplicateLogging = l
@@ -1,6 +1,8 @@
 package strategy
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
@@ -149,6 +151,48 @@ func TestMergeContextCreation(t *testing.T) {
 	}
 }
 
+func TestMergeContextZeroValueFeeds(t *testing.T) {
+	// Feeds built without gtfs.NewFeed have nil maps
+	source := &gtfs.Feed{
+		Agencies: map[gtfs.AgencyID]*gtfs.Agency{"A1": {ID: "A1", Name: "Test Agency"}},
+		Areas:    map[gtfs.AreaID]*gtfs.Area{"area1": {ID: "area1", Name: "Zone"}},
+	}
+	target := &gtfs.Feed{}
+
+	ctx := NewMergeContext(source, target, "")
+
+	// Every strategy must tolerate the zero-value target
+	strategies := []EntityMergeStrategy{
+		NewAgencyMergeStrategy(),
+		NewAreaMergeStrategy(),
+		NewStopMergeStrategy(),
+		NewCalendarMergeStrategy(),
+		NewCalendarDateMergeStrategy(),
+		NewRouteMergeStrategy(),
+		NewShapeMergeStrategy(),
+		NewTripMergeStrategy(),
+		NewStopTimeMergeStrategy(),
+		NewFrequencyMergeStrategy(),
+		NewTransferMergeStrategy(),
+		NewPathwayMergeStrategy(),
+		NewFareAttributeMergeStrategy(),
+		NewFareRuleMergeStrategy(),
+		NewFeedInfoMergeStrategy(),
+	}
+	for _, s := range strategies {
+		if err := s.Merge(ctx); err != nil {
+			t.Fatalf("%s: Merge failed: %v", s.Name(), err)
+		}
+	}
+
+	if len(target.Agencies) != 1 {
+		t.Errorf("expected 1 agency in target, got %d", len(target.Agencies))
+	}
+	if len(target.Areas) != 1 {
+		t.Errorf("expected 1 area in target, got %d", len(target.Areas))
+	}
+}
+
 func TestMergeContextResolvedDetection(t *testing.T) {
 	source := gtfs.NewFeed()
 	target := gtfs.NewFeed()
@@ -204,3 +248,58 @@ func TestBaseStrategy(t *testing.T) {
 		t.Errorf("RenamingStrategy = %v, want %v", base.RenamingStrategy, RenameAgency)
 	}
 }
+
+func TestMergeContextErr(t *testing.T) {
+	mctx := NewMergeContext(gtfs.NewFeed(), gtfs.NewFeed(), "")
+
+	// Without a context the merge cannot be canceled
+	if err := mctx.Err(); err != nil {
+		t.Errorf("Expected nil error without a context, got %v", err)
+	}
+	if mctx.Context() == nil {
+		t.Error("Expected Context() to default to a non-nil context")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mctx.SetContext(ctx)
+	if err := mctx.Err(); err != nil {
+		t.Errorf("Expected nil error before cancel, got %v", err)
+	}
+
+	cancel()
+	if err := mctx.Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled after cancel, got %v", err)
+	}
+}
+
+func TestStrategiesReturnContextError(t *testing.T) {
+	// Given: a source feed with one of each entity and a canceled context
+	source := gtfs.NewFeed()
+	source.AddAgency(&gtfs.Agency{ID: "a1", Name: "Agency", Timezone: "UTC"})
+	source.AddStop(&gtfs.Stop{ID: "s1", Name: "Stop"})
+	source.AddRoute(&gtfs.Route{ID: "r1", AgencyID: "a1", Type: 3})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	strategies := []EntityMergeStrategy{
+		NewAgencyMergeStrategy(),
+		NewStopMergeStrategy(),
+		NewRouteMergeStrategy(),
+	}
+
+	for _, s := range strategies {
+		t.Run(s.Name(), func(t *testing.T) {
+			mctx := NewMergeContext(source, gtfs.NewFeed(), "")
+			mctx.SetContext(ctx)
+
+			// When: merged
+			err := s.Merge(mctx)
+
+			// Then: the context error is returned unchanged
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected context.Canceled, got %v", err)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package strategy
 
 import (
+	"log"
+
 	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
 )
 
@@ -16,6 +18,14 @@ func NewTransferMergeStrategy() *TransferMergeStrategy {
 	}
 }
 
+// inSeatTransfer reports whether transferType is an in-seat transfer
+// (types 4 and 5), from one trip to the next on the same vehicle, which
+// stays at one stop by definition and leads from its from_trip_id to its
+// to_trip_id
+func inSeatTransfer(transferType int) bool {
+	return transferType == 4 || transferType == 5
+}
+
 // Merge performs the merge operation for transfers
 func (s *TransferMergeStrategy) Merge(ctx *MergeContext) error {
 	// Build index for O(1) duplicate detection (avoids O(n²) linear scan)
@@ -24,6 +34,7 @@ func (s *TransferMergeStrategy) Merge(ctx *MergeContext) error {
 		toStopID        gtfs.StopID
 		transferType    int
 		minTransferTime int
+		hasMinTransfer  bool // distinguishes an empty min_transfer_time from 0
 		fromRouteID     gtfs.RouteID
 		toRouteID       gtfs.RouteID
 		fromTripID      gtfs.TripID
@@ -33,10 +44,11 @@ func (s *TransferMergeStrategy) Merge(ctx *MergeContext) error {
 	// makeKey creates a normalized key for a transfer.
 	// For symmetric transfers (from_stop_id == to_stop_id), route and trip IDs
 	// are normalized to canonical order (smaller first) to ensure consistent deduplication.
-	makeKey := func(fromStop, toStop gtfs.StopID, transferType, minTransferTime int,
+	// In-seat transfers keep their direction.
+	makeKey := func(fromStop, toStop gtfs.StopID, transferType int, minTransferTime *int,
 		fromRoute, toRoute gtfs.RouteID, fromTrip, toTrip gtfs.TripID) transferKey {
 		// Normalize symmetric transfers
-		if fromStop == toStop {
+		if fromStop == toStop && !inSeatTransfer(transferType) {
 			// Normalize route IDs: ensure fromRouteID <= toRouteID
 			if fromRoute > toRoute {
 				fromRoute, toRoute = toRoute, fromRoute
@@ -46,10 +58,14 @@ func (s *TransferMergeStrategy) Merge(ctx *MergeContext) error {
 				fromTrip, toTrip = toTrip, fromTrip
 			}
 		}
-		return transferKey{
-			fromStop, toStop, transferType, minTransferTime,
-			fromRoute, toRoute, fromTrip, toTrip,
+		key := transferKey{
+			fromStopID: fromStop, toStopID: toStop, transferType: transferType,
+			fromRouteID: fromRoute, toRouteID: toRoute, fromTripID: fromTrip, toTripID: toTrip,
+		}
+		if minTransferTime != nil {
+			key.minTransferTime, key.hasMinTransfer = *minTransferTime, true
 		}
+		return key
 	}
 
 	// Always build the existing keys index for deduplication
@@ -65,47 +81,33 @@ func (s *TransferMergeStrategy) Merge(ctx *MergeContext) error {
 		existingKeys[key] = true
 	}
 
-	for _, transfer := range ctx.Source.Transfers {
-		// Map stop references
-		fromStopID := transfer.FromStopID
-		if mappedStop, ok := ctx.StopIDMapping[fromStopID]; ok {
-			fromStopID = mappedStop
+	for i, transfer := range ctx.Source.Transfers {
+		if err := ctx.checkCanceled(i); err != nil {
+			return err
 		}
+		// Map stop references
+		fromStopID, _ := ctx.MapStopID(transfer.FromStopID)
 
-		toStopID := transfer.ToStopID
-		if mappedStop, ok := ctx.StopIDMapping[toStopID]; ok {
-			toStopID = mappedStop
+		toStopID, _ := ctx.MapStopID(transfer.ToStopID)
+
+		// A transfer between two stops merged into one is an artifact of
+		// deduplication rather than a transfer within a stop, so drop it;
+		// an in-seat transfer is between trips, and stays at one stop
+		if transfer.FromStopID != transfer.ToStopID && fromStopID == toStopID && !inSeatTransfer(transfer.TransferType) {
+			log.Printf("WARNING: Dropped transfer from %q to %q: both stops merged into %q", transfer.FromStopID, transfer.ToStopID, fromStopID)
+			ctx.countDropped("transfers.txt", 1)
+			continue
 		}
 
 		// Map route references
-		fromRouteID := transfer.FromRouteID
-		if fromRouteID != "" {
-			if mappedRoute, ok := ctx.RouteIDMapping[fromRouteID]; ok {
-				fromRouteID = mappedRoute
-			}
-		}
+		fromRouteID, _ := ctx.MapRouteID(transfer.FromRouteID)
 
-		toRouteID := transfer.ToRouteID
-		if toRouteID != "" {
-			if mappedRoute, ok := ctx.RouteIDMapping[toRouteID]; ok {
-				toRouteID = mappedRoute
-			}
-		}
+		toRouteID, _ := ctx.MapRouteID(transfer.ToRouteID)
 
 		// Map trip references
-		fromTripID := transfer.FromTripID
-		if fromTripID != "" {
-			if mappedTrip, ok := ctx.TripIDMapping[fromTripID]; ok {
-				fromTripID = mappedTrip
-			}
-		}
+		fromTripID, _ := ctx.MapTripID(transfer.FromTripID)
 
-		toTripID := transfer.ToTripID
-		if toTripID != "" {
-			if mappedTrip, ok := ctx.TripIDMapping[toTripID]; ok {
-				toTripID = mappedTrip
-			}
-		}
+		toTripID, _ := ctx.MapTripID(transfer.ToTripID)
 
 		// Check for duplicates using O(1) lookup (always deduplicate transfers)
 		key := makeKey(
@@ -115,6 +117,7 @@ func (s *TransferMergeStrategy) Merge(ctx *MergeContext) error {
 			fromTripID, toTripID,
 		)
 		if existingKeys[key] {
+			ctx.countDeduplicated("transfers.txt", 1)
 			continue
 		}
 		// Add to index for subsequent source items
@@ -13,7 +13,7 @@ func TestTransferMergeNoDuplicates(t *testing.T) {
 		FromStopID:      "stop1",
 		ToStopID:        "stop2",
 		TransferType:    0,
-		MinTransferTime: 120,
+		MinTransferTime: intPtr(120),
 	})
 
 	target := gtfs.NewFeed()
@@ -21,7 +21,7 @@ func TestTransferMergeNoDuplicates(t *testing.T) {
 		FromStopID:      "stop3",
 		ToStopID:        "stop4",
 		TransferType:    1,
-		MinTransferTime: 180,
+		MinTransferTime: intPtr(180),
 	})
 
 	ctx := NewMergeContext(source, target, "")
@@ -51,7 +51,7 @@ func TestTransferMergeIdentical(t *testing.T) {
 		FromStopID:      "stop1",
 		ToStopID:        "stop2",
 		TransferType:    0,
-		MinTransferTime: 120,
+		MinTransferTime: intPtr(120),
 	})
 
 	target := gtfs.NewFeed()
@@ -59,7 +59,7 @@ func TestTransferMergeIdentical(t *testing.T) {
 		FromStopID:      "stop1",
 		ToStopID:        "stop2",
 		TransferType:    0,
-		MinTransferTime: 120,
+		MinTransferTime: intPtr(120),
 	})
 
 	ctx := NewMergeContext(source, target, "")
@@ -89,7 +89,7 @@ func TestTransferMergeMappedStops(t *testing.T) {
 		FromStopID:      "stop1",
 		ToStopID:        "stop2",
 		TransferType:    0,
-		MinTransferTime: 120,
+		MinTransferTime: intPtr(120),
 	})
 
 	target := gtfs.NewFeed()
@@ -118,3 +118,64 @@ func TestTransferMergeMappedStops(t *testing.T) {
 		t.Errorf("Expected ToStopID = a_stop2, got %q", target.Transfers[0].ToStopID)
 	}
 }
+
+func TestTransferMergeDropsDeduplicatedSelfTransfer(t *testing.T) {
+	// Given: a transfer between two stops that deduplication merged into
+	// one, and a transfer within a stop in the source data
+	source := gtfs.NewFeed()
+	source.Transfers = append(source.Transfers,
+		&gtfs.Transfer{FromStopID: "north", ToStopID: "south", TransferType: 2, MinTransferTime: intPtr(180)},
+		&gtfs.Transfer{FromStopID: "north", ToStopID: "north", TransferType: 1, FromRouteID: "r1", ToRouteID: "r2"},
+	)
+
+	target := gtfs.NewFeed()
+
+	ctx := NewMergeContext(source, target, "")
+	ctx.StopIDMapping["north"] = "central"
+	ctx.StopIDMapping["south"] = "central"
+
+	// When: merged
+	if err := NewTransferMergeStrategy().Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: only the transfer within a stop is kept
+	if len(target.Transfers) != 1 {
+		t.Fatalf("Expected 1 transfer, got %d", len(target.Transfers))
+	}
+	if tr := target.Transfers[0]; tr.FromStopID != "central" || tr.ToStopID != "central" || tr.TransferType != 1 {
+		t.Errorf("Expected the transfer within central, got %+v", tr)
+	}
+}
+
+func TestTransferMergeKeepsInSeatTransfers(t *testing.T) {
+	// Given: in-seat transfers between stops that deduplication merged
+	// into one, each way between two trips
+	source := gtfs.NewFeed()
+	source.Transfers = append(source.Transfers,
+		&gtfs.Transfer{FromStopID: "north", ToStopID: "south", TransferType: 4, FromTripID: "t1", ToTripID: "t2"},
+		&gtfs.Transfer{FromStopID: "south", ToStopID: "north", TransferType: 4, FromTripID: "t2", ToTripID: "t1"},
+		&gtfs.Transfer{FromStopID: "north", ToStopID: "south", TransferType: 5, FromTripID: "t1", ToTripID: "t3"},
+	)
+
+	target := gtfs.NewFeed()
+
+	ctx := NewMergeContext(source, target, "")
+	ctx.StopIDMapping["north"] = "central"
+	ctx.StopIDMapping["south"] = "central"
+
+	// When: merged
+	if err := NewTransferMergeStrategy().Merge(ctx); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Then: every in-seat transfer is kept, in both directions
+	if len(target.Transfers) != 3 {
+		t.Fatalf("Expected 3 transfers, got %d: %+v", len(target.Transfers), target.Transfers)
+	}
+	for i, tr := range target.Transfers {
+		if tr.FromStopID != "central" || tr.ToStopID != "central" || tr.FromTripID != source.Transfers[i].FromTripID {
+			t.Errorf("Expected transfer %d from trip %s within central, got %+v", i, source.Transfers[i].FromTripID, tr)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
 )
@@ -17,6 +18,21 @@ type TripMergeStrategy struct {
 	FuzzyThreshold float64
 	// Concurrent controls concurrent processing for fuzzy matching
 	Concurrent ConcurrentConfig
+	// MaxStopDifference lets a fuzzy match pair trips whose stop sequences
+	// differ by up to this many inserted stops (default 0: stops must match)
+	MaxStopDifference int
+	// StopTimeTolerance is how far apart times at shared stops may be for a
+	// fuzzy match (default 0: times must match exactly)
+	StopTimeTolerance time.Duration
+	// StrictBoarding refuses fuzzy matches between trips whose stop_times
+	// differ in pickup_type, drop_off_type or timepoint at a shared stop.
+	// When false (default) such trips match, the surviving trip's values
+	// are kept and each difference is recorded in
+	// MergeContext.BoardingDifferences.
+	StrictBoarding bool
+	// FuzzyLimits bounds the fuzzy matching done per input feed (default
+	// DefaultFuzzyLimits)
+	FuzzyLimits FuzzyLimits
 }
 
 // NewTripMergeStrategy creates a new TripMergeStrategy
@@ -25,6 +41,7 @@ func NewTripMergeStrategy() *TripMergeStrategy {
 		BaseStrategy:   NewBaseStrategy("trip"),
 		FuzzyThreshold: 0.5,
 		Concurrent:     DefaultConcurrentConfig(),
+		FuzzyLimits:    DefaultFuzzyLimits(),
 	}
 }
 
@@ -40,6 +57,22 @@ func (s *TripMergeStrategy) SetConcurrentWorkers(n int) {
 	}
 }
 
+// SetStopSubset sets MaxStopDifference and StopTimeTolerance
+func (s *TripMergeStrategy) SetStopSubset(maxStopDifference int, tolerance time.Duration) {
+	s.MaxStopDifference = maxStopDifference
+	s.StopTimeTolerance = tolerance
+}
+
+// SetStrictBoarding sets StrictBoarding
+func (s *TripMergeStrategy) SetStrictBoarding(strict bool) {
+	s.StrictBoarding = strict
+}
+
+// SetFuzzyLimits sets FuzzyLimits
+func (s *TripMergeStrategy) SetFuzzyLimits(limits FuzzyLimits) {
+	s.FuzzyLimits = limits
+}
+
 // Merge performs the merge operation for trips
 func (s *TripMergeStrategy) Merge(ctx *MergeContext) error {
 	// Sort source trip IDs to match Java output order
@@ -50,13 +83,18 @@ func (s *TripMergeStrategy) Merge(ctx *MergeContext) error {
 		return sortedTripIDs[i] < sortedTripIDs[j]
 	})
 
-	for _, tripID := range sortedTripIDs {
+	budget := newFuzzyBudget(s.FuzzyLimits)
+	for i, tripID := range sortedTripIDs {
+		if err := ctx.checkCanceled(i); err != nil {
+			return err
+		}
 		trip := ctx.Source.Trips[tripID]
+		detection := budget.detection(s.DuplicateDetection)
 		// Check for duplicates based on detection mode
-		if s.DuplicateDetection == DetectionIdentity {
-			if existing, found := ctx.Target.Trips[trip.ID]; found {
+		if detection == DetectionIdentity {
+			if existingID, found := identityMatch(ctx, gtfs.KindTrip, trip.ID, ctx.Target.Trips); found && !ctx.blocked(gtfs.KindTrip, string(trip.ID), string(existingID)) {
 				// Duplicate detected - map source ID to existing target ID
-				ctx.TripIDMapping[trip.ID] = existing.ID
+				ctx.RegisterTripID(trip.ID, existingID)
 
 				// Handle logging based on configuration
 				switch s.DuplicateLogging {
@@ -67,15 +105,28 @@ func (s *TripMergeStrategy) Merge(ctx *MergeContext) error {
 				}
 
 				// Skip adding this trip - use the existing one
+				ctx.countDeduplicated("trips.txt", 1)
 				continue
 			}
 		}
 
 		// Check for fuzzy duplicates
-		if s.DuplicateDetection == DetectionFuzzy {
-			if matchID := s.findFuzzyMatch(ctx, trip); matchID != "" {
+		if detection == DetectionFuzzy {
+			matchID, score, extra, diffs := s.findFuzzyMatch(ctx, trip, budget)
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if !ctx.acceptFuzzyMatch("trip", string(trip.ID), string(matchID), score, s.FuzzyThreshold) {
+				matchID = ""
+			}
+			if matchID != "" && !ctx.blocked(gtfs.KindTrip, string(trip.ID), string(matchID)) {
 				// Fuzzy duplicate detected - map source ID to existing target ID
-				ctx.TripIDMapping[trip.ID] = matchID
+				ctx.RegisterTripID(trip.ID, matchID)
+				ctx.recordMatchedTrip(trip.ID, extra > 0)
+				if extra != 0 {
+					ctx.recordTripSubsetMatch(trip.ID, matchID, score, extra, s.MaxStopDifference)
+				}
+				ctx.recordBoardingDifferences(diffs)
 
 				switch s.DuplicateLogging {
 				case LogWarning:
@@ -85,35 +136,21 @@ func (s *TripMergeStrategy) Merge(ctx *MergeContext) error {
 				}
 
 				// Skip adding this trip - use the existing one
+				ctx.countDeduplicated("trips.txt", 1)
 				continue
 			}
 		}
 
 		// Determine new ID - only apply prefix if there's a collision
-		newID := trip.ID
-		if _, exists := ctx.Target.Trips[trip.ID]; exists {
-			// Collision detected - apply prefix
-			newID = gtfs.TripID(ctx.Prefix + string(trip.ID))
-		}
-		ctx.TripIDMapping[trip.ID] = newID
+		newID := UniqueID(ctx, ctx.Target.Trips, trip.ID)
+		ctx.RegisterTripID(trip.ID, newID)
 
 		// Map references
-		routeID := trip.RouteID
-		if mappedRoute, ok := ctx.RouteIDMapping[routeID]; ok {
-			routeID = mappedRoute
-		}
+		routeID, _ := ctx.MapRouteID(trip.RouteID)
 
-		serviceID := trip.ServiceID
-		if mappedService, ok := ctx.ServiceIDMapping[serviceID]; ok {
-			serviceID = mappedService
-		}
+		serviceID, _ := ctx.MapServiceID(trip.ServiceID)
 
-		shapeID := trip.ShapeID
-		if shapeID != "" {
-			if mappedShape, ok := ctx.ShapeIDMapping[shapeID]; ok {
-				shapeID = mappedShape
-			}
-		}
+		shapeID, _ := ctx.MapShapeID(trip.ShapeID)
 
 		newTrip := &gtfs.Trip{
 			ID:                   newID,
@@ -131,20 +168,35 @@ func (s *TripMergeStrategy) Merge(ctx *MergeContext) error {
 		ctx.Target.TripOrder = append(ctx.Target.TripOrder, newID)
 	}
 
+	ctx.recordFuzzyLimits("trip", budget)
 	return nil
 }
 
 // findFuzzyMatch searches for a fuzzy duplicate in the target trips.
-// Returns the ID of the matching trip if found, or empty string if no match.
+// Returns the ID and score of the best matching trip, or empty string if no
+// trip scores at least the search threshold (see fuzzySearchThreshold).
 // Uses route, service_id, shared stops, and schedule overlap (multiplicative scoring).
-// Additionally validates that stop times match exactly.
-// Supports concurrent processing when enabled.
-func (s *TripMergeStrategy) findFuzzyMatch(ctx *MergeContext, source *gtfs.Trip) gtfs.TripID {
-	// Convert map to slice for concurrent processing
+// Additionally validates that stop times match, exactly unless
+// MaxStopDifference or StopTimeTolerance allow otherwise; the third result
+// is how many more stops the source trip has than the match (see
+// compareTripStopTimes), and the fourth how their stop_times differ in
+// pickup_type, drop_off_type and timepoint; under StrictBoarding trips that
+// differ don't match. Ties go to the lowest trip ID (see betterMatch). Only
+// trips on the same route and service are candidates, and they are charged
+// to budget.
+func (s *TripMergeStrategy) findFuzzyMatch(ctx *MergeContext, source *gtfs.Trip, budget *fuzzyBudget) (gtfs.TripID, float64, int, []BoardingDifference) {
+	threshold := ctx.fuzzySearchThreshold(s.FuzzyThreshold)
+
+	// Trips on another route or service score 0 whatever their stops, so
+	// they aren't candidates. Candidates are taken in map order, which
+	// doesn't change the match (see RouteMergeStrategy.findFuzzyMatch).
 	targets := make([]*gtfs.Trip, 0, len(ctx.Target.Trips))
-	for _, trip := range ctx.Target.Trips {
-		targets = append(targets, trip)
+	for _, target := range ctx.Target.Trips {
+		if tripRouteScore(ctx, source, target) > 0 && tripServiceScore(ctx, source, target) > 0 {
+			targets = append(targets, target)
+		}
 	}
+	targets = limitCandidates(budget, targets, func(trip *gtfs.Trip) gtfs.TripID { return trip.ID })
 
 	// Note: Trip fuzzy matching includes stop time validation which needs sequential access
 	// to maintain correctness. We still use sequential matching for trips due to this
@@ -154,6 +206,8 @@ func (s *TripMergeStrategy) findFuzzyMatch(ctx *MergeContext, source *gtfs.Trip)
 	// Sequential matching (default for trips due to stop time validation)
 	var bestMatch gtfs.TripID
 	var bestScore float64
+	var bestExtra int
+	var bestDiffs []BoardingDifference
 
 	for _, target := range targets {
 		// Calculate combined score: route * serviceId * stopsInCommon * scheduleOverlap
@@ -165,25 +219,30 @@ func (s *TripMergeStrategy) findFuzzyMatch(ctx *MergeContext, source *gtfs.Trip)
 		// Multiplicative scoring - any 0 fails the match
 		score := routeScore * serviceScore * stopsScore * scheduleScore
 
-		if score >= s.FuzzyThreshold && score > bestScore {
-			// Additional validation: check stop times match exactly
-			if validateTripStopTimes(ctx, source.ID, target.ID) {
-				bestScore = score
-				bestMatch = target.ID
+		if score >= threshold && betterMatch(score, target.ID, bestScore, bestMatch) {
+			// Additional validation: check stop times match
+			extra, pairs, ok := compareTripStopTimes(ctx, source.ID, target.ID, s.MaxStopDifference, s.StopTimeTolerance)
+			if !ok {
+				continue
+			}
+			diffs := boardingDifferences(pairs, source.ID, target.ID)
+			if s.StrictBoarding && len(diffs) > 0 {
+				continue
 			}
+			bestScore = score
+			bestMatch = target.ID
+			bestExtra = extra
+			bestDiffs = diffs
 		}
 	}
 
-	return bestMatch
+	return bestMatch, bestScore, bestExtra, bestDiffs
 }
 
 // tripRouteScore returns 1.0 if routes match (considering mappings), 0.0 otherwise.
 func tripRouteScore(ctx *MergeContext, source, target *gtfs.Trip) float64 {
 	// Get mapped route ID for source
-	mappedSourceRoute := source.RouteID
-	if mapped, ok := ctx.RouteIDMapping[source.RouteID]; ok {
-		mappedSourceRoute = mapped
-	}
+	mappedSourceRoute, _ := ctx.MapRouteID(source.RouteID)
 
 	if mappedSourceRoute == target.RouteID {
 		return 1.0
@@ -194,10 +253,7 @@ func tripRouteScore(ctx *MergeContext, source, target *gtfs.Trip) float64 {
 // tripServiceScore returns 1.0 if service IDs match (considering mappings), 0.0 otherwise.
 func tripServiceScore(ctx *MergeContext, source, target *gtfs.Trip) float64 {
 	// Get mapped service ID for source
-	mappedSourceService := source.ServiceID
-	if mapped, ok := ctx.ServiceIDMapping[source.ServiceID]; ok {
-		mappedSourceService = mapped
-	}
+	mappedSourceService, _ := ctx.MapServiceID(source.ServiceID)
 
 	if mappedSourceService == target.ServiceID {
 		return 1.0
@@ -333,56 +389,6 @@ func intervalOverlapScore(start1, end1, start2, end2 float64) float64 {
 	return (scoreA + scoreB) / 2.0
 }
 
-// validateTripStopTimes checks if two trips have matching stop times.
-// Returns false if:
-// - Stop count differs
-// - Any stop at the same sequence position differs
-// - Any arrival or departure time differs
-func validateTripStopTimes(ctx *MergeContext, sourceTripID, targetTripID gtfs.TripID) bool {
-	sourceStopTimes := getStopTimesForTrip(ctx.Source, sourceTripID)
-	targetStopTimes := getStopTimesForTrip(ctx.Target, targetTripID)
-
-	// Check stop count matches
-	if len(sourceStopTimes) != len(targetStopTimes) {
-		return false
-	}
-
-	// Sort both by stop sequence
-	sort.Slice(sourceStopTimes, func(i, j int) bool {
-		return sourceStopTimes[i].StopSequence < sourceStopTimes[j].StopSequence
-	})
-	sort.Slice(targetStopTimes, func(i, j int) bool {
-		return targetStopTimes[i].StopSequence < targetStopTimes[j].StopSequence
-	})
-
-	// Check each stop time matches
-	for i := range sourceStopTimes {
-		src := sourceStopTimes[i]
-		tgt := targetStopTimes[i]
-
-		// Get mapped stop ID for source
-		mappedSourceStop := src.StopID
-		if mapped, ok := ctx.StopIDMapping[src.StopID]; ok {
-			mappedSourceStop = mapped
-		}
-
-		// Check stop matches
-		if mappedSourceStop != tgt.StopID {
-			return false
-		}
-
-		// Check times match exactly
-		if src.ArrivalTime != tgt.ArrivalTime {
-			return false
-		}
-		if src.DepartureTime != tgt.DepartureTime {
-			return false
-		}
-	}
-
-	return true
-}
-
 // getStopTimesForTrip returns all stop times for a trip.
 func getStopTimesForTrip(feed *gtfs.Feed, tripID gtfs.TripID) []*gtfs.StopTime {
 	var stopTimes []*gtfs.StopTime
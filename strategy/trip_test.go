@@ -1,6 +1,8 @@
 package strategy
 
 import (
+	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
@@ -319,6 +321,77 @@ func TestTripMergeFuzzyByStops(t *testing.T) {
 	}
 }
 
+func TestTripMergeFuzzyByStopsWithDifferingBoarding(t *testing.T) {
+	// boardingFeed returns a feed with one two-stop trip whose last stop
+	// has the given pickup_type and timepoint
+	boardingFeed := func(tripID gtfs.TripID, lastPickup int, lastTimepoint *int) *gtfs.Feed {
+		feed := gtfs.NewFeed()
+		feed.Routes[gtfs.RouteID("route1")] = &gtfs.Route{ID: "route1", ShortName: "1"}
+		feed.Calendars[gtfs.ServiceID("svc1")] = &gtfs.Calendar{ServiceID: "svc1", Monday: true, StartDate: "20240101", EndDate: "20241231"}
+		feed.Trips[tripID] = &gtfs.Trip{ID: tripID, RouteID: "route1", ServiceID: "svc1", Headsign: "Downtown"}
+		feed.Stops[gtfs.StopID("stop1")] = &gtfs.Stop{ID: "stop1", Name: "Stop 1"}
+		feed.Stops[gtfs.StopID("stop2")] = &gtfs.Stop{ID: "stop2", Name: "Stop 2"}
+		feed.StopTimes = append(feed.StopTimes,
+			&gtfs.StopTime{TripID: tripID, StopID: "stop1", StopSequence: 1, ArrivalTime: "08:00:00", DepartureTime: "08:00:00"},
+			&gtfs.StopTime{TripID: tripID, StopID: "stop2", StopSequence: 2, ArrivalTime: "08:30:00", DepartureTime: "08:30:00",
+				PickupType: lastPickup, Timepoint: lastTimepoint},
+		)
+		return feed
+	}
+
+	for _, strict := range []bool{false, true} {
+		t.Run(fmt.Sprintf("strict=%v", strict), func(t *testing.T) {
+			// Given: matching trips, the source marking its last stop
+			// drop-off only and exact where the target marks it approximate
+			source := boardingFeed("trip_a", 1, intPtr(1))
+			target := boardingFeed("trip_b", 0, intPtr(0))
+
+			ctx := NewMergeContext(source, target, "")
+			ctx.SourceFeed = "a"
+			ctx.RouteIDMapping["route1"] = "route1"
+			ctx.ServiceIDMapping["svc1"] = "svc1"
+			ctx.StopIDMapping["stop1"] = "stop1"
+			ctx.StopIDMapping["stop2"] = "stop2"
+
+			strategy := NewTripMergeStrategy()
+			strategy.SetDuplicateDetection(DetectionFuzzy)
+			strategy.SetStrictBoarding(strict)
+
+			// When: merged with DetectionFuzzy
+			if err := strategy.Merge(ctx); err != nil {
+				t.Fatalf("Merge failed: %v", err)
+			}
+
+			if strict {
+				// Then: the trips are kept apart
+				if len(target.Trips) != 2 || len(ctx.BoardingDifferences) != 0 {
+					t.Errorf("Expected 2 trips and no differences, got %d trips and %v", len(target.Trips), ctx.BoardingDifferences)
+				}
+				return
+			}
+
+			// Then: the trips merge, the target's stop_times surviving
+			if ctx.TripIDMapping["trip_a"] != "trip_b" {
+				t.Fatalf("Expected TripIDMapping[trip_a] = trip_b, got %q", ctx.TripIDMapping["trip_a"])
+			}
+			if survives, ok := ctx.MatchedTrips["trip_a"]; !ok || survives {
+				t.Errorf("Expected trip_a's stop_times to be dropped, got %v", ctx.MatchedTrips)
+			}
+
+			// And: both differences at the last stop are recorded
+			want := []BoardingDifference{
+				{SourceFeed: "a", SourceID: "trip_a", TargetID: "trip_b", StopID: "stop2", StopSequence: 2,
+					Field: "pickup_type", SourceValue: "1", TargetValue: "0"},
+				{SourceFeed: "a", SourceID: "trip_a", TargetID: "trip_b", StopID: "stop2", StopSequence: 2,
+					Field: "timepoint", SourceValue: "1", TargetValue: "0"},
+			}
+			if !reflect.DeepEqual(ctx.BoardingDifferences, want) {
+				t.Errorf("Expected differences %+v, got %+v", want, ctx.BoardingDifferences)
+			}
+		})
+	}
+}
+
 func TestTripMergeFuzzyBySchedule(t *testing.T) {
 	// Given: trips with overlapping schedule windows
 	source := gtfs.NewFeed()
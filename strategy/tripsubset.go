@@ -0,0 +1,192 @@
+package strategy
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// TripSubsetMatch records a fuzzy trip match between trips whose stop
+// sequences differ by inserted stops (see TripMergeStrategy.MaxStopDifference)
+type TripSubsetMatch struct {
+	// SourceFeed names the feed the source trip came from
+	SourceFeed string
+
+	// SourceID is the ID of the trip being merged and TargetID the ID of
+	// the trip already merged that it matched
+	SourceID gtfs.TripID
+	TargetID gtfs.TripID
+
+	// Score is the fuzzy match score
+	Score float64
+
+	// ExtraStops is how many more stops the survivor has than the other
+	// trip, and MaxStopDifference the limit it was allowed
+	ExtraStops        int
+	MaxStopDifference int
+
+	// SourceSurvives is true if the source trip had more stops, so its
+	// stop_times replaced the target trip's
+	SourceSurvives bool
+}
+
+// String describes the match for debug output
+func (m TripSubsetMatch) String() string {
+	survivor, other := m.TargetID, m.SourceID
+	if m.SourceSurvives {
+		survivor, other = m.SourceID, m.TargetID
+	}
+	return fmt.Sprintf("feed %s: trip %q matches %q (score %.3f): %q has %d more stops (max %d) and keeps its stop_times over %q",
+		m.SourceFeed, m.SourceID, m.TargetID, m.Score, survivor, m.ExtraStops, m.MaxStopDifference, other)
+}
+
+// recordTripSubsetMatch records that source trip sourceID, having extra
+// more stops than target trip targetID, was merged into it
+func (ctx *MergeContext) recordTripSubsetMatch(sourceID, targetID gtfs.TripID, score float64, extra, maxDiff int) {
+	match := TripSubsetMatch{
+		SourceFeed:        ctx.SourceFeed,
+		SourceID:          sourceID,
+		TargetID:          targetID,
+		Score:             score,
+		ExtraStops:        max(extra, -extra),
+		MaxStopDifference: maxDiff,
+		SourceSurvives:    extra > 0,
+	}
+	ctx.TripSubsetMatches = append(ctx.TripSubsetMatches, match)
+}
+
+// recordMatchedTrip records that source trip sourceID was fuzzy matched
+// onto a target trip, and whether its stop_times replace the target's
+func (ctx *MergeContext) recordMatchedTrip(sourceID gtfs.TripID, sourceSurvives bool) {
+	if ctx.MatchedTrips == nil {
+		ctx.MatchedTrips = make(map[gtfs.TripID]bool)
+	}
+	ctx.MatchedTrips[sourceID] = sourceSurvives
+}
+
+// compareTripStopTimes checks whether the source trip's stop_times match
+// the target trip's. With maxDiff 0 and a zero tolerance the trips must
+// visit the same stops in the same order at the same times. Otherwise the
+// shorter trip's stops may be a subsequence of the longer trip's with up to
+// maxDiff stops missing, and the times at shared stops may differ by up to
+// tolerance. A trip may visit a stop more than once, as loop trips do, so
+// the subsequence is found by dynamic programming rather than by pairing
+// each stop with its first visit; of the embeddings that fit, the one with
+// the smallest total time difference is chosen. Trips with as many stops
+// are paired stop for stop without the search. extra is the number of
+// stops the source has more than the target, negative if it has fewer, and
+// pairs the stop_times of the stops the trips share.
+func compareTripStopTimes(ctx *MergeContext, sourceTripID, targetTripID gtfs.TripID, maxDiff int, tolerance time.Duration) (extra int, pairs []stopTimePair, ok bool) {
+	sourceStopTimes := getStopTimesForTrip(ctx.Source, sourceTripID)
+	targetStopTimes := getStopTimesForTrip(ctx.Target, targetTripID)
+
+	extra = len(sourceStopTimes) - len(targetStopTimes)
+	if extra > maxDiff || -extra > maxDiff {
+		return extra, nil, false
+	}
+
+	sort.Slice(sourceStopTimes, func(i, j int) bool {
+		return sourceStopTimes[i].StopSequence < sourceStopTimes[j].StopSequence
+	})
+	sort.Slice(targetStopTimes, func(i, j int) bool {
+		return targetStopTimes[i].StopSequence < targetStopTimes[j].StopSequence
+	})
+
+	// Trips with as many stops, as maxDiff 0 requires, can only pair stop
+	// for stop, so there is no embedding to search for
+	if extra == 0 {
+		pairs = make([]stopTimePair, len(sourceStopTimes))
+		for i, source := range sourceStopTimes {
+			pairs[i] = stopTimePair{source: source, target: targetStopTimes[i]}
+			if _, match := stopTimeCost(ctx, pairs[i], tolerance); !match {
+				return extra, nil, false
+			}
+		}
+		return extra, pairs, true
+	}
+
+	shorter, longer := sourceStopTimes, targetStopTimes
+	if extra > 0 {
+		shorter, longer = targetStopTimes, sourceStopTimes
+	}
+	pair := func(i, j int) stopTimePair {
+		if extra > 0 {
+			return stopTimePair{source: longer[j], target: shorter[i]}
+		}
+		return stopTimePair{source: shorter[i], target: longer[j]}
+	}
+
+	// cost[i][j] is the smallest total time difference with which
+	// shorter[i:] embeds in longer[j:], or -1 if it does not
+	n, m := len(shorter), len(longer)
+	cost := make([][]int, n+1)
+	for i := range cost {
+		cost[i] = make([]int, m+1)
+		if i < n {
+			cost[i][m] = -1
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			best := cost[i][j+1]
+			if c, match := stopTimeCost(ctx, pair(i, j), tolerance); match && cost[i+1][j+1] >= 0 {
+				if c += cost[i+1][j+1]; best < 0 || c <= best {
+					best = c
+				}
+			}
+			cost[i][j] = best
+		}
+	}
+	if cost[0][0] < 0 {
+		return extra, nil, false
+	}
+
+	// Follow the cheapest embedding, pairing a stop as early as it allows
+	for i, j := 0, 0; i < n; j++ {
+		p := pair(i, j)
+		if c, match := stopTimeCost(ctx, p, tolerance); match && cost[i+1][j+1] >= 0 && c+cost[i+1][j+1] == cost[i][j] {
+			pairs = append(pairs, p)
+			i++
+		}
+	}
+	return extra, pairs, true
+}
+
+// stopTimeCost reports whether a pair of stop_times can be matched, at the
+// same stop with times within tolerance, and the total seconds by which
+// their times differ
+func stopTimeCost(ctx *MergeContext, p stopTimePair, tolerance time.Duration) (cost int, ok bool) {
+	if stopID, _ := ctx.MapStopID(p.source.StopID); stopID != p.target.StopID {
+		return 0, false
+	}
+	if !timesWithin(p.source.ArrivalTime, p.target.ArrivalTime, tolerance) ||
+		!timesWithin(p.source.DepartureTime, p.target.DepartureTime, tolerance) {
+		return 0, false
+	}
+	return timeDifference(p.source.ArrivalTime, p.target.ArrivalTime) +
+		timeDifference(p.source.DepartureTime, p.target.DepartureTime), true
+}
+
+// timeDifference returns the seconds between two GTFS times, 0 if the
+// strings are equal
+func timeDifference(a, b string) int {
+	if a == b {
+		return 0
+	}
+	d := parseGTFSTime(a) - parseGTFSTime(b)
+	return max(d, -d)
+}
+
+// timesWithin reports whether two GTFS times are within tolerance of each
+// other. With a zero tolerance the strings must be equal.
+func timesWithin(a, b string, tolerance time.Duration) bool {
+	if a == b {
+		return true
+	}
+	if tolerance <= 0 || a == "" || b == "" {
+		return false
+	}
+	return time.Duration(timeDifference(a, b))*time.Second <= tolerance
+}
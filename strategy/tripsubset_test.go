@@ -0,0 +1,212 @@
+package strategy
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aaronbrethorst/gtfs-merge-go/gtfs"
+)
+
+// subsetTripFeed builds a feed with one trip on route1/svc1 visiting the
+// given stops, the nth at 08:00 plus offsets[n] seconds
+func subsetTripFeed(tripID gtfs.TripID, stops []gtfs.StopID, offsets []int) *gtfs.Feed {
+	feed := gtfs.NewFeed()
+	feed.Routes["route1"] = &gtfs.Route{ID: "route1", ShortName: "1"}
+	feed.Calendars["svc1"] = &gtfs.Calendar{ServiceID: "svc1", Monday: true, StartDate: "20240101", EndDate: "20241231"}
+	feed.Trips[tripID] = &gtfs.Trip{ID: tripID, RouteID: "route1", ServiceID: "svc1"}
+	feed.TripOrder = append(feed.TripOrder, tripID)
+	for i, stopID := range stops {
+		feed.Stops[stopID] = &gtfs.Stop{ID: stopID, Name: string(stopID)}
+		at := fmt.Sprintf("08:%02d:%02d", offsets[i]/60, offsets[i]%60)
+		feed.StopTimes = append(feed.StopTimes, &gtfs.StopTime{
+			TripID: tripID, StopID: stopID, StopSequence: i + 1, ArrivalTime: at, DepartureTime: at,
+		})
+	}
+	return feed
+}
+
+func TestTripMergeStopSubset(t *testing.T) {
+	// Times are in seconds after 08:00 (see subsetTripFeed)
+	tests := []struct {
+		name          string
+		sourceStops   []gtfs.StopID
+		sourceTimes   []int
+		targetStops   []gtfs.StopID
+		targetTimes   []int
+		maxDiff       int
+		tolerance     time.Duration
+		expectMerged  bool
+		expectSurvive bool
+	}{
+		{
+			name:        "source inserts a stop",
+			sourceStops: []gtfs.StopID{"s1", "s2", "s3"}, sourceTimes: []int{0, 600, 1200},
+			targetStops: []gtfs.StopID{"s1", "s3"}, targetTimes: []int{0, 1200},
+			maxDiff: 1, expectMerged: true, expectSurvive: true,
+		},
+		{
+			name:        "target inserts a stop, times within tolerance",
+			sourceStops: []gtfs.StopID{"s1", "s3"}, sourceTimes: []int{30, 1200},
+			targetStops: []gtfs.StopID{"s1", "s2", "s3"}, targetTimes: []int{0, 600, 1230},
+			maxDiff: 1, tolerance: time.Minute, expectMerged: true,
+		},
+		{
+			name:        "inserted stop with default difference",
+			sourceStops: []gtfs.StopID{"s1", "s2", "s3"}, sourceTimes: []int{0, 600, 1200},
+			targetStops: []gtfs.StopID{"s1", "s3"}, targetTimes: []int{0, 1200},
+		},
+		{
+			name:        "more inserted stops than allowed",
+			sourceStops: []gtfs.StopID{"s1", "s2", "s3", "s4"}, sourceTimes: []int{0, 300, 600, 1200},
+			targetStops: []gtfs.StopID{"s1", "s4"}, targetTimes: []int{0, 1200},
+			maxDiff: 1,
+		},
+		{
+			name:        "shared stop times outside tolerance",
+			sourceStops: []gtfs.StopID{"s1", "s2", "s3"}, sourceTimes: []int{0, 600, 1200},
+			targetStops: []gtfs.StopID{"s1", "s3"}, targetTimes: []int{0, 1320},
+			maxDiff: 1, tolerance: time.Minute,
+		},
+		{
+			name:        "shared stops out of order",
+			sourceStops: []gtfs.StopID{"s1", "s2", "s3"}, sourceTimes: []int{0, 600, 1200},
+			targetStops: []gtfs.StopID{"s3", "s1"}, targetTimes: []int{0, 1200},
+			maxDiff: 1, tolerance: time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: a source and a target trip on the same route and service
+			source := subsetTripFeed("trip_a", tt.sourceStops, tt.sourceTimes)
+			target := subsetTripFeed("trip_b", tt.targetStops, tt.targetTimes)
+			ctx := NewMergeContext(source, target, "a_")
+
+			// When: trips and stop_times are merged fuzzily with the
+			// configured stop difference
+			trips := NewTripMergeStrategy()
+			trips.SetDuplicateDetection(DetectionFuzzy)
+			trips.SetStopSubset(tt.maxDiff, tt.tolerance)
+			if err := trips.Merge(ctx); err != nil {
+				t.Fatalf("trip Merge failed: %v", err)
+			}
+			if err := NewStopTimeMergeStrategy().Merge(ctx); err != nil {
+				t.Fatalf("stop_time Merge failed: %v", err)
+			}
+
+			// Then: the trips are merged only when expected
+			merged := ctx.TripIDMapping["trip_a"] == "trip_b"
+			if merged != tt.expectMerged {
+				t.Fatalf("Expected merged=%v, got mapping %q", tt.expectMerged, ctx.TripIDMapping["trip_a"])
+			}
+			if !merged {
+				if len(ctx.TripSubsetMatches) != 0 {
+					t.Errorf("Expected no subset matches, got %v", ctx.TripSubsetMatches)
+				}
+				return
+			}
+
+			// And: the match is recorded with the difference allowed
+			if len(ctx.TripSubsetMatches) != 1 {
+				t.Fatalf("Expected 1 subset match, got %v", ctx.TripSubsetMatches)
+			}
+			match := ctx.TripSubsetMatches[0]
+			if match.ExtraStops != 1 || match.MaxStopDifference != tt.maxDiff || match.SourceSurvives != tt.expectSurvive {
+				t.Errorf("Expected 1 extra stop, max %d, source survives %v, got %+v", tt.maxDiff, tt.expectSurvive, match)
+			}
+
+			// And: the surviving trip's stop_times are kept, and only those
+			if len(target.StopTimes) != 3 {
+				t.Fatalf("Expected the 3 stop_times of the longer trip, got %d", len(target.StopTimes))
+			}
+			for _, st := range target.StopTimes {
+				if st.TripID != "trip_b" {
+					t.Errorf("Expected stop_times on trip_b, got %s", st.TripID)
+				}
+			}
+		})
+	}
+}
+
+func TestCompareTripStopTimesExact(t *testing.T) {
+	// Given: identical trips, and one a second late at one stop
+	source := subsetTripFeed("trip_a", []gtfs.StopID{"s1", "s2"}, []int{0, 600})
+	target := subsetTripFeed("trip_b", []gtfs.StopID{"s1", "s2"}, []int{0, 601})
+	ctx := NewMergeContext(source, target, "")
+
+	// When/Then: with no difference allowed, the times must match exactly
+	if _, _, ok := compareTripStopTimes(ctx, "trip_a", "trip_b", 0, 0); ok {
+		t.Errorf("Expected a 1 second difference to fail without tolerance")
+	}
+	if extra, _, ok := compareTripStopTimes(ctx, "trip_a", "trip_b", 0, time.Second); !ok || extra != 0 {
+		t.Errorf("Expected a match within 1 second, got extra=%d ok=%v", extra, ok)
+	}
+
+	// And: trips with as many stops pair stop for stop, whatever maxDiff
+	extra, pairs, ok := compareTripStopTimes(ctx, "trip_a", "trip_b", 2, time.Second)
+	if !ok || extra != 0 || len(pairs) != 2 {
+		t.Fatalf("Expected 2 pairs, got extra=%d ok=%v pairs=%d", extra, ok, len(pairs))
+	}
+	for i, p := range pairs {
+		if p.source.StopSequence != i+1 || p.target.StopSequence != i+1 {
+			t.Errorf("Expected pair %d to be stop %d of both trips, got %d and %d", i, i+1, p.source.StopSequence, p.target.StopSequence)
+		}
+	}
+}
+
+func TestCompareTripStopTimesLoop(t *testing.T) {
+	tests := []struct {
+		name        string
+		targetStops []gtfs.StopID
+		targetTimes []int
+		tolerance   time.Duration
+		expectSeqs  []int
+	}{
+		{
+			name:        "only the second visit matches",
+			targetStops: []gtfs.StopID{"s1", "s3"},
+			targetTimes: []int{600, 900},
+			expectSeqs:  []int{3, 4},
+		},
+		{
+			name:        "the closer visit is chosen",
+			targetStops: []gtfs.StopID{"s1", "s3"},
+			targetTimes: []int{500, 900},
+			tolerance:   10 * time.Minute,
+			expectSeqs:  []int{3, 4},
+		},
+		{
+			name:        "the first visit matches",
+			targetStops: []gtfs.StopID{"s1", "s2"},
+			targetTimes: []int{0, 300},
+			expectSeqs:  []int{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: a loop trip visiting s1 twice, and a shorter trip
+			source := subsetTripFeed("trip_a", []gtfs.StopID{"s1", "s2", "s1", "s3"}, []int{0, 300, 600, 900})
+			target := subsetTripFeed("trip_b", tt.targetStops, tt.targetTimes)
+			ctx := NewMergeContext(source, target, "")
+
+			// When: the stop_times are compared
+			extra, pairs, ok := compareTripStopTimes(ctx, "trip_a", "trip_b", 2, tt.tolerance)
+
+			// Then: the shorter trip is matched to the right visits
+			if !ok || extra != 2 {
+				t.Fatalf("Expected a match with 2 extra stops, got extra=%d ok=%v", extra, ok)
+			}
+			if len(pairs) != len(tt.expectSeqs) {
+				t.Fatalf("Expected %d pairs, got %d", len(tt.expectSeqs), len(pairs))
+			}
+			for i, p := range pairs {
+				if p.source.StopSequence != tt.expectSeqs[i] || p.target.StopSequence != i+1 {
+					t.Errorf("Expected pair %d to be source %d with target %d, got %d with %d",
+						i, tt.expectSeqs[i], i+1, p.source.StopSequence, p.target.StopSequence)
+				}
+			}
+		})
+	}
+}